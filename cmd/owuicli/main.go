@@ -2,28 +2,35 @@ package main
 
 import (
 	"os"
+	"strings"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	"github.com/vosiander/open-webui-backup/pkg/cli"
 	"github.com/vosiander/open-webui-backup/pkg/config"
+	"github.com/vosiander/open-webui-backup/pkg/metrics"
+	"github.com/vosiander/open-webui-backup/pkg/notify"
 	"github.com/vosiander/open-webui-backup/pkg/plugin"
 	"github.com/vosiander/open-webui-backup/plugins"
 )
 
+// commandStart is set when a command starts running; zero means cobra rejected the invocation
+var commandStart time.Time
+
 func main() {
-	// Configure logrus
+	// Configure logrus; stdout is reserved for command output
 	logrus.SetFormatter(&logrus.TextFormatter{
 		FullTimestamp: true,
 	})
-	logrus.SetOutput(os.Stdout)
+	logrus.SetOutput(os.Stderr)
 	logrus.SetLevel(logrus.InfoLevel)
 
-	if err := run(); err != nil {
-		logrus.WithError(err).Fatal("Application error")
-	}
+	os.Exit(run())
 }
 
-func run() error {
+// run executes the command line and returns the exit code
+func run() int {
 	// Load configuration from environment variables
 	cfg := config.Load()
 
@@ -37,30 +44,149 @@ func run() error {
 
 	// Register age encryption and backup management plugins
 	registry.Register(plugins.NewNewIdentityPlugin())
+	registry.Register(plugins.NewNewSigningKeyPlugin())
 	registry.Register(plugins.NewVerifyPlugin())
+	registry.Register(plugins.NewDrillPlugin())
+	registry.Register(plugins.NewDiffPlugin())
+	registry.Register(plugins.NewInspectPlugin())
+	registry.Register(plugins.NewImportPlugin())
+	registry.Register(plugins.NewDatasetExportPlugin())
+	registry.Register(plugins.NewRedactPlugin())
+	registry.Register(plugins.NewUserExportPlugin())
+	registry.Register(plugins.NewEraseUserPlugin())
 	registry.Register(plugins.NewFullBackupPlugin())
 	registry.Register(plugins.NewDecryptPlugin())
 	registry.Register(plugins.NewStatisticsPlugin())
 	registry.Register(plugins.NewChatsPlugin())
+	registry.Register(plugins.NewNotifyTestPlugin())
 
 	// Register database backup plugins
 	registry.Register(plugins.NewBackupDatabasePlugin())
 	registry.Register(plugins.NewPurgeDatabasePlugin())
 	registry.Register(plugins.NewRestoreDatabasePlugin())
 
+	// Register point-in-time recovery plugins
+	registry.Register(plugins.NewBaseBackupPlugin())
+	registry.Register(plugins.NewWALArchivePlugin())
+	registry.Register(plugins.NewWALRestorePlugin())
+
+	// Register vector store backup plugins
+	registry.Register(plugins.NewBackupVectorsPlugin())
+	registry.Register(plugins.NewRestoreVectorsPlugin())
+
+	// Register data directory backup plugins
+	registry.Register(plugins.NewBackupUploadsPlugin())
+	registry.Register(plugins.NewRestoreUploadsPlugin())
+
 	// Create root command
 	rootCmd := &cobra.Command{
 		Use:   "owuicli",
 		Short: "Open WebUI Backup CLI Tool",
 		Long:  "Command-line tool to backup and restore various important information from an Open WebUI application",
+		// Errors and usage are reported below, after the exit code is known
+		SilenceErrors: true,
+		SilenceUsage:  true,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return configureOutput(cfg.Output)
+		},
 	}
+	rootCmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return cli.Usage(err)
+	})
+
+	rootCmd.PersistentFlags().StringVar(&cfg.Output, "output", cfg.Output, "Output format: text or json (prints a result object on stdout; or use OWUI_OUTPUT env variable)")
+	rootCmd.PersistentFlags().StringVar(&cfg.NotifyConfig, "notify-config", cfg.NotifyConfig, "Notification sinks config file (or use OWUI_NOTIFY_CONFIG env variable)")
+	rootCmd.PersistentFlags().StringVar(&cfg.MetricsPushURL, "metrics-push-url", cfg.MetricsPushURL, "Push metrics to a Pushgateway-compatible endpoint after the command (or use OWUI_METRICS_PUSH_URL env variable)")
 
 	// Register all plugin commands
 	for _, p := range registry.GetPlugins() {
 		cmd := plugin.CreateCommand(p, cfg)
+		instrumentCommand(cmd, p.Name(), cfg)
+		trackStart(cmd)
 		rootCmd.AddCommand(cmd)
 	}
 
 	// Execute root command
-	return rootCmd.Execute()
+	cmd, err := rootCmd.ExecuteC()
+	if commandStart.IsZero() {
+		if err == nil {
+			// Help output
+			return cli.ExitOK
+		}
+		// Flags are parsed even if the command was rejected, so --output json still applies
+		configureOutput(cfg.Output)
+		err = cli.Usage(err)
+	}
+
+	if cli.JSON() {
+		name := strings.TrimPrefix(strings.TrimPrefix(cmd.CommandPath(), rootCmd.Name()), " ")
+		envelope := cli.NewEnvelope(name, commandStart, err, cli.TakeResult())
+		if writeErr := envelope.Write(os.Stdout); writeErr != nil {
+			logrus.Errorf("Failed to write result: %v", writeErr)
+		}
+	}
+
+	if err != nil {
+		logrus.WithError(err).Error("Command failed")
+		if cli.Code(err) == cli.ExitUsage && !cli.JSON() {
+			cmd.SetOut(os.Stderr)
+			cmd.Usage()
+		}
+	}
+	return cli.Code(err)
+}
+
+// configureOutput selects the output format; JSON output also switches logs to JSON
+func configureOutput(format string) error {
+	if err := cli.SetFormat(format); err != nil {
+		return err
+	}
+	if cli.JSON() {
+		logrus.SetFormatter(&logrus.JSONFormatter{})
+	}
+	return nil
+}
+
+// trackStart records when a command or one of its subcommands starts running
+func trackStart(cmd *cobra.Command) {
+	for _, sub := range cmd.Commands() {
+		trackStart(sub)
+	}
+	if cmd.RunE == nil {
+		return
+	}
+	run := cmd.RunE
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		commandStart = time.Now()
+		return run(cmd, args)
+	}
+}
+
+// instrumentCommand records the outcome of a command, sends notifications and pushes metrics if configured
+func instrumentCommand(cmd *cobra.Command, name string, cfg *config.Config) {
+	run := cmd.RunE
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		start := time.Now()
+		err := run(cmd, args)
+		metrics.ObserveOperation(name, start, err)
+
+		if cfg.NotifyConfig != "" && notify.IsOperation(name) {
+			notifier, loadErr := notify.Load(cfg.NotifyConfig)
+			if loadErr != nil {
+				logrus.Warnf("Notifications disabled: %v", loadErr)
+			} else {
+				notifier.Notify(notify.NewEvent(name, "cli", start, err))
+			}
+		}
+
+		if cfg.MetricsPushURL != "" {
+			// Grouping by command keeps e.g. the last backup timestamp when verify pushes later
+			if pushErr := metrics.Default.Push(cfg.MetricsPushURL, "owuicli", "command", name); pushErr != nil {
+				logrus.Warnf("Failed to push metrics: %v", pushErr)
+			} else {
+				logrus.Debugf("Pushed metrics to %s", cfg.MetricsPushURL)
+			}
+		}
+		return err
+	}
 }
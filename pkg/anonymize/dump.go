@@ -0,0 +1,405 @@
+package anonymize
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// nullValue is how COPY writes SQL NULL
+const nullValue = `\N`
+
+// Stats counts the values changed by an Anonymizer
+type Stats struct {
+	Rows   int `json:"rows"`
+	Values int `json:"values"`
+}
+
+// Anonymizer applies a ruleset to the COPY data of plain SQL dumps
+// Hashes and fake values are keyed by the ruleset salt, so the same email becomes
+// the same fake email in every table and joins on hashed columns keep working
+type Anonymizer struct {
+	ruleset *Ruleset
+	key     []byte
+	used    map[string]bool // columns of the rules that matched a dumped column
+	Stats   Stats
+}
+
+// New creates an anonymizer for a ruleset
+func New(ruleset *Ruleset) (*Anonymizer, error) {
+	if err := ruleset.Validate(); err != nil {
+		return nil, err
+	}
+	a := &Anonymizer{ruleset: ruleset, used: make(map[string]bool)}
+	if ruleset.Salt != "" {
+		a.key = []byte(ruleset.Salt)
+	} else {
+		a.key = make([]byte, 32)
+		if _, err := rand.Read(a.key); err != nil {
+			return nil, fmt.Errorf("failed to generate anonymization key: %w", err)
+		}
+	}
+	return a, nil
+}
+
+// Ruleset returns the ruleset applied by the anonymizer
+func (a *Anonymizer) Ruleset() *Ruleset {
+	return a.ruleset
+}
+
+// Unmatched returns the rules that matched no column of the dump, e.g. a typo or an older schema
+func (a *Anonymizer) Unmatched() []string {
+	var unmatched []string
+	for _, rule := range a.ruleset.Rules {
+		if !a.used[rule.Column] {
+			unmatched = append(unmatched, rule.Column)
+		}
+	}
+	return unmatched
+}
+
+// Writer returns a writer that anonymizes a plain SQL dump written through it into w
+// Close must be called to flush the last line
+func (a *Anonymizer) Writer(w io.Writer) io.WriteCloser {
+	return &dumpWriter{a: a, w: w}
+}
+
+// dumpWriter rewrites the rows of COPY blocks line by line
+type dumpWriter struct {
+	a       *Anonymizer
+	w       io.Writer
+	pending []byte        // start of a line without its newline yet
+	inCopy  bool          // inside a COPY block
+	columns []*columnRule // rule of each column of the current COPY block, nil if unchanged
+	err     error
+}
+
+func (d *dumpWriter) Write(p []byte) (int, error) {
+	if d.err != nil {
+		return 0, d.err
+	}
+	n := len(p)
+	for len(p) > 0 {
+		i := bytes.IndexByte(p, '\n')
+		if i < 0 {
+			d.pending = append(d.pending, p...)
+			break
+		}
+		line := p[:i+1]
+		if len(d.pending) > 0 {
+			line = append(d.pending, line...)
+			d.pending = d.pending[:0]
+		}
+		if err := d.line(line); err != nil {
+			d.err = err
+			return n - len(p), err
+		}
+		p = p[i+1:]
+	}
+	return n, nil
+}
+
+// Close writes a last line that has no newline
+func (d *dumpWriter) Close() error {
+	if d.err != nil {
+		return d.err
+	}
+	if len(d.pending) > 0 {
+		d.err = d.line(d.pending)
+		d.pending = nil
+	}
+	return d.err
+}
+
+// line writes one line, with its newline if it has one
+func (d *dumpWriter) line(line []byte) error {
+	body := bytes.TrimRight(line, "\r\n")
+	switch {
+	case !d.inCopy:
+		// Every COPY starts a data block; one that cannot be parsed would pass its rows through unchanged
+		if bytes.HasPrefix(body, []byte("COPY ")) {
+			table, columns, err := parseCopyHeader(string(body))
+			if err != nil {
+				return fmt.Errorf("failed to parse COPY statement %q: %w", body, err)
+			}
+			d.inCopy = true
+			d.columns = d.a.columnRules(table, columns)
+		}
+	case string(body) == `\.`:
+		d.inCopy = false
+		d.columns = nil
+	case d.columns != nil:
+		row := d.a.row(string(body), d.columns)
+		line = append([]byte(row), line[len(body):]...)
+	}
+	if _, err := d.w.Write(line); err != nil {
+		return fmt.Errorf("failed to write dump: %w", err)
+	}
+	return nil
+}
+
+// columnRule is the rule applied to a column of a COPY block
+type columnRule struct {
+	*Rule
+	name string
+}
+
+// columnRules returns the rule of each column of a COPY block, or nil if no column is changed
+func (a *Anonymizer) columnRules(table string, columns []string) []*columnRule {
+	var rules []*columnRule
+	for i, column := range columns {
+		rule := a.ruleset.match(table, column)
+		if rule == nil {
+			continue
+		}
+		if rules == nil {
+			rules = make([]*columnRule, len(columns))
+		}
+		rules[i] = &columnRule{Rule: rule, name: column}
+		a.used[rule.Column] = true
+	}
+	return rules
+}
+
+// parseCopyHeader returns the table and columns of a COPY ... FROM stdin; line of a plain pg_dump,
+// e.g. COPY public."user" (id, "Name") FROM stdin;
+func parseCopyHeader(line string) (string, []string, error) {
+	rest := strings.TrimRight(strings.TrimPrefix(line, "COPY "), " \t")
+
+	// [schema.]table
+	table, rest, err := parseIdentifier(rest)
+	if err != nil {
+		return "", nil, err
+	}
+	if strings.HasPrefix(rest, ".") {
+		if table, rest, err = parseIdentifier(rest[1:]); err != nil {
+			return "", nil, err
+		}
+	}
+
+	// Optional column list; tables without columns have none
+	var columns []string
+	rest = strings.TrimLeft(rest, " \t")
+	if strings.HasPrefix(rest, "(") {
+		rest = rest[1:]
+		for {
+			var column string
+			if column, rest, err = parseIdentifier(strings.TrimLeft(rest, " \t")); err != nil {
+				return "", nil, err
+			}
+			columns = append(columns, column)
+			rest = strings.TrimLeft(rest, " \t")
+			if strings.HasPrefix(rest, ",") {
+				rest = rest[1:]
+				continue
+			}
+			if !strings.HasPrefix(rest, ")") {
+				return "", nil, fmt.Errorf("unterminated column list")
+			}
+			rest = rest[1:]
+			break
+		}
+	}
+
+	if strings.TrimLeft(rest, " \t") != "FROM stdin;" {
+		return "", nil, fmt.Errorf("expected FROM stdin; after the table")
+	}
+	return table, columns, nil
+}
+
+// parseIdentifier reads a plain or double-quoted SQL identifier from the start of s
+// and returns it with the rest of s; "" inside quotes is a literal quote
+func parseIdentifier(s string) (string, string, error) {
+	if strings.HasPrefix(s, `"`) {
+		var sb strings.Builder
+		for i := 1; i < len(s); i++ {
+			if s[i] != '"' {
+				sb.WriteByte(s[i])
+				continue
+			}
+			if i+1 < len(s) && s[i+1] == '"' {
+				sb.WriteByte('"')
+				i++
+				continue
+			}
+			return sb.String(), s[i+1:], nil
+		}
+		return "", "", fmt.Errorf("unterminated quoted identifier")
+	}
+
+	end := 0
+	for end < len(s) && isIdentifierByte(s[end], end == 0) {
+		end++
+	}
+	if end == 0 {
+		return "", "", fmt.Errorf("expected an identifier at %q", s)
+	}
+	return s[:end], s[end:], nil
+}
+
+// isIdentifierByte reports whether c may appear in an unquoted identifier
+func isIdentifierByte(c byte, first bool) bool {
+	switch {
+	case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80:
+		return true
+	case c >= '0' && c <= '9' || c == '$':
+		return !first
+	}
+	return false
+}
+
+// row applies the column rules to a COPY row
+func (a *Anonymizer) row(row string, rules []*columnRule) string {
+	fields := strings.Split(row, "\t")
+	changed := 0
+	for i, rule := range rules {
+		if rule == nil || i >= len(fields) || fields[i] == nullValue {
+			continue
+		}
+		value := a.transform(rule, fields[i])
+		if value != fields[i] {
+			fields[i] = value
+			changed++
+		}
+	}
+	if changed == 0 {
+		return row
+	}
+	a.Stats.Rows++
+	a.Stats.Values += changed
+	return strings.Join(fields, "\t")
+}
+
+// transform applies a rule to a COPY text field and returns the new field
+func (a *Anonymizer) transform(rule *columnRule, field string) string {
+	switch rule.Transform {
+	case TransformNull:
+		return nullValue
+	case TransformSet:
+		return escapeCopy(rule.Value)
+	case TransformTruncate:
+		value := []rune(unescapeCopy(field))
+		if len(value) <= rule.Length {
+			return field
+		}
+		return escapeCopy(string(value[:rule.Length]))
+	case TransformHash:
+		return a.hash(unescapeCopy(field))
+	case TransformFake:
+		return a.fake(rule.name, unescapeCopy(field))
+	}
+	return field
+}
+
+// hash returns the keyed SHA-256 of a value in hex
+func (a *Anonymizer) hash(value string) string {
+	mac := hmac.New(sha256.New, a.key)
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// fake returns a fake value that is the same for equal values
+// Emails stay valid addresses; other values are the column name with a short hash
+func (a *Anonymizer) fake(column, value string) string {
+	if value == "" {
+		return ""
+	}
+	id := a.hash(strings.ToLower(value))[:12]
+	if strings.Contains(value, "@") || strings.Contains(column, "email") {
+		return "user-" + id + "@example.invalid"
+	}
+	return column + "-" + id
+}
+
+// unescapeCopy decodes a field of the COPY text format
+func unescapeCopy(field string) string {
+	if !strings.Contains(field, `\`) {
+		return field
+	}
+	var sb strings.Builder
+	for i := 0; i < len(field); i++ {
+		c := field[i]
+		if c != '\\' || i+1 == len(field) {
+			sb.WriteByte(c)
+			continue
+		}
+		i++
+		switch c = field[i]; c {
+		case 'b':
+			sb.WriteByte('\b')
+		case 'f':
+			sb.WriteByte('\f')
+		case 'n':
+			sb.WriteByte('\n')
+		case 'r':
+			sb.WriteByte('\r')
+		case 't':
+			sb.WriteByte('\t')
+		case 'v':
+			sb.WriteByte('\v')
+		case 'x':
+			// \xHH with one or two hex digits
+			end := i + 1
+			for end < len(field) && end < i+3 && isHex(field[end]) {
+				end++
+			}
+			if end == i+1 {
+				sb.WriteByte(c)
+				continue
+			}
+			v, _ := strconv.ParseUint(field[i+1:end], 16, 8)
+			sb.WriteByte(byte(v))
+			i = end - 1
+		case '0', '1', '2', '3', '4', '5', '6', '7':
+			// \NNN with one to three octal digits
+			v := int(c - '0')
+			end := i + 1
+			for end < len(field) && end < i+3 && field[end] >= '0' && field[end] <= '7' {
+				v = v*8 + int(field[end]-'0')
+				end++
+			}
+			sb.WriteByte(byte(v))
+			i = end - 1
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return sb.String()
+}
+
+// escapeCopy encodes a value for the COPY text format
+func escapeCopy(value string) string {
+	var sb strings.Builder
+	for i := 0; i < len(value); i++ {
+		switch c := value[i]; c {
+		case '\\':
+			sb.WriteString(`\\`)
+		case '\b':
+			sb.WriteString(`\b`)
+		case '\f':
+			sb.WriteString(`\f`)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\r':
+			sb.WriteString(`\r`)
+		case '\t':
+			sb.WriteString(`\t`)
+		case '\v':
+			sb.WriteString(`\v`)
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return sb.String()
+}
+
+// isHex reports whether c is a hexadecimal digit
+func isHex(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F'
+}
@@ -0,0 +1,124 @@
+package anonymize
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestParseCopyHeader(t *testing.T) {
+	tests := []struct {
+		line    string
+		table   string
+		columns string
+	}{
+		{`COPY public.chat (id, title, meta) FROM stdin;`, "chat", "id|title|meta"},
+		{`COPY public."user" (id, "name", email) FROM stdin;`, "user", "id|name|email"},
+		{`COPY "My Schema"."a ""quoted"" table" ("Mixed, Case", "x)y") FROM stdin;`, `a "quoted" table`, `Mixed, Case|x)y`},
+		{`COPY memory (id, content) FROM stdin;`, "memory", "id|content"},
+		{`COPY public.empty  FROM stdin;`, "empty", ""},
+	}
+	for _, tt := range tests {
+		table, columns, err := parseCopyHeader(tt.line)
+		if err != nil {
+			t.Errorf("parseCopyHeader(%s): %v", tt.line, err)
+			continue
+		}
+		if table != tt.table || strings.Join(columns, "|") != tt.columns {
+			t.Errorf("parseCopyHeader(%s) = %q, %q", tt.line, table, columns)
+		}
+	}
+
+	for _, line := range []string{
+		`COPY public."user (id) FROM stdin;`,
+		`COPY public.chat (id, title FROM stdin;`,
+		`COPY public.chat (id) TO stdout;`,
+		`COPY public.chat (id,) FROM stdin;`,
+	} {
+		if _, _, err := parseCopyHeader(line); err == nil {
+			t.Errorf("parseCopyHeader(%s) succeeded", line)
+		}
+	}
+}
+
+func TestWriterAnonymizesQuotedTables(t *testing.T) {
+	a, err := New(&Ruleset{Salt: "salt", Rules: []Rule{
+		{Column: "user.email", Transform: TransformFake},
+		{Column: "memory.content", Transform: TransformTruncate, Length: 3},
+		{Column: "chat.chat", Transform: TransformSet, Value: "{}"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dump := "COPY public.\"user\" (id, \"email\") FROM stdin;\n" +
+		"1\talice@example.com\n" +
+		"\\.\n" +
+		"COPY public.memory (id, content) FROM stdin;\n" +
+		"1\tsecret memory\n" +
+		"2\t\\N\n" +
+		"\\.\n" +
+		"COPY public.chat (id, chat) FROM stdin;\n" +
+		"1\t{\"messages\": []}\n" +
+		"\\.\n"
+	var out bytes.Buffer
+	w := a.Writer(&out)
+	if _, err := w.Write([]byte(dump)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	got := out.String()
+	for _, leaked := range []string{"alice@example.com", "secret memory", "messages"} {
+		if strings.Contains(got, leaked) {
+			t.Errorf("anonymized dump contains %q:\n%s", leaked, got)
+		}
+	}
+	for _, want := range []string{"@example.invalid\n", "1\tsec\n", "2\t\\N\n", "1\t{}\n"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("anonymized dump has no %q:\n%s", want, got)
+		}
+	}
+	if a.Stats.Rows != 3 || a.Stats.Values != 3 {
+		t.Errorf("Stats = %+v", a.Stats)
+	}
+}
+
+func TestWriterFailsOnUnparsableCopy(t *testing.T) {
+	a, err := New(&Ruleset{Rules: []Rule{{Column: "user.email", Transform: TransformNull}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	w := a.Writer(&out)
+	_, err = w.Write([]byte("COPY public.\"user (id, email) FROM stdin;\n1\talice@example.com\n\\.\n"))
+	if err == nil {
+		err = w.Close()
+	}
+	if err == nil {
+		t.Fatal("writer accepted a COPY statement it could not parse")
+	}
+	if strings.Contains(out.String(), "alice@example.com") {
+		t.Error("rows of the unparsable COPY block were written")
+	}
+}
+
+func TestValidateTruncateLength(t *testing.T) {
+	for _, length := range []int{0, -1} {
+		r := &Ruleset{Rules: []Rule{{Column: "memory.content", Transform: TransformTruncate, Length: length}}}
+		if err := r.Validate(); err == nil {
+			t.Errorf("Validate accepted truncate with length %d", length)
+		}
+	}
+	for _, name := range RulesetNames() {
+		r, err := Load(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := r.Validate(); err != nil {
+			t.Errorf("built-in ruleset %s: %v", name, err)
+		}
+	}
+}
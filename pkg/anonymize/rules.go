@@ -0,0 +1,152 @@
+package anonymize
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Transforms applied to column values
+const (
+	TransformFake     = "fake"     // deterministic fake value, e.g. user-1a2b3c4d5e6f@example.invalid
+	TransformHash     = "hash"     // keyed SHA-256 of the value, so equal values stay equal
+	TransformNull     = "null"     // SQL NULL
+	TransformTruncate = "truncate" // first Length characters
+	TransformSet      = "set"      // fixed Value
+)
+
+// Ruleset maps Open WebUI table columns to transforms
+type Ruleset struct {
+	Name  string `json:"name"`
+	Salt  string `json:"salt,omitempty"` // keys hashes and fake values; random per run if empty
+	Rules []Rule `json:"rules"`
+}
+
+// Rule transforms the values of the columns matching Column
+// Column is table.column, both parts may use shell wildcards; the first matching rule wins
+type Rule struct {
+	Column    string `json:"column"`
+	Transform string `json:"transform"`
+	Length    int    `json:"length,omitempty"` // characters kept by truncate
+	Value     string `json:"value,omitempty"`  // value written by set
+}
+
+// rulesets are the built-in rulesets selectable by name
+var rulesets = map[string]*Ruleset{
+	"openwebui": {
+		Name: "openwebui",
+		Rules: []Rule{
+			{Column: "user.email", Transform: TransformFake},
+			{Column: "user.name", Transform: TransformFake},
+			{Column: "user.username", Transform: TransformFake},
+			{Column: "user.profile_image_url", Transform: TransformSet, Value: "/user.png"},
+			{Column: "user.api_key", Transform: TransformHash},
+			{Column: "user.oauth_sub", Transform: TransformHash},
+			{Column: "auth.email", Transform: TransformFake},
+			{Column: "auth.password", Transform: TransformHash},
+			{Column: "api_key.key", Transform: TransformHash},
+			{Column: "chat.title", Transform: TransformFake},
+			{Column: "chat.chat", Transform: TransformSet, Value: "{}"},
+			{Column: "message.content", Transform: TransformSet},
+			{Column: "memory.content", Transform: TransformSet},
+			{Column: "oauth_session.token", Transform: TransformSet, Value: "{}"},
+		},
+	},
+}
+
+// Load returns a built-in ruleset by name or reads a ruleset JSON file
+func Load(nameOrPath string) (*Ruleset, error) {
+	if r, ok := rulesets[nameOrPath]; ok {
+		copied := *r
+		return &copied, nil
+	}
+
+	data, err := os.ReadFile(nameOrPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("unknown anonymization ruleset %q (built-in: %s, or a JSON file)", nameOrPath, strings.Join(RulesetNames(), ", "))
+		}
+		return nil, fmt.Errorf("failed to read anonymization ruleset: %w", err)
+	}
+
+	var r Ruleset
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("failed to parse anonymization ruleset %s: %w", nameOrPath, err)
+	}
+	if r.Name == "" {
+		r.Name = strings.TrimSuffix(filepath.Base(nameOrPath), filepath.Ext(nameOrPath))
+	}
+	if err := r.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid anonymization ruleset %s: %w", nameOrPath, err)
+	}
+	return &r, nil
+}
+
+// RulesetNames returns the built-in ruleset names in alphabetical order
+func RulesetNames() []string {
+	names := make([]string, 0, len(rulesets))
+	for name := range rulesets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Validate checks the column patterns and transforms of every rule
+func (r *Ruleset) Validate() error {
+	if len(r.Rules) == 0 {
+		return fmt.Errorf("no rules")
+	}
+	for _, rule := range r.Rules {
+		table, column, ok := strings.Cut(rule.Column, ".")
+		if !ok || table == "" || column == "" || strings.Contains(column, ".") {
+			return fmt.Errorf("column %q must be table.column", rule.Column)
+		}
+		if _, err := path.Match(rule.Column, ""); err != nil {
+			return fmt.Errorf("invalid column pattern %q: %w", rule.Column, err)
+		}
+		switch rule.Transform {
+		case TransformFake, TransformHash, TransformNull, TransformSet:
+		case TransformTruncate:
+			if rule.Length <= 0 {
+				return fmt.Errorf("truncate length of %s must be positive; use set for an empty value", rule.Column)
+			}
+		default:
+			return fmt.Errorf("unknown transform %q for %s (use fake, hash, null, truncate or set)", rule.Transform, rule.Column)
+		}
+		if rule.Length != 0 && rule.Transform != TransformTruncate {
+			return fmt.Errorf("length of %s only applies to truncate", rule.Column)
+		}
+		if rule.Value != "" && rule.Transform != TransformSet {
+			return fmt.Errorf("value of %s only applies to set", rule.Column)
+		}
+	}
+	return nil
+}
+
+// Fingerprint returns a short hash identifying the rules, independent of the name and salt
+func (r *Ruleset) Fingerprint() string {
+	data, _ := json.Marshal(r.Rules)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
+
+// match returns the first rule for a table column, or nil
+func (r *Ruleset) match(table, column string) *Rule {
+	for i := range r.Rules {
+		tablePattern, columnPattern, _ := strings.Cut(r.Rules[i].Column, ".")
+		if ok, _ := path.Match(tablePattern, table); !ok {
+			continue
+		}
+		if ok, _ := path.Match(columnPattern, column); ok {
+			return &r.Rules[i]
+		}
+	}
+	return nil
+}
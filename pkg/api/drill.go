@@ -0,0 +1,163 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+	"github.com/vosiander/open-webui-backup/pkg/drill"
+	"github.com/vosiander/open-webui-backup/pkg/signing"
+)
+
+// DrillScheduler runs restore drills on demand and on a fixed interval
+type DrillScheduler struct {
+	server  *Server
+	mu      sync.Mutex
+	running bool
+	last    *drill.Report
+}
+
+// NewDrillScheduler creates a new drill scheduler for the server
+func NewDrillScheduler(server *Server) *DrillScheduler {
+	return &DrillScheduler{server: server}
+}
+
+// Start launches the periodic drill job if OWUI_DRILL_INTERVAL is set
+func (d *DrillScheduler) Start() {
+	value := os.Getenv(drill.EnvDrillInterval)
+	if value == "" {
+		return
+	}
+
+	interval, err := time.ParseDuration(value)
+	if err != nil || interval <= 0 {
+		logrus.Warnf("Invalid %s %q, scheduled drills disabled", drill.EnvDrillInterval, value)
+		return
+	}
+
+	logrus.Infof("Scheduled restore drills every %s", interval)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if _, err := d.Trigger(); err != nil {
+				logrus.WithError(err).Warn("Scheduled restore drill not started")
+			}
+		}
+	}()
+}
+
+// Trigger starts a drill of the newest backup as an async operation
+func (d *DrillScheduler) Trigger() (string, error) {
+	d.mu.Lock()
+	if d.running {
+		d.mu.Unlock()
+		return "", fmt.Errorf("a restore drill is already running")
+	}
+	d.running = true
+	d.mu.Unlock()
+
+	backupsDir := d.server.config.BackupsDir
+
+	return d.server.opMgr.StartOperation("drill", func(progress ProgressCallback) error {
+		defer func() {
+			d.mu.Lock()
+			d.running = false
+			d.mu.Unlock()
+		}()
+
+		backupFile, err := drill.LatestBackup(backupsDir)
+		if err != nil {
+			return err
+		}
+
+		opts, err := d.options()
+		if err != nil {
+			return err
+		}
+
+		report, runErr := drill.Run(backupFile, opts, func(percent int, message string) {
+			progress(percent, message)
+		})
+		report.Log()
+
+		if _, err := drill.WriteReport(report, filepath.Join(backupsDir, "drills")); err != nil {
+			logrus.WithError(err).Warn("Failed to write drill report")
+		}
+
+		d.mu.Lock()
+		d.last = report
+		d.mu.Unlock()
+
+		if runErr != nil {
+			return runErr
+		}
+		if !report.Passed {
+			return fmt.Errorf("drill failed: scratch instance does not match %s", report.Backup)
+		}
+		return nil
+	})
+}
+
+// Last returns the most recent drill report, or nil
+func (d *DrillScheduler) Last() *drill.Report {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.last
+}
+
+// options builds drill options from the server environment
+func (d *DrillScheduler) options() (*drill.Options, error) {
+	opts := &drill.Options{
+		ProductionURL:    d.server.config.OpenWebUIURL,
+		RequireSignature: signing.IsSignatureRequired(false),
+	}
+	opts.LoadFromEnv()
+
+	if identityPath := os.Getenv("AGE_IDENTITY"); identityPath != "" {
+		content, err := os.ReadFile(identityPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read identity file %s: %w", identityPath, err)
+		}
+		opts.Identities = []string{string(content)}
+	}
+
+	trusted, err := signing.GetTrustedKeysFromEnvOrFlag(nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load trusted keys: %w", err)
+	}
+	opts.TrustedKeys = trusted
+
+	return opts, opts.Validate()
+}
+
+// handleStartDrill starts a restore drill of the newest backup
+func (s *Server) handleStartDrill(c echo.Context) error {
+	operationID, err := s.drills.Trigger()
+	if err != nil {
+		return c.JSON(http.StatusConflict, map[string]string{
+			"error": fmt.Sprintf("Failed to start drill: %v", err),
+		})
+	}
+
+	return c.JSON(http.StatusOK, OperationStartResponse{
+		OperationID: operationID,
+	})
+}
+
+// handleGetLatestDrill returns the most recent drill report
+func (s *Server) handleGetLatestDrill(c echo.Context) error {
+	report := s.drills.Last()
+	if report == nil {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"error": "No drill has run yet",
+		})
+	}
+
+	return c.JSON(http.StatusOK, report)
+}
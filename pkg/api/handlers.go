@@ -5,14 +5,20 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/sirupsen/logrus"
+	"github.com/vosiander/open-webui-backup/pkg/archive"
 	"github.com/vosiander/open-webui-backup/pkg/backup"
 	"github.com/vosiander/open-webui-backup/pkg/encryption"
+	"github.com/vosiander/open-webui-backup/pkg/metrics"
+	"github.com/vosiander/open-webui-backup/pkg/notify"
 	"github.com/vosiander/open-webui-backup/pkg/openwebui"
 	"github.com/vosiander/open-webui-backup/pkg/restore"
+	"github.com/vosiander/open-webui-backup/pkg/signing"
 )
 
 // handleGetConfig returns the current configuration
@@ -95,6 +101,14 @@ func (s *Server) handleStartBackup(c echo.Context) error {
 		outputFile += ".age"
 	}
 
+	// Load the signing key (OWUI_SIGNING_KEY), if configured
+	signKey, err := signing.GetSigningKeyFromEnvOrFlag("")
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": fmt.Sprintf("Failed to load signing key: %v", err),
+		})
+	}
+
 	// Create OpenWebUI client
 	client := openwebui.NewClient(s.config.OpenWebUIURL, s.config.OpenWebUIAPIKey)
 
@@ -130,6 +144,16 @@ func (s *Server) handleStartBackup(c echo.Context) error {
 			// (encryption happens inline based on .age extension)
 		}
 
+		// Sign the backup if a signing key is configured
+		if signKey != nil {
+			progress(98, "Signing backup...")
+			if _, err := signing.SignArchive(signKey, outputFile, outputFile); err != nil {
+				return fmt.Errorf("failed to sign backup: %w", err)
+			}
+		}
+
+		// Record the size of the file that is kept, after encryption and signing
+		metrics.AddBytesWritten("backup", outputFile)
 		return nil
 	})
 
@@ -173,20 +197,23 @@ func (s *Server) handleStartRestore(c echo.Context) error {
 		})
 	}
 
+	// Check the detached signature before starting the restore
+	sigResult, err := checkBackupSignature(inputFile)
+	if err != nil {
+		return c.JSON(http.StatusForbidden, map[string]string{
+			"error": fmt.Sprintf("Refusing to restore: %v", err),
+		})
+	}
+
 	// Create OpenWebUI client
 	client := openwebui.NewClient(s.config.OpenWebUIURL, s.config.OpenWebUIAPIKey)
 
-	// Convert request data types to restore options
-	options := &restore.SelectiveRestoreOptions{
-		Knowledge: req.DataTypes.Knowledge,
-		Models:    req.DataTypes.Models,
-		Tools:     req.DataTypes.Tools,
-		Prompts:   req.DataTypes.Prompts,
-		Files:     req.DataTypes.Files,
-		Chats:     req.DataTypes.Chats,
-		Users:     req.DataTypes.Users,
-		Groups:    req.DataTypes.Groups,
-		Feedbacks: req.DataTypes.Feedbacks,
+	// Convert request data types and item selectors to restore options
+	options, err := restoreOptions(req)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
 	}
 
 	// Start the restore operation asynchronously
@@ -204,25 +231,9 @@ func (s *Server) handleStartRestore(c echo.Context) error {
 			restoreProgress(5, "Decrypting backup file...")
 
 			// Get identity content
-			var identityContent string
-			if req.DecryptIdentity != "" {
-				// Identity content provided directly from web UI
-				identityContent = req.DecryptIdentity
-				logrus.Debug("Using identity content from request")
-			} else {
-				// Fall back to identity file from environment variable
-				identityPath := os.Getenv("AGE_IDENTITY")
-				if identityPath == "" {
-					return fmt.Errorf("encrypted backup requires age identity (set AGE_IDENTITY or provide decryptIdentity)")
-				}
-
-				// Read the identity file
-				content, err := os.ReadFile(identityPath)
-				if err != nil {
-					return fmt.Errorf("failed to read identity file %s: %w", identityPath, err)
-				}
-				identityContent = string(content)
-				logrus.Debugf("Using identity from file: %s", identityPath)
+			identityContent, err := resolveIdentity(req.DecryptIdentity)
+			if err != nil {
+				return err
 			}
 
 			// Create temporary file for decrypted content
@@ -250,6 +261,13 @@ func (s *Server) handleStartRestore(c echo.Context) error {
 			restoreProgress(10, "Decryption complete, starting restore...")
 		}
 
+		// Make sure the contents match the signed manifest
+		if sigResult.Manifest != nil {
+			if err := signing.VerifyEntries(sigResult.Manifest, actualInputFile); err != nil {
+				return fmt.Errorf("backup contents do not match signed manifest: %w", err)
+			}
+		}
+
 		// Perform the restore
 		return restore.RestoreSelective(client, actualInputFile, options, req.Overwrite, restoreProgress)
 	})
@@ -265,6 +283,170 @@ func (s *Server) handleStartRestore(c echo.Context) error {
 	})
 }
 
+// handleRestorePlan compares a backup with the live instance without restoring anything
+func (s *Server) handleRestorePlan(c echo.Context) error {
+	var req RestoreRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request body",
+		})
+	}
+
+	// Validate request
+	if req.InputFilename == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Input filename is required",
+		})
+	}
+
+	options, err := restoreOptions(req)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+	}
+
+	zipPath, cleanup, status, err := s.openBackup(req.InputFilename, req.DecryptIdentity)
+	if err != nil {
+		return c.JSON(status, map[string]string{
+			"error": err.Error(),
+		})
+	}
+	defer cleanup()
+
+	client := openwebui.NewClient(s.config.OpenWebUIURL, s.config.OpenWebUIAPIKey)
+
+	plan, err := restore.BuildPlan(client, zipPath, options, req.Overwrite)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": fmt.Sprintf("Failed to build restore plan: %v", err),
+		})
+	}
+	plan.Backup = req.InputFilename
+
+	return c.JSON(http.StatusOK, plan)
+}
+
+// handleBackupContents lists the restorable items stored in a backup
+func (s *Server) handleBackupContents(c echo.Context) error {
+	var req BackupContentsRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request body",
+		})
+	}
+
+	if req.Filename == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Filename is required",
+		})
+	}
+
+	zipPath, cleanup, status, err := s.openBackup(req.Filename, req.DecryptIdentity)
+	if err != nil {
+		return c.JSON(status, map[string]string{
+			"error": err.Error(),
+		})
+	}
+	defer cleanup()
+
+	a, err := archive.Load(zipPath)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": fmt.Sprintf("Failed to read backup: %v", err),
+		})
+	}
+
+	response := BackupContentsResponse{
+		Filename: req.Filename,
+		Metadata: a.Metadata,
+		Counts:   a.Counts(),
+		Items:    make(map[string][]BackupItem),
+	}
+	for _, entityType := range archive.Types {
+		for _, entity := range a.Entities[entityType] {
+			response.Items[entityType] = append(response.Items[entityType], BackupItem{
+				ID:          entity.ID,
+				Name:        entity.Name,
+				Selector:    entityType + ":=" + entity.ID,
+				CreatedAt:   entity.CreatedAt,
+				UpdatedAt:   entity.UpdatedAt,
+				Attachments: len(entity.Attachments),
+			})
+		}
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+// handleBackupEntity returns a single entity's JSON, or the content of one of its attachments
+func (s *Server) handleBackupEntity(c echo.Context) error {
+	var req EntityPreviewRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request body",
+		})
+	}
+
+	if req.Filename == "" || req.Type == "" || req.ID == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Filename, type and id are required",
+		})
+	}
+
+	if !archive.IsType(req.Type) {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": fmt.Sprintf("Unknown entity type: %s", req.Type),
+		})
+	}
+
+	zipPath, cleanup, status, err := s.openBackup(req.Filename, req.DecryptIdentity)
+	if err != nil {
+		return c.JSON(status, map[string]string{
+			"error": err.Error(),
+		})
+	}
+	defer cleanup()
+
+	a, err := archive.Load(zipPath)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": fmt.Sprintf("Failed to read backup: %v", err),
+		})
+	}
+
+	entity := a.Find(req.Type, req.ID)
+	if entity == nil {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"error": "Entity not found in backup",
+		})
+	}
+
+	if req.Attachment == "" {
+		return c.JSON(http.StatusOK, EntityPreviewResponse{
+			Entity: entity,
+			Data:   entity.Data,
+		})
+	}
+
+	// Only attachments of the requested entity can be read
+	if !slices.Contains(entity.Attachments, req.Attachment) {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"error": "Attachment not found for entity",
+		})
+	}
+
+	content, err := archive.ReadEntry(zipPath, req.Attachment)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": fmt.Sprintf("Failed to read attachment: %v", err),
+		})
+	}
+
+	c.Response().Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=%q", filepath.Base(req.Attachment)))
+	return c.Blob(http.StatusOK, http.DetectContentType(content), content)
+}
+
 // handleGetStatus returns the status of an operation
 func (s *Server) handleGetStatus(c echo.Context) error {
 	operationID := c.Param("id")
@@ -342,6 +524,11 @@ func (s *Server) handleDeleteBackup(c echo.Context) error {
 		})
 	}
 
+	// Remove the detached signature along with the backup
+	if err := os.Remove(signing.SignaturePath(filePath)); err != nil && !os.IsNotExist(err) {
+		logrus.WithError(err).Warnf("Failed to delete signature file for: %s", filename)
+	}
+
 	logrus.Infof("Deleted backup file: %s", filename)
 
 	return c.JSON(http.StatusOK, map[string]string{
@@ -386,11 +573,37 @@ func (s *Server) handleVerifyBackup(c echo.Context) error {
 		})
 	}
 
+	start := time.Now()
+
+	// Check the detached signature first
+	sigResult, err := checkBackupSignature(filePath)
+	if err != nil {
+		logrus.WithError(err).Warnf("Signature verification failed for file: %s", req.Filename)
+		s.recordVerify(start, req.Filename, err)
+		return c.JSON(http.StatusOK, map[string]string{
+			"success":   "false",
+			"message":   fmt.Sprintf("Verification failed: %v", err),
+			"signature": string(sigResult.Status),
+		})
+	}
+
 	// Check if file is encrypted
 	if !encryption.IsEncrypted(filePath) {
+		if sigResult.Manifest != nil {
+			if err := signing.VerifyEntries(sigResult.Manifest, filePath); err != nil {
+				s.recordVerify(start, req.Filename, err)
+				return c.JSON(http.StatusOK, map[string]string{
+					"success":   "false",
+					"message":   fmt.Sprintf("Verification failed: %v", err),
+					"signature": string(signing.StatusInvalid),
+				})
+			}
+		}
+		s.recordVerify(start, req.Filename, nil)
 		return c.JSON(http.StatusOK, map[string]string{
-			"success": "true",
-			"message": "File is not encrypted - verification not needed",
+			"success":   "true",
+			"message":   "File is not encrypted - verification not needed",
+			"signature": string(sigResult.Status),
 		})
 	}
 
@@ -427,23 +640,47 @@ func (s *Server) handleVerifyBackup(c echo.Context) error {
 	// Attempt to decrypt the file
 	if err := encryption.DecryptFileWithIdentities(filePath, tempFile, []string{identityContent}); err != nil {
 		logrus.WithError(err).Warnf("Verification failed for file: %s", req.Filename)
+		s.recordVerify(start, req.Filename, err)
 		return c.JSON(http.StatusOK, map[string]string{
 			"success": "false",
 			"message": fmt.Sprintf("Verification failed: %v", err),
 		})
 	}
 
+	if sigResult.Manifest != nil {
+		if err := signing.VerifyEntries(sigResult.Manifest, tempFile); err != nil {
+			s.recordVerify(start, req.Filename, err)
+			return c.JSON(http.StatusOK, map[string]string{
+				"success":   "false",
+				"message":   fmt.Sprintf("Verification failed: %v", err),
+				"signature": string(signing.StatusInvalid),
+			})
+		}
+	}
+
 	// Clean up temp file
 	os.Remove(tempFile)
 
 	logrus.Infof("Backup verification successful: %s", req.Filename)
+	s.recordVerify(start, req.Filename, nil)
 
 	return c.JSON(http.StatusOK, map[string]string{
-		"success": "true",
-		"message": "Backup verified successfully - decryption key is correct",
+		"success":   "true",
+		"message":   "Backup verified successfully - decryption key is correct",
+		"signature": string(sigResult.Status),
 	})
 }
 
+// recordVerify records a verification result in metrics and notifications
+func (s *Server) recordVerify(start time.Time, filename string, err error) {
+	metrics.ObserveOperation("verify", start, err)
+	if s.opMgr.notifier != nil {
+		event := notify.NewEvent("verify", "server", start, err)
+		event.File = filename
+		go s.opMgr.notifier.Notify(event)
+	}
+}
+
 // handleUploadBackup handles file upload for backup files
 func (s *Server) handleUploadBackup(c echo.Context) error {
 	// Get the uploaded file
@@ -456,9 +693,9 @@ func (s *Server) handleUploadBackup(c echo.Context) error {
 
 	// Validate filename
 	filename := filepath.Base(file.Filename)
-	if !strings.HasSuffix(filename, ".age") && !strings.HasSuffix(filename, ".zip") {
+	if !strings.HasSuffix(filename, ".age") && !strings.HasSuffix(filename, ".zip") && !strings.HasSuffix(filename, signing.SignatureExtension) {
 		return c.JSON(http.StatusBadRequest, map[string]string{
-			"error": "Only .age and .zip files are allowed",
+			"error": "Only .age, .zip and .sig files are allowed",
 		})
 	}
 
@@ -568,6 +805,7 @@ type BackupFileInfo struct {
 	Size        int64  `json:"size"`
 	ModTime     string `json:"modTime"`
 	DownloadURL string `json:"downloadUrl"`
+	Signed      bool   `json:"signed"`
 }
 
 // listBackupFilesWithMetadata returns a list of backup files with their metadata
@@ -596,9 +834,118 @@ func listBackupFilesWithMetadata(dir string) ([]BackupFileInfo, error) {
 				Size:        info.Size(),
 				ModTime:     info.ModTime().Format("2006-01-02T15:04:05Z07:00"),
 				DownloadURL: fmt.Sprintf("/api/backups/%s", entry.Name()),
+				Signed:      fileExists(signing.SignaturePath(filepath.Join(dir, entry.Name()))),
 			})
 		}
 	}
 
 	return backups, nil
 }
+
+// checkBackupSignature verifies a backup's detached signature against OWUI_TRUSTED_KEYS
+// and applies the OWUI_REQUIRE_SIGNATURE policy
+func checkBackupSignature(path string) (*signing.VerifyResult, error) {
+	trusted, err := signing.GetTrustedKeysFromEnvOrFlag(nil)
+	if err != nil {
+		return &signing.VerifyResult{Status: signing.StatusInvalid}, fmt.Errorf("failed to load trusted keys: %w", err)
+	}
+
+	result, err := signing.VerifyArchive(path, trusted)
+	if err != nil {
+		return &signing.VerifyResult{Status: signing.StatusInvalid}, err
+	}
+
+	return result, signing.Enforce(result, signing.IsSignatureRequired(false))
+}
+
+// resolveIdentity returns the identity supplied by the web UI, falling back to the AGE_IDENTITY file
+func resolveIdentity(requestIdentity string) (string, error) {
+	if requestIdentity != "" {
+		// Identity content provided directly from web UI
+		logrus.Debug("Using identity content from request")
+		return requestIdentity, nil
+	}
+
+	// Fall back to identity file from environment variable
+	identityPath := os.Getenv("AGE_IDENTITY")
+	if identityPath == "" {
+		return "", fmt.Errorf("encrypted backup requires age identity (set AGE_IDENTITY or provide decryptIdentity)")
+	}
+
+	content, err := os.ReadFile(identityPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read identity file %s: %w", identityPath, err)
+	}
+	logrus.Debugf("Using identity from file: %s", identityPath)
+
+	return string(content), nil
+}
+
+// openBackup validates a backup filename, checks its signature and decrypts it to a temporary ZIP
+// On failure the HTTP status to respond with is returned alongside the error
+func (s *Server) openBackup(filename, requestIdentity string) (string, func(), int, error) {
+	// Validate filename (prevent path traversal)
+	if strings.Contains(filename, "..") || strings.Contains(filename, "/") {
+		return "", nil, http.StatusBadRequest, fmt.Errorf("Invalid filename")
+	}
+
+	inputFile := filepath.Join(s.config.BackupsDir, filename)
+	if _, err := os.Stat(inputFile); os.IsNotExist(err) {
+		return "", nil, http.StatusNotFound, fmt.Errorf("Backup file not found")
+	}
+
+	sigResult, err := checkBackupSignature(inputFile)
+	if err != nil {
+		return "", nil, http.StatusForbidden, fmt.Errorf("Refusing to open backup: %w", err)
+	}
+
+	var identities []string
+	if encryption.IsEncrypted(inputFile) {
+		identityContent, err := resolveIdentity(requestIdentity)
+		if err != nil {
+			return "", nil, http.StatusBadRequest, err
+		}
+		identities = []string{identityContent}
+	}
+
+	zipPath, cleanup, err := archive.Decrypt(inputFile, identities)
+	if err != nil {
+		return "", nil, http.StatusBadRequest, fmt.Errorf("Failed to decrypt backup: %w", err)
+	}
+
+	if sigResult.Manifest != nil {
+		if err := signing.VerifyEntries(sigResult.Manifest, zipPath); err != nil {
+			cleanup()
+			return "", nil, http.StatusForbidden, fmt.Errorf("Backup contents do not match signed manifest: %w", err)
+		}
+	}
+
+	return zipPath, cleanup, http.StatusOK, nil
+}
+
+// restoreOptions converts the data types and item selectors of a restore request
+func restoreOptions(req RestoreRequest) (*restore.SelectiveRestoreOptions, error) {
+	items, err := restore.ParseItemSelectors(req.Items)
+	if err != nil {
+		return nil, err
+	}
+
+	return &restore.SelectiveRestoreOptions{
+		Knowledge: req.DataTypes.Knowledge,
+		Models:    req.DataTypes.Models,
+		Tools:     req.DataTypes.Tools,
+		Prompts:   req.DataTypes.Prompts,
+		Files:     req.DataTypes.Files,
+		Chats:     req.DataTypes.Chats,
+		Users:     req.DataTypes.Users,
+		Groups:    req.DataTypes.Groups,
+		Feedbacks: req.DataTypes.Feedbacks,
+		Items:     items,
+	}, nil
+}
+
+// fileExists reports whether a regular file exists at path
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
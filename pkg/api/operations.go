@@ -6,6 +6,8 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/vosiander/open-webui-backup/pkg/metrics"
+	"github.com/vosiander/open-webui-backup/pkg/notify"
 )
 
 // ProgressCallback is a function that receives progress updates
@@ -16,6 +18,7 @@ type OperationManager struct {
 	operations map[string]*OperationStatus
 	mu         sync.RWMutex
 	hub        *Hub
+	notifier   *notify.Notifier
 }
 
 // NewOperationManager creates a new operation manager
@@ -55,6 +58,8 @@ func (om *OperationManager) StartOperation(opType string, fn func(progress Progr
 
 		// Execute the operation
 		err := fn(progressCallback)
+		metrics.ObserveOperation(opType, status.StartTime, err)
+		defer om.notify(opType, status, err)
 
 		// Update final status
 		om.mu.Lock()
@@ -78,6 +83,19 @@ func (om *OperationManager) StartOperation(opType string, fn func(progress Progr
 	return id, nil
 }
 
+// notify sends the outcome of an operation to the configured notification sinks
+func (om *OperationManager) notify(opType string, status *OperationStatus, err error) {
+	if om.notifier == nil {
+		return
+	}
+	event := notify.NewEvent(opType, "server", status.StartTime, err)
+	om.mu.RLock()
+	event.File = status.OutputFile
+	event.Details = map[string]string{"operation_id": status.ID}
+	om.mu.RUnlock()
+	om.notifier.Notify(event)
+}
+
 // updateProgress updates the progress of an operation
 func (om *OperationManager) updateProgress(id string, percent int, message string) {
 	om.mu.Lock()
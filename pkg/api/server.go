@@ -4,11 +4,14 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	"github.com/sirupsen/logrus"
 	"github.com/vosiander/open-webui-backup/pkg/config"
+	"github.com/vosiander/open-webui-backup/pkg/metrics"
+	"github.com/vosiander/open-webui-backup/pkg/notify"
 	"github.com/vosiander/open-webui-backup/pkg/web"
 )
 
@@ -18,6 +21,7 @@ type Server struct {
 	echo   *echo.Echo
 	hub    *Hub
 	opMgr  *OperationManager
+	drills *DrillScheduler
 }
 
 // NewServer creates a new HTTP server instance
@@ -31,12 +35,22 @@ func NewServer(cfg *config.Config) *Server {
 	// Create operation manager
 	opMgr := NewOperationManager(hub)
 
+	// Notification sinks (OWUI_NOTIFY_CONFIG), if configured
+	notifier, err := notify.FromEnv()
+	if err != nil {
+		logrus.WithError(err).Warn("Notifications disabled")
+	} else if notifier != nil {
+		logrus.Infof("Notifications enabled: %s", strings.Join(notifier.SinkNames(), ", "))
+	}
+	opMgr.notifier = notifier
+
 	server := &Server{
 		config: cfg,
 		echo:   e,
 		hub:    hub,
 		opMgr:  opMgr,
 	}
+	server.drills = NewDrillScheduler(server)
 
 	// Setup routes and middleware
 	server.setupRoutes()
@@ -58,18 +72,26 @@ func (s *Server) setupRoutes() {
 		api.PUT("/config", s.handleUpdateConfig)
 		api.POST("/backup", s.handleStartBackup)
 		api.POST("/restore", s.handleStartRestore)
+		api.POST("/restore/plan", s.handleRestorePlan)
 		api.GET("/status/:id", s.handleGetStatus)
 		api.GET("/backups", s.handleListBackups)
 		api.POST("/backups/upload", s.handleUploadBackup)
 		api.POST("/backups/verify", s.handleVerifyBackup)
+		api.POST("/backups/contents", s.handleBackupContents)
+		api.POST("/backups/entity", s.handleBackupEntity)
 		api.GET("/backups/:filename", s.handleDownloadBackup)
 		api.DELETE("/backups/:filename", s.handleDeleteBackup)
 		api.POST("/identity/generate", s.handleGenerateIdentity)
+		api.POST("/drill", s.handleStartDrill)
+		api.GET("/drill/latest", s.handleGetLatestDrill)
 	}
 
 	// WebSocket route
 	s.echo.GET("/ws", s.hub.HandleWebSocket)
 
+	// Prometheus metrics
+	s.echo.GET("/metrics", echo.WrapHandler(metrics.Default.Handler()))
+
 	// Serve embedded frontend
 	s.serveEmbeddedFrontend()
 }
@@ -105,6 +127,9 @@ func (s *Server) Start() error {
 	// Start WebSocket hub in background
 	go s.hub.Run()
 
+	// Start scheduled restore drills, if configured
+	s.drills.Start()
+
 	addr := fmt.Sprintf(":%d", s.config.ServerPort)
 	logrus.Infof("Starting server on http://localhost%s", addr)
 	logrus.Infof("Open WebUI URL: %s", s.config.OpenWebUIURL)
@@ -122,6 +147,11 @@ func (s *Server) customLogger() echo.MiddlewareFunc {
 
 			err := next(c)
 
+			// Scrapes would flood the log
+			if req.URL.Path == "/metrics" {
+				return err
+			}
+
 			// Log in simple text format matching logrus
 			logrus.Infof("%s %s %d %s",
 				req.Method,
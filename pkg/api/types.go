@@ -1,7 +1,11 @@
 package api
 
 import (
+	"encoding/json"
 	"time"
+
+	"github.com/vosiander/open-webui-backup/pkg/archive"
+	"github.com/vosiander/open-webui-backup/pkg/openwebui"
 )
 
 // Config represents the application configuration
@@ -38,6 +42,46 @@ type RestoreRequest struct {
 	DecryptIdentity string            `json:"decryptIdentity"`
 	DataTypes       DataTypeSelection `json:"dataTypes"`
 	Overwrite       bool              `json:"overwrite"`
+	Items           []string          `json:"items,omitempty"`
+}
+
+// BackupContentsRequest asks for the list of items stored in a backup
+type BackupContentsRequest struct {
+	Filename        string `json:"filename"`
+	DecryptIdentity string `json:"decryptIdentity"`
+}
+
+// BackupItem is a single restorable item in a backup
+type BackupItem struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Selector    string `json:"selector"`
+	CreatedAt   int64  `json:"createdAt,omitempty"`
+	UpdatedAt   int64  `json:"updatedAt,omitempty"`
+	Attachments int    `json:"attachments,omitempty"`
+}
+
+// BackupContentsResponse lists the items of a backup grouped by type
+type BackupContentsResponse struct {
+	Filename string                    `json:"filename"`
+	Metadata *openwebui.BackupMetadata `json:"metadata,omitempty"`
+	Counts   map[string]int            `json:"counts"`
+	Items    map[string][]BackupItem   `json:"items"`
+}
+
+// EntityPreviewRequest asks for a single entity or attachment of a backup
+type EntityPreviewRequest struct {
+	Filename        string `json:"filename"`
+	DecryptIdentity string `json:"decryptIdentity"`
+	Type            string `json:"type"`
+	ID              string `json:"id"`
+	Attachment      string `json:"attachment,omitempty"`
+}
+
+// EntityPreviewResponse holds an entity's JSON and its attachment paths
+type EntityPreviewResponse struct {
+	Entity *archive.Entity `json:"entity"`
+	Data   json.RawMessage `json:"data"`
 }
 
 // OperationStartResponse represents the response when starting an operation
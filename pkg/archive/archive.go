@@ -0,0 +1,311 @@
+package archive
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/vosiander/open-webui-backup/pkg/openwebui"
+)
+
+// Entity types as recorded in owui.json contained_types
+const (
+	TypeKnowledge = "knowledge"
+	TypeModel     = "model"
+	TypeTool      = "tool"
+	TypePrompt    = "prompt"
+	TypeFile      = "file"
+	TypeChat      = "chat"
+	TypeUser      = "user"
+	TypeGroup     = "group"
+	TypeFeedback  = "feedback"
+)
+
+// Types lists all entity types in restore order
+var Types = []string{
+	TypeUser,
+	TypeGroup,
+	TypeKnowledge,
+	TypeModel,
+	TypeTool,
+	TypePrompt,
+	TypeFile,
+	TypeChat,
+	TypeFeedback,
+}
+
+// layout describes where an entity type lives in the unified backup ZIP
+type layout struct {
+	Dir  string
+	File string
+}
+
+// layouts maps entity types to their directory and JSON filename
+var layouts = map[string]layout{
+	TypeKnowledge: {Dir: "knowledge-bases", File: "knowledge_base.json"},
+	TypeModel:     {Dir: "models", File: "model.json"},
+	TypeTool:      {Dir: "tools", File: "tool.json"},
+	TypePrompt:    {Dir: "prompts", File: "prompt.json"},
+	TypeFile:      {Dir: "files", File: "file.json"},
+	TypeChat:      {Dir: "chats", File: "chat.json"},
+	TypeUser:      {Dir: "users", File: "user.json"},
+	TypeGroup:     {Dir: "groups", File: "group.json"},
+	TypeFeedback:  {Dir: "feedbacks", File: "feedback.json"},
+}
+
+// Entity is a single item stored in a backup archive
+type Entity struct {
+	Type        string          `json:"type"`
+	ID          string          `json:"id"`
+	Key         string          `json:"key"`
+	Name        string          `json:"name"`
+	Path        string          `json:"path"`
+	CreatedAt   int64           `json:"createdAt,omitempty"`
+	UpdatedAt   int64           `json:"updatedAt,omitempty"`
+	Attachments []string        `json:"attachments,omitempty"`
+	Data        json.RawMessage `json:"-"`
+}
+
+// Archive is the parsed content of a unified backup ZIP
+type Archive struct {
+	Path     string                    `json:"path"`
+	Metadata *openwebui.BackupMetadata `json:"metadata,omitempty"`
+	Entities map[string][]*Entity      `json:"entities"`
+	Other    []string                  `json:"other,omitempty"`
+}
+
+// Load reads every entity from an unencrypted unified backup ZIP
+func Load(zipPath string) (*Archive, error) {
+	r, err := zip.OpenReader(zipPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open ZIP file: %w", err)
+	}
+	defer r.Close()
+
+	return Read(&r.Reader, zipPath)
+}
+
+// Read parses entities from an already opened ZIP reader
+func Read(r *zip.Reader, path string) (*Archive, error) {
+	a := &Archive{
+		Path:     path,
+		Entities: make(map[string][]*Entity),
+	}
+
+	// Attachments are collected per entity directory and assigned afterwards
+	entityDirs := make(map[string]*Entity)
+	var rest []string
+
+	for _, f := range r.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+
+		if f.Name == "owui.json" {
+			data, err := readFile(f)
+			if err != nil {
+				return nil, err
+			}
+			var metadata openwebui.BackupMetadata
+			if err := json.Unmarshal(data, &metadata); err != nil {
+				return nil, fmt.Errorf("failed to parse owui.json: %w", err)
+			}
+			a.Metadata = &metadata
+			continue
+		}
+
+		entityType, ok := entityTypeForPath(f.Name)
+		if !ok {
+			rest = append(rest, f.Name)
+			continue
+		}
+
+		data, err := readFile(f)
+		if err != nil {
+			return nil, err
+		}
+
+		entity, err := NewEntity(entityType, data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", f.Name, err)
+		}
+		entity.Path = f.Name
+
+		a.Entities[entityType] = append(a.Entities[entityType], entity)
+		entityDirs[dirOf(f.Name)] = entity
+	}
+
+	for _, name := range rest {
+		if entity := findOwner(entityDirs, name); entity != nil {
+			entity.Attachments = append(entity.Attachments, name)
+			continue
+		}
+		a.Other = append(a.Other, name)
+	}
+
+	for _, entities := range a.Entities {
+		sort.Slice(entities, func(i, j int) bool {
+			return entities[i].ID < entities[j].ID
+		})
+	}
+
+	return a, nil
+}
+
+// NewEntity builds an entity of the given type from its raw JSON
+func NewEntity(entityType string, data []byte) (*Entity, error) {
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+
+	return &Entity{
+		Type:      entityType,
+		ID:        idOf(entityType, fields),
+		Key:       KeyOf(entityType, fields),
+		Name:      nameOf(fields),
+		CreatedAt: timestampOf(fields, "created_at"),
+		UpdatedAt: timestampOf(fields, "updated_at"),
+		Data:      json.RawMessage(data),
+	}, nil
+}
+
+// Counts returns the number of entities per type
+func (a *Archive) Counts() map[string]int {
+	counts := make(map[string]int)
+	for entityType, entities := range a.Entities {
+		counts[entityType] = len(entities)
+	}
+	return counts
+}
+
+// Find returns the entity of the given type and ID, or nil
+func (a *Archive) Find(entityType, id string) *Entity {
+	for _, entity := range a.Entities[entityType] {
+		if entity.ID == id {
+			return entity
+		}
+	}
+	return nil
+}
+
+// Fields decodes the entity JSON into a generic map
+func (e *Entity) Fields() (map[string]interface{}, error) {
+	var fields map[string]interface{}
+	if err := json.Unmarshal(e.Data, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// Dir returns the ZIP directory that holds the entity and its attachments
+func (e *Entity) Dir() string {
+	return dirOf(e.Path)
+}
+
+// EntityPath returns the ZIP path of an entity JSON file for the given directory name
+func EntityPath(entityType, dirName string) (string, error) {
+	l, ok := layouts[entityType]
+	if !ok {
+		return "", fmt.Errorf("unknown entity type: %s", entityType)
+	}
+	return l.Dir + "/" + dirName + "/" + l.File, nil
+}
+
+// IsType reports whether the given string is a known entity type
+func IsType(entityType string) bool {
+	_, ok := layouts[entityType]
+	return ok
+}
+
+// entityTypeForPath returns the entity type of a top-level entity JSON path
+func entityTypeForPath(name string) (string, bool) {
+	parts := strings.Split(name, "/")
+	if len(parts) != 3 {
+		return "", false
+	}
+	for entityType, l := range layouts {
+		if parts[0] == l.Dir && parts[2] == l.File {
+			return entityType, true
+		}
+	}
+	return "", false
+}
+
+// findOwner returns the entity whose directory contains the given path
+func findOwner(entityDirs map[string]*Entity, name string) *Entity {
+	parts := strings.Split(name, "/")
+	if len(parts) < 3 {
+		return nil
+	}
+	return entityDirs[parts[0]+"/"+parts[1]+"/"]
+}
+
+// dirOf returns the directory part of a ZIP path including the trailing slash
+func dirOf(name string) string {
+	idx := strings.LastIndex(name, "/")
+	if idx < 0 {
+		return ""
+	}
+	return name[:idx+1]
+}
+
+// idOf returns the identifier of an entity (prompts are identified by command)
+func idOf(entityType string, fields map[string]interface{}) string {
+	field := "id"
+	if entityType == TypePrompt {
+		field = "command"
+	}
+	id, _ := fields[field].(string)
+	return id
+}
+
+// nameOf picks a human-readable name from entity fields
+func nameOf(fields map[string]interface{}) string {
+	for _, field := range []string{"name", "title", "command", "filename", "email", "id"} {
+		if value, ok := fields[field].(string); ok && value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// timestampOf returns a numeric timestamp field, or 0 if it is missing
+func timestampOf(fields map[string]interface{}, field string) int64 {
+	value, _ := fields[field].(float64)
+	return int64(value)
+}
+
+// ReadEntry reads a single entry from an unencrypted backup ZIP
+func ReadEntry(zipPath, name string) ([]byte, error) {
+	r, err := zip.OpenReader(zipPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open ZIP file: %w", err)
+	}
+	defer r.Close()
+
+	for _, f := range r.File {
+		if f.Name == name {
+			return readFile(f)
+		}
+	}
+	return nil, fmt.Errorf("%s not found in backup", name)
+}
+
+// readFile reads the full content of a ZIP entry
+func readFile(f *zip.File) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", f.Name, err)
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", f.Name, err)
+	}
+	return data, nil
+}
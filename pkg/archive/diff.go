@@ -0,0 +1,248 @@
+package archive
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+)
+
+// Change kinds
+const (
+	ChangeAdded    = "added"
+	ChangeRemoved  = "removed"
+	ChangeModified = "modified"
+)
+
+// diffContext is the number of context lines in text diffs
+const diffContext = 3
+
+// ignoredFields are never reported as changes because they change on every save
+var ignoredFields = map[string]bool{
+	"created_at":     true,
+	"updated_at":     true,
+	"last_active_at": true,
+}
+
+// textFields always get a unified diff, even when they are single-line
+var textFields = map[string][]string{
+	TypePrompt: {"content"},
+	TypeTool:   {"content"},
+	TypeModel:  {"params.system"},
+	TypeFile:   {"data.content"},
+}
+
+// FieldChange describes a changed field of an entity
+type FieldChange struct {
+	Field string      `json:"field"`
+	Old   interface{} `json:"old,omitempty"`
+	New   interface{} `json:"new,omitempty"`
+	Diff  string      `json:"diff,omitempty"`
+}
+
+// EntityChange describes an added, removed or modified entity
+type EntityChange struct {
+	Type   string        `json:"type"`
+	Key    string        `json:"key"`
+	Name   string        `json:"name"`
+	Kind   string        `json:"kind"`
+	Fields []FieldChange `json:"fields,omitempty"`
+}
+
+// TypeDiff summarizes the changes for one entity type
+type TypeDiff struct {
+	Type      string         `json:"type"`
+	Added     int            `json:"added"`
+	Removed   int            `json:"removed"`
+	Modified  int            `json:"modified"`
+	Unchanged int            `json:"unchanged"`
+	Changes   []EntityChange `json:"changes,omitempty"`
+}
+
+// DiffResult is the difference between two archives
+type DiffResult struct {
+	Old   string     `json:"old"`
+	New   string     `json:"new"`
+	Types []TypeDiff `json:"types"`
+}
+
+// DiffKey returns the key used to match an entity between two archives
+// Entities are keyed by ID, prompts by command and knowledge bases by name
+func DiffKey(e *Entity) string {
+	if e.Type == TypeKnowledge {
+		return e.Name
+	}
+	return e.ID
+}
+
+// Diff compares two archives entity by entity
+func Diff(old, new *Archive) *DiffResult {
+	result := &DiffResult{Old: old.Path, New: new.Path}
+
+	for _, entityType := range Types {
+		oldEntities := indexByDiffKey(old.Entities[entityType])
+		newEntities := indexByDiffKey(new.Entities[entityType])
+		if len(oldEntities) == 0 && len(newEntities) == 0 {
+			continue
+		}
+
+		typeDiff := TypeDiff{Type: entityType}
+
+		for _, key := range sortedKeys(oldEntities, newEntities) {
+			before, inOld := oldEntities[key]
+			after, inNew := newEntities[key]
+
+			switch {
+			case !inOld:
+				typeDiff.Added++
+				typeDiff.Changes = append(typeDiff.Changes, EntityChange{
+					Type: entityType, Key: key, Name: after.Name, Kind: ChangeAdded,
+				})
+			case !inNew:
+				typeDiff.Removed++
+				typeDiff.Changes = append(typeDiff.Changes, EntityChange{
+					Type: entityType, Key: key, Name: before.Name, Kind: ChangeRemoved,
+				})
+			default:
+				oldFields, _ := before.Fields()
+				newFields, _ := after.Fields()
+				fields := CompareFields(entityType, oldFields, newFields)
+				if len(fields) == 0 {
+					typeDiff.Unchanged++
+					continue
+				}
+				typeDiff.Modified++
+				typeDiff.Changes = append(typeDiff.Changes, EntityChange{
+					Type: entityType, Key: key, Name: after.Name, Kind: ChangeModified, Fields: fields,
+				})
+			}
+		}
+
+		result.Types = append(result.Types, typeDiff)
+	}
+
+	return result
+}
+
+// HasChanges reports whether any entity was added, removed or modified
+func (r *DiffResult) HasChanges() bool {
+	for _, t := range r.Types {
+		if t.Added+t.Removed+t.Modified > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// CompareFields returns the field-level differences between two decoded entities
+// Nested objects are compared leaf by leaf using dotted paths; arrays are compared whole
+func CompareFields(entityType string, old, new map[string]interface{}) []FieldChange {
+	oldFlat := make(map[string]interface{})
+	newFlat := make(map[string]interface{})
+	flatten("", old, oldFlat)
+	flatten("", new, newFlat)
+
+	forceText := make(map[string]bool)
+	for _, field := range textFields[entityType] {
+		forceText[field] = true
+	}
+
+	var changes []FieldChange
+	for _, field := range sortedKeys(oldFlat, newFlat) {
+		if ignoredFields[lastSegment(field)] || (entityType == TypeKnowledge && field == "id") {
+			continue
+		}
+
+		before, after := oldFlat[field], newFlat[field]
+		if jsonEqual(before, after) {
+			continue
+		}
+
+		oldText, oldIsText := before.(string)
+		newText, newIsText := after.(string)
+		if (before == nil || oldIsText) && (after == nil || newIsText) &&
+			(forceText[field] || strings.Contains(oldText, "\n") || strings.Contains(newText, "\n")) {
+			changes = append(changes, FieldChange{
+				Field: field,
+				Diff:  UnifiedDiff("a/"+field, "b/"+field, oldText, newText, diffContext),
+			})
+			continue
+		}
+
+		changes = append(changes, FieldChange{Field: field, Old: before, New: after})
+	}
+
+	return changes
+}
+
+// flatten writes leaf values of nested objects into out using dotted paths
+func flatten(prefix string, value map[string]interface{}, out map[string]interface{}) {
+	for key, v := range value {
+		path := key
+		if prefix != "" {
+			path = prefix + "." + key
+		}
+		if nested, ok := v.(map[string]interface{}); ok && len(nested) > 0 {
+			flatten(path, nested, out)
+			continue
+		}
+		out[path] = v
+	}
+}
+
+// jsonEqual compares two decoded JSON values
+func jsonEqual(a, b interface{}) bool {
+	if isEmpty(a) && isEmpty(b) {
+		return true
+	}
+	ja, _ := json.Marshal(a)
+	jb, _ := json.Marshal(b)
+	return string(ja) == string(jb)
+}
+
+// isEmpty treats null, empty strings, arrays and objects as equivalent
+func isEmpty(v interface{}) bool {
+	switch value := v.(type) {
+	case nil:
+		return true
+	case string:
+		return value == ""
+	case []interface{}:
+		return len(value) == 0
+	case map[string]interface{}:
+		return len(value) == 0
+	}
+	return false
+}
+
+// indexByDiffKey maps entities by their diff key
+func indexByDiffKey(entities []*Entity) map[string]*Entity {
+	index := make(map[string]*Entity, len(entities))
+	for _, e := range entities {
+		index[DiffKey(e)] = e
+	}
+	return index
+}
+
+// sortedKeys returns the sorted union of the keys of two maps
+func sortedKeys[V any](a, b map[string]V) []string {
+	seen := make(map[string]bool, len(a)+len(b))
+	keys := make([]string, 0, len(a)+len(b))
+	for _, m := range []map[string]V{a, b} {
+		for key := range m {
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// lastSegment returns the final element of a dotted path
+func lastSegment(path string) string {
+	if idx := strings.LastIndex(path, "."); idx >= 0 {
+		return path[idx+1:]
+	}
+	return path
+}
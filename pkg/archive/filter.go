@@ -0,0 +1,59 @@
+package archive
+
+import (
+	"archive/zip"
+	"fmt"
+	"os"
+)
+
+// Filter copies a unified backup ZIP to dstPath keeping only the entities accepted by keep
+// Entity attachments follow their entity; owui.json and other entries are always copied.
+// Entries are copied raw without recompression. The kept entities are returned.
+func Filter(srcPath, dstPath string, keep func(*Entity) bool) ([]*Entity, error) {
+	r, err := zip.OpenReader(srcPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open ZIP file: %w", err)
+	}
+	defer r.Close()
+
+	a, err := Read(&r.Reader, srcPath)
+	if err != nil {
+		return nil, err
+	}
+
+	// Decide per entity directory so attachments share the decision of their entity
+	keptDirs := make(map[string]bool)
+	entityDirs := make(map[string]*Entity)
+	var kept []*Entity
+	for _, entityType := range Types {
+		for _, entity := range a.Entities[entityType] {
+			entityDirs[entity.Dir()] = entity
+			if keep(entity) {
+				keptDirs[entity.Dir()] = true
+				kept = append(kept, entity)
+			}
+		}
+	}
+
+	out, err := os.Create(dstPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", dstPath, err)
+	}
+	defer out.Close()
+
+	w := zip.NewWriter(out)
+	for _, f := range r.File {
+		if owner := findOwner(entityDirs, f.Name); owner != nil && !keptDirs[owner.Dir()] {
+			continue
+		}
+		if err := w.Copy(f); err != nil {
+			return nil, fmt.Errorf("failed to copy %s: %w", f.Name, err)
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("failed to finalize ZIP file: %w", err)
+	}
+
+	return kept, nil
+}
@@ -0,0 +1,103 @@
+package archive
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+)
+
+// fingerprintSpec lists the fields that identify an entity and the fields that
+// survive a restore unchanged, so archive and live copies can be compared
+type fingerprintSpec struct {
+	Key     string
+	Content []string
+}
+
+// specs holds the fingerprint spec per entity type
+// Knowledge bases, files, chats, groups and feedbacks get new IDs when restored,
+// so they are matched by name or content instead of ID
+var specs = map[string]fingerprintSpec{
+	TypeKnowledge: {Key: "name", Content: []string{"name", "description"}},
+	TypeModel:     {Key: "id", Content: []string{"id", "name", "base_model_id", "params"}},
+	TypeTool:      {Key: "id", Content: []string{"id", "name", "content"}},
+	TypePrompt:    {Key: "command", Content: []string{"command", "title", "content"}},
+	TypeFile:      {Key: "meta.name", Content: []string{"meta.name", "data.content"}},
+	TypeChat:      {Key: "", Content: []string{"title", "chat.messages"}},
+	TypeUser:      {Key: "email", Content: []string{"email", "name", "role"}},
+	TypeGroup:     {Key: "name", Content: []string{"name", "description"}},
+	TypeFeedback:  {Key: "", Content: []string{"type", "data"}},
+}
+
+// KeyOf returns the matching key of an entity
+// Types without a natural key are keyed by their content hash
+func KeyOf(entityType string, fields map[string]interface{}) string {
+	spec, ok := specs[entityType]
+	if !ok {
+		if id, ok := fields["id"].(string); ok {
+			return id
+		}
+		return ""
+	}
+	if spec.Key == "" {
+		return ContentHash(entityType, fields)
+	}
+	value, _ := lookup(fields, spec.Key).(string)
+	return value
+}
+
+// ContentHash returns a SHA-256 over the restore-stable fields of an entity
+func ContentHash(entityType string, fields map[string]interface{}) string {
+	spec, ok := specs[entityType]
+
+	selected := make(map[string]interface{})
+	if ok {
+		for _, field := range spec.Content {
+			selected[field] = lookup(fields, field)
+		}
+	} else {
+		selected = fields
+	}
+
+	// encoding/json sorts map keys, which makes the encoding canonical
+	data, _ := json.Marshal(selected)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Fingerprint returns the key and content hash of any JSON-encodable entity
+func Fingerprint(entityType string, v interface{}) (string, string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", "", err
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return "", "", err
+	}
+
+	return KeyOf(entityType, fields), ContentHash(entityType, fields), nil
+}
+
+// Hash returns the content hash of an archived entity
+func (e *Entity) Hash() string {
+	fields, err := e.Fields()
+	if err != nil {
+		return ""
+	}
+	return ContentHash(e.Type, fields)
+}
+
+// lookup resolves a dotted field path in a decoded JSON object
+func lookup(fields map[string]interface{}, path string) interface{} {
+	var current interface{} = fields
+	for _, part := range strings.Split(path, ".") {
+		obj, ok := current.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		current = obj[part]
+	}
+	return current
+}
@@ -0,0 +1,122 @@
+package archive
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+	"github.com/vosiander/open-webui-backup/pkg/openwebui"
+)
+
+// FetchLive lists all entities of a type from an Open WebUI instance
+// Files are fetched with their content so they can be compared with archived copies
+func FetchLive(client *openwebui.Client, entityType string) ([]interface{}, error) {
+	var items []interface{}
+
+	switch entityType {
+	case TypeKnowledge:
+		list, err := client.ListKnowledge()
+		if err != nil {
+			return nil, err
+		}
+		for i := range list {
+			items = append(items, list[i])
+		}
+	case TypeModel:
+		list, err := client.ExportModels()
+		if err != nil {
+			return nil, err
+		}
+		for i := range list {
+			items = append(items, list[i])
+		}
+	case TypeTool:
+		list, err := client.ExportTools()
+		if err != nil {
+			return nil, err
+		}
+		for i := range list {
+			items = append(items, list[i])
+		}
+	case TypePrompt:
+		list, err := client.ListPrompts()
+		if err != nil {
+			return nil, err
+		}
+		for i := range list {
+			items = append(items, list[i])
+		}
+	case TypeFile:
+		list, err := client.ListFiles()
+		if err != nil {
+			return nil, err
+		}
+		for _, meta := range list {
+			file, err := client.GetFileWithContent(meta.ID)
+			if err != nil {
+				logrus.Warnf("Failed to fetch file %s: %v", meta.ID, err)
+				continue
+			}
+			items = append(items, file)
+		}
+	case TypeChat:
+		list, err := client.GetAllChatsDB()
+		if err != nil {
+			return nil, err
+		}
+		for i := range list {
+			items = append(items, list[i])
+		}
+	case TypeUser:
+		list, err := client.GetAllUsers()
+		if err != nil {
+			return nil, err
+		}
+		for i := range list {
+			items = append(items, list[i])
+		}
+	case TypeGroup:
+		list, err := client.GetAllGroups()
+		if err != nil {
+			return nil, err
+		}
+		for i := range list {
+			items = append(items, list[i])
+		}
+	case TypeFeedback:
+		list, err := client.GetAllFeedbacks()
+		if err != nil {
+			return nil, err
+		}
+		for i := range list {
+			items = append(items, list[i])
+		}
+	default:
+		return nil, fmt.Errorf("unknown entity type: %s", entityType)
+	}
+
+	return items, nil
+}
+
+// FetchLiveEntities lists all entities of a type from an Open WebUI instance as archive entities
+func FetchLiveEntities(client *openwebui.Client, entityType string) ([]*Entity, error) {
+	items, err := FetchLive(client, entityType)
+	if err != nil {
+		return nil, err
+	}
+
+	entities := make([]*Entity, 0, len(items))
+	for _, item := range items {
+		data, err := json.Marshal(item)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal %s: %w", entityType, err)
+		}
+		entity, err := NewEntity(entityType, data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", entityType, err)
+		}
+		entities = append(entities, entity)
+	}
+
+	return entities, nil
+}
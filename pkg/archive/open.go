@@ -0,0 +1,57 @@
+package archive
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/vosiander/open-webui-backup/pkg/encryption"
+)
+
+// Decrypt returns a path to the plaintext ZIP of a backup file
+// Encrypted backups are decrypted to a temporary file; cleanup removes it and
+// must always be called. Unencrypted backups are returned as-is.
+func Decrypt(backupFile string, identities []string) (string, func(), error) {
+	noop := func() {}
+
+	if !encryption.IsEncrypted(backupFile) {
+		return backupFile, noop, nil
+	}
+
+	if len(identities) == 0 {
+		return "", noop, fmt.Errorf("%s is encrypted but no age identity was provided", backupFile)
+	}
+
+	tmp, err := os.CreateTemp("", "owui-archive-*.zip")
+	if err != nil {
+		return "", noop, fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	tmp.Close()
+
+	cleanup := func() {
+		os.Remove(tmp.Name())
+	}
+
+	if err := encryption.DecryptFileWithIdentities(backupFile, tmp.Name(), identities); err != nil {
+		cleanup()
+		return "", noop, fmt.Errorf("failed to decrypt %s: %w", backupFile, err)
+	}
+
+	return tmp.Name(), cleanup, nil
+}
+
+// LoadBackup decrypts a backup if needed and loads its entities
+func LoadBackup(backupFile string, identities []string) (*Archive, error) {
+	zipPath, cleanup, err := Decrypt(backupFile, identities)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	a, err := Load(zipPath)
+	if err != nil {
+		return nil, err
+	}
+	a.Path = backupFile
+
+	return a, nil
+}
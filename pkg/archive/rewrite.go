@@ -0,0 +1,222 @@
+package archive
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/vosiander/open-webui-backup/pkg/openwebui"
+)
+
+// RewriteOptions controls how Rewrite transforms a unified backup ZIP
+// Nil hooks leave the corresponding entries unchanged
+type RewriteOptions struct {
+	// Entity returns the new JSON of an entity, or nil to drop it with its attachments
+	// Returning e.Data unchanged keeps the original entry
+	Entity func(e *Entity) ([]byte, error)
+	// Attachment reports whether an attachment of a kept entity is copied
+	Attachment func(e *Entity, name string) bool
+	// Other reports whether an entry that belongs to no entity is copied
+	Other func(name string) bool
+	// Content returns a filter that rewrites a kept entry that belongs to no entity, or nil to copy it unchanged
+	// The filter reports whether it changed anything
+	Content func(name string) func(dst io.Writer, src io.Reader) (bool, error)
+	// Metadata updates owui.json before it is written
+	Metadata func(metadata *openwebui.BackupMetadata) error
+}
+
+// RewriteResult counts what Rewrite changed
+type RewriteResult struct {
+	Rewritten   int
+	Dropped     int
+	Attachments int // attachments dropped
+	Other       int // other entries dropped
+	Filtered    int // other entries changed by a content filter
+}
+
+// Rewrite copies a unified backup ZIP to dstPath, passing entities and owui.json through the hooks
+// Unchanged entries are copied raw without recompression; entry order is preserved
+func Rewrite(srcPath, dstPath string, options *RewriteOptions) (*RewriteResult, error) {
+	r, err := zip.OpenReader(srcPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open ZIP file: %w", err)
+	}
+	defer r.Close()
+
+	a, err := Read(&r.Reader, srcPath)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &RewriteResult{}
+	entityPaths := make(map[string]*Entity)
+	entityDirs := make(map[string]*Entity)
+	replaced := make(map[string][]byte)
+	droppedDirs := make(map[string]bool)
+
+	for _, entityType := range Types {
+		for _, entity := range a.Entities[entityType] {
+			entityPaths[entity.Path] = entity
+			entityDirs[entity.Dir()] = entity
+			if options.Entity == nil {
+				continue
+			}
+
+			data, err := options.Entity(entity)
+			if err != nil {
+				return nil, fmt.Errorf("failed to rewrite %s: %w", entity.Path, err)
+			}
+			if data == nil {
+				droppedDirs[entity.Dir()] = true
+				result.Dropped++
+				continue
+			}
+			if bytes.Equal(data, entity.Data) {
+				continue
+			}
+			replaced[entity.Path] = data
+			result.Rewritten++
+		}
+	}
+
+	out, err := os.Create(dstPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", dstPath, err)
+	}
+	defer out.Close()
+
+	w := zip.NewWriter(out)
+	metadataWritten := false
+
+	for _, f := range r.File {
+		switch {
+		case f.Name == "owui.json":
+			if err := writeMetadata(w, a.Metadata, f.Modified, result, options); err != nil {
+				return nil, err
+			}
+			metadataWritten = true
+			continue
+
+		case entityPaths[f.Name] != nil:
+			if droppedDirs[entityPaths[f.Name].Dir()] {
+				continue
+			}
+			if data, ok := replaced[f.Name]; ok {
+				if err := writeEntry(w, f, data); err != nil {
+					return nil, err
+				}
+				continue
+			}
+
+		default:
+			if owner := findOwner(entityDirs, f.Name); owner != nil {
+				if droppedDirs[owner.Dir()] {
+					continue
+				}
+				if options.Attachment != nil && !f.FileInfo().IsDir() && !options.Attachment(owner, f.Name) {
+					result.Attachments++
+					continue
+				}
+			} else if options.Other != nil && !options.Other(f.Name) {
+				result.Other++
+				continue
+			} else if options.Content != nil && !f.FileInfo().IsDir() {
+				if filter := options.Content(f.Name); filter != nil {
+					changed, err := filterEntry(w, f, filter)
+					if err != nil {
+						return nil, err
+					}
+					if changed {
+						result.Filtered++
+					}
+					continue
+				}
+			}
+		}
+
+		if err := w.Copy(f); err != nil {
+			return nil, fmt.Errorf("failed to copy %s: %w", f.Name, err)
+		}
+	}
+
+	if !metadataWritten && options.Metadata != nil {
+		if err := writeMetadata(w, nil, time.Now(), result, options); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("failed to finalize ZIP file: %w", err)
+	}
+
+	return result, nil
+}
+
+// writeMetadata writes owui.json after applying the metadata hook
+func writeMetadata(w *zip.Writer, metadata *openwebui.BackupMetadata, modified time.Time, result *RewriteResult, options *RewriteOptions) error {
+	if metadata == nil {
+		metadata = &openwebui.BackupMetadata{UnifiedBackup: true}
+	}
+	if result.Dropped > 0 && metadata.ItemCount >= result.Dropped {
+		metadata.ItemCount -= result.Dropped
+	}
+	if options.Metadata != nil {
+		if err := options.Metadata(metadata); err != nil {
+			return err
+		}
+	}
+
+	data, err := json.MarshalIndent(metadata, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal metadata: %w", err)
+	}
+
+	f, err := w.CreateHeader(&zip.FileHeader{Name: "owui.json", Method: zip.Deflate, Modified: modified})
+	if err != nil {
+		return fmt.Errorf("failed to create owui.json in zip: %w", err)
+	}
+	if _, err := f.Write(data); err != nil {
+		return fmt.Errorf("failed to write owui.json: %w", err)
+	}
+	return nil
+}
+
+// writeEntry writes new content for an entry, keeping its name and modification time
+func writeEntry(w *zip.Writer, f *zip.File, data []byte) error {
+	header := &zip.FileHeader{
+		Name:     f.Name,
+		Method:   zip.Deflate,
+		Modified: f.Modified,
+	}
+	entry, err := w.CreateHeader(header)
+	if err != nil {
+		return fmt.Errorf("failed to create %s in zip: %w", f.Name, err)
+	}
+	if _, err := entry.Write(data); err != nil {
+		return fmt.Errorf("failed to write %s: %w", f.Name, err)
+	}
+	return nil
+}
+
+// filterEntry writes an entry through a content filter, keeping its name, compression method and modification time
+func filterEntry(w *zip.Writer, f *zip.File, filter func(dst io.Writer, src io.Reader) (bool, error)) (bool, error) {
+	src, err := f.Open()
+	if err != nil {
+		return false, fmt.Errorf("failed to open %s: %w", f.Name, err)
+	}
+	defer src.Close()
+
+	entry, err := w.CreateHeader(&zip.FileHeader{Name: f.Name, Method: f.Method, Modified: f.Modified})
+	if err != nil {
+		return false, fmt.Errorf("failed to create %s in zip: %w", f.Name, err)
+	}
+	changed, err := filter(entry, src)
+	if err != nil {
+		return false, fmt.Errorf("failed to filter %s: %w", f.Name, err)
+	}
+	return changed, nil
+}
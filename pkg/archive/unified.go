@@ -0,0 +1,173 @@
+package archive
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxDiffCells bounds the LCS table so huge texts do not exhaust memory
+const maxDiffCells = 4_000_000
+
+// diffOp is a single line operation in an edit script
+type diffOp struct {
+	Kind byte // ' ', '-' or '+'
+	Line string
+}
+
+// UnifiedDiff returns a unified diff between two texts with the given number of context lines
+// It returns an empty string when the texts are equal
+func UnifiedDiff(oldName, newName, oldText, newText string, context int) string {
+	if oldText == newText {
+		return ""
+	}
+
+	a := splitLines(oldText)
+	b := splitLines(newText)
+
+	if len(a)*len(b) > maxDiffCells {
+		return fmt.Sprintf("--- %s\n+++ %s\n@@ content too large to diff (%d → %d lines) @@\n", oldName, newName, len(a), len(b))
+	}
+
+	ops := editScript(a, b)
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", oldName, newName)
+
+	// Walk the edit script and emit hunks around changed lines
+	i := 0
+	for i < len(ops) {
+		if ops[i].Kind == ' ' {
+			i++
+			continue
+		}
+
+		start := i - context
+		if start < 0 {
+			start = 0
+		}
+
+		// Extend the hunk while changes are within 2*context lines of each other
+		end := i
+		for end < len(ops) {
+			if ops[end].Kind != ' ' {
+				end++
+				continue
+			}
+			next := end
+			for next < len(ops) && ops[next].Kind == ' ' {
+				next++
+			}
+			if next < len(ops) && next-end <= 2*context {
+				end = next
+				continue
+			}
+			break
+		}
+		stop := end + context
+		if stop > len(ops) {
+			stop = len(ops)
+		}
+
+		oldStart, newStart := lineNumbers(ops, start)
+		oldCount, newCount := 0, 0
+		for _, op := range ops[start:stop] {
+			if op.Kind != '+' {
+				oldCount++
+			}
+			if op.Kind != '-' {
+				newCount++
+			}
+		}
+
+		fmt.Fprintf(&sb, "@@ -%s +%s @@\n", hunkRange(oldStart, oldCount), hunkRange(newStart, newCount))
+		for _, op := range ops[start:stop] {
+			sb.WriteByte(op.Kind)
+			sb.WriteString(op.Line)
+			sb.WriteByte('\n')
+		}
+
+		i = stop
+	}
+
+	return sb.String()
+}
+
+// editScript computes a line edit script using the longest common subsequence
+func editScript(a, b []string) []diffOp {
+	n, m := len(a), len(b)
+
+	// lcs[i][j] is the LCS length of a[i:] and b[j:]
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	ops := make([]diffOp, 0, n+m)
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, diffOp{Kind: ' ', Line: a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, diffOp{Kind: '-', Line: a[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{Kind: '+', Line: b[j]})
+			j++
+		}
+	}
+	for ; i < n; i++ {
+		ops = append(ops, diffOp{Kind: '-', Line: a[i]})
+	}
+	for ; j < m; j++ {
+		ops = append(ops, diffOp{Kind: '+', Line: b[j]})
+	}
+
+	return ops
+}
+
+// lineNumbers returns the 1-based old and new line numbers at an op index
+func lineNumbers(ops []diffOp, index int) (int, int) {
+	oldLine, newLine := 1, 1
+	for _, op := range ops[:index] {
+		if op.Kind != '+' {
+			oldLine++
+		}
+		if op.Kind != '-' {
+			newLine++
+		}
+	}
+	return oldLine, newLine
+}
+
+// hunkRange formats a unified diff range
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start-1)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
+
+// splitLines splits text into lines without trailing newline characters
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
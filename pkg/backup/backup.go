@@ -12,7 +12,10 @@ import (
 	"time"
 
 	"github.com/sirupsen/logrus"
+	"github.com/vosiander/open-webui-backup/pkg/archive"
 	"github.com/vosiander/open-webui-backup/pkg/config"
+	"github.com/vosiander/open-webui-backup/pkg/database"
+	"github.com/vosiander/open-webui-backup/pkg/metrics"
 	"github.com/vosiander/open-webui-backup/pkg/openwebui"
 )
 
@@ -779,7 +782,7 @@ func backupSingleChat(chat *openwebui.Chat, client *openwebui.Client, outputDir
 }
 
 // backupAllChats backs up all chats into the unified ZIP
-func backupAllChats(zipWriter *zip.Writer, client *openwebui.Client) (int, error) {
+func backupAllChats(zipWriter *zip.Writer, client *openwebui.Client, entities entityLog) (int, error) {
 	chats, err := client.GetAllChatsDB()
 	if err != nil {
 		return 0, fmt.Errorf("failed to get chats: %w", err)
@@ -787,7 +790,7 @@ func backupAllChats(zipWriter *zip.Writer, client *openwebui.Client) (int, error
 
 	for i, chat := range chats {
 		logrus.Infof("  Backing up chat %d/%d: %s", i+1, len(chats), chat.Title)
-		if err := backupChatToZip(zipWriter, &chat); err != nil {
+		if err := backupChatToZip(zipWriter, &chat, entities); err != nil {
 			logrus.Warnf("  Failed to backup chat '%s': %v", chat.Title, err)
 			continue
 		}
@@ -797,7 +800,7 @@ func backupAllChats(zipWriter *zip.Writer, client *openwebui.Client) (int, error
 }
 
 // backupChatToZip backs up a single chat into an existing ZIP writer
-func backupChatToZip(zipWriter *zip.Writer, chat *openwebui.Chat) error {
+func backupChatToZip(zipWriter *zip.Writer, chat *openwebui.Chat, entities entityLog) error {
 	// Create chats/{id}/ directory
 	chatDir := fmt.Sprintf("chats/%s/", chat.ID)
 
@@ -814,7 +817,35 @@ func backupChatToZip(zipWriter *zip.Writer, chat *openwebui.Chat) error {
 	if _, err := chatFile.Write(chatJSON); err != nil {
 		return fmt.Errorf("failed to write chat.json: %w", err)
 	}
+	if err := entities.add(archive.TypeChat, chatDir+"chat.json", chatJSON); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Summary reports the items of a selective backup and the types that could not be backed up completely
+// Entities lists what was written, so a snapshot record can be computed without reading the ZIP back
+type Summary struct {
+	Items    map[string]int               `json:"items"`
+	Failed   map[string]string            `json:"failed,omitempty"`
+	Entities map[string][]*archive.Entity `json:"-"`
+}
 
+// entityLog collects the entities written into a unified ZIP by type; a nil log records nothing
+type entityLog map[string][]*archive.Entity
+
+// add records an entity from the JSON just written to path
+func (l entityLog) add(entityType, path string, data []byte) error {
+	if l == nil {
+		return nil
+	}
+	entity, err := archive.NewEntity(entityType, data)
+	if err != nil {
+		return fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	entity.Path = path
+	l[entityType] = append(l[entityType], entity)
 	return nil
 }
 
@@ -822,35 +853,77 @@ func backupChatToZip(zipWriter *zip.Writer, chat *openwebui.Chat) error {
 // outputFile should be the full path to the output ZIP file
 // progressCallback is an optional callback function for progress updates (can be nil)
 func BackupSelective(client *openwebui.Client, outputFile string, options *SelectiveBackupOptions, progressCallback ProgressCallback) error {
-	logrus.Info("Starting selective backup...")
+	_, err := BackupSelectiveWithSummary(client, outputFile, options, progressCallback)
+	return err
+}
 
-	if progressCallback != nil {
-		progressCallback(0, "Starting selective backup...")
+// BackupSelectiveWithSummary performs a selective backup and reports what it contains
+// Types that fail are logged and listed in the summary; the backup still succeeds
+func BackupSelectiveWithSummary(client *openwebui.Client, outputFile string, options *SelectiveBackupOptions, progressCallback ProgressCallback) (*Summary, error) {
+	if err := options.validate(); err != nil {
+		return nil, err
 	}
 
-	// Validate that at least one option is enabled
-	if !options.Knowledge && !options.Models && !options.Tools && !options.Prompts && !options.Files && !options.Chats {
-		return fmt.Errorf("at least one data type must be selected for backup")
+	zipFile, err := CreateZip(outputFile)
+	if err != nil {
+		return nil, err
 	}
+	defer zipFile.Close()
 
-	// Check if file already exists
-	if _, err := os.Stat(outputFile); err == nil {
-		return &openwebui.FileExistsError{Path: outputFile}
+	zipWriter := zip.NewWriter(zipFile)
+	defer zipWriter.Close()
+
+	summary, err := WriteSelective(client, zipWriter, options, progressCallback)
+	if err != nil {
+		return nil, err
 	}
 
-	// Create ZIP file
+	if progressCallback != nil {
+		progressCallback(100, "Backup completed successfully")
+	}
+	logrus.Infof("Created selective backup: %s", filepath.Base(outputFile))
+	logrus.Info("Selective backup completed successfully")
+	return summary, nil
+}
+
+// CreateZip creates the file of a new backup ZIP, refusing to overwrite an existing one
+func CreateZip(outputFile string) (*os.File, error) {
+	if _, err := os.Stat(outputFile); err == nil {
+		return nil, &openwebui.FileExistsError{Path: outputFile}
+	}
 	zipFile, err := os.Create(outputFile)
 	if err != nil {
-		return fmt.Errorf("failed to create zip file: %w", err)
+		return nil, fmt.Errorf("failed to create zip file: %w", err)
 	}
-	defer zipFile.Close()
+	return zipFile, nil
+}
 
-	zipWriter := zip.NewWriter(zipFile)
-	defer zipWriter.Close()
+// validate checks that at least one data type is selected
+func (o *SelectiveBackupOptions) validate() error {
+	if !o.Knowledge && !o.Models && !o.Tools && !o.Prompts && !o.Files && !o.Chats {
+		return fmt.Errorf("at least one data type must be selected for backup")
+	}
+	return nil
+}
+
+// WriteSelective writes the API export of a selective backup and owui.json into an open ZIP
+// The caller closes the ZIP, so further sections such as the database dump can follow
+func WriteSelective(client *openwebui.Client, zipWriter *zip.Writer, options *SelectiveBackupOptions, progressCallback ProgressCallback) (*Summary, error) {
+	logrus.Info("Starting selective backup...")
+
+	if progressCallback != nil {
+		progressCallback(0, "Starting selective backup...")
+	}
+
+	if err := options.validate(); err != nil {
+		return nil, err
+	}
 
 	// Track contained types and total item count
 	containedTypes := []string{}
 	totalItems := 0
+	entities := entityLog{}
+	summary := &Summary{Items: map[string]int{}, Failed: map[string]string{}, Entities: entities}
 
 	// Backup selected types
 	if options.Knowledge {
@@ -858,13 +931,16 @@ func BackupSelective(client *openwebui.Client, outputFile string, options *Selec
 			progressCallback(10, "Backing up knowledge bases...")
 		}
 		logrus.Info("Backing up knowledge bases...")
-		kbCount, err := backupAllKnowledgeBases(zipWriter, client)
+		kbCount, err := backupAllKnowledgeBases(zipWriter, client, entities)
 		if err != nil {
 			logrus.Warnf("Failed to backup some knowledge bases: %v", err)
+			summary.Failed["knowledge"] = err.Error()
 		}
 		if kbCount > 0 {
 			containedTypes = append(containedTypes, "knowledge")
+			summary.Items["knowledge"] = kbCount
 			totalItems += kbCount
+			metrics.AddItems("backup", "knowledge", kbCount)
 			logrus.Infof("  Backed up %d knowledge base(s)", kbCount)
 		}
 	}
@@ -874,13 +950,16 @@ func BackupSelective(client *openwebui.Client, outputFile string, options *Selec
 			progressCallback(25, "Backing up models...")
 		}
 		logrus.Info("Backing up models...")
-		modelCount, err := backupAllModels(zipWriter, client)
+		modelCount, err := backupAllModels(zipWriter, client, entities)
 		if err != nil {
 			logrus.Warnf("Failed to backup some models: %v", err)
+			summary.Failed["model"] = err.Error()
 		}
 		if modelCount > 0 {
 			containedTypes = append(containedTypes, "model")
+			summary.Items["model"] = modelCount
 			totalItems += modelCount
+			metrics.AddItems("backup", "model", modelCount)
 			logrus.Infof("  Backed up %d model(s)", modelCount)
 		}
 	}
@@ -890,13 +969,16 @@ func BackupSelective(client *openwebui.Client, outputFile string, options *Selec
 			progressCallback(40, "Backing up tools...")
 		}
 		logrus.Info("Backing up tools...")
-		toolCount, err := backupAllTools(zipWriter, client)
+		toolCount, err := backupAllTools(zipWriter, client, entities)
 		if err != nil {
 			logrus.Warnf("Failed to backup some tools: %v", err)
+			summary.Failed["tool"] = err.Error()
 		}
 		if toolCount > 0 {
 			containedTypes = append(containedTypes, "tool")
+			summary.Items["tool"] = toolCount
 			totalItems += toolCount
+			metrics.AddItems("backup", "tool", toolCount)
 			logrus.Infof("  Backed up %d tool(s)", toolCount)
 		}
 	}
@@ -906,13 +988,16 @@ func BackupSelective(client *openwebui.Client, outputFile string, options *Selec
 			progressCallback(55, "Backing up prompts...")
 		}
 		logrus.Info("Backing up prompts...")
-		promptCount, err := backupAllPrompts(zipWriter, client)
+		promptCount, err := backupAllPrompts(zipWriter, client, entities)
 		if err != nil {
 			logrus.Warnf("Failed to backup some prompts: %v", err)
+			summary.Failed["prompt"] = err.Error()
 		}
 		if promptCount > 0 {
 			containedTypes = append(containedTypes, "prompt")
+			summary.Items["prompt"] = promptCount
 			totalItems += promptCount
+			metrics.AddItems("backup", "prompt", promptCount)
 			logrus.Infof("  Backed up %d prompt(s)", promptCount)
 		}
 	}
@@ -922,13 +1007,16 @@ func BackupSelective(client *openwebui.Client, outputFile string, options *Selec
 			progressCallback(65, "Backing up files...")
 		}
 		logrus.Info("Backing up files...")
-		fileCount, err := backupAllFiles(zipWriter, client)
+		fileCount, err := backupAllFiles(zipWriter, client, entities)
 		if err != nil {
 			logrus.Warnf("Failed to backup some files: %v", err)
+			summary.Failed["file"] = err.Error()
 		}
 		if fileCount > 0 {
 			containedTypes = append(containedTypes, "file")
+			summary.Items["file"] = fileCount
 			totalItems += fileCount
+			metrics.AddItems("backup", "file", fileCount)
 			logrus.Infof("  Backed up %d file(s)", fileCount)
 		}
 	}
@@ -938,13 +1026,16 @@ func BackupSelective(client *openwebui.Client, outputFile string, options *Selec
 			progressCallback(75, "Backing up chats...")
 		}
 		logrus.Info("Backing up chats...")
-		chatCount, err := backupAllChats(zipWriter, client)
+		chatCount, err := backupAllChats(zipWriter, client, entities)
 		if err != nil {
 			logrus.Warnf("Failed to backup some chats: %v", err)
+			summary.Failed["chat"] = err.Error()
 		}
 		if chatCount > 0 {
 			containedTypes = append(containedTypes, "chat")
+			summary.Items["chat"] = chatCount
 			totalItems += chatCount
+			metrics.AddItems("backup", "chat", chatCount)
 			logrus.Infof("  Backed up %d chat(s)", chatCount)
 		}
 	}
@@ -954,13 +1045,16 @@ func BackupSelective(client *openwebui.Client, outputFile string, options *Selec
 			progressCallback(82, "Backing up groups...")
 		}
 		logrus.Info("Backing up groups...")
-		groupCount, err := backupAllGroups(zipWriter, client)
+		groupCount, err := backupAllGroups(zipWriter, client, entities)
 		if err != nil {
 			logrus.Warnf("Failed to backup some groups: %v", err)
+			summary.Failed["group"] = err.Error()
 		}
 		if groupCount > 0 {
 			containedTypes = append(containedTypes, "group")
+			summary.Items["group"] = groupCount
 			totalItems += groupCount
+			metrics.AddItems("backup", "group", groupCount)
 			logrus.Infof("  Backed up %d group(s)", groupCount)
 		}
 	}
@@ -970,13 +1064,16 @@ func BackupSelective(client *openwebui.Client, outputFile string, options *Selec
 			progressCallback(88, "Backing up feedbacks...")
 		}
 		logrus.Info("Backing up feedbacks...")
-		feedbackCount, err := backupAllFeedbacks(zipWriter, client)
+		feedbackCount, err := backupAllFeedbacks(zipWriter, client, entities)
 		if err != nil {
 			logrus.Warnf("Failed to backup some feedbacks: %v", err)
+			summary.Failed["feedback"] = err.Error()
 		}
 		if feedbackCount > 0 {
 			containedTypes = append(containedTypes, "feedback")
+			summary.Items["feedback"] = feedbackCount
 			totalItems += feedbackCount
+			metrics.AddItems("backup", "feedback", feedbackCount)
 			logrus.Infof("  Backed up %d feedback(s)", feedbackCount)
 		}
 	}
@@ -987,13 +1084,16 @@ func BackupSelective(client *openwebui.Client, outputFile string, options *Selec
 			progressCallback(93, "Backing up users...")
 		}
 		logrus.Info("Backing up users...")
-		userCount, err := backupAllUsers(zipWriter, client)
+		userCount, err := backupAllUsers(zipWriter, client, entities)
 		if err != nil {
 			logrus.Warnf("Failed to backup some users: %v", err)
+			summary.Failed["user"] = err.Error()
 		}
 		if userCount > 0 {
 			containedTypes = append(containedTypes, "user")
+			summary.Items["user"] = userCount
 			totalItems += userCount
+			metrics.AddItems("backup", "user", userCount)
 			logrus.Infof("  Backed up %d user(s)", userCount)
 		}
 	}
@@ -1007,15 +1107,11 @@ func BackupSelective(client *openwebui.Client, outputFile string, options *Selec
 	// Add unified metadata
 	metadata := generateMetadata(client, backupType, totalItems, true, containedTypes)
 	if err := writeMetadataToZip(zipWriter, metadata); err != nil {
-		return fmt.Errorf("failed to write metadata: %w", err)
+		return nil, fmt.Errorf("failed to write metadata: %w", err)
 	}
 
-	if progressCallback != nil {
-		progressCallback(100, "Backup completed successfully")
-	}
-	logrus.Infof("Created selective backup: %s (%d total items)", filepath.Base(outputFile), totalItems)
-	logrus.Info("Selective backup completed successfully")
-	return nil
+	logrus.Infof("Exported %d total items", totalItems)
+	return summary, nil
 }
 
 // BackupAll backs up all data types into a single unified ZIP file
@@ -1053,109 +1149,118 @@ func BackupAll(client *openwebui.Client, outputDir string) error {
 
 	// Step 1: Backup knowledge bases
 	logrus.Info("Step 1/5: Backing up knowledge bases...")
-	kbCount, err := backupAllKnowledgeBases(zipWriter, client)
+	kbCount, err := backupAllKnowledgeBases(zipWriter, client, nil)
 	if err != nil {
 		logrus.Warnf("Failed to backup some knowledge bases: %v", err)
 	}
 	if kbCount > 0 {
 		containedTypes = append(containedTypes, "knowledge")
 		totalItems += kbCount
+		metrics.AddItems("backup", "knowledge", kbCount)
 		logrus.Infof("  Backed up %d knowledge base(s)", kbCount)
 	}
 
 	// Step 2: Backup models
 	logrus.Info("Step 2/5: Backing up models...")
-	modelCount, err := backupAllModels(zipWriter, client)
+	modelCount, err := backupAllModels(zipWriter, client, nil)
 	if err != nil {
 		logrus.Warnf("Failed to backup some models: %v", err)
 	}
 	if modelCount > 0 {
 		containedTypes = append(containedTypes, "model")
 		totalItems += modelCount
+		metrics.AddItems("backup", "model", modelCount)
 		logrus.Infof("  Backed up %d model(s)", modelCount)
 	}
 
 	// Step 3: Backup tools
 	logrus.Info("Step 3/5: Backing up tools...")
-	toolCount, err := backupAllTools(zipWriter, client)
+	toolCount, err := backupAllTools(zipWriter, client, nil)
 	if err != nil {
 		logrus.Warnf("Failed to backup some tools: %v", err)
 	}
 	if toolCount > 0 {
 		containedTypes = append(containedTypes, "tool")
 		totalItems += toolCount
+		metrics.AddItems("backup", "tool", toolCount)
 		logrus.Infof("  Backed up %d tool(s)", toolCount)
 	}
 
 	// Step 4: Backup prompts
 	logrus.Info("Step 4/5: Backing up prompts...")
-	promptCount, err := backupAllPrompts(zipWriter, client)
+	promptCount, err := backupAllPrompts(zipWriter, client, nil)
 	if err != nil {
 		logrus.Warnf("Failed to backup some prompts: %v", err)
 	}
 	if promptCount > 0 {
 		containedTypes = append(containedTypes, "prompt")
 		totalItems += promptCount
+		metrics.AddItems("backup", "prompt", promptCount)
 		logrus.Infof("  Backed up %d prompt(s)", promptCount)
 	}
 
 	// Step 5: Backup files
 	logrus.Info("Step 5/6: Backing up files...")
-	fileCount, err := backupAllFiles(zipWriter, client)
+	fileCount, err := backupAllFiles(zipWriter, client, nil)
 	if err != nil {
 		logrus.Warnf("Failed to backup some files: %v", err)
 	}
 	if fileCount > 0 {
 		containedTypes = append(containedTypes, "file")
 		totalItems += fileCount
+		metrics.AddItems("backup", "file", fileCount)
 		logrus.Infof("  Backed up %d file(s)", fileCount)
 	}
 
 	// Step 6: Backup chats
 	logrus.Info("Step 6/9: Backing up chats...")
-	chatCount, err := backupAllChats(zipWriter, client)
+	chatCount, err := backupAllChats(zipWriter, client, nil)
 	if err != nil {
 		logrus.Warnf("Failed to backup some chats: %v", err)
 	}
 	if chatCount > 0 {
 		containedTypes = append(containedTypes, "chat")
 		totalItems += chatCount
+		metrics.AddItems("backup", "chat", chatCount)
 		logrus.Infof("  Backed up %d chat(s)", chatCount)
 	}
 
 	// Step 7: Backup groups
 	logrus.Info("Step 7/9: Backing up groups...")
-	groupCount, err := backupAllGroups(zipWriter, client)
+	groupCount, err := backupAllGroups(zipWriter, client, nil)
 	if err != nil {
 		logrus.Warnf("Failed to backup some groups: %v", err)
 	}
 	if groupCount > 0 {
 		containedTypes = append(containedTypes, "group")
 		totalItems += groupCount
+		metrics.AddItems("backup", "group", groupCount)
 		logrus.Infof("  Backed up %d group(s)", groupCount)
 	}
 
 	// Step 8: Backup feedbacks
 	logrus.Info("Step 8/9: Backing up feedbacks...")
-	feedbackCount, err := backupAllFeedbacks(zipWriter, client)
+	feedbackCount, err := backupAllFeedbacks(zipWriter, client, nil)
 	if err != nil {
 		logrus.Warnf("Failed to backup some feedbacks: %v", err)
 	}
 	if feedbackCount > 0 {
 		containedTypes = append(containedTypes, "feedback")
 		totalItems += feedbackCount
+		metrics.AddItems("backup", "feedback", feedbackCount)
 		logrus.Infof("  Backed up %d feedback(s)", feedbackCount)
 	}
 
 	// Step 9: Backup users (MUST be LAST)
 	logrus.Info("Step 9/9: Backing up users...")
-	userCount, err := backupAllUsers(zipWriter, client)
+	userCount, err := backupAllUsers(zipWriter, client, nil)
 	if err != nil {
 		logrus.Warnf("Failed to backup some users: %v", err)
 	}
 	if userCount > 0 {
 		containedTypes = append(containedTypes, "user")
 		totalItems += userCount
+		metrics.AddItems("backup", "user", userCount)
 		logrus.Infof("  Backed up %d user(s)", userCount)
 	}
 
@@ -1171,7 +1276,7 @@ func BackupAll(client *openwebui.Client, outputDir string) error {
 }
 
 // backupAllKnowledgeBases backs up all knowledge bases into the unified ZIP
-func backupAllKnowledgeBases(zipWriter *zip.Writer, client *openwebui.Client) (int, error) {
+func backupAllKnowledgeBases(zipWriter *zip.Writer, client *openwebui.Client, entities entityLog) (int, error) {
 	knowledgeBases, err := client.ListKnowledge()
 	if err != nil {
 		return 0, fmt.Errorf("failed to list knowledge bases: %w", err)
@@ -1179,7 +1284,7 @@ func backupAllKnowledgeBases(zipWriter *zip.Writer, client *openwebui.Client) (i
 
 	for i, kb := range knowledgeBases {
 		logrus.Infof("  Backing up knowledge base %d/%d: %s", i+1, len(knowledgeBases), kb.Name)
-		if err := backupKnowledgeToZip(zipWriter, &kb, client); err != nil {
+		if err := backupKnowledgeToZip(zipWriter, &kb, client, entities); err != nil {
 			logrus.Warnf("  Failed to backup knowledge base '%s': %v", kb.Name, err)
 			continue
 		}
@@ -1189,7 +1294,7 @@ func backupAllKnowledgeBases(zipWriter *zip.Writer, client *openwebui.Client) (i
 }
 
 // backupKnowledgeToZip backs up a single knowledge base into an existing ZIP writer
-func backupKnowledgeToZip(zipWriter *zip.Writer, kb *openwebui.KnowledgeBase, client *openwebui.Client) error {
+func backupKnowledgeToZip(zipWriter *zip.Writer, kb *openwebui.KnowledgeBase, client *openwebui.Client, entities entityLog) error {
 	// Create knowledge-bases/{id}/ directory
 	kbDir := fmt.Sprintf("knowledge-bases/%s/", kb.ID)
 
@@ -1206,6 +1311,9 @@ func backupKnowledgeToZip(zipWriter *zip.Writer, kb *openwebui.KnowledgeBase, cl
 	if _, err := kbFile.Write(kbJSON); err != nil {
 		return fmt.Errorf("failed to write knowledge_base.json: %w", err)
 	}
+	if err := entities.add(archive.TypeKnowledge, kbDir+"knowledge_base.json", kbJSON); err != nil {
+		return err
+	}
 
 	// Collect and download files
 	var fileIDs []string
@@ -1249,7 +1357,7 @@ func backupKnowledgeToZip(zipWriter *zip.Writer, kb *openwebui.KnowledgeBase, cl
 }
 
 // backupAllModels backs up all models into the unified ZIP
-func backupAllModels(zipWriter *zip.Writer, client *openwebui.Client) (int, error) {
+func backupAllModels(zipWriter *zip.Writer, client *openwebui.Client, entities entityLog) (int, error) {
 	models, err := client.ExportModels()
 	if err != nil {
 		return 0, fmt.Errorf("failed to export models: %w", err)
@@ -1257,7 +1365,7 @@ func backupAllModels(zipWriter *zip.Writer, client *openwebui.Client) (int, erro
 
 	for i, model := range models {
 		logrus.Infof("  Backing up model %d/%d: %s", i+1, len(models), model.Name)
-		if err := backupModelToZip(zipWriter, &model, client); err != nil {
+		if err := backupModelToZip(zipWriter, &model, client, entities); err != nil {
 			logrus.Warnf("  Failed to backup model '%s': %v", model.Name, err)
 			continue
 		}
@@ -1267,7 +1375,7 @@ func backupAllModels(zipWriter *zip.Writer, client *openwebui.Client) (int, erro
 }
 
 // backupModelToZip backs up a single model into an existing ZIP writer
-func backupModelToZip(zipWriter *zip.Writer, model *openwebui.Model, client *openwebui.Client) error {
+func backupModelToZip(zipWriter *zip.Writer, model *openwebui.Model, client *openwebui.Client, entities entityLog) error {
 	// Create models/{id}/ directory
 	modelDir := fmt.Sprintf("models/%s/", model.ID)
 
@@ -1284,6 +1392,9 @@ func backupModelToZip(zipWriter *zip.Writer, model *openwebui.Model, client *ope
 	if _, err := modelFile.Write(modelJSON); err != nil {
 		return fmt.Errorf("failed to write model.json: %w", err)
 	}
+	if err := entities.add(archive.TypeModel, modelDir+"model.json", modelJSON); err != nil {
+		return err
+	}
 
 	// Backup embedded knowledge bases if present
 	if len(model.Meta.Knowledge) > 0 {
@@ -1429,7 +1540,7 @@ func backupModelCollectionItem(zipWriter *zip.Writer, modelDir string, item map[
 }
 
 // backupAllTools backs up all tools into the unified ZIP
-func backupAllTools(zipWriter *zip.Writer, client *openwebui.Client) (int, error) {
+func backupAllTools(zipWriter *zip.Writer, client *openwebui.Client, entities entityLog) (int, error) {
 	tools, err := client.ExportTools()
 	if err != nil {
 		return 0, fmt.Errorf("failed to export tools: %w", err)
@@ -1437,7 +1548,7 @@ func backupAllTools(zipWriter *zip.Writer, client *openwebui.Client) (int, error
 
 	for i, tool := range tools {
 		logrus.Infof("  Backing up tool %d/%d: %s", i+1, len(tools), tool.Name)
-		if err := backupToolToZip(zipWriter, &tool); err != nil {
+		if err := backupToolToZip(zipWriter, &tool, entities); err != nil {
 			logrus.Warnf("  Failed to backup tool '%s': %v", tool.Name, err)
 			continue
 		}
@@ -1447,7 +1558,7 @@ func backupAllTools(zipWriter *zip.Writer, client *openwebui.Client) (int, error
 }
 
 // backupToolToZip backs up a single tool into an existing ZIP writer
-func backupToolToZip(zipWriter *zip.Writer, tool *openwebui.Tool) error {
+func backupToolToZip(zipWriter *zip.Writer, tool *openwebui.Tool, entities entityLog) error {
 	// Create tools/{id}/ directory
 	toolDir := fmt.Sprintf("tools/%s/", tool.ID)
 
@@ -1464,12 +1575,15 @@ func backupToolToZip(zipWriter *zip.Writer, tool *openwebui.Tool) error {
 	if _, err := toolFile.Write(toolJSON); err != nil {
 		return fmt.Errorf("failed to write tool.json: %w", err)
 	}
+	if err := entities.add(archive.TypeTool, toolDir+"tool.json", toolJSON); err != nil {
+		return err
+	}
 
 	return nil
 }
 
 // backupAllPrompts backs up all prompts into the unified ZIP
-func backupAllPrompts(zipWriter *zip.Writer, client *openwebui.Client) (int, error) {
+func backupAllPrompts(zipWriter *zip.Writer, client *openwebui.Client, entities entityLog) (int, error) {
 	prompts, err := client.ListPrompts()
 	if err != nil {
 		return 0, fmt.Errorf("failed to list prompts: %w", err)
@@ -1477,7 +1591,7 @@ func backupAllPrompts(zipWriter *zip.Writer, client *openwebui.Client) (int, err
 
 	for i, prompt := range prompts {
 		logrus.Infof("  Backing up prompt %d/%d: %s", i+1, len(prompts), prompt.Title)
-		if err := backupPromptToZip(zipWriter, &prompt); err != nil {
+		if err := backupPromptToZip(zipWriter, &prompt, entities); err != nil {
 			logrus.Warnf("  Failed to backup prompt '%s': %v", prompt.Title, err)
 			continue
 		}
@@ -1487,7 +1601,7 @@ func backupAllPrompts(zipWriter *zip.Writer, client *openwebui.Client) (int, err
 }
 
 // backupPromptToZip backs up a single prompt into an existing ZIP writer
-func backupPromptToZip(zipWriter *zip.Writer, prompt *openwebui.Prompt) error {
+func backupPromptToZip(zipWriter *zip.Writer, prompt *openwebui.Prompt, entities entityLog) error {
 	// Create prompts/{command}/ directory
 	sanitizedCommand := sanitizeFilename(prompt.Command)
 	promptDir := fmt.Sprintf("prompts/%s/", sanitizedCommand)
@@ -1505,12 +1619,15 @@ func backupPromptToZip(zipWriter *zip.Writer, prompt *openwebui.Prompt) error {
 	if _, err := promptFile.Write(promptJSON); err != nil {
 		return fmt.Errorf("failed to write prompt.json: %w", err)
 	}
+	if err := entities.add(archive.TypePrompt, promptDir+"prompt.json", promptJSON); err != nil {
+		return err
+	}
 
 	return nil
 }
 
 // backupAllFiles backs up all files into the unified ZIP
-func backupAllFiles(zipWriter *zip.Writer, client *openwebui.Client) (int, error) {
+func backupAllFiles(zipWriter *zip.Writer, client *openwebui.Client, entities entityLog) (int, error) {
 	files, err := client.ListFiles()
 	if err != nil {
 		return 0, fmt.Errorf("failed to list files: %w", err)
@@ -1518,7 +1635,7 @@ func backupAllFiles(zipWriter *zip.Writer, client *openwebui.Client) (int, error
 
 	for i, fileMeta := range files {
 		logrus.Infof("  Backing up file %d/%d: %s", i+1, len(files), fileMeta.Meta.Name)
-		if err := backupFileToZip(zipWriter, fileMeta.ID, client); err != nil {
+		if err := backupFileToZip(zipWriter, fileMeta.ID, client, entities); err != nil {
 			logrus.Warnf("  Failed to backup file '%s': %v", fileMeta.Meta.Name, err)
 			continue
 		}
@@ -1528,7 +1645,7 @@ func backupAllFiles(zipWriter *zip.Writer, client *openwebui.Client) (int, error
 }
 
 // backupFileToZip backs up a single file into an existing ZIP writer
-func backupFileToZip(zipWriter *zip.Writer, fileID string, client *openwebui.Client) error {
+func backupFileToZip(zipWriter *zip.Writer, fileID string, client *openwebui.Client, entities entityLog) error {
 	// Get file with content
 	fileExport, err := client.GetFileWithContent(fileID)
 	if err != nil {
@@ -1551,6 +1668,9 @@ func backupFileToZip(zipWriter *zip.Writer, fileID string, client *openwebui.Cli
 	if _, err := metaFile.Write(fileJSON); err != nil {
 		return fmt.Errorf("failed to write file.json: %w", err)
 	}
+	if err := entities.add(archive.TypeFile, fileDir+"file.json", fileJSON); err != nil {
+		return err
+	}
 
 	// Add file content
 	var content []byte
@@ -1613,7 +1733,7 @@ func BackupGroups(client *openwebui.Client, outputDir string) error {
 	// Backup each group
 	for i, group := range groups {
 		logrus.Infof("Backing up group %d/%d: %s", i+1, len(groups), group.Name)
-		if err := backupGroupToZip(zipWriter, &group); err != nil {
+		if err := backupGroupToZip(zipWriter, &group, nil); err != nil {
 			logrus.Warnf("Failed to backup group '%s': %v", group.Name, err)
 			continue
 		}
@@ -1631,7 +1751,7 @@ func BackupGroups(client *openwebui.Client, outputDir string) error {
 }
 
 // backupAllGroups backs up all groups into the unified ZIP
-func backupAllGroups(zipWriter *zip.Writer, client *openwebui.Client) (int, error) {
+func backupAllGroups(zipWriter *zip.Writer, client *openwebui.Client, entities entityLog) (int, error) {
 	groups, err := client.GetAllGroups()
 	if err != nil {
 		return 0, fmt.Errorf("failed to get groups: %w", err)
@@ -1639,7 +1759,7 @@ func backupAllGroups(zipWriter *zip.Writer, client *openwebui.Client) (int, erro
 
 	for i, group := range groups {
 		logrus.Infof("  Backing up group %d/%d: %s", i+1, len(groups), group.Name)
-		if err := backupGroupToZip(zipWriter, &group); err != nil {
+		if err := backupGroupToZip(zipWriter, &group, entities); err != nil {
 			logrus.Warnf("  Failed to backup group '%s': %v", group.Name, err)
 			continue
 		}
@@ -1649,7 +1769,7 @@ func backupAllGroups(zipWriter *zip.Writer, client *openwebui.Client) (int, erro
 }
 
 // backupGroupToZip backs up a single group into an existing ZIP writer
-func backupGroupToZip(zipWriter *zip.Writer, group *openwebui.Group) error {
+func backupGroupToZip(zipWriter *zip.Writer, group *openwebui.Group, entities entityLog) error {
 	// Create groups/{id}/ directory
 	groupDir := fmt.Sprintf("groups/%s/", group.ID)
 
@@ -1666,6 +1786,9 @@ func backupGroupToZip(zipWriter *zip.Writer, group *openwebui.Group) error {
 	if _, err := groupFile.Write(groupJSON); err != nil {
 		return fmt.Errorf("failed to write group.json: %w", err)
 	}
+	if err := entities.add(archive.TypeGroup, groupDir+"group.json", groupJSON); err != nil {
+		return err
+	}
 
 	return nil
 }
@@ -1713,7 +1836,7 @@ func BackupFeedbacks(client *openwebui.Client, outputDir string) error {
 	// Backup each feedback
 	for i, feedback := range feedbacks {
 		logrus.Infof("Backing up feedback %d/%d (ID: %s)", i+1, len(feedbacks), feedback.ID)
-		if err := backupFeedbackToZip(zipWriter, &feedback); err != nil {
+		if err := backupFeedbackToZip(zipWriter, &feedback, nil); err != nil {
 			logrus.Warnf("Failed to backup feedback '%s': %v", feedback.ID, err)
 			continue
 		}
@@ -1731,7 +1854,7 @@ func BackupFeedbacks(client *openwebui.Client, outputDir string) error {
 }
 
 // backupAllFeedbacks backs up all feedbacks into the unified ZIP
-func backupAllFeedbacks(zipWriter *zip.Writer, client *openwebui.Client) (int, error) {
+func backupAllFeedbacks(zipWriter *zip.Writer, client *openwebui.Client, entities entityLog) (int, error) {
 	feedbacks, err := client.GetAllFeedbacks()
 	if err != nil {
 		return 0, fmt.Errorf("failed to get feedbacks: %w", err)
@@ -1739,7 +1862,7 @@ func backupAllFeedbacks(zipWriter *zip.Writer, client *openwebui.Client) (int, e
 
 	for i, feedback := range feedbacks {
 		logrus.Infof("  Backing up feedback %d/%d (ID: %s)", i+1, len(feedbacks), feedback.ID)
-		if err := backupFeedbackToZip(zipWriter, &feedback); err != nil {
+		if err := backupFeedbackToZip(zipWriter, &feedback, entities); err != nil {
 			logrus.Warnf("  Failed to backup feedback '%s': %v", feedback.ID, err)
 			continue
 		}
@@ -1749,7 +1872,7 @@ func backupAllFeedbacks(zipWriter *zip.Writer, client *openwebui.Client) (int, e
 }
 
 // backupFeedbackToZip backs up a single feedback into an existing ZIP writer
-func backupFeedbackToZip(zipWriter *zip.Writer, feedback *openwebui.Feedback) error {
+func backupFeedbackToZip(zipWriter *zip.Writer, feedback *openwebui.Feedback, entities entityLog) error {
 	// Create feedbacks/{id}/ directory
 	feedbackDir := fmt.Sprintf("feedbacks/%s/", feedback.ID)
 
@@ -1766,6 +1889,9 @@ func backupFeedbackToZip(zipWriter *zip.Writer, feedback *openwebui.Feedback) er
 	if _, err := feedbackFile.Write(feedbackJSON); err != nil {
 		return fmt.Errorf("failed to write feedback.json: %w", err)
 	}
+	if err := entities.add(archive.TypeFeedback, feedbackDir+"feedback.json", feedbackJSON); err != nil {
+		return err
+	}
 
 	return nil
 }
@@ -1813,7 +1939,7 @@ func BackupUsers(client *openwebui.Client, outputDir string) error {
 	// Backup each user
 	for i, user := range users {
 		logrus.Infof("Backing up user %d/%d: %s", i+1, len(users), user.Name)
-		if err := backupUserToZip(zipWriter, &user); err != nil {
+		if err := backupUserToZip(zipWriter, &user, nil); err != nil {
 			logrus.Warnf("Failed to backup user '%s': %v", user.Name, err)
 			continue
 		}
@@ -1831,7 +1957,7 @@ func BackupUsers(client *openwebui.Client, outputDir string) error {
 }
 
 // backupAllUsers backs up all users into the unified ZIP
-func backupAllUsers(zipWriter *zip.Writer, client *openwebui.Client) (int, error) {
+func backupAllUsers(zipWriter *zip.Writer, client *openwebui.Client, entities entityLog) (int, error) {
 	users, err := client.GetAllUsers()
 	if err != nil {
 		return 0, fmt.Errorf("failed to get users: %w", err)
@@ -1839,7 +1965,7 @@ func backupAllUsers(zipWriter *zip.Writer, client *openwebui.Client) (int, error
 
 	for i, user := range users {
 		logrus.Infof("  Backing up user %d/%d: %s", i+1, len(users), user.Name)
-		if err := backupUserToZip(zipWriter, &user); err != nil {
+		if err := backupUserToZip(zipWriter, &user, entities); err != nil {
 			logrus.Warnf("  Failed to backup user '%s': %v", user.Name, err)
 			continue
 		}
@@ -1849,7 +1975,7 @@ func backupAllUsers(zipWriter *zip.Writer, client *openwebui.Client) (int, error
 }
 
 // backupUserToZip backs up a single user into an existing ZIP writer
-func backupUserToZip(zipWriter *zip.Writer, user *openwebui.User) error {
+func backupUserToZip(zipWriter *zip.Writer, user *openwebui.User, entities entityLog) error {
 	// Create users/{id}/ directory
 	userDir := fmt.Sprintf("users/%s/", user.ID)
 
@@ -1866,6 +1992,9 @@ func backupUserToZip(zipWriter *zip.Writer, user *openwebui.User) error {
 	if _, err := userFile.Write(userJSON); err != nil {
 		return fmt.Errorf("failed to write user.json: %w", err)
 	}
+	if err := entities.add(archive.TypeUser, userDir+"user.json", userJSON); err != nil {
+		return err
+	}
 
 	return nil
 }
@@ -1931,108 +2060,37 @@ func writeMetadataToZip(zipWriter *zip.Writer, metadata *openwebui.BackupMetadat
 	return nil
 }
 
-// AddDatabaseToZip adds database dump and metadata to an existing ZIP file
-func AddDatabaseToZip(zipPath string, dumpData []byte, databaseName string, postgresVersion string) error {
-	// Open the existing ZIP file for reading
-	zipReader, err := zip.OpenReader(zipPath)
-	if err != nil {
-		return fmt.Errorf("failed to open ZIP file: %w", err)
-	}
-	defer zipReader.Close()
-
-	// Create a temporary file for the new ZIP
-	tempZip := zipPath + ".new"
-	tempFile, err := os.Create(tempZip)
-	if err != nil {
-		zipReader.Close()
-		return fmt.Errorf("failed to create temporary ZIP file: %w", err)
-	}
-	defer func() {
-		tempFile.Close()
-		os.Remove(tempZip) // Clean up temp file on error
-	}()
-
-	zipWriter := zip.NewWriter(tempFile)
-
-	// Copy all existing files from the old ZIP to the new ZIP
-	for _, file := range zipReader.File {
-		if err := copyZipFile(zipWriter, file); err != nil {
-			zipWriter.Close()
-			return fmt.Errorf("failed to copy file %s: %w", file.Name, err)
-		}
+// WriteDatabase streams a database dump or snapshot into a ZIP and adds its metadata
+// The entry (database/dump.sql, database/dump.pgdump or database/webui.db) follows metadata.
+// Custom format dumps are compressed by pg_dump and stored as is; other dumps are deflated
+// unless metadata says the backup is uncompressed
+func WriteDatabase(zipWriter *zip.Writer, metadata *database.DatabaseBackupMetadata, dump func(io.Writer) error) error {
+	method := zip.Deflate
+	if !metadata.Compressed || metadata.DumpFormat == "custom" {
+		method = zip.Store
 	}
-
-	// Add database dump to database/dump.sql
-	dumpFile, err := zipWriter.Create("database/dump.sql")
+	dumpFile, err := zipWriter.CreateHeader(&zip.FileHeader{
+		Name:     metadata.Entry(),
+		Method:   method,
+		Modified: time.Now(),
+	})
 	if err != nil {
-		zipWriter.Close()
-		return fmt.Errorf("failed to create dump.sql in zip: %w", err)
-	}
-	if _, err := dumpFile.Write(dumpData); err != nil {
-		zipWriter.Close()
-		return fmt.Errorf("failed to write dump.sql: %w", err)
+		return fmt.Errorf("failed to create %s in zip: %w", metadata.Entry(), err)
 	}
-
-	// Add database metadata
-	metadata := map[string]interface{}{
-		"backup_timestamp": time.Now().UTC().Format(time.RFC3339),
-		"database_name":    databaseName,
-		"postgres_version": postgresVersion,
-		"dump_format":      "plain",
-		"compressed":       false,
+	if err := dump(dumpFile); err != nil {
+		return err
 	}
 
 	metadataJSON, err := json.MarshalIndent(metadata, "", "  ")
 	if err != nil {
-		zipWriter.Close()
 		return fmt.Errorf("failed to marshal database metadata: %w", err)
 	}
-
-	metadataFile, err := zipWriter.Create("database/metadata.json")
+	metadataFile, err := zipWriter.Create(database.MetadataEntry)
 	if err != nil {
-		zipWriter.Close()
 		return fmt.Errorf("failed to create metadata.json in zip: %w", err)
 	}
 	if _, err := metadataFile.Write(metadataJSON); err != nil {
-		zipWriter.Close()
 		return fmt.Errorf("failed to write metadata.json: %w", err)
 	}
-
-	// Close the new ZIP
-	if err := zipWriter.Close(); err != nil {
-		return fmt.Errorf("failed to close new ZIP: %w", err)
-	}
-	tempFile.Close()
-	zipReader.Close()
-
-	// Replace the old ZIP with the new one
-	if err := os.Rename(tempZip, zipPath); err != nil {
-		return fmt.Errorf("failed to replace old ZIP: %w", err)
-	}
-
-	logrus.Debug("Database backup added to ZIP successfully")
-	return nil
-}
-
-// copyZipFile copies a file from one ZIP archive to another
-func copyZipFile(zipWriter *zip.Writer, file *zip.File) error {
-	// Open the file in the source ZIP
-	reader, err := file.Open()
-	if err != nil {
-		return err
-	}
-	defer reader.Close()
-
-	// Create the file in the destination ZIP
-	writer, err := zipWriter.Create(file.Name)
-	if err != nil {
-		return err
-	}
-
-	// Copy the content
-	if _, err := io.Copy(writer, reader); err != nil {
-		return err
-	}
-
 	return nil
 }
@@ -0,0 +1,252 @@
+package chatexport
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/vosiander/open-webui-backup/pkg/archive"
+	"github.com/vosiander/open-webui-backup/pkg/openwebui"
+)
+
+// Export formats
+const (
+	FormatMarkdown = "markdown"
+	FormatHTML     = "html"
+	FormatJSONL    = "jsonl"
+)
+
+// Source holds the chats to export and the users they belong to
+type Source struct {
+	Chats []openwebui.Chat
+	Users map[string]openwebui.User
+}
+
+// Filter selects which chats are exported
+// Empty fields match everything
+type Filter struct {
+	Users   []string  // user ID, email or name
+	Since   time.Time // created at or after
+	Until   time.Time // created before
+	Models  []string  // model IDs, globs allowed
+	Folders []string  // folder IDs
+	Query   string    // case-insensitive match on title and message content
+}
+
+// FromArchive reads chats and users from an unencrypted unified backup ZIP
+func FromArchive(zipPath string) (*Source, error) {
+	a, err := archive.Load(zipPath)
+	if err != nil {
+		return nil, err
+	}
+
+	source := &Source{Users: make(map[string]openwebui.User)}
+
+	for _, entity := range a.Entities[archive.TypeChat] {
+		var chat openwebui.Chat
+		if err := json.Unmarshal(entity.Data, &chat); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", entity.Path, err)
+		}
+		source.Chats = append(source.Chats, chat)
+	}
+
+	for _, entity := range a.Entities[archive.TypeUser] {
+		var user openwebui.User
+		if err := json.Unmarshal(entity.Data, &user); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", entity.Path, err)
+		}
+		source.Users[user.ID] = user
+	}
+
+	return source, nil
+}
+
+// FromLive fetches chats from the live instance
+// With allUsers the admin endpoint is used to export every user's chats
+func FromLive(client *openwebui.Client, allUsers bool) (*Source, error) {
+	var chats []openwebui.Chat
+	var err error
+	if allUsers {
+		chats, err = client.GetAllChatsDB()
+	} else {
+		chats, err = client.GetAllChats()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch chats: %w", err)
+	}
+
+	source := &Source{Chats: chats, Users: make(map[string]openwebui.User)}
+
+	// User names are only used for display and filtering, so a non-admin key is not fatal
+	users, err := client.GetAllUsers()
+	if err != nil {
+		logrus.Warnf("Failed to fetch users: %v. Exporting without user names.", err)
+		return source, nil
+	}
+	for _, user := range users {
+		source.Users[user.ID] = user
+	}
+
+	return source, nil
+}
+
+// Apply returns the chats matching the filter, oldest first
+func (s *Source) Apply(filter *Filter) []openwebui.Chat {
+	var matched []openwebui.Chat
+	for _, chat := range s.Chats {
+		if filter.Match(&chat, s.Users) {
+			matched = append(matched, chat)
+		}
+	}
+
+	sort.SliceStable(matched, func(i, j int) bool {
+		return matched[i].CreatedAt < matched[j].CreatedAt
+	})
+	return matched
+}
+
+// Match reports whether a chat passes the filter
+func (f *Filter) Match(chat *openwebui.Chat, users map[string]openwebui.User) bool {
+	if len(f.Users) > 0 && !matchUser(f.Users, chat.UserID, users) {
+		return false
+	}
+
+	created := time.Unix(chat.CreatedAt, 0)
+	if !f.Since.IsZero() && created.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && !created.Before(f.Until) {
+		return false
+	}
+
+	if len(f.Models) > 0 && !matchModel(f.Models, ChatModels(chat)) {
+		return false
+	}
+
+	if len(f.Folders) > 0 {
+		if chat.FolderID == nil || !contains(f.Folders, *chat.FolderID) {
+			return false
+		}
+	}
+
+	if f.Query != "" && !matchQuery(strings.ToLower(f.Query), chat) {
+		return false
+	}
+
+	return true
+}
+
+// ChatModels returns the models used in a chat, in order of first use
+func ChatModels(chat *openwebui.Chat) []string {
+	seen := make(map[string]bool)
+	var models []string
+	add := func(model string) {
+		if model != "" && !seen[model] {
+			seen[model] = true
+			models = append(models, model)
+		}
+	}
+
+	for _, model := range chat.Chat.Models {
+		add(model)
+	}
+	for _, message := range chat.Chat.Messages {
+		add(message.Model)
+	}
+	return models
+}
+
+// UserLabel returns a display name for a user ID
+func UserLabel(userID string, users map[string]openwebui.User) string {
+	user, ok := users[userID]
+	if !ok {
+		return userID
+	}
+	if user.Email != "" {
+		return fmt.Sprintf("%s <%s>", user.Name, user.Email)
+	}
+	return user.Name
+}
+
+// FileName returns a file name for an exported chat
+func FileName(chat *openwebui.Chat, ext string) string {
+	id := chat.ID
+	if len(id) > 8 {
+		id = id[:8]
+	}
+	return slugify(chat.Title) + "-" + id + ext
+}
+
+// matchUser matches a user ID against IDs, emails or names
+func matchUser(values []string, userID string, users map[string]openwebui.User) bool {
+	user := users[userID]
+	for _, value := range values {
+		if value == userID || (user.Email != "" && strings.EqualFold(value, user.Email)) ||
+			(user.Name != "" && strings.EqualFold(value, user.Name)) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchModel matches used models against model patterns
+func matchModel(patterns []string, models []string) bool {
+	for _, pattern := range patterns {
+		for _, model := range models {
+			if matched, _ := path.Match(pattern, model); matched {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchQuery searches the title and message contents
+func matchQuery(query string, chat *openwebui.Chat) bool {
+	if strings.Contains(strings.ToLower(chat.Title), query) {
+		return true
+	}
+	for _, message := range chat.Chat.Messages {
+		if strings.Contains(strings.ToLower(message.Content), query) {
+			return true
+		}
+	}
+	return false
+}
+
+// contains checks if a string slice contains a specific string
+func contains(slice []string, str string) bool {
+	for _, s := range slice {
+		if s == str {
+			return true
+		}
+	}
+	return false
+}
+
+var slugPattern = regexp.MustCompile("[^a-z0-9]+")
+
+// slugify turns a chat title into a file-system friendly name
+func slugify(title string) string {
+	slug := strings.Trim(slugPattern.ReplaceAllString(strings.ToLower(title), "-"), "-")
+	if len(slug) > 60 {
+		slug = strings.TrimRight(slug[:60], "-")
+	}
+	if slug == "" {
+		slug = "chat"
+	}
+	return slug
+}
+
+// formatTime renders a Unix timestamp for exports
+func formatTime(ts int64) string {
+	if ts == 0 {
+		return ""
+	}
+	return time.Unix(ts, 0).UTC().Format("2006-01-02 15:04:05 UTC")
+}
@@ -0,0 +1,125 @@
+package chatexport
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+
+	"github.com/vosiander/open-webui-backup/pkg/openwebui"
+)
+
+// htmlTemplate renders a standalone page; branches are nested <details> blocks
+var htmlTemplate = template.Must(template.New("chat").Funcs(template.FuncMap{
+	"role":  roleLabel,
+	"time":  formatTime,
+	"trim":  strings.TrimSpace,
+	"add":   func(a, b int) int { return a + b },
+	"label": branchLabel,
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", Roboto, sans-serif; max-width: 860px; margin: 2rem auto; padding: 0 1rem; color: #212529; }
+h1 { font-size: 1.5rem; }
+.meta { color: #6c757d; font-size: 0.875rem; margin-bottom: 2rem; }
+.meta dt { font-weight: 600; float: left; clear: left; width: 7rem; }
+.meta dd { margin: 0 0 0.25rem 7rem; }
+.message { border: 1px solid #e9ecef; border-radius: 8px; padding: 0.75rem 1rem; margin: 0.75rem 0; }
+.message.user { background: #f1f3ff; }
+.message.assistant { background: #ffffff; }
+.message.system { background: #fff8e1; }
+.message header { font-size: 0.8125rem; font-weight: 600; color: #495057; margin-bottom: 0.5rem; }
+.message header span { font-weight: normal; color: #6c757d; }
+.content { white-space: pre-wrap; word-wrap: break-word; line-height: 1.5; }
+details.branch { border-left: 3px solid #667eea; padding-left: 0.75rem; margin: 0.75rem 0; }
+details.branch > summary { cursor: pointer; color: #667eea; font-weight: 600; font-size: 0.875rem; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<dl class="meta">
+<dt>Chat ID</dt><dd>{{.Chat.ID}}</dd>
+{{- if .User}}<dt>User</dt><dd>{{.User}}</dd>{{end}}
+<dt>Created</dt><dd>{{time .Chat.CreatedAt}}</dd>
+<dt>Updated</dt><dd>{{time .Chat.UpdatedAt}}</dd>
+{{- if .Models}}<dt>Models</dt><dd>{{.Models}}</dd>{{end}}
+{{- if .Chat.FolderID}}<dt>Folder</dt><dd>{{.Chat.FolderID}}</dd>{{end}}
+{{- if gt .Branches 1}}<dt>Branches</dt><dd>{{.Branches}}</dd>{{end}}
+</dl>
+{{template "thread" .Thread}}
+</body>
+</html>
+{{define "thread"}}
+{{- range .Linear}}
+<article class="message {{.Role}}">
+<header>{{role .Role}}{{if and .Model (eq .Role "assistant")}} <span>{{.Model}}</span>{{end}}{{with time .Timestamp}} <span>· {{.}}</span>{{end}}</header>
+<div class="content">{{trim .Content}}</div>
+</article>
+{{- end}}
+{{- $label := .Label}}{{$count := len .Branches}}
+{{- range $i, $branch := .Branches}}
+<details class="branch" open>
+<summary>Branch {{label $label (add $i 1)}} ({{add $i 1}} of {{$count}})</summary>
+{{template "thread" $branch}}
+</details>
+{{- end}}
+{{end}}`))
+
+// htmlThread is a linear run of messages followed by the branches it forks into
+type htmlThread struct {
+	Label    string
+	Linear   []openwebui.Message
+	Branches []htmlThread
+}
+
+// WriteHTML renders a chat as a standalone HTML page
+func WriteHTML(w io.Writer, chat *openwebui.Chat, users map[string]openwebui.User) error {
+	roots := BuildTree(chat.Chat.Messages)
+
+	data := struct {
+		Title    string
+		Chat     *openwebui.Chat
+		User     string
+		Models   string
+		Branches int
+		Thread   htmlThread
+	}{
+		Title:    titleOf(chat),
+		Chat:     chat,
+		Models:   strings.Join(ChatModels(chat), ", "),
+		Branches: CountBranches(roots),
+		Thread:   buildHTMLThread(roots, ""),
+	}
+	if chat.UserID != "" {
+		data.User = UserLabel(chat.UserID, users)
+	}
+
+	if err := htmlTemplate.Execute(w, data); err != nil {
+		return fmt.Errorf("failed to render chat %s: %w", chat.ID, err)
+	}
+	return nil
+}
+
+// buildHTMLThread converts sibling nodes into the nested template structure
+func buildHTMLThread(nodes []*Node, label string) htmlThread {
+	thread := htmlThread{Label: label}
+	for len(nodes) == 1 {
+		thread.Linear = append(thread.Linear, nodes[0].Message)
+		nodes = nodes[0].Children
+	}
+	for i, node := range nodes {
+		thread.Branches = append(thread.Branches, buildHTMLThread([]*Node{node}, branchLabel(label, i+1)))
+	}
+	return thread
+}
+
+// branchLabel returns the dotted label of a branch, e.g. "2.1"
+func branchLabel(parent string, index int) string {
+	if parent == "" {
+		return fmt.Sprintf("%d", index)
+	}
+	return fmt.Sprintf("%s.%d", parent, index)
+}
@@ -0,0 +1,25 @@
+package chatexport
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/vosiander/open-webui-backup/pkg/openwebui"
+)
+
+// WriteJSONL writes one chat per line in the Open WebUI chat format
+func WriteJSONL(w io.Writer, chats []openwebui.Chat) error {
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	enc.SetEscapeHTML(false)
+
+	for i := range chats {
+		if err := enc.Encode(&chats[i]); err != nil {
+			return fmt.Errorf("failed to encode chat %s: %w", chats[i].ID, err)
+		}
+	}
+
+	return bw.Flush()
+}
@@ -0,0 +1,89 @@
+package chatexport
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/vosiander/open-webui-backup/pkg/openwebui"
+)
+
+// WriteMarkdown renders a chat as Markdown
+// Branches are rendered one after another below the message they fork from
+func WriteMarkdown(w io.Writer, chat *openwebui.Chat, users map[string]openwebui.User) error {
+	bw := bufio.NewWriter(w)
+	roots := BuildTree(chat.Chat.Messages)
+
+	fmt.Fprintf(bw, "# %s\n\n", titleOf(chat))
+	fmt.Fprintf(bw, "- **Chat ID:** %s\n", chat.ID)
+	if chat.UserID != "" {
+		fmt.Fprintf(bw, "- **User:** %s\n", UserLabel(chat.UserID, users))
+	}
+	fmt.Fprintf(bw, "- **Created:** %s\n", formatTime(chat.CreatedAt))
+	fmt.Fprintf(bw, "- **Updated:** %s\n", formatTime(chat.UpdatedAt))
+	if models := ChatModels(chat); len(models) > 0 {
+		fmt.Fprintf(bw, "- **Models:** %s\n", strings.Join(models, ", "))
+	}
+	if chat.FolderID != nil {
+		fmt.Fprintf(bw, "- **Folder:** %s\n", *chat.FolderID)
+	}
+	if branches := CountBranches(roots); branches > 1 {
+		fmt.Fprintf(bw, "- **Branches:** %d\n", branches)
+	}
+	fmt.Fprintln(bw)
+
+	writeMarkdownThread(bw, roots, "")
+
+	return bw.Flush()
+}
+
+// writeMarkdownThread writes a list of sibling nodes and their descendants
+func writeMarkdownThread(w *bufio.Writer, nodes []*Node, label string) {
+	for len(nodes) == 1 {
+		writeMarkdownMessage(w, &nodes[0].Message)
+		nodes = nodes[0].Children
+	}
+
+	for i, node := range nodes {
+		branch := branchLabel(label, i+1)
+		fmt.Fprintf(w, "---\n\n> **Branch %s** (%d of %d)\n\n", branch, i+1, len(nodes))
+		writeMarkdownThread(w, []*Node{node}, branch)
+	}
+}
+
+// writeMarkdownMessage writes a single message
+func writeMarkdownMessage(w *bufio.Writer, message *openwebui.Message) {
+	heading := roleLabel(message.Role)
+	if message.Model != "" && message.Role == "assistant" {
+		heading += " (" + message.Model + ")"
+	}
+	if ts := formatTime(message.Timestamp); ts != "" {
+		heading += " · " + ts
+	}
+
+	fmt.Fprintf(w, "### %s\n\n%s\n\n", heading, strings.TrimSpace(message.Content))
+}
+
+// roleLabel returns a display label for a message role
+func roleLabel(role string) string {
+	switch role {
+	case "user":
+		return "User"
+	case "assistant":
+		return "Assistant"
+	case "system":
+		return "System"
+	case "":
+		return "Message"
+	}
+	return strings.ToUpper(role[:1]) + role[1:]
+}
+
+// titleOf returns the chat title or a placeholder
+func titleOf(chat *openwebui.Chat) string {
+	if chat.Title == "" {
+		return "Untitled chat"
+	}
+	return chat.Title
+}
@@ -0,0 +1,98 @@
+package chatexport
+
+import (
+	"github.com/vosiander/open-webui-backup/pkg/openwebui"
+)
+
+// Node is a message with its replies
+// Regenerated responses and edited prompts create sibling nodes (branches)
+type Node struct {
+	Message  openwebui.Message
+	Children []*Node
+}
+
+// BuildTree links messages through ParentID/ChildrenIDs and returns the root nodes
+// Messages without IDs (older exports) are treated as a linear conversation
+func BuildTree(messages []openwebui.Message) []*Node {
+	nodes := make(map[string]*Node, len(messages))
+	ordered := make([]*Node, 0, len(messages))
+	for _, message := range messages {
+		node := &Node{Message: message}
+		ordered = append(ordered, node)
+		if message.ID != "" {
+			nodes[message.ID] = node
+		}
+	}
+
+	var roots []*Node
+	var previous *Node
+	linked := make(map[*Node]bool)
+
+	for _, node := range ordered {
+		message := node.Message
+
+		switch {
+		case message.ID == "":
+			// No tree information: continue the linear conversation
+			if previous != nil {
+				previous.Children = append(previous.Children, node)
+			} else {
+				roots = append(roots, node)
+			}
+			linked[node] = true
+		case message.ParentID != nil && *message.ParentID != message.ID && nodes[*message.ParentID] != nil:
+			// Attached below once the parent's children are ordered
+		default:
+			roots = append(roots, node)
+			linked[node] = true
+		}
+		previous = node
+	}
+
+	// Children follow the parent's ChildrenIDs order, then message order for the rest
+	for _, parent := range ordered {
+		if parent.Message.ID == "" {
+			continue
+		}
+		for _, childID := range parent.Message.ChildrenIDs {
+			child := nodes[childID]
+			if child == nil || linked[child] || !isChildOf(child, parent) {
+				continue
+			}
+			parent.Children = append(parent.Children, child)
+			linked[child] = true
+		}
+	}
+	for _, node := range ordered {
+		if linked[node] {
+			continue
+		}
+		parent := nodes[*node.Message.ParentID]
+		parent.Children = append(parent.Children, node)
+		linked[node] = true
+	}
+
+	return roots
+}
+
+// isChildOf reports whether child names parent as its parent
+func isChildOf(child, parent *Node) bool {
+	return child.Message.ParentID != nil && *child.Message.ParentID == parent.Message.ID
+}
+
+// CountBranches returns the number of leaf messages, i.e. distinct conversation paths
+func CountBranches(roots []*Node) int {
+	count := 0
+	var walk func(nodes []*Node)
+	walk = func(nodes []*Node) {
+		for _, node := range nodes {
+			if len(node.Children) == 0 {
+				count++
+				continue
+			}
+			walk(node.Children)
+		}
+	}
+	walk(roots)
+	return count
+}
@@ -0,0 +1,101 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/vosiander/open-webui-backup/pkg/database"
+	"github.com/vosiander/open-webui-backup/pkg/openwebui"
+)
+
+// Exit codes of owuicli
+const (
+	ExitOK           = 0 // success
+	ExitError        = 1 // general failure
+	ExitUsage        = 2 // invalid flags or arguments
+	ExitAuth         = 3 // Open WebUI rejected the API key or PostgreSQL the credentials
+	ExitPartial      = 4 // finished, but some items failed
+	ExitVerification = 5 // signature, manifest, decryption or content check failed
+	ExitAborted      = 6 // interrupted by the user
+)
+
+// ErrAborted is returned when the user interrupts a command before it changes anything
+var ErrAborted = WithCode(ExitAborted, errors.New("aborted by user"))
+
+// codedError carries the exit code for an error
+type codedError struct {
+	Code int
+	Err  error
+}
+
+func (e *codedError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *codedError) Unwrap() error {
+	return e.Err
+}
+
+// WithCode attaches an exit code to an error
+func WithCode(code int, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &codedError{Code: code, Err: err}
+}
+
+// Usage marks an error as caused by invalid flags or arguments
+func Usage(err error) error {
+	return WithCode(ExitUsage, err)
+}
+
+// Partial marks a command that finished with some failed items
+func Partial(err error) error {
+	return WithCode(ExitPartial, err)
+}
+
+// Partialf formats a partial success error
+func Partialf(format string, args ...interface{}) error {
+	return Partial(fmt.Errorf(format, args...))
+}
+
+// Verification marks a failed integrity or authenticity check
+func Verification(err error) error {
+	return WithCode(ExitVerification, err)
+}
+
+// Code returns the exit code for an error
+// API errors with status 401 or 403 and rejected database credentials map to ExitAuth
+// unless a code was set explicitly
+func Code(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+	var coded *codedError
+	if errors.As(err, &coded) {
+		return coded.Code
+	}
+	var apiErr *openwebui.APIError
+	if errors.As(err, &apiErr) && (apiErr.StatusCode == http.StatusUnauthorized || apiErr.StatusCode == http.StatusForbidden) {
+		return ExitAuth
+	}
+	if errors.Is(err, database.ErrAuthentication) {
+		return ExitAuth
+	}
+	return ExitError
+}
+
+// Status returns the envelope status for an error
+func Status(err error) string {
+	switch Code(err) {
+	case ExitOK:
+		return StatusOK
+	case ExitPartial:
+		return StatusPartial
+	case ExitAborted:
+		return StatusAborted
+	default:
+		return StatusFailed
+	}
+}
@@ -0,0 +1,110 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// Output formats
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// Command status values
+const (
+	StatusOK      = "ok"
+	StatusPartial = "partial"
+	StatusFailed  = "failed"
+	StatusAborted = "aborted"
+)
+
+var (
+	mu     sync.Mutex
+	format = FormatText
+	result interface{}
+)
+
+// SetFormat selects the output format
+func SetFormat(f string) error {
+	switch f {
+	case "", FormatText:
+		f = FormatText
+	case FormatJSON:
+	default:
+		return Usage(fmt.Errorf("unknown output format %q (use text or json)", f))
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	format = f
+	return nil
+}
+
+// JSON reports whether the result is printed as JSON
+// Commands must not write anything else to stdout in this mode
+func JSON() bool {
+	mu.Lock()
+	defer mu.Unlock()
+	return format == FormatJSON
+}
+
+// SetResult records the result object of the running command
+// It is printed on stdout with --output json and ignored otherwise
+func SetResult(v interface{}) {
+	mu.Lock()
+	defer mu.Unlock()
+	result = v
+}
+
+// TakeResult returns and clears the recorded result
+func TakeResult() interface{} {
+	mu.Lock()
+	defer mu.Unlock()
+	v := result
+	result = nil
+	return v
+}
+
+// Envelope is the JSON document printed for every command
+type Envelope struct {
+	Command    string      `json:"command"`
+	Status     string      `json:"status"`
+	ExitCode   int         `json:"exitCode"`
+	Error      string      `json:"error,omitempty"`
+	StartedAt  *time.Time  `json:"startedAt,omitempty"`
+	FinishedAt time.Time   `json:"finishedAt"`
+	Duration   float64     `json:"durationSeconds"`
+	Result     interface{} `json:"result,omitempty"`
+}
+
+// NewEnvelope describes a command that started at start and ended with err
+// A zero start means the command never ran (e.g. invalid flags)
+func NewEnvelope(command string, start time.Time, err error, result interface{}) *Envelope {
+	now := time.Now().UTC()
+	e := &Envelope{
+		Command:    command,
+		Status:     Status(err),
+		ExitCode:   Code(err),
+		FinishedAt: now,
+		Result:     result,
+	}
+	if !start.IsZero() {
+		started := start.UTC()
+		e.StartedAt = &started
+		e.Duration = now.Sub(start).Seconds()
+	}
+	if err != nil {
+		e.Error = err.Error()
+	}
+	return e
+}
+
+// Write prints the envelope as indented JSON
+func (e *Envelope) Write(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(e)
+}
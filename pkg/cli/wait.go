@@ -0,0 +1,26 @@
+package cli
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// Wait sleeps for d and returns ErrAborted if the user presses Ctrl+C in the meantime
+// Destructive commands use it as their last chance to cancel
+func Wait(d time.Duration) error {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-signals:
+		return ErrAborted
+	}
+}
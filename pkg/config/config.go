@@ -15,6 +15,9 @@ type Config struct {
 	PostgresURL     string
 	ServerPort      int
 	BackupsDir      string
+	MetricsPushURL  string // Pushgateway-compatible endpoint the CLI pushes metrics to after each command
+	NotifyConfig    string // notification sinks config file
+	Output          string // CLI output format: text or json
 }
 
 // Load loads configuration from environment variables
@@ -25,6 +28,9 @@ func Load() *Config {
 		PostgresURL:     getEnv("POSTGRES_URL", ""),
 		ServerPort:      getEnvInt("OWUI_SERVER_PORT", 3000),
 		BackupsDir:      getEnv("OWUI_BACKUPS_DIR", "./backups"),
+		MetricsPushURL:  getEnv("OWUI_METRICS_PUSH_URL", ""),
+		NotifyConfig:    getEnv("OWUI_NOTIFY_CONFIG", ""),
+		Output:          getEnv("OWUI_OUTPUT", "text"),
 	}
 }
 
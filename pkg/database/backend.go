@@ -0,0 +1,82 @@
+package database
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Dump and restore backends
+const (
+	BackendAuto   = "auto"   // docker if USE_DOCKER_PG_TOOLS is set, tools if installed, native otherwise
+	BackendTools  = "tools"  // local pg_dump, pg_restore and psql binaries
+	BackendDocker = "docker" // pg_dump and psql in a postgres container matching the server version
+	BackendNative = "native" // built-in client speaking the PostgreSQL wire protocol
+)
+
+// Backends lists the valid backend names
+var Backends = []string{BackendAuto, BackendTools, BackendDocker, BackendNative}
+
+// GetBackend returns the backend selected by the OWUI_PG_BACKEND environment variable
+func GetBackend() string {
+	if backend := os.Getenv("OWUI_PG_BACKEND"); backend != "" {
+		return strings.ToLower(backend)
+	}
+	return BackendAuto
+}
+
+// ValidateBackend checks that name is a known backend
+func ValidateBackend(name string) error {
+	for _, b := range Backends {
+		if name == b {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown PostgreSQL backend %q (use %s)", name, strings.Join(Backends, ", "))
+}
+
+// ResolveBackend returns the concrete backend used for config
+func ResolveBackend(config *DatabaseConfig) string {
+	backend := BackendAuto
+	if config != nil && config.Backend != "" {
+		backend = config.Backend
+	}
+	if backend != BackendAuto {
+		return backend
+	}
+
+	if UseDockerPgTools() {
+		return BackendDocker
+	}
+	if _, err := exec.LookPath(GetPgDumpPath()); err == nil {
+		if _, err := exec.LookPath(GetPsqlPath()); err == nil {
+			return BackendTools
+		}
+	}
+	logrus.Debug("PostgreSQL client tools not found, using the native backend")
+	return BackendNative
+}
+
+// CheckBackendAvailable verifies that the backend selected for config can run
+func CheckBackendAvailable(config *DatabaseConfig) error {
+	backend := ResolveBackend(config)
+	if err := ValidateBackend(backend); err != nil {
+		return err
+	}
+
+	switch backend {
+	case BackendNative:
+		return nil
+	case BackendDocker:
+		if !IsDockerAvailable() {
+			return fmt.Errorf("Docker is not available. Install Docker or set OWUI_PG_BACKEND=native")
+		}
+		// Connection tests and purges still use the local psql
+		return CheckToolsAvailable()
+	default:
+		return CheckToolsAvailable()
+	}
+}
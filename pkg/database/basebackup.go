@@ -0,0 +1,140 @@
+package database
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// BaseBackupOptions configures pg_basebackup
+type BaseBackupOptions struct {
+	Label   string // backup label shown in pg_stat_progress_basebackup and backup_label
+	Verbose bool
+}
+
+// ArchiveSettings are the server settings that point-in-time recovery depends on
+type ArchiveSettings struct {
+	WALLevel       string
+	ArchiveMode    string
+	ArchiveCommand string // empty if the user may not read it
+}
+
+// GetPgBasebackupPath returns the path to the pg_basebackup binary
+// Checks PG_BASEBACKUP_BINARY environment variable, defaults to /usr/local/bin/pg_basebackup
+func GetPgBasebackupPath() string {
+	if path := os.Getenv("PG_BASEBACKUP_BINARY"); path != "" {
+		return path
+	}
+	return "/usr/local/bin/pg_basebackup"
+}
+
+// GetArchiveSettings reads wal_level, archive_mode and archive_command from the server
+func GetArchiveSettings(config *DatabaseConfig) (*ArchiveSettings, error) {
+	session, err := OpenSession(config)
+	if err != nil {
+		return nil, err
+	}
+	defer session.Close()
+
+	rows, err := session.Query("SELECT current_setting('wal_level'), current_setting('archive_mode')")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read archive settings: %w", err)
+	}
+	if len(rows) == 0 || len(rows[0]) < 2 {
+		return nil, fmt.Errorf("failed to read archive settings: no result")
+	}
+	settings := &ArchiveSettings{WALLevel: rows[0][0], ArchiveMode: rows[0][1]}
+
+	// archive_command is only visible to superusers and pg_read_all_settings members
+	if rows, err := session.Query("SELECT current_setting('archive_command')"); err == nil && len(rows) > 0 {
+		settings.ArchiveCommand = rows[0][0]
+	} else if err != nil {
+		logrus.Debugf("Failed to read archive_command: %v", err)
+	}
+	return settings, nil
+}
+
+// CreateBaseBackup runs pg_basebackup into dir in tar format
+// The output is base.tar.gz, pg_wal.tar.gz with the WAL needed to make it consistent,
+// one <oid>.tar.gz per tablespace and backup_manifest on PostgreSQL 13 and newer
+func CreateBaseBackup(config *DatabaseConfig, dir string, options *BaseBackupOptions) error {
+	if config == nil {
+		return fmt.Errorf("database config is nil")
+	}
+	if options == nil {
+		options = &BaseBackupOptions{}
+	}
+
+	args := []string{
+		"-p", strconv.Itoa(config.Port),
+		"-U", config.User,
+		"-Ft", "-z",
+		"-X", "fetch",
+		"--checkpoint=fast",
+		"--no-password",
+	}
+	if options.Label != "" {
+		args = append(args, "-l", options.Label)
+	}
+	if options.Verbose {
+		args = append(args, "-v")
+	}
+
+	var cmd *exec.Cmd
+	switch ResolveBackend(config) {
+	case BackendNative:
+		return fmt.Errorf("base backups need pg_basebackup; install the PostgreSQL client tools or set OWUI_PG_BACKEND=docker")
+	case BackendDocker:
+		if !IsDockerAvailable() {
+			return fmt.Errorf("Docker is not available. Install Docker or set USE_DOCKER_PG_TOOLS=false")
+		}
+		serverVersion, err := GetPostgresVersion(config)
+		if err != nil {
+			logrus.Warnf("Failed to detect server version, using 'latest': %v", err)
+			serverVersion = "latest"
+		}
+		dockerImage := fmt.Sprintf("postgres:%s", ExtractMajorVersion(serverVersion))
+		logrus.Infof("Creating base backup of %s using Docker image %s...", config.Host, dockerImage)
+
+		dockerArgs := []string{"run", "--rm"}
+		if NeedsDockerHostNetwork(config.Host) {
+			dockerArgs = append(dockerArgs, "--network=host")
+		}
+		// Run as the current user so the backup files can be read and removed afterwards
+		dockerArgs = append(dockerArgs,
+			"--user", fmt.Sprintf("%d:%d", os.Getuid(), os.Getgid()),
+			"-v", dir+":/backup",
+			"-e", fmt.Sprintf("PGPASSWORD=%s", config.Password),
+			dockerImage,
+			"pg_basebackup", "-h", ResolveDockerHost(config.Host), "-D", "/backup",
+		)
+		cmd = exec.Command("docker", append(dockerArgs, args...)...)
+	default:
+		logrus.Infof("Creating base backup of %s...", config.Host)
+		cmd = exec.Command(GetPgBasebackupPath(), append([]string{"-h", config.Host, "-D", dir}, args...)...)
+		cmd.Env = append(cmd.Env, fmt.Sprintf("PGPASSWORD=%s", config.Password))
+	}
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		output := stderr.String()
+		if strings.Contains(output, "password authentication failed") {
+			return fmt.Errorf("%w: %s", ErrAuthentication, strings.TrimSpace(output))
+		}
+		return fmt.Errorf("pg_basebackup failed: %w\nError output: %s", err, output)
+	}
+	if stderr.Len() > 0 {
+		if options.Verbose {
+			logrus.Infof("pg_basebackup output: %s", stderr.String())
+		} else {
+			logrus.Debugf("pg_basebackup output: %s", stderr.String())
+		}
+	}
+	return nil
+}
@@ -1,8 +1,11 @@
 package database
 
 import (
+	"bufio"
 	"bytes"
+	"errors"
 	"fmt"
+	"io"
 	"net/url"
 	"os/exec"
 	"strconv"
@@ -11,25 +14,33 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
+// ErrAuthentication is returned when PostgreSQL rejects the credentials
+var ErrAuthentication = errors.New("database authentication failed")
+
 // DatabaseConfig holds PostgreSQL connection details
 type DatabaseConfig struct {
-	URL      string // Full connection string
-	Host     string
-	Port     int
-	Database string
-	User     string
-	Password string
+	URL         string // Full connection string
+	Host        string
+	Port        int
+	Database    string
+	User        string
+	Password    string
+	SSLMode     string // sslmode query parameter, used by the native backend
+	SSLRootCert string // sslrootcert query parameter, used by the native backend
+	Backend     string // dump and restore backend, see GetBackend
 }
 
 // DumpOptions configures pg_dump behavior
 type DumpOptions struct {
 	Format       string // "plain" for SQL, "custom" for pg_restore format
 	Verbose      bool
-	Compress     int // 0-9 compression level (for custom format)
+	Compress     int  // 0-9 compression level (for custom format)
+	NoCompress   bool // write the custom format without compression (-Z0)
 	SchemaOnly   bool
 	DataOnly     bool
 	NoOwner      bool
 	NoPrivileges bool
+	Snapshot     string // exported snapshot to dump from, see ExportSnapshot
 }
 
 // RestoreOptions configures pg_restore behavior
@@ -38,15 +49,56 @@ type RestoreOptions struct {
 	NoOwner      bool
 	NoPrivileges bool
 	Verbose      bool
+
+	Tables        []string // restore only these tables (name or schema.name, shell wildcards), see RestoreTables
+	ExcludeTables []string // restore all tables except these
+	SideSchema    string   // load the tables into this new schema instead of replacing the live ones
 }
 
+// Database engines stored in a backup
+const (
+	EnginePostgres = "postgres"
+	EngineSQLite   = "sqlite"
+)
+
+// Entries of the database section in a backup ZIP
+const (
+	DumpEntry       = "database/dump.sql"
+	CustomDumpEntry = "database/dump.pgdump"
+	SQLiteEntry     = "database/webui.db"
+	MetadataEntry   = "database/metadata.json"
+)
+
 // DatabaseBackupMetadata tracks database backup information
 type DatabaseBackupMetadata struct {
-	BackupTimestamp string
-	DatabaseName    string
-	PostgresVersion string
-	DumpFormat      string
-	Compressed      bool
+	BackupTimestamp string         `json:"backup_timestamp"`
+	Engine          string         `json:"engine"`
+	DatabaseName    string         `json:"database_name"`
+	PostgresVersion string         `json:"postgres_version,omitempty"`
+	SQLiteVersion   string         `json:"sqlite_version,omitempty"`
+	DumpFormat      string         `json:"dump_format"`
+	Compressed      bool           `json:"compressed"` // by pg_dump for custom dumps, in the ZIP entry otherwise
+	Anonymization   *Anonymization `json:"anonymization,omitempty"`
+	// Open WebUI schema at dump time, compared with the target on restore
+	AlembicRevision  string `json:"alembic_revision,omitempty"`
+	OpenWebUIVersion string `json:"open_webui_version,omitempty"`
+}
+
+// Anonymization records the ruleset an anonymized dump was written with
+type Anonymization struct {
+	Ruleset     string `json:"ruleset"`
+	Fingerprint string `json:"fingerprint"`
+}
+
+// Entry returns the ZIP entry that holds the dump or snapshot
+func (m *DatabaseBackupMetadata) Entry() string {
+	if m.Engine == EngineSQLite {
+		return SQLiteEntry
+	}
+	if m.DumpFormat == "custom" {
+		return CustomDumpEntry
+	}
+	return DumpEntry
 }
 
 // CheckToolsAvailable verifies that pg_dump and pg_restore are installed
@@ -117,12 +169,15 @@ func ParsePostgresURL(connectionURL string) (*DatabaseConfig, error) {
 	}
 
 	config := &DatabaseConfig{
-		URL:      connectionURL,
-		Host:     host,
-		Port:     port,
-		Database: database,
-		User:     user,
-		Password: password,
+		URL:         connectionURL,
+		Host:        host,
+		Port:        port,
+		Database:    database,
+		User:        user,
+		Password:    password,
+		SSLMode:     u.Query().Get("sslmode"),
+		SSLRootCert: u.Query().Get("sslrootcert"),
+		Backend:     GetBackend(),
 	}
 
 	logrus.Debugf("Parsed database config: host=%s, port=%d, database=%s, user=%s",
@@ -139,6 +194,10 @@ func TestConnection(config *DatabaseConfig) error {
 
 	logrus.Debug("Testing database connection...")
 
+	if ResolveBackend(config) == BackendNative {
+		return testConnectionNative(config)
+	}
+
 	// Use psql to test connection with a simple query
 	cmd := exec.Command(GetPsqlPath(),
 		"-h", config.Host,
@@ -155,6 +214,9 @@ func TestConnection(config *DatabaseConfig) error {
 	// Run the command
 	output, err := cmd.CombinedOutput()
 	if err != nil {
+		if strings.Contains(string(output), "password authentication failed") {
+			return fmt.Errorf("%w: %s", ErrAuthentication, strings.TrimSpace(string(output)))
+		}
 		return fmt.Errorf("database connection test failed: %w\nOutput: %s", err, string(output))
 	}
 
@@ -162,10 +224,12 @@ func TestConnection(config *DatabaseConfig) error {
 	return nil
 }
 
-// CreateDump creates a database dump using pg_dump (or Docker)
-func CreateDump(config *DatabaseConfig, options *DumpOptions) ([]byte, error) {
+// WriteDump streams a database dump into w using pg_dump (or Docker, or the native client)
+// and returns the number of bytes written. The dump is never held in memory, so its size is
+// only limited by w
+func WriteDump(config *DatabaseConfig, options *DumpOptions, w io.Writer) (int64, error) {
 	if config == nil {
-		return nil, fmt.Errorf("database config is nil")
+		return 0, fmt.Errorf("database config is nil")
 	}
 
 	if options == nil {
@@ -176,9 +240,16 @@ func CreateDump(config *DatabaseConfig, options *DumpOptions) ([]byte, error) {
 		}
 	}
 
-	// Check if Docker mode should be used
-	if UseDockerPgTools() {
-		return createDumpWithDocker(config, options)
+	cw := &countingWriter{w: w}
+	switch ResolveBackend(config) {
+	case BackendNative:
+		if err := WriteNativeDump(config, options, cw); err != nil {
+			return cw.n, err
+		}
+		logrus.Infof("Database dump created successfully (%d bytes)", cw.n)
+		return cw.n, nil
+	case BackendDocker:
+		return writeDumpWithDocker(config, options, cw)
 	}
 
 	logrus.Infof("Creating database dump for '%s'...", config.Database)
@@ -194,9 +265,7 @@ func CreateDump(config *DatabaseConfig, options *DumpOptions) ([]byte, error) {
 	// Add format option
 	if options.Format == "custom" {
 		args = append(args, "-Fc") // Custom format
-		if options.Compress > 0 {
-			args = append(args, fmt.Sprintf("-Z%d", options.Compress))
-		}
+		args = append(args, options.compressArgs()...)
 	} else {
 		args = append(args, "-Fp") // Plain SQL format
 	}
@@ -214,6 +283,9 @@ func CreateDump(config *DatabaseConfig, options *DumpOptions) ([]byte, error) {
 	if options.DataOnly {
 		args = append(args, "--data-only")
 	}
+	if options.Snapshot != "" {
+		args = append(args, "--snapshot="+options.Snapshot)
+	}
 	if options.Verbose {
 		args = append(args, "-v")
 	}
@@ -223,9 +295,9 @@ func CreateDump(config *DatabaseConfig, options *DumpOptions) ([]byte, error) {
 	// Set PGPASSWORD environment variable
 	cmd.Env = append(cmd.Env, fmt.Sprintf("PGPASSWORD=%s", config.Password))
 
-	// Capture stdout (the dump) and stderr (logs/errors)
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
+	// Stream stdout (the dump) and capture stderr (logs/errors)
+	var stderr bytes.Buffer
+	cmd.Stdout = cw
 	cmd.Stderr = &stderr
 
 	// Run the command
@@ -233,7 +305,7 @@ func CreateDump(config *DatabaseConfig, options *DumpOptions) ([]byte, error) {
 		// Check for version mismatch error
 		stderrStr := stderr.String()
 		if strings.Contains(stderrStr, "server version mismatch") {
-			return nil, fmt.Errorf(`pg_dump failed due to version mismatch.
+			return cw.n, fmt.Errorf(`pg_dump failed due to version mismatch.
 
 Error: %s
 
@@ -249,7 +321,7 @@ Solutions:
 4. Use Docker manually:
    docker run --rm -e PGPASSWORD=xxx postgres:<version> pg_dump ...`, stderrStr)
 		}
-		return nil, fmt.Errorf("pg_dump failed: %w\nError output: %s", err, stderrStr)
+		return cw.n, fmt.Errorf("pg_dump failed: %w\nError output: %s", err, stderrStr)
 	}
 
 	// Log any warnings from stderr
@@ -257,17 +329,38 @@ Solutions:
 		logrus.Debugf("pg_dump output: %s", stderr.String())
 	}
 
-	dumpData := stdout.Bytes()
-	logrus.Infof("Database dump created successfully (%d bytes)", len(dumpData))
+	logrus.Infof("Database dump created successfully (%d bytes)", cw.n)
+	return cw.n, nil
+}
 
-	return dumpData, nil
+// compressArgs returns the pg_dump compression arguments for the custom format
+func (o *DumpOptions) compressArgs() []string {
+	if o.NoCompress {
+		return []string{"-Z0"}
+	}
+	if o.Compress > 0 {
+		return []string{fmt.Sprintf("-Z%d", o.Compress)}
+	}
+	return nil
 }
 
-// createDumpWithDocker creates a database dump using Docker with matching PostgreSQL version
-func createDumpWithDocker(config *DatabaseConfig, options *DumpOptions) ([]byte, error) {
+// countingWriter counts the bytes written through it
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
+
+// writeDumpWithDocker streams a database dump into w using Docker with matching PostgreSQL version
+func writeDumpWithDocker(config *DatabaseConfig, options *DumpOptions, w *countingWriter) (int64, error) {
 	// Check if Docker is available
 	if !IsDockerAvailable() {
-		return nil, fmt.Errorf("Docker is not available. Install Docker or set USE_DOCKER_PG_TOOLS=false")
+		return 0, fmt.Errorf("Docker is not available. Install Docker or set USE_DOCKER_PG_TOOLS=false")
 	}
 
 	// Get server version to determine Docker image
@@ -299,9 +392,7 @@ func createDumpWithDocker(config *DatabaseConfig, options *DumpOptions) ([]byte,
 	// Add format option
 	if options.Format == "custom" {
 		pgDumpArgs = append(pgDumpArgs, "-Fc")
-		if options.Compress > 0 {
-			pgDumpArgs = append(pgDumpArgs, fmt.Sprintf("-Z%d", options.Compress))
-		}
+		pgDumpArgs = append(pgDumpArgs, options.compressArgs()...)
 	} else {
 		pgDumpArgs = append(pgDumpArgs, "-Fp")
 	}
@@ -319,6 +410,9 @@ func createDumpWithDocker(config *DatabaseConfig, options *DumpOptions) ([]byte,
 	if options.DataOnly {
 		pgDumpArgs = append(pgDumpArgs, "--data-only")
 	}
+	if options.Snapshot != "" {
+		pgDumpArgs = append(pgDumpArgs, "--snapshot="+options.Snapshot)
+	}
 	if options.Verbose {
 		pgDumpArgs = append(pgDumpArgs, "-v")
 	}
@@ -354,14 +448,14 @@ func createDumpWithDocker(config *DatabaseConfig, options *DumpOptions) ([]byte,
 
 	cmd := exec.Command("docker", dockerArgs...)
 
-	// Capture stdout and stderr
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
+	// Stream stdout and capture stderr
+	var stderr bytes.Buffer
+	cmd.Stdout = w
 	cmd.Stderr = &stderr
 
 	// Run the command
 	if err := cmd.Run(); err != nil {
-		return nil, fmt.Errorf("Docker pg_dump failed: %w\nError output: %s", err, stderr.String())
+		return w.n, fmt.Errorf("Docker pg_dump failed: %w\nError output: %s", err, stderr.String())
 	}
 
 	// Log stderr output based on verbose setting
@@ -373,21 +467,27 @@ func createDumpWithDocker(config *DatabaseConfig, options *DumpOptions) ([]byte,
 		}
 	}
 
-	dumpData := stdout.Bytes()
-	logrus.Infof("Database dump created successfully via Docker (%d bytes)", len(dumpData))
-
-	return dumpData, nil
+	logrus.Infof("Database dump created successfully via Docker (%d bytes)", w.n)
+	return w.n, nil
 }
 
-// RestoreDump restores a database dump using pg_restore or psql (or Docker)
-func RestoreDump(config *DatabaseConfig, dumpData []byte, options *RestoreOptions) error {
+// RestoreDump restores a database dump using pg_restore or psql (or Docker, or the native client)
+// The dump is streamed from r into the restore, so its size is not limited by memory
+func RestoreDump(config *DatabaseConfig, r io.Reader, options *RestoreOptions) error {
 	if config == nil {
 		return fmt.Errorf("database config is nil")
 	}
 
-	if len(dumpData) == 0 {
+	// Determine if this is a custom format or plain SQL
+	dump := bufio.NewReader(r)
+	header, err := dump.Peek(len(customDumpMagic))
+	if len(header) == 0 {
+		if err != nil && err != io.EOF {
+			return fmt.Errorf("failed to read dump: %w", err)
+		}
 		return fmt.Errorf("dump data is empty")
 	}
+	isCustomFormat := isCustomDump(header)
 
 	if options == nil {
 		options = &RestoreOptions{
@@ -396,27 +496,29 @@ func RestoreDump(config *DatabaseConfig, dumpData []byte, options *RestoreOption
 		}
 	}
 
-	// Check if Docker mode should be used
-	if UseDockerPgTools() {
-		return restoreDumpWithDocker(config, dumpData, options)
+	switch ResolveBackend(config) {
+	case BackendNative:
+		if isCustomFormat {
+			return fmt.Errorf("custom format dumps need pg_restore; use OWUI_PG_BACKEND=tools or docker")
+		}
+		return restoreDumpNative(config, dump, options)
+	case BackendDocker:
+		return restoreDumpWithDocker(config, dump, isCustomFormat, options)
 	}
 
 	logrus.Infof("Restoring database dump to '%s'...", config.Database)
 
-	// Determine if this is a custom format or plain SQL
-	isCustomFormat := len(dumpData) > 5 && string(dumpData[:5]) == "PGDMP"
-
 	if isCustomFormat {
 		// Use pg_restore for custom format
-		return restoreWithPgRestore(config, dumpData, options)
+		return restoreWithPgRestore(config, dump, options)
 	} else {
 		// Use psql for plain SQL format
-		return restoreWithPsql(config, dumpData, options)
+		return restoreWithPsql(config, dump, options)
 	}
 }
 
 // restoreDumpWithDocker restores a database dump using Docker with matching PostgreSQL version
-func restoreDumpWithDocker(config *DatabaseConfig, dumpData []byte, options *RestoreOptions) error {
+func restoreDumpWithDocker(config *DatabaseConfig, dump io.Reader, isCustomFormat bool, options *RestoreOptions) error {
 	// Check if Docker is available
 	if !IsDockerAvailable() {
 		return fmt.Errorf("Docker is not available. Install Docker or set USE_DOCKER_PG_TOOLS=false")
@@ -440,9 +542,6 @@ func restoreDumpWithDocker(config *DatabaseConfig, dumpData []byte, options *Res
 		logrus.Debugf("Resolved host '%s' to '%s' for Docker", config.Host, dockerHost)
 	}
 
-	// Determine if this is a custom format or plain SQL
-	isCustomFormat := len(dumpData) > 5 && string(dumpData[:5]) == "PGDMP"
-
 	var tool string
 	var toolArgs []string
 
@@ -474,6 +573,10 @@ func restoreDumpWithDocker(config *DatabaseConfig, dumpData []byte, options *Res
 			"-d", config.Database,
 			"-q",
 		}
+		if options.selective() {
+			// Selected tables are restored in one transaction that must stop at the first error
+			toolArgs = append(toolArgs, "-v", "ON_ERROR_STOP=1")
+		}
 		if options.Verbose {
 			toolArgs = append(toolArgs, "-a")
 		}
@@ -510,8 +613,8 @@ func restoreDumpWithDocker(config *DatabaseConfig, dumpData []byte, options *Res
 
 	cmd := exec.Command("docker", dockerArgs...)
 
-	// Pipe dump data to stdin
-	cmd.Stdin = bytes.NewReader(dumpData)
+	// Stream dump data to stdin
+	cmd.Stdin = dump
 
 	// Capture stderr for logging
 	var stderr bytes.Buffer
@@ -536,7 +639,7 @@ func restoreDumpWithDocker(config *DatabaseConfig, dumpData []byte, options *Res
 }
 
 // restoreWithPgRestore restores a custom format dump using pg_restore
-func restoreWithPgRestore(config *DatabaseConfig, dumpData []byte, options *RestoreOptions) error {
+func restoreWithPgRestore(config *DatabaseConfig, dump io.Reader, options *RestoreOptions) error {
 	logrus.Debug("Using pg_restore for custom format dump")
 
 	// Build pg_restore command
@@ -563,8 +666,8 @@ func restoreWithPgRestore(config *DatabaseConfig, dumpData []byte, options *Rest
 	// Set PGPASSWORD environment variable
 	cmd.Env = append(cmd.Env, fmt.Sprintf("PGPASSWORD=%s", config.Password))
 
-	// Pipe dump data to stdin
-	cmd.Stdin = bytes.NewReader(dumpData)
+	// Stream dump data to stdin
+	cmd.Stdin = dump
 
 	// Capture stderr for logging
 	var stderr bytes.Buffer
@@ -585,7 +688,7 @@ func restoreWithPgRestore(config *DatabaseConfig, dumpData []byte, options *Rest
 }
 
 // restoreWithPsql restores a plain SQL dump using psql
-func restoreWithPsql(config *DatabaseConfig, dumpData []byte, options *RestoreOptions) error {
+func restoreWithPsql(config *DatabaseConfig, dump io.Reader, options *RestoreOptions) error {
 	logrus.Debug("Using psql for plain SQL dump")
 
 	// Build psql command
@@ -597,6 +700,10 @@ func restoreWithPsql(config *DatabaseConfig, dumpData []byte, options *RestoreOp
 		"-q", // Quiet mode
 	}
 
+	if options.selective() {
+		// Selected tables are restored in one transaction that must stop at the first error
+		args = append(args, "-v", "ON_ERROR_STOP=1")
+	}
 	if options.Verbose {
 		args = append(args, "-a") // Echo all
 	}
@@ -606,8 +713,8 @@ func restoreWithPsql(config *DatabaseConfig, dumpData []byte, options *RestoreOp
 	// Set PGPASSWORD environment variable
 	cmd.Env = append(cmd.Env, fmt.Sprintf("PGPASSWORD=%s", config.Password))
 
-	// Pipe SQL data to stdin
-	cmd.Stdin = bytes.NewReader(dumpData)
+	// Stream SQL data to stdin
+	cmd.Stdin = dump
 
 	// Capture stderr for logging
 	var stderr bytes.Buffer
@@ -664,9 +771,8 @@ BEGIN
 END $$;
 `
 
-	if dryRun {
-		// Just list what would be dropped
-		listSQL := `
+	// SQL to list what would be dropped
+	listSQL := `
 SELECT 'TABLE' as type, tablename as name FROM pg_tables WHERE schemaname = 'public'
 UNION ALL
 SELECT 'SEQUENCE', sequence_name FROM information_schema.sequences WHERE sequence_schema = 'public'
@@ -674,6 +780,12 @@ UNION ALL
 SELECT 'VIEW', table_name FROM information_schema.views WHERE table_schema = 'public'
 ORDER BY type, name;
 `
+
+	if ResolveBackend(config) == BackendNative {
+		return purgeDatabaseNative(config, dryRun, dropSQL, listSQL)
+	}
+
+	if dryRun {
 		cmd := exec.Command(GetPsqlPath(),
 			"-h", config.Host,
 			"-p", strconv.Itoa(config.Port),
@@ -719,6 +831,10 @@ func GetPostgresVersion(config *DatabaseConfig) (string, error) {
 		return "", fmt.Errorf("database config is nil")
 	}
 
+	if ResolveBackend(config) == BackendNative {
+		return getPostgresVersionNative(config)
+	}
+
 	cmd := exec.Command(GetPsqlPath(),
 		"-h", config.Host,
 		"-p", strconv.Itoa(config.Port),
@@ -0,0 +1,721 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// nativeMinServerVersion is the oldest server the native backend supports (pg_sequence was added in 10)
+const nativeMinServerVersion = 100000
+
+// userSchemaFilter excludes system schemas; n is pg_namespace
+const userSchemaFilter = `n.nspname <> 'information_schema' AND n.nspname !~ '^pg_'`
+
+// notExtensionMember excludes objects created by extensions; the placeholders are catalog and oid column
+const notExtensionMember = `NOT EXISTS (SELECT 1 FROM pg_depend dep WHERE dep.classid = '%s'::regclass AND dep.objid = %s AND dep.deptype = 'e')`
+
+// nativeTable is a table included in a native dump
+type nativeTable struct {
+	OID         string
+	Name        string // schema-qualified and quoted
+	Unlogged    bool
+	Columns     []string // column definitions for CREATE TABLE
+	CopyColumns []string // quoted names of the columns with data
+}
+
+// nativeSequence is a sequence included in a native dump
+type nativeSequence struct {
+	Name     string
+	Type     string
+	Start    string
+	Inc      string
+	Min      string
+	Max      string
+	Cache    string
+	Cycle    bool
+	Identity bool
+}
+
+// testConnectionNative connects with the native client and runs SELECT 1
+func testConnectionNative(config *DatabaseConfig) error {
+	conn, err := connectNative(config)
+	if err != nil {
+		return fmt.Errorf("database connection test failed: %w", err)
+	}
+	defer conn.Close()
+
+	if err := conn.Exec("SELECT 1"); err != nil {
+		return fmt.Errorf("database connection test failed: %w", err)
+	}
+	return nil
+}
+
+// getPostgresVersionNative returns SELECT version() using the native client
+func getPostgresVersionNative(config *DatabaseConfig) (string, error) {
+	conn, err := connectNative(config)
+	if err != nil {
+		return "", fmt.Errorf("failed to get PostgreSQL version: %w", err)
+	}
+	defer conn.Close()
+
+	res, err := conn.Query("SELECT version()")
+	if err != nil {
+		return "", fmt.Errorf("failed to get PostgreSQL version: %w", err)
+	}
+	if len(res.Rows) == 0 || len(res.Rows[0]) < 1 {
+		return "", fmt.Errorf("failed to get PostgreSQL version: no rows returned")
+	}
+	return res.Rows[0][0], nil
+}
+
+// purgeDatabaseNative runs the purge or listing SQL using the native client
+func purgeDatabaseNative(config *DatabaseConfig, dryRun bool, dropSQL, listSQL string) error {
+	conn, err := connectNative(config)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer conn.Close()
+
+	if dryRun {
+		res, err := conn.Query(listSQL)
+		if err != nil {
+			return fmt.Errorf("failed to list database objects: %w", err)
+		}
+		logrus.Info("Objects that would be deleted:")
+		for _, row := range res.Rows {
+			logrus.Infof("  %s %s", row[0], row[1])
+		}
+		if len(res.Rows) == 0 {
+			logrus.Info("  (none)")
+		}
+		return nil
+	}
+
+	if err := conn.Exec(dropSQL); err != nil {
+		return fmt.Errorf("failed to purge database: %w", err)
+	}
+	logrus.Infof("Database '%s' purged successfully", config.Database)
+	return nil
+}
+
+// WriteNativeDump streams a plain SQL dump of the database to w
+// The schema and all table data are read in one REPEATABLE READ, READ ONLY transaction,
+// so the dump is a consistent snapshot; the output can be restored with psql or RestoreDump
+func WriteNativeDump(config *DatabaseConfig, options *DumpOptions, w io.Writer) error {
+	if options == nil {
+		options = &DumpOptions{Format: "plain", NoOwner: true, NoPrivileges: true}
+	}
+	if options.Format == "custom" {
+		return fmt.Errorf("the native backend only writes plain SQL dumps; use OWUI_PG_BACKEND=tools or docker for the custom format")
+	}
+	if options.SchemaOnly && options.DataOnly {
+		return fmt.Errorf("schema-only and data-only cannot be combined")
+	}
+
+	logrus.Infof("Creating database dump for '%s' (native backend)...", config.Database)
+
+	conn, err := connectNative(config)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	d := &nativeDumper{conn: conn, options: options}
+	if err := d.begin(); err != nil {
+		return err
+	}
+	if err := d.dump(w); err != nil {
+		conn.Exec("ROLLBACK")
+		return err
+	}
+	if err := conn.Exec("COMMIT"); err != nil {
+		return fmt.Errorf("failed to end dump transaction: %w", err)
+	}
+	return nil
+}
+
+// nativeDumper reads the catalog and data for a native dump
+type nativeDumper struct {
+	conn          *pgConn
+	options       *DumpOptions
+	versionNum    int
+	serverVersion string
+}
+
+// begin prepares the session and opens the snapshot transaction
+func (d *nativeDumper) begin() error {
+	res, err := d.conn.Query("SELECT current_setting('server_version_num'), current_setting('server_version')")
+	if err != nil {
+		return fmt.Errorf("failed to read server version: %w", err)
+	}
+	if len(res.Rows) == 0 || len(res.Rows[0]) < 2 {
+		return fmt.Errorf("failed to read server version: no rows returned")
+	}
+	d.versionNum, _ = strconv.Atoi(res.Rows[0][0])
+	d.serverVersion = res.Rows[0][1]
+	if d.versionNum < nativeMinServerVersion {
+		return fmt.Errorf("the native backend requires PostgreSQL 10 or newer (server is %s)", d.serverVersion)
+	}
+
+	// Same session settings as pg_dump: exact floats, ISO dates and fully qualified names
+	setup := []string{
+		"SET statement_timeout = 0",
+		"SET lock_timeout = 0",
+		"SET idle_in_transaction_session_timeout = 0",
+		"SET DateStyle = ISO",
+		"SET IntervalStyle = postgres",
+		"SET extra_float_digits TO 3",
+		"SET row_security = off",
+		"SELECT pg_catalog.set_config('search_path', '', false)",
+		"BEGIN ISOLATION LEVEL REPEATABLE READ, READ ONLY",
+	}
+	if d.options.Snapshot != "" {
+		// Must be the first statement of the transaction
+		setup = append(setup, "SET TRANSACTION SNAPSHOT "+quoteLiteral(d.options.Snapshot))
+	}
+	for _, stmt := range setup {
+		if err := d.conn.Exec(stmt); err != nil {
+			return fmt.Errorf("failed to prepare dump session (%s): %w", stmt, err)
+		}
+	}
+	return nil
+}
+
+// dump writes the complete script
+func (d *nativeDumper) dump(w io.Writer) error {
+	out := &dumpWriter{w: w}
+
+	out.printf("--\n-- PostgreSQL database dump\n--\n\n")
+	out.printf("-- Dumped from database version %s\n", d.serverVersion)
+	out.printf("-- Dumped by owuicli (native backend)\n\n")
+	out.printf("SET statement_timeout = 0;\n")
+	out.printf("SET lock_timeout = 0;\n")
+	out.printf("SET idle_in_transaction_session_timeout = 0;\n")
+	out.printf("SET client_encoding = 'UTF8';\n")
+	out.printf("SET standard_conforming_strings = on;\n")
+	out.printf("SELECT pg_catalog.set_config('search_path', '', false);\n")
+	out.printf("SET check_function_bodies = false;\n")
+	out.printf("SET xmloption = content;\n")
+	out.printf("SET client_min_messages = warning;\n")
+	out.printf("SET row_security = off;\n\n")
+
+	tables, err := d.tables()
+	if err != nil {
+		return err
+	}
+	if err := d.lockTables(tables); err != nil {
+		return err
+	}
+	sequences, err := d.sequences()
+	if err != nil {
+		return err
+	}
+
+	if !d.options.DataOnly {
+		if err := d.dumpSchemaObjects(out, tables, sequences); err != nil {
+			return err
+		}
+	}
+
+	if !d.options.SchemaOnly {
+		for _, table := range tables {
+			if err := d.dumpTableData(out, table); err != nil {
+				return err
+			}
+		}
+		if err := d.dumpSequenceValues(out, sequences); err != nil {
+			return err
+		}
+	}
+
+	if !d.options.DataOnly {
+		if err := d.dumpPostDataObjects(out, tables); err != nil {
+			return err
+		}
+	}
+
+	out.printf("--\n-- PostgreSQL database dump complete\n--\n\n")
+	return out.err
+}
+
+// dumpSchemaObjects writes schemas, extensions, types, functions, sequences, tables and views
+func (d *nativeDumper) dumpSchemaObjects(out *dumpWriter, tables []*nativeTable, sequences []*nativeSequence) error {
+	res, err := d.conn.Query(`SELECT format('%I', n.nspname) FROM pg_namespace n
+		WHERE ` + userSchemaFilter + ` AND n.nspname <> 'public' AND ` + fmt.Sprintf(notExtensionMember, "pg_namespace", "n.oid") + `
+		ORDER BY n.nspname`)
+	if err != nil {
+		return fmt.Errorf("failed to list schemas: %w", err)
+	}
+	for _, row := range res.Rows {
+		out.section(row[0], "SCHEMA", "-")
+		out.printf("CREATE SCHEMA %s;\n\n\n", row[0])
+	}
+
+	res, err = d.conn.Query(`SELECT format('%I', e.extname), format('%I', n.nspname), e.extname
+		FROM pg_extension e JOIN pg_namespace n ON n.oid = e.extnamespace
+		WHERE e.extname <> 'plpgsql' ORDER BY e.extname`)
+	if err != nil {
+		return fmt.Errorf("failed to list extensions: %w", err)
+	}
+	for _, row := range res.Rows {
+		out.section(row[2], "EXTENSION", "-")
+		out.printf("CREATE EXTENSION IF NOT EXISTS %s WITH SCHEMA %s;\n\n\n", row[0], row[1])
+	}
+
+	res, err = d.conn.Query(`SELECT format('%I.%I', n.nspname, t.typname), t.typname, n.nspname,
+		(SELECT string_agg(quote_literal(e.enumlabel), ', ' ORDER BY e.enumsortorder) FROM pg_enum e WHERE e.enumtypid = t.oid)
+		FROM pg_type t JOIN pg_namespace n ON n.oid = t.typnamespace
+		WHERE t.typtype = 'e' AND ` + userSchemaFilter + ` AND ` + fmt.Sprintf(notExtensionMember, "pg_type", "t.oid") + `
+		ORDER BY t.oid`)
+	if err != nil {
+		return fmt.Errorf("failed to list enum types: %w", err)
+	}
+	for _, row := range res.Rows {
+		out.section(row[1], "TYPE", row[2])
+		out.printf("CREATE TYPE %s AS ENUM (\n    %s\n);\n\n\n", row[0], strings.ReplaceAll(row[3], ", ", ",\n    "))
+	}
+
+	res, err = d.conn.Query(`SELECT count(*) FROM pg_type t JOIN pg_namespace n ON n.oid = t.typnamespace
+		WHERE t.typtype IN ('d', 'r') AND ` + userSchemaFilter + ` AND ` + fmt.Sprintf(notExtensionMember, "pg_type", "t.oid"))
+	if err == nil && len(res.Rows) > 0 && res.Rows[0][0] != "0" {
+		logrus.Warnf("Native dump skips %s domain or range type(s); use OWUI_PG_BACKEND=tools for a complete dump", res.Rows[0][0])
+	}
+
+	routineFilter := "p.prokind IN ('f', 'p')"
+	if d.versionNum < 110000 {
+		routineFilter = "NOT p.proisagg AND NOT p.proiswindow"
+	}
+	res, err = d.conn.Query(`SELECT p.proname, n.nspname, pg_get_functiondef(p.oid)
+		FROM pg_proc p JOIN pg_namespace n ON n.oid = p.pronamespace
+		WHERE ` + routineFilter + ` AND ` + userSchemaFilter + ` AND ` + fmt.Sprintf(notExtensionMember, "pg_proc", "p.oid") + `
+		ORDER BY p.oid`)
+	if err != nil {
+		return fmt.Errorf("failed to list functions: %w", err)
+	}
+	for _, row := range res.Rows {
+		out.section(row[0], "FUNCTION", row[1])
+		out.printf("%s;\n\n\n", strings.TrimRight(row[2], "\n"))
+	}
+
+	for _, seq := range sequences {
+		if seq.Identity {
+			continue
+		}
+		out.section(seq.Name, "SEQUENCE", "-")
+		out.printf("%s\n\n\n", seq.createSQL())
+	}
+
+	for _, table := range tables {
+		out.section(table.Name, "TABLE", "-")
+		out.printf("%s\n\n\n", table.createSQL())
+	}
+
+	res, err = d.conn.Query(`SELECT format('%I.%I', sn.nspname, s.relname), format('%I.%I', tn.nspname, t.relname), format('%I', a.attname)
+		FROM pg_depend dep
+		JOIN pg_class s ON s.oid = dep.objid AND s.relkind = 'S'
+		JOIN pg_namespace sn ON sn.oid = s.relnamespace
+		JOIN pg_class t ON t.oid = dep.refobjid
+		JOIN pg_namespace tn ON tn.oid = t.relnamespace
+		JOIN pg_attribute a ON a.attrelid = t.oid AND a.attnum = dep.refobjsubid
+		WHERE dep.classid = 'pg_class'::regclass AND dep.refclassid = 'pg_class'::regclass AND dep.deptype = 'a'
+		ORDER BY 1`)
+	if err != nil {
+		return fmt.Errorf("failed to list sequence ownership: %w", err)
+	}
+	for _, row := range res.Rows {
+		out.printf("ALTER SEQUENCE %s OWNED BY %s.%s;\n\n\n", row[0], row[1], row[2])
+	}
+
+	res, err = d.conn.Query(`SELECT format('%I.%I', n.nspname, c.relname), c.relkind, pg_get_viewdef(c.oid)
+		FROM pg_class c JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE c.relkind IN ('v', 'm') AND ` + userSchemaFilter + ` AND ` + fmt.Sprintf(notExtensionMember, "pg_class", "c.oid") + `
+		ORDER BY c.oid`)
+	if err != nil {
+		return fmt.Errorf("failed to list views: %w", err)
+	}
+	for _, row := range res.Rows {
+		definition := strings.TrimSuffix(strings.TrimSpace(row[2]), ";")
+		if row[1] == "m" {
+			out.section(row[0], "MATERIALIZED VIEW", "-")
+			out.printf("CREATE MATERIALIZED VIEW %s AS\n %s\n  WITH NO DATA;\n\n\n", row[0], definition)
+		} else {
+			out.section(row[0], "VIEW", "-")
+			out.printf("CREATE VIEW %s AS\n %s;\n\n\n", row[0], definition)
+		}
+	}
+
+	return out.err
+}
+
+// dumpPostDataObjects writes constraints, indexes, triggers and materialized view refreshes
+func (d *nativeDumper) dumpPostDataObjects(out *dumpWriter, tables []*nativeTable) error {
+	included := map[string]*nativeTable{}
+	for _, table := range tables {
+		included[table.OID] = table
+	}
+
+	res, err := d.conn.Query(`SELECT co.conrelid, format('%I', co.conname), pg_get_constraintdef(co.oid), co.contype
+		FROM pg_constraint co
+		WHERE co.contype IN ('p', 'u', 'c', 'x', 'f') AND co.conislocal
+		ORDER BY co.contype = 'f', co.conrelid, co.conname`)
+	if err != nil {
+		return fmt.Errorf("failed to list constraints: %w", err)
+	}
+	for _, row := range res.Rows {
+		table, ok := included[row[0]]
+		if !ok {
+			continue
+		}
+		kind := "CONSTRAINT"
+		if row[3] == "f" {
+			kind = "FK CONSTRAINT"
+		}
+		out.section(row[1], kind, "-")
+		out.printf("ALTER TABLE ONLY %s\n    ADD CONSTRAINT %s %s;\n\n\n", table.Name, row[1], row[2])
+	}
+
+	res, err = d.conn.Query(`SELECT i.indrelid, c.relkind, format('%I', ic.relname), pg_get_indexdef(i.indexrelid)
+		FROM pg_index i
+		JOIN pg_class c ON c.oid = i.indrelid
+		JOIN pg_class ic ON ic.oid = i.indexrelid
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE ` + userSchemaFilter + `
+		AND NOT EXISTS (SELECT 1 FROM pg_constraint co WHERE co.conindid = i.indexrelid AND co.contype IN ('p', 'u', 'x'))
+		ORDER BY ic.relname`)
+	if err != nil {
+		return fmt.Errorf("failed to list indexes: %w", err)
+	}
+	for _, row := range res.Rows {
+		if _, ok := included[row[0]]; !ok && row[1] != "m" {
+			continue
+		}
+		out.section(row[2], "INDEX", "-")
+		out.printf("%s;\n\n\n", row[3])
+	}
+
+	res, err = d.conn.Query(`SELECT t.tgrelid, format('%I', t.tgname), pg_get_triggerdef(t.oid)
+		FROM pg_trigger t WHERE NOT t.tgisinternal ORDER BY t.tgname`)
+	if err != nil {
+		return fmt.Errorf("failed to list triggers: %w", err)
+	}
+	for _, row := range res.Rows {
+		if _, ok := included[row[0]]; !ok {
+			continue
+		}
+		out.section(row[1], "TRIGGER", "-")
+		out.printf("%s;\n\n\n", row[2])
+	}
+
+	if !d.options.SchemaOnly {
+		res, err = d.conn.Query(`SELECT format('%I.%I', n.nspname, c.relname)
+			FROM pg_class c JOIN pg_namespace n ON n.oid = c.relnamespace
+			WHERE c.relkind = 'm' AND c.relispopulated AND ` + userSchemaFilter + `
+			AND ` + fmt.Sprintf(notExtensionMember, "pg_class", "c.oid") + `
+			ORDER BY c.oid`)
+		if err != nil {
+			return fmt.Errorf("failed to list materialized views: %w", err)
+		}
+		for _, row := range res.Rows {
+			out.section(row[0], "MATERIALIZED VIEW DATA", "-")
+			out.printf("REFRESH MATERIALIZED VIEW %s;\n\n\n", row[0])
+		}
+	}
+
+	return out.err
+}
+
+// tables lists the ordinary tables with their column definitions
+func (d *nativeDumper) tables() ([]*nativeTable, error) {
+	res, err := d.conn.Query(`SELECT c.oid, format('%I.%I', n.nspname, c.relname), c.relpersistence, c.relkind, c.relispartition
+		FROM pg_class c JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE c.relkind IN ('r', 'p') AND ` + userSchemaFilter + ` AND ` + fmt.Sprintf(notExtensionMember, "pg_class", "c.oid") + `
+		ORDER BY n.nspname, c.relname`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tables: %w", err)
+	}
+
+	var tables []*nativeTable
+	for _, row := range res.Rows {
+		if row[3] == "p" || row[4] == "t" {
+			logrus.Warnf("Native dump skips partitioned table %s; use OWUI_PG_BACKEND=tools for a complete dump", row[1])
+			continue
+		}
+		table := &nativeTable{OID: row[0], Name: row[1], Unlogged: row[2] == "u"}
+		if err := d.loadColumns(table); err != nil {
+			return nil, err
+		}
+		tables = append(tables, table)
+	}
+	return tables, nil
+}
+
+// loadColumns reads the column definitions of a table
+func (d *nativeDumper) loadColumns(table *nativeTable) error {
+	generated := "''"
+	if d.versionNum >= 120000 {
+		generated = "a.attgenerated"
+	}
+	res, err := d.conn.Query(`SELECT format('%I', a.attname), format_type(a.atttypid, a.atttypmod), a.attnotnull,
+		pg_get_expr(ad.adbin, ad.adrelid), a.attidentity, ` + generated + `,
+		CASE WHEN a.attcollation <> t.typcollation THEN format('%I.%I', cn.nspname, co.collname) END,
+		CASE WHEN a.attidentity <> '' THEN pg_get_serial_sequence(a.attrelid::regclass::text, a.attname) END
+		FROM pg_attribute a
+		JOIN pg_type t ON t.oid = a.atttypid
+		LEFT JOIN pg_attrdef ad ON ad.adrelid = a.attrelid AND ad.adnum = a.attnum
+		LEFT JOIN pg_collation co ON co.oid = a.attcollation
+		LEFT JOIN pg_namespace cn ON cn.oid = co.collnamespace
+		WHERE a.attrelid = ` + table.OID + ` AND a.attnum > 0 AND NOT a.attisdropped
+		ORDER BY a.attnum`)
+	if err != nil {
+		return fmt.Errorf("failed to read columns of %s: %w", table.Name, err)
+	}
+
+	for _, row := range res.Rows {
+		table.addColumn(row)
+	}
+	return nil
+}
+
+// addColumn adds a column from a row of the loadColumns catalog query:
+// name, type, not null, default, identity, generated, collation and identity sequence
+func (t *nativeTable) addColumn(row []string) {
+	name, typ, notNull, def, identity, gen, collation, identitySeq := row[0], row[1], row[2], row[3], row[4], row[5], row[6], row[7]
+	column := name + " " + typ
+	if collation != "" {
+		column += " COLLATE " + collation
+	}
+	switch {
+	case gen == "s":
+		column += " GENERATED ALWAYS AS (" + def + ") STORED"
+	case def != "":
+		column += " DEFAULT " + def
+	}
+	switch identity {
+	case "a":
+		column += " GENERATED ALWAYS AS IDENTITY"
+	case "d":
+		column += " GENERATED BY DEFAULT AS IDENTITY"
+	}
+	if identity != "" && identitySeq != "" {
+		column += " (SEQUENCE NAME " + identitySeq + ")"
+	}
+	if notNull == "t" {
+		column += " NOT NULL"
+	}
+	t.Columns = append(t.Columns, column)
+	// Generated columns are computed on restore and cannot be copied into
+	if gen == "" {
+		t.CopyColumns = append(t.CopyColumns, name)
+	}
+}
+
+// createSQL returns the CREATE TABLE statement of the table
+func (t *nativeTable) createSQL() string {
+	prefix := "CREATE TABLE"
+	if t.Unlogged {
+		prefix = "CREATE UNLOGGED TABLE"
+	}
+	return fmt.Sprintf("%s %s (\n    %s\n);", prefix, t.Name, strings.Join(t.Columns, ",\n    "))
+}
+
+// createSQL returns the CREATE SEQUENCE statement of the sequence
+func (s *nativeSequence) createSQL() string {
+	sql := fmt.Sprintf("CREATE SEQUENCE %s\n    AS %s\n    START WITH %s\n    INCREMENT BY %s\n    MINVALUE %s\n    MAXVALUE %s\n    CACHE %s",
+		s.Name, s.Type, s.Start, s.Inc, s.Min, s.Max, s.Cache)
+	if s.Cycle {
+		sql += "\n    CYCLE"
+	}
+	return sql + ";"
+}
+
+// lockTables takes ACCESS SHARE locks so tables cannot be dropped or altered during the dump
+func (d *nativeDumper) lockTables(tables []*nativeTable) error {
+	if len(tables) == 0 {
+		return nil
+	}
+	names := make([]string, len(tables))
+	for i, table := range tables {
+		names[i] = table.Name
+	}
+	if err := d.conn.Exec("LOCK TABLE " + strings.Join(names, ", ") + " IN ACCESS SHARE MODE"); err != nil {
+		return fmt.Errorf("failed to lock tables: %w", err)
+	}
+	return nil
+}
+
+// sequences lists all user sequences, including identity sequences
+func (d *nativeDumper) sequences() ([]*nativeSequence, error) {
+	res, err := d.conn.Query(`SELECT format('%I.%I', n.nspname, c.relname), format_type(s.seqtypid, NULL),
+		s.seqstart, s.seqincrement, s.seqmin, s.seqmax, s.seqcache, s.seqcycle,
+		EXISTS (SELECT 1 FROM pg_depend dep WHERE dep.classid = 'pg_class'::regclass AND dep.objid = c.oid AND dep.deptype = 'i')
+		FROM pg_class c
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		JOIN pg_sequence s ON s.seqrelid = c.oid
+		WHERE c.relkind = 'S' AND ` + userSchemaFilter + ` AND ` + fmt.Sprintf(notExtensionMember, "pg_class", "c.oid") + `
+		ORDER BY n.nspname, c.relname`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sequences: %w", err)
+	}
+
+	var sequences []*nativeSequence
+	for _, row := range res.Rows {
+		sequences = append(sequences, &nativeSequence{
+			Name:     row[0],
+			Type:     row[1],
+			Start:    row[2],
+			Inc:      row[3],
+			Min:      row[4],
+			Max:      row[5],
+			Cache:    row[6],
+			Cycle:    row[7] == "t",
+			Identity: row[8] == "t",
+		})
+	}
+	return sequences, nil
+}
+
+// dumpTableData streams the rows of a table as a COPY block
+func (d *nativeDumper) dumpTableData(out *dumpWriter, table *nativeTable) error {
+	if len(table.CopyColumns) == 0 {
+		return nil
+	}
+	columns := strings.Join(table.CopyColumns, ", ")
+
+	out.section(table.Name, "TABLE DATA", "-")
+	out.printf("COPY %s (%s) FROM stdin;\n", table.Name, columns)
+	if out.err != nil {
+		return out.err
+	}
+
+	rows, err := d.conn.CopyOut(fmt.Sprintf("COPY %s (%s) TO STDOUT", table.Name, columns), out)
+	if err != nil {
+		return fmt.Errorf("failed to copy data of %s: %w", table.Name, err)
+	}
+	out.printf("\\.\n\n\n")
+
+	if d.options.Verbose {
+		logrus.Infof("Dumped %d rows from %s", rows, table.Name)
+	} else {
+		logrus.Debugf("Dumped %d rows from %s", rows, table.Name)
+	}
+	return out.err
+}
+
+// dumpSequenceValues writes setval calls with the current value of every sequence
+func (d *nativeDumper) dumpSequenceValues(out *dumpWriter, sequences []*nativeSequence) error {
+	for _, seq := range sequences {
+		res, err := d.conn.Query("SELECT last_value, is_called FROM " + seq.Name)
+		if err != nil {
+			return fmt.Errorf("failed to read sequence %s: %w", seq.Name, err)
+		}
+		if len(res.Rows) == 0 || len(res.Rows[0]) < 2 {
+			return fmt.Errorf("failed to read sequence %s: no rows returned", seq.Name)
+		}
+		isCalled := "false"
+		if res.Rows[0][1] == "t" {
+			isCalled = "true"
+		}
+		out.section(seq.Name, "SEQUENCE SET", "-")
+		out.printf("SELECT pg_catalog.setval(%s, %s, %s);\n\n\n", quoteLiteral(seq.Name), res.Rows[0][0], isCalled)
+	}
+	return out.err
+}
+
+// dumpWriter remembers the first write error so callers can check once
+type dumpWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (dw *dumpWriter) Write(p []byte) (int, error) {
+	if dw.err != nil {
+		return 0, dw.err
+	}
+	n, err := dw.w.Write(p)
+	if err != nil {
+		dw.err = fmt.Errorf("failed to write dump: %w", err)
+	}
+	return n, dw.err
+}
+
+func (dw *dumpWriter) printf(format string, args ...interface{}) {
+	fmt.Fprintf(dw, format, args...)
+}
+
+// section writes a pg_dump style object header comment
+func (dw *dumpWriter) section(name, kind, schema string) {
+	dw.printf("--\n-- Name: %s; Type: %s; Schema: %s; Owner: -\n--\n\n", name, kind, schema)
+}
+
+// quoteLiteral quotes s as an SQL string literal
+func quoteLiteral(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// restoreDumpNative replays a plain SQL dump with the native client
+// Like psql, it continues after failed statements and reports them at the end
+func restoreDumpNative(config *DatabaseConfig, dump io.Reader, options *RestoreOptions) error {
+	logrus.Infof("Restoring database dump to '%s' (native backend)...", config.Database)
+
+	conn, err := connectNative(config)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	script := newSQLScript(dump)
+	var statements, failed int
+	var firstErr error
+	for stmt := script.Next(); stmt != nil; stmt = script.Next() {
+		statements++
+		if options != nil && options.Verbose {
+			logrus.Infof("Executing (line %d): %s", stmt.Line, firstLine(stmt.SQL))
+		}
+
+		var execErr error
+		if stmt.IsCopy {
+			var rows int64
+			rows, execErr = conn.CopyIn(stmt.SQL, stmt.Copy)
+			if execErr == nil {
+				logrus.Debugf("Restored %d rows: %s", rows, firstLine(stmt.SQL))
+			}
+		} else {
+			execErr = conn.Exec(stmt.SQL)
+		}
+
+		if execErr != nil {
+			var pgErr *PGError
+			if !errors.As(execErr, &pgErr) {
+				return fmt.Errorf("restore aborted at line %d: %w", stmt.Line, execErr)
+			}
+			failed++
+			logrus.Warnf("Statement at line %d failed: %v", stmt.Line, execErr)
+			if firstErr == nil {
+				firstErr = fmt.Errorf("line %d: %w", stmt.Line, execErr)
+			}
+		}
+	}
+
+	if err := script.Err(); err != nil {
+		return fmt.Errorf("failed to read dump: %w", err)
+	}
+	if failed > 0 {
+		return fmt.Errorf("restore finished with %d of %d statements failed; first error: %w", failed, statements, firstErr)
+	}
+
+	logrus.Infof("Database restored successfully (%d statements)", statements)
+	return nil
+}
+
+// firstLine returns the first line of a statement for logging
+func firstLine(sql string) string {
+	if idx := strings.IndexByte(sql, '\n'); idx >= 0 {
+		return sql[:idx] + " ..."
+	}
+	return sql
+}
@@ -0,0 +1,729 @@
+package database
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Protocol constants of the PostgreSQL frontend/backend protocol version 3.0
+const (
+	pgProtocolVersion = 196608
+	pgSSLRequestCode  = 80877103
+
+	pgAuthOK                = 0
+	pgAuthCleartextPassword = 3
+	pgAuthMD5Password       = 5
+	pgAuthSASL              = 10
+	pgAuthSASLContinue      = 11
+	pgAuthSASLFinal         = 12
+
+	pgDialTimeout = 30 * time.Second
+)
+
+// PGError is an ErrorResponse sent by the PostgreSQL server
+type PGError struct {
+	Severity string
+	Code     string // SQLSTATE
+	Message  string
+	Detail   string
+	Hint     string
+}
+
+func (e *PGError) Error() string {
+	msg := fmt.Sprintf("%s: %s (SQLSTATE %s)", e.Severity, e.Message, e.Code)
+	if e.Detail != "" {
+		msg += "\nDETAIL: " + e.Detail
+	}
+	if e.Hint != "" {
+		msg += "\nHINT: " + e.Hint
+	}
+	return msg
+}
+
+// pgConn is a minimal PostgreSQL client connection using the simple query protocol
+type pgConn struct {
+	conn          net.Conn
+	reader        *bufio.Reader
+	writer        *bufio.Writer
+	serverVersion string
+	txStatus      byte
+}
+
+// pgResult holds the rows returned by a simple query
+// Values are in text format; NULL is returned as an empty string
+type pgResult struct {
+	Columns []string
+	Rows    [][]string
+	Tag     string
+}
+
+// connectNative opens an authenticated connection to the server described by config
+func connectNative(config *DatabaseConfig) (*pgConn, error) {
+	if config == nil {
+		return nil, fmt.Errorf("database config is nil")
+	}
+
+	address := net.JoinHostPort(config.Host, strconv.Itoa(config.Port))
+	netConn, err := net.DialTimeout("tcp", address, pgDialTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", address, err)
+	}
+
+	c := &pgConn{conn: netConn}
+	if err := c.negotiateTLS(config); err != nil {
+		netConn.Close()
+		return nil, err
+	}
+	c.reader = bufio.NewReaderSize(c.conn, 64*1024)
+	c.writer = bufio.NewWriterSize(c.conn, 64*1024)
+
+	if err := c.startup(config); err != nil {
+		c.conn.Close()
+		return nil, err
+	}
+
+	logrus.Debugf("Connected to PostgreSQL %s at %s (native protocol)", c.serverVersion, address)
+	return c, nil
+}
+
+// negotiateTLS upgrades the connection according to the sslmode of the URL
+func (c *pgConn) negotiateTLS(config *DatabaseConfig) error {
+	mode := config.SSLMode
+	if mode == "" {
+		mode = "prefer"
+	}
+	if mode == "disable" || mode == "allow" {
+		return nil
+	}
+
+	request := make([]byte, 8)
+	binary.BigEndian.PutUint32(request[0:4], 8)
+	binary.BigEndian.PutUint32(request[4:8], pgSSLRequestCode)
+	if _, err := c.conn.Write(request); err != nil {
+		return fmt.Errorf("failed to send SSL request: %w", err)
+	}
+
+	answer := make([]byte, 1)
+	if _, err := io.ReadFull(c.conn, answer); err != nil {
+		return fmt.Errorf("failed to read SSL response: %w", err)
+	}
+
+	if answer[0] != 'S' {
+		if mode == "prefer" {
+			logrus.Debug("Server does not support SSL, continuing without encryption")
+			return nil
+		}
+		return fmt.Errorf("server does not support SSL, but sslmode=%s was requested", mode)
+	}
+
+	tlsConfig := &tls.Config{ServerName: config.Host}
+	if config.SSLRootCert != "" {
+		pem, err := os.ReadFile(config.SSLRootCert)
+		if err != nil {
+			return fmt.Errorf("failed to read sslrootcert: %w", err)
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no certificates found in sslrootcert %s", config.SSLRootCert)
+		}
+	}
+
+	switch mode {
+	case "prefer", "require":
+		// Encrypt without verifying the certificate, as libpq does
+		tlsConfig.InsecureSkipVerify = true
+	case "verify-ca":
+		// Verify the chain but not the host name
+		roots := tlsConfig.RootCAs
+		tlsConfig.InsecureSkipVerify = true
+		tlsConfig.VerifyConnection = func(state tls.ConnectionState) error {
+			if len(state.PeerCertificates) == 0 {
+				return fmt.Errorf("server did not present a certificate")
+			}
+			opts := x509.VerifyOptions{Roots: roots, Intermediates: x509.NewCertPool()}
+			for _, cert := range state.PeerCertificates[1:] {
+				opts.Intermediates.AddCert(cert)
+			}
+			_, err := state.PeerCertificates[0].Verify(opts)
+			return err
+		}
+	case "verify-full":
+	default:
+		return fmt.Errorf("unsupported sslmode %q", mode)
+	}
+
+	tlsConn := tls.Client(c.conn, tlsConfig)
+	if err := tlsConn.Handshake(); err != nil {
+		return fmt.Errorf("TLS handshake failed: %w", err)
+	}
+	c.conn = tlsConn
+	return nil
+}
+
+// startup sends the startup message and completes authentication
+func (c *pgConn) startup(config *DatabaseConfig) error {
+	var body []byte
+	body = binary.BigEndian.AppendUint32(body, pgProtocolVersion)
+	for _, kv := range [][2]string{
+		{"user", config.User},
+		{"database", config.Database},
+		{"application_name", "owuicli"},
+		{"client_encoding", "UTF8"},
+	} {
+		body = append(body, kv[0]...)
+		body = append(body, 0)
+		body = append(body, kv[1]...)
+		body = append(body, 0)
+	}
+	body = append(body, 0)
+
+	message := binary.BigEndian.AppendUint32(nil, uint32(len(body)+4))
+	message = append(message, body...)
+	if _, err := c.writer.Write(message); err != nil {
+		return fmt.Errorf("failed to send startup message: %w", err)
+	}
+	if err := c.writer.Flush(); err != nil {
+		return fmt.Errorf("failed to send startup message: %w", err)
+	}
+
+	var scram *scramClient
+	for {
+		msgType, payload, err := c.receive()
+		if err != nil {
+			return err
+		}
+
+		switch msgType {
+		case 'R':
+			if len(payload) < 4 {
+				return fmt.Errorf("malformed authentication request")
+			}
+			code := binary.BigEndian.Uint32(payload[:4])
+			data := payload[4:]
+			switch code {
+			case pgAuthOK:
+				if scram != nil && !scram.verified {
+					return fmt.Errorf("server finished SCRAM authentication without proving its identity")
+				}
+			case pgAuthCleartextPassword:
+				if err := c.sendPassword(config.Password); err != nil {
+					return err
+				}
+			case pgAuthMD5Password:
+				if len(data) < 4 {
+					return fmt.Errorf("malformed MD5 authentication request")
+				}
+				if err := c.sendPassword(md5Password(config.User, config.Password, data[:4])); err != nil {
+					return err
+				}
+			case pgAuthSASL:
+				mechanisms := splitCStrings(data)
+				if !containsString(mechanisms, "SCRAM-SHA-256") {
+					return fmt.Errorf("unsupported SASL mechanisms: %s", strings.Join(mechanisms, ", "))
+				}
+				scram, err = newScramClient(config.Password)
+				if err != nil {
+					return err
+				}
+				if err := c.sendSASLInitial("SCRAM-SHA-256", scram.clientFirst()); err != nil {
+					return err
+				}
+			case pgAuthSASLContinue:
+				if scram == nil {
+					return fmt.Errorf("unexpected SASL continue message")
+				}
+				final, err := scram.clientFinal(data)
+				if err != nil {
+					return err
+				}
+				if err := c.send('p', final); err != nil {
+					return err
+				}
+			case pgAuthSASLFinal:
+				if scram == nil {
+					return fmt.Errorf("unexpected SASL final message")
+				}
+				if err := scram.verifyServer(data); err != nil {
+					return err
+				}
+			default:
+				return fmt.Errorf("unsupported authentication method (code %d)", code)
+			}
+		case 'S':
+			params := splitCStrings(payload)
+			if len(params) == 2 && params[0] == "server_version" {
+				c.serverVersion = params[1]
+			}
+		case 'K', 'N':
+			// Backend key data and notices are not needed
+		case 'Z':
+			if len(payload) > 0 {
+				c.txStatus = payload[0]
+			}
+			return nil
+		case 'E':
+			pgErr := parsePGError(payload)
+			if pgErr.Code == "28P01" || pgErr.Code == "28000" {
+				return fmt.Errorf("%w: %s", ErrAuthentication, pgErr.Message)
+			}
+			return pgErr
+		default:
+			return fmt.Errorf("unexpected message %q during startup", msgType)
+		}
+	}
+}
+
+// Close terminates the session
+func (c *pgConn) Close() error {
+	c.send('X', nil)
+	return c.conn.Close()
+}
+
+// Exec runs one or more statements and discards any rows
+func (c *pgConn) Exec(sql string) error {
+	_, err := c.Query(sql)
+	return err
+}
+
+// Query runs a simple query and returns the rows of the last statement
+func (c *pgConn) Query(sql string) (*pgResult, error) {
+	if err := c.send('Q', cString(sql)); err != nil {
+		return nil, err
+	}
+
+	result := &pgResult{}
+	var queryErr error
+	for {
+		msgType, payload, err := c.receive()
+		if err != nil {
+			return nil, err
+		}
+
+		switch msgType {
+		case 'T':
+			result = &pgResult{Columns: parseRowDescription(payload)}
+		case 'D':
+			result.Rows = append(result.Rows, parseDataRow(payload))
+		case 'C':
+			result.Tag = strings.TrimRight(string(payload), "\x00")
+		case 'I', 'N', 'S':
+		case 'E':
+			queryErr = parsePGError(payload)
+		case 'G':
+			// COPY FROM STDIN issued through Query: refuse to send data
+			c.send('f', cString("COPY FROM STDIN is not supported by Query"))
+		case 'H':
+			// COPY TO STDOUT issued through Query: drain the data
+		case 'd', 'c':
+		case 'Z':
+			if len(payload) > 0 {
+				c.txStatus = payload[0]
+			}
+			return result, queryErr
+		default:
+			return nil, fmt.Errorf("unexpected message %q in query response", msgType)
+		}
+	}
+}
+
+// CopyOut runs a COPY ... TO STDOUT statement and writes the data to w
+// It returns the number of rows copied
+func (c *pgConn) CopyOut(sql string, w io.Writer) (int64, error) {
+	if err := c.send('Q', cString(sql)); err != nil {
+		return 0, err
+	}
+
+	var rows int64
+	var copyErr error
+	for {
+		msgType, payload, err := c.receive()
+		if err != nil {
+			return rows, err
+		}
+
+		switch msgType {
+		case 'H', 'c', 'N', 'S':
+		case 'd':
+			if copyErr != nil {
+				continue
+			}
+			if _, err := w.Write(payload); err != nil {
+				// Keep draining the protocol so the connection stays usable
+				copyErr = fmt.Errorf("failed to write COPY data: %w", err)
+				continue
+			}
+			rows++
+		case 'C':
+			if n, ok := parseCommandRows(payload); ok {
+				rows = n
+			}
+		case 'E':
+			copyErr = parsePGError(payload)
+		case 'Z':
+			if len(payload) > 0 {
+				c.txStatus = payload[0]
+			}
+			return rows, copyErr
+		default:
+			return rows, fmt.Errorf("unexpected message %q in COPY response", msgType)
+		}
+	}
+}
+
+// CopyIn runs a COPY ... FROM STDIN statement and streams r to the server
+// The data is sent as-is and must not contain the \. end marker
+func (c *pgConn) CopyIn(sql string, r io.Reader) (int64, error) {
+	if err := c.send('Q', cString(sql)); err != nil {
+		return 0, err
+	}
+
+	var rows int64
+	var copyErr error
+	for {
+		msgType, payload, err := c.receive()
+		if err != nil {
+			return rows, err
+		}
+
+		switch msgType {
+		case 'G':
+			if err := c.sendCopyData(r); err != nil {
+				c.send('f', cString(err.Error()))
+				copyErr = err
+				continue
+			}
+			if err := c.send('c', nil); err != nil {
+				return rows, err
+			}
+		case 'C':
+			if n, ok := parseCommandRows(payload); ok {
+				rows = n
+			}
+		case 'N', 'S':
+		case 'E':
+			if copyErr == nil {
+				copyErr = parsePGError(payload)
+			}
+		case 'Z':
+			if len(payload) > 0 {
+				c.txStatus = payload[0]
+			}
+			return rows, copyErr
+		default:
+			return rows, fmt.Errorf("unexpected message %q in COPY response", msgType)
+		}
+	}
+}
+
+// sendCopyData streams r as CopyData messages
+func (c *pgConn) sendCopyData(r io.Reader) error {
+	buf := make([]byte, 64*1024)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			if sendErr := c.sendNoFlush('d', buf[:n]); sendErr != nil {
+				return sendErr
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read COPY data: %w", err)
+		}
+	}
+}
+
+// sendPassword sends a PasswordMessage
+func (c *pgConn) sendPassword(password string) error {
+	return c.send('p', cString(password))
+}
+
+// sendSASLInitial sends a SASLInitialResponse
+func (c *pgConn) sendSASLInitial(mechanism string, data []byte) error {
+	body := cString(mechanism)
+	body = binary.BigEndian.AppendUint32(body, uint32(len(data)))
+	body = append(body, data...)
+	return c.send('p', body)
+}
+
+// send writes a message and flushes the connection
+func (c *pgConn) send(msgType byte, body []byte) error {
+	if err := c.sendNoFlush(msgType, body); err != nil {
+		return err
+	}
+	if err := c.writer.Flush(); err != nil {
+		return fmt.Errorf("failed to send message: %w", err)
+	}
+	return nil
+}
+
+// sendNoFlush buffers a message
+func (c *pgConn) sendNoFlush(msgType byte, body []byte) error {
+	header := make([]byte, 5)
+	header[0] = msgType
+	binary.BigEndian.PutUint32(header[1:], uint32(len(body)+4))
+	if _, err := c.writer.Write(header); err != nil {
+		return fmt.Errorf("failed to send message: %w", err)
+	}
+	if _, err := c.writer.Write(body); err != nil {
+		return fmt.Errorf("failed to send message: %w", err)
+	}
+	return nil
+}
+
+// receive reads one message from the server
+func (c *pgConn) receive() (byte, []byte, error) {
+	header := make([]byte, 5)
+	if _, err := io.ReadFull(c.reader, header); err != nil {
+		return 0, nil, fmt.Errorf("failed to read from server: %w", err)
+	}
+	length := int(binary.BigEndian.Uint32(header[1:]))
+	if length < 4 {
+		return 0, nil, fmt.Errorf("invalid message length %d", length)
+	}
+	payload := make([]byte, length-4)
+	if _, err := io.ReadFull(c.reader, payload); err != nil {
+		return 0, nil, fmt.Errorf("failed to read from server: %w", err)
+	}
+	if header[0] == 'N' {
+		notice := parsePGError(payload)
+		logrus.Debugf("PostgreSQL %s: %s", notice.Severity, notice.Message)
+	}
+	return header[0], payload, nil
+}
+
+// parsePGError decodes the fields of an ErrorResponse or NoticeResponse
+func parsePGError(payload []byte) *PGError {
+	e := &PGError{}
+	for len(payload) > 1 {
+		field := payload[0]
+		end := indexZero(payload[1:])
+		value := string(payload[1 : 1+end])
+		payload = payload[2+end:]
+		switch field {
+		case 'V':
+			e.Severity = value
+		case 'S':
+			if e.Severity == "" {
+				e.Severity = value
+			}
+		case 'C':
+			e.Code = value
+		case 'M':
+			e.Message = value
+		case 'D':
+			e.Detail = value
+		case 'H':
+			e.Hint = value
+		}
+	}
+	return e
+}
+
+// parseRowDescription returns the column names of a RowDescription
+func parseRowDescription(payload []byte) []string {
+	count := int(binary.BigEndian.Uint16(payload[:2]))
+	payload = payload[2:]
+	columns := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		end := indexZero(payload)
+		columns = append(columns, string(payload[:end]))
+		// Skip the name terminator and the 18 bytes of field metadata
+		payload = payload[end+1+18:]
+	}
+	return columns
+}
+
+// parseDataRow returns the text values of a DataRow
+func parseDataRow(payload []byte) []string {
+	count := int(binary.BigEndian.Uint16(payload[:2]))
+	payload = payload[2:]
+	values := make([]string, count)
+	for i := 0; i < count; i++ {
+		length := int32(binary.BigEndian.Uint32(payload[:4]))
+		payload = payload[4:]
+		if length < 0 {
+			continue
+		}
+		values[i] = string(payload[:length])
+		payload = payload[length:]
+	}
+	return values
+}
+
+// parseCommandRows extracts the row count of a CommandComplete tag such as "COPY 42"
+func parseCommandRows(payload []byte) (int64, bool) {
+	fields := strings.Fields(strings.TrimRight(string(payload), "\x00"))
+	if len(fields) == 0 {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(fields[len(fields)-1], 10, 64)
+	return n, err == nil
+}
+
+// md5Password computes the response to an MD5 authentication request
+func md5Password(user, password string, salt []byte) string {
+	inner := md5.Sum([]byte(password + user))
+	innerHex := hex.EncodeToString(inner[:])
+	outer := md5.Sum(append([]byte(innerHex), salt...))
+	return "md5" + hex.EncodeToString(outer[:])
+}
+
+// scramClient implements the client side of SCRAM-SHA-256 (RFC 5802, RFC 7677)
+type scramClient struct {
+	password       string
+	clientNonce    string
+	clientFirstMsg string
+	authMessage    string
+	saltedPassword []byte
+	verified       bool
+}
+
+func newScramClient(password string) (*scramClient, error) {
+	nonce := make([]byte, 18)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate SCRAM nonce: %w", err)
+	}
+	return &scramClient{
+		password:    password,
+		clientNonce: base64.RawStdEncoding.EncodeToString(nonce),
+	}, nil
+}
+
+// clientFirst returns the client-first-message; the user name is taken from the startup message
+func (s *scramClient) clientFirst() []byte {
+	s.clientFirstMsg = "n=,r=" + s.clientNonce
+	return []byte("n,," + s.clientFirstMsg)
+}
+
+// clientFinal answers the server-first-message with the client proof
+func (s *scramClient) clientFinal(serverFirst []byte) ([]byte, error) {
+	attrs := scramAttributes(string(serverFirst))
+	nonce, saltB64, iterStr := attrs["r"], attrs["s"], attrs["i"]
+	if !strings.HasPrefix(nonce, s.clientNonce) || len(nonce) == len(s.clientNonce) {
+		return nil, fmt.Errorf("invalid SCRAM server nonce")
+	}
+	salt, err := base64.StdEncoding.DecodeString(saltB64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SCRAM salt: %w", err)
+	}
+	iterations, err := strconv.Atoi(iterStr)
+	if err != nil || iterations <= 0 {
+		return nil, fmt.Errorf("invalid SCRAM iteration count %q", iterStr)
+	}
+
+	s.saltedPassword, err = pbkdf2.Key(sha256.New, s.password, salt, iterations, sha256.Size)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive SCRAM key: %w", err)
+	}
+
+	clientFinalWithoutProof := "c=biws,r=" + nonce
+	s.authMessage = s.clientFirstMsg + "," + string(serverFirst) + "," + clientFinalWithoutProof
+
+	clientKey := hmacSHA256(s.saltedPassword, "Client Key")
+	storedKey := sha256.Sum256(clientKey)
+	signature := hmacSHA256(storedKey[:], s.authMessage)
+	proof := make([]byte, len(clientKey))
+	for i := range clientKey {
+		proof[i] = clientKey[i] ^ signature[i]
+	}
+
+	return []byte(clientFinalWithoutProof + ",p=" + base64.StdEncoding.EncodeToString(proof)), nil
+}
+
+// verifyServer checks the server signature of the server-final-message
+func (s *scramClient) verifyServer(serverFinal []byte) error {
+	attrs := scramAttributes(string(serverFinal))
+	if e, ok := attrs["e"]; ok {
+		return fmt.Errorf("%w: SCRAM error %s", ErrAuthentication, e)
+	}
+	signature, err := base64.StdEncoding.DecodeString(attrs["v"])
+	if err != nil {
+		return fmt.Errorf("invalid SCRAM server signature: %w", err)
+	}
+	serverKey := hmacSHA256(s.saltedPassword, "Server Key")
+	expected := hmacSHA256(serverKey, s.authMessage)
+	if !hmac.Equal(signature, expected) {
+		return errors.New("SCRAM server signature mismatch")
+	}
+	s.verified = true
+	return nil
+}
+
+// scramAttributes splits a SCRAM message into its key=value attributes
+func scramAttributes(msg string) map[string]string {
+	attrs := map[string]string{}
+	for _, part := range strings.Split(msg, ",") {
+		if len(part) >= 2 && part[1] == '=' {
+			attrs[part[:1]] = part[2:]
+		}
+	}
+	return attrs
+}
+
+func hmacSHA256(key []byte, msg string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(msg))
+	return mac.Sum(nil)
+}
+
+// cString returns s as a NUL-terminated byte slice
+func cString(s string) []byte {
+	return append([]byte(s), 0)
+}
+
+// splitCStrings splits a sequence of NUL-terminated strings
+func splitCStrings(data []byte) []string {
+	var out []string
+	for len(data) > 0 {
+		end := indexZero(data)
+		if end == 0 {
+			break
+		}
+		out = append(out, string(data[:end]))
+		if end >= len(data) {
+			break
+		}
+		data = data[end+1:]
+	}
+	return out
+}
+
+// indexZero returns the index of the first NUL byte, or len(data)
+func indexZero(data []byte) int {
+	for i, b := range data {
+		if b == 0 {
+			return i
+		}
+	}
+	return len(data)
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,122 @@
+package database
+
+import (
+	"bytes"
+	"testing"
+)
+
+// SCRAM-SHA-256 exchange from RFC 7677, section 3
+const (
+	rfc7677Password     = "pencil"
+	rfc7677ClientNonce  = "rOprNGfwEbeRWgbNEkqO"
+	rfc7677ServerFirst  = "r=rOprNGfwEbeRWgbNEkqO%hvYDpWUa2RaTCAfuxFIlj)hNlF$k0,s=W22ZaJ0SNY7soEsUEjb6gQ==,i=4096"
+	rfc7677ClientFinal  = "c=biws,r=rOprNGfwEbeRWgbNEkqO%hvYDpWUa2RaTCAfuxFIlj)hNlF$k0,p=dHzbZapWIk4jUhN+Ute9ytag9zjfMHgsqmmiz7AndVQ="
+	rfc7677ServerFinal  = "v=6rriTRBi23WpRR/wtup+mMhUZUn/dB5nLTJRsjl95G4="
+	rfc7677ClientFirstN = "n=user,r=" + rfc7677ClientNonce
+)
+
+// rfc7677Client returns a client in the state after sending the RFC's client-first-message
+// PostgreSQL clients send an empty user name, so the message is set instead of built by clientFirst
+func rfc7677Client() *scramClient {
+	return &scramClient{
+		password:       rfc7677Password,
+		clientNonce:    rfc7677ClientNonce,
+		clientFirstMsg: rfc7677ClientFirstN,
+	}
+}
+
+func TestScramClientProofMatchesRFC7677(t *testing.T) {
+	s := rfc7677Client()
+
+	final, err := s.clientFinal([]byte(rfc7677ServerFirst))
+	if err != nil {
+		t.Fatalf("clientFinal: %v", err)
+	}
+	if string(final) != rfc7677ClientFinal {
+		t.Errorf("client-final-message:\n got %s\nwant %s", final, rfc7677ClientFinal)
+	}
+
+	if err := s.verifyServer([]byte(rfc7677ServerFinal)); err != nil {
+		t.Fatalf("verifyServer: %v", err)
+	}
+	if !s.verified {
+		t.Error("server signature not marked as verified")
+	}
+}
+
+func TestScramClientRejectsBadServerMessages(t *testing.T) {
+	s := rfc7677Client()
+	if _, err := s.clientFinal([]byte("r=otherNonce,s=W22ZaJ0SNY7soEsUEjb6gQ==,i=4096")); err == nil {
+		t.Error("accepted a server nonce that does not extend the client nonce")
+	}
+	if _, err := s.clientFinal([]byte("r=" + rfc7677ClientNonce + ",s=W22ZaJ0SNY7soEsUEjb6gQ==,i=4096")); err == nil {
+		t.Error("accepted a server nonce without a server part")
+	}
+	if _, err := s.clientFinal([]byte("r=" + rfc7677ClientNonce + "xyz,s=W22ZaJ0SNY7soEsUEjb6gQ==,i=0")); err == nil {
+		t.Error("accepted an iteration count of 0")
+	}
+
+	s = rfc7677Client()
+	if _, err := s.clientFinal([]byte(rfc7677ServerFirst)); err != nil {
+		t.Fatalf("clientFinal: %v", err)
+	}
+	if err := s.verifyServer([]byte("v=AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=")); err == nil {
+		t.Error("accepted a wrong server signature")
+	}
+	if err := s.verifyServer([]byte("e=invalid-proof")); err == nil {
+		t.Error("accepted a server error")
+	}
+}
+
+func TestScramClientFirstMessage(t *testing.T) {
+	s, err := newScramClient("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := string(s.clientFirst())
+	want := "n,,n=,r=" + s.clientNonce
+	if first != want {
+		t.Errorf("client-first-message = %q, want %q", first, want)
+	}
+	if s.clientFirstMsg != "n=,r="+s.clientNonce {
+		t.Errorf("client-first-message-bare = %q", s.clientFirstMsg)
+	}
+}
+
+func TestMD5Password(t *testing.T) {
+	got := md5Password("postgres", "secret", []byte{1, 2, 3, 4})
+	if want := "md5bb41a296aab6baccb36ff243a562abff"; got != want {
+		t.Errorf("md5Password = %s, want %s", got, want)
+	}
+}
+
+func TestParseDataRow(t *testing.T) {
+	// Two columns: "abc" and NULL
+	payload := []byte{0, 2, 0, 0, 0, 3, 'a', 'b', 'c', 0xff, 0xff, 0xff, 0xff}
+	row := parseDataRow(payload)
+	if len(row) != 2 || row[0] != "abc" || row[1] != "" {
+		t.Errorf("parseDataRow = %q", row)
+	}
+}
+
+func TestSplitCStrings(t *testing.T) {
+	got := splitCStrings(append(append(cString("SCRAM-SHA-256"), cString("SCRAM-SHA-256-PLUS")...), 0))
+	if len(got) != 2 || got[0] != "SCRAM-SHA-256" || got[1] != "SCRAM-SHA-256-PLUS" {
+		t.Errorf("splitCStrings = %q", got)
+	}
+	if !bytes.Equal(cString("a"), []byte{'a', 0}) {
+		t.Errorf("cString = %v", cString("a"))
+	}
+}
+
+func TestParseCommandRows(t *testing.T) {
+	for tag, want := range map[string]int64{"COPY 42\x00": 42, "INSERT 0 3\x00": 3, "UPDATE 0\x00": 0} {
+		got, ok := parseCommandRows([]byte(tag))
+		if !ok || got != want {
+			t.Errorf("parseCommandRows(%q) = %d, %v; want %d", tag, got, ok, want)
+		}
+	}
+	if _, ok := parseCommandRows([]byte("CREATE TABLE\x00")); ok {
+		t.Error("parseCommandRows found a row count in CREATE TABLE")
+	}
+}
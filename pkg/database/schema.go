@@ -0,0 +1,221 @@
+package database
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// SchemaInfo describes the Open WebUI schema of a database
+type SchemaInfo struct {
+	Tables          int    // tables besides the internal ones; 0 for an empty database
+	AlembicRevision string // Alembic migration head(s), empty if the database has no alembic_version table
+}
+
+// Empty reports whether the database has no tables
+func (s *SchemaInfo) Empty() bool {
+	return s.Tables == 0
+}
+
+// schemaInfoSQL counts the tables outside the system schemas and checks for the Alembic version table
+const schemaInfoSQL = `SELECT (SELECT count(*) FROM pg_tables WHERE schemaname NOT IN ('pg_catalog', 'information_schema') AND schemaname NOT LIKE 'pg\_%'), to_regclass('public.alembic_version') IS NOT NULL`
+
+// alembicRevisionSQL lists the Alembic heads; Open WebUI has one, a branched history has several
+const alembicRevisionSQL = `SELECT version_num FROM alembic_version ORDER BY version_num`
+
+// GetSchemaInfo reads the table count and Alembic revision of a PostgreSQL database
+func GetSchemaInfo(config *DatabaseConfig) (*SchemaInfo, error) {
+	if config == nil {
+		return nil, fmt.Errorf("database config is nil")
+	}
+
+	rows, err := querySchemaRows(config, schemaInfoSQL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read database schema: %w", err)
+	}
+	if len(rows) == 0 || len(rows[0]) < 2 {
+		return nil, fmt.Errorf("failed to read database schema: no result")
+	}
+	tables, err := strconv.Atoi(rows[0][0])
+	if err != nil {
+		return nil, fmt.Errorf("failed to read database schema: unexpected table count %q", rows[0][0])
+	}
+	info := &SchemaInfo{Tables: tables}
+	if rows[0][1] != "t" {
+		return info, nil
+	}
+
+	rows, err = querySchemaRows(config, alembicRevisionSQL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Alembic revision: %w", err)
+	}
+	info.AlembicRevision = joinRevisions(rows)
+	return info, nil
+}
+
+// querySchemaRows runs a query with the native client or psql and returns its rows in text format
+func querySchemaRows(config *DatabaseConfig, sql string) ([][]string, error) {
+	if ResolveBackend(config) == BackendNative {
+		conn, err := connectNative(config)
+		if err != nil {
+			return nil, err
+		}
+		defer conn.Close()
+
+		res, err := conn.Query(sql)
+		if err != nil {
+			return nil, err
+		}
+		return res.Rows, nil
+	}
+
+	cmd := exec.Command(GetPsqlPath(),
+		"-h", config.Host,
+		"-p", strconv.Itoa(config.Port),
+		"-U", config.User,
+		"-d", config.Database,
+		"-t", // Tuples only
+		"-A", // Unaligned
+		"-F", "\t",
+		"-c", sql,
+	)
+	cmd.Env = append(cmd.Env, fmt.Sprintf("PGPASSWORD=%s", config.Password))
+
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+	var rows [][]string
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		if line != "" {
+			rows = append(rows, strings.Split(line, "\t"))
+		}
+	}
+	return rows, nil
+}
+
+// GetSQLiteSchemaInfo reads the table count and Alembic revision of a SQLite database
+// A missing file is an empty database
+func GetSQLiteSchemaInfo(path string) (*SchemaInfo, error) {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return &SchemaInfo{}, nil
+	}
+
+	output, err := RunSQLite(path, `SELECT count(*), coalesce(sum(name = 'alembic_version'), 0) FROM sqlite_master
+WHERE type = 'table' AND name NOT LIKE 'sqlite_%';`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read database schema: %w", err)
+	}
+	tables, hasAlembic, _ := strings.Cut(strings.TrimSpace(output), "|")
+	info := &SchemaInfo{}
+	if info.Tables, err = strconv.Atoi(tables); err != nil {
+		return nil, fmt.Errorf("failed to read database schema: unexpected output %q", output)
+	}
+	if hasAlembic != "1" {
+		return info, nil
+	}
+
+	output, err = RunSQLite(path, alembicRevisionSQL+";")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Alembic revision: %w", err)
+	}
+	var rows [][]string
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		if line != "" {
+			rows = append(rows, []string{line})
+		}
+	}
+	info.AlembicRevision = joinRevisions(rows)
+	return info, nil
+}
+
+// joinRevisions joins the revisions of the first column with commas
+func joinRevisions(rows [][]string) string {
+	revisions := make([]string, 0, len(rows))
+	for _, row := range rows {
+		if len(row) > 0 && row[0] != "" {
+			revisions = append(revisions, row[0])
+		}
+	}
+	return strings.Join(revisions, ",")
+}
+
+// ReadMetadata reads database/metadata.json from a backup ZIP
+// It returns nil without an error if the backup has no database section
+func ReadMetadata(r *zip.Reader) (*DatabaseBackupMetadata, error) {
+	for _, f := range r.File {
+		if f.Name != MetadataEntry {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("failed to open %s: %w", MetadataEntry, err)
+		}
+		defer rc.Close()
+
+		data, err := io.ReadAll(rc)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", MetadataEntry, err)
+		}
+		var metadata DatabaseBackupMetadata
+		if err := json.Unmarshal(data, &metadata); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", MetadataEntry, err)
+		}
+		return &metadata, nil
+	}
+	return nil, nil
+}
+
+// CompareOpenWebUIVersions compares two Open WebUI versions such as 0.6.5 or v0.6.10
+// It returns -1, 0 or 1, and false if either version cannot be parsed
+func CompareOpenWebUIVersions(a, b string) (int, bool) {
+	pa, ok := parseAppVersion(a)
+	if !ok {
+		return 0, false
+	}
+	pb, ok := parseAppVersion(b)
+	if !ok {
+		return 0, false
+	}
+	for i := 0; i < len(pa) || i < len(pb); i++ {
+		var x, y int
+		if i < len(pa) {
+			x = pa[i]
+		}
+		if i < len(pb) {
+			y = pb[i]
+		}
+		if x != y {
+			if x < y {
+				return -1, true
+			}
+			return 1, true
+		}
+	}
+	return 0, true
+}
+
+// parseAppVersion splits a dotted version into numbers, ignoring a v prefix and pre-release suffix
+func parseAppVersion(version string) ([]int, bool) {
+	version = strings.TrimPrefix(strings.TrimSpace(version), "v")
+	if i := strings.IndexAny(version, "-+ "); i >= 0 {
+		version = version[:i]
+	}
+	if version == "" {
+		return nil, false
+	}
+	var parts []int
+	for _, part := range strings.Split(version, ".") {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, false
+		}
+		parts = append(parts, n)
+	}
+	return parts, true
+}
@@ -0,0 +1,314 @@
+package database
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// copyFromStdin matches a COPY statement that reads its data from the script
+var copyFromStdin = regexp.MustCompile(`(?is)^COPY\s.*\sFROM\s+stdin\b`)
+
+// scriptBufferSize is the read buffer of a script; longer COPY rows are passed on in pieces
+const scriptBufferSize = 64 * 1024
+
+// maxDollarTag is the longest dollar-quote tag that is recognized
+const maxDollarTag = 64
+
+// scriptStatement is one statement of a plain SQL script
+// Copy streams the inline data of a COPY ... FROM stdin statement, without the \. terminator;
+// it is only valid until the next call to Next
+type scriptStatement struct {
+	SQL    string
+	Copy   io.Reader
+	IsCopy bool
+	Line   int
+}
+
+// sqlScript splits a plain SQL script such as pg_dump output into statements as it is read
+// It understands quoted strings, quoted identifiers, dollar quoting, comments and
+// inline COPY data; psql meta-commands (lines starting with a backslash) are skipped
+type sqlScript struct {
+	r         *bufio.Reader
+	line      int
+	prev      [2]byte // last two bytes read, most recent first
+	lineStart bool    // only whitespace was read since the last newline
+	copy      *copyData
+	err       error
+}
+
+func newSQLScript(r io.Reader) *sqlScript {
+	return &sqlScript{r: bufio.NewReaderSize(r, scriptBufferSize), line: 1, lineStart: true}
+}
+
+// Err returns the first error reading the script
+func (s *sqlScript) Err() error {
+	return s.err
+}
+
+// Next returns the next statement, or nil at the end of the script
+func (s *sqlScript) Next() *scriptStatement {
+	// Data of the previous COPY that the caller did not read
+	if s.copy != nil {
+		io.Copy(io.Discard, s.copy)
+		s.copy = nil
+	}
+
+	var sb strings.Builder
+	startLine := 0
+
+	for {
+		c, ok := s.peek(0)
+		if !ok {
+			break
+		}
+
+		// psql meta-command at the start of a line
+		if c == '\\' && s.lineStart {
+			s.skipLine()
+			continue
+		}
+
+		switch {
+		case c == '\n':
+			s.read()
+			sb.WriteByte(c)
+			continue
+		case c == '-' && s.peekIs(1, '-'):
+			s.skipLine()
+			sb.WriteByte('\n')
+			continue
+		case c == '/' && s.peekIs(1, '*'):
+			s.skipBlockComment()
+			sb.WriteByte(' ')
+			continue
+		}
+
+		if startLine == 0 && c != ' ' && c != '\t' && c != '\r' {
+			startLine = s.line
+		}
+
+		switch {
+		case c == '\'':
+			escapes := (s.prev[0] == 'E' || s.prev[0] == 'e') && !isIdentChar(s.prev[1])
+			s.quoted(&sb, '\'', escapes)
+		case c == '"':
+			s.quoted(&sb, '"', false)
+		case c == '$' && !isIdentChar(s.prev[0]):
+			if tag := s.dollarTag(); tag != "" {
+				s.dollarQuoted(&sb, tag)
+			} else {
+				s.read()
+				sb.WriteByte(c)
+			}
+		case c == ';':
+			s.read()
+			stmt := &scriptStatement{SQL: strings.TrimSpace(sb.String()), Line: startLine}
+			if copyFromStdin.MatchString(stmt.SQL) {
+				stmt.IsCopy = true
+				s.skipLine()
+				s.copy = &copyData{s: s}
+				stmt.Copy = s.copy
+			}
+			if stmt.SQL == "" {
+				sb.Reset()
+				startLine = 0
+				continue
+			}
+			return stmt
+		default:
+			s.read()
+			sb.WriteByte(c)
+		}
+	}
+
+	if rest := strings.TrimSpace(sb.String()); rest != "" {
+		return &scriptStatement{SQL: rest, Line: startLine}
+	}
+	return nil
+}
+
+// read consumes one byte and tracks lines
+func (s *sqlScript) read() (byte, bool) {
+	c, err := s.r.ReadByte()
+	if err != nil {
+		if err != io.EOF && s.err == nil {
+			s.err = err
+		}
+		return 0, false
+	}
+	s.prev[1], s.prev[0] = s.prev[0], c
+	switch c {
+	case '\n':
+		s.line++
+		s.lineStart = true
+	case ' ', '\t', '\r':
+	default:
+		s.lineStart = false
+	}
+	return c, true
+}
+
+// peek returns the byte at offset without consuming it
+func (s *sqlScript) peek(offset int) (byte, bool) {
+	b, err := s.r.Peek(offset + 1)
+	if len(b) > offset {
+		return b[offset], true
+	}
+	if err != nil && err != io.EOF && s.err == nil {
+		s.err = err
+	}
+	return 0, false
+}
+
+func (s *sqlScript) peekIs(offset int, c byte) bool {
+	b, ok := s.peek(offset)
+	return ok && b == c
+}
+
+// quoted consumes a quoted string or identifier into sb including the quotes
+func (s *sqlScript) quoted(sb *strings.Builder, quote byte, escapes bool) {
+	c, _ := s.read()
+	sb.WriteByte(c)
+	for {
+		c, ok := s.read()
+		if !ok {
+			return
+		}
+		sb.WriteByte(c)
+		switch {
+		case c == '\\' && escapes:
+			if c, ok := s.read(); ok {
+				sb.WriteByte(c)
+			}
+		case c == quote:
+			if !s.peekIs(0, quote) {
+				return
+			}
+			s.read()
+			sb.WriteByte(quote)
+		}
+	}
+}
+
+// dollarTag returns the dollar-quote tag at the current position, or "" if there is none
+func (s *sqlScript) dollarTag() string {
+	b, _ := s.r.Peek(maxDollarTag)
+	for end := 1; end < len(b); end++ {
+		if b[end] == '$' {
+			return string(b[:end+1])
+		}
+		if !isIdentChar(b[end]) || end == 1 && b[end] >= '0' && b[end] <= '9' {
+			return ""
+		}
+	}
+	return ""
+}
+
+// dollarQuoted consumes a dollar-quoted string into sb including the tags
+func (s *sqlScript) dollarQuoted(sb *strings.Builder, tag string) {
+	for range tag {
+		c, _ := s.read()
+		sb.WriteByte(c)
+	}
+	start := sb.Len()
+	for {
+		c, ok := s.read()
+		if !ok {
+			return
+		}
+		sb.WriteByte(c)
+		if c == '$' && sb.Len()-start >= len(tag) && strings.HasSuffix(sb.String()[start:], tag) {
+			return
+		}
+	}
+}
+
+// skipLine moves past the end of the current line
+func (s *sqlScript) skipLine() {
+	for {
+		c, ok := s.read()
+		if !ok || c == '\n' {
+			return
+		}
+	}
+}
+
+// skipBlockComment moves past a possibly nested /* */ comment
+func (s *sqlScript) skipBlockComment() {
+	depth := 0
+	for {
+		c, ok := s.read()
+		if !ok {
+			return
+		}
+		switch {
+		case c == '/' && s.peekIs(0, '*'):
+			s.read()
+			depth++
+		case c == '*' && s.peekIs(0, '/'):
+			s.read()
+			depth--
+			if depth == 0 {
+				return
+			}
+		}
+	}
+}
+
+// copyData streams the inline rows of a COPY statement up to the \. line
+type copyData struct {
+	s       *sqlScript
+	pending []byte
+	midLine bool // the last read ended inside a line longer than the buffer
+	done    bool
+}
+
+func (c *copyData) Read(p []byte) (int, error) {
+	for len(c.pending) == 0 {
+		if c.done {
+			return 0, io.EOF
+		}
+		if !c.midLine && c.atEnd() {
+			c.done = true
+			continue
+		}
+		line, err := c.s.r.ReadSlice('\n')
+		c.midLine = err == bufio.ErrBufferFull
+		if len(line) > 0 && line[len(line)-1] == '\n' {
+			c.s.line++
+		}
+		switch {
+		case err == io.EOF:
+			// Data without terminator runs to the end of the script
+			c.done = true
+		case err != nil && err != bufio.ErrBufferFull:
+			if c.s.err == nil {
+				c.s.err = err
+			}
+			return 0, err
+		}
+		c.pending = line
+	}
+	n := copy(p, c.pending)
+	c.pending = c.pending[n:]
+	return n, nil
+}
+
+// atEnd consumes the \. line if it is next
+func (c *copyData) atEnd() bool {
+	b, _ := c.s.r.Peek(3)
+	if len(b) == 0 {
+		return true
+	}
+	if len(b) < 2 || b[0] != '\\' || b[1] != '.' || len(b) == 3 && b[2] != '\n' && b[2] != '\r' {
+		return false
+	}
+	c.s.skipLine()
+	return true
+}
+
+func isIdentChar(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c >= 0x80
+}
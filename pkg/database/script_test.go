@@ -0,0 +1,109 @@
+package database
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+// collectScript reads all statements of a script, with the data of COPY statements
+func collectScript(t *testing.T, script string) ([]string, map[int]string) {
+	t.Helper()
+	s := newSQLScript(strings.NewReader(script))
+	var statements []string
+	copies := map[int]string{}
+	for stmt := s.Next(); stmt != nil; stmt = s.Next() {
+		if stmt.IsCopy {
+			data, err := io.ReadAll(stmt.Copy)
+			if err != nil {
+				t.Fatalf("reading COPY data: %v", err)
+			}
+			copies[len(statements)] = string(data)
+		}
+		statements = append(statements, stmt.SQL)
+	}
+	if err := s.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return statements, copies
+}
+
+func TestScriptCopyDataIsPassedVerbatim(t *testing.T) {
+	// COPY text escapes: \t and \n inside values, an escaped backslash, \N for NULL
+	// and a value that is a literal backslash-dot, written as \\.
+	data := "1\ttab\\there\\nnewline\t\\N\n" +
+		"2\tback\\\\slash\t{}\n" +
+		"\\\\.\tnot the end\t[]\n"
+	script := "SET client_encoding = 'UTF8';\n" +
+		"COPY public.chat (id, title, meta) FROM stdin;\n" +
+		data +
+		"\\.\n" +
+		"SELECT pg_catalog.setval('public.chat_id_seq', 3, true);\n"
+
+	statements, copies := collectScript(t, script)
+	if len(statements) != 3 {
+		t.Fatalf("got %d statements: %q", len(statements), statements)
+	}
+	if statements[1] != "COPY public.chat (id, title, meta) FROM stdin" {
+		t.Errorf("COPY statement = %q", statements[1])
+	}
+	if copies[1] != data {
+		t.Errorf("COPY data:\n%q\nwant:\n%q", copies[1], data)
+	}
+	if !strings.HasPrefix(statements[2], "SELECT pg_catalog.setval") {
+		t.Errorf("statement after COPY = %q", statements[2])
+	}
+}
+
+func TestScriptCopyDataWithCRLFTerminator(t *testing.T) {
+	statements, copies := collectScript(t, "COPY t (a) FROM stdin;\r\nx\r\n\\.\r\nSELECT 1;\r\n")
+	if len(statements) != 2 || statements[1] != "SELECT 1" {
+		t.Fatalf("statements = %q", statements)
+	}
+	if copies[0] != "x\r\n" {
+		t.Errorf("COPY data = %q", copies[0])
+	}
+}
+
+func TestScriptCopyRowLongerThanBuffer(t *testing.T) {
+	row := strings.Repeat("a", 3*scriptBufferSize) + "\t\\\\.\n"
+	statements, copies := collectScript(t, "COPY t (a, b) FROM stdin;\n"+row+"\\.\nSELECT 1;\n")
+	if len(statements) != 2 {
+		t.Fatalf("got %d statements", len(statements))
+	}
+	if copies[0] != row {
+		t.Errorf("COPY data has %d bytes, want %d", len(copies[0]), len(row))
+	}
+}
+
+func TestScriptUnreadCopyDataIsSkipped(t *testing.T) {
+	s := newSQLScript(strings.NewReader("COPY t (a) FROM stdin;\n1\n2\n\\.\nSELECT 2;\n"))
+	if stmt := s.Next(); stmt == nil || !stmt.IsCopy {
+		t.Fatalf("first statement is not a COPY: %+v", stmt)
+	}
+	if stmt := s.Next(); stmt == nil || stmt.SQL != "SELECT 2" {
+		t.Fatalf("statement after unread COPY = %+v", stmt)
+	}
+}
+
+func TestScriptQuotingAndComments(t *testing.T) {
+	script := "-- header; with semicolon\n" +
+		"\\connect owui\n" +
+		"INSERT INTO t VALUES ('a;b', E'c\\';d', \"x;y\");\n" +
+		"CREATE FUNCTION f() RETURNS text AS $body$ SELECT ';' $body$ LANGUAGE sql;\n" +
+		"/* block; /* nested; */ */ SELECT 1;\n"
+	statements, _ := collectScript(t, script)
+	want := []string{
+		"INSERT INTO t VALUES ('a;b', E'c\\';d', \"x;y\")",
+		"CREATE FUNCTION f() RETURNS text AS $body$ SELECT ';' $body$ LANGUAGE sql",
+		"SELECT 1",
+	}
+	if len(statements) != len(want) {
+		t.Fatalf("statements = %q", statements)
+	}
+	for i := range want {
+		if strings.TrimSpace(statements[i]) != want[i] {
+			t.Errorf("statement %d = %q, want %q", i, statements[i], want[i])
+		}
+	}
+}
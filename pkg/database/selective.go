@@ -0,0 +1,634 @@
+package database
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// createTablePattern matches the start of a CREATE TABLE statement up to the table name
+var createTablePattern = regexp.MustCompile(`(?is)^CREATE\s+(?:UNLOGGED\s+)?TABLE\s+`)
+
+// columnClausePattern finds where the constraints and defaults of a column definition start
+var columnClausePattern = regexp.MustCompile(`(?is)\s+(DEFAULT|GENERATED|NOT\s+NULL|NULL|CONSTRAINT|CHECK|REFERENCES|PRIMARY\s+KEY|UNIQUE)\b`)
+
+// tableConstraintPattern matches table constraints in a CREATE TABLE column list
+var tableConstraintPattern = regexp.MustCompile(`(?i)^(CONSTRAINT|CHECK|UNIQUE|PRIMARY\s+KEY|FOREIGN\s+KEY|EXCLUDE|LIKE)\b`)
+
+// sideSchemaPattern restricts side schema names to plain lower-case identifiers
+var sideSchemaPattern = regexp.MustCompile(`^[a-z_][a-z0-9_]{0,62}$`)
+
+// alterSequencePattern matches the start of an ALTER SEQUENCE statement up to the sequence name
+var alterSequencePattern = regexp.MustCompile(`(?is)^ALTER\s+SEQUENCE\s+`)
+
+// ownedByPattern matches the OWNED BY clause after the sequence name
+var ownedByPattern = regexp.MustCompile(`(?is)^\s*OWNED\s+BY\s+`)
+
+// alterTablePattern matches the start of an ALTER TABLE statement up to the table name
+var alterTablePattern = regexp.MustCompile(`(?is)^ALTER\s+TABLE\s+(?:ONLY\s+)?`)
+
+// identitySequencePattern finds the sequence of an identity column
+var identitySequencePattern = regexp.MustCompile(`(?is)\bSEQUENCE\s+NAME\s+`)
+
+// setvalPattern matches the sequence values written by pg_dump and the native dumper
+var setvalPattern = regexp.MustCompile(`(?is)^SELECT\s+pg_catalog\.setval\(\s*'((?:[^']|'')*)'`)
+
+// foreignKeysSQL lists the foreign keys of the database as referencing and referenced schema and table
+const foreignKeysSQL = `SELECT cn.nspname, c.relname, rn.nspname, r.relname FROM pg_constraint k ` +
+	`JOIN pg_class c ON c.oid = k.conrelid JOIN pg_namespace cn ON cn.oid = c.relnamespace ` +
+	`JOIN pg_class r ON r.oid = k.confrelid JOIN pg_namespace rn ON rn.oid = r.relnamespace ` +
+	`WHERE k.contype = 'f'`
+
+// dumpTable collects the statements of one table in a plain SQL dump
+type dumpTable struct {
+	Schema    string
+	Name      string
+	Create    string
+	HasData   bool
+	Sequences []string // sequences owned by the table, as schema.name
+}
+
+// Qualified returns the quoted schema-qualified name
+func (t *dumpTable) Qualified() string {
+	return quoteIdent(t.Schema) + "." + quoteIdent(t.Name)
+}
+
+// String returns schema.name for logging
+func (t *dumpTable) String() string {
+	return t.Schema + "." + t.Name
+}
+
+// selective reports whether the options restore only part of the dump
+func (o *RestoreOptions) selective() bool {
+	return len(o.Tables) > 0 || len(o.ExcludeTables) > 0 || o.SideSchema != ""
+}
+
+// ValidateSideSchema checks that name can be used as a side schema
+func ValidateSideSchema(name string) error {
+	if !sideSchemaPattern.MatchString(name) {
+		return fmt.Errorf("invalid side schema name %q (use lower-case letters, digits and underscores)", name)
+	}
+	if name == "public" || strings.HasPrefix(name, "pg_") || name == "information_schema" {
+		return fmt.Errorf("side schema %q would overwrite live tables; choose a new schema name", name)
+	}
+	return nil
+}
+
+// RestoreTables restores the data of selected tables from a dump and returns their names
+// Without SideSchema the live tables are emptied and reloaded in one transaction, so a failure
+// leaves them untouched; with SideSchema the tables are created in that new schema next to the
+// live ones, without constraints, so rows can be compared and copied back with SQL
+// Custom format dumps are converted to SQL with pg_restore first, locally or in Docker. The SQL
+// is spooled to a private temporary file, since the tables are selected before any data is sent
+func RestoreTables(config *DatabaseConfig, r io.Reader, options *RestoreOptions) ([]string, error) {
+	if config == nil {
+		return nil, fmt.Errorf("database config is nil")
+	}
+	if options == nil || !options.selective() {
+		return nil, fmt.Errorf("no tables selected")
+	}
+	if options.SideSchema != "" {
+		if err := ValidateSideSchema(options.SideSchema); err != nil {
+			return nil, err
+		}
+	}
+
+	spool, err := spoolSQL(config, r, options.Verbose)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		spool.Close()
+		os.Remove(spool.Name())
+	}()
+
+	preamble, all, err := scanTables(spool)
+	if err != nil {
+		return nil, err
+	}
+	tables, err := selectTables(all, options)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(tables))
+	for i, table := range tables {
+		names[i] = table.String()
+	}
+	if options.SideSchema == "" {
+		if err := checkReferences(config, tables); err != nil {
+			return nil, err
+		}
+	}
+	if options.SideSchema != "" {
+		logrus.Infof("Restoring %d table(s) into side schema %s: %s", len(tables), options.SideSchema, strings.Join(names, ", "))
+	} else {
+		logrus.Infof("Replacing the data of %d table(s): %s", len(tables), strings.Join(names, ", "))
+	}
+
+	if _, err := spool.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("failed to rewind dump: %w", err)
+	}
+	script, pw := io.Pipe()
+	written := make(chan error, 1)
+	go func() {
+		err := writeSelection(pw, spool, preamble, tables, options.SideSchema)
+		pw.CloseWithError(err)
+		written <- err
+	}()
+
+	switch ResolveBackend(config) {
+	case BackendNative:
+		err = restoreDumpNative(config, script, options)
+	case BackendDocker:
+		err = restoreDumpWithDocker(config, script, false, options)
+	default:
+		err = restoreWithPsql(config, script, options)
+	}
+	// Unblock the writer if the restore stopped reading early
+	script.CloseWithError(io.ErrClosedPipe)
+	if writeErr := <-written; writeErr != nil && writeErr != io.ErrClosedPipe && err == nil {
+		err = writeErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	return names, nil
+}
+
+// spoolSQL copies a plain SQL dump, or the SQL of a custom format dump, to a temporary file
+func spoolSQL(config *DatabaseConfig, r io.Reader, verbose bool) (*os.File, error) {
+	dump := bufio.NewReader(r)
+	header, err := dump.Peek(len(customDumpMagic))
+	if len(header) == 0 {
+		if err != nil && err != io.EOF {
+			return nil, fmt.Errorf("failed to read dump: %w", err)
+		}
+		return nil, fmt.Errorf("dump data is empty")
+	}
+
+	spool, err := os.CreateTemp("", "owui-restore-*.sql")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	if isCustomDump(header) {
+		err = ConvertToSQL(config, dump, spool, verbose)
+	} else if _, err = io.Copy(spool, dump); err != nil {
+		err = fmt.Errorf("failed to read dump: %w", err)
+	}
+	if err == nil {
+		if _, err = spool.Seek(0, io.SeekStart); err != nil {
+			err = fmt.Errorf("failed to rewind dump: %w", err)
+		}
+	}
+	if err != nil {
+		spool.Close()
+		os.Remove(spool.Name())
+		return nil, err
+	}
+	return spool, nil
+}
+
+// ConvertToSQL streams the plain SQL script of a custom format dump into w using pg_restore
+func ConvertToSQL(config *DatabaseConfig, r io.Reader, w io.Writer, verbose bool) error {
+	args := []string{"--no-owner", "--no-privileges"}
+
+	var cmd *exec.Cmd
+	switch ResolveBackend(config) {
+	case BackendNative:
+		return fmt.Errorf("custom format dumps need pg_restore; use OWUI_PG_BACKEND=tools or docker")
+	case BackendDocker:
+		if !IsDockerAvailable() {
+			return fmt.Errorf("Docker is not available. Install Docker or set USE_DOCKER_PG_TOOLS=false")
+		}
+		serverVersion, err := GetPostgresVersion(config)
+		if err != nil {
+			logrus.Warnf("Failed to detect server version, using 'latest': %v", err)
+			serverVersion = "latest"
+		}
+		dockerImage := fmt.Sprintf("postgres:%s", ExtractMajorVersion(serverVersion))
+		logrus.Infof("Reading custom format dump using Docker image %s...", dockerImage)
+		cmd = exec.Command("docker", append([]string{"run", "--rm", "-i", dockerImage, "pg_restore"}, args...)...)
+	default:
+		logrus.Info("Reading custom format dump...")
+		cmd = exec.Command(GetPgRestorePath(), args...)
+	}
+
+	var stderr bytes.Buffer
+	cmd.Stdin = r
+	cmd.Stdout = w
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("pg_restore failed to read dump: %w\nError output: %s", err, stderr.String())
+	}
+	if stderr.Len() > 0 {
+		if verbose {
+			logrus.Infof("pg_restore output: %s", stderr.String())
+		} else {
+			logrus.Debugf("pg_restore output: %s", stderr.String())
+		}
+	}
+	return nil
+}
+
+// scanTables reads the session settings and the tables of a plain SQL dump, skipping the data
+func scanTables(r io.Reader) ([]string, []*dumpTable, error) {
+	var preamble []string
+	var all []*dumpTable
+	byName := make(map[string]*dumpTable)
+	lookup := func(schema, name string) *dumpTable {
+		key := schema + "." + name
+		if table, ok := byName[key]; ok {
+			return table
+		}
+		table := &dumpTable{Schema: schema, Name: name}
+		byName[key] = table
+		all = append(all, table)
+		return table
+	}
+
+	script := newSQLScript(r)
+	for stmt := script.Next(); stmt != nil; stmt = script.Next() {
+		switch {
+		case stmt.IsCopy:
+			schema, name, _, ok := parseQualifiedName(strings.TrimSpace(stmt.SQL[len("COPY"):]))
+			if !ok {
+				return nil, nil, fmt.Errorf("failed to parse table name at line %d: %s", stmt.Line, firstLine(stmt.SQL))
+			}
+			lookup(schema, name).HasData = true
+		case createTablePattern.MatchString(stmt.SQL):
+			loc := createTablePattern.FindStringIndex(stmt.SQL)
+			schema, name, rest, ok := parseQualifiedName(stmt.SQL[loc[1]:])
+			// CREATE TABLE ... AS and PARTITION OF have no column list to copy
+			if !ok || !strings.HasPrefix(strings.TrimSpace(rest), "(") {
+				continue
+			}
+			table := lookup(schema, name)
+			table.Create = stmt.SQL
+			// The native dumper declares identity sequences in the column definition
+			table.Sequences = append(table.Sequences, identitySequences(rest)...)
+		case alterSequencePattern.MatchString(stmt.SQL):
+			// ALTER SEQUENCE s OWNED BY schema.table.column, for serial columns
+			seqSchema, seqName, rest, ok := parseQualifiedName(stmt.SQL[len(alterSequencePattern.FindString(stmt.SQL)):])
+			loc := ownedByPattern.FindStringIndex(rest)
+			if !ok || loc == nil {
+				continue
+			}
+			schema, name, _, ok := parseQualifiedName(rest[loc[1]:])
+			if table, found := byName[schema+"."+name]; ok && found {
+				table.Sequences = append(table.Sequences, seqSchema+"."+seqName)
+			}
+		case alterTablePattern.MatchString(stmt.SQL):
+			// ALTER TABLE t ALTER COLUMN c ADD GENERATED ... AS IDENTITY (SEQUENCE NAME s ...)
+			schema, name, rest, ok := parseQualifiedName(stmt.SQL[len(alterTablePattern.FindString(stmt.SQL)):])
+			if table, found := byName[schema+"."+name]; ok && found {
+				table.Sequences = append(table.Sequences, identitySequences(rest)...)
+			}
+		case len(all) == 0 && (hasPrefixFold(stmt.SQL, "SET ") || hasPrefixFold(stmt.SQL, "SELECT pg_catalog.set_config(")):
+			preamble = append(preamble, stmt.SQL)
+		}
+	}
+	if err := script.Err(); err != nil {
+		return nil, nil, fmt.Errorf("failed to read dump: %w", err)
+	}
+	return preamble, all, nil
+}
+
+// identitySequences returns the sequences named in the identity clauses of a table definition
+func identitySequences(sql string) []string {
+	var sequences []string
+	for _, loc := range identitySequencePattern.FindAllStringIndex(sql, -1) {
+		if schema, name, _, ok := parseQualifiedName(sql[loc[1]:]); ok {
+			sequences = append(sequences, schema+"."+name)
+		}
+	}
+	return sequences
+}
+
+// setvalSequence returns the sequence of a setval statement as schema.name
+func setvalSequence(sql string) (string, bool) {
+	m := setvalPattern.FindStringSubmatch(sql)
+	if m == nil {
+		return "", false
+	}
+	schema, name, _, ok := parseQualifiedName(strings.ReplaceAll(m[1], "''", "'"))
+	if !ok {
+		return "", false
+	}
+	return schema + "." + name, true
+}
+
+// checkReferences fails if a table outside the selection has a foreign key to a selected table,
+// which makes TRUNCATE fail; checking first names those tables instead of a bare PostgreSQL error
+func checkReferences(config *DatabaseConfig, tables []*dumpTable) error {
+	rows, err := querySchemaRows(config, foreignKeysSQL)
+	if err != nil {
+		logrus.Warnf("Failed to list foreign keys, the restore fails if another table references a selected one: %v", err)
+		return nil
+	}
+	if referencing := referencingTables(rows, tables); len(referencing) > 0 {
+		return fmt.Errorf("tables %s have foreign keys to the selected tables, so they cannot be emptied on their own; add them with --table or use --side-schema", strings.Join(referencing, ", "))
+	}
+	return nil
+}
+
+// referencingTables returns the tables outside the selection that have foreign keys to selected tables
+// Each row is the referencing schema and table followed by the referenced schema and table
+func referencingTables(foreignKeys [][]string, tables []*dumpTable) []string {
+	selected := make(map[string]bool, len(tables))
+	for _, table := range tables {
+		selected[table.String()] = true
+	}
+	seen := make(map[string]bool)
+	var referencing []string
+	for _, row := range foreignKeys {
+		if len(row) < 4 {
+			continue
+		}
+		from, to := row[0]+"."+row[1], row[2]+"."+row[3]
+		if selected[to] && !selected[from] && !seen[from] {
+			seen[from] = true
+			referencing = append(referencing, from)
+		}
+	}
+	sort.Strings(referencing)
+	return referencing
+}
+
+// selectTables applies the --table and --exclude-table patterns
+func selectTables(all []*dumpTable, options *RestoreOptions) ([]*dumpTable, error) {
+	// Every --table pattern must match, so a typo does not silently restore nothing
+	matched := make(map[string]bool)
+	var selected []*dumpTable
+	for _, table := range all {
+		include := len(options.Tables) == 0
+		for _, pattern := range options.Tables {
+			if matchTable(pattern, table) {
+				include = true
+				matched[pattern] = true
+			}
+		}
+		for _, pattern := range options.ExcludeTables {
+			if matchTable(pattern, table) {
+				include = false
+			}
+		}
+		if include {
+			selected = append(selected, table)
+		}
+	}
+	for _, pattern := range options.Tables {
+		if !matched[pattern] {
+			return nil, fmt.Errorf("table %q not found in backup", pattern)
+		}
+	}
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("no tables left to restore after exclusions")
+	}
+	if options.SideSchema != "" {
+		seen := make(map[string]string)
+		for _, table := range selected {
+			if table.Create == "" {
+				return nil, fmt.Errorf("backup has no definition of table %s (data-only dump)", table)
+			}
+			if other, ok := seen[table.Name]; ok {
+				return nil, fmt.Errorf("tables %s and %s have the same name; restore them into separate side schemas with --table", other, table)
+			}
+			seen[table.Name] = table.String()
+		}
+	}
+	return selected, nil
+}
+
+// writeSelection streams the script that restores the selected tables, reading their data from the dump
+func writeSelection(w io.Writer, dump io.Reader, preamble []string, tables []*dumpTable, sideSchema string) error {
+	out := &dumpWriter{w: w}
+	for _, stmt := range preamble {
+		out.printf("%s;\n", stmt)
+	}
+	out.printf("BEGIN;\n")
+
+	// Target table of each selected source table
+	targets := make(map[string]string)
+	// Sequences whose values are restored with their tables
+	sequences := make(map[string]bool)
+	if sideSchema != "" {
+		out.printf("CREATE SCHEMA %s;\n", quoteIdent(sideSchema))
+		for _, table := range tables {
+			columns, err := sideColumns(table.Create)
+			if err != nil {
+				return fmt.Errorf("failed to read definition of %s: %w", table, err)
+			}
+			name := quoteIdent(sideSchema) + "." + quoteIdent(table.Name)
+			out.printf("CREATE TABLE %s (\n    %s\n);\n", name, strings.Join(columns, ",\n    "))
+			targets[table.String()] = name
+		}
+	} else {
+		names := make([]string, len(tables))
+		for i, table := range tables {
+			names[i] = table.Qualified()
+			targets[table.String()] = table.Qualified()
+			for _, seq := range table.Sequences {
+				sequences[seq] = true
+			}
+		}
+		out.printf("TRUNCATE TABLE %s;\n", strings.Join(names, ", "))
+	}
+
+	script := newSQLScript(dump)
+	for stmt := script.Next(); stmt != nil && out.err == nil; stmt = script.Next() {
+		if !stmt.IsCopy {
+			// Side tables have no defaults, so only live tables get their sequence values back
+			if seq, ok := setvalSequence(stmt.SQL); ok && sequences[seq] {
+				out.printf("%s;\n", stmt.SQL)
+			}
+			continue
+		}
+		schema, name, rest, _ := parseQualifiedName(strings.TrimSpace(stmt.SQL[len("COPY"):]))
+		target, ok := targets[schema+"."+name]
+		if !ok {
+			continue
+		}
+		out.printf("COPY %s %s;\n", target, strings.TrimSpace(rest))
+		last, err := copyLastByte(out, stmt.Copy)
+		if err != nil {
+			return err
+		}
+		if last != 0 && last != '\n' {
+			out.printf("\n")
+		}
+		out.printf("\\.\n")
+	}
+	if err := script.Err(); err != nil {
+		return fmt.Errorf("failed to read dump: %w", err)
+	}
+
+	out.printf("COMMIT;\n")
+	return out.err
+}
+
+// copyLastByte copies r to w and returns the last byte copied, or 0 if there was none
+func copyLastByte(w io.Writer, r io.Reader) (byte, error) {
+	var last byte
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			last = buf[n-1]
+			if _, werr := w.Write(buf[:n]); werr != nil {
+				return last, werr
+			}
+		}
+		if err == io.EOF {
+			return last, nil
+		}
+		if err != nil {
+			return last, err
+		}
+	}
+}
+
+// sideColumns returns the column names and types of a CREATE TABLE statement
+// Defaults, generated expressions, identities and constraints are left out: they may reference
+// sequences and tables of the live schema, and the side tables only hold data for comparison
+func sideColumns(create string) ([]string, error) {
+	start := strings.IndexByte(create, '(')
+	if start < 0 {
+		return nil, fmt.Errorf("no column list")
+	}
+	items, ok := splitTopLevel(create[start+1:])
+	if !ok {
+		return nil, fmt.Errorf("unbalanced column list")
+	}
+
+	var columns []string
+	for _, item := range items {
+		item = strings.TrimSpace(item)
+		if item == "" || tableConstraintPattern.MatchString(item) {
+			continue
+		}
+		_, name, rest, ok := parseQualifiedName(item)
+		if !ok {
+			return nil, fmt.Errorf("failed to parse column %q", item)
+		}
+		if loc := columnClausePattern.FindStringIndex(rest); loc != nil {
+			rest = rest[:loc[0]]
+		}
+		columns = append(columns, quoteIdent(name)+" "+strings.TrimSpace(rest))
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("no columns")
+	}
+	return columns, nil
+}
+
+// splitTopLevel splits a parenthesized list at top-level commas up to its closing parenthesis
+func splitTopLevel(s string) ([]string, bool) {
+	var items []string
+	depth, start := 0, 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\'', '"':
+			end := strings.IndexByte(s[i+1:], s[i])
+			if end < 0 {
+				return nil, false
+			}
+			i += end + 1
+		case '(':
+			depth++
+		case ')':
+			if depth == 0 {
+				return append(items, s[start:i]), true
+			}
+			depth--
+		case ',':
+			if depth == 0 {
+				items = append(items, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return nil, false
+}
+
+// parseQualifiedName reads an optionally schema-qualified, optionally quoted name from the start of s
+// A name without schema is returned with an empty schema
+func parseQualifiedName(s string) (schema, name, rest string, ok bool) {
+	first, rest, ok := parseIdent(s)
+	if !ok {
+		return "", "", s, false
+	}
+	if !strings.HasPrefix(rest, ".") {
+		return "", first, rest, true
+	}
+	second, rest, ok := parseIdent(rest[1:])
+	if !ok {
+		return "", "", s, false
+	}
+	return first, second, rest, true
+}
+
+// parseIdent reads one identifier, unquoting it if needed
+func parseIdent(s string) (string, string, bool) {
+	if strings.HasPrefix(s, `"`) {
+		var sb strings.Builder
+		for i := 1; i < len(s); i++ {
+			if s[i] != '"' {
+				sb.WriteByte(s[i])
+				continue
+			}
+			if i+1 < len(s) && s[i+1] == '"' {
+				sb.WriteByte('"')
+				i++
+				continue
+			}
+			return sb.String(), s[i+1:], true
+		}
+		return "", s, false
+	}
+	end := 0
+	for end < len(s) && isIdentChar(s[end]) {
+		end++
+	}
+	if end == 0 {
+		return "", s, false
+	}
+	// Unquoted identifiers are folded to lower case
+	return strings.ToLower(s[:end]), s[end:], true
+}
+
+// matchTable matches a --table pattern, name or schema.name with shell wildcards, against a table
+func matchTable(pattern string, table *dumpTable) bool {
+	target := table.Name
+	if strings.Contains(pattern, ".") {
+		target = table.String()
+	}
+	ok, _ := path.Match(pattern, target)
+	return ok
+}
+
+// hasPrefixFold is strings.HasPrefix ignoring case
+func hasPrefixFold(s, prefix string) bool {
+	return len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix)
+}
+
+// customDumpMagic starts every pg_dump custom format archive
+const customDumpMagic = "PGDMP"
+
+// isCustomDump reports whether the data starts like a pg_dump custom format archive
+func isCustomDump(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(customDumpMagic))
+}
+
+// quoteIdent quotes an SQL identifier
+func quoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
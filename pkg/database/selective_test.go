@@ -0,0 +1,121 @@
+package database
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// sampleDump is a plain pg_dump with a serial, an identity and a referencing table
+const sampleDump = `SET statement_timeout = 0;
+SET client_encoding = 'UTF8';
+CREATE TABLE public.chat (
+    id integer NOT NULL,
+    title text
+);
+CREATE SEQUENCE public.chat_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    CACHE 1;
+ALTER SEQUENCE public.chat_id_seq OWNED BY public.chat.id;
+CREATE TABLE public."Tag" (
+    id bigint NOT NULL,
+    chat_id integer
+);
+ALTER TABLE public."Tag" ALTER COLUMN id ADD GENERATED BY DEFAULT AS IDENTITY (
+    SEQUENCE NAME public."Tag_id_seq"
+    START WITH 1
+    CACHE 1
+);
+ALTER TABLE ONLY public.chat ALTER COLUMN id SET DEFAULT nextval('public.chat_id_seq'::regclass);
+COPY public.chat (id, title) FROM stdin;
+1	first
+2	second
+\.
+COPY public."Tag" (id, chat_id) FROM stdin;
+7	1
+\.
+SELECT pg_catalog.setval('public.chat_id_seq', 2, true);
+SELECT pg_catalog.setval('public."Tag_id_seq"', 7, true);
+ALTER TABLE ONLY public."Tag"
+    ADD CONSTRAINT tag_chat_fkey FOREIGN KEY (chat_id) REFERENCES public.chat(id);
+`
+
+func TestWriteSelectionRestoresOwnedSequences(t *testing.T) {
+	preamble, all, err := scanTables(strings.NewReader(sampleDump))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(preamble) != 2 || len(all) != 2 {
+		t.Fatalf("preamble %q, tables %v", preamble, all)
+	}
+	if got := strings.Join(all[0].Sequences, ","); got != "public.chat_id_seq" {
+		t.Errorf("sequences of chat = %s", got)
+	}
+	if got := strings.Join(all[1].Sequences, ","); got != "public.Tag_id_seq" {
+		t.Errorf("sequences of Tag = %s", got)
+	}
+
+	tables, err := selectTables(all, &RestoreOptions{Tables: []string{"chat"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var script bytes.Buffer
+	if err := writeSelection(&script, strings.NewReader(sampleDump), preamble, tables, ""); err != nil {
+		t.Fatal(err)
+	}
+	got := script.String()
+	for _, want := range []string{
+		"TRUNCATE TABLE \"public\".\"chat\";\n",
+		"COPY \"public\".\"chat\" (id, title) FROM stdin;\n1\tfirst\n2\tsecond\n\\.\n",
+		"SELECT pg_catalog.setval('public.chat_id_seq', 2, true);\nCOMMIT;\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("script has no %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "Tag") {
+		t.Errorf("script touches the unselected table:\n%s", got)
+	}
+
+	// Side tables have no defaults, so their sequences are left alone
+	script.Reset()
+	if err := writeSelection(&script, strings.NewReader(sampleDump), preamble, tables, "restored"); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(script.String(), "setval") {
+		t.Errorf("side schema script sets sequences:\n%s", script.String())
+	}
+}
+
+func TestReferencingTables(t *testing.T) {
+	_, all, err := scanTables(strings.NewReader(sampleDump))
+	if err != nil {
+		t.Fatal(err)
+	}
+	foreignKeys := [][]string{
+		{"public", "Tag", "public", "chat"},
+		{"public", "chat", "public", "chat"}, // self-reference
+		{"public", "note", "public", "chat"},
+		{"public", "note", "public", "chat"}, // second key of the same table
+		{"public", "chat", "public", "user"},
+	}
+
+	chat, _ := selectTables(all, &RestoreOptions{Tables: []string{"chat"}})
+	if got := strings.Join(referencingTables(foreignKeys, chat), ", "); got != "public.Tag, public.note" {
+		t.Errorf("referencing tables of chat = %s", got)
+	}
+
+	// Restoring the referencing table together with the referenced one is fine
+	both, _ := selectTables(all, &RestoreOptions{Tables: []string{"chat", "Tag"}})
+	if got := strings.Join(referencingTables(foreignKeys[:2], both), ", "); got != "" {
+		t.Errorf("referencing tables of chat and Tag = %s", got)
+	}
+
+	// A referencing table may be restored on its own
+	tag, _ := selectTables(all, &RestoreOptions{Tables: []string{"Tag"}})
+	if got := referencingTables(foreignKeys, tag); len(got) != 0 {
+		t.Errorf("referencing tables of Tag = %v", got)
+	}
+}
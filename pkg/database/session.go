@@ -0,0 +1,50 @@
+package database
+
+import (
+	"fmt"
+	"io"
+)
+
+// Session is a native protocol connection for callers that stream table data with COPY
+// It does not depend on the configured backend and needs no PostgreSQL binaries
+type Session struct {
+	conn *pgConn
+}
+
+// OpenSession connects to the database with the native client
+func OpenSession(config *DatabaseConfig) (*Session, error) {
+	conn, err := connectNative(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	return &Session{conn: conn}, nil
+}
+
+// Exec runs one or more statements and discards any rows
+func (s *Session) Exec(sql string) error {
+	return s.conn.Exec(sql)
+}
+
+// Query runs a query and returns its rows in text format; NULL is returned as an empty string
+func (s *Session) Query(sql string) ([][]string, error) {
+	res, err := s.conn.Query(sql)
+	if err != nil {
+		return nil, err
+	}
+	return res.Rows, nil
+}
+
+// CopyOut runs a COPY ... TO STDOUT statement and writes the data to w
+func (s *Session) CopyOut(sql string, w io.Writer) (int64, error) {
+	return s.conn.CopyOut(sql, w)
+}
+
+// CopyIn runs a COPY ... FROM STDIN statement and streams r to the server
+func (s *Session) CopyIn(sql string, r io.Reader) (int64, error) {
+	return s.conn.CopyIn(sql, r)
+}
+
+// Close terminates the session
+func (s *Session) Close() error {
+	return s.conn.Close()
+}
@@ -0,0 +1,74 @@
+package database
+
+import (
+	"fmt"
+	"time"
+)
+
+// Snapshot is an exported PostgreSQL snapshot, held open by a transaction until Release
+// Dumps taken with DumpOptions.Snapshot see the database exactly as it was at Time,
+// however long after the export they run
+type Snapshot struct {
+	ID         string    // pg_export_snapshot() identifier
+	LSN        string    // current WAL position, or the replay position on a standby
+	TxSnapshot string    // txid_current_snapshot(): xmin:xmax:running transactions
+	Time       time.Time // start of the exporting transaction
+	session    *Session
+}
+
+// ExportSnapshot opens a REPEATABLE READ transaction and exports its snapshot
+// The connection stays open so the snapshot can be imported by a later dump
+func ExportSnapshot(config *DatabaseConfig) (*Snapshot, error) {
+	session, err := OpenSession(config)
+	if err != nil {
+		return nil, err
+	}
+
+	// The transaction idles while the snapshot is in use, so the server must not end it
+	if err := session.Exec("SET idle_in_transaction_session_timeout = 0"); err != nil {
+		session.Close()
+		return nil, fmt.Errorf("failed to prepare snapshot session: %w", err)
+	}
+	if err := session.Exec("BEGIN ISOLATION LEVEL REPEATABLE READ, READ ONLY"); err != nil {
+		session.Close()
+		return nil, fmt.Errorf("failed to start snapshot transaction: %w", err)
+	}
+
+	rows, err := session.Query(`SELECT pg_export_snapshot(),
+	(CASE WHEN pg_is_in_recovery() THEN pg_last_wal_replay_lsn() ELSE pg_current_wal_lsn() END)::text,
+	txid_current_snapshot()::text,
+	extract(epoch FROM now())::text`)
+	if err != nil {
+		session.Close()
+		return nil, fmt.Errorf("failed to export snapshot: %w", err)
+	}
+	if len(rows) == 0 || len(rows[0]) < 4 {
+		session.Close()
+		return nil, fmt.Errorf("failed to export snapshot: no result")
+	}
+
+	var epoch float64
+	if _, err := fmt.Sscanf(rows[0][3], "%f", &epoch); err != nil {
+		session.Close()
+		return nil, fmt.Errorf("failed to parse snapshot time %q: %w", rows[0][3], err)
+	}
+
+	return &Snapshot{
+		ID:         rows[0][0],
+		LSN:        rows[0][1],
+		TxSnapshot: rows[0][2],
+		Time:       time.Unix(0, int64(epoch*1e9)).UTC(),
+		session:    session,
+	}, nil
+}
+
+// Release ends the exporting transaction; the snapshot can no longer be imported afterwards
+func (s *Snapshot) Release() error {
+	if s.session == nil {
+		return nil
+	}
+	s.session.Exec("ROLLBACK")
+	err := s.session.Close()
+	s.session = nil
+	return err
+}
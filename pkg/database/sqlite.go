@@ -0,0 +1,352 @@
+package database
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// SQLiteFileName is the database file in an Open WebUI data directory
+const SQLiteFileName = "webui.db"
+
+// sqliteHeader starts every SQLite database file
+const sqliteHeader = "SQLite format 3\x00"
+
+// sqliteBusyTimeoutMs is how long the sqlite3 CLI waits for locks held by Open WebUI
+const sqliteBusyTimeoutMs = 10000
+
+// ErrNoDataDir is returned when no Open WebUI data directory is configured or found
+var ErrNoDataDir = errors.New("no Open WebUI data directory found")
+
+// DefaultDataDirs are checked when no data directory is configured
+var DefaultDataDirs = []string{"/app/backend/data"}
+
+// GetSqlite3Path returns the path to the sqlite3 binary
+// Checks SQLITE3_BINARY environment variable, defaults to sqlite3 on the PATH
+func GetSqlite3Path() string {
+	if path := os.Getenv("SQLITE3_BINARY"); path != "" {
+		return path
+	}
+	return "sqlite3"
+}
+
+// CheckSQLiteAvailable verifies that the sqlite3 CLI is installed
+func CheckSQLiteAvailable() error {
+	path := GetSqlite3Path()
+	if _, err := exec.LookPath(path); err != nil {
+		return fmt.Errorf("sqlite3 not found at %s. Set SQLITE3_BINARY environment variable or install the SQLite CLI", path)
+	}
+	return nil
+}
+
+// GetDataDirFromEnv returns the Open WebUI data directory from OWUI_DATA_DIR or DATA_DIR
+func GetDataDirFromEnv() string {
+	if dir := os.Getenv("OWUI_DATA_DIR"); dir != "" {
+		return dir
+	}
+	return os.Getenv("DATA_DIR")
+}
+
+// sqlitePathFromDatabaseURL returns the file of a sqlite:/// DATABASE_URL, as used by Open WebUI
+func sqlitePathFromDatabaseURL() string {
+	url := os.Getenv("DATABASE_URL")
+	if !strings.HasPrefix(url, "sqlite:///") {
+		return ""
+	}
+	return strings.TrimPrefix(url, "sqlite:///")
+}
+
+// IsSQLiteConfigured reports whether an Open WebUI SQLite database is configured through the environment
+func IsSQLiteConfigured() bool {
+	if GetDataDirFromEnv() == "" && sqlitePathFromDatabaseURL() == "" {
+		return false
+	}
+	_, err := FindSQLiteDatabase("")
+	return err == nil
+}
+
+// ResolveSQLitePath returns the webui.db path for a data directory (or a .db file)
+// An empty dataDir falls back to DATABASE_URL, OWUI_DATA_DIR, DATA_DIR and DefaultDataDirs
+func ResolveSQLitePath(dataDir string) (string, error) {
+	if dataDir != "" {
+		return sqlitePathIn(dataDir), nil
+	}
+	if path := sqlitePathFromDatabaseURL(); path != "" {
+		return path, nil
+	}
+	if dir := GetDataDirFromEnv(); dir != "" {
+		return sqlitePathIn(dir), nil
+	}
+	for _, dir := range DefaultDataDirs {
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			return sqlitePathIn(dir), nil
+		}
+	}
+	return "", ErrNoDataDir
+}
+
+// FindSQLiteDatabase resolves the webui.db path like ResolveSQLitePath and checks that it exists
+func FindSQLiteDatabase(dataDir string) (string, error) {
+	path, err := ResolveSQLitePath(dataDir)
+	if err != nil {
+		return "", err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", fmt.Errorf("SQLite database not found: %w", err)
+	}
+	if info.IsDir() {
+		return "", fmt.Errorf("SQLite database %s is a directory", path)
+	}
+	return path, nil
+}
+
+// sqlitePathIn returns dir/webui.db, or dir itself if it names a database file
+func sqlitePathIn(dir string) string {
+	if ext := filepath.Ext(dir); ext == ".db" || ext == ".sqlite" || ext == ".sqlite3" {
+		return dir
+	}
+	return filepath.Join(dir, SQLiteFileName)
+}
+
+// RunSQLite executes SQL (and sqlite3 dot-commands) against a database file with the sqlite3 CLI
+// It stops at the first error and returns the CLI output
+func RunSQLite(path, sql string) (string, error) {
+	cmd := exec.Command(GetSqlite3Path(), "-bail", "-batch", "-noheader", "-list", path)
+	cmd.Stdin = strings.NewReader(fmt.Sprintf(".timeout %d\n%s\n", sqliteBusyTimeoutMs, sql))
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return stdout.String(), fmt.Errorf("sqlite3 failed: %w\nError output: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	// sqlite3 reports some errors without a non-zero exit code in batch mode
+	if msg := strings.TrimSpace(stderr.String()); strings.Contains(msg, "Error") {
+		return stdout.String(), fmt.Errorf("sqlite3 failed: %s", msg)
+	}
+	return stdout.String(), nil
+}
+
+// GetSQLiteVersion returns the version of the sqlite3 CLI
+func GetSQLiteVersion() (string, error) {
+	output, err := exec.Command(GetSqlite3Path(), "--version").Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to get SQLite version: %w", err)
+	}
+	fields := strings.Fields(string(output))
+	if len(fields) == 0 {
+		return "", fmt.Errorf("failed to get SQLite version: empty output")
+	}
+	return fields[0], nil
+}
+
+// WriteSQLiteSnapshot streams a consistent online copy of a SQLite database into w
+// It uses VACUUM INTO, falling back to the online backup API (.backup) on older sqlite3 versions,
+// so Open WebUI can keep running; the copy is checked with PRAGMA quick_check
+func WriteSQLiteSnapshot(path string, w io.Writer) (int64, error) {
+	logrus.Infof("Creating SQLite snapshot of '%s'...", path)
+
+	tempDir, err := os.MkdirTemp("", "owui-sqlite-")
+	if err != nil {
+		return 0, fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(tempDir)
+	snapshot := filepath.Join(tempDir, SQLiteFileName)
+
+	if err := SnapshotSQLiteTo(path, snapshot); err != nil {
+		return 0, err
+	}
+
+	n, err := CopySQLiteSnapshot(snapshot, w)
+	if err != nil {
+		return n, err
+	}
+
+	logrus.Infof("SQLite snapshot created successfully (%d bytes)", n)
+	return n, nil
+}
+
+// CopySQLiteSnapshot streams a snapshot taken earlier with SnapshotSQLiteTo into w
+func CopySQLiteSnapshot(snapshot string, w io.Writer) (int64, error) {
+	file, err := os.Open(snapshot)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open snapshot: %w", err)
+	}
+	defer file.Close()
+
+	n, err := io.Copy(w, file)
+	if err != nil {
+		return n, fmt.Errorf("failed to copy snapshot: %w", err)
+	}
+	return n, nil
+}
+
+// SnapshotSQLiteTo writes a consistent online copy of the database at path to dst, which must not exist
+func SnapshotSQLiteTo(path, dst string) error {
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("SQLite database not found: %w", err)
+	}
+
+	if _, err := RunSQLite(path, "VACUUM INTO "+quoteLiteral(dst)+";"); err != nil {
+		logrus.Debugf("VACUUM INTO failed, falling back to .backup: %v", err)
+		os.Remove(dst)
+		if _, err := RunSQLite(path, ".backup "+quoteLiteral(dst)); err != nil {
+			return fmt.Errorf("failed to snapshot SQLite database: %w", err)
+		}
+	}
+
+	if err := CheckSQLiteIntegrity(dst); err != nil {
+		return fmt.Errorf("snapshot failed integrity check: %w", err)
+	}
+	return nil
+}
+
+// CheckSQLiteIntegrity runs PRAGMA quick_check on a database file
+func CheckSQLiteIntegrity(path string) error {
+	output, err := RunSQLite(path, "PRAGMA quick_check;")
+	if err != nil {
+		return err
+	}
+	if result := strings.TrimSpace(output); result != "ok" {
+		return fmt.Errorf("quick_check reported: %s", result)
+	}
+	return nil
+}
+
+// RestoreSQLite replaces the database at path with a snapshot
+// The snapshot is checked before the swap, the previous file is kept as <path>.pre-restore
+// and stale -wal and -shm files are removed. Open WebUI must be stopped while restoring
+func RestoreSQLite(path string, r io.Reader) error {
+	snapshot := bufio.NewReader(r)
+	header, err := snapshot.Peek(len(sqliteHeader))
+	if len(header) == 0 {
+		if err != nil && err != io.EOF {
+			return fmt.Errorf("failed to read snapshot: %w", err)
+		}
+		return fmt.Errorf("snapshot data is empty")
+	}
+	if string(header) != sqliteHeader {
+		return fmt.Errorf("snapshot is not a SQLite database")
+	}
+
+	logrus.Infof("Restoring SQLite database to '%s'...", path)
+
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create data directory: %w", err)
+	}
+
+	tempPath := path + ".restore.tmp"
+	defer os.Remove(tempPath)
+	if err := writeFile(tempPath, snapshot); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+
+	if err := CheckSQLiteIntegrity(tempPath); err != nil {
+		return fmt.Errorf("snapshot failed integrity check: %w", err)
+	}
+
+	if _, err := os.Stat(path); err == nil {
+		previous := path + ".pre-restore"
+		if err := os.Rename(path, previous); err != nil {
+			return fmt.Errorf("failed to move current database aside: %w", err)
+		}
+		logrus.Infof("Previous database kept as %s", previous)
+	}
+	for _, suffix := range []string{"-wal", "-shm", "-journal"} {
+		if err := os.Remove(path + suffix); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove %s%s: %w", path, suffix, err)
+		}
+	}
+
+	if err := os.Rename(tempPath, path); err != nil {
+		return fmt.Errorf("failed to move snapshot into place: %w", err)
+	}
+
+	logrus.Info("SQLite database restored successfully")
+	return nil
+}
+
+// writeFile streams r into a new file at path
+func writeFile(path string, r io.Reader) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(file, r); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// PurgeSQLite drops all tables and views from a SQLite database
+func PurgeSQLite(path string, dryRun bool) error {
+	if dryRun {
+		logrus.Info("DRY RUN: Showing what would be deleted without actually deleting...")
+	} else {
+		logrus.Warnf("PURGING SQLite database '%s' - this will drop all tables and data!", path)
+	}
+
+	output, err := RunSQLite(path, `SELECT upper(type), name FROM sqlite_master
+WHERE type IN ('table', 'view') AND name NOT LIKE 'sqlite_%' ORDER BY type, name;`)
+	if err != nil {
+		return fmt.Errorf("failed to list database objects: %w", err)
+	}
+
+	var views, tables []string
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		kind, name, ok := strings.Cut(line, "|")
+		if !ok {
+			continue
+		}
+		if kind == "VIEW" {
+			views = append(views, name)
+		} else {
+			tables = append(tables, name)
+		}
+	}
+
+	if dryRun {
+		logrus.Info("Objects that would be deleted:")
+		for _, name := range tables {
+			logrus.Infof("  TABLE %s", name)
+		}
+		for _, name := range views {
+			logrus.Infof("  VIEW %s", name)
+		}
+		return nil
+	}
+
+	var sql strings.Builder
+	sql.WriteString("PRAGMA foreign_keys = OFF;\nBEGIN;\n")
+	for _, name := range views {
+		fmt.Fprintf(&sql, "DROP VIEW IF EXISTS %s;\n", quoteSQLiteIdent(name))
+	}
+	for _, name := range tables {
+		fmt.Fprintf(&sql, "DROP TABLE IF EXISTS %s;\n", quoteSQLiteIdent(name))
+	}
+	sql.WriteString("COMMIT;\nVACUUM;\n")
+
+	if _, err := RunSQLite(path, sql.String()); err != nil {
+		return fmt.Errorf("failed to purge database: %w", err)
+	}
+
+	logrus.Infof("SQLite database '%s' purged successfully (%d tables, %d views)", path, len(tables), len(views))
+	return nil
+}
+
+// quoteSQLiteIdent quotes an SQLite identifier
+func quoteSQLiteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
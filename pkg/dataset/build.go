@@ -0,0 +1,288 @@
+package dataset
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/vosiander/open-webui-backup/pkg/openwebui"
+)
+
+// ChatMessage is a message in OpenAI fine-tuning format
+type ChatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// ChatRecord is a line of an OpenAI chat fine-tuning dataset
+type ChatRecord struct {
+	Messages []ChatMessage `json:"messages"`
+}
+
+// PreferenceInput is the prompt of a preference pair
+type PreferenceInput struct {
+	Messages []ChatMessage `json:"messages"`
+}
+
+// PreferenceRecord is a line of an OpenAI preference (DPO) dataset
+type PreferenceRecord struct {
+	Input              PreferenceInput `json:"input"`
+	PreferredOutput    []ChatMessage   `json:"preferred_output"`
+	NonPreferredOutput []ChatMessage   `json:"non_preferred_output"`
+}
+
+// Options controls how a dataset is built
+type Options struct {
+	Format       string
+	Filter       Filter
+	Dedup        bool
+	Scrubbers    []Scrubber
+	SystemPrompt string // prepended to every conversation if set
+}
+
+// Result is a built dataset and what was left out
+type Result struct {
+	Records    []interface{}
+	Ratings    int // feedbacks joined with their message
+	Matched    int // ratings passing the filter
+	Skipped    int // feedbacks whose chat or message was not found
+	Duplicates int // records dropped by deduplication
+}
+
+// Build turns rated conversations into dataset records
+func Build(source *Source, options *Options) (*Result, error) {
+	ratings, skipped := source.Ratings()
+	result := &Result{Ratings: len(ratings), Skipped: skipped}
+
+	var records []interface{}
+	var err error
+	switch options.Format {
+	case FormatChat:
+		records, err = buildChat(ratings, options, result)
+	case FormatPreference:
+		records, err = buildPreference(ratings, options, result)
+	default:
+		return nil, fmt.Errorf("unsupported dataset format %q (use %s or %s)", options.Format, FormatChat, FormatPreference)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if options.Dedup {
+		records, result.Duplicates = dedup(records)
+	}
+	result.Records = records
+	return result, nil
+}
+
+// WriteJSONL writes one record per line
+func WriteJSONL(w io.Writer, records []interface{}) error {
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	enc.SetEscapeHTML(false)
+
+	for _, record := range records {
+		if err := enc.Encode(record); err != nil {
+			return fmt.Errorf("failed to encode record: %w", err)
+		}
+	}
+	return bw.Flush()
+}
+
+// buildChat emits the thread up to each rated message
+func buildChat(ratings []*Rating, options *Options, result *Result) ([]interface{}, error) {
+	var records []interface{}
+	for _, rating := range ratings {
+		if !options.Filter.Match(rating) {
+			continue
+		}
+		result.Matched++
+
+		messages, err := convertMessages(rating.Thread, options)
+		if err != nil {
+			return nil, err
+		}
+		if len(messages) < 2 {
+			continue
+		}
+		records = append(records, &ChatRecord{Messages: messages})
+	}
+	return records, nil
+}
+
+// buildPreference pairs liked and disliked replies to the same prompt
+// Arena comparisons also pair the winner with its unrated sibling models
+func buildPreference(ratings []*Rating, options *Options, result *Result) ([]interface{}, error) {
+	// The rating direction is what makes a pair, so only the other filters apply
+	filter := options.Filter
+	filter.Rating = 0
+
+	type group struct {
+		prompt    []openwebui.Message
+		preferred []*Rating
+		rejected  []*Rating
+	}
+	groups := make(map[string]*group)
+	var order []string
+	rated := make(map[string]bool)
+
+	for _, rating := range ratings {
+		rated[rating.Chat.ID+"/"+rating.Message.ID] = true
+		if !filter.Match(rating) || rating.Value == 0 {
+			continue
+		}
+		result.Matched++
+
+		key := rating.Chat.ID + "/"
+		if rating.Message.ParentID != nil {
+			key += *rating.Message.ParentID
+		}
+		g, ok := groups[key]
+		if !ok {
+			g = &group{prompt: rating.Thread[:len(rating.Thread)-1]}
+			groups[key] = g
+			order = append(order, key)
+		}
+		if rating.Value > 0 {
+			g.preferred = append(g.preferred, rating)
+		} else {
+			g.rejected = append(g.rejected, rating)
+		}
+	}
+
+	var records []interface{}
+	for _, key := range order {
+		g := groups[key]
+		prompt, err := convertMessages(g.prompt, options)
+		if err != nil {
+			return nil, err
+		}
+		if len(prompt) == 0 {
+			continue
+		}
+
+		for _, preferred := range g.preferred {
+			rejected := make([]openwebui.Message, 0, len(g.rejected))
+			for _, r := range g.rejected {
+				rejected = append(rejected, *r.Message)
+			}
+			rejected = append(rejected, arenaRejected(preferred, rated)...)
+
+			for _, other := range rejected {
+				record, err := preferenceRecord(prompt, preferred.Message, &other, options)
+				if err != nil {
+					return nil, err
+				}
+				if record != nil {
+					records = append(records, record)
+				}
+			}
+		}
+	}
+	return records, nil
+}
+
+// arenaRejected returns unrated sibling replies from the models a winner was compared against
+func arenaRejected(winner *Rating, rated map[string]bool) []openwebui.Message {
+	values, _ := winner.Feedback.Data["sibling_model_ids"].([]interface{})
+	if len(values) == 0 {
+		return nil
+	}
+	models := make(map[string]bool, len(values))
+	for _, v := range values {
+		models[stringValue(v)] = true
+	}
+
+	var rejected []openwebui.Message
+	for _, sibling := range siblings(winner.Chat, winner.Message) {
+		if sibling.Role == "assistant" && models[sibling.Model] && !rated[winner.Chat.ID+"/"+sibling.ID] {
+			rejected = append(rejected, sibling)
+		}
+	}
+	return rejected
+}
+
+// preferenceRecord builds a pair, or nil if either reply is empty after scrubbing
+func preferenceRecord(prompt []ChatMessage, preferred, rejected *openwebui.Message, options *Options) (*PreferenceRecord, error) {
+	chosen, err := convertMessages([]openwebui.Message{*preferred}, &Options{Scrubbers: options.Scrubbers})
+	if err != nil {
+		return nil, err
+	}
+	other, err := convertMessages([]openwebui.Message{*rejected}, &Options{Scrubbers: options.Scrubbers})
+	if err != nil {
+		return nil, err
+	}
+	if len(chosen) == 0 || len(other) == 0 || chosen[0].Content == other[0].Content {
+		return nil, nil
+	}
+
+	return &PreferenceRecord{
+		Input:              PreferenceInput{Messages: prompt},
+		PreferredOutput:    chosen,
+		NonPreferredOutput: other,
+	}, nil
+}
+
+// convertMessages scrubs messages and converts them to fine-tuning format
+// Messages with other roles or no content are dropped
+func convertMessages(messages []openwebui.Message, options *Options) ([]ChatMessage, error) {
+	var result []ChatMessage
+	if options.SystemPrompt != "" && (len(messages) == 0 || messages[0].Role != "system") {
+		result = append(result, ChatMessage{Role: "system", Content: options.SystemPrompt})
+	}
+
+	for _, message := range messages {
+		if message.Role != "user" && message.Role != "assistant" && message.Role != "system" {
+			continue
+		}
+		content, err := Scrub(strings.TrimSpace(message.Content), options.Scrubbers)
+		if err != nil {
+			return nil, err
+		}
+		if content == "" {
+			continue
+		}
+		result = append(result, ChatMessage{Role: message.Role, Content: content})
+	}
+	return result, nil
+}
+
+// dedup drops records whose normalized content was already seen
+func dedup(records []interface{}) ([]interface{}, int) {
+	seen := make(map[string]bool, len(records))
+	kept := records[:0]
+	for _, record := range records {
+		key := recordKey(record)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		kept = append(kept, record)
+	}
+	return kept, len(records) - len(kept)
+}
+
+// recordKey hashes a record with case and whitespace normalized
+func recordKey(record interface{}) string {
+	h := sha256.New()
+	write := func(messages []ChatMessage) {
+		for _, m := range messages {
+			fmt.Fprintf(h, "%s\x00%s\x00", m.Role, strings.Join(strings.Fields(strings.ToLower(m.Content)), " "))
+		}
+		h.Write([]byte{0x01})
+	}
+
+	switch r := record.(type) {
+	case *ChatRecord:
+		write(r.Messages)
+	case *PreferenceRecord:
+		write(r.Input.Messages)
+		write(r.PreferredOutput)
+		write(r.NonPreferredOutput)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,344 @@
+package dataset
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/vosiander/open-webui-backup/pkg/archive"
+	"github.com/vosiander/open-webui-backup/pkg/openwebui"
+)
+
+// Output formats
+const (
+	FormatChat       = "chat" // OpenAI chat fine-tuning JSONL
+	FormatPreference = "dpo"  // OpenAI preference (DPO) pairs JSONL
+)
+
+// Source holds the chats and feedbacks a dataset is built from
+type Source struct {
+	Chats     map[string]*openwebui.Chat
+	Feedbacks []openwebui.Feedback
+}
+
+// Rating is a feedback joined with the message it rates
+type Rating struct {
+	Feedback *openwebui.Feedback
+	Chat     *openwebui.Chat
+	Message  *openwebui.Message
+	Thread   []openwebui.Message // root to the rated message, inclusive
+	Value    int                 // 1 for thumbs up, -1 for thumbs down
+	Score    int                 // detailed 1-10 score, 0 if not given
+	Model    string
+	Tags     []string
+}
+
+// Filter selects which ratings are used
+// Empty fields match everything
+type Filter struct {
+	Rating   int       // 1 or -1 to keep only positive or negative ratings
+	MinScore int       // minimum detailed score
+	Models   []string  // model IDs of the rated message, globs allowed
+	Tags     []string  // feedback or chat tags, any of them
+	Since    time.Time // rated at or after
+	Until    time.Time // rated before
+}
+
+// FromArchive reads chats and feedbacks from an unencrypted unified backup ZIP
+func FromArchive(zipPath string) (*Source, error) {
+	a, err := archive.Load(zipPath)
+	if err != nil {
+		return nil, err
+	}
+
+	source := &Source{Chats: make(map[string]*openwebui.Chat)}
+
+	for _, entity := range a.Entities[archive.TypeChat] {
+		var chat openwebui.Chat
+		if err := json.Unmarshal(entity.Data, &chat); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", entity.Path, err)
+		}
+		source.Chats[chat.ID] = &chat
+	}
+
+	for _, entity := range a.Entities[archive.TypeFeedback] {
+		var feedback openwebui.Feedback
+		if err := json.Unmarshal(entity.Data, &feedback); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", entity.Path, err)
+		}
+		source.Feedbacks = append(source.Feedbacks, feedback)
+	}
+
+	return source, nil
+}
+
+// FromLive fetches all chats and feedbacks from the live instance (admin API key required)
+func FromLive(client *openwebui.Client) (*Source, error) {
+	feedbacks, err := client.GetAllFeedbacks()
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch feedbacks: %w", err)
+	}
+
+	source := &Source{Chats: make(map[string]*openwebui.Chat), Feedbacks: feedbacks}
+
+	// Feedback snapshots still cover the rated chats if the chat export fails
+	chats, err := client.GetAllChatsDB()
+	if err != nil {
+		logrus.Warnf("Failed to fetch chats: %v. Using feedback snapshots only.", err)
+		return source, nil
+	}
+	for i := range chats {
+		source.Chats[chats[i].ID] = &chats[i]
+	}
+
+	return source, nil
+}
+
+// Ratings joins every feedback with its chat and rated message, oldest first
+// Feedbacks whose chat or message cannot be found are counted as skipped
+func (s *Source) Ratings() ([]*Rating, int) {
+	var ratings []*Rating
+	skipped := 0
+
+	for i := range s.Feedbacks {
+		feedback := &s.Feedbacks[i]
+		rating, err := s.join(feedback)
+		if err != nil {
+			logrus.Debugf("Skipping feedback %s: %v", feedback.ID, err)
+			skipped++
+			continue
+		}
+		ratings = append(ratings, rating)
+	}
+
+	sort.SliceStable(ratings, func(i, j int) bool {
+		return ratings[i].Feedback.CreatedAt < ratings[j].Feedback.CreatedAt
+	})
+	return ratings, skipped
+}
+
+// join resolves the chat and message a feedback refers to
+// The live chat is preferred; the snapshot taken when rating is the fallback
+func (s *Source) join(feedback *openwebui.Feedback) (*Rating, error) {
+	chatID := stringValue(feedback.Meta["chat_id"])
+	messageID := stringValue(feedback.Meta["message_id"])
+	if messageID == "" {
+		return nil, fmt.Errorf("feedback has no message ID")
+	}
+
+	chat := s.Chats[chatID]
+	if chat == nil || messageIndex(chat, messageID) < 0 {
+		snapshot, err := snapshotChat(feedback)
+		if err != nil {
+			return nil, err
+		}
+		chat = snapshot
+	}
+
+	thread := threadTo(chat, messageID)
+	if len(thread) == 0 {
+		return nil, fmt.Errorf("message %s not found in chat %s", messageID, chatID)
+	}
+	message := &thread[len(thread)-1]
+	if message.Role != "assistant" {
+		return nil, fmt.Errorf("rated message %s is not an assistant message", messageID)
+	}
+
+	rating := &Rating{
+		Feedback: feedback,
+		Chat:     chat,
+		Message:  message,
+		Thread:   thread,
+		Value:    intValue(feedback.Data["rating"]),
+		Model:    stringValue(feedback.Data["model_id"]),
+		Tags:     feedbackTags(feedback, chat),
+	}
+	if details, ok := feedback.Data["details"].(map[string]interface{}); ok {
+		rating.Score = intValue(details["rating"])
+	}
+	if rating.Model == "" {
+		rating.Model = message.Model
+	}
+
+	return rating, nil
+}
+
+// Match reports whether a rating passes the filter
+func (f *Filter) Match(r *Rating) bool {
+	if f.Rating != 0 && r.Value != f.Rating {
+		return false
+	}
+	if f.MinScore > 0 && r.Score < f.MinScore {
+		return false
+	}
+
+	rated := time.Unix(r.Feedback.CreatedAt, 0)
+	if !f.Since.IsZero() && rated.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && !rated.Before(f.Until) {
+		return false
+	}
+
+	if len(f.Models) > 0 && !matchGlob(f.Models, r.Model) {
+		return false
+	}
+
+	if len(f.Tags) > 0 && !matchTags(f.Tags, r.Tags) {
+		return false
+	}
+
+	return true
+}
+
+// snapshotChat decodes the chat stored in a feedback snapshot
+func snapshotChat(feedback *openwebui.Feedback) (*openwebui.Chat, error) {
+	raw, ok := feedback.Snapshot["chat"]
+	if !ok {
+		return nil, fmt.Errorf("chat not found and feedback has no snapshot")
+	}
+
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot: %w", err)
+	}
+	var chat openwebui.Chat
+	if err := json.Unmarshal(data, &chat); err != nil {
+		return nil, fmt.Errorf("failed to parse snapshot: %w", err)
+	}
+	return &chat, nil
+}
+
+// messageIndex returns the position of a message in the chat, or -1
+func messageIndex(chat *openwebui.Chat, messageID string) int {
+	for i := range chat.Chat.Messages {
+		if chat.Chat.Messages[i].ID == messageID {
+			return i
+		}
+	}
+	return -1
+}
+
+// threadTo returns the messages from the root of the chat to a message
+// Parent links are followed when present, otherwise the message list is linear
+func threadTo(chat *openwebui.Chat, messageID string) []openwebui.Message {
+	messages := chat.Chat.Messages
+	idx := messageIndex(chat, messageID)
+	if idx < 0 {
+		return nil
+	}
+
+	linked := false
+	index := make(map[string]int, len(messages))
+	for i := range messages {
+		if messages[i].ParentID != nil {
+			linked = true
+		}
+		if messages[i].ID != "" {
+			index[messages[i].ID] = i
+		}
+	}
+	if !linked {
+		return append([]openwebui.Message(nil), messages[:idx+1]...)
+	}
+
+	var thread []openwebui.Message
+	seen := make(map[int]bool)
+	for i, ok := idx, true; ok && !seen[i]; {
+		seen[i] = true
+		thread = append(thread, messages[i])
+		if messages[i].ParentID == nil {
+			break
+		}
+		i, ok = index[*messages[i].ParentID]
+	}
+
+	for l, r := 0, len(thread)-1; l < r; l, r = l+1, r-1 {
+		thread[l], thread[r] = thread[r], thread[l]
+	}
+	return thread
+}
+
+// siblings returns the other replies to the parent of a message
+func siblings(chat *openwebui.Chat, message *openwebui.Message) []openwebui.Message {
+	if message.ParentID == nil {
+		return nil
+	}
+	var result []openwebui.Message
+	for _, m := range chat.Chat.Messages {
+		if m.ID != message.ID && m.ParentID != nil && *m.ParentID == *message.ParentID {
+			result = append(result, m)
+		}
+	}
+	return result
+}
+
+// feedbackTags collects the tags of a feedback and its chat
+func feedbackTags(feedback *openwebui.Feedback, chat *openwebui.Chat) []string {
+	var tags []string
+	for _, source := range []interface{}{feedback.Data["tags"], feedback.Meta["tags"], chat.Meta["tags"]} {
+		values, _ := source.([]interface{})
+		for _, value := range values {
+			switch tag := value.(type) {
+			case string:
+				tags = append(tags, tag)
+			case map[string]interface{}:
+				// Chat tags may be stored as {"id": ..., "name": ...}
+				if name := stringValue(tag["name"]); name != "" {
+					tags = append(tags, name)
+				} else if id := stringValue(tag["id"]); id != "" {
+					tags = append(tags, id)
+				}
+			}
+		}
+	}
+	return tags
+}
+
+// matchGlob matches a value against glob patterns
+func matchGlob(patterns []string, value string) bool {
+	for _, pattern := range patterns {
+		if pattern == value {
+			return true
+		}
+		if ok, _ := path.Match(pattern, value); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// matchTags reports whether any tag is wanted, ignoring case
+func matchTags(wanted []string, tags []string) bool {
+	for _, w := range wanted {
+		for _, tag := range tags {
+			if strings.EqualFold(w, tag) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// stringValue returns a JSON value as a string
+func stringValue(v interface{}) string {
+	s, _ := v.(string)
+	return s
+}
+
+// intValue returns a JSON number or numeric string as an int
+func intValue(v interface{}) int {
+	switch value := v.(type) {
+	case float64:
+		return int(value)
+	case string:
+		n, _ := strconv.Atoi(strings.TrimSpace(value))
+		return n
+	}
+	return 0
+}
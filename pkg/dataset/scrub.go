@@ -0,0 +1,117 @@
+package dataset
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/vosiander/open-webui-backup/pkg/redact"
+)
+
+// Scrubber rewrites message content before it is written to a dataset
+type Scrubber interface {
+	Name() string
+	Scrub(text string) (string, error)
+}
+
+// RegexScrubber replaces every match of a pattern with a placeholder
+type RegexScrubber struct {
+	name        string
+	pattern     *regexp.Regexp
+	replacement string
+}
+
+// CommandScrubber pipes each message through an external command
+// The command reads the text on stdin and writes the scrubbed text to stdout
+type CommandScrubber struct {
+	command string
+	args    []string
+}
+
+// builtinScrubbers are the scrubbers selectable by name, built from the redaction patterns
+var builtinScrubbers = func() map[string]*RegexScrubber {
+	scrubbers := make(map[string]*RegexScrubber, len(redact.Patterns))
+	for name, pattern := range redact.Patterns {
+		scrubbers[name] = &RegexScrubber{name: name, pattern: pattern.Regexp, replacement: pattern.Replacement}
+	}
+	return scrubbers
+}()
+
+// NewRegexScrubber creates a scrubber replacing matches of pattern
+func NewRegexScrubber(name, pattern, replacement string) *RegexScrubber {
+	return &RegexScrubber{name: name, pattern: regexp.MustCompile(pattern), replacement: replacement}
+}
+
+// Name returns the scrubber name
+func (s *RegexScrubber) Name() string {
+	return s.name
+}
+
+// Scrub replaces all matches
+func (s *RegexScrubber) Scrub(text string) (string, error) {
+	return s.pattern.ReplaceAllString(text, s.replacement), nil
+}
+
+// NewCommandScrubber creates a scrubber running a command line split on whitespace
+func NewCommandScrubber(commandLine string) (*CommandScrubber, error) {
+	fields := strings.Fields(commandLine)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("scrub command is empty")
+	}
+	if _, err := exec.LookPath(fields[0]); err != nil {
+		return nil, fmt.Errorf("scrub command not found: %w", err)
+	}
+	return &CommandScrubber{command: fields[0], args: fields[1:]}, nil
+}
+
+// Name returns the command name
+func (s *CommandScrubber) Name() string {
+	return s.command
+}
+
+// Scrub runs the command on the text
+func (s *CommandScrubber) Scrub(text string) (string, error) {
+	cmd := exec.Command(s.command, s.args...)
+	cmd.Stdin = strings.NewReader(text)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("scrub command %s failed: %w: %s", s.command, err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimRight(stdout.String(), "\n"), nil
+}
+
+// BuiltinScrubber returns a built-in scrubber by name
+func BuiltinScrubber(name string) (Scrubber, error) {
+	s, ok := builtinScrubbers[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("unknown scrubber %q (available: %s)", name, strings.Join(ScrubberNames(), ", "))
+	}
+	return s, nil
+}
+
+// ScrubberNames returns the built-in scrubber names in alphabetical order
+func ScrubberNames() []string {
+	names := make([]string, 0, len(builtinScrubbers))
+	for name := range builtinScrubbers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Scrub applies scrubbers in order
+func Scrub(text string, scrubbers []Scrubber) (string, error) {
+	for _, s := range scrubbers {
+		var err error
+		if text, err = s.Scrub(text); err != nil {
+			return "", err
+		}
+	}
+	return strings.TrimSpace(text), nil
+}
@@ -0,0 +1,345 @@
+package drill
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/vosiander/open-webui-backup/pkg/archive"
+	"github.com/vosiander/open-webui-backup/pkg/openwebui"
+	"github.com/vosiander/open-webui-backup/pkg/restore"
+	"github.com/vosiander/open-webui-backup/pkg/signing"
+)
+
+// Environment variable constants
+const (
+	EnvDrillURL      = "OWUI_DRILL_URL"
+	EnvDrillAPIKey   = "OWUI_DRILL_API_KEY"
+	EnvDrillInterval = "OWUI_DRILL_INTERVAL"
+)
+
+// ProgressCallback is called with progress updates during a drill
+type ProgressCallback func(percent int, message string)
+
+// Options configures a restore drill
+type Options struct {
+	// TargetURL and TargetAPIKey point at the scratch Open WebUI instance
+	TargetURL    string
+	TargetAPIKey string
+	// ProductionURL is refused as a target so a drill never overwrites live data
+	ProductionURL string
+	// Identities are age identity contents used to decrypt the backup
+	Identities []string
+	// TrustedKeys and RequireSignature apply the signature policy before decrypting
+	TrustedKeys      []*signing.PublicKey
+	RequireSignature bool
+}
+
+// TypeResult is the comparison outcome for a single entity type
+type TypeResult struct {
+	Type       string   `json:"type"`
+	Expected   int      `json:"expected"`
+	Found      int      `json:"found"`
+	Matched    int      `json:"matched"`
+	Missing    []string `json:"missing,omitempty"`
+	Mismatched []string `json:"mismatched,omitempty"`
+	Passed     bool     `json:"passed"`
+}
+
+// Report is the result of a restore drill
+type Report struct {
+	Backup     string       `json:"backup"`
+	TargetURL  string       `json:"targetUrl"`
+	StartedAt  time.Time    `json:"startedAt"`
+	FinishedAt time.Time    `json:"finishedAt"`
+	Signature  string       `json:"signature"`
+	Passed     bool         `json:"passed"`
+	Results    []TypeResult `json:"results"`
+	Error      string       `json:"error,omitempty"`
+}
+
+// LoadFromEnv fills the scratch target from OWUI_DRILL_URL and OWUI_DRILL_API_KEY
+// when not set explicitly
+func (o *Options) LoadFromEnv() {
+	if o.TargetURL == "" {
+		o.TargetURL = os.Getenv(EnvDrillURL)
+	}
+	if o.TargetAPIKey == "" {
+		o.TargetAPIKey = os.Getenv(EnvDrillAPIKey)
+	}
+}
+
+// Validate checks that the drill has a usable scratch target
+func (o *Options) Validate() error {
+	if o.TargetURL == "" {
+		return fmt.Errorf("scratch instance URL is required (use --target-url or %s)", EnvDrillURL)
+	}
+	if o.TargetAPIKey == "" {
+		return fmt.Errorf("scratch instance API key is required (use --target-api-key or %s)", EnvDrillAPIKey)
+	}
+	if o.ProductionURL != "" && sameInstance(o.TargetURL, o.ProductionURL) {
+		return fmt.Errorf("refusing to drill against %s: target is the production instance (OPEN_WEBUI_URL)", o.TargetURL)
+	}
+	return nil
+}
+
+// LatestBackup returns the newest .age or .zip backup in a directory
+func LatestBackup(dir string) (string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to read directory: %w", err)
+	}
+
+	var newestFile string
+	var newestTime time.Time
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || (!strings.HasSuffix(name, ".age") && !strings.HasSuffix(name, ".zip")) {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+
+		if info.ModTime().After(newestTime) {
+			newestTime = info.ModTime()
+			newestFile = filepath.Join(dir, name)
+		}
+	}
+
+	if newestFile == "" {
+		return "", fmt.Errorf("no backup files found in %s", dir)
+	}
+
+	return newestFile, nil
+}
+
+// Run restores a backup into the scratch instance and compares the result with the archive
+// The returned report is always non-nil; err is set when the drill could not complete
+func Run(backupFile string, opts *Options, progress ProgressCallback) (*Report, error) {
+	report := &Report{
+		Backup:    filepath.Base(backupFile),
+		TargetURL: opts.TargetURL,
+		StartedAt: time.Now().UTC(),
+	}
+
+	err := run(backupFile, opts, report, progress)
+	report.FinishedAt = time.Now().UTC()
+	if err != nil {
+		report.Passed = false
+		report.Error = err.Error()
+	}
+
+	return report, err
+}
+
+// run performs the drill steps and fills in the report
+func run(backupFile string, opts *Options, report *Report, progress ProgressCallback) error {
+	if progress == nil {
+		progress = func(int, string) {}
+	}
+
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+
+	// Signature policy
+	progress(2, "Checking backup signature...")
+	sigResult, err := signing.VerifyArchive(backupFile, opts.TrustedKeys)
+	if err != nil {
+		return fmt.Errorf("failed to verify signature: %w", err)
+	}
+	report.Signature = string(sigResult.Status)
+	if err := signing.Enforce(sigResult, opts.RequireSignature); err != nil {
+		return err
+	}
+
+	// Decrypt
+	progress(5, "Decrypting backup...")
+	zipPath, cleanup, err := archive.Decrypt(backupFile, opts.Identities)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	if sigResult.Manifest != nil {
+		if err := signing.VerifyEntries(sigResult.Manifest, zipPath); err != nil {
+			return fmt.Errorf("backup contents do not match signed manifest: %w", err)
+		}
+	}
+
+	// Load expected state from the archive
+	progress(10, "Reading archive contents...")
+	a, err := archive.Load(zipPath)
+	if err != nil {
+		return err
+	}
+	if a.Metadata == nil || !a.Metadata.UnifiedBackup {
+		return fmt.Errorf("drills require a unified backup (owui.json missing or not unified)")
+	}
+
+	// Restore into the scratch instance
+	client := openwebui.NewClient(opts.TargetURL, opts.TargetAPIKey)
+	options := restoreOptionsFor(a)
+
+	restoreProgress := func(percent int, message string) {
+		progress(10+percent*70/100, message)
+	}
+	logrus.Infof("Restoring %s into scratch instance %s", report.Backup, opts.TargetURL)
+	if err := restore.RestoreSelective(client, zipPath, options, true, restoreProgress); err != nil {
+		return fmt.Errorf("restore into scratch instance failed: %w", err)
+	}
+
+	// Compare the scratch instance with the archive
+	progress(85, "Comparing scratch instance with archive...")
+	report.Passed = true
+	for _, entityType := range archive.Types {
+		expected := a.Entities[entityType]
+		if len(expected) == 0 {
+			continue
+		}
+
+		live, err := archive.FetchLive(client, entityType)
+		if err != nil {
+			return fmt.Errorf("failed to read %s from scratch instance: %w", entityType, err)
+		}
+
+		result := Compare(entityType, expected, live)
+		report.Results = append(report.Results, result)
+		if !result.Passed {
+			report.Passed = false
+		}
+	}
+
+	progress(100, "Drill complete")
+	return nil
+}
+
+// Compare checks that every archived entity exists in the live set with identical content
+func Compare(entityType string, expected []*archive.Entity, live []interface{}) TypeResult {
+	result := TypeResult{
+		Type:     entityType,
+		Expected: len(expected),
+		Found:    len(live),
+	}
+
+	liveHashes := make(map[string]string)
+	for _, item := range live {
+		key, hash, err := archive.Fingerprint(entityType, item)
+		if err != nil || key == "" {
+			continue
+		}
+		liveHashes[key] = hash
+	}
+
+	for _, entity := range expected {
+		label := entity.Name
+		if label == "" {
+			label = entity.Key
+		}
+
+		hash, ok := liveHashes[entity.Key]
+		switch {
+		case !ok:
+			result.Missing = append(result.Missing, label)
+		case hash != entity.Hash():
+			result.Mismatched = append(result.Mismatched, label)
+		default:
+			result.Matched++
+		}
+	}
+
+	sort.Strings(result.Missing)
+	sort.Strings(result.Mismatched)
+	result.Passed = result.Matched == result.Expected
+	return result
+}
+
+// restoreOptionsFor selects every type present in the archive
+func restoreOptionsFor(a *archive.Archive) *restore.SelectiveRestoreOptions {
+	has := func(entityType string) bool {
+		return len(a.Entities[entityType]) > 0
+	}
+	return &restore.SelectiveRestoreOptions{
+		Knowledge: has(archive.TypeKnowledge),
+		Models:    has(archive.TypeModel),
+		Tools:     has(archive.TypeTool),
+		Prompts:   has(archive.TypePrompt),
+		Files:     has(archive.TypeFile),
+		Chats:     has(archive.TypeChat),
+		Users:     has(archive.TypeUser),
+		Groups:    has(archive.TypeGroup),
+		Feedbacks: has(archive.TypeFeedback),
+	}
+}
+
+// sameInstance compares two base URLs ignoring scheme case and trailing slashes
+func sameInstance(a, b string) bool {
+	ua, errA := url.Parse(strings.TrimRight(a, "/"))
+	ub, errB := url.Parse(strings.TrimRight(b, "/"))
+	if errA != nil || errB != nil {
+		return strings.TrimRight(a, "/") == strings.TrimRight(b, "/")
+	}
+	return strings.EqualFold(ua.Host, ub.Host) && ua.Path == ub.Path
+}
+
+// WriteReport saves the report as JSON in dir and returns the file path
+func WriteReport(report *Report, dir string) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create report directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal report: %w", err)
+	}
+
+	path := filepath.Join(dir, fmt.Sprintf("drill-%s.json", report.StartedAt.Format("20060102-150405")))
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return "", fmt.Errorf("failed to write report: %w", err)
+	}
+
+	return path, nil
+}
+
+// Log prints a human-readable pass/fail summary of the report
+func (r *Report) Log() {
+	logrus.Info("═══════════════════════════════════════════════════════════════")
+	logrus.Infof("Restore drill: %s → %s", r.Backup, r.TargetURL)
+	logrus.Infof("Signature: %s", r.Signature)
+
+	for _, result := range r.Results {
+		status := "✓ PASS"
+		if !result.Passed {
+			status = "❌ FAIL"
+		}
+		logrus.Infof("%s %-10s expected %d, matched %d, found %d in scratch instance",
+			status, result.Type, result.Expected, result.Matched, result.Found)
+		for _, name := range result.Missing {
+			logrus.Warnf("    missing: %s", name)
+		}
+		for _, name := range result.Mismatched {
+			logrus.Warnf("    content differs: %s", name)
+		}
+	}
+
+	if r.Error != "" {
+		logrus.Errorf("Error: %s", r.Error)
+	}
+
+	if r.Passed {
+		logrus.Infof("✓ Drill PASSED in %s", r.FinishedAt.Sub(r.StartedAt).Round(time.Second))
+	} else {
+		logrus.Errorf("❌ Drill FAILED in %s", r.FinishedAt.Sub(r.StartedAt).Round(time.Second))
+	}
+	logrus.Info("═══════════════════════════════════════════════════════════════")
+}
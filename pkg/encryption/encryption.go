@@ -24,16 +24,18 @@ type DecryptOptions struct {
 }
 
 // EncryptFile encrypts a file using age encryption
+// The file is streamed, so its size is not limited by memory
 func EncryptFile(inputPath, outputPath string, opts *EncryptOptions) error {
 	if opts == nil {
 		return fmt.Errorf("encryption options are required")
 	}
 
-	// Read input file
-	inputData, err := os.ReadFile(inputPath)
+	// Open input file
+	in, err := os.Open(inputPath)
 	if err != nil {
 		return fmt.Errorf("failed to read input file: %w", err)
 	}
+	defer in.Close()
 
 	// Create output file
 	out, err := os.Create(outputPath)
@@ -42,51 +44,23 @@ func EncryptFile(inputPath, outputPath string, opts *EncryptOptions) error {
 	}
 	defer out.Close()
 
-	var w io.Writer
-	var recipients []age.Recipient
-
-	// Determine encryption mode
-	if opts.Passphrase != "" {
-		// Passphrase-based encryption
-		recipient, err := age.NewScryptRecipient(opts.Passphrase)
-		if err != nil {
-			return fmt.Errorf("failed to create passphrase recipient: %w", err)
-		}
-		recipients = []age.Recipient{recipient}
-		logrus.Debug("Using passphrase-based encryption")
-	} else if len(opts.Recipients) > 0 {
-		// Public key encryption
-		for _, recipientStr := range opts.Recipients {
-			recipient, err := age.ParseX25519Recipient(recipientStr)
-			if err != nil {
-				return fmt.Errorf("failed to parse recipient %s: %w", recipientStr, err)
-			}
-			recipients = append(recipients, recipient)
-		}
-		logrus.Debugf("Using public key encryption with %d recipient(s)", len(recipients))
-	} else {
-		return fmt.Errorf("either passphrase or recipients must be provided")
-	}
-
 	// Create age writer with armor (ASCII output)
-	armorWriter := armor.NewWriter(out)
-	defer armorWriter.Close()
-
-	w, err = age.Encrypt(armorWriter, recipients...)
+	w, err := NewEncryptWriter(out, opts)
 	if err != nil {
-		return fmt.Errorf("failed to create encryption writer: %w", err)
+		return err
 	}
 
 	// Write encrypted data
-	if _, err := w.Write(inputData); err != nil {
+	if _, err := io.Copy(w, in); err != nil {
 		return fmt.Errorf("failed to write encrypted data: %w", err)
 	}
 
 	// Close the writer to finalize encryption
-	if closer, ok := w.(io.Closer); ok {
-		if err := closer.Close(); err != nil {
-			return fmt.Errorf("failed to finalize encryption: %w", err)
-		}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return fmt.Errorf("failed to write encrypted file: %w", err)
 	}
 
 	logrus.Infof("File encrypted: %s -> %s", inputPath, outputPath)
@@ -94,6 +68,7 @@ func EncryptFile(inputPath, outputPath string, opts *EncryptOptions) error {
 }
 
 // DecryptFile decrypts an age-encrypted file
+// The file is streamed, so its size is not limited by memory
 func DecryptFile(inputPath, outputPath string, opts *DecryptOptions) error {
 	if opts == nil {
 		return fmt.Errorf("decryption options are required")
@@ -106,6 +81,112 @@ func DecryptFile(inputPath, outputPath string, opts *DecryptOptions) error {
 	}
 	defer inputFile.Close()
 
+	// Decrypt with the armor decoder (ASCII format)
+	r, err := NewDecryptReader(inputFile, opts)
+	if err != nil {
+		return err
+	}
+
+	// Write to output file
+	out, err := os.OpenFile(outputPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to write decrypted file: %w", err)
+	}
+	defer out.Close()
+
+	if _, err := io.Copy(out, r); err != nil {
+		return fmt.Errorf("failed to read decrypted data: %w", err)
+	}
+	if err := out.Close(); err != nil {
+		return fmt.Errorf("failed to write decrypted file: %w", err)
+	}
+
+	logrus.Infof("File decrypted: %s -> %s", inputPath, outputPath)
+	return nil
+}
+
+// NewEncryptWriter returns a writer that encrypts everything written to it into dst
+// The output is armored like EncryptFile's; Close finalizes the encryption but does not close dst
+func NewEncryptWriter(dst io.Writer, opts *EncryptOptions) (io.WriteCloser, error) {
+	if opts == nil {
+		return nil, fmt.Errorf("encryption options are required")
+	}
+	recipients, err := parseRecipients(opts)
+	if err != nil {
+		return nil, err
+	}
+	armorWriter := armor.NewWriter(dst)
+	w, err := age.Encrypt(armorWriter, recipients...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create encryption writer: %w", err)
+	}
+	return &encryptWriter{WriteCloser: w, armor: armorWriter}, nil
+}
+
+// encryptWriter closes the age stream and then its armor
+type encryptWriter struct {
+	io.WriteCloser
+	armor io.WriteCloser
+}
+
+// Close finalizes the encryption
+func (w *encryptWriter) Close() error {
+	if err := w.WriteCloser.Close(); err != nil {
+		return fmt.Errorf("failed to finalize encryption: %w", err)
+	}
+	if err := w.armor.Close(); err != nil {
+		return fmt.Errorf("failed to finalize encryption: %w", err)
+	}
+	return nil
+}
+
+// NewDecryptReader returns a reader that decrypts an armored age stream as it is read
+func NewDecryptReader(src io.Reader, opts *DecryptOptions) (io.Reader, error) {
+	if opts == nil {
+		return nil, fmt.Errorf("decryption options are required")
+	}
+	identities, err := parseIdentities(opts)
+	if err != nil {
+		return nil, err
+	}
+	r, err := age.Decrypt(armor.NewReader(src), identities...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt file: %w", err)
+	}
+	return r, nil
+}
+
+// parseRecipients returns the age recipients for a passphrase or public keys
+func parseRecipients(opts *EncryptOptions) ([]age.Recipient, error) {
+	var recipients []age.Recipient
+
+	// Determine encryption mode
+	if opts.Passphrase != "" {
+		// Passphrase-based encryption
+		recipient, err := age.NewScryptRecipient(opts.Passphrase)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create passphrase recipient: %w", err)
+		}
+		recipients = []age.Recipient{recipient}
+		logrus.Debug("Using passphrase-based encryption")
+	} else if len(opts.Recipients) > 0 {
+		// Public key encryption
+		for _, recipientStr := range opts.Recipients {
+			recipient, err := age.ParseX25519Recipient(recipientStr)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse recipient %s: %w", recipientStr, err)
+			}
+			recipients = append(recipients, recipient)
+		}
+		logrus.Debugf("Using public key encryption with %d recipient(s)", len(recipients))
+	} else {
+		return nil, fmt.Errorf("either passphrase or recipients must be provided")
+	}
+	return recipients, nil
+}
+
+// parseIdentities returns the age identities for a passphrase or identity file contents
+func parseIdentities(opts *DecryptOptions) ([]age.Identity, error) {
 	var identities []age.Identity
 
 	// Determine decryption mode
@@ -113,7 +194,7 @@ func DecryptFile(inputPath, outputPath string, opts *DecryptOptions) error {
 		// Passphrase-based decryption
 		identity, err := age.NewScryptIdentity(opts.Passphrase)
 		if err != nil {
-			return fmt.Errorf("failed to create passphrase identity: %w", err)
+			return nil, fmt.Errorf("failed to create passphrase identity: %w", err)
 		}
 		identities = []age.Identity{identity}
 		logrus.Debug("Using passphrase-based decryption")
@@ -122,37 +203,15 @@ func DecryptFile(inputPath, outputPath string, opts *DecryptOptions) error {
 		for i, identityContent := range opts.Identities {
 			ids, err := age.ParseIdentities(strings.NewReader(identityContent))
 			if err != nil {
-				return fmt.Errorf("failed to parse identity %d: %w", i+1, err)
+				return nil, fmt.Errorf("failed to parse identity %d: %w", i+1, err)
 			}
 			identities = append(identities, ids...)
 		}
 		logrus.Debugf("Using identity decryption with %d identity string(s)", len(opts.Identities))
 	} else {
-		return fmt.Errorf("either passphrase or identities must be provided")
-	}
-
-	// Try to read with armor decoder first (ASCII format)
-	armorReader := armor.NewReader(inputFile)
-
-	// Decrypt
-	r, err := age.Decrypt(armorReader, identities...)
-	if err != nil {
-		return fmt.Errorf("failed to decrypt file: %w", err)
+		return nil, fmt.Errorf("either passphrase or identities must be provided")
 	}
-
-	// Read decrypted data
-	decryptedData, err := io.ReadAll(r)
-	if err != nil {
-		return fmt.Errorf("failed to read decrypted data: %w", err)
-	}
-
-	// Write to output file
-	if err := os.WriteFile(outputPath, decryptedData, 0600); err != nil {
-		return fmt.Errorf("failed to write decrypted file: %w", err)
-	}
-
-	logrus.Infof("File decrypted: %s -> %s", inputPath, outputPath)
-	return nil
+	return identities, nil
 }
 
 // IsEncrypted checks if a file appears to be age-encrypted
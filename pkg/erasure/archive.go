@@ -0,0 +1,201 @@
+package erasure
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/vosiander/open-webui-backup/pkg/archive"
+	"github.com/vosiander/open-webui-backup/pkg/database"
+	"github.com/vosiander/open-webui-backup/pkg/openwebui"
+	"github.com/vosiander/open-webui-backup/pkg/snapshot"
+	"github.com/vosiander/open-webui-backup/pkg/uploads"
+	"github.com/vosiander/open-webui-backup/pkg/vectorstore"
+)
+
+// dumpEntry is the database dump inside a unified backup
+const dumpEntry = database.DumpEntry
+
+// ArchiveResult records what was removed from one backup
+type ArchiveResult struct {
+	File        string    `json:"file"`
+	RewrittenAt time.Time `json:"rewritten_at"`
+	Entities    int       `json:"entities"`
+	Memberships int       `json:"memberships"`
+	DumpRows    int       `json:"dump_rows"`
+	Resigned    bool      `json:"resigned,omitempty"`
+}
+
+// Changed reports whether anything was removed
+func (r *ArchiveResult) Changed() bool {
+	return r.Entities > 0 || r.Memberships > 0 || r.DumpRows > 0
+}
+
+// Applied reports whether owui.json already lists the erasure request
+func Applied(zipPath, requestID string) (bool, error) {
+	data, err := archive.ReadEntry(zipPath, "owui.json")
+	if err != nil {
+		return false, err
+	}
+	var metadata openwebui.BackupMetadata
+	if err := json.Unmarshal(data, &metadata); err != nil {
+		return false, fmt.Errorf("failed to parse owui.json: %w", err)
+	}
+	for _, id := range metadata.Erasures {
+		if id == requestID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// EraseArchive copies an unencrypted unified backup ZIP to dstPath without the user's data
+// The user and everything with its user_id are dropped, the user is removed from membership
+// and access control lists, and matching rows are dropped from the database dump or SQLite snapshot
+// and from pgvector embeddings
+func EraseArchive(srcPath, dstPath string, request *Request) (*ArchiveResult, error) {
+	result := &ArchiveResult{RewrittenAt: time.Now().UTC()}
+	// Dropped entity IDs by type, for the snapshot record
+	droppedIDs := make(map[string]map[string]bool)
+
+	_, err := archive.Rewrite(srcPath, dstPath, &archive.RewriteOptions{
+		Entity: func(e *archive.Entity) ([]byte, error) {
+			data, dropped, removed, err := eraseEntity(e, request.UserID)
+			if dropped {
+				result.Entities++
+				if droppedIDs[e.Type] == nil {
+					droppedIDs[e.Type] = make(map[string]bool)
+				}
+				droppedIDs[e.Type][e.ID] = true
+			}
+			result.Memberships += removed
+			return data, err
+		},
+		Content: func(name string) func(dst io.Writer, src io.Reader) (bool, error) {
+			filter := FilterDump
+			switch {
+			case name == dumpEntry:
+			case name == sqliteEntry:
+				filter = FilterSQLite
+			case name == vectorstore.PgvectorEntry:
+				filter = FilterVectorRows
+			case name == database.CustomDumpEntry:
+				logrus.Warnf("Custom format database dump in %s is not filtered; take a new backup after erasing the user from the live instance", srcPath)
+				return nil
+			case name == vectorstore.ChromaPrefix+vectorstore.ChromaSQLiteFile:
+				logrus.Warnf("Chroma embeddings in %s are not filtered; remove the user's files from the live instance and take a new backup", srcPath)
+				return nil
+			case name == uploads.ManifestEntry:
+				logrus.Warnf("Uploaded files in %s are not filtered; remove the user's files from the live instance and take a new backup", srcPath)
+				return nil
+			case name == snapshot.Entry:
+				return func(dst io.Writer, src io.Reader) (bool, error) {
+					return filterSnapshot(dst, src, droppedIDs)
+				}
+			default:
+				return nil
+			}
+			return func(dst io.Writer, src io.Reader) (bool, error) {
+				stats, err := filter(dst, src, request.UserID)
+				if err != nil {
+					return false, err
+				}
+				result.DumpRows += stats.Rows
+				result.Memberships += stats.Members
+				return stats.Rows > 0 || stats.Members > 0, nil
+			}
+		},
+		Metadata: func(metadata *openwebui.BackupMetadata) error {
+			metadata.Erasures = append(metadata.Erasures, request.ID)
+			return nil
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// filterSnapshot removes dropped entities from the snapshot record of a consistent backup
+func filterSnapshot(dst io.Writer, src io.Reader, dropped map[string]map[string]bool) (bool, error) {
+	data, err := io.ReadAll(src)
+	if err != nil {
+		return false, fmt.Errorf("failed to read %s: %w", snapshot.Entry, err)
+	}
+	var record snapshot.Record
+	if err := json.Unmarshal(data, &record); err != nil {
+		return false, fmt.Errorf("failed to parse %s: %w", snapshot.Entry, err)
+	}
+	if !record.Remove(dropped) {
+		_, err := dst.Write(data)
+		return false, err
+	}
+	data, err = json.MarshalIndent(&record, "", "  ")
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal %s: %w", snapshot.Entry, err)
+	}
+	_, err = dst.Write(data)
+	return true, err
+}
+
+// eraseEntity returns the entity without the user, or nil if the entity belongs to the user
+func eraseEntity(e *archive.Entity, userID string) ([]byte, bool, int, error) {
+	if e.Type == archive.TypeUser && e.ID == userID {
+		return nil, true, 0, nil
+	}
+	if !bytes.Contains(e.Data, []byte(userID)) {
+		return e.Data, false, 0, nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(e.Data))
+	decoder.UseNumber()
+	var fields map[string]interface{}
+	if err := decoder.Decode(&fields); err != nil {
+		return nil, false, 0, fmt.Errorf("failed to parse entity: %w", err)
+	}
+	if owner, _ := fields["user_id"].(string); owner == userID {
+		return nil, true, 0, nil
+	}
+
+	removed := removeMember(fields, userID)
+	if removed == 0 {
+		return e.Data, false, 0, nil
+	}
+	data, err := json.MarshalIndent(fields, "", "  ")
+	if err != nil {
+		return nil, false, 0, fmt.Errorf("failed to marshal entity: %w", err)
+	}
+	return data, false, removed, nil
+}
+
+// removeMember drops the user from every user_ids and admin_ids list, including access_control
+func removeMember(value interface{}, userID string) int {
+	removed := 0
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			list, ok := child.([]interface{})
+			if ok && memberColumns[key] {
+				kept := make([]interface{}, 0, len(list))
+				for _, item := range list {
+					if id, _ := item.(string); id == userID {
+						removed++
+						continue
+					}
+					kept = append(kept, item)
+				}
+				v[key] = kept
+				continue
+			}
+			removed += removeMember(child, userID)
+		}
+	case []interface{}:
+		for _, child := range v {
+			removed += removeMember(child, userID)
+		}
+	}
+	return removed
+}
@@ -0,0 +1,160 @@
+package erasure
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/vosiander/open-webui-backup/pkg/archive"
+	"github.com/vosiander/open-webui-backup/pkg/encryption"
+	"github.com/vosiander/open-webui-backup/pkg/signing"
+)
+
+// BackupOptions configures how stored backups are rewritten
+type BackupOptions struct {
+	Identities []string // age identity contents for encrypted backups
+	Recipients []string // age recipients for re-encrypting rewritten backups
+	SigningKey *signing.PrivateKey
+	DryRun     bool // count what would be removed without replacing any file
+}
+
+// FindBackups lists the unified backup files (*.zip and *.zip.age) in the given directories
+func FindBackups(dirs []string) ([]string, error) {
+	var files []string
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read backup directory %s: %w", dir, err)
+		}
+		for _, entry := range entries {
+			name := entry.Name()
+			if entry.IsDir() || strings.Contains(name, ".erase.") {
+				continue
+			}
+			if !strings.HasSuffix(name, ".zip") && !strings.HasSuffix(name, ".zip.age") {
+				continue
+			}
+			files = append(files, filepath.Join(dir, name))
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// EraseBackup removes the user of a request from one stored backup and replaces it atomically
+// Returns nil when the request was already applied; a signed backup is re-signed or the file is left untouched
+func EraseBackup(path string, request *Request, options *BackupOptions) (*ArchiveResult, error) {
+	encrypted := strings.HasSuffix(path, ".age")
+	if encrypted && len(options.Recipients) == 0 && !options.DryRun {
+		return nil, fmt.Errorf("encryption recipients are required to rewrite encrypted backups")
+	}
+	signed := false
+	if _, err := os.Stat(signing.SignaturePath(path)); err == nil {
+		signed = true
+		if options.SigningKey == nil && !options.DryRun {
+			return nil, fmt.Errorf("backup is signed; a signing key is required to re-sign it")
+		}
+	}
+
+	zipPath, cleanup, err := archive.Decrypt(path, options.Identities)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	applied, err := Applied(zipPath, request.ID)
+	if err != nil {
+		return nil, err
+	}
+	if applied {
+		return nil, nil
+	}
+
+	tmpZip := path + ".erase.zip"
+	defer os.Remove(tmpZip)
+	result, err := EraseArchive(zipPath, tmpZip, request)
+	if err != nil {
+		return nil, err
+	}
+	result.File = filepath.Base(path)
+	if options.DryRun {
+		return result, nil
+	}
+
+	// The request ID is recorded even when nothing was removed, so later runs skip the file
+	tmpOut := path + ".erase.tmp"
+	defer os.Remove(tmpOut)
+	if encrypted {
+		if err := encryption.EncryptFileWithRecipients(tmpZip, tmpOut, options.Recipients); err != nil {
+			return nil, fmt.Errorf("failed to encrypt rewritten backup: %w", err)
+		}
+	} else if err := os.Rename(tmpZip, tmpOut); err != nil {
+		return nil, fmt.Errorf("failed to stage rewritten backup: %w", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+	if err := os.Chmod(tmpOut, info.Mode().Perm()); err != nil {
+		return nil, fmt.Errorf("failed to set permissions: %w", err)
+	}
+	if err := os.Rename(tmpOut, path); err != nil {
+		return nil, fmt.Errorf("failed to replace %s: %w", path, err)
+	}
+	// Keep the original timestamp so retention by age still works
+	if err := os.Chtimes(path, info.ModTime(), info.ModTime()); err != nil {
+		logrus.Warnf("Failed to restore modification time of %s: %v", path, err)
+	}
+
+	if signed {
+		signZip := tmpZip
+		if !encrypted {
+			signZip = path
+		}
+		if _, err := signing.SignArchive(options.SigningKey, signZip, path); err != nil {
+			return nil, fmt.Errorf("backup rewritten but re-signing failed: %w", err)
+		}
+		result.Resigned = true
+	}
+	return result, nil
+}
+
+// EraseBackups applies the requests to every backup in the directories
+// Failures are logged and counted; the remaining backups are still processed
+func EraseBackups(dirs []string, requests []*Request, options *BackupOptions) (int, error) {
+	files, err := FindBackups(dirs)
+	if err != nil {
+		return 0, err
+	}
+	logrus.Infof("Found %d backup(s) in %s", len(files), strings.Join(dirs, ", "))
+
+	failed := 0
+	for _, file := range files {
+		for _, request := range requests {
+			result, err := EraseBackup(file, request, options)
+			if err != nil {
+				logrus.Errorf("Failed to erase user %s from %s: %v", request.UserID, filepath.Base(file), err)
+				failed++
+				continue
+			}
+			if result == nil {
+				logrus.Debugf("%s: request %s already applied", filepath.Base(file), request.ID)
+				continue
+			}
+			if result.Changed() {
+				logrus.Infof("%s: removed %d entities, %d memberships, %d database rows",
+					result.File, result.Entities, result.Memberships, result.DumpRows)
+			} else {
+				logrus.Debugf("%s: no data of user %s", result.File, request.UserID)
+			}
+			if !options.DryRun {
+				request.Archives = append(request.Archives, *result)
+			}
+		}
+	}
+	return failed, nil
+}
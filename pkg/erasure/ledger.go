@@ -0,0 +1,115 @@
+package erasure
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// LedgerFile is the default ledger file name inside the backups directory
+const LedgerFile = "erasure-ledger.json"
+
+// Request is a recorded erasure request
+// Only the user ID and a hash of the email are kept, so the ledger itself holds no personal data
+type Request struct {
+	ID          string          `json:"id"`
+	UserID      string          `json:"user_id"`
+	EmailSHA256 string          `json:"email_sha256,omitempty"`
+	Reason      string          `json:"reason,omitempty"`
+	RequestedAt time.Time       `json:"requested_at"`
+	Live        *LiveResult     `json:"live,omitempty"`
+	Archives    []ArchiveResult `json:"archives,omitempty"`
+}
+
+// Ledger is the list of erasure requests, stored as JSON
+type Ledger struct {
+	path     string
+	Requests []*Request `json:"requests"`
+}
+
+// NewRequest creates a request for a user
+func NewRequest(userID, email, reason string) *Request {
+	r := &Request{
+		ID:          uuid.New().String(),
+		UserID:      userID,
+		Reason:      reason,
+		RequestedAt: time.Now().UTC(),
+	}
+	if email != "" {
+		r.EmailSHA256 = HashEmail(email)
+	}
+	return r
+}
+
+// HashEmail returns the hex SHA-256 of a normalized email
+func HashEmail(email string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(strings.TrimSpace(email))))
+	return hex.EncodeToString(sum[:])
+}
+
+// LoadLedger reads a ledger file; a missing file is an empty ledger
+func LoadLedger(path string) (*Ledger, error) {
+	l := &Ledger{path: path}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return l, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read erasure ledger: %w", err)
+	}
+	if err := json.Unmarshal(data, l); err != nil {
+		return nil, fmt.Errorf("failed to parse erasure ledger %s: %w", path, err)
+	}
+	return l, nil
+}
+
+// Add appends a request to the ledger
+func (l *Ledger) Add(r *Request) {
+	l.Requests = append(l.Requests, r)
+}
+
+// Find returns the requests matching a user ID or email
+func (l *Ledger) Find(selector string) []*Request {
+	emailHash := HashEmail(selector)
+	var found []*Request
+	for _, r := range l.Requests {
+		if r.UserID == selector || r.EmailSHA256 == emailHash {
+			found = append(found, r)
+		}
+	}
+	return found
+}
+
+// Save writes the ledger atomically
+func (l *Ledger) Save() error {
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal erasure ledger: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(l.path), 0755); err != nil {
+		return fmt.Errorf("failed to create ledger directory: %w", err)
+	}
+	tmp := l.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write erasure ledger: %w", err)
+	}
+	if err := os.Rename(tmp, l.path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write erasure ledger: %w", err)
+	}
+	return nil
+}
+
+// Path returns the ledger file path
+func (l *Ledger) Path() string {
+	return l.path
+}
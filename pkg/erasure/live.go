@@ -0,0 +1,178 @@
+package erasure
+
+import (
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+	"github.com/vosiander/open-webui-backup/pkg/openwebui"
+)
+
+// LiveResult counts what was deleted from the live instance
+type LiveResult struct {
+	Chats       int      `json:"chats"`
+	Files       int      `json:"files"`
+	Knowledge   int      `json:"knowledge"`
+	Prompts     int      `json:"prompts"`
+	Models      int      `json:"models"`
+	Tools       int      `json:"tools"`
+	Feedbacks   int      `json:"feedbacks"`
+	Memories    int      `json:"memories"`
+	UserDeleted bool     `json:"user_deleted"`
+	Errors      []string `json:"errors,omitempty"`
+}
+
+// Plan lists the IDs of a user's data on the live instance
+type Plan struct {
+	User      openwebui.User
+	Chats     []string
+	Files     []string
+	Knowledge []string
+	Prompts   []string // prompt commands
+	Models    []string
+	Tools     []string
+	Feedbacks []string
+	Memories  []string
+	Notes     []string
+}
+
+// PlanLive collects the IDs of everything owned by a user (admin API key required)
+func PlanLive(client *openwebui.Client, user openwebui.User) (*Plan, error) {
+	p := &Plan{User: user}
+	owned := func(userID string) bool { return userID == user.ID }
+
+	chats, err := client.GetAllChatsDB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch chats: %w", err)
+	}
+	for _, chat := range chats {
+		if owned(chat.UserID) {
+			p.Chats = append(p.Chats, chat.ID)
+		}
+	}
+
+	files, err := client.ListFiles()
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch files: %w", err)
+	}
+	for _, file := range files {
+		if owned(file.UserID) {
+			p.Files = append(p.Files, file.ID)
+		}
+	}
+
+	knowledge, err := client.ListKnowledge()
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch knowledge bases: %w", err)
+	}
+	for _, kb := range knowledge {
+		if owned(kb.UserID) {
+			p.Knowledge = append(p.Knowledge, kb.ID)
+		}
+	}
+
+	prompts, err := client.ListPrompts()
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch prompts: %w", err)
+	}
+	for _, prompt := range prompts {
+		if owned(prompt.UserID) {
+			p.Prompts = append(p.Prompts, prompt.Command)
+		}
+	}
+
+	models, err := client.ExportModels()
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch models: %w", err)
+	}
+	for _, model := range models {
+		if owned(model.UserID) {
+			p.Models = append(p.Models, model.ID)
+		}
+	}
+
+	tools, err := client.ExportTools()
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch tools: %w", err)
+	}
+	for _, tool := range tools {
+		if owned(tool.UserID) {
+			p.Tools = append(p.Tools, tool.ID)
+		}
+	}
+
+	feedbacks, err := client.GetAllFeedbacks()
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch feedbacks: %w", err)
+	}
+	for _, feedback := range feedbacks {
+		if owned(feedback.UserID) {
+			p.Feedbacks = append(p.Feedbacks, feedback.ID)
+		}
+	}
+
+	// Memories are only reachable with the user's own API key; otherwise they go with the user record
+	if current, err := client.GetCurrentUser(); err != nil {
+		return nil, fmt.Errorf("failed to fetch current user: %w", err)
+	} else if current.ID == user.ID {
+		memories, err := client.ListMemories()
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch memories: %w", err)
+		}
+		for _, memory := range memories {
+			p.Memories = append(p.Memories, memory.ID)
+		}
+	} else {
+		p.Notes = append(p.Notes, "Memories are not listed with an admin key; Open WebUI removes them with the user record")
+	}
+
+	return p, nil
+}
+
+// Count returns the number of items in the plan
+func (p *Plan) Count() int {
+	return len(p.Chats) + len(p.Files) + len(p.Knowledge) + len(p.Prompts) + len(p.Models) +
+		len(p.Tools) + len(p.Feedbacks) + len(p.Memories)
+}
+
+// Execute deletes everything in the plan, then the user itself
+// Failures are recorded and do not stop the remaining deletions; the user is only deleted if everything else succeeded
+func (p *Plan) Execute(client *openwebui.Client) *LiveResult {
+	result := &LiveResult{}
+
+	run := func(what string, ids []string, del func(string) error, counter *int) {
+		for _, id := range ids {
+			if err := del(id); err != nil {
+				logrus.Errorf("Failed to delete %s %s: %v", what, id, err)
+				result.Errors = append(result.Errors, fmt.Sprintf("%s %s: %v", what, id, err))
+				continue
+			}
+			*counter++
+		}
+		if len(ids) > 0 {
+			logrus.Infof("Deleted %d/%d %s", *counter, len(ids), what)
+		}
+	}
+
+	run("chat", p.Chats, client.DeleteChatByID, &result.Chats)
+	run("feedback", p.Feedbacks, client.DeleteFeedbackByID, &result.Feedbacks)
+	run("knowledge base", p.Knowledge, client.DeleteKnowledgeByID, &result.Knowledge)
+	run("file", p.Files, client.DeleteFileByID, &result.Files)
+	run("prompt", p.Prompts, client.DeletePromptByCommand, &result.Prompts)
+	run("model", p.Models, client.DeleteModelByID, &result.Models)
+	run("tool", p.Tools, client.DeleteToolByID, &result.Tools)
+	run("memory", p.Memories, client.DeleteMemoryByID, &result.Memories)
+
+	if len(result.Errors) > 0 {
+		logrus.Warnf("Keeping user %s because %d deletion(s) failed; rerun erase-user to retry", p.User.ID, len(result.Errors))
+		return result
+	}
+
+	if err := client.DeleteUserByID(p.User.ID); err != nil {
+		logrus.Errorf("Failed to delete user %s: %v", p.User.ID, err)
+		result.Errors = append(result.Errors, fmt.Sprintf("user %s: %v", p.User.ID, err))
+		return result
+	}
+	result.UserDeleted = true
+	logrus.Infof("Deleted user %s", p.User.ID)
+	return result
+}
@@ -0,0 +1,142 @@
+package erasure
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// copyHeader matches the start of a COPY block in a plain pg_dump
+var copyHeader = regexp.MustCompile(`^COPY\s+(?:"?(\w+)"?\.)?"?(\w+)"?\s*\(([^)]*)\)\s+FROM\s+stdin;\s*$`)
+
+// userTables are tables whose id column is the user ID
+var userTables = map[string]bool{"user": true, "auth": true}
+
+// memberColumns hold JSON arrays of user IDs
+var memberColumns = map[string]bool{"user_ids": true, "admin_ids": true}
+
+// copyBlock describes the COPY block being filtered
+type copyBlock struct {
+	idColumn      int   // index of id in a user table, -1 otherwise
+	userIDColumn  int   // index of user_id, -1 if absent
+	memberColumns []int // indexes of JSON user ID arrays
+}
+
+// DumpStats counts what FilterDump removed
+type DumpStats struct {
+	Rows    int `json:"rows"`
+	Members int `json:"members"`
+}
+
+// FilterDump copies a plain SQL dump, dropping COPY rows that belong to the user
+// Rows are dropped from user and auth when their id matches, and from any table when user_id matches;
+// the user is also removed from JSON membership arrays such as group user_ids
+func FilterDump(dst io.Writer, src io.Reader, userID string) (*DumpStats, error) {
+	stats := &DumpStats{}
+	reader := bufio.NewReader(src)
+	writer := bufio.NewWriter(dst)
+	var block *copyBlock
+
+	for {
+		line, readErr := reader.ReadString('\n')
+		if readErr != nil && readErr != io.EOF {
+			return nil, fmt.Errorf("failed to read dump: %w", readErr)
+		}
+		if line == "" && readErr == io.EOF {
+			break
+		}
+
+		body := strings.TrimRight(line, "\r\n")
+		switch {
+		case block == nil:
+			if m := copyHeader.FindStringSubmatch(body); m != nil {
+				block = newCopyBlock(m[2], m[3])
+			}
+		case body == `\.`:
+			block = nil
+		default:
+			keep, rewritten := block.filter(body, userID, stats)
+			if !keep {
+				line = ""
+			} else if rewritten != body {
+				line = rewritten + line[len(body):]
+			}
+		}
+
+		if _, err := writer.WriteString(line); err != nil {
+			return nil, fmt.Errorf("failed to write dump: %w", err)
+		}
+		if readErr == io.EOF {
+			break
+		}
+	}
+
+	if err := writer.Flush(); err != nil {
+		return nil, fmt.Errorf("failed to write dump: %w", err)
+	}
+	return stats, nil
+}
+
+// newCopyBlock locates the relevant columns of a COPY column list
+func newCopyBlock(table, columnList string) *copyBlock {
+	block := &copyBlock{idColumn: -1, userIDColumn: -1}
+	for i, column := range strings.Split(columnList, ",") {
+		column = strings.Trim(strings.TrimSpace(column), `"`)
+		switch {
+		case column == "id" && userTables[table]:
+			block.idColumn = i
+		case column == "user_id":
+			block.userIDColumn = i
+		case memberColumns[column]:
+			block.memberColumns = append(block.memberColumns, i)
+		}
+	}
+	return block
+}
+
+// filter decides whether a COPY row is kept and returns it with the user removed from membership arrays
+func (b *copyBlock) filter(row, userID string, stats *DumpStats) (bool, string) {
+	fields := strings.Split(row, "\t")
+	for _, i := range []int{b.idColumn, b.userIDColumn} {
+		if i >= 0 && i < len(fields) && fields[i] == userID {
+			stats.Rows++
+			return false, row
+		}
+	}
+
+	changed := false
+	for _, i := range b.memberColumns {
+		if i >= len(fields) || !strings.Contains(fields[i], userID) {
+			continue
+		}
+		// Escaped values are left alone rather than risk corrupting them
+		if strings.Contains(fields[i], `\`) {
+			continue
+		}
+		var ids []string
+		if err := json.Unmarshal([]byte(fields[i]), &ids); err != nil {
+			continue
+		}
+		kept := ids[:0]
+		for _, id := range ids {
+			if id != userID {
+				kept = append(kept, id)
+			}
+		}
+		data, err := json.Marshal(kept)
+		if err != nil {
+			continue
+		}
+		fields[i] = string(data)
+		stats.Members++
+		changed = true
+	}
+
+	if !changed {
+		return true, row
+	}
+	return true, strings.Join(fields, "\t")
+}
@@ -0,0 +1,122 @@
+package erasure
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/vosiander/open-webui-backup/pkg/database"
+)
+
+// sqliteEntry is the SQLite snapshot inside a unified backup
+const sqliteEntry = database.SQLiteEntry
+
+// FilterSQLite copies a SQLite snapshot, deleting the rows that belong to the user
+// It applies the same rules as FilterDump and vacuums the copy so deleted data does not linger in free pages
+func FilterSQLite(dst io.Writer, src io.Reader, userID string) (*DumpStats, error) {
+	tempDir, err := os.MkdirTemp("", "owui-erase-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(tempDir)
+	path := filepath.Join(tempDir, database.SQLiteFileName)
+
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create snapshot copy: %w", err)
+	}
+	if _, err := io.Copy(file, src); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to copy snapshot: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return nil, fmt.Errorf("failed to copy snapshot: %w", err)
+	}
+
+	output, err := database.RunSQLite(path, `SELECT m.name, p.name FROM sqlite_master m, pragma_table_info(m.name) p
+WHERE m.type = 'table' AND p.name IN ('id', 'user_id', 'user_ids', 'admin_ids') ORDER BY m.name, p.cid;`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect snapshot: %w", err)
+	}
+
+	id := quoteSQLiteLiteral(userID)
+	var deletes, counts, updates []string
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		table, column, ok := strings.Cut(line, "|")
+		if !ok {
+			continue
+		}
+		t, c := quoteSQLiteIdent(table), quoteSQLiteIdent(column)
+		switch {
+		case column == "id" && userTables[table], column == "user_id":
+			deletes = append(deletes, fmt.Sprintf("DELETE FROM %s WHERE %s = %s;", t, c, id))
+		case memberColumns[column]:
+			member := fmt.Sprintf("json_valid(%[1]s.%[2]s) AND EXISTS (SELECT 1 FROM json_each(%[1]s.%[2]s) WHERE value = %[3]s)", t, c, id)
+			counts = append(counts, fmt.Sprintf("(SELECT count(*) FROM %s WHERE %s)", t, member))
+			updates = append(updates, fmt.Sprintf(
+				"UPDATE %[1]s SET %[2]s = (SELECT json_group_array(value) FROM json_each(%[1]s.%[2]s) WHERE value <> %[3]s) WHERE %[4]s;",
+				t, c, id, member))
+		}
+	}
+
+	var sql strings.Builder
+	sql.WriteString("PRAGMA foreign_keys = OFF;\nBEGIN;\n")
+	for _, stmt := range deletes {
+		sql.WriteString(stmt + "\n")
+	}
+	sql.WriteString("SELECT total_changes();\n")
+	if len(counts) > 0 {
+		sql.WriteString("SELECT " + strings.Join(counts, " + ") + ";\n")
+	} else {
+		sql.WriteString("SELECT 0;\n")
+	}
+	for _, stmt := range updates {
+		sql.WriteString(stmt + "\n")
+	}
+	sql.WriteString("COMMIT;\n")
+
+	output, err = database.RunSQLite(path, sql.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to filter snapshot: %w", err)
+	}
+	fields := strings.Fields(output)
+	if len(fields) != 2 {
+		return nil, fmt.Errorf("failed to filter snapshot: unexpected output %q", output)
+	}
+	stats := &DumpStats{}
+	if stats.Rows, err = strconv.Atoi(fields[0]); err != nil {
+		return nil, fmt.Errorf("failed to filter snapshot: %w", err)
+	}
+	if stats.Members, err = strconv.Atoi(fields[1]); err != nil {
+		return nil, fmt.Errorf("failed to filter snapshot: %w", err)
+	}
+
+	if stats.Rows > 0 || stats.Members > 0 {
+		if _, err := database.RunSQLite(path, "VACUUM;"); err != nil {
+			return nil, fmt.Errorf("failed to vacuum snapshot: %w", err)
+		}
+	}
+
+	file, err = os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read filtered snapshot: %w", err)
+	}
+	defer file.Close()
+	if _, err := io.Copy(dst, file); err != nil {
+		return nil, fmt.Errorf("failed to write filtered snapshot: %w", err)
+	}
+	return stats, nil
+}
+
+// quoteSQLiteIdent quotes an SQLite identifier
+func quoteSQLiteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// quoteSQLiteLiteral quotes an SQLite string literal
+func quoteSQLiteLiteral(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
@@ -0,0 +1,96 @@
+package erasure
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ownerKeys are the chunk metadata keys Open WebUI stores the uploading user in
+var ownerKeys = []string{"created_by", "user_id"}
+
+// FilterVectorRows copies pgvector rows in COPY text format, dropping chunks created by the user
+// A row is dropped when one of its JSON object columns (vmetadata) names the user as created_by or user_id
+func FilterVectorRows(dst io.Writer, src io.Reader, userID string) (*DumpStats, error) {
+	stats := &DumpStats{}
+	reader := bufio.NewReader(src)
+	writer := bufio.NewWriter(dst)
+
+	for {
+		line, readErr := reader.ReadString('\n')
+		if readErr != nil && readErr != io.EOF {
+			return nil, fmt.Errorf("failed to read vector rows: %w", readErr)
+		}
+		if line == "" && readErr == io.EOF {
+			break
+		}
+
+		if strings.Contains(line, userID) && ownedChunk(strings.TrimRight(line, "\r\n"), userID) {
+			stats.Rows++
+		} else if _, err := writer.WriteString(line); err != nil {
+			return nil, fmt.Errorf("failed to write vector rows: %w", err)
+		}
+		if readErr == io.EOF {
+			break
+		}
+	}
+
+	if err := writer.Flush(); err != nil {
+		return nil, fmt.Errorf("failed to write vector rows: %w", err)
+	}
+	return stats, nil
+}
+
+// ownedChunk reports whether a COPY row has a JSON object column owned by the user
+func ownedChunk(row, userID string) bool {
+	for _, field := range strings.Split(row, "\t") {
+		if !strings.HasPrefix(field, "{") {
+			continue
+		}
+		var metadata map[string]interface{}
+		if err := json.Unmarshal([]byte(unescapeCopy(field)), &metadata); err != nil {
+			continue
+		}
+		for _, key := range ownerKeys {
+			if owner, _ := metadata[key].(string); owner == userID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// unescapeCopy decodes the backslash escapes of the COPY text format
+func unescapeCopy(field string) string {
+	if !strings.Contains(field, `\`) {
+		return field
+	}
+	var sb strings.Builder
+	for i := 0; i < len(field); i++ {
+		c := field[i]
+		if c != '\\' || i+1 == len(field) {
+			sb.WriteByte(c)
+			continue
+		}
+		i++
+		switch field[i] {
+		case 'n':
+			sb.WriteByte('\n')
+		case 'r':
+			sb.WriteByte('\r')
+		case 't':
+			sb.WriteByte('\t')
+		case 'b':
+			sb.WriteByte('\b')
+		case 'f':
+			sb.WriteByte('\f')
+		case 'v':
+			sb.WriteByte('\v')
+		default:
+			sb.WriteByte(field[i])
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,252 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/vosiander/open-webui-backup/pkg/openwebui"
+)
+
+func init() {
+	Register(&ChatGPTImporter{})
+}
+
+// ChatGPTImporter reads conversations.json from a ChatGPT data export
+type ChatGPTImporter struct{}
+
+// chatgptConversation is a conversation in conversations.json
+type chatgptConversation struct {
+	ID               string                 `json:"id"`
+	ConversationID   string                 `json:"conversation_id"`
+	Title            string                 `json:"title"`
+	CreateTime       float64                `json:"create_time"`
+	UpdateTime       float64                `json:"update_time"`
+	DefaultModelSlug string                 `json:"default_model_slug"`
+	Mapping          map[string]chatgptNode `json:"mapping"`
+}
+
+// chatgptNode is a node of the conversation tree
+type chatgptNode struct {
+	ID       string          `json:"id"`
+	Message  *chatgptMessage `json:"message"`
+	Parent   *string         `json:"parent"`
+	Children []string        `json:"children"`
+}
+
+// chatgptMessage is the message stored in a tree node
+type chatgptMessage struct {
+	ID     string `json:"id"`
+	Author struct {
+		Role string `json:"role"`
+	} `json:"author"`
+	CreateTime *float64               `json:"create_time"`
+	Content    chatgptContent         `json:"content"`
+	Recipient  string                 `json:"recipient"`
+	Metadata   map[string]interface{} `json:"metadata"`
+}
+
+// chatgptContent holds the typed message content
+type chatgptContent struct {
+	ContentType string        `json:"content_type"`
+	Parts       []interface{} `json:"parts"`
+	Text        string        `json:"text"`
+	Language    string        `json:"language"`
+}
+
+// Name returns the format name
+func (i *ChatGPTImporter) Name() string {
+	return "chatgpt"
+}
+
+// Description returns the format description
+func (i *ChatGPTImporter) Description() string {
+	return "ChatGPT data export (conversations.json or the export ZIP)"
+}
+
+// ExportFile returns the file holding the conversations in the export ZIP
+func (i *ChatGPTImporter) ExportFile() string {
+	return "conversations.json"
+}
+
+// Parse converts ChatGPT conversations into Open WebUI chats
+func (i *ChatGPTImporter) Parse(r io.Reader) ([]openwebui.Chat, error) {
+	var conversations []chatgptConversation
+	if err := json.NewDecoder(r).Decode(&conversations); err != nil {
+		return nil, fmt.Errorf("failed to parse ChatGPT conversations: %w", err)
+	}
+
+	chats := make([]openwebui.Chat, 0, len(conversations))
+	for _, conversation := range conversations {
+		chats = append(chats, convertConversation(&conversation))
+	}
+	return chats, nil
+}
+
+// convertConversation builds an Open WebUI chat from a ChatGPT conversation
+// Hidden, empty and tool messages are dropped and their replies are attached to the
+// nearest visible ancestor, so branches from regenerations and edits are preserved
+func convertConversation(c *chatgptConversation) openwebui.Chat {
+	id := c.ConversationID
+	if id == "" {
+		id = c.ID
+	}
+	if id == "" {
+		id = uuid.New().String()
+	}
+
+	var messages []*openwebui.Message
+	index := make(map[string]*openwebui.Message)
+	visited := make(map[string]bool)
+
+	var walk func(nodeID, parentID string)
+	walk = func(nodeID, parentID string) {
+		node, ok := c.Mapping[nodeID]
+		if !ok || visited[nodeID] {
+			return
+		}
+		visited[nodeID] = true
+
+		next := parentID
+		if message := convertMessage(nodeID, &node); message != nil {
+			if parentID != "" {
+				parent := parentID
+				message.ParentID = &parent
+				index[parentID].ChildrenIDs = append(index[parentID].ChildrenIDs, message.ID)
+			}
+			messages = append(messages, message)
+			index[message.ID] = message
+			next = message.ID
+		}
+
+		for _, child := range node.Children {
+			walk(child, next)
+		}
+	}
+
+	for _, root := range rootNodes(c.Mapping) {
+		walk(root, "")
+	}
+
+	chat := openwebui.Chat{
+		ID:        id,
+		Title:     c.Title,
+		CreatedAt: int64(c.CreateTime),
+		UpdatedAt: int64(c.UpdateTime),
+	}
+	if chat.Title == "" {
+		chat.Title = "Imported chat"
+	}
+
+	seen := make(map[string]bool)
+	addModel := func(model string) {
+		if model != "" && !seen[model] {
+			seen[model] = true
+			chat.Chat.Models = append(chat.Chat.Models, model)
+		}
+	}
+	addModel(c.DefaultModelSlug)
+
+	chat.Chat.Messages = make([]openwebui.Message, 0, len(messages))
+	for _, message := range messages {
+		if message.Role == "assistant" {
+			addModel(message.Model)
+		}
+		chat.Chat.Messages = append(chat.Chat.Messages, *message)
+	}
+
+	return chat
+}
+
+// convertMessage converts a visible node into a message, or returns nil
+func convertMessage(nodeID string, node *chatgptNode) *openwebui.Message {
+	m := node.Message
+	if m == nil {
+		return nil
+	}
+
+	role := m.Author.Role
+	if role != "user" && role != "assistant" && role != "system" {
+		return nil
+	}
+	if hidden, _ := m.Metadata["is_visually_hidden_from_conversation"].(bool); hidden {
+		return nil
+	}
+	// Assistant messages addressed to a tool (browsing, code interpreter) are intermediate steps
+	if m.Recipient != "" && m.Recipient != "all" {
+		return nil
+	}
+
+	content := strings.TrimSpace(contentText(&m.Content))
+	if content == "" {
+		return nil
+	}
+
+	id := m.ID
+	if id == "" {
+		id = nodeID
+	}
+
+	message := &openwebui.Message{
+		ID:      id,
+		Role:    role,
+		Content: content,
+	}
+	if m.CreateTime != nil {
+		message.Timestamp = int64(*m.CreateTime)
+	}
+	if slug, ok := m.Metadata["model_slug"].(string); ok && role == "assistant" {
+		message.Model = slug
+	}
+
+	return message
+}
+
+// contentText extracts displayable text from typed content
+func contentText(c *chatgptContent) string {
+	switch c.ContentType {
+	case "text", "multimodal_text":
+		var parts []string
+		for _, part := range c.Parts {
+			switch value := part.(type) {
+			case string:
+				if value != "" {
+					parts = append(parts, value)
+				}
+			case map[string]interface{}:
+				if text, ok := value["text"].(string); ok && text != "" {
+					parts = append(parts, text)
+				} else if strings.Contains(fmt.Sprint(value["content_type"]), "image") {
+					parts = append(parts, "[image]")
+				}
+			}
+		}
+		return strings.Join(parts, "\n")
+	case "code":
+		language := c.Language
+		if language == "unknown" {
+			language = ""
+		}
+		return "```" + language + "\n" + c.Text + "\n```"
+	}
+	return ""
+}
+
+// rootNodes returns the IDs of nodes without a parent in the mapping, sorted for stable output
+func rootNodes(mapping map[string]chatgptNode) []string {
+	var roots []string
+	for id, node := range mapping {
+		if node.Parent == nil {
+			roots = append(roots, id)
+			continue
+		}
+		if _, ok := mapping[*node.Parent]; !ok {
+			roots = append(roots, id)
+		}
+	}
+	sort.Strings(roots)
+	return roots
+}
@@ -0,0 +1,150 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/vosiander/open-webui-backup/pkg/archive"
+	"github.com/vosiander/open-webui-backup/pkg/config"
+	"github.com/vosiander/open-webui-backup/pkg/openwebui"
+)
+
+// Importer converts a third-party chat export into Open WebUI chats
+type Importer interface {
+	// Name is the format name used on the command line
+	Name() string
+	// Description is a short human-readable description of the format
+	Description() string
+	// ExportFile is the file to read when the input is a ZIP export
+	ExportFile() string
+	// Parse converts the export into chats with message trees
+	Parse(r io.Reader) ([]openwebui.Chat, error)
+}
+
+// importers holds the registered formats by name
+var importers = make(map[string]Importer)
+
+// Register makes an importer available by name
+func Register(imp Importer) {
+	importers[imp.Name()] = imp
+}
+
+// Get returns the importer for a format
+func Get(name string) (Importer, error) {
+	imp, ok := importers[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown import format %q (available: %s)", name, strings.Join(Names(), ", "))
+	}
+	return imp, nil
+}
+
+// Names returns the registered format names in alphabetical order
+func Names() []string {
+	names := make([]string, 0, len(importers))
+	for name := range importers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParseFile reads an export file, or the importer's file inside a ZIP export
+func ParseFile(imp Importer, inputPath string) ([]openwebui.Chat, error) {
+	if strings.EqualFold(path.Ext(inputPath), ".zip") {
+		return parseZip(imp, inputPath)
+	}
+
+	f, err := os.Open(inputPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", inputPath, err)
+	}
+	defer f.Close()
+
+	return imp.Parse(f)
+}
+
+// parseZip finds the importer's export file inside a ZIP archive
+func parseZip(imp Importer, zipPath string) ([]openwebui.Chat, error) {
+	r, err := zip.OpenReader(zipPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open ZIP file: %w", err)
+	}
+	defer r.Close()
+
+	for _, f := range r.File {
+		if path.Base(f.Name) != imp.ExportFile() {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("failed to open %s: %w", f.Name, err)
+		}
+		defer rc.Close()
+		return imp.Parse(rc)
+	}
+
+	return nil, fmt.Errorf("%s not found in %s", imp.ExportFile(), zipPath)
+}
+
+// WriteArchive writes chats as an unencrypted unified backup ZIP that can be restored later
+func WriteArchive(chats []openwebui.Chat, zipPath string) error {
+	out, err := os.Create(zipPath)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", zipPath, err)
+	}
+	defer out.Close()
+
+	w := zip.NewWriter(out)
+
+	metadata := &openwebui.BackupMetadata{
+		BackupToolVersion: config.BackupToolVersion,
+		BackupTimestamp:   time.Now().UTC().Format(time.RFC3339),
+		BackupType:        "all",
+		ItemCount:         len(chats),
+		UnifiedBackup:     true,
+		ContainedTypes:    []string{archive.TypeChat},
+	}
+	if err := writeJSON(w, "owui.json", metadata); err != nil {
+		return err
+	}
+
+	for i := range chats {
+		name, err := archive.EntityPath(archive.TypeChat, chats[i].ID)
+		if err != nil {
+			return err
+		}
+		if err := writeJSON(w, name, &chats[i]); err != nil {
+			return err
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to finalize ZIP file: %w", err)
+	}
+	return nil
+}
+
+// writeJSON adds an indented JSON file to the ZIP archive
+func writeJSON(w *zip.Writer, name string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", name, err)
+	}
+
+	f, err := w.Create(name)
+	if err != nil {
+		return fmt.Errorf("failed to create %s in zip: %w", name, err)
+	}
+	if _, err := io.Copy(f, bytes.NewReader(data)); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package metrics
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Default is the registry served on /metrics and pushed by the CLI
+var Default = NewRegistry()
+
+// Outcome label values
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+)
+
+var (
+	operationsTotal = Default.NewCounterVec("owui_operations_total",
+		"Operations run, by type and outcome", "operation", "outcome")
+	operationDuration = Default.NewHistogramVec("owui_operation_duration_seconds",
+		"Duration of operations", []float64{1, 5, 15, 30, 60, 120, 300, 600, 1800, 3600}, "operation")
+	lastSuccess = Default.NewGaugeVec("owui_last_success_timestamp_seconds",
+		"Unix time of the last successful operation, e.g. backup or verify", "operation")
+	bytesWritten = Default.NewCounterVec("owui_bytes_written_total",
+		"Bytes written to backup files", "operation")
+	itemsTotal = Default.NewCounterVec("owui_items_total",
+		"Items processed, by operation and data type", "operation", "type")
+	apiRequestDuration = Default.NewHistogramVec("owui_openwebui_request_duration_seconds",
+		"Latency of Open WebUI API requests", []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}, "method", "endpoint")
+	apiRequestsTotal = Default.NewCounterVec("owui_openwebui_requests_total",
+		"Open WebUI API requests, by status code (\"error\" for transport failures)", "method", "endpoint", "code")
+)
+
+// ObserveOperation records the outcome and duration of an operation that started at start
+func ObserveOperation(operation string, start time.Time, err error) {
+	outcome := OutcomeSuccess
+	if err != nil {
+		outcome = OutcomeFailure
+	}
+	operationsTotal.Inc(operation, outcome)
+	operationDuration.Observe(time.Since(start).Seconds(), operation)
+	if err == nil {
+		lastSuccess.Set(float64(time.Now().Unix()), operation)
+	}
+}
+
+// AddItems records items of a data type processed by an operation
+func AddItems(operation, dataType string, count int) {
+	itemsTotal.Add(float64(count), operation, dataType)
+}
+
+// AddBytesWritten records the size of a written file
+func AddBytesWritten(operation, path string) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return
+	}
+	bytesWritten.Add(float64(info.Size()), operation)
+}
+
+// ObserveAPIRequest records an Open WebUI API request; status 0 means the request failed before a response
+func ObserveAPIRequest(method, path string, status int, start time.Time) {
+	endpoint := Endpoint(path)
+	code := "error"
+	if status > 0 {
+		code = strconv.Itoa(status)
+	}
+	apiRequestDuration.Observe(time.Since(start).Seconds(), method, endpoint)
+	apiRequestsTotal.Inc(method, endpoint, code)
+}
+
+// Endpoint turns a request path into a low-cardinality route, replacing IDs with :id
+func Endpoint(path string) string {
+	if i := strings.IndexAny(path, "?#"); i >= 0 {
+		path = path[:i]
+	}
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		// Tool IDs and prompt commands are user-chosen names
+		if i > 0 && (segments[i-1] == "command" || segments[i-1] == "id") {
+			segments[i] = ":id"
+			continue
+		}
+		if isID(segment) {
+			segments[i] = ":id"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// isID reports whether a path segment is an ID rather than a route name (e.g. "v1")
+func isID(segment string) bool {
+	if segment == "" {
+		return false
+	}
+	if _, err := strconv.Atoi(segment); err == nil {
+		return true
+	}
+	return len(segment) >= 8 && strings.ContainsAny(segment, "0123456789")
+}
@@ -0,0 +1,44 @@
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Push sends the registry to a Pushgateway-compatible endpoint
+// Metrics are grouped by job and the given label pairs, so each command replaces only its own group
+func (r *Registry) Push(gatewayURL, job string, grouping ...string) error {
+	var body bytes.Buffer
+	if err := r.Write(&body); err != nil {
+		return fmt.Errorf("failed to render metrics: %w", err)
+	}
+
+	target := strings.TrimRight(gatewayURL, "/") + "/metrics/job/" + url.PathEscape(job)
+	for i := 0; i+1 < len(grouping); i += 2 {
+		target += "/" + url.PathEscape(grouping[i]) + "/" + url.PathEscape(grouping[i+1])
+	}
+
+	req, err := http.NewRequest(http.MethodPut, target, &body)
+	if err != nil {
+		return fmt.Errorf("failed to create push request: %w", err)
+	}
+	req.Header.Set("Content-Type", "text/plain; version=0.0.4")
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to push metrics: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("failed to push metrics: status %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
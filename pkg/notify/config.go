@@ -0,0 +1,162 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Sink types
+const (
+	TypeWebhook = "webhook"
+	TypeSlack   = "slack"
+	TypeTeams   = "teams"
+	TypeEmail   = "email"
+)
+
+// Config is the notification config file
+type Config struct {
+	Sinks []SinkConfig `json:"sinks"`
+}
+
+// SinkConfig configures one notification sink
+// String values may reference environment variables as ${NAME}, so secrets can stay out of the file
+type SinkConfig struct {
+	Name string `json:"name,omitempty"`
+	Type string `json:"type"` // webhook, slack, teams or email
+
+	// Filters; empty means every operation and both outcomes
+	Operations []string `json:"operations,omitempty"`
+	On         []string `json:"on,omitempty"` // success, failure
+
+	// Delivery
+	Retries *int   `json:"retries,omitempty"` // default 3
+	Backoff string `json:"backoff,omitempty"` // first retry delay, doubled each time, default 2s
+	Timeout string `json:"timeout,omitempty"` // per attempt, default 10s
+
+	// Message templates (Go text/template over the event)
+	Title    string `json:"title,omitempty"`
+	Template string `json:"template,omitempty"`
+
+	// webhook, slack, teams
+	URL     string            `json:"url,omitempty"`
+	Secret  string            `json:"secret,omitempty"` // webhook HMAC-SHA256 key
+	Headers map[string]string `json:"headers,omitempty"`
+
+	// email
+	Host     string   `json:"host,omitempty"`
+	Port     int      `json:"port,omitempty"`
+	Username string   `json:"username,omitempty"`
+	Password string   `json:"password,omitempty"`
+	From     string   `json:"from,omitempty"`
+	To       []string `json:"to,omitempty"`
+	TLS      string   `json:"tls,omitempty"` // starttls (default), tls or none
+}
+
+// LoadConfig reads a notification config file and expands environment variables
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read notification config: %w", err)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	var cfg Config
+	if err := decoder.Decode(&cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse notification config %s: %w", path, err)
+	}
+
+	for i := range cfg.Sinks {
+		cfg.Sinks[i].expandEnv()
+	}
+	return &cfg, nil
+}
+
+// expandEnv replaces ${NAME} references in connection settings
+func (c *SinkConfig) expandEnv() {
+	c.URL = os.ExpandEnv(c.URL)
+	c.Secret = os.ExpandEnv(c.Secret)
+	c.Host = os.ExpandEnv(c.Host)
+	c.Username = os.ExpandEnv(c.Username)
+	c.Password = os.ExpandEnv(c.Password)
+	for key, value := range c.Headers {
+		c.Headers[key] = os.ExpandEnv(value)
+	}
+}
+
+// Matches reports whether the sink wants the event
+func (c *SinkConfig) Matches(e *Event) bool {
+	return contains(c.Operations, e.Operation) && contains(c.On, e.Outcome)
+}
+
+// retries returns the number of retries after the first attempt
+func (c *SinkConfig) retries() int {
+	if c.Retries == nil {
+		return 3
+	}
+	return *c.Retries
+}
+
+// backoff returns the delay before the first retry
+func (c *SinkConfig) backoff() time.Duration {
+	return parseDuration(c.Backoff, 2*time.Second)
+}
+
+// timeout returns the per-attempt timeout
+func (c *SinkConfig) timeout() time.Duration {
+	return parseDuration(c.Timeout, 10*time.Second)
+}
+
+// newSink creates the sink for a config
+func newSink(c *SinkConfig) (Sink, error) {
+	for _, outcome := range c.On {
+		if outcome != OutcomeSuccess && outcome != OutcomeFailure {
+			return nil, fmt.Errorf("unknown outcome %q (use success or failure)", outcome)
+		}
+	}
+	if _, err := time.ParseDuration(c.Backoff); c.Backoff != "" && err != nil {
+		return nil, fmt.Errorf("invalid backoff: %w", err)
+	}
+	if _, err := time.ParseDuration(c.Timeout); c.Timeout != "" && err != nil {
+		return nil, fmt.Errorf("invalid timeout: %w", err)
+	}
+
+	switch c.Type {
+	case TypeWebhook, TypeSlack, TypeTeams:
+		if c.URL == "" {
+			return nil, fmt.Errorf("url is required")
+		}
+		return &webhookSink{config: c}, nil
+	case TypeEmail:
+		if c.Host == "" || c.From == "" || len(c.To) == 0 {
+			return nil, fmt.Errorf("host, from and to are required")
+		}
+		return &emailSink{config: c}, nil
+	default:
+		return nil, fmt.Errorf("unknown sink type %q (use webhook, slack, teams or email)", c.Type)
+	}
+}
+
+// contains reports whether value is in list; an empty list matches everything
+func contains(list []string, value string) bool {
+	if len(list) == 0 {
+		return true
+	}
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// parseDuration parses a duration, falling back to a default
+func parseDuration(value string, fallback time.Duration) time.Duration {
+	if d, err := time.ParseDuration(value); err == nil && d > 0 {
+		return d
+	}
+	return fallback
+}
@@ -0,0 +1,125 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// emailSink sends notifications through an SMTP server
+type emailSink struct {
+	config *SinkConfig
+}
+
+// Send delivers the message as a plain text email
+func (s *emailSink) Send(ctx context.Context, e *Event, msg *Message) error {
+	tlsMode := s.config.TLS
+	if tlsMode == "" {
+		tlsMode = "starttls"
+	}
+	port := s.config.Port
+	if port == 0 {
+		port = 587
+		if tlsMode == "tls" {
+			port = 465
+		}
+	}
+	addr := net.JoinHostPort(s.config.Host, strconv.Itoa(port))
+	tlsConfig := &tls.Config{ServerName: s.config.Host}
+
+	dialer := &net.Dialer{}
+	var conn net.Conn
+	var err error
+	if tlsMode == "tls" {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: tlsConfig}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to connect to %s: %w", addr, err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, s.config.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to start SMTP session: %w", err)
+	}
+	defer client.Close()
+
+	switch tlsMode {
+	case "starttls":
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return Permanent(fmt.Errorf("%s does not support STARTTLS (set tls to none for plain connections)", addr))
+		}
+		if err := client.StartTLS(tlsConfig); err != nil {
+			return fmt.Errorf("failed to start TLS: %w", err)
+		}
+	case "tls", "none":
+	default:
+		return Permanent(fmt.Errorf("unknown tls mode %q (use starttls, tls or none)", tlsMode))
+	}
+
+	if s.config.Username != "" {
+		// PlainAuth refuses unencrypted connections except to localhost
+		auth := smtp.PlainAuth("", s.config.Username, s.config.Password, s.config.Host)
+		if err := client.Auth(auth); err != nil {
+			return Permanent(fmt.Errorf("SMTP authentication failed: %w", err))
+		}
+	}
+
+	if err := client.Mail(s.config.From); err != nil {
+		return fmt.Errorf("MAIL FROM failed: %w", err)
+	}
+	for _, to := range s.config.To {
+		if err := client.Rcpt(to); err != nil {
+			return fmt.Errorf("RCPT TO %s failed: %w", to, err)
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("DATA failed: %w", err)
+	}
+	if _, err := w.Write(s.compose(e, msg)); err != nil {
+		return fmt.Errorf("failed to write message: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to send message: %w", err)
+	}
+	return client.Quit()
+}
+
+// compose builds the RFC 5322 message
+func (s *emailSink) compose(e *Event, msg *Message) []byte {
+	var b bytes.Buffer
+	header := func(key, value string) {
+		fmt.Fprintf(&b, "%s: %s\r\n", key, value)
+	}
+	header("From", s.config.From)
+	header("To", strings.Join(s.config.To, ", "))
+	header("Subject", mime.QEncoding.Encode("utf-8", msg.Title))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("Message-ID", fmt.Sprintf("<%s@owui-backup>", e.ID))
+	header("MIME-Version", "1.0")
+	header("Content-Type", "text/plain; charset=utf-8")
+	header("Content-Transfer-Encoding", "8bit")
+	header("X-OWUI-Event", e.Operation+"."+e.Outcome)
+	b.WriteString("\r\n")
+
+	for _, line := range strings.Split(msg.Text, "\n") {
+		// Dot-stuffing is done by the DATA writer; only normalize line endings
+		b.WriteString(strings.TrimRight(line, "\r"))
+		b.WriteString("\r\n")
+	}
+	return b.Bytes()
+}
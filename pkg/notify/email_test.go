@@ -0,0 +1,220 @@
+package notify
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// smtpStandIn is a minimal SMTP server that records one session
+type smtpStandIn struct {
+	listener net.Listener
+	rcptCode int // reply to RCPT TO, 250 if 0
+
+	mu       sync.Mutex
+	commands []string
+	data     string
+	sessions int
+}
+
+func newSMTPStandIn(t *testing.T) *smtpStandIn {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &smtpStandIn{listener: l}
+	t.Cleanup(func() { l.Close() })
+	go s.serve()
+	return s
+}
+
+func (s *smtpStandIn) port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (s *smtpStandIn) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.sessions++
+		s.mu.Unlock()
+		s.session(conn)
+	}
+}
+
+func (s *smtpStandIn) session(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { fmt.Fprintf(conn, "%s\r\n", line) }
+
+	reply("220 localhost ESMTP stand-in")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		s.mu.Lock()
+		s.commands = append(s.commands, line)
+		s.mu.Unlock()
+
+		verb := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		switch verb {
+		case "EHLO", "HELO":
+			reply("250-localhost")
+			reply("250 8BITMIME")
+		case "MAIL":
+			reply("250 OK")
+		case "RCPT":
+			if s.rcptCode != 0 {
+				reply(strconv.Itoa(s.rcptCode) + " mailbox unavailable")
+				continue
+			}
+			reply("250 OK")
+		case "DATA":
+			reply("354 end with <CRLF>.<CRLF>")
+			var data strings.Builder
+			for {
+				line, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if line == ".\r\n" {
+					break
+				}
+				data.WriteString(line)
+			}
+			s.mu.Lock()
+			s.data = data.String()
+			s.mu.Unlock()
+			reply("250 queued")
+		case "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("502 not implemented")
+		}
+	}
+}
+
+func TestEmailSink(t *testing.T) {
+	server := newSMTPStandIn(t)
+	n, err := New(&Config{Sinks: []SinkConfig{{
+		Type:  TypeEmail,
+		Host:  "127.0.0.1",
+		Port:  server.port(),
+		TLS:   "none",
+		From:  "backup@example.com",
+		To:    []string{"ops@example.com", "oncall@example.com"},
+		Title: "Backup {{.Outcome}} ✓",
+	}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := NewEvent("backup", "cli", time.Now().Add(-time.Minute), nil)
+	if err := n.Notify(e); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	commands := strings.Join(server.commands, "\n")
+	for _, want := range []string{"MAIL FROM:<backup@example.com>", "RCPT TO:<ops@example.com>", "RCPT TO:<oncall@example.com>", "QUIT"} {
+		if !strings.Contains(commands, want) {
+			t.Errorf("session has no %q:\n%s", want, commands)
+		}
+	}
+
+	headers, body, ok := strings.Cut(server.data, "\r\n\r\n")
+	if !ok {
+		t.Fatalf("message has no header separator:\n%s", server.data)
+	}
+	for _, want := range []string{
+		"From: backup@example.com",
+		"To: ops@example.com, oncall@example.com",
+		"Subject: =?utf-8?q?Backup_success_=E2=9C=93?=",
+		"Message-ID: <" + e.ID + "@owui-backup>",
+		"X-OWUI-Event: backup.success",
+	} {
+		if !strings.Contains(headers, want) {
+			t.Errorf("headers have no %q:\n%s", want, headers)
+		}
+	}
+	if body == "" || strings.Contains(strings.ReplaceAll(body, "\r\n", ""), "\n") {
+		t.Errorf("body is empty or has bare newlines: %q", body)
+	}
+}
+
+func TestEmailSinkRequiresSTARTTLS(t *testing.T) {
+	server := newSMTPStandIn(t)
+	retries := 2
+	n, err := New(&Config{Sinks: []SinkConfig{{
+		Type:    TypeEmail,
+		Host:    "127.0.0.1",
+		Port:    server.port(),
+		From:    "backup@example.com",
+		To:      []string{"ops@example.com"},
+		Retries: &retries,
+		Backoff: "1ms",
+	}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The stand-in offers no STARTTLS, which is a configuration error and not retried
+	if err := n.Notify(NewEvent("backup", "cli", time.Now(), nil)); err == nil || !strings.Contains(err.Error(), "STARTTLS") {
+		t.Fatalf("Notify error = %v, want a STARTTLS error", err)
+	}
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	if server.sessions != 1 {
+		t.Errorf("%d SMTP sessions, want 1", server.sessions)
+	}
+}
+
+func TestEmailSinkRetriesRejectedRecipient(t *testing.T) {
+	server := newSMTPStandIn(t)
+	server.rcptCode = 450
+	retries := 1
+	n, err := New(&Config{Sinks: []SinkConfig{{
+		Type:    TypeEmail,
+		Host:    "127.0.0.1",
+		Port:    server.port(),
+		TLS:     "none",
+		From:    "backup@example.com",
+		To:      []string{"ops@example.com"},
+		Retries: &retries,
+		Backoff: "1ms",
+	}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := n.Notify(NewEvent("backup", "cli", time.Now(), nil)); err == nil {
+		t.Fatal("Notify succeeded with a rejected recipient")
+	}
+	// Wait for the stand-in to count the second connection
+	deadline := time.Now().Add(time.Second)
+	for {
+		server.mu.Lock()
+		sessions := server.sessions
+		server.mu.Unlock()
+		if sessions == 2 || time.Now().After(deadline) {
+			if sessions != 2 {
+				t.Errorf("%d SMTP sessions, want 2", sessions)
+			}
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
@@ -0,0 +1,241 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// EnvNotifyConfig points to the notification config file
+const EnvNotifyConfig = "OWUI_NOTIFY_CONFIG"
+
+// Outcome values of an event
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+)
+
+// Operations are the commands that send notifications
+var Operations = []string{
+	"backup", "full-backup", "restore", "verify", "purge", "drill",
+	"backup-database", "restore-database", "purge-database", "erase-user", "base-backup",
+}
+
+// IsOperation reports whether a command sends notifications
+func IsOperation(name string) bool {
+	for _, op := range Operations {
+		if op == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Event describes a finished operation
+type Event struct {
+	ID         string            `json:"id"`
+	Operation  string            `json:"operation"`
+	Outcome    string            `json:"outcome"`
+	Error      string            `json:"error,omitempty"`
+	Source     string            `json:"source"` // "cli" or "server"
+	Host       string            `json:"host"`
+	File       string            `json:"file,omitempty"`
+	StartedAt  time.Time         `json:"started_at"`
+	FinishedAt time.Time         `json:"finished_at"`
+	Duration   float64           `json:"duration_seconds"`
+	Details    map[string]string `json:"details,omitempty"`
+	Test       bool              `json:"test,omitempty"`
+}
+
+// NewEvent creates an event for an operation that started at start and ended with err
+func NewEvent(operation, source string, start time.Time, err error) *Event {
+	host, _ := os.Hostname()
+	now := time.Now().UTC()
+	e := &Event{
+		ID:         uuid.New().String(),
+		Operation:  operation,
+		Outcome:    OutcomeSuccess,
+		Source:     source,
+		Host:       host,
+		StartedAt:  start.UTC(),
+		FinishedAt: now,
+		Duration:   now.Sub(start).Seconds(),
+	}
+	if err != nil {
+		e.Outcome = OutcomeFailure
+		e.Error = err.Error()
+	}
+	return e
+}
+
+// Failed reports whether the operation failed
+func (e *Event) Failed() bool {
+	return e.Outcome == OutcomeFailure
+}
+
+// Sink delivers a rendered notification
+type Sink interface {
+	Send(ctx context.Context, e *Event, msg *Message) error
+}
+
+// permanentError marks a delivery failure that retrying cannot fix
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *permanentError) Unwrap() error {
+	return e.err
+}
+
+// Permanent wraps an error so the delivery is not retried
+func Permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+// Notifier sends events to the configured sinks
+type Notifier struct {
+	sinks []*configuredSink
+}
+
+// configuredSink is a sink with its filters, templates and retry policy
+type configuredSink struct {
+	config   SinkConfig
+	sink     Sink
+	template *template
+}
+
+// New creates a notifier from a config
+func New(cfg *Config) (*Notifier, error) {
+	n := &Notifier{}
+	for i, sc := range cfg.Sinks {
+		if sc.Name == "" {
+			sc.Name = fmt.Sprintf("%s-%d", sc.Type, i+1)
+		}
+		sink, err := newSink(&sc)
+		if err != nil {
+			return nil, fmt.Errorf("invalid sink %s: %w", sc.Name, err)
+		}
+		tmpl, err := newTemplate(&sc)
+		if err != nil {
+			return nil, fmt.Errorf("invalid template in sink %s: %w", sc.Name, err)
+		}
+		n.sinks = append(n.sinks, &configuredSink{config: sc, sink: sink, template: tmpl})
+	}
+	return n, nil
+}
+
+// Load creates a notifier from a config file; an empty path returns nil
+func Load(path string) (*Notifier, error) {
+	if path == "" {
+		return nil, nil
+	}
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	return New(cfg)
+}
+
+// FromEnv creates a notifier from OWUI_NOTIFY_CONFIG; returns nil if it is not set
+func FromEnv() (*Notifier, error) {
+	return Load(os.Getenv(EnvNotifyConfig))
+}
+
+// SinkNames returns the names of the configured sinks
+func (n *Notifier) SinkNames() []string {
+	var names []string
+	for _, s := range n.sinks {
+		names = append(names, s.config.Name)
+	}
+	return names
+}
+
+// Notify sends an event to every matching sink and returns the combined delivery errors
+// A nil notifier does nothing
+func (n *Notifier) Notify(e *Event) error {
+	if n == nil {
+		return nil
+	}
+	var errs []error
+	for _, s := range n.sinks {
+		if !e.Test && !s.config.Matches(e) {
+			continue
+		}
+		if err := n.deliver(s, e); err != nil {
+			logrus.Warnf("Notification to %s failed: %v", s.config.Name, err)
+			errs = append(errs, fmt.Errorf("%s: %w", s.config.Name, err))
+			continue
+		}
+		logrus.Debugf("Notification %s sent to %s", e.ID, s.config.Name)
+	}
+	return errors.Join(errs...)
+}
+
+// Only restricts the notifier to the named sinks
+func (n *Notifier) Only(names []string) (*Notifier, error) {
+	if len(names) == 0 {
+		return n, nil
+	}
+	filtered := &Notifier{}
+	for _, name := range names {
+		found := false
+		for _, s := range n.sinks {
+			if s.config.Name == name {
+				filtered.sinks = append(filtered.sinks, s)
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown sink %q (available: %s)", name, strings.Join(n.SinkNames(), ", "))
+		}
+	}
+	return filtered, nil
+}
+
+// deliver renders the message and sends it with retries
+func (n *Notifier) deliver(s *configuredSink, e *Event) error {
+	msg, err := s.template.render(e)
+	if err != nil {
+		return fmt.Errorf("failed to render message: %w", err)
+	}
+
+	attempts := s.config.retries() + 1
+	backoff := s.config.backoff()
+	for attempt := 1; ; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), s.config.timeout())
+		err = s.sink.Send(ctx, e, msg)
+		cancel()
+		if err == nil {
+			return nil
+		}
+
+		var permanent *permanentError
+		if errors.As(err, &permanent) || attempt >= attempts {
+			return err
+		}
+		logrus.Debugf("Notification to %s failed (attempt %d/%d), retrying in %s: %v", s.config.Name, attempt, attempts, backoff, err)
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// marshalEvent encodes an event for webhook payloads
+func marshalEvent(e *Event, msg *Message) ([]byte, error) {
+	payload := struct {
+		*Event
+		Title string `json:"title"`
+		Text  string `json:"text"`
+	}{e, msg.Title, msg.Text}
+	return json.Marshal(payload)
+}
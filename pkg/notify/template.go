@@ -0,0 +1,73 @@
+package notify
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	texttemplate "text/template"
+	"time"
+)
+
+// Message is a rendered notification
+type Message struct {
+	Title string
+	Text  string
+}
+
+// Default templates, used when a sink does not set its own
+const (
+	DefaultTitle    = `[owui] {{.Operation}} {{if .Failed}}failed{{else}}succeeded{{end}}{{if .Host}} on {{.Host}}{{end}}`
+	DefaultTemplate = `{{.Operation}} {{if .Failed}}failed after {{duration .Duration}}: {{.Error}}{{else}}completed in {{duration .Duration}}{{end}}
+{{- if .File}}
+File: {{.File}}{{end}}
+{{- range $key, $value := .Details}}
+{{$key}}: {{$value}}{{end}}
+Finished: {{.FinishedAt.Format "2006-01-02 15:04:05 MST"}} ({{.Source}})`
+)
+
+// template holds the parsed title and body templates of a sink
+type template struct {
+	title *texttemplate.Template
+	text  *texttemplate.Template
+}
+
+// funcs are available in templates
+var funcs = texttemplate.FuncMap{
+	"duration": func(seconds float64) string {
+		return (time.Duration(seconds * float64(time.Second))).Round(time.Second).String()
+	},
+	"upper": strings.ToUpper,
+}
+
+// newTemplate parses the templates of a sink
+func newTemplate(c *SinkConfig) (*template, error) {
+	title, text := c.Title, c.Template
+	if title == "" {
+		title = DefaultTitle
+	}
+	if text == "" {
+		text = DefaultTemplate
+	}
+
+	t := &template{}
+	var err error
+	if t.title, err = texttemplate.New("title").Funcs(funcs).Parse(title); err != nil {
+		return nil, fmt.Errorf("title: %w", err)
+	}
+	if t.text, err = texttemplate.New("template").Funcs(funcs).Parse(text); err != nil {
+		return nil, fmt.Errorf("template: %w", err)
+	}
+	return t, nil
+}
+
+// render executes the templates for an event
+func (t *template) render(e *Event) (*Message, error) {
+	var title, text bytes.Buffer
+	if err := t.title.Execute(&title, e); err != nil {
+		return nil, err
+	}
+	if err := t.text.Execute(&text, e); err != nil {
+		return nil, err
+	}
+	return &Message{Title: strings.TrimSpace(title.String()), Text: strings.TrimSpace(text.String())}, nil
+}
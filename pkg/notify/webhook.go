@@ -0,0 +1,109 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Webhook headers
+const (
+	HeaderSignature = "X-OWUI-Signature"
+	HeaderTimestamp = "X-OWUI-Timestamp"
+	HeaderEvent     = "X-OWUI-Event"
+	HeaderDelivery  = "X-OWUI-Delivery"
+)
+
+// webhookSink posts to a generic JSON webhook or a Slack/Teams incoming webhook
+type webhookSink struct {
+	config *SinkConfig
+}
+
+// Send posts the payload for the sink type
+func (s *webhookSink) Send(ctx context.Context, e *Event, msg *Message) error {
+	body, err := s.payload(e, msg)
+	if err != nil {
+		return Permanent(fmt.Errorf("failed to encode payload: %w", err))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.config.URL, bytes.NewReader(body))
+	if err != nil {
+		return Permanent(fmt.Errorf("failed to create request: %w", err))
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "owui-backup")
+	for key, value := range s.config.Headers {
+		req.Header.Set(key, value)
+	}
+
+	if s.config.Type == TypeWebhook {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set(HeaderEvent, e.Operation+"."+e.Outcome)
+		req.Header.Set(HeaderDelivery, e.ID)
+		req.Header.Set(HeaderTimestamp, timestamp)
+		if s.config.Secret != "" {
+			req.Header.Set(HeaderSignature, "sha256="+Sign(s.config.Secret, timestamp, body))
+		}
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 == 2 {
+		return nil
+	}
+	detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	err = fmt.Errorf("webhook returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(detail)))
+	// Client errors other than rate limiting will not go away on retry
+	if resp.StatusCode/100 == 4 && resp.StatusCode != http.StatusTooManyRequests {
+		return Permanent(err)
+	}
+	return err
+}
+
+// payload builds the request body for the sink type
+func (s *webhookSink) payload(e *Event, msg *Message) ([]byte, error) {
+	switch s.config.Type {
+	case TypeSlack:
+		return json.Marshal(map[string]string{
+			"text": "*" + msg.Title + "*\n" + msg.Text,
+		})
+	case TypeTeams:
+		color := "2EB67D"
+		if e.Failed() {
+			color = "E01E5A"
+		}
+		return json.Marshal(map[string]string{
+			"@type":      "MessageCard",
+			"@context":   "https://schema.org/extensions",
+			"summary":    msg.Title,
+			"title":      msg.Title,
+			"themeColor": color,
+			// Teams renders the text as Markdown, where single newlines are ignored
+			"text": strings.ReplaceAll(msg.Text, "\n", "\n\n"),
+		})
+	default:
+		return marshalEvent(e, msg)
+	}
+}
+
+// Sign returns the hex HMAC-SHA256 of "<timestamp>.<body>", as sent in X-OWUI-Signature
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
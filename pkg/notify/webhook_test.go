@@ -0,0 +1,166 @@
+package notify
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testNotifier creates a notifier with one sink and a short backoff
+func testNotifier(t *testing.T, sink SinkConfig) *Notifier {
+	t.Helper()
+	retries := 2
+	sink.Retries = &retries
+	sink.Backoff = "1ms"
+	n, err := New(&Config{Sinks: []SinkConfig{sink}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func testEvent() *Event {
+	return NewEvent("backup", "cli", time.Now().Add(-time.Minute), nil)
+}
+
+func TestWebhookSignature(t *testing.T) {
+	const secret = "s3cret"
+	var got struct {
+		signature, timestamp, event, delivery string
+		body                                  []byte
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got.signature = r.Header.Get(HeaderSignature)
+		got.timestamp = r.Header.Get(HeaderTimestamp)
+		got.event = r.Header.Get(HeaderEvent)
+		got.delivery = r.Header.Get(HeaderDelivery)
+		got.body, _ = io.ReadAll(r.Body)
+	}))
+	defer server.Close()
+
+	e := testEvent()
+	n := testNotifier(t, SinkConfig{Type: TypeWebhook, URL: server.URL, Secret: secret})
+	if err := n.Notify(e); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+
+	if want := "sha256=" + Sign(secret, got.timestamp, got.body); got.signature != want {
+		t.Errorf("%s = %q, want %q", HeaderSignature, got.signature, want)
+	}
+	if got.timestamp == "" {
+		t.Errorf("%s is missing", HeaderTimestamp)
+	}
+	if got.event != "backup.success" || got.delivery != e.ID {
+		t.Errorf("event headers = %q, %q", got.event, got.delivery)
+	}
+
+	var payload map[string]interface{}
+	if err := json.Unmarshal(got.body, &payload); err != nil {
+		t.Fatalf("payload is not JSON: %v", err)
+	}
+	if payload["id"] != e.ID || payload["operation"] != "backup" || payload["title"] == "" {
+		t.Errorf("payload = %v", payload)
+	}
+}
+
+func TestSignIsHMACOfTimestampAndBody(t *testing.T) {
+	// echo -n '1700000000.{}' | openssl dgst -sha256 -hmac key
+	if got, want := Sign("key", "1700000000", []byte("{}")), "9d713ed406bb7076d4123f0dc2c39d2df5c654ed4b0cd56b52c8b4c940bd63ae"; got != want {
+		t.Errorf("Sign = %s, want %s", got, want)
+	}
+	if Sign("key", "1", []byte("{}")) == Sign("key", "2", []byte("{}")) {
+		t.Error("signature does not depend on the timestamp")
+	}
+	if Sign("key", "1", []byte("{}")) == Sign("other", "1", []byte("{}")) {
+		t.Error("signature does not depend on the secret")
+	}
+}
+
+func TestWebhookRetries(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []int // responses in order; the last one repeats
+		attempts int32
+		wantErr  bool
+	}{
+		{"server error is retried", []int{http.StatusBadGateway, http.StatusOK}, 2, false},
+		{"server errors exhaust retries", []int{http.StatusInternalServerError}, 3, true},
+		{"rate limit is retried", []int{http.StatusTooManyRequests, http.StatusNoContent}, 2, false},
+		{"client error is not retried", []int{http.StatusBadRequest, http.StatusOK}, 1, true},
+		{"unauthorized is not retried", []int{http.StatusUnauthorized, http.StatusOK}, 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				i := int(atomic.AddInt32(&attempts, 1)) - 1
+				if i >= len(tt.statuses) {
+					i = len(tt.statuses) - 1
+				}
+				w.WriteHeader(tt.statuses[i])
+			}))
+			defer server.Close()
+
+			err := testNotifier(t, SinkConfig{Type: TypeWebhook, URL: server.URL}).Notify(testEvent())
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Notify error = %v, want error %v", err, tt.wantErr)
+			}
+			if got := atomic.LoadInt32(&attempts); got != tt.attempts {
+				t.Errorf("%d attempts, want %d", got, tt.attempts)
+			}
+		})
+	}
+}
+
+func TestSlackAndTeamsPayloads(t *testing.T) {
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(HeaderSignature) != "" || r.Header.Get(HeaderEvent) != "" {
+			t.Error("Slack and Teams requests carry webhook headers")
+		}
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+	}))
+	defer server.Close()
+
+	n, err := New(&Config{Sinks: []SinkConfig{
+		{Type: TypeSlack, URL: server.URL},
+		{Type: TypeTeams, URL: server.URL},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := n.Notify(NewEvent("verify", "cli", time.Now(), io.ErrUnexpectedEOF)); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+	if len(bodies) != 2 {
+		t.Fatalf("%d requests, want 2", len(bodies))
+	}
+	if !strings.HasPrefix(bodies[0], `{"text":"*`) {
+		t.Errorf("Slack payload = %s", bodies[0])
+	}
+	if !strings.Contains(bodies[1], `"@type":"MessageCard"`) || !strings.Contains(bodies[1], `"themeColor":"E01E5A"`) {
+		t.Errorf("Teams payload = %s", bodies[1])
+	}
+}
+
+func TestSinkFilters(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+	}))
+	defer server.Close()
+
+	n := testNotifier(t, SinkConfig{Type: TypeWebhook, URL: server.URL, Operations: []string{"backup"}, On: []string{OutcomeFailure}})
+	n.Notify(testEvent())                                    // success: filtered
+	n.Notify(NewEvent("restore", "cli", time.Now(), io.EOF)) // other operation: filtered
+	n.Notify(NewEvent("backup", "cli", time.Now(), io.EOF))
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Errorf("%d deliveries, want 1", got)
+	}
+}
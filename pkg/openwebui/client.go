@@ -2,12 +2,17 @@ package openwebui
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"mime/multipart"
 	"net/http"
+	"net/url"
 	"strings"
+	"time"
+
+	"github.com/vosiander/open-webui-backup/pkg/metrics"
 )
 
 // Client represents an HTTP client for the Open WebUI API
@@ -139,7 +144,7 @@ func (c *Client) UploadFile(filename string, content []byte) (*FileUploadRespons
 	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.apiKey))
 	req.Header.Set("Content-Type", writer.FormDataContentType())
 
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute request: %w", err)
 	}
@@ -774,6 +779,26 @@ func (c *Client) DeleteChatByID(id string) error {
 	return nil
 }
 
+// DeleteFileByID deletes a specific file by ID
+func (c *Client) DeleteFileByID(id string) error {
+	path := fmt.Sprintf("/api/v1/files/%s", id)
+	resp, err := c.doRequest("DELETE", path, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return &APIError{
+			StatusCode: resp.StatusCode,
+			Message:    string(body),
+		}
+	}
+
+	return nil
+}
+
 // DeleteAllFiles deletes all files
 func (c *Client) DeleteAllFiles() error {
 	resp, err := c.doRequest("DELETE", "/api/v1/files/all", nil)
@@ -812,6 +837,26 @@ func (c *Client) DeleteAllModels() error {
 	return nil
 }
 
+// DeleteModelByID deletes a specific custom model by ID
+func (c *Client) DeleteModelByID(id string) error {
+	path := "/api/v1/models/model/delete?id=" + url.QueryEscape(id)
+	resp, err := c.doRequest("DELETE", path, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return &APIError{
+			StatusCode: resp.StatusCode,
+			Message:    string(body),
+		}
+	}
+
+	return nil
+}
+
 // DeleteKnowledgeByID deletes a specific knowledge base by ID
 func (c *Client) DeleteKnowledgeByID(id string) error {
 	path := fmt.Sprintf("/api/v1/knowledge/%s/delete", id)
@@ -1072,6 +1117,30 @@ func (c *Client) GetAllUsers() ([]User, error) {
 	return allUsers, nil
 }
 
+// GetCurrentUser fetches the user the API key belongs to from /api/v1/auths/
+func (c *Client) GetCurrentUser() (*User, error) {
+	resp, err := c.doRequest("GET", "/api/v1/auths/", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, &APIError{
+			StatusCode: resp.StatusCode,
+			Message:    string(body),
+		}
+	}
+
+	var user User
+	if err := json.NewDecoder(resp.Body).Decode(&user); err != nil {
+		return nil, fmt.Errorf("failed to decode current user response: %w", err)
+	}
+
+	return &user, nil
+}
+
 // ImportUser creates a new user via /api/v1/auths/add
 func (c *Client) ImportUser(userForm *UserForm) error {
 	jsonData, err := json.Marshal(userForm)
@@ -1336,6 +1405,47 @@ func (c *Client) DeleteFeedbackByID(id string) error {
 	return nil
 }
 
+// versionTimeout bounds the version lookup, which only annotates backups and must not stall them
+const versionTimeout = 10 * time.Second
+
+// GetVersion fetches the Open WebUI version, e.g. 0.6.5
+func (c *Client) GetVersion() (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), versionTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+"/api/version", nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.apiKey))
+
+	resp, err := c.do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return "", &APIError{
+			StatusCode: resp.StatusCode,
+			Message:    string(body),
+		}
+	}
+
+	var version struct {
+		Version string `json:"version"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&version); err != nil {
+		return "", fmt.Errorf("failed to decode version response: %w", err)
+	}
+	if version.Version == "" {
+		return "", fmt.Errorf("version response has no version")
+	}
+
+	return version.Version, nil
+}
+
 // doRequest makes an authenticated HTTP request to the API
 func (c *Client) doRequest(method, path string, body io.Reader) (*http.Response, error) {
 	url := c.baseURL + path
@@ -1349,10 +1459,22 @@ func (c *Client) doRequest(method, path string, body io.Reader) (*http.Response,
 	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.apiKey))
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute request: %w", err)
 	}
 
 	return resp, nil
 }
+
+// do executes a request and records its latency and status code
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := c.httpClient.Do(req)
+	status := 0
+	if err == nil {
+		status = resp.StatusCode
+	}
+	metrics.ObserveAPIRequest(req.Method, req.URL.Path, status, start)
+	return resp, err
+}
@@ -22,6 +22,7 @@ type KnowledgeData struct {
 // FileMetadata represents file metadata from the API
 type FileMetadata struct {
 	ID        string   `json:"id"`
+	UserID    string   `json:"user_id,omitempty"`
 	Meta      FileMeta `json:"meta"`
 	CreatedAt int64    `json:"created_at"`
 	UpdatedAt int64    `json:"updated_at"`
@@ -198,6 +199,7 @@ type Chat struct {
 	Title     string                 `json:"title"`
 	Chat      ChatMessages           `json:"chat"` // Array of messages
 	Meta      map[string]interface{} `json:"meta,omitempty"`
+	FolderID  *string                `json:"folder_id,omitempty"`
 	CreatedAt int64                  `json:"created_at"`
 	UpdatedAt int64                  `json:"updated_at"`
 }
@@ -213,6 +215,7 @@ type ChatTitleID struct {
 
 // ChatMessages represents the messages array in a chat
 type ChatMessages struct {
+	Models   []string  `json:"models,omitempty"`
 	Messages []Message `json:"messages"`
 }
 
@@ -335,12 +338,22 @@ type FeedbackForm struct {
 
 // BackupMetadata contains information about the backup
 type BackupMetadata struct {
-	OpenWebUIURL      string   `json:"open_webui_url"`
-	OpenWebUIVersion  string   `json:"open_webui_version,omitempty"`
-	BackupToolVersion string   `json:"backup_tool_version"`
-	BackupTimestamp   string   `json:"backup_timestamp"`
-	BackupType        string   `json:"backup_type"` // "knowledge", "model", "tool", "prompt", "file", "chat", "all"
-	ItemCount         int      `json:"item_count"`
-	UnifiedBackup     bool     `json:"unified_backup"`            // true for backup-all
-	ContainedTypes    []string `json:"contained_types,omitempty"` // ["knowledge", "model", "tool", "prompt", "file", "chat", "user"]
+	OpenWebUIURL      string         `json:"open_webui_url"`
+	OpenWebUIVersion  string         `json:"open_webui_version,omitempty"`
+	BackupToolVersion string         `json:"backup_tool_version"`
+	BackupTimestamp   string         `json:"backup_timestamp"`
+	BackupType        string         `json:"backup_type"` // "knowledge", "model", "tool", "prompt", "file", "chat", "all"
+	ItemCount         int            `json:"item_count"`
+	UnifiedBackup     bool           `json:"unified_backup"`            // true for backup-all
+	ContainedTypes    []string       `json:"contained_types,omitempty"` // ["knowledge", "model", "tool", "prompt", "file", "chat", "user"]
+	Redaction         *RedactionInfo `json:"redaction,omitempty"`
+	Erasures          []string       `json:"erasures,omitempty"` // IDs of erasure requests applied to this backup
+}
+
+// RedactionInfo records the redaction profile applied to a backup
+type RedactionInfo struct {
+	Profile    string   `json:"profile"`
+	RedactedAt string   `json:"redacted_at"`
+	Rules      []string `json:"rules"`
+	Keyed      bool     `json:"keyed"` // pseudonyms are derived from a profile salt and stable across backups
 }
@@ -0,0 +1,46 @@
+package redact
+
+import (
+	"regexp"
+	"sort"
+)
+
+// Pattern is a named regular expression for a kind of sensitive data
+type Pattern struct {
+	Regexp      *regexp.Regexp
+	Replacement string
+}
+
+// Patterns are the built-in masks selectable by name
+var Patterns = map[string]Pattern{
+	"email": {
+		Regexp:      regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`),
+		Replacement: "[EMAIL]",
+	},
+	"phone": {
+		Regexp:      regexp.MustCompile(`(?:\+\d{1,3}[\s.\-]?)?(?:\(\d{2,4}\)[\s.\-]?)?\d{3,4}[\s.\-]\d{3,4}(?:[\s.\-]\d{2,4})?`),
+		Replacement: "[PHONE]",
+	},
+	"ip": {
+		Regexp:      regexp.MustCompile(`\b(?:(?:25[0-5]|2[0-4]\d|1?\d?\d)\.){3}(?:25[0-5]|2[0-4]\d|1?\d?\d)\b`),
+		Replacement: "[IP]",
+	},
+	"card": {
+		Regexp:      regexp.MustCompile(`\b(?:\d[ \-]?){12,18}\d\b`),
+		Replacement: "[CARD]",
+	},
+	"secret": {
+		Regexp:      regexp.MustCompile(`\b(?:sk-[A-Za-z0-9_\-]{16,}|gh[pousr]_[A-Za-z0-9]{20,}|AKIA[0-9A-Z]{16}|xox[abpr]-[A-Za-z0-9\-]{10,}|eyJ[A-Za-z0-9_\-]+\.[A-Za-z0-9_\-]+\.[A-Za-z0-9_\-]+)\b`),
+		Replacement: "[SECRET]",
+	},
+}
+
+// PatternNames returns the built-in pattern names in alphabetical order
+func PatternNames() []string {
+	names := make([]string, 0, len(Patterns))
+	for name := range Patterns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,173 @@
+package redact
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Profile configures which redaction rules are applied
+type Profile struct {
+	Name              string     `json:"name"`
+	Salt              string     `json:"salt,omitempty"` // keys hashes and pseudonyms; random per run if empty
+	HashEmails        bool       `json:"hash_emails"`
+	PseudonymizeNames bool       `json:"pseudonymize_names"`
+	StripAPIKeys      bool       `json:"strip_api_keys"`
+	StripFields       []string   `json:"strip_fields,omitempty"` // further fields emptied wherever they appear
+	Masks             []MaskRule `json:"masks,omitempty"`
+	MaskFields        []string   `json:"mask_fields,omitempty"` // fields whose text is masked, e.g. message content
+	DropAttachments   bool       `json:"drop_attachments"`
+	DropDatabase      bool       `json:"drop_database"`
+}
+
+// MaskRule replaces matches in text fields
+// Either Pattern names a built-in pattern or Regex gives a custom expression
+type MaskRule struct {
+	Pattern     string `json:"pattern,omitempty"`
+	Regex       string `json:"regex,omitempty"`
+	Replacement string `json:"replacement,omitempty"`
+}
+
+// defaultMaskFields are the text fields masked when a profile does not list any
+var defaultMaskFields = []string{"content", "title", "description", "bio", "comment", "reason"}
+
+// profiles are the built-in profiles selectable by name
+var profiles = map[string]*Profile{
+	"standard": {
+		Name:              "standard",
+		HashEmails:        true,
+		PseudonymizeNames: true,
+		StripAPIKeys:      true,
+		StripFields:       []string{"oauth_sub", "profile_image_url"},
+		Masks: []MaskRule{
+			{Pattern: "secret"},
+			{Pattern: "card"},
+			{Pattern: "phone"},
+			{Pattern: "ip"},
+		},
+		DropAttachments: true,
+		DropDatabase:    true,
+	},
+	"keys-only": {
+		Name:         "keys-only",
+		StripAPIKeys: true,
+		Masks:        []MaskRule{{Pattern: "secret"}},
+		DropDatabase: true,
+	},
+}
+
+// LoadProfile returns a built-in profile by name or reads a profile JSON file
+func LoadProfile(nameOrPath string) (*Profile, error) {
+	if p, ok := profiles[nameOrPath]; ok {
+		copied := *p
+		return &copied, nil
+	}
+
+	data, err := os.ReadFile(nameOrPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("unknown redaction profile %q (built-in: %s, or a JSON file)", nameOrPath, strings.Join(ProfileNames(), ", "))
+		}
+		return nil, fmt.Errorf("failed to read redaction profile: %w", err)
+	}
+
+	var p Profile
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("failed to parse redaction profile %s: %w", nameOrPath, err)
+	}
+	if p.Name == "" {
+		p.Name = strings.TrimSuffix(filepath.Base(nameOrPath), filepath.Ext(nameOrPath))
+	}
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// ProfileNames returns the built-in profile names in alphabetical order
+func ProfileNames() []string {
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Validate checks that every mask rule compiles
+func (p *Profile) Validate() error {
+	for _, rule := range p.Masks {
+		if _, _, err := rule.compile(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Rules describes the enabled rules, as recorded in owui.json
+func (p *Profile) Rules() []string {
+	var rules []string
+	if p.HashEmails {
+		rules = append(rules, "hash_emails")
+	}
+	if p.PseudonymizeNames {
+		rules = append(rules, "pseudonymize_names")
+	}
+	if p.StripAPIKeys {
+		rules = append(rules, "strip_api_keys")
+	}
+	for _, field := range p.StripFields {
+		rules = append(rules, "strip:"+field)
+	}
+	for _, rule := range p.Masks {
+		if rule.Pattern != "" {
+			rules = append(rules, "mask:"+rule.Pattern)
+		} else {
+			rules = append(rules, "mask:/"+rule.Regex+"/")
+		}
+	}
+	if p.DropAttachments {
+		rules = append(rules, "drop_attachments")
+	}
+	if p.DropDatabase {
+		rules = append(rules, "drop_database")
+	}
+	return rules
+}
+
+// maskFields returns the configured text fields or the defaults
+func (p *Profile) maskFields() []string {
+	if len(p.MaskFields) > 0 {
+		return p.MaskFields
+	}
+	return defaultMaskFields
+}
+
+// compile returns the expression and replacement of a mask rule
+func (r *MaskRule) compile() (*regexp.Regexp, string, error) {
+	if r.Regex != "" {
+		re, err := regexp.Compile(r.Regex)
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid mask regex %q: %w", r.Regex, err)
+		}
+		replacement := r.Replacement
+		if replacement == "" {
+			replacement = "[REDACTED]"
+		}
+		return re, replacement, nil
+	}
+
+	pattern, ok := Patterns[r.Pattern]
+	if !ok {
+		return nil, "", fmt.Errorf("unknown mask pattern %q (available: %s)", r.Pattern, strings.Join(PatternNames(), ", "))
+	}
+	replacement := r.Replacement
+	if replacement == "" {
+		replacement = pattern.Replacement
+	}
+	return pattern.Regexp, replacement, nil
+}
@@ -0,0 +1,322 @@
+package redact
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/vosiander/open-webui-backup/pkg/archive"
+	"github.com/vosiander/open-webui-backup/pkg/openwebui"
+)
+
+// Stats counts the values changed by a Redactor
+type Stats struct {
+	Emails      int `json:"emails"`
+	Names       int `json:"names"`
+	Keys        int `json:"keys"`
+	Fields      int `json:"fields"`
+	Masked      int `json:"masked"`
+	Attachments int `json:"attachments"`
+	Database    int `json:"database"`
+}
+
+// Redactor applies a profile to backup entities
+// Hashes and pseudonyms are keyed by the profile salt, so the same email or
+// name is replaced by the same value in every entity
+type Redactor struct {
+	profile     *Profile
+	key         []byte
+	masks       []mask
+	maskFields  map[string]bool
+	stripFields map[string]bool
+	names       map[string]string // lowercased real name -> pseudonym
+	namePattern *regexp.Regexp
+	Stats       Stats
+}
+
+// mask is a compiled mask rule
+type mask struct {
+	re          *regexp.Regexp
+	replacement string
+}
+
+// userFields are name fields pseudonymized inside user objects
+var userFields = map[string]bool{"name": true, "username": true}
+
+// New creates a redactor for a profile
+func New(profile *Profile) (*Redactor, error) {
+	r := &Redactor{
+		profile:     profile,
+		maskFields:  make(map[string]bool),
+		stripFields: make(map[string]bool),
+		names:       make(map[string]string),
+	}
+
+	if profile.Salt != "" {
+		r.key = []byte(profile.Salt)
+	} else {
+		r.key = make([]byte, 32)
+		if _, err := rand.Read(r.key); err != nil {
+			return nil, fmt.Errorf("failed to generate redaction key: %w", err)
+		}
+	}
+
+	for _, rule := range profile.Masks {
+		re, replacement, err := rule.compile()
+		if err != nil {
+			return nil, err
+		}
+		r.masks = append(r.masks, mask{re: re, replacement: replacement})
+	}
+	for _, field := range profile.maskFields() {
+		r.maskFields[field] = true
+	}
+	for _, field := range profile.StripFields {
+		r.stripFields[field] = true
+	}
+
+	return r, nil
+}
+
+// Learn collects user names from an archive so they are also replaced in text
+func (r *Redactor) Learn(a *archive.Archive) {
+	if !r.profile.PseudonymizeNames {
+		return
+	}
+
+	for _, entities := range a.Entities {
+		for _, entity := range entities {
+			var value interface{}
+			if err := json.Unmarshal(entity.Data, &value); err != nil {
+				continue
+			}
+			r.learn(value, entity.Type == archive.TypeUser)
+		}
+	}
+
+	var names []string
+	for name := range r.names {
+		if len([]rune(name)) >= 3 {
+			names = append(names, regexp.QuoteMeta(name))
+		}
+	}
+	if len(names) == 0 {
+		return
+	}
+	// Longest first so full names win over parts of them
+	sort.Slice(names, func(i, j int) bool { return len(names[i]) > len(names[j]) })
+	r.namePattern = regexp.MustCompile(`(?i)\b(?:` + strings.Join(names, "|") + `)\b`)
+}
+
+// learn walks a JSON value and records names found in user objects
+func (r *Redactor) learn(value interface{}, inUser bool) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			if s, ok := child.(string); ok && inUser && userFields[key] && strings.TrimSpace(s) != "" {
+				r.pseudonym(s)
+				continue
+			}
+			r.learn(child, key == "user")
+		}
+	case []interface{}:
+		for _, child := range v {
+			r.learn(child, inUser)
+		}
+	}
+}
+
+// RedactEntity returns the redacted JSON of an entity
+func (r *Redactor) RedactEntity(e *archive.Entity) ([]byte, error) {
+	// Numbers are kept as written so large timestamps do not lose precision
+	dec := json.NewDecoder(bytes.NewReader(e.Data))
+	dec.UseNumber()
+	var value interface{}
+	if err := dec.Decode(&value); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", e.Path, err)
+	}
+
+	value = r.walk(value, e.Type == archive.TypeUser, false)
+
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal %s: %w", e.Path, err)
+	}
+	return data, nil
+}
+
+// Text redacts free text with the mask rules, email hashing and known names
+func (r *Redactor) Text(text string) string {
+	result := text
+	if r.profile.HashEmails {
+		result = Patterns["email"].Regexp.ReplaceAllStringFunc(result, r.HashEmail)
+	}
+	for _, m := range r.masks {
+		result = m.re.ReplaceAllString(result, m.replacement)
+	}
+	if r.namePattern != nil {
+		result = r.namePattern.ReplaceAllStringFunc(result, r.pseudonym)
+	}
+	if result != text {
+		r.Stats.Masked++
+	}
+	return result
+}
+
+// HashEmail replaces an email with a stable, non-reversible address
+func (r *Redactor) HashEmail(email string) string {
+	if email == "" {
+		return email
+	}
+	r.Stats.Emails++
+	return "user-" + r.digest("email", strings.ToLower(email))[:12] + "@redacted.invalid"
+}
+
+// Info describes the applied profile for owui.json
+func (r *Redactor) Info() *openwebui.RedactionInfo {
+	return &openwebui.RedactionInfo{
+		Profile:    r.profile.Name,
+		RedactedAt: time.Now().UTC().Format(time.RFC3339),
+		Rules:      r.profile.Rules(),
+		Keyed:      r.profile.Salt != "",
+	}
+}
+
+// KeepAttachment reports whether attachments are kept by the profile
+func (r *Redactor) KeepAttachment(e *archive.Entity, name string) bool {
+	if r.profile.DropAttachments {
+		r.Stats.Attachments++
+		return false
+	}
+	return true
+}
+
+// KeepOther reports whether an entry outside the entity directories is kept
+// Database dumps and vector stores cannot be redacted field by field, so they are dropped if the profile says so;
+// data directory uploads are attachment originals and follow drop_attachments
+func (r *Redactor) KeepOther(name string) bool {
+	if r.profile.DropDatabase && (strings.HasPrefix(name, "database/") || strings.HasPrefix(name, "vector_db/")) {
+		r.Stats.Database++
+		return false
+	}
+	if r.profile.DropAttachments && strings.HasPrefix(name, "uploads/") {
+		r.Stats.Attachments++
+		return false
+	}
+	return true
+}
+
+// walk redacts a JSON value; inUser marks user objects, inText marks text fields
+func (r *Redactor) walk(value interface{}, inUser, inText bool) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			v[key] = r.field(key, child, inUser, inText)
+		}
+		return v
+	case []interface{}:
+		for i, child := range v {
+			v[i] = r.walk(child, inUser, inText)
+		}
+		return v
+	case string:
+		if inText {
+			return r.Text(v)
+		}
+	}
+	return value
+}
+
+// field redacts the value of a single object field
+func (r *Redactor) field(key string, value interface{}, inUser, inText bool) interface{} {
+	s, isString := value.(string)
+
+	if isString && s != "" {
+		switch {
+		case r.profile.StripAPIKeys && isAPIKeyField(key):
+			r.Stats.Keys++
+			return ""
+		case r.stripFields[key]:
+			r.Stats.Fields++
+			return ""
+		case r.profile.HashEmails && key == "email":
+			return r.HashEmail(s)
+		case r.profile.PseudonymizeNames && inUser && userFields[key]:
+			r.Stats.Names++
+			pseudonym := r.pseudonym(s)
+			if key == "username" {
+				return strings.ToLower(strings.ReplaceAll(pseudonym, " ", "_"))
+			}
+			return pseudonym
+		}
+	}
+
+	return r.walk(value, key == "user", inText || r.maskFields[key])
+}
+
+// pseudonym returns the stable replacement for a name
+func (r *Redactor) pseudonym(name string) string {
+	lower := strings.ToLower(strings.TrimSpace(name))
+	if p, ok := r.names[lower]; ok {
+		return p
+	}
+	p := "User " + r.digest("name", lower)[:8]
+	r.names[lower] = p
+	return p
+}
+
+// digest returns the keyed hash of a value
+func (r *Redactor) digest(kind, value string) string {
+	mac := hmac.New(sha256.New, r.key)
+	mac.Write([]byte(kind + ":" + value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// isAPIKeyField reports whether a field name holds an API key or token
+func isAPIKeyField(key string) bool {
+	lower := strings.ToLower(key)
+	if strings.HasSuffix(lower, "api_key") || strings.HasSuffix(lower, "apikey") || strings.HasSuffix(lower, "api_keys") {
+		return true
+	}
+	switch lower {
+	case "token", "access_token", "refresh_token", "secret", "client_secret", "password":
+		return true
+	}
+	return false
+}
+
+// Archive redacts a unified backup ZIP into dstPath and records the profile in owui.json
+func Archive(srcPath, dstPath string, profile *Profile) (*Stats, error) {
+	r, err := New(profile)
+	if err != nil {
+		return nil, err
+	}
+
+	a, err := archive.Load(srcPath)
+	if err != nil {
+		return nil, err
+	}
+	r.Learn(a)
+
+	_, err = archive.Rewrite(srcPath, dstPath, &archive.RewriteOptions{
+		Entity:     r.RedactEntity,
+		Attachment: r.KeepAttachment,
+		Other:      r.KeepOther,
+		Metadata: func(metadata *openwebui.BackupMetadata) error {
+			metadata.Redaction = r.Info()
+			return nil
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &r.Stats, nil
+}
@@ -0,0 +1,224 @@
+package restore
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/vosiander/open-webui-backup/pkg/archive"
+	"github.com/vosiander/open-webui-backup/pkg/openwebui"
+)
+
+// Plan actions
+const (
+	ActionCreate   = "create"
+	ActionUpdate   = "update"
+	ActionSkip     = "skip"
+	ActionConflict = "conflict"
+)
+
+// PlanItem describes what a restore would do with a single archived entity
+type PlanItem struct {
+	Type   string                `json:"type"`
+	Key    string                `json:"key"`
+	Name   string                `json:"name"`
+	Action string                `json:"action"`
+	Reason string                `json:"reason,omitempty"`
+	Fields []archive.FieldChange `json:"fields,omitempty"`
+}
+
+// PlanSummary counts plan actions
+type PlanSummary struct {
+	Create   int `json:"create"`
+	Update   int `json:"update"`
+	Skip     int `json:"skip"`
+	Conflict int `json:"conflict"`
+}
+
+// Plan is a dry-run of a restore against the live instance
+type Plan struct {
+	Backup    string      `json:"backup"`
+	TargetURL string      `json:"targetUrl"`
+	Overwrite bool        `json:"overwrite"`
+	CreatedAt string      `json:"createdAt"`
+	Summary   PlanSummary `json:"summary"`
+	Items     []PlanItem  `json:"items"`
+}
+
+// BuildPlan compares a backup with the live instance and classifies every selected entity
+// Nothing is written to the instance
+func BuildPlan(client *openwebui.Client, inputFile string, options *SelectiveRestoreOptions, overwrite bool) (*Plan, error) {
+	a, err := archive.Load(inputFile)
+	if err != nil {
+		return nil, err
+	}
+	if a.Metadata == nil || !a.Metadata.UnifiedBackup {
+		return nil, fmt.Errorf("backup file is not a unified backup")
+	}
+
+	options = options.withItemTypes()
+
+	plan := &Plan{
+		Backup:    a.Path,
+		TargetURL: client.GetBaseURL(),
+		Overwrite: overwrite,
+		CreatedAt: time.Now().UTC().Format(time.RFC3339),
+	}
+
+	for _, entityType := range archive.Types {
+		if !options.includesType(entityType) || len(a.Entities[entityType]) == 0 {
+			continue
+		}
+
+		live, err := archive.FetchLiveEntities(client, entityType)
+		if err != nil {
+			if isAuthError(err) {
+				return nil, fmt.Errorf("authentication failed - please check your API key: %w", err)
+			}
+			return nil, fmt.Errorf("failed to fetch live %s: %w", entityType, err)
+		}
+
+		liveByKey := make(map[string]*archive.Entity, len(live))
+		for _, entity := range live {
+			liveByKey[planKey(entity)] = entity
+		}
+
+		for _, entity := range a.Entities[entityType] {
+			if !options.includesEntity(entity) {
+				continue
+			}
+			item := classify(entity, liveByKey[planKey(entity)], overwrite)
+			plan.Items = append(plan.Items, item)
+			plan.Summary.add(item.Action)
+		}
+	}
+
+	return plan, nil
+}
+
+// includesType reports whether the options select the given entity type
+func (o *SelectiveRestoreOptions) includesType(entityType string) bool {
+	switch entityType {
+	case archive.TypeKnowledge:
+		return o.Knowledge
+	case archive.TypeModel:
+		return o.Models
+	case archive.TypeTool:
+		return o.Tools
+	case archive.TypePrompt:
+		return o.Prompts
+	case archive.TypeFile:
+		return o.Files
+	case archive.TypeChat:
+		return o.Chats
+	case archive.TypeUser:
+		return o.Users
+	case archive.TypeGroup:
+		return o.Groups
+	case archive.TypeFeedback:
+		return o.Feedbacks
+	}
+	return false
+}
+
+// classify decides the plan action for an archived entity and its live counterpart
+func classify(backup, live *archive.Entity, overwrite bool) PlanItem {
+	item := PlanItem{
+		Type: backup.Type,
+		Key:  planKey(backup),
+		Name: backup.Name,
+	}
+
+	if live == nil {
+		item.Action = ActionCreate
+		return item
+	}
+
+	backupFields, _ := backup.Fields()
+	liveFields, _ := live.Fields()
+
+	// Field changes are expressed as live → backup, i.e. what the restore would apply
+	item.Fields = archive.CompareFields(backup.Type, liveFields, backupFields)
+	if len(item.Fields) == 0 {
+		item.Action = ActionSkip
+		item.Reason = "unchanged"
+		return item
+	}
+
+	if live.UpdatedAt > backup.UpdatedAt {
+		item.Action = ActionConflict
+		item.Reason = "live copy was modified after the backup was taken"
+		return item
+	}
+
+	if !overwrite {
+		item.Action = ActionSkip
+		item.Reason = "exists and differs (use --overwrite to update)"
+		return item
+	}
+
+	item.Action = ActionUpdate
+	return item
+}
+
+// planKey returns the key the restore uses to find an existing entity
+// Knowledge bases are matched by name, prompts by command and users by email
+func planKey(e *archive.Entity) string {
+	switch e.Type {
+	case archive.TypeKnowledge:
+		return e.Name
+	case archive.TypeUser:
+		fields, err := e.Fields()
+		if err != nil {
+			return e.ID
+		}
+		email, _ := fields["email"].(string)
+		return email
+	}
+	return e.ID
+}
+
+// add counts an action in the summary
+func (s *PlanSummary) add(action string) {
+	switch action {
+	case ActionCreate:
+		s.Create++
+	case ActionUpdate:
+		s.Update++
+	case ActionSkip:
+		s.Skip++
+	case ActionConflict:
+		s.Conflict++
+	}
+}
+
+// HasConflicts reports whether any entity is in conflict
+func (p *Plan) HasConflicts() bool {
+	return p.Summary.Conflict > 0
+}
+
+// Changes returns the items that are not skipped, in restore order
+func (p *Plan) Changes() []PlanItem {
+	var changes []PlanItem
+	for _, item := range p.Items {
+		if item.Action != ActionSkip {
+			changes = append(changes, item)
+		}
+	}
+	return changes
+}
+
+// WritePlan saves the plan as JSON for approval workflows
+func WritePlan(plan *Plan, path string) error {
+	data, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal plan: %w", err)
+	}
+
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write plan: %w", err)
+	}
+
+	return nil
+}
@@ -41,6 +41,9 @@ type SelectiveRestoreOptions struct {
 	Users     bool
 	Groups    bool
 	Feedbacks bool
+
+	// Items restricts the restore to matching entities; types referenced here are enabled automatically
+	Items []*ItemSelector
 }
 
 // generateRandomPassword creates a cryptographically secure random password
@@ -1054,6 +1057,9 @@ func RestoreChat(client *openwebui.Client, zipPath string, overwrite bool) error
 		return fmt.Errorf("chat with ID %s already exists (use --overwrite to replace)", chat.ID)
 	}
 
+	// Folders are not part of backups, so restored chats are placed at the top level
+	chat.FolderID = nil
+
 	// Import the chat
 	logrus.Infof("Importing chat: %s", chat.Title)
 	if err := client.ImportChat(chat); err != nil {
@@ -1201,24 +1207,50 @@ func extractFileFromZip(zipPath string) (*openwebui.FileExport, error) {
 	return fileExport, nil
 }
 
+// Summary reports the types a selective restore processed and those that failed
+type Summary struct {
+	Restored []string          `json:"restored"`
+	Missing  []string          `json:"missing,omitempty"`
+	Failed   map[string]string `json:"failed,omitempty"`
+}
+
 // RestoreSelective performs a selective restore from a unified backup file based on the provided options
 // progressCallback is an optional callback function for progress updates (can be nil)
 func RestoreSelective(client *openwebui.Client, inputFile string, options *SelectiveRestoreOptions, overwrite bool, progressCallback ProgressCallback) error {
+	_, err := RestoreSelectiveWithSummary(client, inputFile, options, overwrite, progressCallback)
+	return err
+}
+
+// RestoreSelectiveWithSummary performs a selective restore and reports which types failed
+// Failed types are logged and listed in the summary; the restore still succeeds
+func RestoreSelectiveWithSummary(client *openwebui.Client, inputFile string, options *SelectiveRestoreOptions, overwrite bool, progressCallback ProgressCallback) (*Summary, error) {
 	logrus.Info("Starting selective restore...")
 
 	if progressCallback != nil {
 		progressCallback(0, "Starting selective restore...")
 	}
 
+	options = options.withItemTypes()
+
 	// Validate that at least one option is enabled
 	if !options.Knowledge && !options.Models && !options.Tools && !options.Prompts && !options.Files && !options.Chats && !options.Users && !options.Groups && !options.Feedbacks {
-		return fmt.Errorf("at least one data type must be selected for restore")
+		return nil, fmt.Errorf("at least one data type must be selected for restore")
+	}
+
+	// Restore from a copy that only holds the selected items
+	if options.hasItems() {
+		filtered, cleanup, err := filterItems(inputFile, options)
+		if err != nil {
+			return nil, err
+		}
+		defer cleanup()
+		inputFile = filtered
 	}
 
 	// Open the backup file
 	r, err := zip.OpenReader(inputFile)
 	if err != nil {
-		return fmt.Errorf("failed to open backup file: %w", err)
+		return nil, fmt.Errorf("failed to open backup file: %w", err)
 	}
 	defer r.Close()
 
@@ -1228,29 +1260,30 @@ func RestoreSelective(client *openwebui.Client, inputFile string, options *Selec
 		if f.Name == "owui.json" {
 			rc, err := f.Open()
 			if err != nil {
-				return fmt.Errorf("failed to read metadata: %w", err)
+				return nil, fmt.Errorf("failed to read metadata: %w", err)
 			}
 			data, err := io.ReadAll(rc)
 			rc.Close()
 			if err != nil {
-				return fmt.Errorf("failed to read metadata: %w", err)
+				return nil, fmt.Errorf("failed to read metadata: %w", err)
 			}
 			metadata = &openwebui.BackupMetadata{}
 			if err := json.Unmarshal(data, metadata); err != nil {
-				return fmt.Errorf("failed to parse metadata: %w", err)
+				return nil, fmt.Errorf("failed to parse metadata: %w", err)
 			}
 			break
 		}
 	}
 
 	if metadata == nil {
-		return fmt.Errorf("metadata not found in backup file (not a unified backup)")
+		return nil, fmt.Errorf("metadata not found in backup file (not a unified backup)")
 	}
 
 	if !metadata.UnifiedBackup {
-		return fmt.Errorf("backup file is not a unified backup")
+		return nil, fmt.Errorf("backup file is not a unified backup")
 	}
 
+	summary := &Summary{Failed: map[string]string{}}
 	logrus.Infof("Restoring from unified backup with %d items", metadata.ItemCount)
 	logrus.Infof("Available types: %v", metadata.ContainedTypes)
 
@@ -1263,9 +1296,13 @@ func RestoreSelective(client *openwebui.Client, inputFile string, options *Selec
 			logrus.Info("Restoring users...")
 			if err := restoreUsersFromUnified(r, client, overwrite); err != nil {
 				logrus.Warnf("Failed to restore some users: %v", err)
+				summary.Failed["user"] = err.Error()
+			} else {
+				summary.Restored = append(summary.Restored, "user")
 			}
 		} else {
 			logrus.Info("Users not present in backup, skipping")
+			summary.Missing = append(summary.Missing, "user")
 		}
 	}
 
@@ -1277,9 +1314,13 @@ func RestoreSelective(client *openwebui.Client, inputFile string, options *Selec
 			logrus.Info("Restoring knowledge bases...")
 			if err := restoreKnowledgeBasesFromUnified(r, client, overwrite); err != nil {
 				logrus.Warnf("Failed to restore some knowledge bases: %v", err)
+				summary.Failed["knowledge"] = err.Error()
+			} else {
+				summary.Restored = append(summary.Restored, "knowledge")
 			}
 		} else {
 			logrus.Info("Knowledge bases not present in backup, skipping")
+			summary.Missing = append(summary.Missing, "knowledge")
 		}
 	}
 
@@ -1291,9 +1332,13 @@ func RestoreSelective(client *openwebui.Client, inputFile string, options *Selec
 			logrus.Info("Restoring models...")
 			if err := restoreModelsFromUnified(r, client, overwrite); err != nil {
 				logrus.Warnf("Failed to restore some models: %v", err)
+				summary.Failed["model"] = err.Error()
+			} else {
+				summary.Restored = append(summary.Restored, "model")
 			}
 		} else {
 			logrus.Info("Models not present in backup, skipping")
+			summary.Missing = append(summary.Missing, "model")
 		}
 	}
 
@@ -1305,9 +1350,13 @@ func RestoreSelective(client *openwebui.Client, inputFile string, options *Selec
 			logrus.Info("Restoring tools...")
 			if err := restoreToolsFromUnified(r, client, overwrite); err != nil {
 				logrus.Warnf("Failed to restore some tools: %v", err)
+				summary.Failed["tool"] = err.Error()
+			} else {
+				summary.Restored = append(summary.Restored, "tool")
 			}
 		} else {
 			logrus.Info("Tools not present in backup, skipping")
+			summary.Missing = append(summary.Missing, "tool")
 		}
 	}
 
@@ -1319,9 +1368,13 @@ func RestoreSelective(client *openwebui.Client, inputFile string, options *Selec
 			logrus.Info("Restoring prompts...")
 			if err := restorePromptsFromUnified(r, client, overwrite); err != nil {
 				logrus.Warnf("Failed to restore some prompts: %v", err)
+				summary.Failed["prompt"] = err.Error()
+			} else {
+				summary.Restored = append(summary.Restored, "prompt")
 			}
 		} else {
 			logrus.Info("Prompts not present in backup, skipping")
+			summary.Missing = append(summary.Missing, "prompt")
 		}
 	}
 
@@ -1333,9 +1386,13 @@ func RestoreSelective(client *openwebui.Client, inputFile string, options *Selec
 			logrus.Info("Restoring files...")
 			if err := restoreFilesFromUnified(r, client, overwrite); err != nil {
 				logrus.Warnf("Failed to restore some files: %v", err)
+				summary.Failed["file"] = err.Error()
+			} else {
+				summary.Restored = append(summary.Restored, "file")
 			}
 		} else {
 			logrus.Info("Files not present in backup, skipping")
+			summary.Missing = append(summary.Missing, "file")
 		}
 	}
 
@@ -1347,9 +1404,13 @@ func RestoreSelective(client *openwebui.Client, inputFile string, options *Selec
 			logrus.Info("Restoring chats...")
 			if err := restoreChatsFromUnified(r, client, overwrite); err != nil {
 				logrus.Warnf("Failed to restore some chats: %v", err)
+				summary.Failed["chat"] = err.Error()
+			} else {
+				summary.Restored = append(summary.Restored, "chat")
 			}
 		} else {
 			logrus.Info("Chats not present in backup, skipping")
+			summary.Missing = append(summary.Missing, "chat")
 		}
 	}
 
@@ -1361,9 +1422,13 @@ func RestoreSelective(client *openwebui.Client, inputFile string, options *Selec
 			logrus.Info("Restoring groups...")
 			if err := restoreGroupsFromUnified(r, client, overwrite); err != nil {
 				logrus.Warnf("Failed to restore some groups: %v", err)
+				summary.Failed["group"] = err.Error()
+			} else {
+				summary.Restored = append(summary.Restored, "group")
 			}
 		} else {
 			logrus.Info("Groups not present in backup, skipping")
+			summary.Missing = append(summary.Missing, "group")
 		}
 	}
 
@@ -1375,9 +1440,13 @@ func RestoreSelective(client *openwebui.Client, inputFile string, options *Selec
 			logrus.Info("Restoring feedbacks...")
 			if err := restoreFeedbacksFromUnified(r, client, overwrite); err != nil {
 				logrus.Warnf("Failed to restore some feedbacks: %v", err)
+				summary.Failed["feedback"] = err.Error()
+			} else {
+				summary.Restored = append(summary.Restored, "feedback")
 			}
 		} else {
 			logrus.Info("Feedbacks not present in backup, skipping")
+			summary.Missing = append(summary.Missing, "feedback")
 		}
 	}
 
@@ -1385,7 +1454,7 @@ func RestoreSelective(client *openwebui.Client, inputFile string, options *Selec
 		progressCallback(100, "Restore completed successfully")
 	}
 	logrus.Info("Selective restore completed successfully")
-	return nil
+	return summary, nil
 }
 
 // contains checks if a string slice contains a specific string
@@ -1945,6 +2014,9 @@ func restoreChatsFromUnified(r *zip.ReadCloser, client *openwebui.Client, overwr
 					continue
 				}
 
+				// Folders are not part of backups, so restored chats are placed at the top level
+				chat.FolderID = nil
+
 				logrus.Infof("  Restoring chat: %s", chat.Title)
 				if err := client.ImportChat(&chat); err != nil {
 					if isAuthError(err) {
@@ -0,0 +1,221 @@
+package restore
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/vosiander/open-webui-backup/pkg/archive"
+)
+
+// ItemSelector restricts a restore to matching entities of one type
+//
+// Selectors have the form "type:pattern". The pattern is matched against the
+// entity ID, name, title, prompt command, user email and file name. Patterns
+// starting with "~" are regular expressions, patterns starting with "=" are
+// matched literally, everything else is a glob (see path.Match).
+type ItemSelector struct {
+	Type    string `json:"type"`
+	Pattern string `json:"pattern"`
+	regex   *regexp.Regexp
+}
+
+// ParseItemSelector parses a "type:pattern" selector
+func ParseItemSelector(value string) (*ItemSelector, error) {
+	typeName, pattern, ok := strings.Cut(value, ":")
+	if !ok || pattern == "" {
+		return nil, fmt.Errorf("invalid selector %q (expected type:pattern)", value)
+	}
+
+	entityType := normalizeType(typeName)
+	if entityType == "" {
+		return nil, fmt.Errorf("invalid selector %q: unknown type %q", value, typeName)
+	}
+
+	selector := &ItemSelector{Type: entityType, Pattern: pattern}
+
+	switch {
+	case strings.HasPrefix(pattern, "~"):
+		regex, err := regexp.Compile(pattern[1:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid selector %q: %w", value, err)
+		}
+		selector.regex = regex
+	case strings.HasPrefix(pattern, "="):
+	default:
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid selector %q: %w", value, err)
+		}
+	}
+
+	return selector, nil
+}
+
+// ParseItemSelectors parses a list of "type:pattern" selectors
+func ParseItemSelectors(values []string) ([]*ItemSelector, error) {
+	var selectors []*ItemSelector
+	for _, value := range values {
+		selector, err := ParseItemSelector(strings.TrimSpace(value))
+		if err != nil {
+			return nil, err
+		}
+		selectors = append(selectors, selector)
+	}
+	return selectors, nil
+}
+
+// String returns the selector in its "type:pattern" form
+func (s *ItemSelector) String() string {
+	return s.Type + ":" + s.Pattern
+}
+
+// Matches reports whether the selector matches the given archived entity
+func (s *ItemSelector) Matches(e *archive.Entity) bool {
+	if e.Type != s.Type {
+		return false
+	}
+
+	for _, candidate := range selectorCandidates(e) {
+		if s.matchString(candidate) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchString matches the pattern against a single value
+func (s *ItemSelector) matchString(value string) bool {
+	switch {
+	case s.regex != nil:
+		return s.regex.MatchString(value)
+	case strings.HasPrefix(s.Pattern, "="):
+		return value == s.Pattern[1:]
+	default:
+		matched, _ := path.Match(s.Pattern, value)
+		return matched
+	}
+}
+
+// selectorCandidates returns the values a selector can match for an entity
+func selectorCandidates(e *archive.Entity) []string {
+	candidates := []string{e.ID, e.Name}
+
+	fields, err := e.Fields()
+	if err != nil {
+		return candidates
+	}
+	for _, field := range []string{"title", "command", "email", "filename"} {
+		if value, ok := fields[field].(string); ok && value != "" {
+			candidates = append(candidates, value)
+		}
+	}
+	if meta, ok := fields["meta"].(map[string]interface{}); ok {
+		if value, ok := meta["name"].(string); ok && value != "" {
+			candidates = append(candidates, value)
+		}
+	}
+
+	return candidates
+}
+
+// normalizeType maps selector type names such as "prompts" or "knowledge-bases" to entity types
+func normalizeType(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	switch name {
+	case "knowledge-base", "knowledge-bases", "kb":
+		return archive.TypeKnowledge
+	}
+	if archive.IsType(name) {
+		return name
+	}
+	if trimmed := strings.TrimSuffix(name, "s"); archive.IsType(trimmed) {
+		return trimmed
+	}
+	return ""
+}
+
+// hasItems reports whether item selectors are set
+func (o *SelectiveRestoreOptions) hasItems() bool {
+	return len(o.Items) > 0
+}
+
+// withItemTypes returns a copy of the options with every type referenced by an item selector enabled
+func (o *SelectiveRestoreOptions) withItemTypes() *SelectiveRestoreOptions {
+	effective := *o
+	for _, selector := range o.Items {
+		switch selector.Type {
+		case archive.TypeKnowledge:
+			effective.Knowledge = true
+		case archive.TypeModel:
+			effective.Models = true
+		case archive.TypeTool:
+			effective.Tools = true
+		case archive.TypePrompt:
+			effective.Prompts = true
+		case archive.TypeFile:
+			effective.Files = true
+		case archive.TypeChat:
+			effective.Chats = true
+		case archive.TypeUser:
+			effective.Users = true
+		case archive.TypeGroup:
+			effective.Groups = true
+		case archive.TypeFeedback:
+			effective.Feedbacks = true
+		}
+	}
+	return &effective
+}
+
+// includesEntity reports whether an archived entity is selected for restore
+// Types without item selectors are restored whole when their type flag is set
+func (o *SelectiveRestoreOptions) includesEntity(e *archive.Entity) bool {
+	if !o.includesType(e.Type) {
+		return false
+	}
+
+	selected := true
+	for _, selector := range o.Items {
+		if selector.Type != e.Type {
+			continue
+		}
+		if selector.Matches(e) {
+			return true
+		}
+		selected = false
+	}
+	return selected
+}
+
+// filterItems writes a copy of the backup containing only the selected entities
+// The caller must call cleanup when done with the returned file
+func filterItems(inputFile string, options *SelectiveRestoreOptions) (string, func(), error) {
+	tmp, err := os.CreateTemp("", "owui-restore-items-*.zip")
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create temp file: %w", err)
+	}
+	tmp.Close()
+	cleanup := func() { os.Remove(tmp.Name()) }
+
+	selected, err := archive.Filter(inputFile, tmp.Name(), options.includesEntity)
+	if err != nil {
+		cleanup()
+		return "", nil, err
+	}
+
+	if len(selected) == 0 {
+		cleanup()
+		return "", nil, fmt.Errorf("no items in %s match the given selectors", filepath.Base(inputFile))
+	}
+
+	logrus.Infof("Selected %d item(s) for restore", len(selected))
+	for _, e := range selected {
+		logrus.Debugf("Selected %s %q", e.Type, e.Name)
+	}
+
+	return tmp.Name(), cleanup, nil
+}
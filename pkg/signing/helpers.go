@@ -0,0 +1,93 @@
+package signing
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Environment variable constants
+const (
+	EnvSigningKey       = "OWUI_SIGNING_KEY"
+	EnvTrustedKeys      = "OWUI_TRUSTED_KEYS"
+	EnvRequireSignature = "OWUI_REQUIRE_SIGNATURE"
+)
+
+// GetSigningKeyFromEnvOrFlag loads the signing key from the flag or OWUI_SIGNING_KEY
+// Returns nil without error when signing is not configured
+func GetSigningKeyFromEnvOrFlag(flagPath string) (*PrivateKey, error) {
+	path := flagPath
+	if path == "" {
+		path = os.Getenv(EnvSigningKey)
+	}
+	if path == "" {
+		return nil, nil
+	}
+
+	// Allow the key itself to be passed through the environment
+	if strings.HasPrefix(strings.TrimSpace(path), PrivateKeyPrefix) {
+		return ParsePrivateKey(path)
+	}
+
+	return LoadPrivateKeyFile(path)
+}
+
+// GetTrustedKeysFromEnvOrFlag loads trusted public keys from the flag or OWUI_TRUSTED_KEYS
+// Supports both file paths and direct key strings, comma-separated in the environment
+func GetTrustedKeysFromEnvOrFlag(flagKeys []string) ([]*PublicKey, error) {
+	inputs := flagKeys
+	if len(inputs) == 0 {
+		if env := os.Getenv(EnvTrustedKeys); env != "" {
+			inputs = strings.Split(env, ",")
+		}
+	}
+	return LoadTrustedKeys(inputs)
+}
+
+// IsSignatureRequired returns true if the flag or OWUI_REQUIRE_SIGNATURE demands a valid signature
+func IsSignatureRequired(flagValue bool) bool {
+	if flagValue {
+		return true
+	}
+	val := os.Getenv(EnvRequireSignature)
+	return val == "true" || val == "1" || val == "yes"
+}
+
+// EnsureSigningKeyFiles loads signing.key from dir or generates a new signing.key/signing.pub pair
+// Returns the key and whether it was newly generated
+func EnsureSigningKeyFiles(dir string) (*PrivateKey, bool, error) {
+	keyPath := filepath.Join(dir, "signing.key")
+	pubPath := filepath.Join(dir, "signing.pub")
+
+	if _, err := os.Stat(keyPath); err == nil {
+		key, err := LoadPrivateKeyFile(keyPath)
+		return key, false, err
+	}
+
+	key, err := GenerateKey()
+	if err != nil {
+		return nil, false, err
+	}
+
+	if err := WriteKeyFiles(key, keyPath, pubPath); err != nil {
+		return nil, false, err
+	}
+
+	return key, true, nil
+}
+
+// WriteKeyFiles saves a signing key (0600) and its public key (0644)
+func WriteKeyFiles(key *PrivateKey, keyPath, pubPath string) error {
+	if err := os.WriteFile(keyPath, []byte(key.String()+"\n"), 0600); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(keyPath), err)
+	}
+
+	if err := os.WriteFile(pubPath, []byte(key.Public().String()+"\n"), 0644); err != nil {
+		// Clean up private key if public key save fails
+		os.Remove(keyPath)
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(pubPath), err)
+	}
+
+	return nil
+}
@@ -0,0 +1,177 @@
+package signing
+
+import (
+	"bufio"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// PrivateKeyPrefix marks an encoded Ed25519 signing key (seed)
+	PrivateKeyPrefix = "OWUI-SIGNING-KEY-"
+	// PublicKeyPrefix marks an encoded Ed25519 verification key
+	PublicKeyPrefix = "owuisig1"
+)
+
+// PrivateKey is an Ed25519 key used to sign backup manifests
+type PrivateKey struct {
+	key ed25519.PrivateKey
+}
+
+// PublicKey is an Ed25519 key used to verify backup manifests
+type PublicKey struct {
+	key ed25519.PublicKey
+}
+
+// GenerateKey generates a new Ed25519 signing key
+func GenerateKey() (*PrivateKey, error) {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate Ed25519 key: %w", err)
+	}
+	logrus.Debug("Generated new Ed25519 signing key")
+	return &PrivateKey{key: priv}, nil
+}
+
+// String encodes the private key (seed) for storage
+func (k *PrivateKey) String() string {
+	return PrivateKeyPrefix + base64.RawStdEncoding.EncodeToString(k.key.Seed())
+}
+
+// Public returns the matching public key
+func (k *PrivateKey) Public() *PublicKey {
+	return &PublicKey{key: k.key.Public().(ed25519.PublicKey)}
+}
+
+// Sign signs the given message
+func (k *PrivateKey) Sign(message []byte) []byte {
+	return ed25519.Sign(k.key, message)
+}
+
+// String encodes the public key for storage and sharing
+func (k *PublicKey) String() string {
+	return PublicKeyPrefix + base64.RawStdEncoding.EncodeToString(k.key)
+}
+
+// KeyID returns a short fingerprint identifying the public key
+func (k *PublicKey) KeyID() string {
+	sum := sha256.Sum256(k.key)
+	return hex.EncodeToString(sum[:8])
+}
+
+// Verify checks a signature over the given message
+func (k *PublicKey) Verify(message, signature []byte) bool {
+	return ed25519.Verify(k.key, message, signature)
+}
+
+// ParsePrivateKey decodes a private key created by PrivateKey.String
+func ParsePrivateKey(s string) (*PrivateKey, error) {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, PrivateKeyPrefix) {
+		return nil, fmt.Errorf("invalid signing key: missing %s prefix", PrivateKeyPrefix)
+	}
+
+	seed, err := base64.RawStdEncoding.DecodeString(strings.TrimPrefix(s, PrivateKeyPrefix))
+	if err != nil {
+		return nil, fmt.Errorf("invalid signing key encoding: %w", err)
+	}
+	if len(seed) != ed25519.SeedSize {
+		return nil, fmt.Errorf("invalid signing key length: %d", len(seed))
+	}
+
+	return &PrivateKey{key: ed25519.NewKeyFromSeed(seed)}, nil
+}
+
+// ParsePublicKey decodes a public key created by PublicKey.String
+func ParsePublicKey(s string) (*PublicKey, error) {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, PublicKeyPrefix) {
+		return nil, fmt.Errorf("invalid public key: missing %s prefix", PublicKeyPrefix)
+	}
+
+	raw, err := base64.RawStdEncoding.DecodeString(strings.TrimPrefix(s, PublicKeyPrefix))
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key encoding: %w", err)
+	}
+	if len(raw) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid public key length: %d", len(raw))
+	}
+
+	return &PublicKey{key: ed25519.PublicKey(raw)}, nil
+}
+
+// LoadPrivateKeyFile reads a signing key from a file
+func LoadPrivateKeyFile(path string) (*PrivateKey, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read signing key %s: %w", path, err)
+	}
+
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		return ParsePrivateKey(line)
+	}
+
+	return nil, fmt.Errorf("no signing key found in %s", path)
+}
+
+// LoadTrustedKeys reads public keys from files or direct key strings
+// Files may contain several keys, one per line; lines starting with # are ignored
+func LoadTrustedKeys(inputs []string) ([]*PublicKey, error) {
+	var keys []*PublicKey
+
+	for _, input := range inputs {
+		input = strings.TrimSpace(input)
+		if input == "" {
+			continue
+		}
+
+		// Direct key string
+		if strings.HasPrefix(input, PublicKeyPrefix) {
+			key, err := ParsePublicKey(input)
+			if err != nil {
+				return nil, err
+			}
+			keys = append(keys, key)
+			continue
+		}
+
+		// Otherwise treat it as a file path
+		file, err := os.Open(input)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read trusted keys file %s: %w", input, err)
+		}
+
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			key, err := ParsePublicKey(line)
+			if err != nil {
+				file.Close()
+				return nil, fmt.Errorf("invalid key in %s: %w", input, err)
+			}
+			keys = append(keys, key)
+		}
+		file.Close()
+
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read trusted keys file %s: %w", input, err)
+		}
+	}
+
+	return keys, nil
+}
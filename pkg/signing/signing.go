@@ -0,0 +1,308 @@
+package signing
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// SignatureExtension is appended to an archive path to form its detached signature path
+const SignatureExtension = ".sig"
+
+// ManifestVersion is the current manifest format version
+const ManifestVersion = 1
+
+// Status describes the outcome of a signature check
+type Status string
+
+const (
+	// StatusValid means the archive is signed by a trusted key and unmodified
+	StatusValid Status = "valid"
+	// StatusUnsigned means no detached signature exists for the archive
+	StatusUnsigned Status = "unsigned"
+	// StatusUntrusted means the signature is intact but the key is not in the trusted list
+	StatusUntrusted Status = "untrusted"
+	// StatusInvalid means the signature or the archive content does not match
+	StatusInvalid Status = "invalid"
+)
+
+// ManifestEntry describes a single file inside the backup ZIP
+type ManifestEntry struct {
+	Name   string `json:"name"`
+	Size   uint64 `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// Manifest describes the signed contents of a backup archive
+type Manifest struct {
+	Version       int             `json:"version"`
+	CreatedAt     string          `json:"created_at"`
+	Archive       string          `json:"archive"`
+	ArchiveSize   int64           `json:"archive_size"`
+	ArchiveSHA256 string          `json:"archive_sha256"`
+	Entries       []ManifestEntry `json:"entries"`
+}
+
+// SignatureFile is the detached signature stored next to an archive
+type SignatureFile struct {
+	Manifest  Manifest `json:"manifest"`
+	KeyID     string   `json:"key_id"`
+	PublicKey string   `json:"public_key"`
+	Signature string   `json:"signature"`
+}
+
+// VerifyResult contains the outcome of verifying an archive signature
+type VerifyResult struct {
+	Status   Status    `json:"status"`
+	KeyID    string    `json:"keyId,omitempty"`
+	Message  string    `json:"message"`
+	Manifest *Manifest `json:"-"`
+}
+
+// SignaturePath returns the detached signature path for an archive
+func SignaturePath(archivePath string) string {
+	return archivePath + SignatureExtension
+}
+
+// BuildManifest hashes every entry of the plaintext ZIP and the final archive file
+// zipPath is the unencrypted backup, archivePath the file that is distributed (may be the same)
+func BuildManifest(zipPath, archivePath string) (*Manifest, error) {
+	entries, err := hashZipEntries(zipPath)
+	if err != nil {
+		return nil, err
+	}
+
+	archiveHash, archiveSize, err := hashFile(archivePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash archive: %w", err)
+	}
+
+	return &Manifest{
+		Version:       ManifestVersion,
+		CreatedAt:     time.Now().UTC().Format(time.RFC3339),
+		Archive:       filepath.Base(archivePath),
+		ArchiveSize:   archiveSize,
+		ArchiveSHA256: archiveHash,
+		Entries:       entries,
+	}, nil
+}
+
+// SignArchive builds a manifest for the archive and writes a detached signature next to it
+func SignArchive(key *PrivateKey, zipPath, archivePath string) (string, error) {
+	if key == nil {
+		return "", fmt.Errorf("signing key is required")
+	}
+
+	manifest, err := BuildManifest(zipPath, archivePath)
+	if err != nil {
+		return "", err
+	}
+
+	payload, err := json.Marshal(manifest)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+
+	public := key.Public()
+	sigFile := SignatureFile{
+		Manifest:  *manifest,
+		KeyID:     public.KeyID(),
+		PublicKey: public.String(),
+		Signature: base64.StdEncoding.EncodeToString(key.Sign(payload)),
+	}
+
+	data, err := json.MarshalIndent(sigFile, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal signature: %w", err)
+	}
+
+	sigPath := SignaturePath(archivePath)
+	if err := os.WriteFile(sigPath, data, 0644); err != nil {
+		return "", fmt.Errorf("failed to write signature file: %w", err)
+	}
+
+	logrus.Infof("Signed %s with key %s (%d entries)", filepath.Base(archivePath), sigFile.KeyID, len(manifest.Entries))
+	return sigPath, nil
+}
+
+// VerifyArchive checks the detached signature of an archive against the trusted keys
+// It does not look inside the archive; use VerifyEntries on the decrypted ZIP for that
+func VerifyArchive(archivePath string, trusted []*PublicKey) (*VerifyResult, error) {
+	data, err := os.ReadFile(SignaturePath(archivePath))
+	if os.IsNotExist(err) {
+		return &VerifyResult{Status: StatusUnsigned, Message: "no detached signature found"}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read signature file: %w", err)
+	}
+
+	var sigFile SignatureFile
+	if err := json.Unmarshal(data, &sigFile); err != nil {
+		return &VerifyResult{Status: StatusInvalid, Message: fmt.Sprintf("malformed signature file: %v", err)}, nil
+	}
+
+	signer, err := ParsePublicKey(sigFile.PublicKey)
+	if err != nil {
+		return &VerifyResult{Status: StatusInvalid, Message: fmt.Sprintf("malformed signer key: %v", err)}, nil
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(sigFile.Signature)
+	if err != nil {
+		return &VerifyResult{Status: StatusInvalid, KeyID: signer.KeyID(), Message: "malformed signature encoding"}, nil
+	}
+
+	payload, err := json.Marshal(sigFile.Manifest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+
+	if !signer.Verify(payload, signature) {
+		return &VerifyResult{Status: StatusInvalid, KeyID: signer.KeyID(), Message: "signature does not match manifest"}, nil
+	}
+
+	// The manifest is signed for one file name; a signature copied next to another archive does not apply
+	if sigFile.Manifest.Archive != filepath.Base(archivePath) {
+		return &VerifyResult{Status: StatusInvalid, KeyID: signer.KeyID(), Message: fmt.Sprintf("signature was made for %s, not %s", sigFile.Manifest.Archive, filepath.Base(archivePath))}, nil
+	}
+
+	archiveHash, _, err := hashFile(archivePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash archive: %w", err)
+	}
+	if archiveHash != sigFile.Manifest.ArchiveSHA256 {
+		return &VerifyResult{Status: StatusInvalid, KeyID: signer.KeyID(), Message: "archive content does not match signed manifest"}, nil
+	}
+
+	result := &VerifyResult{KeyID: signer.KeyID(), Manifest: &sigFile.Manifest}
+	for _, key := range trusted {
+		if key.String() == signer.String() {
+			result.Status = StatusValid
+			result.Message = fmt.Sprintf("signed by trusted key %s", signer.KeyID())
+			return result, nil
+		}
+	}
+
+	result.Status = StatusUntrusted
+	result.Message = fmt.Sprintf("signed by key %s which is not in the trusted keys list", signer.KeyID())
+	return result, nil
+}
+
+// VerifyEntries checks that a decrypted ZIP matches the entries recorded in the manifest
+func VerifyEntries(manifest *Manifest, zipPath string) error {
+	if manifest == nil {
+		return fmt.Errorf("manifest is nil")
+	}
+
+	entries, err := hashZipEntries(zipPath)
+	if err != nil {
+		return err
+	}
+
+	if len(entries) != len(manifest.Entries) {
+		return fmt.Errorf("archive has %d entries, manifest lists %d", len(entries), len(manifest.Entries))
+	}
+
+	for i, entry := range entries {
+		expected := manifest.Entries[i]
+		if entry.Name != expected.Name || entry.SHA256 != expected.SHA256 {
+			return fmt.Errorf("entry %s does not match signed manifest", entry.Name)
+		}
+	}
+
+	return nil
+}
+
+// Enforce applies the signature policy to a verification result
+// Invalid signatures are always rejected; unsigned or untrusted archives are
+// rejected when require is true and reported with a warning otherwise
+func Enforce(result *VerifyResult, require bool) error {
+	if result == nil {
+		return fmt.Errorf("signature verification result is nil")
+	}
+
+	switch result.Status {
+	case StatusValid:
+		logrus.Infof("✓ Signature valid: %s", result.Message)
+		return nil
+	case StatusInvalid:
+		logrus.Error("❌ Signature INVALID - the archive may have been tampered with")
+		return fmt.Errorf("signature verification failed: %s", result.Message)
+	default:
+		if require {
+			logrus.Errorf("❌ Refusing %s archive: %s", result.Status, result.Message)
+			return fmt.Errorf("archive is %s: %s", result.Status, result.Message)
+		}
+		logrus.Warn("═══════════════════════════════════════════════════════════════")
+		logrus.Warnf("⚠️  WARNING: archive is %s (%s)", result.Status, result.Message)
+		logrus.Warn("⚠️  Its authenticity cannot be confirmed. Use --require-signature to refuse such archives.")
+		logrus.Warn("═══════════════════════════════════════════════════════════════")
+		return nil
+	}
+}
+
+// hashZipEntries returns the SHA-256 of every file in a ZIP, sorted by name
+func hashZipEntries(zipPath string) ([]ManifestEntry, error) {
+	r, err := zip.OpenReader(zipPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open ZIP file: %w", err)
+	}
+	defer r.Close()
+
+	entries := make([]ManifestEntry, 0, len(r.File))
+	for _, f := range r.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("failed to open %s: %w", f.Name, err)
+		}
+
+		h := sha256.New()
+		n, err := io.Copy(h, rc)
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", f.Name, err)
+		}
+
+		entries = append(entries, ManifestEntry{
+			Name:   f.Name,
+			Size:   uint64(n),
+			SHA256: hex.EncodeToString(h.Sum(nil)),
+		})
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name < entries[j].Name
+	})
+
+	return entries, nil
+}
+
+// hashFile returns the SHA-256 and size of a file
+func hashFile(path string) (string, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return "", 0, err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), n, nil
+}
@@ -0,0 +1,256 @@
+package snapshot
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/vosiander/open-webui-backup/pkg/archive"
+)
+
+// Entry is the snapshot record at the root of a backup ZIP
+const Entry = "snapshot.json"
+
+// ModeConsistent marks backups taken with a coordinated snapshot
+const ModeConsistent = "consistent"
+
+// Record describes the point in time a consistent backup represents
+// The database is captured at StartedAt; the API export runs from StartedAt to FinishedAt.
+// Entities updated at or after StartedAt are listed in Changed, because their API copy
+// may be newer than the database snapshot
+type Record struct {
+	Mode        string                `json:"mode"`
+	StartedAt   string                `json:"started_at"`
+	FinishedAt  string                `json:"finished_at"`
+	WindowSecs  float64               `json:"window_seconds"`
+	Database    *Database             `json:"database,omitempty"`
+	Maintenance *Maintenance          `json:"maintenance,omitempty"`
+	Watermarks  map[string]*Watermark `json:"watermarks"`
+	Changed     []Change              `json:"changed,omitempty"`
+}
+
+// Database is the database state the backup was taken at
+type Database struct {
+	Engine     string `json:"engine"`
+	Timestamp  string `json:"timestamp"`
+	LSN        string `json:"lsn,omitempty"`
+	TxSnapshot string `json:"tx_snapshot,omitempty"`
+}
+
+// Maintenance records the hooks run around the capture
+type Maintenance struct {
+	EnteredAt string `json:"entered_at,omitempty"`
+	ExitedAt  string `json:"exited_at,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Watermark is the newest updated_at of an entity type in the backup
+type Watermark struct {
+	Count     int    `json:"count"`
+	UpdatedAt int64  `json:"updated_at"`
+	Newest    string `json:"newest,omitempty"`
+}
+
+// Change is an entity updated during the capture window
+type Change struct {
+	Type      string `json:"type"`
+	ID        string `json:"id"`
+	UpdatedAt int64  `json:"updated_at"`
+}
+
+// New starts a record at the given time
+func New(startedAt time.Time) *Record {
+	return &Record{
+		Mode:       ModeConsistent,
+		StartedAt:  startedAt.UTC().Format(time.RFC3339Nano),
+		Watermarks: map[string]*Watermark{},
+	}
+}
+
+// Finish closes the window and computes watermarks and changes from the exported entities
+func (r *Record) Finish(a *archive.Archive, finishedAt time.Time) {
+	r.FinishedAt = finishedAt.UTC().Format(time.RFC3339Nano)
+	started, _ := time.Parse(time.RFC3339Nano, r.StartedAt)
+	r.WindowSecs = finishedAt.Sub(started).Seconds()
+	// updated_at has second resolution, so an update in the same second as the snapshot counts as a change
+	since := started.Unix()
+
+	for entityType, entities := range a.Entities {
+		watermark := &Watermark{Count: len(entities)}
+		for _, entity := range entities {
+			updated := Seconds(entity.UpdatedAt)
+			if updated > watermark.UpdatedAt {
+				watermark.UpdatedAt = updated
+			}
+			if updated >= since {
+				r.Changed = append(r.Changed, Change{Type: entityType, ID: entity.ID, UpdatedAt: updated})
+			}
+		}
+		if watermark.UpdatedAt > 0 {
+			watermark.Newest = time.Unix(watermark.UpdatedAt, 0).UTC().Format(time.RFC3339)
+		}
+		r.Watermarks[entityType] = watermark
+	}
+
+	sort.Slice(r.Changed, func(i, j int) bool {
+		if r.Changed[i].Type != r.Changed[j].Type {
+			return r.Changed[i].Type < r.Changed[j].Type
+		}
+		return r.Changed[i].ID < r.Changed[j].ID
+	})
+}
+
+// Remove takes entities that were dropped from the backup out of the watermarks and changes
+// Watermarks keep their newest updated_at, which stays an upper bound; removed returns true if anything changed
+func (r *Record) Remove(dropped map[string]map[string]bool) bool {
+	removed := false
+	for entityType, ids := range dropped {
+		if watermark := r.Watermarks[entityType]; watermark != nil && len(ids) > 0 {
+			watermark.Count -= len(ids)
+			if watermark.Count < 0 {
+				watermark.Count = 0
+			}
+			removed = true
+		}
+	}
+	kept := r.Changed[:0]
+	for _, change := range r.Changed {
+		if dropped[change.Type][change.ID] {
+			removed = true
+			continue
+		}
+		kept = append(kept, change)
+	}
+	r.Changed = kept
+	return removed
+}
+
+// Seconds normalizes an Open WebUI timestamp to Unix seconds
+// Most tables store seconds, but some store milliseconds or nanoseconds
+func Seconds(ts int64) int64 {
+	switch {
+	case ts > 1e17:
+		return ts / 1e9
+	case ts > 1e14:
+		return ts / 1e6
+	case ts > 1e11:
+		return ts / 1e3
+	}
+	return ts
+}
+
+// Write adds the record to a ZIP writer
+func Write(zw *zip.Writer, r *Record) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal snapshot record: %w", err)
+	}
+	w, err := zw.Create(Entry)
+	if err != nil {
+		return fmt.Errorf("failed to create %s in zip: %w", Entry, err)
+	}
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("failed to write %s: %w", Entry, err)
+	}
+	return nil
+}
+
+// Read returns the snapshot record of a backup ZIP, or nil if it was not taken as a consistent snapshot
+func Read(zr *zip.Reader) (*Record, error) {
+	for _, f := range zr.File {
+		if f.Name != Entry {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("failed to open %s: %w", Entry, err)
+		}
+		defer rc.Close()
+		data, err := io.ReadAll(rc)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", Entry, err)
+		}
+		record := &Record{}
+		if err := json.Unmarshal(data, record); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", Entry, err)
+		}
+		return record, nil
+	}
+	return nil, nil
+}
+
+// Hook puts the instance into or out of maintenance around a consistent backup
+// An http:// or https:// URL receives a POST with {"phase": "enter"|"exit"};
+// anything else is a command line, split on whitespace, run with the phase as its last argument
+// and in OWUI_MAINTENANCE_PHASE
+type Hook struct {
+	target string
+}
+
+// Maintenance hook phases
+const (
+	PhaseEnter = "enter"
+	PhaseExit  = "exit"
+)
+
+// hookTimeout bounds how long a maintenance hook may run
+const hookTimeout = 2 * time.Minute
+
+// NewHook validates a hook URL or command line
+func NewHook(target string) (*Hook, error) {
+	target = strings.TrimSpace(target)
+	if target == "" {
+		return nil, fmt.Errorf("maintenance hook is empty")
+	}
+	if !isURL(target) {
+		if _, err := exec.LookPath(strings.Fields(target)[0]); err != nil {
+			return nil, fmt.Errorf("maintenance hook command not found: %w", err)
+		}
+	}
+	return &Hook{target: target}, nil
+}
+
+// Run calls the hook for a phase
+func (h *Hook) Run(phase string) error {
+	if isURL(h.target) {
+		body, _ := json.Marshal(map[string]string{"phase": phase})
+		client := &http.Client{Timeout: hookTimeout}
+		resp, err := client.Post(h.target, "application/json", bytes.NewReader(body))
+		if err != nil {
+			return fmt.Errorf("maintenance hook request failed: %w", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode >= 300 {
+			msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+			return fmt.Errorf("maintenance hook returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+		}
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), hookTimeout)
+	defer cancel()
+	fields := strings.Fields(h.target)
+	cmd := exec.CommandContext(ctx, fields[0], append(fields[1:], phase)...)
+	cmd.Env = append(cmd.Environ(), "OWUI_MAINTENANCE_PHASE="+phase)
+	output, err := cmd.CombinedOutput()
+	if ctx.Err() != nil {
+		return fmt.Errorf("maintenance hook %s timed out after %s", fields[0], hookTimeout)
+	}
+	if err != nil {
+		return fmt.Errorf("maintenance hook %s failed: %w: %s", fields[0], err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// isURL reports whether a hook target is a webhook URL
+func isURL(target string) bool {
+	return strings.HasPrefix(target, "http://") || strings.HasPrefix(target, "https://")
+}
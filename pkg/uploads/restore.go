@@ -0,0 +1,190 @@
+package uploads
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/sirupsen/logrus"
+)
+
+// RestoreOptions control how the uploads section is written back
+type RestoreOptions struct {
+	Target    string // directory to restore into
+	Overwrite bool   // replace existing files that differ from the backup
+	DryRun    bool   // only report what would be restored
+}
+
+// RestoreStats summarizes a restore
+type RestoreStats struct {
+	Restored  int   `json:"restored"`
+	Unchanged int   `json:"unchanged"`
+	Kept      int   `json:"kept"`
+	Dirs      int   `json:"dirs"`
+	Symlinks  int   `json:"symlinks"`
+	Size      int64 `json:"size"`
+}
+
+// Restore writes the files of the uploads section below the target directory
+// Files are streamed to a temporary name, checked against the manifest and renamed into place.
+// Files that already exist with the same size and mtime are left alone; files that differ are
+// only replaced with Overwrite. Symlinks are created last so no entry is written through one,
+// and directory modes and mtimes are applied at the end
+func Restore(zr *zip.Reader, manifest *Manifest, opts *RestoreOptions) (*RestoreStats, error) {
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	if !opts.DryRun {
+		if err := os.MkdirAll(opts.Target, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create target directory: %w", err)
+		}
+	}
+
+	stats := &RestoreStats{}
+	var dirs, symlinks []Entry
+	for _, entry := range manifest.Entries {
+		rel, err := cleanPath(entry.Path)
+		if err != nil {
+			return nil, err
+		}
+		dst := filepath.Join(opts.Target, rel)
+
+		switch entry.Type {
+		case TypeDir:
+			dirs = append(dirs, entry)
+			if opts.DryRun {
+				continue
+			}
+			if err := os.MkdirAll(dst, 0755); err != nil {
+				return nil, fmt.Errorf("failed to create directory %s: %w", entry.Path, err)
+			}
+
+		case TypeSymlink:
+			symlinks = append(symlinks, entry)
+
+		case TypeFile:
+			f := files[FilesPrefix+entry.Path]
+			if f == nil {
+				return nil, fmt.Errorf("%s listed in the manifest but missing from the backup", entry.Path)
+			}
+			if info, err := os.Lstat(dst); err == nil {
+				if info.Mode().IsRegular() && info.Size() == entry.Size && info.ModTime().Equal(entry.ModTime) {
+					stats.Unchanged++
+					continue
+				}
+				if !opts.Overwrite {
+					logrus.Warnf("Keeping %s: it differs from the backup (use --overwrite to replace it)", entry.Path)
+					stats.Kept++
+					continue
+				}
+			}
+			if !opts.DryRun {
+				if err := restoreFile(f, dst, &entry); err != nil {
+					return nil, err
+				}
+			}
+			stats.Restored++
+			stats.Size += entry.Size
+
+		default:
+			logrus.Warnf("Skipping %s: unknown entry type %q", entry.Path, entry.Type)
+		}
+	}
+
+	for _, entry := range symlinks {
+		rel, _ := cleanPath(entry.Path)
+		dst := filepath.Join(opts.Target, rel)
+		if target, err := os.Readlink(dst); err == nil && target == entry.Target {
+			stats.Unchanged++
+			continue
+		}
+		if _, err := os.Lstat(dst); err == nil {
+			if !opts.Overwrite {
+				logrus.Warnf("Keeping %s: it differs from the backup (use --overwrite to replace it)", entry.Path)
+				stats.Kept++
+				continue
+			}
+			if !opts.DryRun {
+				if err := os.Remove(dst); err != nil {
+					return nil, fmt.Errorf("failed to replace %s: %w", entry.Path, err)
+				}
+			}
+		}
+		if !opts.DryRun {
+			if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+				return nil, fmt.Errorf("failed to create directory for %s: %w", entry.Path, err)
+			}
+			if err := os.Symlink(entry.Target, dst); err != nil {
+				return nil, fmt.Errorf("failed to create symlink %s: %w", entry.Path, err)
+			}
+		}
+		stats.Symlinks++
+	}
+
+	// Children come after their parents in the manifest, so apply directory times in reverse
+	for i := len(dirs) - 1; i >= 0; i-- {
+		stats.Dirs++
+		if opts.DryRun {
+			continue
+		}
+		rel, _ := cleanPath(dirs[i].Path)
+		dst := filepath.Join(opts.Target, rel)
+		if err := os.Chmod(dst, dirs[i].Mode.Perm()); err != nil {
+			return nil, fmt.Errorf("failed to set mode of %s: %w", dirs[i].Path, err)
+		}
+		if err := os.Chtimes(dst, dirs[i].ModTime, dirs[i].ModTime); err != nil {
+			return nil, fmt.Errorf("failed to set mtime of %s: %w", dirs[i].Path, err)
+		}
+	}
+
+	return stats, nil
+}
+
+// restoreFile streams a ZIP entry to dst through a temporary file and checks its checksum
+func restoreFile(f *zip.File, dst string, entry *Entry) error {
+	dir := filepath.Dir(dst)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", entry.Path, err)
+	}
+
+	rc, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", f.Name, err)
+	}
+	defer rc.Close()
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(dst)+".*.restore.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file for %s: %w", entry.Path, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	hash := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(tmp, hash), rc); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to extract %s: %w", entry.Path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to extract %s: %w", entry.Path, err)
+	}
+	if sum := hex.EncodeToString(hash.Sum(nil)); entry.SHA256 != "" && sum != entry.SHA256 {
+		return fmt.Errorf("checksum mismatch for %s", entry.Path)
+	}
+
+	if err := os.Chmod(tmp.Name(), entry.Mode.Perm()); err != nil {
+		return fmt.Errorf("failed to set mode of %s: %w", entry.Path, err)
+	}
+	if err := os.Chtimes(tmp.Name(), entry.ModTime, entry.ModTime); err != nil {
+		return fmt.Errorf("failed to set mtime of %s: %w", entry.Path, err)
+	}
+	if err := os.Rename(tmp.Name(), dst); err != nil {
+		return fmt.Errorf("failed to move %s into place: %w", entry.Path, err)
+	}
+	return nil
+}
@@ -0,0 +1,367 @@
+package uploads
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/vosiander/open-webui-backup/pkg/database"
+)
+
+// Prefix is the folder of the uploads section in a backup ZIP
+const Prefix = "uploads/"
+
+// FilesPrefix holds the files of the data directory, relative to its root
+const FilesPrefix = Prefix + "files/"
+
+// ManifestEntry lists every backed up path with its mode, mtime and checksum
+const ManifestEntry = Prefix + "manifest.json"
+
+// Entry types in the manifest
+const (
+	TypeFile    = "file"
+	TypeDir     = "dir"
+	TypeSymlink = "symlink"
+)
+
+// DefaultExcludes skips what other backup sections already capture consistently
+// and the leftovers of earlier restores
+var DefaultExcludes = []string{
+	"webui.db",
+	"webui.db-*",
+	"vector_db",
+	"*.pre-restore",
+	"*.restore.tmp",
+}
+
+// Options select the directory and the paths below it
+// Patterns are globs relative to the root; * and ? do not cross /, ** matches any number of
+// directories, and a pattern without / matches a base name at any depth.
+// A matching directory includes or excludes everything below it
+type Options struct {
+	Root    string
+	Include []string
+	Exclude []string
+}
+
+// Manifest describes the uploads section of a backup ZIP
+type Manifest struct {
+	BackupTimestamp string   `json:"backup_timestamp"`
+	Root            string   `json:"root"`
+	Include         []string `json:"include,omitempty"`
+	Exclude         []string `json:"exclude,omitempty"`
+	Files           int      `json:"files"`
+	Dirs            int      `json:"dirs"`
+	Symlinks        int      `json:"symlinks,omitempty"`
+	Size            int64    `json:"size"`
+	Entries         []Entry  `json:"entries"`
+}
+
+// Entry is a file, directory or symlink of the backed up tree
+type Entry struct {
+	Path    string      `json:"path"`
+	Type    string      `json:"type"`
+	Mode    fs.FileMode `json:"mode"`
+	ModTime time.Time   `json:"mtime"`
+	Size    int64       `json:"size,omitempty"`
+	SHA256  string      `json:"sha256,omitempty"`
+	Target  string      `json:"target,omitempty"`
+}
+
+// ResolveRoot returns the data directory from the flag, OWUI_DATA_DIR, DATA_DIR or the default locations
+func ResolveRoot(explicit string) (string, error) {
+	root := explicit
+	if root == "" {
+		root = database.GetDataDirFromEnv()
+	}
+	if root == "" {
+		for _, dir := range database.DefaultDataDirs {
+			if info, err := os.Stat(dir); err == nil && info.IsDir() {
+				root = dir
+				break
+			}
+		}
+	}
+	if root == "" {
+		return "", fmt.Errorf("data directory not found (use --data-dir or OWUI_DATA_DIR)")
+	}
+	info, err := os.Stat(root)
+	if err != nil {
+		return "", fmt.Errorf("data directory not accessible: %w", err)
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("data directory %s is not a directory", root)
+	}
+	return root, nil
+}
+
+// IsConfigured reports whether a data directory is configured through the environment
+func IsConfigured() bool {
+	root := database.GetDataDirFromEnv()
+	if root == "" {
+		return false
+	}
+	info, err := os.Stat(root)
+	return err == nil && info.IsDir()
+}
+
+// ValidatePatterns checks that include and exclude patterns are well-formed globs
+func ValidatePatterns(patterns []string) error {
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// Backup streams the selected files into the ZIP writer and writes the manifest last
+// File contents are hashed while they are copied, so nothing is held in memory
+func Backup(zw *zip.Writer, opts *Options) (*Manifest, error) {
+	root, err := filepath.Abs(opts.Root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve data directory: %w", err)
+	}
+	manifest := &Manifest{
+		BackupTimestamp: time.Now().UTC().Format(time.RFC3339),
+		Root:            root,
+		Include:         opts.Include,
+		Exclude:         opts.Exclude,
+	}
+
+	logrus.Infof("Backing up data directory '%s'...", root)
+
+	err = walk(root, opts.Include, opts.Exclude, func(rel, p string, info fs.FileInfo) error {
+		entry, err := addEntry(zw, rel, p, info)
+		if err != nil {
+			if os.IsNotExist(err) {
+				logrus.Warnf("Skipping %s: removed during backup", rel)
+				return nil
+			}
+			return err
+		}
+		manifest.Entries = append(manifest.Entries, *entry)
+		switch entry.Type {
+		case TypeFile:
+			manifest.Files++
+			manifest.Size += entry.Size
+		case TypeDir:
+			manifest.Dirs++
+		case TypeSymlink:
+			manifest.Symlinks++
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to back up data directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal uploads manifest: %w", err)
+	}
+	w, err := zw.Create(ManifestEntry)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s in zip: %w", ManifestEntry, err)
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, fmt.Errorf("failed to write %s: %w", ManifestEntry, err)
+	}
+
+	logrus.Infof("Data directory backup created: %d files, %d directories (%d bytes)",
+		manifest.Files, manifest.Dirs, manifest.Size)
+	return manifest, nil
+}
+
+// walk calls fn for every selected path below root in lexical order, parents before children
+// Symlinks are not followed; sockets, pipes and devices are skipped
+func walk(root string, include, exclude []string, fn func(rel, p string, info fs.FileInfo) error) error {
+	return filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p == root {
+			return nil
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		if matchAny(exclude, rel) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if len(include) > 0 && !matchAny(include, rel) {
+			// Directories are still searched for included files
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if !info.Mode().IsRegular() && !info.IsDir() && info.Mode()&fs.ModeSymlink == 0 {
+			logrus.Warnf("Skipping %s: not a regular file", rel)
+			return nil
+		}
+		return fn(rel, p, info)
+	})
+}
+
+// addEntry writes one path to the ZIP with its mode and modification time
+func addEntry(zw *zip.Writer, rel, p string, info fs.FileInfo) (*Entry, error) {
+	entry := &Entry{
+		Path:    rel,
+		Mode:    info.Mode(),
+		ModTime: info.ModTime().UTC(),
+	}
+
+	header, err := zip.FileInfoHeader(info)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create header for %s: %w", rel, err)
+	}
+	header.Name = FilesPrefix + rel
+
+	switch {
+	case info.IsDir():
+		entry.Type = TypeDir
+		header.Name += "/"
+		header.Method = zip.Store
+		if _, err := zw.CreateHeader(header); err != nil {
+			return nil, fmt.Errorf("failed to create %s in zip: %w", header.Name, err)
+		}
+
+	case info.Mode()&fs.ModeSymlink != 0:
+		entry.Type = TypeSymlink
+		target, err := os.Readlink(p)
+		if err != nil {
+			return nil, err
+		}
+		entry.Target = target
+		w, err := zw.CreateHeader(header)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create %s in zip: %w", header.Name, err)
+		}
+		if _, err := io.WriteString(w, target); err != nil {
+			return nil, fmt.Errorf("failed to write %s: %w", header.Name, err)
+		}
+
+	default:
+		entry.Type = TypeFile
+		f, err := os.Open(p)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+
+		header.Method = zip.Deflate
+		w, err := zw.CreateHeader(header)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create %s in zip: %w", header.Name, err)
+		}
+		hash := sha256.New()
+		n, err := io.Copy(io.MultiWriter(w, hash), f)
+		if err != nil {
+			return nil, fmt.Errorf("failed to write %s: %w", header.Name, err)
+		}
+		if n != info.Size() {
+			logrus.Warnf("%s changed size during backup (%d -> %d bytes)", rel, info.Size(), n)
+		}
+		entry.Size = n
+		entry.SHA256 = hex.EncodeToString(hash.Sum(nil))
+	}
+	return entry, nil
+}
+
+// ReadManifest returns the uploads manifest of a backup ZIP, or nil if it has no uploads section
+func ReadManifest(zr *zip.Reader) (*Manifest, error) {
+	for _, f := range zr.File {
+		if f.Name != ManifestEntry {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("failed to open %s: %w", ManifestEntry, err)
+		}
+		defer rc.Close()
+		manifest := &Manifest{}
+		if err := json.NewDecoder(rc).Decode(manifest); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", ManifestEntry, err)
+		}
+		return manifest, nil
+	}
+	return nil, nil
+}
+
+// cleanPath validates a manifest path and returns it in OS form
+// Absolute paths and paths leaving the root are rejected
+func cleanPath(rel string) (string, error) {
+	clean := path.Clean(rel)
+	if clean == "." || clean == ".." || strings.HasPrefix(clean, "../") || path.IsAbs(clean) || clean != rel {
+		return "", fmt.Errorf("invalid path in uploads manifest: %q", rel)
+	}
+	return filepath.FromSlash(clean), nil
+}
+
+// matchAny reports whether rel or one of its parent directories matches a pattern
+func matchAny(patterns []string, rel string) bool {
+	for _, pattern := range patterns {
+		for p := rel; p != "."; p = path.Dir(p) {
+			if match(pattern, p) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// match matches a slash-separated path against a pattern with ** support
+// A pattern without / is matched against the base name, a leading / anchors it at the root
+func match(pattern, rel string) bool {
+	anchored := strings.HasPrefix(pattern, "/")
+	pattern = strings.Trim(pattern, "/")
+	if !anchored && !strings.Contains(pattern, "/") && pattern != "**" {
+		ok, _ := path.Match(pattern, path.Base(rel))
+		return ok
+	}
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(rel, "/"))
+}
+
+// matchSegments matches path segments, letting ** consume zero or more of them
+func matchSegments(pattern, segments []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(segments); i++ {
+				if matchSegments(pattern[1:], segments[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(segments) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], segments[0]); !ok {
+			return false
+		}
+		pattern, segments = pattern[1:], segments[1:]
+	}
+	return len(segments) == 0
+}
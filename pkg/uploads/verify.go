@@ -0,0 +1,136 @@
+package uploads
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// VerifyReport lists the differences found by Verify
+type VerifyReport struct {
+	Files   int      `json:"files"`
+	Checked int      `json:"checked"`
+	Corrupt []string `json:"corrupt,omitempty"`
+	// Compared against a live data directory
+	Missing []string `json:"missing,omitempty"`
+	Changed []string `json:"changed,omitempty"`
+	Added   []string `json:"added,omitempty"`
+}
+
+// OK reports whether every file in the backup matches its manifest entry
+func (r *VerifyReport) OK() bool {
+	return len(r.Corrupt) == 0
+}
+
+// InSync reports whether the live directory matches the backup
+func (r *VerifyReport) InSync() bool {
+	return len(r.Missing) == 0 && len(r.Changed) == 0 && len(r.Added) == 0
+}
+
+// Verify checks every file of the uploads section against the size and checksum in the manifest
+// If live is set, the directory is compared with the manifest using the backup's include and
+// exclude patterns: files missing from it, changed since the backup or added after it are listed
+func Verify(zr *zip.Reader, manifest *Manifest, live string) (*VerifyReport, error) {
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	report := &VerifyReport{Files: manifest.Files}
+	for _, entry := range manifest.Entries {
+		if _, err := cleanPath(entry.Path); err != nil {
+			return nil, err
+		}
+		if entry.Type != TypeFile {
+			continue
+		}
+		f := files[FilesPrefix+entry.Path]
+		if f == nil {
+			report.Corrupt = append(report.Corrupt, entry.Path)
+			continue
+		}
+		ok, err := checkEntry(f, &entry)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			report.Corrupt = append(report.Corrupt, entry.Path)
+		}
+		report.Checked++
+	}
+
+	if live != "" {
+		if err := compareLive(report, manifest, live); err != nil {
+			return nil, err
+		}
+	}
+	return report, nil
+}
+
+// checkEntry streams a ZIP entry and compares its size and checksum with the manifest
+func checkEntry(f *zip.File, entry *Entry) (bool, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return false, fmt.Errorf("failed to open %s: %w", f.Name, err)
+	}
+	defer rc.Close()
+
+	hash := sha256.New()
+	n, err := io.Copy(hash, rc)
+	if err != nil {
+		// A damaged entry fails its CRC check while it is read
+		return false, nil
+	}
+	return n == entry.Size && hex.EncodeToString(hash.Sum(nil)) == entry.SHA256, nil
+}
+
+// compareLive compares the manifest with the files currently in the live directory
+func compareLive(report *VerifyReport, manifest *Manifest, live string) error {
+	current := make(map[string]fs.FileInfo)
+	err := walk(live, manifest.Include, manifest.Exclude, func(rel, p string, info fs.FileInfo) error {
+		current[rel] = info
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to read data directory: %w", err)
+	}
+
+	inBackup := make(map[string]bool, len(manifest.Entries))
+	for _, entry := range manifest.Entries {
+		inBackup[entry.Path] = true
+		info, ok := current[entry.Path]
+		if !ok {
+			if _, err := os.Lstat(filepath.Join(live, filepath.FromSlash(entry.Path))); err == nil {
+				// Present but no longer selected by the patterns
+				continue
+			}
+			report.Missing = append(report.Missing, entry.Path)
+			continue
+		}
+		switch entry.Type {
+		case TypeFile:
+			if !info.Mode().IsRegular() || info.Size() != entry.Size || !info.ModTime().Equal(entry.ModTime) {
+				report.Changed = append(report.Changed, entry.Path)
+			}
+		case TypeSymlink:
+			target, err := os.Readlink(filepath.Join(live, filepath.FromSlash(entry.Path)))
+			if err != nil || target != entry.Target {
+				report.Changed = append(report.Changed, entry.Path)
+			}
+		}
+	}
+
+	for rel, info := range current {
+		if !inBackup[rel] && !info.IsDir() {
+			report.Added = append(report.Added, rel)
+		}
+	}
+	sort.Strings(report.Added)
+	return nil
+}
@@ -0,0 +1,376 @@
+package userexport
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/vosiander/open-webui-backup/pkg/chatexport"
+	"github.com/vosiander/open-webui-backup/pkg/openwebui"
+)
+
+// unsafeChars are replaced in file names derived from IDs and titles
+var unsafeChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// Write stores the bundle as a ZIP with an index.md and one file per item
+func (b *Bundle) Write(zipPath string) error {
+	out, err := os.Create(zipPath)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", zipPath, err)
+	}
+	defer out.Close()
+
+	w := zip.NewWriter(out)
+	paths := make(map[string]string) // item key -> path in the bundle, for index links
+
+	// The API key is a credential, not personal data, and must not travel in the export
+	profile := b.User
+	profile.APIKey = ""
+	if err := writeJSON(w, "profile.json", &profile); err != nil {
+		return err
+	}
+
+	users := map[string]openwebui.User{b.User.ID: b.User}
+	for i := range b.Chats {
+		chat := &b.Chats[i]
+		base := "chats/" + chatexport.FileName(chat, "")
+		var md bytes.Buffer
+		if err := chatexport.WriteMarkdown(&md, chat, users); err != nil {
+			return err
+		}
+		if err := writeBytes(w, base+".md", md.Bytes()); err != nil {
+			return err
+		}
+		if err := writeJSON(w, base+".json", chat); err != nil {
+			return err
+		}
+		paths["chat/"+chat.ID] = base + ".md"
+	}
+
+	if len(b.Memories) > 0 {
+		if err := writeJSON(w, "memories.json", b.Memories); err != nil {
+			return err
+		}
+	}
+
+	for i := range b.Files {
+		file := &b.Files[i]
+		dir := "files/" + safeName(file.ID) + "/"
+		if err := writeJSON(w, dir+"file.json", file); err != nil {
+			return err
+		}
+		if file.Data == nil || file.Data.Content == "" {
+			continue
+		}
+		name := dir + textName(fileName(file))
+		if err := writeBytes(w, name, []byte(file.Data.Content)); err != nil {
+			return err
+		}
+		paths["file/"+file.ID] = name
+	}
+
+	for i := range b.Knowledge {
+		name := "knowledge/" + safeName(b.Knowledge[i].ID) + ".json"
+		if err := writeJSON(w, name, &b.Knowledge[i]); err != nil {
+			return err
+		}
+		paths["knowledge/"+b.Knowledge[i].ID] = name
+	}
+
+	for i := range b.Prompts {
+		name := "prompts/" + safeName(strings.TrimPrefix(b.Prompts[i].Command, "/")) + ".json"
+		if err := writeJSON(w, name, &b.Prompts[i]); err != nil {
+			return err
+		}
+		paths["prompt/"+b.Prompts[i].Command] = name
+	}
+
+	for i := range b.Models {
+		name := "models/" + safeName(b.Models[i].ID) + ".json"
+		if err := writeJSON(w, name, &b.Models[i]); err != nil {
+			return err
+		}
+		paths["model/"+b.Models[i].ID] = name
+	}
+
+	for i := range b.Tools {
+		name := "tools/" + safeName(b.Tools[i].ID) + ".json"
+		if err := writeJSON(w, name, &b.Tools[i]); err != nil {
+			return err
+		}
+		paths["tool/"+b.Tools[i].ID] = name
+	}
+
+	if len(b.Feedbacks) > 0 {
+		if err := writeJSON(w, "feedbacks.json", b.Feedbacks); err != nil {
+			return err
+		}
+	}
+
+	var index bytes.Buffer
+	b.writeIndex(&index, paths)
+	if err := writeBytes(w, "index.md", index.Bytes()); err != nil {
+		return err
+	}
+
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to finalize ZIP file: %w", err)
+	}
+	return nil
+}
+
+// writeIndex renders the human-readable overview of the bundle
+func (b *Bundle) writeIndex(w io.Writer, paths map[string]string) {
+	bw := bufio.NewWriter(w)
+	defer bw.Flush()
+
+	u := b.User
+	fmt.Fprintf(bw, "# Personal data export: %s\n\n", displayName(&u))
+	fmt.Fprintf(bw, "This archive contains the data stored for your account. Each section links to the files holding the full records.\n\n")
+	fmt.Fprintf(bw, "- **Generated:** %s\n", b.CreatedAt.Format("2006-01-02 15:04 UTC"))
+	fmt.Fprintf(bw, "- **Source:** %s\n\n", b.Source)
+
+	fmt.Fprintf(bw, "## Contents\n\n| Section | Items | Location |\n|---|---|---|\n")
+	fmt.Fprintf(bw, "| Profile | 1 | [profile.json](profile.json) |\n")
+	sections := []struct {
+		title, location string
+		count           int
+	}{
+		{"Chats", "`chats/`", len(b.Chats)},
+		{"Memories", "[memories.json](memories.json)", len(b.Memories)},
+		{"Files", "`files/`", len(b.Files)},
+		{"Knowledge bases", "`knowledge/`", len(b.Knowledge)},
+		{"Prompts", "`prompts/`", len(b.Prompts)},
+		{"Models", "`models/`", len(b.Models)},
+		{"Tools", "`tools/`", len(b.Tools)},
+		{"Feedbacks", "[feedbacks.json](feedbacks.json)", len(b.Feedbacks)},
+	}
+	for _, s := range sections {
+		location := s.location
+		if s.count == 0 {
+			location = "-"
+		}
+		fmt.Fprintf(bw, "| %s | %d | %s |\n", s.title, s.count, location)
+	}
+
+	fmt.Fprintf(bw, "\n## Profile\n\n")
+	fmt.Fprintf(bw, "- **Name:** %s\n", u.Name)
+	fmt.Fprintf(bw, "- **Email:** %s\n", u.Email)
+	if u.Username != "" {
+		fmt.Fprintf(bw, "- **Username:** %s\n", u.Username)
+	}
+	fmt.Fprintf(bw, "- **User ID:** %s\n", u.ID)
+	fmt.Fprintf(bw, "- **Role:** %s\n", u.Role)
+	if u.CreatedAt > 0 {
+		fmt.Fprintf(bw, "- **Account created:** %s\n", formatTime(u.CreatedAt))
+	}
+	if u.LastActiveAt > 0 {
+		fmt.Fprintf(bw, "- **Last active:** %s\n", formatTime(u.LastActiveAt))
+	}
+
+	if len(b.Chats) > 0 {
+		fmt.Fprintf(bw, "\n## Chats\n\n")
+		for i := range b.Chats {
+			chat := &b.Chats[i]
+			title := chat.Title
+			if title == "" {
+				title = "Untitled chat"
+			}
+			fmt.Fprintf(bw, "- %s [%s](%s), %d messages\n", formatDate(chat.CreatedAt), escape(title), paths["chat/"+chat.ID], len(chat.Chat.Messages))
+		}
+	}
+
+	if len(b.Memories) > 0 {
+		fmt.Fprintf(bw, "\n## Memories\n\n")
+		for _, memory := range b.Memories {
+			fmt.Fprintf(bw, "- %s %s\n", formatDate(memory.CreatedAt), escape(oneLine(memory.Content)))
+		}
+	}
+
+	if len(b.Files) > 0 {
+		fmt.Fprintf(bw, "\n## Files\n\n")
+		for i := range b.Files {
+			file := &b.Files[i]
+			name := escape(fileName(file))
+			if p, ok := paths["file/"+file.ID]; ok {
+				name = fmt.Sprintf("[%s](%s)", name, p)
+			}
+			fmt.Fprintf(bw, "- %s %s", formatDate(file.CreatedAt), name)
+			if file.Meta.ContentType != "" {
+				fmt.Fprintf(bw, " (%s, %d bytes)", file.Meta.ContentType, file.Meta.Size)
+			}
+			fmt.Fprintln(bw)
+		}
+		fmt.Fprintf(bw, "\nFile contents are the text extracted from your uploads.\n")
+	}
+
+	if len(b.Knowledge) > 0 {
+		fmt.Fprintf(bw, "\n## Knowledge bases\n\n")
+		for _, kb := range b.Knowledge {
+			fmt.Fprintf(bw, "- [%s](%s)", escape(kb.Name), paths["knowledge/"+kb.ID])
+			if kb.Description != "" {
+				fmt.Fprintf(bw, ": %s", escape(oneLine(kb.Description)))
+			}
+			fmt.Fprintln(bw)
+		}
+	}
+
+	if len(b.Prompts) > 0 {
+		fmt.Fprintf(bw, "\n## Prompts\n\n")
+		for _, prompt := range b.Prompts {
+			fmt.Fprintf(bw, "- [`%s`](%s) %s\n", prompt.Command, paths["prompt/"+prompt.Command], escape(prompt.Title))
+		}
+	}
+
+	if len(b.Models) > 0 {
+		fmt.Fprintf(bw, "\n## Models\n\n")
+		for _, model := range b.Models {
+			fmt.Fprintf(bw, "- [%s](%s) (`%s`)\n", escape(model.Name), paths["model/"+model.ID], model.ID)
+		}
+	}
+
+	if len(b.Tools) > 0 {
+		fmt.Fprintf(bw, "\n## Tools\n\n")
+		for _, tool := range b.Tools {
+			fmt.Fprintf(bw, "- [%s](%s) (`%s`)\n", escape(tool.Name), paths["tool/"+tool.ID], tool.ID)
+		}
+	}
+
+	if len(b.Feedbacks) > 0 {
+		fmt.Fprintf(bw, "\n## Feedbacks\n\n")
+		for _, feedback := range b.Feedbacks {
+			fmt.Fprintf(bw, "- %s %s", formatDate(feedback.CreatedAt), feedbackLabel(&feedback))
+			if comment, _ := feedback.Data["comment"].(string); comment != "" {
+				fmt.Fprintf(bw, ": %s", escape(oneLine(comment)))
+			}
+			fmt.Fprintln(bw)
+		}
+	}
+
+	if len(b.Notes) > 0 {
+		fmt.Fprintf(bw, "\n## Notes\n\n")
+		for _, note := range b.Notes {
+			fmt.Fprintf(bw, "- %s\n", note)
+		}
+	}
+
+	fmt.Fprintf(bw, "\n## Formats\n\n")
+	fmt.Fprintf(bw, "Chats are provided as readable Markdown (`.md`) and as the original JSON records (`.json`). ")
+	fmt.Fprintf(bw, "All other records are JSON files as stored by Open WebUI. Account API keys are not included.\n")
+}
+
+// feedbackLabel describes a rating
+func feedbackLabel(feedback *openwebui.Feedback) string {
+	label := "Rating"
+	switch rating := feedback.Data["rating"].(type) {
+	case float64:
+		if rating > 0 {
+			label = "Positive rating"
+		} else if rating < 0 {
+			label = "Negative rating"
+		}
+	}
+	if model, _ := feedback.Data["model_id"].(string); model != "" {
+		label += " for " + model
+	}
+	return label
+}
+
+// displayName returns "Name <email>" or whichever part is known
+func displayName(u *openwebui.User) string {
+	switch {
+	case u.Name != "" && u.Email != "":
+		return fmt.Sprintf("%s <%s>", u.Name, u.Email)
+	case u.Name != "":
+		return u.Name
+	case u.Email != "":
+		return u.Email
+	}
+	return u.ID
+}
+
+// fileName returns the original name of an uploaded file
+func fileName(file *openwebui.FileExport) string {
+	if file.Meta.Name != "" {
+		return file.Meta.Name
+	}
+	if file.Filename != "" {
+		return file.Filename
+	}
+	return file.ID
+}
+
+// textName returns a safe file name for extracted text content
+func textName(name string) string {
+	name = safeName(path.Base(name))
+	switch strings.ToLower(path.Ext(name)) {
+	case ".txt", ".md", ".csv", ".json":
+		return name
+	}
+	return name + ".txt"
+}
+
+// safeName replaces characters that are not safe in file names
+func safeName(s string) string {
+	s = strings.Trim(unsafeChars.ReplaceAllString(s, "_"), "_.")
+	if s == "" {
+		return "unnamed"
+	}
+	return s
+}
+
+// oneLine collapses whitespace so text fits in a list item
+func oneLine(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if len([]rune(s)) > 200 {
+		s = string([]rune(s)[:200]) + "…"
+	}
+	return s
+}
+
+// escape keeps user text from being read as Markdown links or emphasis
+func escape(s string) string {
+	return strings.NewReplacer("[", "\\[", "]", "\\]", "*", "\\*", "_", "\\_", "`", "\\`").Replace(s)
+}
+
+// formatTime formats a Unix timestamp in UTC
+func formatTime(ts int64) string {
+	return time.Unix(ts, 0).UTC().Format("2006-01-02 15:04 UTC")
+}
+
+// formatDate formats a Unix timestamp as a date
+func formatDate(ts int64) string {
+	if ts == 0 {
+		return "(no date)"
+	}
+	return time.Unix(ts, 0).UTC().Format("2006-01-02")
+}
+
+// writeJSON adds an indented JSON file to the ZIP archive
+func writeJSON(w *zip.Writer, name string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", name, err)
+	}
+	return writeBytes(w, name, data)
+}
+
+// writeBytes adds a file to the ZIP archive
+func writeBytes(w *zip.Writer, name string, data []byte) error {
+	f, err := w.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: time.Now()})
+	if err != nil {
+		return fmt.Errorf("failed to create %s in zip: %w", name, err)
+	}
+	if _, err := f.Write(data); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return nil
+}
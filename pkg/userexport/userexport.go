@@ -0,0 +1,275 @@
+package userexport
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/vosiander/open-webui-backup/pkg/archive"
+	"github.com/vosiander/open-webui-backup/pkg/openwebui"
+)
+
+// Bundle is everything collected for one user
+type Bundle struct {
+	User      openwebui.User
+	Source    string // instance URL or backup file the data came from
+	CreatedAt time.Time
+	Chats     []openwebui.Chat
+	Memories  []openwebui.Memory
+	Files     []openwebui.FileExport
+	Knowledge []openwebui.KnowledgeBase
+	Prompts   []openwebui.Prompt
+	Models    []openwebui.Model
+	Tools     []openwebui.Tool
+	Feedbacks []openwebui.Feedback
+	Notes     []string // data that could not be collected, listed in the index
+}
+
+// FindUser selects a user by ID or email (case-insensitive)
+func FindUser(users []openwebui.User, selector string) (*openwebui.User, error) {
+	for i := range users {
+		if users[i].ID == selector || strings.EqualFold(users[i].Email, selector) {
+			return &users[i], nil
+		}
+	}
+	return nil, fmt.Errorf("user %q not found", selector)
+}
+
+// Collect gathers a user's data from the live instance (admin API key required)
+// Types that cannot be fetched are recorded in Notes instead of failing the export
+func Collect(client *openwebui.Client, selector string) (*Bundle, error) {
+	users, err := client.GetAllUsers()
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch users: %w", err)
+	}
+	user, err := FindUser(users, selector)
+	if err != nil {
+		return nil, err
+	}
+
+	b := &Bundle{User: *user, Source: client.GetBaseURL(), CreatedAt: time.Now().UTC()}
+	owned := func(userID string) bool { return userID == user.ID }
+
+	if chats, err := client.GetAllChatsDB(); err != nil {
+		b.note("chats", err)
+	} else {
+		for _, chat := range chats {
+			if owned(chat.UserID) {
+				b.Chats = append(b.Chats, chat)
+			}
+		}
+	}
+
+	// Memories are only readable by their owner
+	if current, err := client.GetCurrentUser(); err != nil {
+		b.note("memories", err)
+	} else if current.ID != user.ID {
+		b.Notes = append(b.Notes, "Memories are only readable with the user's own API key and are not included.")
+	} else if memories, err := client.ListMemories(); err != nil {
+		b.note("memories", err)
+	} else {
+		b.Memories = memories
+	}
+
+	if files, err := client.ListFiles(); err != nil {
+		b.note("files", err)
+	} else {
+		for _, file := range files {
+			if !owned(file.UserID) {
+				continue
+			}
+			export, err := client.GetFileWithContent(file.ID)
+			if err != nil {
+				b.note("file "+file.ID, err)
+				continue
+			}
+			b.Files = append(b.Files, *export)
+		}
+	}
+
+	if knowledge, err := client.ListKnowledge(); err != nil {
+		b.note("knowledge bases", err)
+	} else {
+		for _, kb := range knowledge {
+			if owned(kb.UserID) {
+				b.Knowledge = append(b.Knowledge, kb)
+			}
+		}
+	}
+
+	if prompts, err := client.ListPrompts(); err != nil {
+		b.note("prompts", err)
+	} else {
+		for _, prompt := range prompts {
+			if owned(prompt.UserID) {
+				b.Prompts = append(b.Prompts, prompt)
+			}
+		}
+	}
+
+	if models, err := client.ExportModels(); err != nil {
+		b.note("models", err)
+	} else {
+		for _, model := range models {
+			if owned(model.UserID) {
+				b.Models = append(b.Models, model)
+			}
+		}
+	}
+
+	if tools, err := client.ExportTools(); err != nil {
+		b.note("tools", err)
+	} else {
+		for _, tool := range tools {
+			if owned(tool.UserID) {
+				b.Tools = append(b.Tools, tool)
+			}
+		}
+	}
+
+	if feedbacks, err := client.GetAllFeedbacks(); err != nil {
+		b.note("feedbacks", err)
+	} else {
+		for _, feedback := range feedbacks {
+			if owned(feedback.UserID) {
+				b.Feedbacks = append(b.Feedbacks, feedback)
+			}
+		}
+	}
+
+	b.sort()
+	return b, nil
+}
+
+// FromArchive gathers a user's data from an unencrypted unified backup ZIP
+func FromArchive(zipPath, selector string) (*Bundle, error) {
+	a, err := archive.Load(zipPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var users []openwebui.User
+	if err := decodeAll(a, archive.TypeUser, &users); err != nil {
+		return nil, err
+	}
+	user, err := FindUser(users, selector)
+	if err != nil {
+		return nil, err
+	}
+
+	b := &Bundle{User: *user, Source: zipPath, CreatedAt: time.Now().UTC()}
+	if a.Metadata != nil && a.Metadata.BackupTimestamp != "" {
+		b.Source = fmt.Sprintf("%s (backup from %s)", zipPath, a.Metadata.BackupTimestamp)
+	}
+	b.Notes = append(b.Notes, "Memories are not part of backups and are not included.")
+
+	var chats []openwebui.Chat
+	var files []openwebui.FileExport
+	var knowledge []openwebui.KnowledgeBase
+	var prompts []openwebui.Prompt
+	var models []openwebui.Model
+	var tools []openwebui.Tool
+	var feedbacks []openwebui.Feedback
+	for entityType, target := range map[string]interface{}{
+		archive.TypeChat:      &chats,
+		archive.TypeFile:      &files,
+		archive.TypeKnowledge: &knowledge,
+		archive.TypePrompt:    &prompts,
+		archive.TypeModel:     &models,
+		archive.TypeTool:      &tools,
+		archive.TypeFeedback:  &feedbacks,
+	} {
+		if err := decodeAll(a, entityType, target); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, chat := range chats {
+		if chat.UserID == user.ID {
+			b.Chats = append(b.Chats, chat)
+		}
+	}
+	for _, file := range files {
+		if file.UserID == user.ID {
+			b.Files = append(b.Files, file)
+		}
+	}
+	for _, kb := range knowledge {
+		if kb.UserID == user.ID {
+			b.Knowledge = append(b.Knowledge, kb)
+		}
+	}
+	for _, prompt := range prompts {
+		if prompt.UserID == user.ID {
+			b.Prompts = append(b.Prompts, prompt)
+		}
+	}
+	for _, model := range models {
+		if model.UserID == user.ID {
+			b.Models = append(b.Models, model)
+		}
+	}
+	for _, tool := range tools {
+		if tool.UserID == user.ID {
+			b.Tools = append(b.Tools, tool)
+		}
+	}
+	for _, feedback := range feedbacks {
+		if feedback.UserID == user.ID {
+			b.Feedbacks = append(b.Feedbacks, feedback)
+		}
+	}
+
+	b.sort()
+	return b, nil
+}
+
+// Counts returns the number of items per section
+func (b *Bundle) Counts() map[string]int {
+	return map[string]int{
+		"chats":     len(b.Chats),
+		"memories":  len(b.Memories),
+		"files":     len(b.Files),
+		"knowledge": len(b.Knowledge),
+		"prompts":   len(b.Prompts),
+		"models":    len(b.Models),
+		"tools":     len(b.Tools),
+		"feedbacks": len(b.Feedbacks),
+	}
+}
+
+// note records a type that could not be collected
+func (b *Bundle) note(what string, err error) {
+	logrus.Warnf("Failed to collect %s: %v", what, err)
+	b.Notes = append(b.Notes, fmt.Sprintf("Failed to collect %s: %v", what, err))
+}
+
+// sort orders chats oldest first for the index
+func (b *Bundle) sort() {
+	sort.SliceStable(b.Chats, func(i, j int) bool {
+		return b.Chats[i].CreatedAt < b.Chats[j].CreatedAt
+	})
+}
+
+// decodeAll decodes every entity of a type into a slice
+func decodeAll(a *archive.Archive, entityType string, target interface{}) error {
+	var raw []json.RawMessage
+	for _, entity := range a.Entities[entityType] {
+		raw = append(raw, entity.Data)
+	}
+	if len(raw) == 0 {
+		return nil
+	}
+
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return fmt.Errorf("failed to read %s entities: %w", entityType, err)
+	}
+	if err := json.Unmarshal(data, target); err != nil {
+		return fmt.Errorf("failed to parse %s entities: %w", entityType, err)
+	}
+	return nil
+}
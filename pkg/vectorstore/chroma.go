@@ -0,0 +1,249 @@
+package vectorstore
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/vosiander/open-webui-backup/pkg/database"
+)
+
+// ChromaSQLiteFile is Chroma's catalog, documents and write-ahead queue
+const ChromaSQLiteFile = "chroma.sqlite3"
+
+// ChromaPrefix is the folder of the Chroma directory in a backup ZIP
+const ChromaPrefix = Prefix + "chroma/"
+
+// chromaStore is Open WebUI's default on-disk Chroma persistence directory
+type chromaStore struct {
+	path string
+}
+
+// chromaPath returns the Chroma directory from the flag, CHROMA_DATA_PATH or <data dir>/vector_db
+func chromaPath(explicit, dataDir string) (string, error) {
+	if explicit != "" {
+		return explicit, nil
+	}
+	if dataDir == "" {
+		if path := os.Getenv("CHROMA_DATA_PATH"); path != "" {
+			return path, nil
+		}
+		dataDir = database.GetDataDirFromEnv()
+	}
+	if dataDir == "" {
+		for _, dir := range database.DefaultDataDirs {
+			if info, err := os.Stat(dir); err == nil && info.IsDir() {
+				dataDir = dir
+				break
+			}
+		}
+	}
+	if dataDir == "" {
+		return "", fmt.Errorf("Chroma directory not found (use --chroma-path, --data-dir, CHROMA_DATA_PATH or OWUI_DATA_DIR)")
+	}
+	return filepath.Join(dataDir, "vector_db"), nil
+}
+
+func (s *chromaStore) Name() string {
+	return BackendChroma
+}
+
+func (s *chromaStore) String() string {
+	return "chroma:" + s.path
+}
+
+// Check verifies that the sqlite3 CLI is available
+func (s *chromaStore) Check() error {
+	return database.CheckSQLiteAvailable()
+}
+
+// Backup copies the HNSW segment files and then a consistent snapshot of chroma.sqlite3
+// Segments are copied first: Chroma replays newer entries of its queue in chroma.sqlite3
+// on startup, so the snapshot may be ahead of the segments but never behind them
+func (s *chromaStore) Backup(zw *zip.Writer, metadata *Metadata) error {
+	catalog := filepath.Join(s.path, ChromaSQLiteFile)
+	if _, err := os.Stat(catalog); err != nil {
+		return fmt.Errorf("Chroma database not found: %w", err)
+	}
+
+	logrus.Infof("Backing up Chroma directory '%s'...", s.path)
+
+	err := filepath.WalkDir(s.path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(s.path, p)
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(filepath.Base(rel), ChromaSQLiteFile) {
+			// chroma.sqlite3 and its -wal, -shm and -journal files are covered by the snapshot
+			return nil
+		}
+		size, err := addFile(zw, ChromaPrefix+filepath.ToSlash(rel), p)
+		if err != nil {
+			return err
+		}
+		metadata.Files++
+		metadata.Size += size
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to back up Chroma segments: %w", err)
+	}
+
+	tempDir, err := os.MkdirTemp("", "owui-chroma-")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(tempDir)
+	snapshot := filepath.Join(tempDir, ChromaSQLiteFile)
+
+	if err := database.SnapshotSQLiteTo(catalog, snapshot); err != nil {
+		return fmt.Errorf("failed to snapshot %s: %w", ChromaSQLiteFile, err)
+	}
+
+	output, err := database.RunSQLite(snapshot, "SELECT (SELECT count(*) FROM collections), (SELECT count(*) FROM embeddings);")
+	if err != nil {
+		return fmt.Errorf("failed to count Chroma collections: %w", err)
+	}
+	collections, embeddings, _ := strings.Cut(strings.TrimSpace(output), "|")
+	metadata.Collections, _ = strconv.Atoi(collections)
+	metadata.Embeddings, _ = strconv.ParseInt(embeddings, 10, 64)
+
+	size, err := addFile(zw, ChromaPrefix+ChromaSQLiteFile, snapshot)
+	if err != nil {
+		return fmt.Errorf("failed to add %s: %w", ChromaSQLiteFile, err)
+	}
+	metadata.Files++
+	metadata.Size += size
+
+	logrus.Infof("Chroma backup created: %d collections, %d embeddings, %d files (%d bytes)",
+		metadata.Collections, metadata.Embeddings, metadata.Files, metadata.Size)
+	return nil
+}
+
+// Restore replaces the Chroma directory with the one in the backup
+// The backup is extracted next to the directory and checked before the swap; the previous
+// directory is kept as <path>.pre-restore. Open WebUI must be stopped while restoring
+func (s *chromaStore) Restore(zr *zip.Reader, metadata *Metadata) error {
+	logrus.Infof("Restoring Chroma directory to '%s'...", s.path)
+
+	parent := filepath.Dir(s.path)
+	if err := os.MkdirAll(parent, 0755); err != nil {
+		return fmt.Errorf("failed to create data directory: %w", err)
+	}
+
+	tempPath := s.path + ".restore.tmp"
+	if err := os.RemoveAll(tempPath); err != nil {
+		return fmt.Errorf("failed to clean up %s: %w", tempPath, err)
+	}
+	defer os.RemoveAll(tempPath)
+
+	files := 0
+	for _, f := range zr.File {
+		if !strings.HasPrefix(f.Name, ChromaPrefix) || strings.HasSuffix(f.Name, "/") {
+			continue
+		}
+		rel := path.Clean(strings.TrimPrefix(f.Name, ChromaPrefix))
+		if rel == "." || rel == ".." || strings.HasPrefix(rel, "../") || path.IsAbs(rel) {
+			return fmt.Errorf("invalid entry in backup: %s", f.Name)
+		}
+		if err := extractFile(f, filepath.Join(tempPath, filepath.FromSlash(rel))); err != nil {
+			return err
+		}
+		files++
+	}
+	if files == 0 {
+		return fmt.Errorf("no Chroma files found in backup")
+	}
+
+	if err := database.CheckSQLiteIntegrity(filepath.Join(tempPath, ChromaSQLiteFile)); err != nil {
+		return fmt.Errorf("%s failed integrity check: %w", ChromaSQLiteFile, err)
+	}
+
+	if _, err := os.Stat(s.path); err == nil {
+		previous := s.path + ".pre-restore"
+		if err := os.RemoveAll(previous); err != nil {
+			return fmt.Errorf("failed to remove old %s: %w", previous, err)
+		}
+		if err := os.Rename(s.path, previous); err != nil {
+			return fmt.Errorf("failed to move current Chroma directory aside: %w", err)
+		}
+		logrus.Infof("Previous Chroma directory kept as %s", previous)
+	}
+
+	if err := os.Rename(tempPath, s.path); err != nil {
+		return fmt.Errorf("failed to move restored Chroma directory into place: %w", err)
+	}
+
+	logrus.Infof("Chroma directory restored: %d collections, %d embeddings, %d files",
+		metadata.Collections, metadata.Embeddings, files)
+	return nil
+}
+
+// addFile stores a file in the ZIP with its modification time and returns its size
+func addFile(zw *zip.Writer, name, src string) (int64, error) {
+	f, err := os.Open(src)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open %s: %w", src, err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return 0, fmt.Errorf("failed to stat %s: %w", src, err)
+	}
+	header, err := zip.FileInfoHeader(info)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create header for %s: %w", src, err)
+	}
+	header.Name = name
+	header.Method = zip.Deflate
+
+	w, err := zw.CreateHeader(header)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create %s in zip: %w", name, err)
+	}
+	n, err := io.Copy(w, f)
+	if err != nil {
+		return n, fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return n, nil
+}
+
+// extractFile writes a ZIP entry to dst, creating parent directories
+func extractFile(f *zip.File, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", f.Name, err)
+	}
+
+	rc, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", f.Name, err)
+	}
+	defer rc.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", dst, err)
+	}
+	if _, err := io.Copy(out, rc); err != nil {
+		out.Close()
+		return fmt.Errorf("failed to extract %s: %w", f.Name, err)
+	}
+	if err := out.Close(); err != nil {
+		return fmt.Errorf("failed to extract %s: %w", f.Name, err)
+	}
+	return os.Chtimes(dst, f.Modified, f.Modified)
+}
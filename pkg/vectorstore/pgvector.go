@@ -0,0 +1,212 @@
+package vectorstore
+
+import (
+	"archive/zip"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/vosiander/open-webui-backup/pkg/database"
+)
+
+// pgvectorTable is the table Open WebUI stores pgvector embeddings in
+const pgvectorTable = "public.document_chunk"
+
+// PgvectorEntry holds the table rows in COPY text format
+const PgvectorEntry = Prefix + "pgvector/document_chunk.copy"
+
+// pgvectorStore is the document_chunk table of a PostgreSQL database with the vector extension
+// It always uses the native client so rows are streamed with COPY and no binaries are needed
+type pgvectorStore struct {
+	config *database.DatabaseConfig
+}
+
+func (s *pgvectorStore) Name() string {
+	return BackendPgvector
+}
+
+func (s *pgvectorStore) String() string {
+	return "pgvector:" + database.FormatConnectionInfo(s.config)
+}
+
+// Check connects to the database
+func (s *pgvectorStore) Check() error {
+	session, err := database.OpenSession(s.config)
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+	if err := session.Exec("SELECT 1"); err != nil {
+		return fmt.Errorf("database connection test failed: %w", err)
+	}
+	return nil
+}
+
+// Backup copies document_chunk inside a REPEATABLE READ transaction
+func (s *pgvectorStore) Backup(zw *zip.Writer, metadata *Metadata) error {
+	session, err := database.OpenSession(s.config)
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	logrus.Infof("Backing up pgvector table %s...", pgvectorTable)
+
+	if err := session.Exec("BEGIN ISOLATION LEVEL REPEATABLE READ, READ ONLY"); err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer session.Exec("ROLLBACK")
+
+	exists, err := session.Query(fmt.Sprintf("SELECT to_regclass('%s') IS NOT NULL", pgvectorTable))
+	if err != nil {
+		return fmt.Errorf("failed to look up %s: %w", pgvectorTable, err)
+	}
+	if len(exists) == 0 || exists[0][0] != "t" {
+		return fmt.Errorf("table %s not found; is Open WebUI configured with VECTOR_DB=pgvector?", pgvectorTable)
+	}
+
+	columns, err := session.Query(fmt.Sprintf(`SELECT attname, format_type(atttypid, atttypmod), attnotnull
+FROM pg_attribute WHERE attrelid = '%s'::regclass AND attnum > 0 AND NOT attisdropped ORDER BY attnum`, pgvectorTable))
+	if err != nil {
+		return fmt.Errorf("failed to read columns of %s: %w", pgvectorTable, err)
+	}
+	for _, row := range columns {
+		metadata.Columns = append(metadata.Columns, Column{Name: row[0], Type: row[1], NotNull: row[2] == "t"})
+	}
+
+	counts, err := session.Query(fmt.Sprintf("SELECT count(*), count(DISTINCT collection_name) FROM %s", pgvectorTable))
+	if err != nil {
+		return fmt.Errorf("failed to count embeddings: %w", err)
+	}
+	if len(counts) > 0 {
+		metadata.Embeddings, _ = strconv.ParseInt(counts[0][0], 10, 64)
+		metadata.Collections, _ = strconv.Atoi(counts[0][1])
+	}
+
+	w, err := zw.Create(PgvectorEntry)
+	if err != nil {
+		return fmt.Errorf("failed to create %s in zip: %w", PgvectorEntry, err)
+	}
+	cw := &countingWriter{w: w}
+	if _, err := session.CopyOut(fmt.Sprintf("COPY %s (%s) TO STDOUT", pgvectorTable, columnList(metadata.Columns)), cw); err != nil {
+		return fmt.Errorf("failed to copy %s: %w", pgvectorTable, err)
+	}
+	metadata.Files = 1
+	metadata.Size = cw.n
+
+	logrus.Infof("pgvector backup created: %d collections, %d embeddings (%d bytes)",
+		metadata.Collections, metadata.Embeddings, metadata.Size)
+	return nil
+}
+
+// Restore loads the backed up rows into document_chunk in one transaction
+// Collections in the backup replace their current rows; other collections are kept.
+// The vector extension and the table are created if they do not exist yet
+func (s *pgvectorStore) Restore(zr *zip.Reader, metadata *Metadata) error {
+	var entry *zip.File
+	for _, f := range zr.File {
+		if f.Name == PgvectorEntry {
+			entry = f
+			break
+		}
+	}
+	if entry == nil {
+		return fmt.Errorf("%s not found in backup", PgvectorEntry)
+	}
+	if len(metadata.Columns) == 0 {
+		return fmt.Errorf("pgvector backup has no column information")
+	}
+
+	session, err := database.OpenSession(s.config)
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	logrus.Infof("Restoring pgvector table %s...", pgvectorTable)
+
+	exists, err := session.Query(fmt.Sprintf("SELECT to_regclass('%s') IS NOT NULL", pgvectorTable))
+	if err != nil {
+		return fmt.Errorf("failed to look up %s: %w", pgvectorTable, err)
+	}
+	if len(exists) == 0 || exists[0][0] != "t" {
+		logrus.Infof("Creating %s", pgvectorTable)
+		if err := session.Exec("CREATE EXTENSION IF NOT EXISTS vector"); err != nil {
+			return fmt.Errorf("failed to create vector extension: %w", err)
+		}
+		if err := session.Exec(createTableSQL(metadata.Columns)); err != nil {
+			return fmt.Errorf("failed to create %s: %w", pgvectorTable, err)
+		}
+	}
+
+	rc, err := entry.Open()
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", PgvectorEntry, err)
+	}
+	defer rc.Close()
+
+	columns := columnList(metadata.Columns)
+	statements := []string{
+		"BEGIN",
+		fmt.Sprintf("CREATE TEMP TABLE owui_restore_chunk (LIKE %s) ON COMMIT DROP", pgvectorTable),
+	}
+	for _, sql := range statements {
+		if err := session.Exec(sql); err != nil {
+			session.Exec("ROLLBACK")
+			return fmt.Errorf("failed to prepare restore: %w", err)
+		}
+	}
+
+	rows, err := session.CopyIn(fmt.Sprintf("COPY owui_restore_chunk (%s) FROM STDIN", columns), rc)
+	if err != nil {
+		session.Exec("ROLLBACK")
+		return fmt.Errorf("failed to load embeddings: %w", err)
+	}
+
+	statements = []string{
+		fmt.Sprintf("DELETE FROM %s d USING (SELECT DISTINCT collection_name FROM owui_restore_chunk) r WHERE d.collection_name = r.collection_name", pgvectorTable),
+		fmt.Sprintf("DELETE FROM %s WHERE id IN (SELECT id FROM owui_restore_chunk)", pgvectorTable),
+		fmt.Sprintf("INSERT INTO %s (%s) SELECT %s FROM owui_restore_chunk", pgvectorTable, columns, columns),
+		"COMMIT",
+	}
+	for _, sql := range statements {
+		if err := session.Exec(sql); err != nil {
+			session.Exec("ROLLBACK")
+			return fmt.Errorf("failed to restore embeddings: %w", err)
+		}
+	}
+
+	logrus.Infof("pgvector table restored: %d collections, %d embeddings", metadata.Collections, rows)
+	return nil
+}
+
+// columnList returns the quoted column names separated by commas
+func columnList(columns []Column) string {
+	names := make([]string, len(columns))
+	for i, c := range columns {
+		names[i] = quoteIdent(c.Name)
+	}
+	return strings.Join(names, ", ")
+}
+
+// createTableSQL recreates document_chunk from the backed up column definitions
+func createTableSQL(columns []Column) string {
+	defs := make([]string, 0, len(columns))
+	for _, c := range columns {
+		def := quoteIdent(c.Name) + " " + c.Type
+		if c.NotNull {
+			def += " NOT NULL"
+		}
+		if c.Name == "id" {
+			def += " PRIMARY KEY"
+		}
+		defs = append(defs, def)
+	}
+	return fmt.Sprintf("CREATE TABLE %s (%s)", pgvectorTable, strings.Join(defs, ", "))
+}
+
+// quoteIdent quotes a PostgreSQL identifier
+func quoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
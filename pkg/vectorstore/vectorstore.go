@@ -0,0 +1,209 @@
+package vectorstore
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/vosiander/open-webui-backup/pkg/database"
+)
+
+// Vector store backends, named like Open WebUI's VECTOR_DB setting
+const (
+	BackendChroma   = "chroma"
+	BackendPgvector = "pgvector"
+)
+
+// Backends lists the supported vector store backends
+var Backends = []string{BackendChroma, BackendPgvector}
+
+// Prefix is the folder of the vector store section in a backup ZIP
+const Prefix = "vector_db/"
+
+// MetadataEntry describes the vector store section of a backup ZIP
+const MetadataEntry = Prefix + "metadata.json"
+
+// Metadata tracks vector store backup information
+type Metadata struct {
+	BackupTimestamp string   `json:"backup_timestamp"`
+	Backend         string   `json:"backend"`
+	Collections     int      `json:"collections"`
+	Embeddings      int64    `json:"embeddings"`
+	Files           int      `json:"files,omitempty"`
+	Columns         []Column `json:"columns,omitempty"`
+	Size            int64    `json:"size"`
+}
+
+// Column is a column of the pgvector table
+type Column struct {
+	Name    string `json:"name"`
+	Type    string `json:"type"`
+	NotNull bool   `json:"not_null,omitempty"`
+}
+
+// Store is a vector store that can be backed up into and restored from a backup ZIP
+type Store interface {
+	// Name is the backend name
+	Name() string
+	// String describes the store for logging
+	String() string
+	// Check verifies that the store can be read or written
+	Check() error
+	// Backup writes the embeddings below Prefix and fills in the metadata
+	Backup(zw *zip.Writer, metadata *Metadata) error
+	// Restore puts the embeddings from the backup back into the store
+	Restore(zr *zip.Reader, metadata *Metadata) error
+}
+
+// Options select the vector store; empty fields fall back to the environment
+type Options struct {
+	Backend     string // chroma or pgvector (VECTOR_DB)
+	ChromaPath  string // Chroma persistence directory (CHROMA_DATA_PATH)
+	DataDir     string // Open WebUI data directory, holds vector_db/ for Chroma
+	PgvectorURL string // PostgreSQL URL of the pgvector database (PGVECTOR_DB_URL)
+}
+
+// ValidateBackend checks that a backend name is supported
+func ValidateBackend(name string) error {
+	for _, b := range Backends {
+		if name == b {
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported vector store %q (supported: %s)", name, strings.Join(Backends, ", "))
+}
+
+// New resolves the vector store from options and environment
+// The backend is taken from Options, VECTOR_DB, or pgvector if a pgvector URL is set, and Chroma otherwise
+func New(opts *Options) (Store, error) {
+	backend := opts.Backend
+	if backend == "" {
+		backend = os.Getenv("VECTOR_DB")
+	}
+	pgvectorURL := opts.PgvectorURL
+	if pgvectorURL == "" {
+		pgvectorURL = pgvectorURLFromEnv()
+	}
+	if backend == "" {
+		backend = BackendChroma
+		if opts.PgvectorURL != "" || os.Getenv("PGVECTOR_DB_URL") != "" {
+			backend = BackendPgvector
+		}
+	}
+	if err := ValidateBackend(backend); err != nil {
+		return nil, err
+	}
+
+	if backend == BackendPgvector {
+		if pgvectorURL == "" {
+			return nil, fmt.Errorf("pgvector connection URL is required (use --pgvector-url, PGVECTOR_DB_URL, DATABASE_URL or POSTGRES_URL)")
+		}
+		config, err := database.ParsePostgresURL(pgvectorURL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse pgvector URL: %w", err)
+		}
+		return &pgvectorStore{config: config}, nil
+	}
+
+	path, err := chromaPath(opts.ChromaPath, opts.DataDir)
+	if err != nil {
+		return nil, err
+	}
+	return &chromaStore{path: path}, nil
+}
+
+// IsConfigured reports whether a vector store is configured through the environment
+// pgvector needs VECTOR_DB=pgvector; Chroma needs an existing directory from CHROMA_DATA_PATH or the data directory
+func IsConfigured() bool {
+	switch os.Getenv("VECTOR_DB") {
+	case BackendPgvector:
+		return pgvectorURLFromEnv() != ""
+	case "", BackendChroma:
+		if os.Getenv("CHROMA_DATA_PATH") == "" && database.GetDataDirFromEnv() == "" {
+			return false
+		}
+		path, err := chromaPath("", "")
+		if err != nil {
+			return false
+		}
+		_, err = os.Stat(filepath.Join(path, ChromaSQLiteFile))
+		return err == nil
+	}
+	return false
+}
+
+// pgvectorURLFromEnv returns PGVECTOR_DB_URL, a PostgreSQL DATABASE_URL or POSTGRES_URL, as Open WebUI does
+func pgvectorURLFromEnv() string {
+	if url := os.Getenv("PGVECTOR_DB_URL"); url != "" {
+		return url
+	}
+	if url := os.Getenv("DATABASE_URL"); strings.HasPrefix(url, "postgres") {
+		return url
+	}
+	return database.GetPostgresURLFromEnv()
+}
+
+// Backup writes the store and its metadata to the ZIP writer
+func Backup(store Store, zw *zip.Writer) (*Metadata, error) {
+	metadata := &Metadata{
+		BackupTimestamp: time.Now().UTC().Format(time.RFC3339),
+		Backend:         store.Name(),
+	}
+	if err := store.Backup(zw, metadata); err != nil {
+		return nil, err
+	}
+
+	data, err := json.MarshalIndent(metadata, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal vector store metadata: %w", err)
+	}
+	w, err := zw.Create(MetadataEntry)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s in zip: %w", MetadataEntry, err)
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, fmt.Errorf("failed to write %s: %w", MetadataEntry, err)
+	}
+	return metadata, nil
+}
+
+// ReadMetadata returns the vector store metadata of a backup ZIP, or nil if it has no vector store section
+func ReadMetadata(zr *zip.Reader) (*Metadata, error) {
+	for _, f := range zr.File {
+		if f.Name != MetadataEntry {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("failed to open %s: %w", MetadataEntry, err)
+		}
+		defer rc.Close()
+		data, err := io.ReadAll(rc)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", MetadataEntry, err)
+		}
+		metadata := &Metadata{}
+		if err := json.Unmarshal(data, metadata); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", MetadataEntry, err)
+		}
+		return metadata, nil
+	}
+	return nil, nil
+}
+
+// countingWriter counts the bytes written through it
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,292 @@
+package wal
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/vosiander/open-webui-backup/pkg/database"
+	"github.com/vosiander/open-webui-backup/pkg/encryption"
+)
+
+// BaseInfoFile is the unencrypted description of a base backup
+// It holds no data, only what is needed to pick a base backup for a recovery target
+const BaseInfoFile = "backup.json"
+
+// Files written by pg_basebackup in tar format
+const (
+	baseTar    = "base.tar.gz"
+	walTar     = "pg_wal.tar.gz"
+	manifest   = "backup_manifest"
+	encSuffix  = ".age"
+	labelEntry = "backup_label"
+)
+
+// BaseBackup describes a base backup in the store
+type BaseBackup struct {
+	ID              string   `json:"id"`
+	Label           string   `json:"label"`
+	StartTime       string   `json:"start_time"`
+	StopTime        string   `json:"stop_time"`
+	StartWAL        string   `json:"start_wal"`
+	StartLSN        string   `json:"start_lsn"`
+	Timeline        int      `json:"timeline"`
+	PostgresVersion string   `json:"postgres_version,omitempty"`
+	Files           []string `json:"files"`
+	Size            int64    `json:"size"`
+}
+
+// Stopped returns the time the base backup finished
+// Recovery can only stop at targets after it, because the copy is inconsistent until then
+func (b *BaseBackup) Stopped() time.Time {
+	t, _ := time.Parse(time.RFC3339, b.StopTime)
+	return t
+}
+
+// CreateBase takes a base backup with pg_basebackup and stores its files encrypted
+func (s *Store) CreateBase(config *database.DatabaseConfig, opts *encryption.EncryptOptions, verbose bool) (*BaseBackup, error) {
+	id := time.Now().UTC().Format("20060102-150405")
+	dir := filepath.Join(s.Root, BaseDir, id)
+	if _, err := os.Stat(dir); err == nil {
+		return nil, fmt.Errorf("base backup %s already exists", id)
+	}
+	if err := os.MkdirAll(filepath.Dir(dir), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create base backup directory: %w", err)
+	}
+
+	// pg_basebackup writes plaintext, so it goes to a private directory that is removed afterwards
+	raw, err := os.MkdirTemp(filepath.Dir(dir), "."+id+".*.tmp")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(raw)
+	// Docker mounts need an absolute path
+	if raw, err = filepath.Abs(raw); err != nil {
+		return nil, fmt.Errorf("failed to resolve temporary directory: %w", err)
+	}
+
+	label := "owuicli " + id
+	if err := database.CreateBaseBackup(config, raw, &database.BaseBackupOptions{Label: label, Verbose: verbose}); err != nil {
+		return nil, err
+	}
+	stopped := time.Now().UTC()
+
+	base := &BaseBackup{ID: id, Label: label, StopTime: stopped.Format(time.RFC3339)}
+	if err := readBackupLabel(filepath.Join(raw, baseTar), base); err != nil {
+		return nil, err
+	}
+	if version, err := database.GetPostgresVersion(config); err == nil {
+		base.PostgresVersion = database.ExtractMajorVersion(version)
+	}
+
+	entries, err := os.ReadDir(raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read base backup: %w", err)
+	}
+	encrypted, err := os.MkdirTemp(filepath.Dir(dir), "."+id+".*.enc")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(encrypted)
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		if strings.HasSuffix(entry.Name(), ".tar.gz") && entry.Name() != baseTar && entry.Name() != walTar {
+			logrus.Warnf("⚠️  Base backup contains tablespace %s; restores only recreate the default tablespace", entry.Name())
+		}
+		size, err := encryptFile(filepath.Join(raw, entry.Name()), filepath.Join(encrypted, entry.Name()+encSuffix), opts)
+		if err != nil {
+			return nil, err
+		}
+		base.Files = append(base.Files, entry.Name())
+		base.Size += size
+	}
+	sort.Strings(base.Files)
+
+	data, err := json.MarshalIndent(base, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal base backup info: %w", err)
+	}
+	if err := writeFileSync(filepath.Join(encrypted, BaseInfoFile), data); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(encrypted, dir); err != nil {
+		return nil, fmt.Errorf("failed to store base backup: %w", err)
+	}
+	return base, syncDir(filepath.Dir(dir))
+}
+
+// Bases lists the base backups in the store, oldest first
+func (s *Store) Bases() ([]*BaseBackup, error) {
+	entries, err := os.ReadDir(filepath.Join(s.Root, BaseDir))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read base backup directory: %w", err)
+	}
+
+	var bases []*BaseBackup
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(s.Root, BaseDir, entry.Name(), BaseInfoFile))
+		if err != nil {
+			logrus.Debugf("Skipping %s: %v", entry.Name(), err)
+			continue
+		}
+		base := &BaseBackup{}
+		if err := json.Unmarshal(data, base); err != nil {
+			return nil, fmt.Errorf("failed to parse %s of base backup %s: %w", BaseInfoFile, entry.Name(), err)
+		}
+		bases = append(bases, base)
+	}
+	sort.Slice(bases, func(i, j int) bool { return bases[i].StopTime < bases[j].StopTime })
+	return bases, nil
+}
+
+// Base returns the base backup with the given ID
+func (s *Store) Base(id string) (*BaseBackup, error) {
+	bases, err := s.Bases()
+	if err != nil {
+		return nil, err
+	}
+	for _, base := range bases {
+		if base.ID == id {
+			return base, nil
+		}
+	}
+	return nil, fmt.Errorf("base backup %s not found in %s", id, filepath.Join(s.Root, BaseDir))
+}
+
+// BaseFor returns the newest base backup that finished before target
+func (s *Store) BaseFor(target time.Time) (*BaseBackup, error) {
+	bases, err := s.Bases()
+	if err != nil {
+		return nil, err
+	}
+	if len(bases) == 0 {
+		return nil, fmt.Errorf("no base backups in %s (run base-backup first)", filepath.Join(s.Root, BaseDir))
+	}
+	for i := len(bases) - 1; i >= 0; i-- {
+		if !bases[i].Stopped().After(target) {
+			return bases[i], nil
+		}
+	}
+	return nil, fmt.Errorf("no base backup finished before %s; the oldest finished at %s", target.UTC().Format(time.RFC3339), bases[0].StopTime)
+}
+
+// readBackupLabel fills the start position and time from backup_label in base.tar.gz
+func readBackupLabel(path string, base *BaseBackup) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", baseTar, err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", baseTar, err)
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return fmt.Errorf("%s not found in %s", labelEntry, baseTar)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", baseTar, err)
+		}
+		if header.Name == labelEntry || header.Name == "./"+labelEntry {
+			return parseBackupLabel(tr, base)
+		}
+	}
+}
+
+// parseBackupLabel reads the fields of a backup_label file:
+//
+//	START WAL LOCATION: 0/2000028 (file 000000010000000000000002)
+//	START TIME: 2024-05-01 12:00:00 UTC
+//	START TIMELINE: 1
+func parseBackupLabel(r io.Reader, base *BaseBackup) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ": ")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "START WAL LOCATION":
+			fields := strings.Fields(value)
+			if len(fields) >= 3 {
+				base.StartLSN = fields[0]
+				base.StartWAL = strings.TrimSuffix(fields[2], ")")
+			}
+		case "START TIME":
+			// The zone is the server's log_timezone abbreviation, so the start time is informational;
+			// base backups are picked by StopTime, which is taken from the local clock
+			if t, err := time.Parse("2006-01-02 15:04:05 MST", value); err == nil {
+				base.StartTime = t.UTC().Format(time.RFC3339)
+			} else {
+				logrus.Debugf("Failed to parse backup start time %q: %v", value, err)
+			}
+		case "START TIMELINE":
+			base.Timeline, _ = strconv.Atoi(value)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read %s: %w", labelEntry, err)
+	}
+	if base.StartWAL == "" {
+		return fmt.Errorf("%s has no start WAL location", labelEntry)
+	}
+	return nil
+}
+
+// encryptFile encrypts src into dst and returns the encrypted size
+func encryptFile(src, dst string, opts *encryption.EncryptOptions) (int64, error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open %s: %w", src, err)
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create %s: %w", dst, err)
+	}
+	defer out.Close()
+
+	ew, err := encryption.NewEncryptWriter(out, opts)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := io.Copy(ew, in); err != nil {
+		return 0, fmt.Errorf("failed to encrypt %s: %w", filepath.Base(src), err)
+	}
+	if err := ew.Close(); err != nil {
+		return 0, err
+	}
+	if err := out.Sync(); err != nil {
+		return 0, fmt.Errorf("failed to sync %s: %w", dst, err)
+	}
+	info, err := out.Stat()
+	if err != nil {
+		return 0, fmt.Errorf("failed to stat %s: %w", dst, err)
+	}
+	return info.Size(), nil
+}
@@ -0,0 +1,255 @@
+package wal
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/vosiander/open-webui-backup/pkg/encryption"
+)
+
+// RecoveryOptions configures a point-in-time recovery
+type RecoveryOptions struct {
+	DataDir        string    // new PostgreSQL data directory, must be missing or empty
+	TargetTime     time.Time // recovery_target_time
+	RestoreCommand string    // restore_command that fetches WAL from this store
+}
+
+// recoveryMarker starts the settings block written into the restored configuration
+const recoveryMarker = "# Point-in-time recovery settings written by owuicli restore-database --to-time"
+
+// PrepareRecovery unpacks a base backup into a new data directory and configures it to replay the
+// archived WAL up to the target time. Starting PostgreSQL on the directory performs the recovery
+// and promotes the server once the target is reached
+func (s *Store) PrepareRecovery(base *BaseBackup, opts *RecoveryOptions, decrypt *encryption.DecryptOptions) error {
+	if err := checkEmptyDir(opts.DataDir); err != nil {
+		return err
+	}
+	// PostgreSQL refuses to start on a data directory that others can read
+	if err := os.MkdirAll(opts.DataDir, 0700); err != nil {
+		return fmt.Errorf("failed to create data directory: %w", err)
+	}
+	if err := os.Chmod(opts.DataDir, 0700); err != nil {
+		return fmt.Errorf("failed to set data directory permissions: %w", err)
+	}
+
+	dir := filepath.Join(s.Root, BaseDir, base.ID)
+	for _, name := range base.Files {
+		var target string
+		switch name {
+		case baseTar:
+			target = opts.DataDir
+		case walTar:
+			target = filepath.Join(opts.DataDir, "pg_wal")
+		case manifest:
+			continue
+		default:
+			logrus.Warnf("⚠️  Skipping tablespace %s; recreate it before starting PostgreSQL", name)
+			continue
+		}
+		logrus.Infof("Extracting %s...", name)
+		if err := extractEncrypted(filepath.Join(dir, name+encSuffix), target, decrypt); err != nil {
+			return fmt.Errorf("failed to extract %s: %w", name, err)
+		}
+	}
+
+	return writeRecoveryConfig(opts)
+}
+
+// writeRecoveryConfig adds the recovery settings for the restored server version
+// PostgreSQL 12 and newer read them from postgresql.auto.conf once recovery.signal exists;
+// older versions read recovery.conf
+func writeRecoveryConfig(opts *RecoveryOptions) error {
+	settings := []string{
+		recoveryMarker,
+		"restore_command = " + quoteSetting(opts.RestoreCommand),
+		"recovery_target_time = " + quoteSetting(opts.TargetTime.UTC().Format("2006-01-02 15:04:05.999999-07")),
+		"recovery_target_action = 'promote'",
+	}
+
+	major, err := dataDirVersion(opts.DataDir)
+	if err != nil {
+		return err
+	}
+	if major < 12 {
+		return writeFileSync(filepath.Join(opts.DataDir, "recovery.conf"), []byte(strings.Join(settings, "\n")+"\n"))
+	}
+
+	confPath := filepath.Join(opts.DataDir, "postgresql.auto.conf")
+	existing, err := os.ReadFile(confPath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to read postgresql.auto.conf: %w", err)
+	}
+	// Later lines win, so the recovery settings override any left in the base backup
+	conf := string(existing)
+	if conf != "" && !strings.HasSuffix(conf, "\n") {
+		conf += "\n"
+	}
+	conf += strings.Join(settings, "\n") + "\n"
+	if err := writeFileSync(confPath, []byte(conf)); err != nil {
+		return err
+	}
+	return writeFileSync(filepath.Join(opts.DataDir, "recovery.signal"), nil)
+}
+
+// dataDirVersion returns the major version from PG_VERSION
+func dataDirVersion(dataDir string) (int, error) {
+	data, err := os.ReadFile(filepath.Join(dataDir, "PG_VERSION"))
+	if err != nil {
+		return 0, fmt.Errorf("failed to read PG_VERSION of restored data directory: %w", err)
+	}
+	version := strings.TrimSpace(string(data))
+	// 9.6 and older have a two-part major version
+	major, err := strconv.Atoi(strings.SplitN(version, ".", 2)[0])
+	if err != nil {
+		return 0, fmt.Errorf("invalid PG_VERSION %q", version)
+	}
+	return major, nil
+}
+
+// quoteSetting quotes a value for postgresql.conf
+func quoteSetting(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
+
+// checkEmptyDir fails unless dir is missing or empty
+func checkEmptyDir(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", dir, err)
+	}
+	if len(entries) > 0 {
+		return fmt.Errorf("data directory %s is not empty", dir)
+	}
+	return nil
+}
+
+// extractEncrypted unpacks an encrypted tar.gz into dir
+func extractEncrypted(path, dir string, decrypt *encryption.DecryptOptions) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer f.Close()
+
+	r, err := encryption.NewDecryptReader(f, decrypt)
+	if err != nil {
+		return err
+	}
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return fmt.Errorf("failed to decompress: %w", err)
+	}
+	defer gz.Close()
+	return extractTar(tar.NewReader(gz), dir)
+}
+
+// extractTar writes the entries of a pg_basebackup tar below dir with their modes
+func extractTar(tr *tar.Reader, dir string) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read archive: %w", err)
+		}
+
+		name := filepath.Clean(filepath.FromSlash(header.Name))
+		if name == "." {
+			continue
+		}
+		if filepath.IsAbs(name) || name == ".." || strings.HasPrefix(name, ".."+string(filepath.Separator)) {
+			return fmt.Errorf("archive entry %q is outside the data directory", header.Name)
+		}
+		target := filepath.Join(dir, name)
+		mode := os.FileMode(header.Mode).Perm()
+		// Never write through a symlink, even one created earlier from this archive
+		if err := checkNoSymlinks(dir, name); err != nil {
+			return err
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0700); err != nil {
+				return fmt.Errorf("failed to create %s: %w", target, err)
+			}
+			if err := os.Chmod(target, mode); err != nil {
+				return fmt.Errorf("failed to set mode of %s: %w", target, err)
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0700); err != nil {
+				return fmt.Errorf("failed to create %s: %w", filepath.Dir(target), err)
+			}
+			out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+			if err != nil {
+				return fmt.Errorf("failed to create %s: %w", target, err)
+			}
+			if _, err := io.Copy(out, tr); err != nil {
+				out.Close()
+				return fmt.Errorf("failed to write %s: %w", target, err)
+			}
+			if err := out.Close(); err != nil {
+				return fmt.Errorf("failed to write %s: %w", target, err)
+			}
+		case tar.TypeSymlink:
+			// pg_wal or tablespace links; recreated as they were if they stay in the data directory
+			if err := checkLinkTarget(name, header.Linkname); err != nil {
+				return err
+			}
+			if err := os.MkdirAll(filepath.Dir(target), 0700); err != nil {
+				return fmt.Errorf("failed to create %s: %w", filepath.Dir(target), err)
+			}
+			if err := os.Symlink(header.Linkname, target); err != nil {
+				return fmt.Errorf("failed to create symlink %s: %w", target, err)
+			}
+		default:
+			logrus.Debugf("Skipping %s (type %c)", header.Name, header.Typeflag)
+		}
+	}
+}
+
+// checkNoSymlinks fails if name or one of its parents below dir is a symlink
+func checkNoSymlinks(dir, name string) error {
+	path := dir
+	for _, part := range strings.Split(name, string(filepath.Separator)) {
+		path = filepath.Join(path, part)
+		info, err := os.Lstat(path)
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to check %s: %w", path, err)
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			return fmt.Errorf("archive entry %s would be written through the symlink %s", name, path)
+		}
+	}
+	return nil
+}
+
+// checkLinkTarget fails if a symlink at name would point outside the data directory
+func checkLinkTarget(name, linkname string) error {
+	if linkname == "" || filepath.IsAbs(linkname) {
+		return fmt.Errorf("symlink %s points outside the data directory: %q", name, linkname)
+	}
+	resolved := filepath.Clean(filepath.Join(filepath.Dir(name), filepath.FromSlash(linkname)))
+	if resolved == ".." || strings.HasPrefix(resolved, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("symlink %s points outside the data directory: %q", name, linkname)
+	}
+	return nil
+}
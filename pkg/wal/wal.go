@@ -0,0 +1,282 @@
+package wal
+
+import (
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/vosiander/open-webui-backup/pkg/encryption"
+)
+
+// Directories of a WAL store below the backup directory
+const (
+	SegmentDir = "wal"
+	BaseDir    = "base"
+)
+
+// segmentSuffix is the extension of an archived file: gzip compressed, then age encrypted
+const segmentSuffix = ".gz.age"
+
+// checksumSuffix is the extension of the plaintext SHA-256 kept next to each archived file
+// It lets a repeated archive_command call recognize a file it already archived without decrypting it
+const checksumSuffix = ".sha256"
+
+// ErrNotFound is returned by Fetch when the archive does not contain the requested file
+// PostgreSQL asks for files that may not exist, such as the next timeline history file
+var ErrNotFound = errors.New("not found in WAL archive")
+
+// namePattern matches the files PostgreSQL hands to archive_command: segments, partial segments,
+// backup history files and timeline history files
+var namePattern = regexp.MustCompile(`^([0-9A-F]{24}(\.partial|\.[0-9A-F]{8}\.backup)?|[0-9A-F]{8}\.history)$`)
+
+// Store is a WAL archive and its base backups in a backup directory
+type Store struct {
+	Root string
+}
+
+// NewStore returns the store in the given backup directory
+func NewStore(root string) *Store {
+	return &Store{Root: root}
+}
+
+// ValidName checks that name is a WAL file name as passed by PostgreSQL in %f
+func ValidName(name string) error {
+	if !namePattern.MatchString(name) {
+		return fmt.Errorf("invalid WAL file name %q", name)
+	}
+	return nil
+}
+
+// SegmentPath returns the path of an archived WAL file
+func (s *Store) SegmentPath(name string) string {
+	return filepath.Join(s.Root, SegmentDir, name+segmentSuffix)
+}
+
+// Archive compresses, encrypts and stores the WAL file at srcPath under name
+// It returns true if an identical file was already archived. A different file with the same name is
+// an error, as archive_command requires, so a misconfigured second cluster cannot overwrite the archive
+func (s *Store) Archive(srcPath, name string, opts *encryption.EncryptOptions) (bool, error) {
+	if err := ValidName(name); err != nil {
+		return false, err
+	}
+	dir := filepath.Join(s.Root, SegmentDir)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return false, fmt.Errorf("failed to create WAL directory: %w", err)
+	}
+
+	sum, err := fileChecksum(srcPath)
+	if err != nil {
+		return false, err
+	}
+	dstPath := s.SegmentPath(name)
+	sumPath := filepath.Join(dir, name+checksumSuffix)
+	if _, err := os.Stat(dstPath); err == nil {
+		existing, err := os.ReadFile(sumPath)
+		if err == nil {
+			if strings.TrimSpace(string(existing)) == sum {
+				return true, nil
+			}
+			return false, fmt.Errorf("%s is already archived with different contents", name)
+		}
+		// A segment without checksum is left over from an interrupted run and is replaced
+	}
+
+	src, err := os.Open(srcPath)
+	if err != nil {
+		return false, fmt.Errorf("failed to open %s: %w", srcPath, err)
+	}
+	defer src.Close()
+
+	tmp, err := os.CreateTemp(dir, "."+name+".*.tmp")
+	if err != nil {
+		return false, fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	if err := compressEncrypt(tmp, src, opts); err != nil {
+		return false, fmt.Errorf("failed to archive %s: %w", name, err)
+	}
+	if err := tmp.Sync(); err != nil {
+		return false, fmt.Errorf("failed to sync %s: %w", name, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return false, fmt.Errorf("failed to close %s: %w", name, err)
+	}
+
+	// The segment is only complete once its checksum exists, so the checksum is renamed last
+	if err := os.Rename(tmp.Name(), dstPath); err != nil {
+		return false, fmt.Errorf("failed to store %s: %w", name, err)
+	}
+	if err := writeFileSync(sumPath, []byte(sum+"\n")); err != nil {
+		return false, err
+	}
+	return false, syncDir(dir)
+}
+
+// Fetch decrypts the archived WAL file name into dstPath, as restore_command does
+// The file is written to a temporary name and renamed, so PostgreSQL never sees a partial segment
+func (s *Store) Fetch(name, dstPath string, opts *encryption.DecryptOptions) error {
+	if err := ValidName(name); err != nil {
+		return err
+	}
+	src, err := os.Open(s.SegmentPath(name))
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("%s: %w", name, ErrNotFound)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open archived %s: %w", name, err)
+	}
+	defer src.Close()
+
+	tmp, err := os.CreateTemp(filepath.Dir(dstPath), "."+filepath.Base(dstPath)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	hash := sha256.New()
+	if err := decryptDecompress(io.MultiWriter(tmp, hash), src, opts); err != nil {
+		return fmt.Errorf("failed to restore %s: %w", name, err)
+	}
+	if expected, err := os.ReadFile(filepath.Join(s.Root, SegmentDir, name+checksumSuffix)); err == nil {
+		if actual := hex.EncodeToString(hash.Sum(nil)); strings.TrimSpace(string(expected)) != actual {
+			return fmt.Errorf("archived %s does not match its checksum", name)
+		}
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close %s: %w", dstPath, err)
+	}
+	if err := os.Rename(tmp.Name(), dstPath); err != nil {
+		return fmt.Errorf("failed to move %s into place: %w", name, err)
+	}
+	return nil
+}
+
+// Segment is an archived WAL file
+type Segment struct {
+	Name       string
+	Size       int64
+	ArchivedAt time.Time
+}
+
+// Segments lists the archived WAL files sorted by name
+func (s *Store) Segments() ([]Segment, error) {
+	entries, err := os.ReadDir(filepath.Join(s.Root, SegmentDir))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read WAL directory: %w", err)
+	}
+
+	var segments []Segment
+	for _, entry := range entries {
+		name := strings.TrimSuffix(entry.Name(), segmentSuffix)
+		if name == entry.Name() || ValidName(name) != nil {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		segments = append(segments, Segment{Name: name, Size: info.Size(), ArchivedAt: info.ModTime()})
+	}
+	sort.Slice(segments, func(i, j int) bool { return segments[i].Name < segments[j].Name })
+	return segments, nil
+}
+
+// compressEncrypt writes src to dst gzip compressed and encrypted
+func compressEncrypt(dst io.Writer, src io.Reader, opts *encryption.EncryptOptions) error {
+	ew, err := encryption.NewEncryptWriter(dst, opts)
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(ew)
+	if _, err := io.Copy(gz, src); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	return ew.Close()
+}
+
+// decryptDecompress reverses compressEncrypt
+func decryptDecompress(dst io.Writer, src io.Reader, opts *encryption.DecryptOptions) error {
+	r, err := encryption.NewDecryptReader(src, opts)
+	if err != nil {
+		return err
+	}
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return fmt.Errorf("failed to decompress: %w", err)
+	}
+	defer gz.Close()
+	if _, err := io.Copy(dst, gz); err != nil {
+		return err
+	}
+	return nil
+}
+
+// fileChecksum returns the hex SHA-256 of a file
+func fileChecksum(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer f.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// writeFileSync writes a small file through a temporary name and syncs it
+func writeFileSync(path string, data []byte) error {
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return fmt.Errorf("failed to sync %s: %w", path, err)
+	}
+	f.Close()
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// syncDir makes renames in dir durable
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", dir, err)
+	}
+	defer d.Close()
+	if err := d.Sync(); err != nil {
+		return fmt.Errorf("failed to sync %s: %w", dir, err)
+	}
+	return nil
+}
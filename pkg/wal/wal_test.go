@@ -0,0 +1,313 @@
+package wal
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vosiander/open-webui-backup/pkg/encryption"
+)
+
+const segmentName = "000000010000000000000002"
+
+// testKeys returns encryption options for a new age identity
+func testKeys(t *testing.T) (*encryption.EncryptOptions, *encryption.DecryptOptions) {
+	t.Helper()
+	identity, err := encryption.GenerateIdentity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &encryption.EncryptOptions{Recipients: []string{identity.Recipient().String()}},
+		&encryption.DecryptOptions{Identities: []string{identity.String()}}
+}
+
+// writeSegment writes a fake WAL segment and returns its path
+func writeSegment(t *testing.T, dir, name string, data []byte) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestArchiveFetch(t *testing.T) {
+	encrypt, decrypt := testKeys(t)
+	store := NewStore(t.TempDir())
+	pgWAL := t.TempDir()
+
+	data := bytes.Repeat([]byte("wal record "), 10000)
+	src := writeSegment(t, pgWAL, segmentName, data)
+
+	existed, err := store.Archive(src, segmentName, encrypt)
+	if err != nil {
+		t.Fatalf("Archive: %v", err)
+	}
+	if existed {
+		t.Error("first Archive reported an existing segment")
+	}
+
+	// PostgreSQL retries archive_command after a crash; an identical file is a no-op
+	existed, err = store.Archive(src, segmentName, encrypt)
+	if err != nil {
+		t.Fatalf("Archive of identical file: %v", err)
+	}
+	if !existed {
+		t.Error("identical Archive did not report the existing segment")
+	}
+
+	// A different file under the same name must not replace the archived one
+	other := writeSegment(t, t.TempDir(), segmentName, []byte("another cluster"))
+	if _, err := store.Archive(other, segmentName, encrypt); err == nil {
+		t.Fatal("Archive of a different file with the same name succeeded")
+	}
+
+	dst := filepath.Join(t.TempDir(), "RECOVERYXLOG")
+	if err := store.Fetch(segmentName, dst, decrypt); err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	got, err := os.ReadFile(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Errorf("fetched %d bytes that differ from the archived %d bytes", len(got), len(data))
+	}
+
+	// The archive holds only the compressed and encrypted segment
+	archived, err := os.ReadFile(store.SegmentPath(segmentName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(archived, []byte("wal record")) {
+		t.Error("archived segment contains plaintext")
+	}
+
+	segments, err := store.Segments()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(segments) != 1 || segments[0].Name != segmentName {
+		t.Errorf("Segments = %+v", segments)
+	}
+}
+
+func TestArchiveReplacesSegmentWithoutChecksum(t *testing.T) {
+	encrypt, decrypt := testKeys(t)
+	store := NewStore(t.TempDir())
+	src := writeSegment(t, t.TempDir(), segmentName, []byte("complete segment"))
+
+	// Left over from a run that was interrupted before the checksum was written
+	if err := os.MkdirAll(filepath.Dir(store.SegmentPath(segmentName)), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(store.SegmentPath(segmentName), []byte("truncated"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := store.Archive(src, segmentName, encrypt); err != nil {
+		t.Fatalf("Archive over an incomplete segment: %v", err)
+	}
+	dst := filepath.Join(t.TempDir(), segmentName)
+	if err := store.Fetch(segmentName, dst, decrypt); err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	if got, _ := os.ReadFile(dst); string(got) != "complete segment" {
+		t.Errorf("fetched %q", got)
+	}
+}
+
+func TestFetchMissingSegment(t *testing.T) {
+	_, decrypt := testKeys(t)
+	store := NewStore(t.TempDir())
+	dst := filepath.Join(t.TempDir(), segmentName)
+
+	err := store.Fetch(segmentName, dst, decrypt)
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Fetch of a missing segment = %v, want ErrNotFound", err)
+	}
+	if _, err := os.Stat(dst); !os.IsNotExist(err) {
+		t.Error("Fetch of a missing segment created the target file")
+	}
+}
+
+func TestValidName(t *testing.T) {
+	valid := []string{
+		segmentName,
+		segmentName + ".partial",
+		segmentName + ".00000028.backup",
+		"00000002.history",
+	}
+	for _, name := range valid {
+		if err := ValidName(name); err != nil {
+			t.Errorf("ValidName(%q) = %v", name, err)
+		}
+	}
+
+	invalid := []string{
+		"",
+		"../" + segmentName,
+		segmentName + "/../../etc/passwd",
+		"..",
+		"/etc/passwd",
+		"00000001000000000000000",         // too short
+		"000000010000000000000002x",       // trailing garbage
+		"000000010000000000000002.gz.age", // archive file name, not a WAL name
+		"00000001000000000000000a",        // lowercase hex
+		"00000002.history/..",
+	}
+	for _, name := range invalid {
+		if err := ValidName(name); err == nil {
+			t.Errorf("ValidName(%q) accepted the name", name)
+		}
+	}
+
+	// Archive and Fetch refuse invalid names before touching the file system
+	encrypt, decrypt := testKeys(t)
+	root := t.TempDir()
+	store := NewStore(filepath.Join(root, "archive"))
+	src := writeSegment(t, root, "segment", []byte("x"))
+	if _, err := store.Archive(src, "../escaped", encrypt); err == nil {
+		t.Error("Archive accepted a path-traversal name")
+	}
+	if err := store.Fetch("../escaped", filepath.Join(root, "out"), decrypt); err == nil {
+		t.Error("Fetch accepted a path-traversal name")
+	}
+	if _, err := os.Stat(filepath.Join(root, "archive")); !os.IsNotExist(err) {
+		t.Error("invalid names created the archive directory")
+	}
+}
+
+func TestParseBackupLabel(t *testing.T) {
+	label := `START WAL LOCATION: 0/2000028 (file 000000010000000000000002)
+CHECKPOINT LOCATION: 0/2000060
+BACKUP METHOD: streamed
+BACKUP FROM: primary
+START TIME: 2024-05-01 12:00:00 UTC
+LABEL: owuicli base backup
+START TIMELINE: 1
+`
+	base := &BaseBackup{}
+	if err := parseBackupLabel(strings.NewReader(label), base); err != nil {
+		t.Fatal(err)
+	}
+	if base.StartWAL != segmentName || base.StartLSN != "0/2000028" || base.Timeline != 1 {
+		t.Errorf("parsed %+v", base)
+	}
+	if base.StartTime != "2024-05-01T12:00:00Z" {
+		t.Errorf("StartTime = %s", base.StartTime)
+	}
+
+	if err := parseBackupLabel(strings.NewReader("LABEL: empty\n"), &BaseBackup{}); err == nil {
+		t.Error("accepted a label without start WAL location")
+	}
+}
+
+func TestBaseFor(t *testing.T) {
+	store := NewStore(t.TempDir())
+	for _, base := range []*BaseBackup{
+		{ID: "20240501-120000", StopTime: "2024-05-01T12:05:00Z"},
+		{ID: "20240502-120000", StopTime: "2024-05-02T12:05:00Z"},
+	} {
+		dir := filepath.Join(store.Root, BaseDir, base.ID)
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			t.Fatal(err)
+		}
+		data, _ := json.Marshal(base)
+		if err := os.WriteFile(filepath.Join(dir, BaseInfoFile), data, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		target string
+		want   string
+	}{
+		{"2024-05-01T18:00:00Z", "20240501-120000"},
+		{"2024-05-02T12:05:00Z", "20240502-120000"},
+		{"2024-05-03T00:00:00Z", "20240502-120000"},
+		{"2024-05-01T12:00:00Z", ""}, // before the oldest base backup finished
+	}
+	for _, tt := range tests {
+		target, _ := time.Parse(time.RFC3339, tt.target)
+		base, err := store.BaseFor(target)
+		switch {
+		case tt.want == "" && err == nil:
+			t.Errorf("BaseFor(%s) = %s, want an error", tt.target, base.ID)
+		case tt.want != "" && err != nil:
+			t.Errorf("BaseFor(%s): %v", tt.target, err)
+		case tt.want != "" && base.ID != tt.want:
+			t.Errorf("BaseFor(%s) = %s, want %s", tt.target, base.ID, tt.want)
+		}
+	}
+}
+
+// tarOf builds a tar archive from the given headers; regular files get the content "x"
+func tarOf(t *testing.T, headers ...*tar.Header) *tar.Reader {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, h := range headers {
+		if h.Typeflag == tar.TypeReg {
+			h.Size = 1
+		}
+		if err := tw.WriteHeader(h); err != nil {
+			t.Fatal(err)
+		}
+		if h.Typeflag == tar.TypeReg {
+			tw.Write([]byte("x"))
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return tar.NewReader(&buf)
+}
+
+func TestExtractTarSymlinks(t *testing.T) {
+	dir := t.TempDir()
+	err := extractTar(tarOf(t,
+		&tar.Header{Name: "pg_wal/", Typeflag: tar.TypeDir, Mode: 0700},
+		&tar.Header{Name: "PG_VERSION", Typeflag: tar.TypeReg, Mode: 0600},
+		&tar.Header{Name: "pg_tblspc/16384", Typeflag: tar.TypeSymlink, Linkname: "../pg_wal"},
+	), dir)
+	if err != nil {
+		t.Fatalf("extractTar: %v", err)
+	}
+	if link, err := os.Readlink(filepath.Join(dir, "pg_tblspc", "16384")); err != nil || link != "../pg_wal" {
+		t.Errorf("symlink = %q, %v", link, err)
+	}
+
+	tests := []struct {
+		name    string
+		headers []*tar.Header
+	}{
+		{"absolute link", []*tar.Header{
+			{Name: "pg_wal", Typeflag: tar.TypeSymlink, Linkname: "/var/lib/postgresql/wal"},
+		}},
+		{"escaping link", []*tar.Header{
+			{Name: "pg_tblspc/16384", Typeflag: tar.TypeSymlink, Linkname: "../../outside"},
+		}},
+		{"file through a link", []*tar.Header{
+			{Name: "self", Typeflag: tar.TypeSymlink, Linkname: "."},
+			{Name: "self/PG_VERSION", Typeflag: tar.TypeReg, Mode: 0600},
+		}},
+		{"file over a link", []*tar.Header{
+			{Name: "PG_VERSION", Typeflag: tar.TypeSymlink, Linkname: "base"},
+			{Name: "PG_VERSION", Typeflag: tar.TypeReg, Mode: 0600},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := extractTar(tarOf(t, tt.headers...), t.TempDir()); err == nil {
+				t.Error("extractTar accepted the archive")
+			}
+		})
+	}
+}
@@ -1,23 +1,35 @@
 package plugins
 
 import (
+	"archive/zip"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/vosiander/open-webui-backup/pkg/backup"
+	"github.com/vosiander/open-webui-backup/pkg/cli"
 	"github.com/vosiander/open-webui-backup/pkg/config"
-	"github.com/vosiander/open-webui-backup/pkg/database"
 	"github.com/vosiander/open-webui-backup/pkg/encryption"
+	"github.com/vosiander/open-webui-backup/pkg/metrics"
 	"github.com/vosiander/open-webui-backup/pkg/openwebui"
+	"github.com/vosiander/open-webui-backup/pkg/redact"
+	"github.com/vosiander/open-webui-backup/pkg/signing"
+	"github.com/vosiander/open-webui-backup/pkg/vectorstore"
 )
 
 type BackupPlugin struct {
 	out              string
 	encryptRecipient []string
+	signKey          string
 	database         bool
+	vectors          bool
+	uploads          bool
+	uploadPaths      uploadFlags
+	consistent       bool
+	maintHook        string
 	prompts          bool
 	tools            bool
 	knowledge        bool
@@ -27,6 +39,25 @@ type BackupPlugin struct {
 	users            bool
 	groups           bool
 	feedbacks        bool
+	redactProfile    string
+	redactSalt       string
+}
+
+// BackupResult is the result object of the backup command
+type BackupResult struct {
+	File             string            `json:"file"`
+	Size             int64             `json:"size"`
+	Signed           bool              `json:"signed"`
+	Items            map[string]int    `json:"items"`
+	Failed           map[string]string `json:"failed,omitempty"`
+	Database         bool              `json:"database"`
+	DatabaseError    string            `json:"databaseError,omitempty"`
+	Vectors          bool              `json:"vectors"`
+	VectorsError     string            `json:"vectorsError,omitempty"`
+	Uploads          bool              `json:"uploads"`
+	UploadsError     string            `json:"uploadsError,omitempty"`
+	RedactionProfile string            `json:"redactionProfile,omitempty"`
+	Snapshot         *SnapshotResult   `json:"snapshot,omitempty"`
 }
 
 func NewBackupPlugin() *BackupPlugin {
@@ -47,7 +78,13 @@ func (p *BackupPlugin) SetupFlags(cmd *cobra.Command) {
 	cmd.Flags().StringVarP(&p.out, "out", "o", "", "Output file path for the backup (required, .age extension will be appended)")
 	cmd.MarkFlagRequired("out")
 	cmd.Flags().StringSliceVar(&p.encryptRecipient, "encrypt-recipient", nil, "Encrypt backup with age public key(s) (or use OWUI_ENCRYPTED_RECIPIENT env variable)")
-	cmd.Flags().BoolVar(&p.database, "database", false, "Include database backup (auto-enabled if POSTGRES_URL is set)")
+	cmd.Flags().StringVar(&p.signKey, "sign-key", "", "Sign the backup manifest with an Ed25519 key file (or use OWUI_SIGNING_KEY env variable)")
+	cmd.Flags().BoolVar(&p.database, "database", false, "Include database backup (auto-enabled if POSTGRES_URL or OWUI_DATA_DIR is set)")
+	cmd.Flags().BoolVar(&p.vectors, "vectors", false, "Include knowledge embeddings from Chroma or pgvector (auto-enabled if a vector store is configured)")
+	cmd.Flags().BoolVar(&p.uploads, "uploads", false, "Include uploaded files and other assets from the data directory (requires OWUI_DATA_DIR env variable)")
+	p.uploadPaths.setup(cmd, "uploads-")
+	cmd.Flags().BoolVar(&p.consistent, "consistent", false, "Capture the database at the start of the API export and record a point-in-time snapshot")
+	cmd.Flags().StringVar(&p.maintHook, "maintenance-hook", "", "Webhook URL or command run before and after a --consistent capture (implies --consistent)")
 	cmd.Flags().BoolVar(&p.prompts, "prompts", false, "Include only prompts in backup")
 	cmd.Flags().BoolVar(&p.tools, "tools", false, "Include only tools in backup")
 	cmd.Flags().BoolVar(&p.knowledge, "knowledge", false, "Include only knowledge bases in backup")
@@ -57,6 +94,8 @@ func (p *BackupPlugin) SetupFlags(cmd *cobra.Command) {
 	cmd.Flags().BoolVar(&p.groups, "groups", false, "Include only groups in backup (backed up before users)")
 	cmd.Flags().BoolVar(&p.feedbacks, "feedbacks", false, "Include only feedbacks in backup (backed up before users)")
 	cmd.Flags().BoolVar(&p.users, "users", false, "Include only users in backup (backed up LAST)")
+	cmd.Flags().StringVar(&p.redactProfile, "redact-profile", "", "Redact the backup with a profile (standard, keys-only or a profile JSON file)")
+	cmd.Flags().StringVar(&p.redactSalt, "redact-salt", "", "Secret for redaction hashes and pseudonyms (or use OWUI_REDACT_SALT env variable)")
 }
 
 // Execute runs the plugin with the given configuration
@@ -64,21 +103,39 @@ func (p *BackupPlugin) Execute(cfg *config.Config) error {
 	logrus.Info("Starting backup...")
 
 	if cfg.OpenWebUIAPIKey == "" {
-		logrus.Fatalf("OPEN_WEBUI_API_KEY environment variable is required")
+		return fmt.Errorf("OPEN_WEBUI_API_KEY environment variable is required")
 	}
 
 	if p.out == "" {
-		logrus.Fatalf("output file is required (use --out flag)")
+		return cli.Usage(fmt.Errorf("output file is required (use --out flag)"))
 	}
 
 	// Get encryption recipients (required) - supports both files and direct recipient strings
 	recipients, err := encryption.GetEncryptRecipientsFromEnvOrFlag(p.encryptRecipient)
 	if err != nil {
-		logrus.Fatalf("Failed to get encryption recipients: %v", err)
+		return fmt.Errorf("failed to get encryption recipients: %w", err)
+	}
+
+	// Get signing key (optional)
+	signKey, err := signing.GetSigningKeyFromEnvOrFlag(p.signKey)
+	if err != nil {
+		return fmt.Errorf("failed to load signing key: %w", err)
+	}
+
+	// Load the redaction profile before backing up so mistakes fail fast
+	var redactProfile *redact.Profile
+	if p.redactProfile != "" {
+		redactProfile, err = loadRedactionProfile(p.redactProfile, p.redactSalt)
+		if err != nil {
+			return fmt.Errorf("failed to load redaction profile: %w", err)
+		}
 	}
 
 	// Create client
 	client := openwebui.NewClient(cfg.OpenWebUIURL, cfg.OpenWebUIAPIKey)
+	if err := checkAPIKey(client); err != nil {
+		return err
+	}
 
 	// Determine what to backup
 	options := &backup.SelectiveBackupOptions{}
@@ -120,29 +177,128 @@ func (p *BackupPlugin) Execute(cfg *config.Config) error {
 	// Create temporary file for unencrypted backup
 	tempFile := encryptedFile + ".tmp"
 
-	// Perform the backup to temporary file (no progress callback for CLI)
-	if err := backup.BackupSelective(client, tempFile, options, nil); err != nil {
-		logrus.Fatalf("Failed to backup: %v", err)
-	}
-
-	// Auto-enable database backup if POSTGRES_URL is set and flag not explicitly set
+	// Auto-enable database backup if POSTGRES_URL or a SQLite database is configured and flag not explicitly set
 	includeDatabase := p.database
-	if !includeDatabase && database.IsPostgresURLSet() {
+	if !includeDatabase && isDatabaseConfigured() {
 		includeDatabase = true
-		logrus.Info("POSTGRES_URL detected, including database backup automatically")
+		logrus.Info("Database configuration detected, including database backup automatically")
+	}
+	if includeDatabase && redactProfile != nil && redactProfile.DropDatabase {
+		includeDatabase = false
+		logrus.Info("Database backup skipped: the redaction profile drops database dumps")
+	}
+
+	// A consistent backup pins the database before the API export starts
+	var consistent *consistentBackup
+	if p.consistent || p.maintHook != "" {
+		consistent, err = startConsistentBackup(includeDatabase, p.maintHook)
+		if err != nil {
+			return err
+		}
+		defer consistent.close()
+	}
+
+	// The API export and the database dump are streamed into the same temporary ZIP
+	zipFile, err := backup.CreateZip(tempFile)
+	if err != nil {
+		return fmt.Errorf("failed to backup: %w", err)
+	}
+	zipWriter := zip.NewWriter(zipFile)
+
+	// Perform the backup (no progress callback for CLI)
+	summary, err := backup.WriteSelective(client, zipWriter, options, nil)
+	if consistent != nil {
+		consistent.endWindow()
+	}
+	if err != nil {
+		closeZip(zipFile, zipWriter)
+		os.Remove(tempFile)
+		return fmt.Errorf("failed to backup: %w", err)
+	}
+	result := &BackupResult{
+		File:   encryptedFile,
+		Items:  summary.Items,
+		Failed: summary.Failed,
 	}
 
 	// Conditionally add database backup to the ZIP
 	if includeDatabase {
-		if err := p.addDatabaseBackupToZip(tempFile); err != nil {
-			logrus.Warnf("Database backup skipped: %v", err)
+		addDatabase := p.addDatabaseBackupToZip
+		if consistent != nil {
+			addDatabase = consistent.addDatabase
+		}
+		if err := addDatabase(cfg, zipWriter); err != nil {
 			logrus.Warnf("⚠️  Database backup skipped: %v", err)
+			result.DatabaseError = err.Error()
 		} else {
-			logrus.Info("Database backup included")
 			logrus.Info("✓ Database backup included")
+			result.Database = true
+		}
+	}
+
+	// Auto-enable vector store backup if Chroma or pgvector is configured and flag not explicitly set
+	includeVectors := p.vectors
+	if !includeVectors && vectorstore.IsConfigured() {
+		includeVectors = true
+		logrus.Info("Vector store detected, including knowledge embeddings automatically")
+	}
+	if includeVectors && redactProfile != nil && redactProfile.DropDatabase {
+		includeVectors = false
+		logrus.Info("Vector store backup skipped: the redaction profile drops database dumps")
+	}
+
+	// Conditionally add vector store backup to the ZIP
+	if includeVectors {
+		if err := addVectorBackupToZip(zipWriter); err != nil {
+			logrus.Warnf("⚠️  Vector store backup skipped: %v", err)
+			result.VectorsError = err.Error()
+		} else {
+			logrus.Info("✓ Vector store backup included")
+			result.Vectors = true
+		}
+	}
+
+	includeUploads := p.uploads
+	if includeUploads && redactProfile != nil && redactProfile.DropAttachments {
+		includeUploads = false
+		logrus.Info("Data directory backup skipped: the redaction profile drops attachments")
+	}
+
+	// Conditionally add the data directory files to the ZIP
+	if includeUploads {
+		if err := addUploadsBackupToZip(zipWriter, &p.uploadPaths); err != nil {
+			logrus.Warnf("⚠️  Data directory backup skipped: %v", err)
+			result.UploadsError = err.Error()
+		} else {
+			logrus.Info("✓ Data directory backup included")
+			result.Uploads = true
 		}
 	}
 
+	// Record the snapshot window before redaction, which keeps snapshot.json as is
+	if consistent != nil {
+		result.Snapshot, err = consistent.finish(zipWriter, summary)
+		if err != nil {
+			closeZip(zipFile, zipWriter)
+			os.Remove(tempFile)
+			return fmt.Errorf("failed to record consistent snapshot: %w", err)
+		}
+	}
+
+	if err := closeZip(zipFile, zipWriter); err != nil {
+		os.Remove(tempFile)
+		return fmt.Errorf("failed to backup: %w", err)
+	}
+
+	// Redact the backup before it is encrypted and signed
+	if redactProfile != nil {
+		if err := p.redactBackup(tempFile, redactProfile); err != nil {
+			os.Remove(tempFile)
+			return fmt.Errorf("failed to redact backup: %w", err)
+		}
+		result.RedactionProfile = redactProfile.Name
+	}
+
 	// Encrypt the backup
 	logrus.Info("Encrypting backup with public key(s)...")
 	encryptOpts := &encryption.EncryptOptions{
@@ -150,7 +306,17 @@ func (p *BackupPlugin) Execute(cfg *config.Config) error {
 	}
 
 	if err := encryption.EncryptFile(tempFile, encryptedFile, encryptOpts); err != nil {
-		logrus.Fatalf("Failed to encrypt backup: %v", err)
+		os.Remove(tempFile)
+		return fmt.Errorf("failed to encrypt backup: %w", err)
+	}
+
+	// Sign the backup manifest
+	if signKey != nil {
+		if _, err := signing.SignArchive(signKey, tempFile, encryptedFile); err != nil {
+			os.Remove(tempFile)
+			return fmt.Errorf("failed to sign backup: %w", err)
+		}
+		result.Signed = true
 	}
 
 	// Remove unencrypted backup
@@ -158,60 +324,80 @@ func (p *BackupPlugin) Execute(cfg *config.Config) error {
 		logrus.Warnf("Failed to remove unencrypted backup: %v", err)
 	}
 
+	metrics.AddBytesWritten("backup", encryptedFile)
+	if info, err := os.Stat(encryptedFile); err == nil {
+		result.Size = info.Size()
+	}
+	cli.SetResult(result)
+
+	if len(result.Failed) > 0 || result.DatabaseError != "" || result.VectorsError != "" || result.UploadsError != "" {
+		failed := failedNames(result.Failed)
+		if result.DatabaseError != "" {
+			failed = strings.TrimPrefix(failed+", database", ", ")
+		}
+		if result.VectorsError != "" {
+			failed = strings.TrimPrefix(failed+", vectors", ", ")
+		}
+		if result.UploadsError != "" {
+			failed = strings.TrimPrefix(failed+", uploads", ", ")
+		}
+		logrus.Warnf("Backup completed with errors: %s", filepath.Base(encryptedFile))
+		return cli.Partialf("backup incomplete, failed: %s", failed)
+	}
 	logrus.Infof("Backup completed successfully: %s", filepath.Base(encryptedFile))
 	return nil
 }
 
-// addDatabaseBackupToZip adds database backup to an existing ZIP file
-func (p *BackupPlugin) addDatabaseBackupToZip(zipPath string) error {
-	// Check if POSTGRES_URL is set
-	postgresURL := database.GetPostgresURLFromEnv()
-	if postgresURL == "" {
-		return fmt.Errorf("POSTGRES_URL environment variable not set")
+// redactBackup replaces the backup ZIP with a redacted copy
+func (p *BackupPlugin) redactBackup(zipPath string, profile *redact.Profile) error {
+	logrus.Infof("Redacting backup with profile %q...", profile.Name)
+
+	redactedPath := zipPath + ".redacted"
+	stats, err := redact.Archive(zipPath, redactedPath, profile)
+	if err != nil {
+		os.Remove(redactedPath)
+		return err
 	}
+	logRedactionStats(stats)
 
-	// Check if PostgreSQL tools are available
-	if err := database.CheckToolsAvailable(); err != nil {
-		return fmt.Errorf("PostgreSQL tools not available: %w", err)
+	if err := os.Rename(redactedPath, zipPath); err != nil {
+		os.Remove(redactedPath)
+		return fmt.Errorf("failed to replace backup with redacted copy: %w", err)
 	}
+	return nil
+}
 
-	// Parse connection URL
-	dbConfig, err := database.ParsePostgresURL(postgresURL)
+// addDatabaseBackupToZip streams the database backup into the open backup ZIP
+func (p *BackupPlugin) addDatabaseBackupToZip(cfg *config.Config, zipWriter *zip.Writer) error {
+	target, err := resolveDatabaseTarget("", "", "")
 	if err != nil {
-		return fmt.Errorf("failed to parse POSTGRES_URL: %w", err)
+		return err
 	}
 
-	logrus.Infof("Adding database backup for: %s", database.FormatConnectionInfo(dbConfig))
+	logrus.Infof("Adding database backup for: %s", target)
 
-	// Test connection
-	if err := database.TestConnection(dbConfig); err != nil {
-		return fmt.Errorf("database connection failed: %w", err)
+	if err := target.Check(); err != nil {
+		return err
 	}
 
-	// Create database dump
-	dumpOptions := &database.DumpOptions{
-		Format:       "plain",
-		NoOwner:      true,
-		NoPrivileges: true,
-	}
-
-	dumpData, err := database.CreateDump(dbConfig, dumpOptions)
+	// Stream the database dump and metadata next to the API export
+	size, err := target.WriteToZip(cfg, zipWriter, false)
 	if err != nil {
-		return fmt.Errorf("failed to create database dump: %w", err)
+		return err
 	}
 
-	// Get PostgreSQL version for metadata
-	version, err := database.GetPostgresVersion(dbConfig)
-	if err != nil {
-		logrus.Warnf("Failed to get PostgreSQL version: %v", err)
-		version = "unknown"
-	}
+	logrus.Infof("Database backup added successfully (%d bytes)", size)
+	return nil
+}
 
-	// Add database dump and metadata to existing ZIP
-	if err := backup.AddDatabaseToZip(zipPath, dumpData, dbConfig.Database, version); err != nil {
-		return fmt.Errorf("failed to add database to ZIP: %w", err)
+// closeZip writes the central directory of a backup ZIP and closes its file
+func closeZip(zipFile *os.File, zipWriter *zip.Writer) error {
+	if err := zipWriter.Close(); err != nil {
+		zipFile.Close()
+		return fmt.Errorf("failed to close zip file: %w", err)
+	}
+	if err := zipFile.Close(); err != nil {
+		return fmt.Errorf("failed to close zip file: %w", err)
 	}
-
-	logrus.Infof("Database backup added successfully (%d bytes)", len(dumpData))
 	return nil
 }
@@ -2,14 +2,17 @@ package plugins
 
 import (
 	"archive/zip"
-	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
-	"time"
+	"strings"
 
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	"github.com/vosiander/open-webui-backup/pkg/anonymize"
+	"github.com/vosiander/open-webui-backup/pkg/backup"
+	"github.com/vosiander/open-webui-backup/pkg/cli"
 	"github.com/vosiander/open-webui-backup/pkg/config"
 	"github.com/vosiander/open-webui-backup/pkg/database"
 	"github.com/vosiander/open-webui-backup/pkg/encryption"
@@ -17,11 +20,39 @@ import (
 
 type BackupDatabasePlugin struct {
 	postgresURL      string
+	dataDir          string
+	pgBackend        string
 	out              string
+	format           string
+	compress         bool
+	anonymize        string
+	anonymizeSalt    string
 	encryptRecipient []string
 	verbose          bool
 }
 
+// DatabaseResult is the result object of the database commands
+type DatabaseResult struct {
+	File       string   `json:"file,omitempty"`
+	Engine     string   `json:"engine"`
+	Database   string   `json:"database"`
+	Host       string   `json:"host,omitempty"`
+	Backend    string   `json:"backend,omitempty"`
+	Size       int64    `json:"size,omitempty"`
+	DumpSize   int64    `json:"dumpSize,omitempty"`
+	DryRun     bool     `json:"dryRun,omitempty"`
+	Purged     bool     `json:"purged,omitempty"`
+	Tables     []string `json:"tables,omitempty"`
+	SideSchema string   `json:"sideSchema,omitempty"`
+
+	Anonymization *database.Anonymization `json:"anonymization,omitempty"`
+
+	// Schema versions of the restored backup
+	AlembicRevision  string `json:"alembicRevision,omitempty"`
+	OpenWebUIVersion string `json:"openWebUIVersion,omitempty"`
+	SchemaMismatch   bool   `json:"schemaMismatch,omitempty"`
+}
+
 func NewBackupDatabasePlugin() *BackupDatabasePlugin {
 	return &BackupDatabasePlugin{}
 }
@@ -33,13 +64,19 @@ func (p *BackupDatabasePlugin) Name() string {
 
 // Description returns a short description of the plugin
 func (p *BackupDatabasePlugin) Description() string {
-	return "Backup PostgreSQL database to an encrypted ZIP file"
+	return "Backup the PostgreSQL or SQLite database to an encrypted ZIP file"
 }
 
 func (p *BackupDatabasePlugin) SetupFlags(cmd *cobra.Command) {
 	cmd.Flags().StringVar(&p.postgresURL, "postgres-url", "", "PostgreSQL connection URL (or use POSTGRES_URL env variable)")
+	cmd.Flags().StringVar(&p.dataDir, "data-dir", "", "Open WebUI data directory with webui.db, for SQLite deployments (or use OWUI_DATA_DIR env variable)")
+	cmd.Flags().StringVar(&p.pgBackend, "pg-backend", "", "Dump and restore backend: auto, tools, docker or native (or use OWUI_PG_BACKEND env variable)")
 	cmd.Flags().StringVarP(&p.out, "out", "o", "", "Output file path for the backup (required, .age extension will be appended)")
 	cmd.MarkFlagRequired("out")
+	cmd.Flags().StringVar(&p.format, "format", "plain", "PostgreSQL dump format: plain (SQL) or custom (pg_restore archive, needs the tools or docker backend)")
+	cmd.Flags().BoolVar(&p.compress, "compress", true, "Compress the dump; --compress=false stores it as is, e.g. for deduplicating storage")
+	cmd.Flags().StringVar(&p.anonymize, "anonymize", "", "Anonymize the dump for staging with a ruleset: built-in name ("+strings.Join(anonymize.RulesetNames(), ", ")+") or a JSON rules file (plain PostgreSQL dumps)")
+	cmd.Flags().StringVar(&p.anonymizeSalt, "anonymize-salt", "", "Secret for anonymization hashes and fake values, stable across runs (or use OWUI_ANONYMIZE_SALT env variable)")
 	cmd.Flags().StringSliceVar(&p.encryptRecipient, "encrypt-recipient", nil, "Encrypt backup with age public key(s) (or use OWUI_ENCRYPTED_RECIPIENT env variable)")
 	cmd.Flags().BoolVarP(&p.verbose, "verbose", "v", false, "Enable verbose output (show Docker commands and pg_dump output)")
 }
@@ -48,32 +85,39 @@ func (p *BackupDatabasePlugin) SetupFlags(cmd *cobra.Command) {
 func (p *BackupDatabasePlugin) Execute(cfg *config.Config) error {
 	logrus.Info("Starting database backup...")
 
-	// Check if PostgreSQL tools are available
-	if err := database.CheckToolsAvailable(); err != nil {
-		return fmt.Errorf("PostgreSQL tools check failed: %w", err)
+	if p.format != "plain" && p.format != "custom" {
+		return cli.Usage(fmt.Errorf("invalid --format %q (use plain or custom)", p.format))
 	}
 
-	// Get PostgreSQL URL from flag or environment
-	postgresURL := p.postgresURL
-	if postgresURL == "" {
-		postgresURL = database.GetPostgresURLFromEnv()
+	target, err := resolveDatabaseTarget(p.postgresURL, p.dataDir, p.pgBackend)
+	if err != nil {
+		return err
 	}
-
-	if postgresURL == "" {
-		return fmt.Errorf("PostgreSQL connection URL is required (use --postgres-url flag or POSTGRES_URL environment variable)")
+	if p.format == "custom" {
+		if target.Postgres == nil {
+			return cli.Usage(fmt.Errorf("--format custom only applies to PostgreSQL"))
+		}
+		target.DumpFormat = p.format
 	}
-
-	// Parse connection URL
-	dbConfig, err := database.ParsePostgresURL(postgresURL)
-	if err != nil {
-		return fmt.Errorf("failed to parse PostgreSQL URL: %w", err)
+	target.Uncompressed = !p.compress
+	if p.anonymize != "" {
+		if target.Postgres == nil || p.format == "custom" {
+			return cli.Usage(fmt.Errorf("--anonymize only applies to plain PostgreSQL dumps"))
+		}
+		ruleset, err := loadAnonymizationRuleset(p.anonymize, p.anonymizeSalt)
+		if err != nil {
+			return cli.Usage(err)
+		}
+		if target.Anonymizer, err = anonymize.New(ruleset); err != nil {
+			return err
+		}
+		logrus.Infof("Anonymizing the dump with ruleset %s (%s)", ruleset.Name, ruleset.Fingerprint())
 	}
 
-	logrus.Infof("Connecting to database: %s", database.FormatConnectionInfo(dbConfig))
+	logrus.Infof("Connecting to database: %s", target)
 
-	// Test connection
-	if err := database.TestConnection(dbConfig); err != nil {
-		return fmt.Errorf("database connection test failed: %w", err)
+	if err := target.Check(); err != nil {
+		return err
 	}
 
 	// Get encryption recipients (required) - supports both files and direct recipient strings
@@ -92,7 +136,8 @@ func (p *BackupDatabasePlugin) Execute(cfg *config.Config) error {
 	tempFile := encryptedFile + ".tmp"
 
 	// Create the database backup ZIP
-	if err := p.createDatabaseBackupZip(tempFile, dbConfig); err != nil {
+	dumpSize, err := p.createDatabaseBackupZip(cfg, tempFile, target)
+	if err != nil {
 		os.Remove(tempFile) // Clean up temp file on error
 		return fmt.Errorf("failed to create database backup: %w", err)
 	}
@@ -113,73 +158,65 @@ func (p *BackupDatabasePlugin) Execute(cfg *config.Config) error {
 		logrus.Warnf("Failed to remove unencrypted backup: %v", err)
 	}
 
+	result := target.Result()
+	result.File = encryptedFile
+	result.DumpSize = dumpSize
+	result.Anonymization = target.Anonymization()
+	if info, err := os.Stat(encryptedFile); err == nil {
+		result.Size = info.Size()
+	}
+	cli.SetResult(result)
+
 	logrus.Infof("Database backup completed successfully: %s", filepath.Base(encryptedFile))
 	return nil
 }
 
-// createDatabaseBackupZip creates a ZIP file containing the database dump
-func (p *BackupDatabasePlugin) createDatabaseBackupZip(outputPath string, dbConfig *database.DatabaseConfig) error {
-	// Create database dump
-	dumpOptions := &database.DumpOptions{
-		Format:       "plain",
-		NoOwner:      true,
-		NoPrivileges: true,
-		Verbose:      p.verbose,
-	}
-
-	dumpData, err := database.CreateDump(dbConfig, dumpOptions)
+// loadAnonymizationRuleset loads a ruleset and applies the salt from the flag or environment
+func loadAnonymizationRuleset(nameOrPath, salt string) (*anonymize.Ruleset, error) {
+	ruleset, err := anonymize.Load(nameOrPath)
 	if err != nil {
-		return fmt.Errorf("failed to create database dump: %w", err)
+		return nil, err
 	}
-
-	// Get PostgreSQL version for metadata
-	version, err := database.GetPostgresVersion(dbConfig)
-	if err != nil {
-		logrus.Warnf("Failed to get PostgreSQL version: %v", err)
-		version = "unknown"
+	if salt == "" {
+		salt = os.Getenv("OWUI_ANONYMIZE_SALT")
+	}
+	if salt != "" {
+		ruleset.Salt = salt
 	}
+	if ruleset.Salt == "" {
+		logrus.Info("No anonymization salt set; fake values will differ from other anonymized dumps")
+	}
+	return ruleset, nil
+}
+
+// createDatabaseBackupZip streams the database dump or snapshot into a new ZIP file and returns its size
+func (p *BackupDatabasePlugin) createDatabaseBackupZip(cfg *config.Config, outputPath string, target *databaseTarget) (int64, error) {
+	metadata := target.Metadata(cfg)
 
 	// Create ZIP file
 	zipFile, err := os.Create(outputPath)
 	if err != nil {
-		return fmt.Errorf("failed to create zip file: %w", err)
+		return 0, fmt.Errorf("failed to create zip file: %w", err)
 	}
 	defer zipFile.Close()
 
 	zipWriter := zip.NewWriter(zipFile)
-	defer zipWriter.Close()
 
-	// Add database dump to database/dump.sql
-	dumpFile, err := zipWriter.Create("database/dump.sql")
+	// Dump straight into database/dump.sql, database/dump.pgdump or database/webui.db
+	var dumpSize int64
+	err = backup.WriteDatabase(zipWriter, metadata, func(w io.Writer) error {
+		var err error
+		dumpSize, err = target.Dump(w, p.verbose)
+		return err
+	})
 	if err != nil {
-		return fmt.Errorf("failed to create dump.sql in zip: %w", err)
+		zipWriter.Close()
+		return dumpSize, err
 	}
-	if _, err := dumpFile.Write(dumpData); err != nil {
-		return fmt.Errorf("failed to write dump.sql: %w", err)
+	if err := zipWriter.Close(); err != nil {
+		return dumpSize, fmt.Errorf("failed to close zip file: %w", err)
 	}
 
-	// Add metadata
-	metadata := &database.DatabaseBackupMetadata{
-		BackupTimestamp: time.Now().UTC().Format(time.RFC3339),
-		DatabaseName:    dbConfig.Database,
-		PostgresVersion: version,
-		DumpFormat:      "plain",
-		Compressed:      false,
-	}
-
-	metadataJSON, err := json.MarshalIndent(metadata, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to marshal metadata: %w", err)
-	}
-
-	metadataFile, err := zipWriter.Create("database/metadata.json")
-	if err != nil {
-		return fmt.Errorf("failed to create metadata.json in zip: %w", err)
-	}
-	if _, err := metadataFile.Write(metadataJSON); err != nil {
-		return fmt.Errorf("failed to write metadata.json: %w", err)
-	}
-
-	logrus.Infof("Database backup ZIP created: %s (%d bytes)", filepath.Base(outputPath), len(dumpData))
-	return nil
+	logrus.Infof("Database backup ZIP created: %s (%d bytes)", filepath.Base(outputPath), dumpSize)
+	return dumpSize, nil
 }
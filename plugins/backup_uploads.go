@@ -0,0 +1,181 @@
+package plugins
+
+import (
+	"archive/zip"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/vosiander/open-webui-backup/pkg/cli"
+	"github.com/vosiander/open-webui-backup/pkg/config"
+	"github.com/vosiander/open-webui-backup/pkg/encryption"
+	"github.com/vosiander/open-webui-backup/pkg/uploads"
+)
+
+type BackupUploadsPlugin struct {
+	uploadFlags
+	out              string
+	encryptRecipient []string
+}
+
+// uploadFlags select the data directory and the paths below it
+type uploadFlags struct {
+	dataDir string
+	include []string
+	exclude []string
+}
+
+// UploadsResult is the result object of the uploads commands
+type UploadsResult struct {
+	File    string                `json:"file,omitempty"`
+	Root    string                `json:"root"`
+	Files   int                   `json:"files"`
+	Dirs    int                   `json:"dirs"`
+	Size    int64                 `json:"size"`
+	Restore *uploads.RestoreStats `json:"restore,omitempty"`
+	DryRun  bool                  `json:"dryRun,omitempty"`
+}
+
+func NewBackupUploadsPlugin() *BackupUploadsPlugin {
+	return &BackupUploadsPlugin{}
+}
+
+// Name returns the name of the plugin (used as command name)
+func (p *BackupUploadsPlugin) Name() string {
+	return "backup-uploads"
+}
+
+// Description returns a short description of the plugin
+func (p *BackupUploadsPlugin) Description() string {
+	return "Backup uploaded files and other assets of the Open WebUI data directory to an encrypted ZIP file"
+}
+
+func (p *BackupUploadsPlugin) SetupFlags(cmd *cobra.Command) {
+	p.uploadFlags.setup(cmd, "")
+	cmd.Flags().StringVarP(&p.out, "out", "o", "", "Output file path for the backup (required, .age extension will be appended)")
+	cmd.MarkFlagRequired("out")
+	cmd.Flags().StringSliceVar(&p.encryptRecipient, "encrypt-recipient", nil, "Encrypt backup with age public key(s) (or use OWUI_ENCRYPTED_RECIPIENT env variable)")
+}
+
+// setup registers the include and exclude flags with the given name prefix
+// Without a prefix --data-dir is registered as well; otherwise the directory comes from the environment
+func (f *uploadFlags) setup(cmd *cobra.Command, prefix string) {
+	if prefix == "" {
+		cmd.Flags().StringVar(&f.dataDir, "data-dir", "", "Open WebUI data directory (or use OWUI_DATA_DIR env variable)")
+	}
+	cmd.Flags().StringSliceVar(&f.include, prefix+"include", nil, "Only back up data directory paths matching these globs (default: everything)")
+	cmd.Flags().StringSliceVar(&f.exclude, prefix+"exclude", uploads.DefaultExcludes, "Skip data directory paths matching these globs")
+}
+
+// options resolves the data directory and validates the patterns
+func (f *uploadFlags) options() (*uploads.Options, error) {
+	if err := uploads.ValidatePatterns(f.include); err != nil {
+		return nil, cli.Usage(err)
+	}
+	if err := uploads.ValidatePatterns(f.exclude); err != nil {
+		return nil, cli.Usage(err)
+	}
+	root, err := uploads.ResolveRoot(f.dataDir)
+	if err != nil {
+		return nil, cli.Usage(err)
+	}
+	return &uploads.Options{Root: root, Include: f.include, Exclude: f.exclude}, nil
+}
+
+// Execute runs the plugin with the given configuration
+func (p *BackupUploadsPlugin) Execute(cfg *config.Config) error {
+	logrus.Info("Starting data directory backup...")
+
+	opts, err := p.options()
+	if err != nil {
+		return err
+	}
+
+	// Get encryption recipients (required) - supports both files and direct recipient strings
+	recipients, err := encryption.GetEncryptRecipientsFromEnvOrFlag(p.encryptRecipient)
+	if err != nil {
+		return fmt.Errorf("failed to get encryption recipients: %w", err)
+	}
+
+	// Prepare file paths for encryption
+	encryptedFile := p.out
+	if filepath.Ext(encryptedFile) != ".age" {
+		encryptedFile = encryptedFile + ".age"
+	}
+
+	// Create temporary file for unencrypted backup
+	tempFile := encryptedFile + ".tmp"
+
+	manifest, err := createUploadsBackupZip(tempFile, opts)
+	if err != nil {
+		os.Remove(tempFile) // Clean up temp file on error
+		return fmt.Errorf("failed to create data directory backup: %w", err)
+	}
+
+	// Encrypt the backup
+	logrus.Info("Encrypting data directory backup with public key(s)...")
+	encryptOpts := &encryption.EncryptOptions{
+		Recipients: recipients,
+	}
+
+	if err := encryption.EncryptFile(tempFile, encryptedFile, encryptOpts); err != nil {
+		os.Remove(tempFile) // Clean up temp file on error
+		return fmt.Errorf("failed to encrypt backup: %w", err)
+	}
+
+	// Remove unencrypted backup
+	if err := os.Remove(tempFile); err != nil {
+		logrus.Warnf("Failed to remove unencrypted backup: %v", err)
+	}
+
+	cli.SetResult(&UploadsResult{
+		File:  encryptedFile,
+		Root:  manifest.Root,
+		Files: manifest.Files,
+		Dirs:  manifest.Dirs,
+		Size:  manifest.Size,
+	})
+
+	logrus.Infof("Data directory backup completed successfully: %s", filepath.Base(encryptedFile))
+	return nil
+}
+
+// createUploadsBackupZip creates a ZIP file containing the uploads section
+func createUploadsBackupZip(outputPath string, opts *uploads.Options) (*uploads.Manifest, error) {
+	zipFile, err := os.Create(outputPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create zip file: %w", err)
+	}
+	defer zipFile.Close()
+
+	zipWriter := zip.NewWriter(zipFile)
+	manifest, err := uploads.Backup(zipWriter, opts)
+	if err != nil {
+		zipWriter.Close()
+		return nil, err
+	}
+	if err := zipWriter.Close(); err != nil {
+		return nil, fmt.Errorf("failed to close zip file: %w", err)
+	}
+	return manifest, nil
+}
+
+// addUploadsBackupToZip streams the data directory into the open backup ZIP
+func addUploadsBackupToZip(zipWriter *zip.Writer, f *uploadFlags) error {
+	opts, err := f.options()
+	if err != nil {
+		return err
+	}
+
+	logrus.Infof("Adding data directory backup for: %s", opts.Root)
+
+	manifest, err := uploads.Backup(zipWriter, opts)
+	if err != nil {
+		return fmt.Errorf("failed to write data directory to ZIP: %w", err)
+	}
+
+	logrus.Infof("Data directory backup added successfully (%d files, %d bytes)", manifest.Files, manifest.Size)
+	return nil
+}
@@ -0,0 +1,197 @@
+package plugins
+
+import (
+	"archive/zip"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/vosiander/open-webui-backup/pkg/cli"
+	"github.com/vosiander/open-webui-backup/pkg/config"
+	"github.com/vosiander/open-webui-backup/pkg/encryption"
+	"github.com/vosiander/open-webui-backup/pkg/vectorstore"
+)
+
+type BackupVectorsPlugin struct {
+	vectorFlags
+	out              string
+	encryptRecipient []string
+}
+
+// vectorFlags select the vector store on the vector commands
+type vectorFlags struct {
+	vectorDB    string
+	chromaPath  string
+	dataDir     string
+	pgvectorURL string
+}
+
+// VectorResult is the result object of the vector store commands
+type VectorResult struct {
+	File        string `json:"file,omitempty"`
+	Backend     string `json:"backend"`
+	Store       string `json:"store"`
+	Collections int    `json:"collections"`
+	Embeddings  int64  `json:"embeddings"`
+	Size        int64  `json:"size,omitempty"`
+}
+
+func NewBackupVectorsPlugin() *BackupVectorsPlugin {
+	return &BackupVectorsPlugin{}
+}
+
+// Name returns the name of the plugin (used as command name)
+func (p *BackupVectorsPlugin) Name() string {
+	return "backup-vectors"
+}
+
+// Description returns a short description of the plugin
+func (p *BackupVectorsPlugin) Description() string {
+	return "Backup knowledge embeddings from Chroma or pgvector to an encrypted ZIP file"
+}
+
+func (p *BackupVectorsPlugin) SetupFlags(cmd *cobra.Command) {
+	p.vectorFlags.setup(cmd)
+	cmd.Flags().StringVarP(&p.out, "out", "o", "", "Output file path for the backup (required, .age extension will be appended)")
+	cmd.MarkFlagRequired("out")
+	cmd.Flags().StringSliceVar(&p.encryptRecipient, "encrypt-recipient", nil, "Encrypt backup with age public key(s) (or use OWUI_ENCRYPTED_RECIPIENT env variable)")
+}
+
+// setup registers the vector store selection flags
+func (f *vectorFlags) setup(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&f.vectorDB, "vector-db", "", "Vector store: chroma or pgvector (or use VECTOR_DB env variable)")
+	cmd.Flags().StringVar(&f.chromaPath, "chroma-path", "", "Chroma persistence directory (or use CHROMA_DATA_PATH env variable, default: <data dir>/vector_db)")
+	cmd.Flags().StringVar(&f.dataDir, "data-dir", "", "Open WebUI data directory (or use OWUI_DATA_DIR env variable)")
+	cmd.Flags().StringVar(&f.pgvectorURL, "pgvector-url", "", "PostgreSQL URL of the pgvector database (or use PGVECTOR_DB_URL env variable)")
+}
+
+// store resolves the vector store from the flags and environment
+func (f *vectorFlags) store() (vectorstore.Store, error) {
+	if f.vectorDB != "" {
+		if err := vectorstore.ValidateBackend(f.vectorDB); err != nil {
+			return nil, cli.Usage(err)
+		}
+	}
+	store, err := vectorstore.New(&vectorstore.Options{
+		Backend:     f.vectorDB,
+		ChromaPath:  f.chromaPath,
+		DataDir:     f.dataDir,
+		PgvectorURL: f.pgvectorURL,
+	})
+	if err != nil {
+		return nil, cli.Usage(err)
+	}
+	return store, nil
+}
+
+// Execute runs the plugin with the given configuration
+func (p *BackupVectorsPlugin) Execute(cfg *config.Config) error {
+	logrus.Info("Starting vector store backup...")
+
+	store, err := p.store()
+	if err != nil {
+		return err
+	}
+
+	logrus.Infof("Vector store: %s", store)
+
+	if err := store.Check(); err != nil {
+		return err
+	}
+
+	// Get encryption recipients (required) - supports both files and direct recipient strings
+	recipients, err := encryption.GetEncryptRecipientsFromEnvOrFlag(p.encryptRecipient)
+	if err != nil {
+		return fmt.Errorf("failed to get encryption recipients: %w", err)
+	}
+
+	// Prepare file paths for encryption
+	encryptedFile := p.out
+	if filepath.Ext(encryptedFile) != ".age" {
+		encryptedFile = encryptedFile + ".age"
+	}
+
+	// Create temporary file for unencrypted backup
+	tempFile := encryptedFile + ".tmp"
+
+	metadata, err := createVectorBackupZip(tempFile, store)
+	if err != nil {
+		os.Remove(tempFile) // Clean up temp file on error
+		return fmt.Errorf("failed to create vector store backup: %w", err)
+	}
+
+	// Encrypt the backup
+	logrus.Info("Encrypting vector store backup with public key(s)...")
+	encryptOpts := &encryption.EncryptOptions{
+		Recipients: recipients,
+	}
+
+	if err := encryption.EncryptFile(tempFile, encryptedFile, encryptOpts); err != nil {
+		os.Remove(tempFile) // Clean up temp file on error
+		return fmt.Errorf("failed to encrypt backup: %w", err)
+	}
+
+	// Remove unencrypted backup
+	if err := os.Remove(tempFile); err != nil {
+		logrus.Warnf("Failed to remove unencrypted backup: %v", err)
+	}
+
+	result := &VectorResult{
+		File:        encryptedFile,
+		Backend:     store.Name(),
+		Store:       store.String(),
+		Collections: metadata.Collections,
+		Embeddings:  metadata.Embeddings,
+	}
+	if info, err := os.Stat(encryptedFile); err == nil {
+		result.Size = info.Size()
+	}
+	cli.SetResult(result)
+
+	logrus.Infof("Vector store backup completed successfully: %s", filepath.Base(encryptedFile))
+	return nil
+}
+
+// createVectorBackupZip creates a ZIP file containing the vector store section
+func createVectorBackupZip(outputPath string, store vectorstore.Store) (*vectorstore.Metadata, error) {
+	zipFile, err := os.Create(outputPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create zip file: %w", err)
+	}
+	defer zipFile.Close()
+
+	zipWriter := zip.NewWriter(zipFile)
+	metadata, err := vectorstore.Backup(store, zipWriter)
+	if err != nil {
+		zipWriter.Close()
+		return nil, err
+	}
+	if err := zipWriter.Close(); err != nil {
+		return nil, fmt.Errorf("failed to close zip file: %w", err)
+	}
+	return metadata, nil
+}
+
+// addVectorBackupToZip streams the configured vector store into the open backup ZIP
+func addVectorBackupToZip(zipWriter *zip.Writer) error {
+	store, err := vectorstore.New(&vectorstore.Options{})
+	if err != nil {
+		return err
+	}
+
+	logrus.Infof("Adding vector store backup for: %s", store)
+
+	if err := store.Check(); err != nil {
+		return err
+	}
+
+	metadata, err := vectorstore.Backup(store, zipWriter)
+	if err != nil {
+		return fmt.Errorf("failed to write vector store to ZIP: %w", err)
+	}
+
+	logrus.Infof("Vector store backup added successfully (%d collections, %d embeddings)", metadata.Collections, metadata.Embeddings)
+	return nil
+}
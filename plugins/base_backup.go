@@ -0,0 +1,126 @@
+package plugins
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/vosiander/open-webui-backup/pkg/cli"
+	"github.com/vosiander/open-webui-backup/pkg/config"
+	"github.com/vosiander/open-webui-backup/pkg/database"
+	"github.com/vosiander/open-webui-backup/pkg/encryption"
+	"github.com/vosiander/open-webui-backup/pkg/wal"
+)
+
+// BaseBackupPlugin takes an encrypted physical base backup for point-in-time recovery
+type BaseBackupPlugin struct {
+	path             string
+	postgresURL      string
+	pgBackend        string
+	encryptRecipient []string
+	verbose          bool
+}
+
+// BaseBackupResult is the result object of the base-backup command
+type BaseBackupResult struct {
+	ID        string   `json:"id"`
+	Dir       string   `json:"dir"`
+	StartWAL  string   `json:"startWal"`
+	StartLSN  string   `json:"startLsn"`
+	StartTime string   `json:"startTime,omitempty"`
+	StopTime  string   `json:"stopTime"`
+	Files     []string `json:"files"`
+	Size      int64    `json:"size"`
+}
+
+func NewBaseBackupPlugin() *BaseBackupPlugin {
+	return &BaseBackupPlugin{}
+}
+
+// Name returns the name of the plugin (used as command name)
+func (p *BaseBackupPlugin) Name() string {
+	return "base-backup"
+}
+
+// Description returns a short description of the plugin
+func (p *BaseBackupPlugin) Description() string {
+	return "Take an encrypted PostgreSQL base backup for point-in-time recovery with the WAL archive"
+}
+
+func (p *BaseBackupPlugin) SetupFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&p.path, "path", "", "Backup directory holding the WAL archive (required)")
+	cmd.MarkFlagRequired("path")
+	cmd.Flags().StringVar(&p.postgresURL, "postgres-url", "", "PostgreSQL connection URL of a user with the REPLICATION privilege (or use POSTGRES_URL env variable)")
+	cmd.Flags().StringVar(&p.pgBackend, "pg-backend", "", "Backend running pg_basebackup: auto, tools or docker (or use OWUI_PG_BACKEND env variable)")
+	cmd.Flags().StringSliceVar(&p.encryptRecipient, "encrypt-recipient", nil, "Encrypt with age public key(s) (or use OWUI_ENCRYPTED_RECIPIENT env variable, defaults to recipient.txt in --path)")
+	cmd.Flags().BoolVarP(&p.verbose, "verbose", "v", false, "Enable verbose output (show pg_basebackup output)")
+}
+
+// Execute runs the plugin with the given configuration
+func (p *BaseBackupPlugin) Execute(cfg *config.Config) error {
+	logrus.Info("Starting base backup...")
+
+	postgresURL := p.postgresURL
+	if postgresURL == "" {
+		postgresURL = database.GetPostgresURLFromEnv()
+	}
+	if postgresURL == "" {
+		return cli.Usage(fmt.Errorf("PostgreSQL connection URL is required (use --postgres-url flag or POSTGRES_URL environment variable)"))
+	}
+	target, err := resolvePostgresTarget(postgresURL, p.pgBackend)
+	if err != nil {
+		return err
+	}
+
+	recipients, err := loadRecipients(p.encryptRecipient, p.path)
+	if err != nil {
+		return fmt.Errorf("failed to get encryption recipients: %w", err)
+	}
+
+	logrus.Infof("Target database: %s", target)
+	if err := database.TestConnection(target.Postgres); err != nil {
+		return fmt.Errorf("database connection test failed: %w", err)
+	}
+	checkArchiveSettings(target.Postgres)
+
+	store := wal.NewStore(p.path)
+	base, err := store.CreateBase(target.Postgres, &encryption.EncryptOptions{Recipients: recipients}, p.verbose)
+	if err != nil {
+		return fmt.Errorf("failed to create base backup: %w", err)
+	}
+
+	dir := filepath.Join(p.path, wal.BaseDir, base.ID)
+	cli.SetResult(&BaseBackupResult{
+		ID:        base.ID,
+		Dir:       dir,
+		StartWAL:  base.StartWAL,
+		StartLSN:  base.StartLSN,
+		StartTime: base.StartTime,
+		StopTime:  base.StopTime,
+		Files:     base.Files,
+		Size:      base.Size,
+	})
+
+	logrus.Infof("✓ Base backup %s completed (%d bytes, starts at WAL %s)", base.ID, base.Size, base.StartWAL)
+	logrus.Infof("Recovery targets after %s can be restored with restore-database --to-time", base.StopTime)
+	return nil
+}
+
+// checkArchiveSettings warns if the server does not archive WAL, since a base backup alone cannot be recovered to a point in time
+func checkArchiveSettings(config *database.DatabaseConfig) {
+	settings, err := database.GetArchiveSettings(config)
+	if err != nil {
+		logrus.Warnf("⚠️  Could not check WAL archiving settings: %v", err)
+		return
+	}
+	if settings.WALLevel == "minimal" {
+		logrus.Warn("⚠️  wal_level is minimal; set it to replica for base backups and WAL archiving")
+	}
+	if settings.ArchiveMode == "off" {
+		logrus.Warn("⚠️  archive_mode is off; without archived WAL this base backup can only be restored to its own end")
+	} else if settings.ArchiveCommand != "" && !strings.Contains(settings.ArchiveCommand, "wal-archive") {
+		logrus.Warnf("⚠️  archive_command does not call wal-archive: %s", settings.ArchiveCommand)
+	}
+}
@@ -6,12 +6,16 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"syscall"
 	"text/tabwriter"
 	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	"github.com/vosiander/open-webui-backup/pkg/archive"
+	"github.com/vosiander/open-webui-backup/pkg/chatexport"
+	"github.com/vosiander/open-webui-backup/pkg/cli"
 	"github.com/vosiander/open-webui-backup/pkg/config"
 	"github.com/vosiander/open-webui-backup/pkg/openwebui"
 )
@@ -46,6 +50,19 @@ type ChatsPlugin struct {
 	// Live flags
 	timeframe string
 	interval  string
+
+	// Export flags
+	exportFormat   string
+	exportFile     string
+	exportOut      string
+	exportIdentity []string
+	exportAllUsers bool
+	exportUsers    []string
+	exportSince    string
+	exportUntil    string
+	exportModels   []string
+	exportFolders  []string
+	exportQuery    string
 }
 
 func NewChatsPlugin() *ChatsPlugin {
@@ -215,8 +232,29 @@ func (p *ChatsPlugin) SetupFlags(cmd *cobra.Command) {
 	liveCmd.Flags().StringVar(&p.timeframe, "timeframe", "5m", "Show chats updated in last duration (e.g., 5m, 1h, 30s)")
 	liveCmd.Flags().StringVar(&p.interval, "interval", "15s", "Refresh interval (e.g., 15s, 30s, 1m)")
 
+	// Export subcommand
+	exportCmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export chats to Markdown, HTML or JSONL",
+		Long:  "Render chats from the live instance or a backup file, including all message branches",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return p.executeExport()
+		},
+	}
+	exportCmd.Flags().StringVar(&p.exportFormat, "format", chatexport.FormatMarkdown, "Export format: markdown, html or jsonl")
+	exportCmd.Flags().StringVar(&p.exportFile, "file", "", "Export from this backup file instead of the live instance")
+	exportCmd.Flags().StringVar(&p.exportOut, "out", "", "Output directory for markdown/html (required), output file for jsonl (default: stdout)")
+	exportCmd.Flags().StringSliceVar(&p.exportIdentity, "decrypt-identity", nil, "Age identity file(s) for encrypted backups (or use OWUI_DECRYPT_IDENTITY env variable)")
+	exportCmd.Flags().BoolVar(&p.exportAllUsers, "all-users", false, "Export the chats of all users from the live instance (admin API key required)")
+	exportCmd.Flags().StringSliceVar(&p.exportUsers, "user", nil, "Only chats of these users (ID, email or name)")
+	exportCmd.Flags().StringVar(&p.exportSince, "since", "", "Only chats created on or after this date (YYYY-MM-DD or RFC3339)")
+	exportCmd.Flags().StringVar(&p.exportUntil, "until", "", "Only chats created before this date (YYYY-MM-DD or RFC3339)")
+	exportCmd.Flags().StringSliceVar(&p.exportModels, "model", nil, "Only chats that used one of these models (globs allowed)")
+	exportCmd.Flags().StringSliceVar(&p.exportFolders, "folder", nil, "Only chats in these folder IDs")
+	exportCmd.Flags().StringVar(&p.exportQuery, "query", "", "Only chats whose title or messages contain this text")
+
 	// Add subcommands to main command
-	cmd.AddCommand(listCmd, allCmd, allDbCmd, getCmd, searchCmd, folderCmd, archivedCmd, sharedCmd, liveCmd)
+	cmd.AddCommand(listCmd, allCmd, allDbCmd, getCmd, searchCmd, folderCmd, archivedCmd, sharedCmd, liveCmd, exportCmd)
 }
 
 func (p *ChatsPlugin) Execute(cfg *config.Config) error {
@@ -235,7 +273,7 @@ func (p *ChatsPlugin) executeList(client *openwebui.Client) error {
 		return fmt.Errorf("failed to fetch chats: %w", err)
 	}
 
-	if p.jsonOutput {
+	if p.jsonOutput || cli.JSON() {
 		return p.outputJSON(chats)
 	}
 
@@ -280,7 +318,7 @@ func (p *ChatsPlugin) executeAll(client *openwebui.Client) error {
 		logrus.Infof("Total chats fetched: %d", len(allChats))
 	}
 
-	if p.jsonOutput {
+	if p.jsonOutput || cli.JSON() {
 		return p.outputJSON(allChats)
 	}
 
@@ -307,7 +345,7 @@ func (p *ChatsPlugin) executeAllDB(client *openwebui.Client) error {
 		logrus.Infof("Total chats fetched: %d", len(chats))
 	}
 
-	if p.jsonOutput {
+	if p.jsonOutput || cli.JSON() {
 		return p.outputJSON(chats)
 	}
 
@@ -330,7 +368,7 @@ func (p *ChatsPlugin) executeGet(client *openwebui.Client) error {
 		return fmt.Errorf("failed to fetch chat: %w", err)
 	}
 
-	if p.jsonOutput {
+	if p.jsonOutput || cli.JSON() {
 		return p.outputJSON(chat)
 	}
 
@@ -353,7 +391,7 @@ func (p *ChatsPlugin) executeSearch(client *openwebui.Client) error {
 		return fmt.Errorf("failed to search chats: %w", err)
 	}
 
-	if p.jsonOutput {
+	if p.jsonOutput || cli.JSON() {
 		return p.outputJSON(chats)
 	}
 
@@ -376,7 +414,7 @@ func (p *ChatsPlugin) executeFolder(client *openwebui.Client) error {
 		return fmt.Errorf("failed to fetch chats: %w", err)
 	}
 
-	if p.jsonOutput {
+	if p.jsonOutput || cli.JSON() {
 		return p.outputJSON(chats)
 	}
 
@@ -399,7 +437,7 @@ func (p *ChatsPlugin) executeArchived(client *openwebui.Client) error {
 		return fmt.Errorf("failed to fetch archived chats: %w", err)
 	}
 
-	if p.jsonOutput {
+	if p.jsonOutput || cli.JSON() {
 		return p.outputJSON(chats)
 	}
 
@@ -422,7 +460,7 @@ func (p *ChatsPlugin) executeShared(client *openwebui.Client) error {
 		return fmt.Errorf("failed to fetch shared chat: %w", err)
 	}
 
-	if p.jsonOutput {
+	if p.jsonOutput || cli.JSON() {
 		return p.outputJSON(chat)
 	}
 
@@ -581,8 +619,13 @@ func (p *ChatsPlugin) displaySingleChat(chat *openwebui.Chat, userMap map[string
 	return nil
 }
 
-// outputJSON outputs the data as JSON
+// outputJSON outputs the data as JSON; with --output json it becomes the command result
 func (p *ChatsPlugin) outputJSON(data interface{}) error {
+	if cli.JSON() {
+		cli.SetResult(data)
+		return nil
+	}
+
 	jsonData, err := json.MarshalIndent(data, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to marshal JSON: %w", err)
@@ -614,6 +657,10 @@ func truncate(s string, maxLen int) string {
 
 // executeLive continuously monitors and displays recent chats
 func (p *ChatsPlugin) executeLive(client *openwebui.Client) error {
+	if cli.JSON() {
+		return cli.Usage(fmt.Errorf("chats live is interactive and does not support --output json"))
+	}
+
 	// Parse timeframe duration
 	timeframeDuration, err := time.ParseDuration(p.timeframe)
 	if err != nil {
@@ -782,3 +829,116 @@ func formatRelativeTime(ts int64) string {
 		return fmt.Sprintf("%dd ago", days)
 	}
 }
+
+// executeExport renders the selected chats in the requested format
+func (p *ChatsPlugin) executeExport() error {
+	switch p.exportFormat {
+	case chatexport.FormatMarkdown, chatexport.FormatHTML:
+		if p.exportOut == "" {
+			return cli.Usage(fmt.Errorf("--out directory is required for %s exports", p.exportFormat))
+		}
+	case chatexport.FormatJSONL:
+		if (p.exportOut == "" || p.exportOut == "-") && cli.JSON() {
+			return stdoutReserved("--out")
+		}
+	default:
+		return cli.Usage(fmt.Errorf("unsupported format %q (use markdown, html or jsonl)", p.exportFormat))
+	}
+
+	filter := &chatexport.Filter{
+		Users:   p.exportUsers,
+		Models:  p.exportModels,
+		Folders: p.exportFolders,
+		Query:   p.exportQuery,
+	}
+	var err error
+	if filter.Since, err = parseDate(p.exportSince); err != nil {
+		return fmt.Errorf("invalid --since: %w", err)
+	}
+	if filter.Until, err = parseDate(p.exportUntil); err != nil {
+		return fmt.Errorf("invalid --until: %w", err)
+	}
+
+	source, err := p.loadExportSource()
+	if err != nil {
+		return err
+	}
+
+	chats := source.Apply(filter)
+	logrus.Infof("Exporting %d of %d chats as %s", len(chats), len(source.Chats), p.exportFormat)
+
+	if p.exportFormat == chatexport.FormatJSONL {
+		if p.exportOut == "" || p.exportOut == "-" {
+			return chatexport.WriteJSONL(os.Stdout, chats)
+		}
+		f, err := os.Create(p.exportOut)
+		if err != nil {
+			return fmt.Errorf("failed to create %s: %w", p.exportOut, err)
+		}
+		defer f.Close()
+		return chatexport.WriteJSONL(f, chats)
+	}
+
+	if err := os.MkdirAll(p.exportOut, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	ext := ".md"
+	write := chatexport.WriteMarkdown
+	if p.exportFormat == chatexport.FormatHTML {
+		ext = ".html"
+		write = chatexport.WriteHTML
+	}
+
+	for i := range chats {
+		path := filepath.Join(p.exportOut, chatexport.FileName(&chats[i], ext))
+		f, err := os.Create(path)
+		if err != nil {
+			return fmt.Errorf("failed to create %s: %w", path, err)
+		}
+		err = write(f, &chats[i], source.Users)
+		f.Close()
+		if err != nil {
+			return err
+		}
+		if p.verbose {
+			logrus.Infof("Wrote %s", path)
+		}
+	}
+
+	logrus.Infof("Exported %d chats to %s", len(chats), p.exportOut)
+	return nil
+}
+
+// loadExportSource reads chats from a backup file or the live instance
+func (p *ChatsPlugin) loadExportSource() (*chatexport.Source, error) {
+	if p.exportFile != "" {
+		identities, err := loadIdentityContents(p.exportIdentity, "")
+		if err != nil {
+			return nil, err
+		}
+		zipPath, cleanup, err := archive.Decrypt(p.exportFile, identities)
+		if err != nil {
+			return nil, err
+		}
+		defer cleanup()
+		return chatexport.FromArchive(zipPath)
+	}
+
+	if p.config.OpenWebUIAPIKey == "" {
+		return nil, fmt.Errorf("OPEN_WEBUI_API_KEY environment variable is required")
+	}
+	client := openwebui.NewClient(p.config.OpenWebUIURL, p.config.OpenWebUIAPIKey)
+	return chatexport.FromLive(client, p.exportAllUsers)
+}
+
+// parseDate parses a YYYY-MM-DD or RFC3339 date; empty input returns the zero time
+func parseDate(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
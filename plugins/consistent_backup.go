@@ -0,0 +1,221 @@
+package plugins
+
+import (
+	"archive/zip"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/vosiander/open-webui-backup/pkg/archive"
+	"github.com/vosiander/open-webui-backup/pkg/backup"
+	"github.com/vosiander/open-webui-backup/pkg/cli"
+	"github.com/vosiander/open-webui-backup/pkg/config"
+	"github.com/vosiander/open-webui-backup/pkg/database"
+	"github.com/vosiander/open-webui-backup/pkg/snapshot"
+)
+
+// SnapshotResult describes the point in time of a consistent backup
+type SnapshotResult struct {
+	StartedAt     string  `json:"startedAt"`
+	WindowSeconds float64 `json:"windowSeconds"`
+	LSN           string  `json:"lsn,omitempty"`
+	Changed       int     `json:"changed"`
+	Maintenance   bool    `json:"maintenance"`
+}
+
+// maxLoggedChanges limits the changed entities listed in the log
+const maxLoggedChanges = 20
+
+// consistentBackup coordinates a backup whose API export and database dump describe the same moment
+// The database is captured before the API export starts: PostgreSQL by exporting a snapshot that
+// the dump imports later, SQLite by copying the file. The optional maintenance hook runs around
+// the capture, so the window in which API data can drift from the database is as short as possible
+type consistentBackup struct {
+	record     *snapshot.Record
+	hook       *snapshot.Hook
+	target     *databaseTarget
+	pgSnapshot *database.Snapshot
+	dbErr      error
+	finishedAt time.Time
+	entered    bool
+}
+
+// startConsistentBackup enters maintenance and pins the database state
+// Database failures do not abort the backup; they are reported when the database is added
+func startConsistentBackup(includeDatabase bool, maintenanceHook string) (*consistentBackup, error) {
+	c := &consistentBackup{}
+	if maintenanceHook != "" {
+		hook, err := snapshot.NewHook(maintenanceHook)
+		if err != nil {
+			return nil, cli.Usage(err)
+		}
+		c.hook = hook
+	}
+
+	if includeDatabase {
+		c.target, c.dbErr = resolveDatabaseTarget("", "", "")
+		if c.dbErr == nil {
+			c.dbErr = c.target.Check()
+		}
+	}
+
+	var maintenance *snapshot.Maintenance
+	if c.hook != nil {
+		logrus.Info("Entering maintenance...")
+		if err := c.hook.Run(snapshot.PhaseEnter); err != nil {
+			// The hook may have half-applied; try to leave maintenance before giving up
+			if exitErr := c.hook.Run(snapshot.PhaseExit); exitErr != nil {
+				logrus.Warnf("Failed to leave maintenance: %v", exitErr)
+			}
+			return nil, fmt.Errorf("failed to enter maintenance: %w", err)
+		}
+		c.entered = true
+		maintenance = &snapshot.Maintenance{EnteredAt: time.Now().UTC().Format(time.RFC3339Nano)}
+		logrus.Info("✓ Maintenance entered")
+	}
+
+	startedAt := time.Now()
+	var point *snapshot.Database
+	if c.target != nil && c.dbErr == nil {
+		if c.target.Postgres != nil {
+			var err error
+			c.pgSnapshot, err = database.ExportSnapshot(c.target.Postgres)
+			if err != nil {
+				// The dump still runs, just not pinned to the start of the window
+				logrus.Warnf("⚠️  Failed to export a database snapshot, the dump will be taken after the API export: %v", err)
+			} else {
+				startedAt = c.pgSnapshot.Time
+				c.target.FromSnapshot = c.pgSnapshot.ID
+				point = &snapshot.Database{
+					Engine:     database.EnginePostgres,
+					Timestamp:  startedAt.Format(time.RFC3339Nano),
+					LSN:        c.pgSnapshot.LSN,
+					TxSnapshot: c.pgSnapshot.TxSnapshot,
+				}
+				logrus.Infof("✓ Database snapshot %s exported at LSN %s", c.pgSnapshot.ID, c.pgSnapshot.LSN)
+			}
+		} else {
+			// A SQLite snapshot is a complete copy, so it is taken right away
+			var size int64
+			size, c.dbErr = c.target.TakeSQLiteSnapshot()
+			if c.dbErr == nil {
+				point = &snapshot.Database{
+					Engine:    database.EngineSQLite,
+					Timestamp: startedAt.UTC().Format(time.RFC3339Nano),
+				}
+				logrus.Infof("✓ SQLite snapshot taken (%d bytes)", size)
+			} else {
+				logrus.Warnf("⚠️  SQLite snapshot failed: %v", c.dbErr)
+			}
+		}
+	}
+
+	c.record = snapshot.New(startedAt)
+	c.record.Database = point
+	c.record.Maintenance = maintenance
+	return c, nil
+}
+
+// endWindow marks the end of the API export and leaves maintenance
+// The pinned database state stays available for addDatabase
+func (c *consistentBackup) endWindow() {
+	if c.finishedAt.IsZero() {
+		c.finishedAt = time.Now()
+	}
+	if !c.entered {
+		return
+	}
+	c.entered = false
+	logrus.Info("Leaving maintenance...")
+	if err := c.hook.Run(snapshot.PhaseExit); err != nil {
+		logrus.Errorf("❌ Failed to leave maintenance, check the instance: %v", err)
+		c.record.Maintenance.Error = err.Error()
+		return
+	}
+	c.record.Maintenance.ExitedAt = time.Now().UTC().Format(time.RFC3339Nano)
+	logrus.Info("✓ Maintenance left")
+}
+
+// addDatabase streams the database as of the start of the window into the open backup ZIP
+func (c *consistentBackup) addDatabase(cfg *config.Config, zipWriter *zip.Writer) error {
+	if c.dbErr != nil {
+		return c.dbErr
+	}
+	if c.target == nil {
+		return nil
+	}
+	defer c.releaseSnapshot()
+
+	logrus.Infof("Adding database backup for: %s", c.target)
+
+	// The dump reads from the exported snapshot or the SQLite copy taken at the start
+	size, err := c.target.WriteToZip(cfg, zipWriter, false)
+	if err != nil {
+		return err
+	}
+
+	logrus.Infof("Database backup added successfully (%d bytes)", size)
+	return nil
+}
+
+// finish computes the watermarks from the exported entities and writes snapshot.json into the open backup ZIP
+func (c *consistentBackup) finish(zipWriter *zip.Writer, summary *backup.Summary) (*SnapshotResult, error) {
+	c.endWindow()
+
+	c.record.Finish(&archive.Archive{Entities: summary.Entities}, c.finishedAt)
+	if c.record.Database == nil {
+		logrus.Warn("⚠️  No database state was pinned; the watermarks only describe the API export window")
+	}
+
+	if err := snapshot.Write(zipWriter, c.record); err != nil {
+		return nil, fmt.Errorf("failed to write snapshot record to ZIP: %w", err)
+	}
+
+	logrus.Infof("✓ Consistent snapshot recorded (window %.1fs)", c.record.WindowSecs)
+	if len(c.record.Changed) > 0 {
+		logrus.Warnf("⚠️  %d entities changed during the capture window and may be newer than the database dump:", len(c.record.Changed))
+		for i, change := range c.record.Changed {
+			if i == maxLoggedChanges {
+				logrus.Warnf("  ... and %d more (see %s)", len(c.record.Changed)-i, snapshot.Entry)
+				break
+			}
+			logrus.Warnf("  %s %s (updated %s)", change.Type, change.ID, time.Unix(change.UpdatedAt, 0).UTC().Format(time.RFC3339))
+		}
+	}
+	return newSnapshotResult(c.record), nil
+}
+
+// newSnapshotResult summarizes a snapshot record for command results
+func newSnapshotResult(record *snapshot.Record) *SnapshotResult {
+	result := &SnapshotResult{
+		StartedAt:     record.StartedAt,
+		WindowSeconds: record.WindowSecs,
+		Changed:       len(record.Changed),
+		Maintenance:   record.Maintenance != nil,
+	}
+	if record.Database != nil {
+		result.LSN = record.Database.LSN
+	}
+	return result
+}
+
+// close releases the database snapshot and leaves maintenance if that has not happened yet
+func (c *consistentBackup) close() {
+	c.endWindow()
+	c.releaseSnapshot()
+}
+
+// releaseSnapshot ends the transaction that holds the exported PostgreSQL snapshot
+// and removes the SQLite copy
+func (c *consistentBackup) releaseSnapshot() {
+	if c.target != nil {
+		c.target.Release()
+	}
+	if c.pgSnapshot == nil {
+		return
+	}
+	if err := c.pgSnapshot.Release(); err != nil {
+		logrus.Debugf("Failed to release database snapshot: %v", err)
+	}
+	c.pgSnapshot = nil
+}
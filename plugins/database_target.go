@@ -0,0 +1,312 @@
+package plugins
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/vosiander/open-webui-backup/pkg/anonymize"
+	"github.com/vosiander/open-webui-backup/pkg/backup"
+	"github.com/vosiander/open-webui-backup/pkg/cli"
+	"github.com/vosiander/open-webui-backup/pkg/config"
+	"github.com/vosiander/open-webui-backup/pkg/database"
+	"github.com/vosiander/open-webui-backup/pkg/openwebui"
+)
+
+// databaseTarget is the PostgreSQL or SQLite database a command works on
+type databaseTarget struct {
+	Postgres     *database.DatabaseConfig // set for PostgreSQL
+	SQLitePath   string                   // set for SQLite
+	FromSnapshot string                   // exported PostgreSQL snapshot to dump from
+	DumpFormat   string                   // "plain" (default) or "custom" PostgreSQL dump format
+	Uncompressed bool                     // store the dump without compression
+	SnapshotFile string                   // SQLite copy taken by TakeSQLiteSnapshot
+	Anonymizer   *anonymize.Anonymizer    // rewrites the COPY data of plain PostgreSQL dumps
+}
+
+// resolveDatabaseTarget picks the database from flags and environment
+// An explicit --postgres-url wins over --data-dir, which wins over POSTGRES_URL;
+// without any of them the Open WebUI data directory is auto-detected
+func resolveDatabaseTarget(postgresURL, dataDir, pgBackend string) (*databaseTarget, error) {
+	if postgresURL == "" && dataDir == "" {
+		postgresURL = database.GetPostgresURLFromEnv()
+	}
+
+	if postgresURL != "" {
+		return resolvePostgresTarget(postgresURL, pgBackend)
+	}
+
+	path, err := database.FindSQLiteDatabase(dataDir)
+	if errors.Is(err, database.ErrNoDataDir) {
+		return nil, cli.Usage(fmt.Errorf("PostgreSQL connection URL or Open WebUI data directory is required (use --postgres-url, --data-dir, POSTGRES_URL or OWUI_DATA_DIR)"))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &databaseTarget{SQLitePath: path}, nil
+}
+
+// resolvePostgresTarget parses a PostgreSQL URL and applies the --pg-backend override
+func resolvePostgresTarget(postgresURL, pgBackend string) (*databaseTarget, error) {
+	dbConfig, err := database.ParsePostgresURL(postgresURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse PostgreSQL URL: %w", err)
+	}
+	if pgBackend != "" {
+		if err := database.ValidateBackend(pgBackend); err != nil {
+			return nil, cli.Usage(err)
+		}
+		dbConfig.Backend = pgBackend
+	}
+	return &databaseTarget{Postgres: dbConfig}, nil
+}
+
+// Engine returns the database engine of the target
+func (t *databaseTarget) Engine() string {
+	if t.Postgres != nil {
+		return database.EnginePostgres
+	}
+	return database.EngineSQLite
+}
+
+// String describes the target for logging (without the password)
+func (t *databaseTarget) String() string {
+	if t.Postgres != nil {
+		return database.FormatConnectionInfo(t.Postgres)
+	}
+	return "sqlite:" + t.SQLitePath
+}
+
+// Check verifies that the tools are available and the database is reachable
+func (t *databaseTarget) Check() error {
+	if t.Postgres == nil {
+		return database.CheckSQLiteAvailable()
+	}
+
+	// Check that the selected dump and restore backend can run
+	if err := database.CheckBackendAvailable(t.Postgres); err != nil {
+		return fmt.Errorf("PostgreSQL backend check failed: %w", err)
+	}
+	if err := database.TestConnection(t.Postgres); err != nil {
+		return fmt.Errorf("database connection test failed: %w", err)
+	}
+	return nil
+}
+
+// Metadata describes the dump or snapshot that Dump writes
+func (t *databaseTarget) Metadata(cfg *config.Config) *database.DatabaseBackupMetadata {
+	metadata := &database.DatabaseBackupMetadata{
+		BackupTimestamp: time.Now().UTC().Format(time.RFC3339),
+		Engine:          t.Engine(),
+		Compressed:      !t.Uncompressed,
+	}
+
+	if t.Postgres == nil {
+		version, err := database.GetSQLiteVersion()
+		if err != nil {
+			logrus.Warnf("Failed to get SQLite version: %v", err)
+			version = "unknown"
+		}
+		metadata.DatabaseName = database.SQLiteFileName
+		metadata.SQLiteVersion = version
+		metadata.DumpFormat = "sqlite"
+		t.addSchemaVersions(cfg, metadata)
+		return metadata
+	}
+
+	// Get PostgreSQL version for metadata
+	version, err := database.GetPostgresVersion(t.Postgres)
+	if err != nil {
+		logrus.Warnf("Failed to get PostgreSQL version: %v", err)
+		version = "unknown"
+	}
+	metadata.DatabaseName = t.Postgres.Database
+	metadata.PostgresVersion = version
+	metadata.DumpFormat = t.format()
+	metadata.Anonymization = t.Anonymization()
+	t.addSchemaVersions(cfg, metadata)
+	return metadata
+}
+
+// addSchemaVersions records the Alembic revision and Open WebUI version so restores can check them
+func (t *databaseTarget) addSchemaVersions(cfg *config.Config, metadata *database.DatabaseBackupMetadata) {
+	schema, err := t.Schema()
+	if err != nil {
+		logrus.Warnf("Failed to get Alembic revision: %v", err)
+	} else if schema.AlembicRevision == "" {
+		logrus.Warn("Database has no Alembic revision; restores of this backup cannot check schema compatibility")
+	} else {
+		metadata.AlembicRevision = schema.AlembicRevision
+	}
+	metadata.OpenWebUIVersion = openWebUIVersion(cfg)
+}
+
+// Schema reads the table count and Alembic revision of the target
+func (t *databaseTarget) Schema() (*database.SchemaInfo, error) {
+	if t.Postgres != nil {
+		return database.GetSchemaInfo(t.Postgres)
+	}
+	return database.GetSQLiteSchemaInfo(t.SQLitePath)
+}
+
+// openWebUIConfigured reports whether OPEN_WEBUI_URL is set
+// The configuration falls back to a placeholder URL, which database commands must not query
+func openWebUIConfigured() bool {
+	return os.Getenv("OPEN_WEBUI_URL") != ""
+}
+
+// openWebUIVersion asks the Open WebUI API for its version when OPEN_WEBUI_URL is set
+// Database commands work without the API, so failures only leave the version out
+func openWebUIVersion(cfg *config.Config) string {
+	if !openWebUIConfigured() {
+		return ""
+	}
+	version, err := openwebui.NewClient(cfg.OpenWebUIURL, cfg.OpenWebUIAPIKey).GetVersion()
+	if err != nil {
+		logrus.Warnf("Failed to get Open WebUI version: %v", err)
+		return ""
+	}
+	return version
+}
+
+// Anonymization describes the ruleset the dump is anonymized with, or nil
+func (t *databaseTarget) Anonymization() *database.Anonymization {
+	if t.Anonymizer == nil {
+		return nil
+	}
+	return &database.Anonymization{
+		Ruleset:     t.Anonymizer.Ruleset().Name,
+		Fingerprint: t.Anonymizer.Ruleset().Fingerprint(),
+	}
+}
+
+// format returns the PostgreSQL dump format
+func (t *databaseTarget) format() string {
+	if t.DumpFormat == "" {
+		return "plain"
+	}
+	return t.DumpFormat
+}
+
+// Dump streams a PostgreSQL dump or a copy of the SQLite database into w and returns its size
+func (t *databaseTarget) Dump(w io.Writer, verbose bool) (int64, error) {
+	if t.Postgres == nil {
+		if t.SnapshotFile != "" {
+			return database.CopySQLiteSnapshot(t.SnapshotFile, w)
+		}
+		n, err := database.WriteSQLiteSnapshot(t.SQLitePath, w)
+		if err != nil {
+			return n, fmt.Errorf("failed to create SQLite snapshot: %w", err)
+		}
+		return n, nil
+	}
+
+	dumpOptions := &database.DumpOptions{
+		Format:       t.format(),
+		NoOwner:      true,
+		NoPrivileges: true,
+		NoCompress:   t.Uncompressed,
+		Verbose:      verbose,
+		Snapshot:     t.FromSnapshot,
+	}
+	if t.Anonymizer == nil {
+		n, err := database.WriteDump(t.Postgres, dumpOptions, w)
+		if err != nil {
+			return n, fmt.Errorf("failed to create database dump: %w", err)
+		}
+		return n, nil
+	}
+
+	aw := t.Anonymizer.Writer(w)
+	n, err := database.WriteDump(t.Postgres, dumpOptions, aw)
+	if err == nil {
+		err = aw.Close()
+	}
+	if err != nil {
+		return n, fmt.Errorf("failed to create anonymized database dump: %w", err)
+	}
+	stats := t.Anonymizer.Stats
+	logrus.Infof("Anonymized %d values in %d rows with ruleset %s (%s)", stats.Values, stats.Rows, t.Anonymizer.Ruleset().Name, t.Anonymizer.Ruleset().Fingerprint())
+	if unmatched := t.Anonymizer.Unmatched(); len(unmatched) > 0 {
+		logrus.Warnf("⚠️  Anonymization rules matched no dumped column: %s", strings.Join(unmatched, ", "))
+	}
+	return n, nil
+}
+
+// TakeSQLiteSnapshot copies the SQLite database now, so a later Dump writes this copy
+// The copy lives in a temporary directory that Release removes
+func (t *databaseTarget) TakeSQLiteSnapshot() (int64, error) {
+	tempDir, err := os.MkdirTemp("", "owui-sqlite-")
+	if err != nil {
+		return 0, fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	snapshot := filepath.Join(tempDir, database.SQLiteFileName)
+	if err := database.SnapshotSQLiteTo(t.SQLitePath, snapshot); err != nil {
+		os.RemoveAll(tempDir)
+		return 0, fmt.Errorf("failed to create SQLite snapshot: %w", err)
+	}
+	info, err := os.Stat(snapshot)
+	if err != nil {
+		os.RemoveAll(tempDir)
+		return 0, fmt.Errorf("failed to create SQLite snapshot: %w", err)
+	}
+	t.SnapshotFile = snapshot
+	return info.Size(), nil
+}
+
+// Release removes a copy taken with TakeSQLiteSnapshot
+func (t *databaseTarget) Release() {
+	if t.SnapshotFile == "" {
+		return
+	}
+	if err := os.RemoveAll(filepath.Dir(t.SnapshotFile)); err != nil {
+		logrus.Warnf("Failed to remove SQLite snapshot: %v", err)
+	}
+	t.SnapshotFile = ""
+}
+
+// WriteToZip streams the database and its metadata into an open backup ZIP and returns the dump size
+func (t *databaseTarget) WriteToZip(cfg *config.Config, zipWriter *zip.Writer, verbose bool) (int64, error) {
+	var size int64
+	err := backup.WriteDatabase(zipWriter, t.Metadata(cfg), func(w io.Writer) error {
+		var err error
+		size, err = t.Dump(w, verbose)
+		return err
+	})
+	if err != nil {
+		return size, fmt.Errorf("failed to write database to ZIP: %w", err)
+	}
+	return size, nil
+}
+
+// Purge drops all tables and views, or lists them when dryRun is set
+func (t *databaseTarget) Purge(dryRun bool) error {
+	if t.Postgres == nil {
+		return database.PurgeSQLite(t.SQLitePath, dryRun)
+	}
+	return database.PurgeDatabase(t.Postgres, dryRun)
+}
+
+// Result describes the target in a command result
+func (t *databaseTarget) Result() *DatabaseResult {
+	if t.Postgres == nil {
+		return &DatabaseResult{Engine: database.EngineSQLite, Database: t.SQLitePath}
+	}
+	return &DatabaseResult{
+		Engine:   database.EnginePostgres,
+		Database: t.Postgres.Database,
+		Host:     t.Postgres.Host,
+		Backend:  database.ResolveBackend(t.Postgres),
+	}
+}
+
+// isDatabaseConfigured reports whether POSTGRES_URL or an Open WebUI SQLite database is configured
+func isDatabaseConfigured() bool {
+	return database.IsPostgresURLSet() || database.IsSQLiteConfigured()
+}
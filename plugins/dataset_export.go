@@ -0,0 +1,171 @@
+package plugins
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/vosiander/open-webui-backup/pkg/archive"
+	"github.com/vosiander/open-webui-backup/pkg/cli"
+	"github.com/vosiander/open-webui-backup/pkg/config"
+	"github.com/vosiander/open-webui-backup/pkg/dataset"
+	"github.com/vosiander/open-webui-backup/pkg/openwebui"
+)
+
+// DatasetExportPlugin turns rated conversations into fine-tuning datasets
+type DatasetExportPlugin struct {
+	format       string
+	file         string
+	identity     []string
+	out          string
+	rating       string
+	minScore     int
+	models       []string
+	tags         []string
+	since        string
+	until        string
+	dedup        bool
+	scrub        []string
+	scrubCommand string
+	systemPrompt string
+}
+
+// NewDatasetExportPlugin creates a new instance of the DatasetExportPlugin
+func NewDatasetExportPlugin() *DatasetExportPlugin {
+	return &DatasetExportPlugin{}
+}
+
+// Name returns the command name
+func (p *DatasetExportPlugin) Name() string {
+	return "dataset-export"
+}
+
+// Description returns the command description
+func (p *DatasetExportPlugin) Description() string {
+	return "Export rated conversations as a fine-tuning dataset (OpenAI chat or DPO preference JSONL)"
+}
+
+// SetupFlags configures the command-line flags
+func (p *DatasetExportPlugin) SetupFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&p.format, "format", dataset.FormatChat, "Dataset format: chat (fine-tuning) or dpo (preference pairs)")
+	cmd.Flags().StringVar(&p.file, "file", "", "Build from this backup file instead of the live instance")
+	cmd.Flags().StringSliceVar(&p.identity, "decrypt-identity", nil, "Age identity file(s) for encrypted backups (or use OWUI_DECRYPT_IDENTITY env variable)")
+	cmd.Flags().StringVarP(&p.out, "out", "o", "", "Output file (default: stdout)")
+	cmd.Flags().StringVar(&p.rating, "rating", "up", "Ratings to use for the chat format: up, down or any")
+	cmd.Flags().IntVar(&p.minScore, "min-score", 0, "Only ratings with at least this detailed score (1-10)")
+	cmd.Flags().StringSliceVar(&p.models, "model", nil, "Only ratings of these models (globs allowed)")
+	cmd.Flags().StringSliceVar(&p.tags, "tag", nil, "Only ratings with one of these feedback or chat tags")
+	cmd.Flags().StringVar(&p.since, "since", "", "Only ratings given on or after this date (YYYY-MM-DD or RFC3339)")
+	cmd.Flags().StringVar(&p.until, "until", "", "Only ratings given before this date (YYYY-MM-DD or RFC3339)")
+	cmd.Flags().BoolVar(&p.dedup, "dedup", true, "Drop records with the same content (ignoring case and whitespace)")
+	cmd.Flags().StringSliceVar(&p.scrub, "scrub", nil, "Built-in PII scrubbers to apply: "+strings.Join(dataset.ScrubberNames(), ", "))
+	cmd.Flags().StringVar(&p.scrubCommand, "scrub-command", "", "External command that scrubs each message (text on stdin, scrubbed text on stdout)")
+	cmd.Flags().StringVar(&p.systemPrompt, "system-prompt", "", "System message to prepend to conversations without one")
+}
+
+// Execute builds and writes the dataset
+func (p *DatasetExportPlugin) Execute(cfg *config.Config) error {
+	if (p.out == "" || p.out == "-") && cli.JSON() {
+		return stdoutReserved("--out")
+	}
+
+	options := &dataset.Options{
+		Format:       p.format,
+		Dedup:        p.dedup,
+		SystemPrompt: p.systemPrompt,
+		Filter: dataset.Filter{
+			MinScore: p.minScore,
+			Models:   p.models,
+			Tags:     p.tags,
+		},
+	}
+
+	switch p.rating {
+	case "up":
+		options.Filter.Rating = 1
+	case "down":
+		options.Filter.Rating = -1
+	case "any":
+	default:
+		return fmt.Errorf("invalid --rating %q (use up, down or any)", p.rating)
+	}
+
+	var err error
+	if options.Filter.Since, err = parseDate(p.since); err != nil {
+		return fmt.Errorf("invalid --since: %w", err)
+	}
+	if options.Filter.Until, err = parseDate(p.until); err != nil {
+		return fmt.Errorf("invalid --until: %w", err)
+	}
+
+	for _, name := range p.scrub {
+		scrubber, err := dataset.BuiltinScrubber(name)
+		if err != nil {
+			return err
+		}
+		options.Scrubbers = append(options.Scrubbers, scrubber)
+	}
+	if p.scrubCommand != "" {
+		scrubber, err := dataset.NewCommandScrubber(p.scrubCommand)
+		if err != nil {
+			return err
+		}
+		options.Scrubbers = append(options.Scrubbers, scrubber)
+	}
+
+	source, err := p.loadSource(cfg)
+	if err != nil {
+		return err
+	}
+	logrus.Infof("Loaded %d chats and %d feedbacks", len(source.Chats), len(source.Feedbacks))
+
+	result, err := dataset.Build(source, options)
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if p.out != "" && p.out != "-" {
+		f, err := os.Create(p.out)
+		if err != nil {
+			return fmt.Errorf("failed to create %s: %w", p.out, err)
+		}
+		defer f.Close()
+		w = f
+	}
+	if err := dataset.WriteJSONL(w, result.Records); err != nil {
+		return err
+	}
+
+	if result.Skipped > 0 {
+		logrus.Warnf("Skipped %d feedbacks whose chat or message was not found", result.Skipped)
+	}
+	logrus.Infof("Wrote %d %s records from %d of %d ratings (%d duplicates dropped)",
+		len(result.Records), p.format, result.Matched, result.Ratings, result.Duplicates)
+	return nil
+}
+
+// loadSource reads chats and feedbacks from a backup file or the live instance
+func (p *DatasetExportPlugin) loadSource(cfg *config.Config) (*dataset.Source, error) {
+	if p.file != "" {
+		identities, err := loadIdentityContents(p.identity, "")
+		if err != nil {
+			return nil, err
+		}
+		zipPath, cleanup, err := archive.Decrypt(p.file, identities)
+		if err != nil {
+			return nil, err
+		}
+		defer cleanup()
+		return dataset.FromArchive(zipPath)
+	}
+
+	if cfg.OpenWebUIAPIKey == "" {
+		return nil, fmt.Errorf("OPEN_WEBUI_API_KEY environment variable is required (or use --file)")
+	}
+	client := openwebui.NewClient(cfg.OpenWebUIURL, cfg.OpenWebUIAPIKey)
+	return dataset.FromLive(client)
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	"github.com/vosiander/open-webui-backup/pkg/cli"
 	"github.com/vosiander/open-webui-backup/pkg/config"
 	"github.com/vosiander/open-webui-backup/pkg/encryption"
 )
@@ -60,6 +61,9 @@ func (p *DecryptPlugin) Execute(cfg *config.Config) error {
 		return fmt.Errorf("failed to scan directory: %w", err)
 	}
 
+	stats := &decryptStats{Path: p.path, Decrypted: []string{}, Skipped: []string{}, Failed: map[string]string{}}
+	cli.SetResult(stats)
+
 	if len(encryptedFiles) == 0 {
 		logrus.Warnf("⚠️  No .age files found in %s", p.path)
 		return nil
@@ -68,7 +72,6 @@ func (p *DecryptPlugin) Execute(cfg *config.Config) error {
 	logrus.Infof("Decrypting %d file(s) from %s\n", len(encryptedFiles), p.path)
 
 	// Decrypt each file
-	stats := &decryptStats{}
 	for _, encryptedFile := range encryptedFiles {
 		if err := p.decryptSingleFile(encryptedFile, identityStr, log, stats); err != nil {
 			log.Warnf("Failed to decrypt %s: %v", filepath.Base(encryptedFile), err)
@@ -77,9 +80,13 @@ func (p *DecryptPlugin) Execute(cfg *config.Config) error {
 
 	// Print summary
 	logrus.Info("" + strings.Repeat("─", 50))
-	logrus.Infof("Summary: %d decrypted, %d skipped, %d failed", stats.decrypted, stats.skipped, stats.failed)
-	if stats.failed > 0 {
+	logrus.Infof("Summary: %d decrypted, %d skipped, %d failed", len(stats.Decrypted), len(stats.Skipped), len(stats.Failed))
+	if len(stats.Failed) > 0 {
 		logrus.Warn("⚠️  Some files failed to decrypt. Check the logs above for details.")
+		if len(stats.Decrypted) == 0 {
+			return fmt.Errorf("failed to decrypt %d file(s): %s", len(stats.Failed), failedNames(stats.Failed))
+		}
+		return cli.Partialf("failed to decrypt %d of %d file(s): %s", len(stats.Failed), len(encryptedFiles), failedNames(stats.Failed))
 	}
 
 	return nil
@@ -92,7 +99,7 @@ func (p *DecryptPlugin) decryptSingleFile(encryptedPath, identityContent string,
 	// Check if file is actually encrypted
 	if !encryption.IsEncrypted(encryptedPath) {
 		logrus.Infof("⊘ %s → skipped (not encrypted)", basename)
-		stats.skipped++
+		stats.Skipped = append(stats.Skipped, basename)
 		return nil
 	}
 
@@ -103,19 +110,19 @@ func (p *DecryptPlugin) decryptSingleFile(encryptedPath, identityContent string,
 	// Check if output file already exists
 	if _, err := os.Stat(outputPath); err == nil && !p.force {
 		logrus.Infof("⊘ %s → skipped (%s already exists, use --force)", basename, outputBasename)
-		stats.skipped++
+		stats.Skipped = append(stats.Skipped, basename)
 		return nil
 	}
 
 	// Decrypt the file
 	if err := encryption.DecryptFileWithIdentities(encryptedPath, outputPath, []string{identityContent}); err != nil {
 		logrus.Errorf("❌ %s → failed (%v)", basename, err)
-		stats.failed++
+		stats.Failed[basename] = err.Error()
 		return err
 	}
 
 	logrus.Infof("✓ %s → %s", basename, outputBasename)
-	stats.decrypted++
+	stats.Decrypted = append(stats.Decrypted, basename)
 	return nil
 }
 
@@ -141,9 +148,10 @@ func findEncryptedFiles(dir string) ([]string, error) {
 	return files, nil
 }
 
-// decryptStats tracks decryption statistics
+// decryptStats tracks decryption results; it is the result object of the decrypt command
 type decryptStats struct {
-	decrypted int
-	skipped   int
-	failed    int
+	Path      string            `json:"path"`
+	Decrypted []string          `json:"decrypted"`
+	Skipped   []string          `json:"skipped"`
+	Failed    map[string]string `json:"failed,omitempty"`
 }
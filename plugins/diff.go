@@ -0,0 +1,155 @@
+package plugins
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/vosiander/open-webui-backup/pkg/archive"
+	"github.com/vosiander/open-webui-backup/pkg/cli"
+	"github.com/vosiander/open-webui-backup/pkg/config"
+)
+
+// DiffPlugin compares two backup archives entity by entity
+type DiffPlugin struct {
+	oldFile         string
+	newFile         string
+	decryptIdentity []string
+	jsonOutput      bool
+	summaryOnly     bool
+}
+
+// NewDiffPlugin creates a new instance of the DiffPlugin
+func NewDiffPlugin() *DiffPlugin {
+	return &DiffPlugin{}
+}
+
+// Name returns the command name
+func (p *DiffPlugin) Name() string {
+	return "diff"
+}
+
+// Description returns the command description
+func (p *DiffPlugin) Description() string {
+	return "Show added, removed and modified entities between two backup files"
+}
+
+// SetupFlags configures the command-line flags
+func (p *DiffPlugin) SetupFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&p.oldFile, "old", "", "Older backup file (required)")
+	cmd.Flags().StringVar(&p.newFile, "new", "", "Newer backup file (required)")
+	cmd.Flags().StringSliceVar(&p.decryptIdentity, "decrypt-identity", nil, "Age identity file(s) for encrypted backups (or use OWUI_DECRYPT_IDENTITY env variable)")
+	cmd.Flags().BoolVar(&p.jsonOutput, "json", false, "Print the diff as JSON")
+	cmd.Flags().BoolVar(&p.summaryOnly, "summary", false, "Only print per-type counts")
+	cmd.MarkFlagRequired("old")
+	cmd.MarkFlagRequired("new")
+}
+
+// Execute compares the two backups
+func (p *DiffPlugin) Execute(cfg *config.Config) error {
+	identities, err := loadIdentityContents(p.decryptIdentity, "")
+	if err != nil {
+		return err
+	}
+
+	oldArchive, err := archive.LoadBackup(p.oldFile, identities)
+	if err != nil {
+		return fmt.Errorf("failed to load %s: %w", p.oldFile, err)
+	}
+
+	newArchive, err := archive.LoadBackup(p.newFile, identities)
+	if err != nil {
+		return fmt.Errorf("failed to load %s: %w", p.newFile, err)
+	}
+
+	result := archive.Diff(oldArchive, newArchive)
+
+	if cli.JSON() {
+		cli.SetResult(result)
+		return nil
+	}
+	if p.jsonOutput {
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal diff: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	p.printText(result)
+	return nil
+}
+
+// printText prints the diff in a human-readable format
+func (p *DiffPlugin) printText(result *archive.DiffResult) {
+	fmt.Printf("--- %s\n+++ %s\n\n", result.Old, result.New)
+
+	if !result.HasChanges() {
+		fmt.Println("No differences.")
+		return
+	}
+
+	for _, t := range result.Types {
+		fmt.Printf("%s: %d added, %d removed, %d modified, %d unchanged\n",
+			strings.Title(t.Type), t.Added, t.Removed, t.Modified, t.Unchanged)
+		if p.summaryOnly {
+			continue
+		}
+
+		for _, change := range t.Changes {
+			switch change.Kind {
+			case archive.ChangeAdded:
+				fmt.Printf("  + %s\n", entityLabel(change))
+			case archive.ChangeRemoved:
+				fmt.Printf("  - %s\n", entityLabel(change))
+			case archive.ChangeModified:
+				fmt.Printf("  ~ %s\n", entityLabel(change))
+				printFieldChanges(change.Fields, "      ")
+			}
+		}
+		fmt.Println()
+	}
+
+	logrus.Debugf("Compared %s and %s", result.Old, result.New)
+}
+
+// printFieldChanges prints field-level differences with the given indentation
+func printFieldChanges(fields []archive.FieldChange, indent string) {
+	for _, field := range fields {
+		if field.Diff != "" {
+			fmt.Printf("%s%s:\n", indent, field.Field)
+			for _, line := range strings.Split(strings.TrimSuffix(field.Diff, "\n"), "\n") {
+				fmt.Printf("%s  %s\n", indent, line)
+			}
+			continue
+		}
+		fmt.Printf("%s%s: %s → %s\n", indent, field.Field, formatValue(field.Old), formatValue(field.New))
+	}
+}
+
+// entityLabel formats an entity for display
+func entityLabel(change archive.EntityChange) string {
+	if change.Name != "" && change.Name != change.Key {
+		return fmt.Sprintf("%s (%s)", change.Name, change.Key)
+	}
+	return change.Key
+}
+
+// formatValue renders a JSON value compactly for display
+func formatValue(v interface{}) string {
+	if v == nil {
+		return "(none)"
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	s := string(data)
+	if len(s) > 120 {
+		s = s[:117] + "..."
+	}
+	return s
+}
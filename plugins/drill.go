@@ -0,0 +1,117 @@
+package plugins
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/vosiander/open-webui-backup/pkg/cli"
+	"github.com/vosiander/open-webui-backup/pkg/config"
+	"github.com/vosiander/open-webui-backup/pkg/drill"
+	"github.com/vosiander/open-webui-backup/pkg/signing"
+)
+
+// DrillPlugin restores the latest backup into a scratch instance and verifies the result
+type DrillPlugin struct {
+	path             string
+	file             string
+	decryptIdentity  []string
+	targetURL        string
+	targetAPIKey     string
+	reportDir        string
+	trustedKeys      []string
+	requireSignature bool
+}
+
+// NewDrillPlugin creates a new instance of the DrillPlugin
+func NewDrillPlugin() *DrillPlugin {
+	return &DrillPlugin{}
+}
+
+// Name returns the command name
+func (p *DrillPlugin) Name() string {
+	return "drill"
+}
+
+// Description returns the command description
+func (p *DrillPlugin) Description() string {
+	return "Restore the latest backup into a scratch Open WebUI instance and verify counts and content hashes"
+}
+
+// SetupFlags configures the command-line flags
+func (p *DrillPlugin) SetupFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&p.path, "path", "", "Directory containing backup files and identity.txt (required)")
+	cmd.Flags().StringVar(&p.file, "file", "", "Specific backup file to drill (optional, auto-detects newest backup if not provided)")
+	cmd.Flags().StringSliceVar(&p.decryptIdentity, "decrypt-identity", nil, "Age identity file(s) (or use OWUI_DECRYPT_IDENTITY env variable, defaults to identity.txt in --path)")
+	cmd.Flags().StringVar(&p.targetURL, "target-url", "", "Scratch Open WebUI URL to restore into (or use OWUI_DRILL_URL env variable)")
+	cmd.Flags().StringVar(&p.targetAPIKey, "target-api-key", "", "API key for the scratch instance (or use OWUI_DRILL_API_KEY env variable)")
+	cmd.Flags().StringVar(&p.reportDir, "report-dir", "", "Directory to write the JSON drill report (default: <path>/drills)")
+	cmd.Flags().StringSliceVar(&p.trustedKeys, "trusted-keys", nil, "Trusted signing public key(s) or key file(s) (or use OWUI_TRUSTED_KEYS env variable)")
+	cmd.Flags().BoolVar(&p.requireSignature, "require-signature", false, "Fail on unsigned or untrusted backups (or use OWUI_REQUIRE_SIGNATURE env variable)")
+	cmd.MarkFlagRequired("path")
+}
+
+// Execute runs the restore drill
+func (p *DrillPlugin) Execute(cfg *config.Config) error {
+	log := logrus.WithField("plugin", p.Name())
+
+	// Determine backup file to drill
+	backupFile := p.file
+	if backupFile != "" {
+		if !filepath.IsAbs(backupFile) {
+			backupFile = filepath.Join(p.path, backupFile)
+		}
+	} else {
+		found, err := drill.LatestBackup(p.path)
+		if err != nil {
+			return fmt.Errorf("failed to find backup file: %w", err)
+		}
+		backupFile = found
+		log.Infof("Auto-detected backup file: %s", filepath.Base(backupFile))
+	}
+
+	identities, err := loadIdentityContents(p.decryptIdentity, p.path)
+	if err != nil {
+		return err
+	}
+
+	trusted, err := signing.GetTrustedKeysFromEnvOrFlag(p.trustedKeys)
+	if err != nil {
+		return fmt.Errorf("failed to load trusted keys: %w", err)
+	}
+
+	opts := &drill.Options{
+		TargetURL:        p.targetURL,
+		TargetAPIKey:     p.targetAPIKey,
+		ProductionURL:    cfg.OpenWebUIURL,
+		Identities:       identities,
+		TrustedKeys:      trusted,
+		RequireSignature: signing.IsSignatureRequired(p.requireSignature),
+	}
+	opts.LoadFromEnv()
+
+	report, runErr := drill.Run(backupFile, opts, nil)
+	report.Log()
+	cli.SetResult(report)
+
+	reportDir := p.reportDir
+	if reportDir == "" {
+		reportDir = filepath.Join(p.path, "drills")
+	}
+	reportPath, err := drill.WriteReport(report, reportDir)
+	if err != nil {
+		logrus.Warnf("Failed to write drill report: %v", err)
+	} else {
+		logrus.Infof("Report: %s", reportPath)
+	}
+
+	if runErr != nil {
+		return fmt.Errorf("drill failed: %w", runErr)
+	}
+	if !report.Passed {
+		return cli.Verification(fmt.Errorf("drill failed: scratch instance does not match the backup"))
+	}
+
+	return nil
+}
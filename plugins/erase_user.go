@@ -0,0 +1,255 @@
+package plugins
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/vosiander/open-webui-backup/pkg/cli"
+	"github.com/vosiander/open-webui-backup/pkg/config"
+	"github.com/vosiander/open-webui-backup/pkg/encryption"
+	"github.com/vosiander/open-webui-backup/pkg/erasure"
+	"github.com/vosiander/open-webui-backup/pkg/openwebui"
+	"github.com/vosiander/open-webui-backup/pkg/signing"
+	"github.com/vosiander/open-webui-backup/pkg/userexport"
+)
+
+// EraseUserPlugin deletes a user's data from the live instance and from stored backups
+type EraseUserPlugin struct {
+	user             string
+	reason           string
+	backupsDirs      []string
+	ledger           string
+	decryptIdentity  []string
+	encryptRecipient []string
+	signKey          string
+	skipLive         bool
+	skipBackups      bool
+	reapply          bool
+	force            bool
+	waitDuration     time.Duration
+}
+
+// NewEraseUserPlugin creates a new instance of the EraseUserPlugin
+func NewEraseUserPlugin() *EraseUserPlugin {
+	return &EraseUserPlugin{}
+}
+
+// Name returns the command name
+func (p *EraseUserPlugin) Name() string {
+	return "erase-user"
+}
+
+// Description returns the command description
+func (p *EraseUserPlugin) Description() string {
+	return "Erase a user's data from Open WebUI and rewrite stored backups without it (right to erasure)"
+}
+
+// SetupFlags configures the command-line flags
+func (p *EraseUserPlugin) SetupFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&p.user, "user", "u", "", "User email or ID (required unless --reapply)")
+	cmd.Flags().StringVar(&p.reason, "reason", "", "Reason or ticket reference recorded in the ledger")
+	cmd.Flags().StringSliceVar(&p.backupsDirs, "backups-dir", nil, "Directory with stored backups, repeatable (default: OWUI_BACKUPS_DIR)")
+	cmd.Flags().StringVar(&p.ledger, "ledger", "", "Erasure ledger file (default: erasure-ledger.json in the first backups directory)")
+	cmd.Flags().StringSliceVar(&p.decryptIdentity, "decrypt-identity", nil, "Age identity file(s) for encrypted backups (or use OWUI_DECRYPT_IDENTITY env variable)")
+	cmd.Flags().StringSliceVar(&p.encryptRecipient, "encrypt-recipient", nil, "Age public key(s) to re-encrypt rewritten backups (or use OWUI_ENCRYPTED_RECIPIENT env variable)")
+	cmd.Flags().StringVar(&p.signKey, "sign-key", "", "Ed25519 key file to re-sign signed backups (or use OWUI_SIGNING_KEY env variable)")
+	cmd.Flags().BoolVar(&p.skipLive, "skip-live", false, "Do not delete anything on the live instance")
+	cmd.Flags().BoolVar(&p.skipBackups, "skip-backups", false, "Do not rewrite stored backups")
+	cmd.Flags().BoolVar(&p.reapply, "reapply", false, "Apply every request in the ledger to the stored backups (e.g. after restoring old backups)")
+	cmd.Flags().BoolVarP(&p.force, "force", "f", false, "Actually perform the erasure (default is a dry run)")
+	cmd.Flags().DurationVarP(&p.waitDuration, "wait", "w", 5*time.Second, "Wait duration before performing deletions")
+}
+
+// Execute plans the erasure and, with --force, performs it
+func (p *EraseUserPlugin) Execute(cfg *config.Config) error {
+	if p.user == "" && !p.reapply {
+		return cli.Usage(fmt.Errorf("--user is required (or use --reapply)"))
+	}
+
+	dirs := p.backupsDirs
+	if len(dirs) == 0 {
+		dirs = []string{cfg.BackupsDir}
+	}
+	ledgerPath := p.ledger
+	if ledgerPath == "" {
+		ledgerPath = filepath.Join(dirs[0], erasure.LedgerFile)
+	}
+	ledger, err := erasure.LoadLedger(ledgerPath)
+	if err != nil {
+		return err
+	}
+
+	var requests []*erasure.Request
+	var plan *erasure.Plan
+	var client *openwebui.Client
+	isNew := false
+
+	if p.reapply {
+		requests = ledger.Requests
+		if len(requests) == 0 {
+			logrus.Infof("Ledger %s has no erasure requests, nothing to reapply", ledgerPath)
+			return nil
+		}
+		logrus.Infof("Reapplying %d erasure request(s) from %s", len(requests), ledgerPath)
+	} else {
+		var previous *erasure.Request
+		if found := ledger.Find(p.user); len(found) > 0 {
+			previous = found[len(found)-1]
+			logrus.Infof("User %s already has erasure request %s from %s", previous.UserID, previous.ID, previous.RequestedAt.Format(time.RFC3339))
+		}
+
+		var user *openwebui.User
+		if !p.skipLive {
+			if cfg.OpenWebUIAPIKey == "" {
+				return fmt.Errorf("OPEN_WEBUI_API_KEY environment variable is required (or use --skip-live)")
+			}
+			client = openwebui.NewClient(cfg.OpenWebUIURL, cfg.OpenWebUIAPIKey)
+			users, err := client.GetAllUsers()
+			if err != nil {
+				return fmt.Errorf("failed to fetch users: %w", err)
+			}
+			user, err = userexport.FindUser(users, p.user)
+			if err != nil && previous == nil {
+				return err
+			}
+		}
+
+		request := previous
+		switch {
+		case user != nil && (previous == nil || previous.UserID != user.ID):
+			request = erasure.NewRequest(user.ID, user.Email, p.reason)
+			isNew = true
+		case request == nil:
+			// Without the live instance only a user ID can be resolved
+			if strings.Contains(p.user, "@") {
+				return fmt.Errorf("user %q is not in the ledger; pass the user ID when skipping the live instance", p.user)
+			}
+			request = erasure.NewRequest(p.user, "", p.reason)
+			isNew = true
+		}
+		requests = []*erasure.Request{request}
+
+		if user != nil {
+			plan, err = erasure.PlanLive(client, *user)
+			if err != nil {
+				return err
+			}
+			p.logPlan(plan)
+		} else if !p.skipLive {
+			logrus.Infof("User %s no longer exists on the instance; only backups are processed", request.UserID)
+		}
+	}
+
+	backupOptions, err := p.backupOptions(dirs[0])
+	if err != nil {
+		return err
+	}
+
+	if !p.force {
+		if !p.skipBackups && len(requests) > 0 {
+			backupOptions.DryRun = true
+			if _, err := erasure.EraseBackups(dirs, requests, backupOptions); err != nil {
+				return err
+			}
+		}
+		logrus.Info("[Dry Run] Run with --force to erase the user's data.")
+		return nil
+	}
+
+	logrus.Warn("⚠️  WARNING: This permanently deletes the user's data and rewrites stored backups")
+	logrus.Infof("Waiting %s before proceeding (press Ctrl+C to abort)...", p.waitDuration)
+	if err := cli.Wait(p.waitDuration); err != nil {
+		logrus.Warn("Erasure aborted, nothing was changed")
+		return err
+	}
+
+	if plan != nil && !p.reapply {
+		requests[0].Live = plan.Execute(client)
+	}
+	// Record the request before touching backups so an interrupted run can be resumed with --reapply
+	if isNew {
+		ledger.Add(requests[0])
+	}
+	if err := ledger.Save(); err != nil {
+		return err
+	}
+	logrus.Infof("Erasure request recorded in %s", ledger.Path())
+
+	failed := 0
+	if !p.skipBackups {
+		failed, err = erasure.EraseBackups(dirs, requests, backupOptions)
+		if err != nil {
+			return err
+		}
+		if err := ledger.Save(); err != nil {
+			return err
+		}
+	}
+
+	if p.reapply {
+		cli.SetResult(requests)
+	} else {
+		cli.SetResult(requests[0])
+	}
+	if plan != nil && !p.reapply && len(requests[0].Live.Errors) > 0 {
+		return cli.Partialf("erasure incomplete: %d live deletion(s) failed", len(requests[0].Live.Errors))
+	}
+	if failed > 0 {
+		return cli.Partialf("erasure incomplete: %d backup(s) could not be rewritten", failed)
+	}
+	logrus.Info("Erasure complete")
+	return nil
+}
+
+// backupOptions loads the keys needed to rewrite encrypted and signed backups
+func (p *EraseUserPlugin) backupOptions(identityDir string) (*erasure.BackupOptions, error) {
+	identities, err := loadIdentityContents(p.decryptIdentity, identityDir)
+	if err != nil {
+		return nil, err
+	}
+	signKey, err := signing.GetSigningKeyFromEnvOrFlag(p.signKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load signing key: %w", err)
+	}
+	// Recipients are optional; encrypted backups fail individually without them
+	recipients, err := encryption.GetEncryptRecipientsFromEnvOrFlag(p.encryptRecipient)
+	if err != nil && len(p.encryptRecipient) > 0 {
+		return nil, fmt.Errorf("failed to get encryption recipients: %w", err)
+	}
+
+	return &erasure.BackupOptions{
+		Identities: identities,
+		Recipients: recipients,
+		SigningKey: signKey,
+	}, nil
+}
+
+// logPlan prints what would be deleted on the live instance
+func (p *EraseUserPlugin) logPlan(plan *erasure.Plan) {
+	logrus.Infof("Live data of %s (%s):", plan.User.Email, plan.User.ID)
+	for _, item := range []struct {
+		name  string
+		count int
+	}{
+		{"chats", len(plan.Chats)},
+		{"files", len(plan.Files)},
+		{"knowledge bases", len(plan.Knowledge)},
+		{"prompts", len(plan.Prompts)},
+		{"models", len(plan.Models)},
+		{"tools", len(plan.Tools)},
+		{"feedbacks", len(plan.Feedbacks)},
+		{"memories", len(plan.Memories)},
+	} {
+		if item.count > 0 {
+			logrus.Infof("  - %s: %d items", item.name, item.count)
+		}
+	}
+	logrus.Infof("  - the user account itself (%d items in total)", plan.Count()+1)
+	for _, note := range plan.Notes {
+		logrus.Warn(note)
+	}
+}
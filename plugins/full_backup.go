@@ -1,6 +1,7 @@
 package plugins
 
 import (
+	"archive/zip"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -10,25 +11,35 @@ import (
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/vosiander/open-webui-backup/pkg/backup"
+	"github.com/vosiander/open-webui-backup/pkg/cli"
 	"github.com/vosiander/open-webui-backup/pkg/config"
-	"github.com/vosiander/open-webui-backup/pkg/database"
 	"github.com/vosiander/open-webui-backup/pkg/encryption"
+	"github.com/vosiander/open-webui-backup/pkg/metrics"
 	"github.com/vosiander/open-webui-backup/pkg/openwebui"
+	"github.com/vosiander/open-webui-backup/pkg/signing"
+	"github.com/vosiander/open-webui-backup/pkg/vectorstore"
 )
 
 // FullBackupPlugin creates a backup with automatic identity management
 type FullBackupPlugin struct {
-	path      string
-	database  bool
-	prompts   bool
-	tools     bool
-	knowledge bool
-	models    bool
-	files     bool
-	chats     bool
-	users     bool
-	groups    bool
-	feedbacks bool
+	path        string
+	sign        bool
+	signKey     string
+	database    bool
+	vectors     bool
+	uploads     bool
+	uploadPaths uploadFlags
+	consistent  bool
+	maintHook   string
+	prompts     bool
+	tools       bool
+	knowledge   bool
+	models      bool
+	files       bool
+	chats       bool
+	users       bool
+	groups      bool
+	feedbacks   bool
 }
 
 // NewFullBackupPlugin creates a new instance of the FullBackupPlugin
@@ -50,7 +61,14 @@ func (p *FullBackupPlugin) Description() string {
 func (p *FullBackupPlugin) SetupFlags(cmd *cobra.Command) {
 	cmd.Flags().StringVar(&p.path, "path", "", "Directory for identity files and backup output (required)")
 	cmd.MarkFlagRequired("path")
-	cmd.Flags().BoolVar(&p.database, "database", false, "Include database backup (requires POSTGRES_URL env variable)")
+	cmd.Flags().BoolVar(&p.sign, "sign", false, "Sign the backup with signing.key from --path (generated if missing)")
+	cmd.Flags().StringVar(&p.signKey, "sign-key", "", "Sign the backup with an Ed25519 key file (or use OWUI_SIGNING_KEY env variable)")
+	cmd.Flags().BoolVar(&p.database, "database", false, "Include database backup (requires POSTGRES_URL or OWUI_DATA_DIR env variable)")
+	cmd.Flags().BoolVar(&p.vectors, "vectors", false, "Include knowledge embeddings from Chroma or pgvector (auto-enabled if a vector store is configured)")
+	cmd.Flags().BoolVar(&p.uploads, "uploads", false, "Include uploaded files and other assets from the data directory (requires OWUI_DATA_DIR env variable)")
+	p.uploadPaths.setup(cmd, "uploads-")
+	cmd.Flags().BoolVar(&p.consistent, "consistent", false, "Capture the database at the start of the API export and record a point-in-time snapshot")
+	cmd.Flags().StringVar(&p.maintHook, "maintenance-hook", "", "Webhook URL or command run before and after a --consistent capture (implies --consistent)")
 	cmd.Flags().BoolVar(&p.prompts, "prompts", false, "Include only prompts in backup")
 	cmd.Flags().BoolVar(&p.tools, "tools", false, "Include only tools in backup")
 	cmd.Flags().BoolVar(&p.knowledge, "knowledge", false, "Include only knowledge bases in backup")
@@ -88,8 +106,29 @@ func (p *FullBackupPlugin) Execute(cfg *config.Config) error {
 		logrus.Info("✓ Using existing age identity keypair")
 	}
 
+	// Resolve signing key: explicit key file/env first, then signing.key in path
+	signKey, err := signing.GetSigningKeyFromEnvOrFlag(p.signKey)
+	if err != nil {
+		return fmt.Errorf("failed to load signing key: %w", err)
+	}
+	if signKey == nil && p.sign {
+		var generated bool
+		signKey, generated, err = signing.EnsureSigningKeyFiles(p.path)
+		if err != nil {
+			return fmt.Errorf("failed to ensure signing key files: %w", err)
+		}
+		if generated {
+			logrus.Info("✓ Generated new signing keypair")
+		} else {
+			logrus.Info("✓ Using existing signing keypair")
+		}
+	}
+
 	// Create client
 	client := openwebui.NewClient(cfg.OpenWebUIURL, cfg.OpenWebUIAPIKey)
+	if err := checkAPIKey(client); err != nil {
+		return err
+	}
 
 	// Determine what to backup
 	options := &backup.SelectiveBackupOptions{}
@@ -132,28 +171,102 @@ func (p *FullBackupPlugin) Execute(cfg *config.Config) error {
 	tempFile := backupPath + ".tmp"
 	defer os.Remove(tempFile) // Ensure cleanup
 
-	// Perform the backup
-	if err := backup.BackupSelective(client, tempFile, options, nil); err != nil {
+	// Auto-enable database backup if POSTGRES_URL or a SQLite database is configured and flag not explicitly set
+	includeDatabase := p.database
+	if !includeDatabase && isDatabaseConfigured() {
+		includeDatabase = true
+		log.Info("Database configuration detected, including database backup automatically")
+	}
+
+	// A consistent backup pins the database before the API export starts
+	var consistent *consistentBackup
+	if p.consistent || p.maintHook != "" {
+		consistent, err = startConsistentBackup(includeDatabase, p.maintHook)
+		if err != nil {
+			return err
+		}
+		defer consistent.close()
+	}
+
+	// The API export and the database dump are streamed into the same temporary ZIP
+	zipFile, err := backup.CreateZip(tempFile)
+	if err != nil {
 		return fmt.Errorf("failed to create backup: %w", err)
 	}
+	zipWriter := zip.NewWriter(zipFile)
 
-	// Auto-enable database backup if POSTGRES_URL is set and flag not explicitly set
-	includeDatabase := p.database
-	if !includeDatabase && database.IsPostgresURLSet() {
-		includeDatabase = true
-		log.Info("POSTGRES_URL detected, including database backup automatically")
+	// Perform the backup
+	summary, err := backup.WriteSelective(client, zipWriter, options, nil)
+	if consistent != nil {
+		consistent.endWindow()
+	}
+	if err != nil {
+		closeZip(zipFile, zipWriter)
+		return fmt.Errorf("failed to create backup: %w", err)
+	}
+	result := &BackupResult{
+		File:   backupPath,
+		Items:  summary.Items,
+		Failed: summary.Failed,
 	}
 
 	// Conditionally add database backup to the ZIP
 	if includeDatabase {
-		if err := p.addDatabaseBackupToZip(tempFile, log); err != nil {
-			logrus.Warnf("Database backup skipped: %v", err)
+		addDatabase := func() error { return p.addDatabaseBackupToZip(cfg, zipWriter, log) }
+		if consistent != nil {
+			addDatabase = func() error { return consistent.addDatabase(cfg, zipWriter) }
+		}
+		if err := addDatabase(); err != nil {
 			logrus.Warnf("⚠️  Database backup skipped: %v", err)
+			result.DatabaseError = err.Error()
 		} else {
 			logrus.Info("✓ Database backup included")
+			result.Database = true
+		}
+	}
+
+	// Auto-enable vector store backup if Chroma or pgvector is configured and flag not explicitly set
+	includeVectors := p.vectors
+	if !includeVectors && vectorstore.IsConfigured() {
+		includeVectors = true
+		log.Info("Vector store detected, including knowledge embeddings automatically")
+	}
+
+	// Conditionally add vector store backup to the ZIP
+	if includeVectors {
+		if err := addVectorBackupToZip(zipWriter); err != nil {
+			logrus.Warnf("⚠️  Vector store backup skipped: %v", err)
+			result.VectorsError = err.Error()
+		} else {
+			logrus.Info("✓ Vector store backup included")
+			result.Vectors = true
 		}
 	}
 
+	// Conditionally add the data directory files to the ZIP
+	if p.uploads {
+		if err := addUploadsBackupToZip(zipWriter, &p.uploadPaths); err != nil {
+			logrus.Warnf("⚠️  Data directory backup skipped: %v", err)
+			result.UploadsError = err.Error()
+		} else {
+			logrus.Info("✓ Data directory backup included")
+			result.Uploads = true
+		}
+	}
+
+	// Record the snapshot window once every section is in place
+	if consistent != nil {
+		result.Snapshot, err = consistent.finish(zipWriter, summary)
+		if err != nil {
+			closeZip(zipFile, zipWriter)
+			return fmt.Errorf("failed to record consistent snapshot: %w", err)
+		}
+	}
+
+	if err := closeZip(zipFile, zipWriter); err != nil {
+		return fmt.Errorf("failed to create backup: %w", err)
+	}
+
 	// Encrypt the backup
 	log.Info("Encrypting backup...")
 	encryptOpts := &encryption.EncryptOptions{
@@ -164,8 +277,23 @@ func (p *FullBackupPlugin) Execute(cfg *config.Config) error {
 		return fmt.Errorf("failed to encrypt backup: %w", err)
 	}
 
+	// Sign the backup manifest
+	var sigPath string
+	if signKey != nil {
+		sigPath, err = signing.SignArchive(signKey, tempFile, backupPath)
+		if err != nil {
+			return fmt.Errorf("failed to sign backup: %w", err)
+		}
+		result.Signed = true
+	}
+
 	// Remove temporary file
 	os.Remove(tempFile)
+	metrics.AddBytesWritten("full-backup", backupPath)
+	if info, err := os.Stat(backupPath); err == nil {
+		result.Size = info.Size()
+	}
+	cli.SetResult(result)
 
 	// Print success message
 	logrus.Info("✓ Backup completed successfully!\n")
@@ -173,10 +301,26 @@ func (p *FullBackupPlugin) Execute(cfg *config.Config) error {
 	logrus.Infof("  Identity (private key): %s", filepath.Join(p.path, "identity.txt"))
 	logrus.Infof("  Recipient (public key): %s", filepath.Join(p.path, "recipient.txt"))
 	logrus.Infof("  Backup: %s", backupPath)
+	if sigPath != "" {
+		logrus.Infof("  Signature: %s", sigPath)
+	}
 	logrus.Info("To verify your backup:")
 	logrus.Infof("  owuiback verify --path %s", p.path)
 	logrus.Info("IMPORTANT: Keep identity.txt secure - it's needed to decrypt and restore your backup!")
 
+	if len(result.Failed) > 0 || result.DatabaseError != "" || result.VectorsError != "" || result.UploadsError != "" {
+		failed := failedNames(result.Failed)
+		if result.DatabaseError != "" {
+			failed = strings.TrimPrefix(failed+", database", ", ")
+		}
+		if result.VectorsError != "" {
+			failed = strings.TrimPrefix(failed+", vectors", ", ")
+		}
+		if result.UploadsError != "" {
+			failed = strings.TrimPrefix(failed+", uploads", ", ")
+		}
+		return cli.Partialf("backup incomplete, failed: %s", failed)
+	}
 	return nil
 }
 
@@ -231,56 +375,25 @@ func ensureIdentityFiles(dir string, log *logrus.Entry) (string, bool, error) {
 	return publicKey, true, nil
 }
 
-// addDatabaseBackupToZip adds database backup to an existing ZIP file
-func (p *FullBackupPlugin) addDatabaseBackupToZip(zipPath string, log *logrus.Entry) error {
-	// Check if POSTGRES_URL is set
-	postgresURL := database.GetPostgresURLFromEnv()
-	if postgresURL == "" {
-		return fmt.Errorf("POSTGRES_URL environment variable not set")
-	}
-
-	// Check if PostgreSQL tools are available
-	if err := database.CheckToolsAvailable(); err != nil {
-		return fmt.Errorf("PostgreSQL tools not available: %w", err)
-	}
-
-	// Parse connection URL
-	dbConfig, err := database.ParsePostgresURL(postgresURL)
+// addDatabaseBackupToZip streams the database backup into the open backup ZIP
+func (p *FullBackupPlugin) addDatabaseBackupToZip(cfg *config.Config, zipWriter *zip.Writer, log *logrus.Entry) error {
+	target, err := resolveDatabaseTarget("", "", "")
 	if err != nil {
-		return fmt.Errorf("failed to parse POSTGRES_URL: %w", err)
-	}
-
-	log.Infof("Adding database backup for: %s", database.FormatConnectionInfo(dbConfig))
-
-	// Test connection
-	if err := database.TestConnection(dbConfig); err != nil {
-		return fmt.Errorf("database connection failed: %w", err)
+		return err
 	}
 
-	// Create database dump
-	dumpOptions := &database.DumpOptions{
-		Format:       "plain",
-		NoOwner:      true,
-		NoPrivileges: true,
-	}
+	log.Infof("Adding database backup for: %s", target)
 
-	dumpData, err := database.CreateDump(dbConfig, dumpOptions)
-	if err != nil {
-		return fmt.Errorf("failed to create database dump: %w", err)
+	if err := target.Check(); err != nil {
+		return err
 	}
 
-	// Get PostgreSQL version for metadata
-	version, err := database.GetPostgresVersion(dbConfig)
+	// Stream the database dump and metadata next to the API export
+	size, err := target.WriteToZip(cfg, zipWriter, false)
 	if err != nil {
-		log.Warnf("Failed to get PostgreSQL version: %v", err)
-		version = "unknown"
-	}
-
-	// Add database dump and metadata to existing ZIP
-	if err := backup.AddDatabaseToZip(zipPath, dumpData, dbConfig.Database, version); err != nil {
-		return fmt.Errorf("failed to add database to ZIP: %w", err)
+		return err
 	}
 
-	log.Infof("Database backup added successfully (%d bytes)", len(dumpData))
+	log.Infof("Database backup added successfully (%d bytes)", size)
 	return nil
 }
@@ -0,0 +1,78 @@
+package plugins
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/vosiander/open-webui-backup/pkg/cli"
+	"github.com/vosiander/open-webui-backup/pkg/encryption"
+	"github.com/vosiander/open-webui-backup/pkg/openwebui"
+)
+
+// loadIdentityContents reads age identities from the flag or OWUI_DECRYPT_IDENTITY,
+// falling back to identity.txt in fallbackDir. Returns nil if none is available.
+func loadIdentityContents(flagIdentities []string, fallbackDir string) ([]string, error) {
+	files, err := encryption.GetDecryptIdentityFilesFromEnvOrFlag(flagIdentities)
+	if err != nil {
+		// Fall back to identity.txt, as created by full-backup
+		if fallbackDir == "" {
+			return nil, nil
+		}
+		defaultIdentity := filepath.Join(fallbackDir, "identity.txt")
+		if _, statErr := os.Stat(defaultIdentity); statErr != nil {
+			return nil, nil
+		}
+		files = []string{defaultIdentity}
+	}
+
+	var identities []string
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read identity file %s: %w", file, err)
+		}
+		identities = append(identities, string(content))
+	}
+
+	return identities, nil
+}
+
+// loadRecipients reads age recipients from the flag or OWUI_ENCRYPTED_RECIPIENT,
+// falling back to recipient.txt in fallbackDir as created by full-backup
+func loadRecipients(flagRecipients []string, fallbackDir string) ([]string, error) {
+	if len(flagRecipients) > 0 || os.Getenv("OWUI_ENCRYPTED_RECIPIENT") != "" {
+		return encryption.GetEncryptRecipientsFromEnvOrFlag(flagRecipients)
+	}
+	data, err := os.ReadFile(filepath.Join(fallbackDir, "recipient.txt"))
+	if err != nil {
+		return nil, fmt.Errorf("no encryption recipients provided (use --encrypt-recipient, OWUI_ENCRYPTED_RECIPIENT or recipient.txt in %s)", fallbackDir)
+	}
+	return []string{strings.TrimSpace(string(data))}, nil
+}
+
+// checkAPIKey fails early if Open WebUI rejects the API key, so the command exits with the auth code
+// instead of producing an empty result
+func checkAPIKey(client *openwebui.Client) error {
+	if _, err := client.GetCurrentUser(); err != nil {
+		return fmt.Errorf("failed to authenticate with Open WebUI: %w", err)
+	}
+	return nil
+}
+
+// failedNames returns the sorted keys of a failure map as a comma-separated list
+func failedNames(failed map[string]string) string {
+	names := make([]string, 0, len(failed))
+	for name := range failed {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// stdoutReserved is returned by commands that would stream raw data to stdout with --output json
+func stdoutReserved(flag string) error {
+	return cli.Usage(fmt.Errorf("stdout is reserved for the result with --output json; use %s to write to a file", flag))
+}
@@ -0,0 +1,156 @@
+package plugins
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/vosiander/open-webui-backup/pkg/cli"
+	"github.com/vosiander/open-webui-backup/pkg/config"
+	"github.com/vosiander/open-webui-backup/pkg/encryption"
+	"github.com/vosiander/open-webui-backup/pkg/importer"
+	"github.com/vosiander/open-webui-backup/pkg/openwebui"
+)
+
+// ImportPlugin converts chats from other assistants into Open WebUI chats
+type ImportPlugin struct {
+	format           string
+	input            string
+	out              string
+	encryptRecipient []string
+	userID           string
+	dryRun           bool
+}
+
+// NewImportPlugin creates a new instance of the ImportPlugin
+func NewImportPlugin() *ImportPlugin {
+	return &ImportPlugin{}
+}
+
+// Name returns the command name
+func (p *ImportPlugin) Name() string {
+	return "import"
+}
+
+// Description returns the command description
+func (p *ImportPlugin) Description() string {
+	return "Import conversations from other assistants (e.g. ChatGPT) into Open WebUI or a backup file"
+}
+
+// SetupFlags configures the command-line flags
+func (p *ImportPlugin) SetupFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&p.format, "format", "chatgpt", "Export format: "+strings.Join(importer.Names(), ", "))
+	cmd.Flags().StringVarP(&p.input, "input", "i", "", "Export file to import, e.g. conversations.json or the export ZIP (required)")
+	cmd.Flags().StringVarP(&p.out, "out", "o", "", "Write a unified backup file instead of importing into the live instance")
+	cmd.Flags().StringSliceVar(&p.encryptRecipient, "encrypt-recipient", nil, "Encrypt the --out backup with age public key(s) (or use OWUI_ENCRYPTED_RECIPIENT env variable)")
+	cmd.Flags().StringVar(&p.userID, "user-id", "", "Owner user ID to record on chats written with --out")
+	cmd.Flags().BoolVar(&p.dryRun, "dry-run", false, "Only list the conversations that would be imported")
+	cmd.MarkFlagRequired("input")
+}
+
+// Execute converts the export and loads the chats
+func (p *ImportPlugin) Execute(cfg *config.Config) error {
+	imp, err := importer.Get(p.format)
+	if err != nil {
+		return err
+	}
+
+	logrus.Infof("Reading %s export from %s", imp.Name(), p.input)
+	chats, err := importer.ParseFile(imp, p.input)
+	if err != nil {
+		return err
+	}
+
+	messages := 0
+	for i := range chats {
+		chats[i].UserID = p.userID
+		messages += len(chats[i].Chat.Messages)
+	}
+	logrus.Infof("Converted %d conversations with %d messages", len(chats), messages)
+
+	if p.dryRun {
+		if cli.JSON() {
+			type conversation struct {
+				Title     string `json:"title"`
+				CreatedAt int64  `json:"createdAt"`
+				Messages  int    `json:"messages"`
+			}
+			conversations := make([]conversation, 0, len(chats))
+			for _, chat := range chats {
+				conversations = append(conversations, conversation{chat.Title, chat.CreatedAt, len(chat.Chat.Messages)})
+			}
+			cli.SetResult(conversations)
+			return nil
+		}
+		for _, chat := range chats {
+			fmt.Printf("%s  %-60s  %d messages\n", formatTimestamp(chat.CreatedAt), truncate(chat.Title, 60), len(chat.Chat.Messages))
+		}
+		return nil
+	}
+
+	if p.out != "" {
+		return p.writeBackup(chats)
+	}
+
+	if cfg.OpenWebUIAPIKey == "" {
+		return fmt.Errorf("OPEN_WEBUI_API_KEY environment variable is required (or use --out to write a backup file)")
+	}
+
+	client := openwebui.NewClient(cfg.OpenWebUIURL, cfg.OpenWebUIAPIKey)
+
+	imported := 0
+	for i := range chats {
+		if err := client.ImportChat(&chats[i]); err != nil {
+			logrus.Warnf("Failed to import %q: %v", chats[i].Title, err)
+			continue
+		}
+		imported++
+		logrus.Debugf("Imported %q", chats[i].Title)
+	}
+
+	logrus.Infof("Imported %d of %d conversations into %s", imported, len(chats), cfg.OpenWebUIURL)
+	if imported == 0 && len(chats) > 0 {
+		return fmt.Errorf("all %d conversations failed to import", len(chats))
+	}
+	if imported < len(chats) {
+		return cli.Partialf("%d conversations failed to import", len(chats)-imported)
+	}
+	return nil
+}
+
+// writeBackup writes the chats to a unified backup file, encrypted if recipients are given
+func (p *ImportPlugin) writeBackup(chats []openwebui.Chat) error {
+	// Encryption is optional for imports
+	var recipients []string
+	if len(p.encryptRecipient) > 0 || os.Getenv("OWUI_ENCRYPTED_RECIPIENT") != "" {
+		var err error
+		recipients, err = encryption.GetEncryptRecipientsFromEnvOrFlag(p.encryptRecipient)
+		if err != nil {
+			return fmt.Errorf("failed to get encryption recipients: %w", err)
+		}
+	}
+
+	zipPath := strings.TrimSuffix(p.out, ".age")
+	if err := importer.WriteArchive(chats, zipPath); err != nil {
+		return err
+	}
+
+	if len(recipients) == 0 {
+		logrus.Infof("Backup written: %s", zipPath)
+		return nil
+	}
+
+	encryptedPath := zipPath + ".age"
+	if err := encryption.EncryptFileWithRecipients(zipPath, encryptedPath, recipients); err != nil {
+		return fmt.Errorf("failed to encrypt backup: %w", err)
+	}
+	if err := os.Remove(zipPath); err != nil {
+		logrus.Warnf("Failed to remove unencrypted backup: %v", err)
+	}
+
+	logrus.Infof("Backup written: %s", filepath.Base(encryptedPath))
+	return nil
+}
@@ -0,0 +1,214 @@
+package plugins
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/vosiander/open-webui-backup/pkg/archive"
+	"github.com/vosiander/open-webui-backup/pkg/cli"
+	"github.com/vosiander/open-webui-backup/pkg/config"
+)
+
+// InspectPlugin browses the contents of a backup without restoring it
+type InspectPlugin struct {
+	file            string
+	decryptIdentity []string
+	entityType      string
+	id              string
+	attachment      string
+	attachments     bool
+	jsonOutput      bool
+}
+
+// NewInspectPlugin creates a new instance of the InspectPlugin
+func NewInspectPlugin() *InspectPlugin {
+	return &InspectPlugin{}
+}
+
+// Name returns the command name
+func (p *InspectPlugin) Name() string {
+	return "inspect"
+}
+
+// Description returns the command description
+func (p *InspectPlugin) Description() string {
+	return "Browse the entities of a backup file without restoring it"
+}
+
+// SetupFlags configures the command-line flags
+func (p *InspectPlugin) SetupFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&p.file, "file", "f", "", "Backup file to inspect (required)")
+	cmd.Flags().StringSliceVar(&p.decryptIdentity, "decrypt-identity", nil, "Age identity file(s) for encrypted backups (or use OWUI_DECRYPT_IDENTITY env variable)")
+	cmd.Flags().StringVar(&p.entityType, "type", "", "Only show entities of this type (knowledge, model, tool, prompt, file, chat, user, group, feedback)")
+	cmd.Flags().StringVar(&p.id, "id", "", "Print the JSON of a single entity (requires --type; prompts use their command)")
+	cmd.Flags().StringVar(&p.attachment, "attachment", "", "Write the raw content of an archive entry to stdout (e.g. files/<id>/content/report.pdf)")
+	cmd.Flags().BoolVar(&p.attachments, "attachments", false, "List attachments below each entity in tree output")
+	cmd.Flags().BoolVar(&p.jsonOutput, "json", false, "Print the listing as JSON")
+	cmd.MarkFlagRequired("file")
+}
+
+// Execute prints the backup contents
+func (p *InspectPlugin) Execute(cfg *config.Config) error {
+	if p.entityType != "" && !archive.IsType(p.entityType) {
+		return cli.Usage(fmt.Errorf("unknown entity type: %s", p.entityType))
+	}
+	if p.id != "" && p.entityType == "" {
+		return cli.Usage(fmt.Errorf("--id requires --type"))
+	}
+
+	identities, err := loadIdentityContents(p.decryptIdentity, "")
+	if err != nil {
+		return err
+	}
+
+	zipPath, cleanup, err := archive.Decrypt(p.file, identities)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	if p.attachment != "" {
+		if cli.JSON() {
+			return cli.Usage(fmt.Errorf("--attachment writes raw content to stdout and does not support --output json"))
+		}
+		content, err := archive.ReadEntry(zipPath, p.attachment)
+		if err != nil {
+			return err
+		}
+		_, err = os.Stdout.Write(content)
+		return err
+	}
+
+	a, err := archive.Load(zipPath)
+	if err != nil {
+		return err
+	}
+	a.Path = p.file
+
+	if p.id != "" {
+		entity := a.Find(p.entityType, p.id)
+		if entity == nil {
+			return fmt.Errorf("%s %q not found in backup", p.entityType, p.id)
+		}
+		if cli.JSON() {
+			cli.SetResult(json.RawMessage(entity.Data))
+			return nil
+		}
+		var out bytes.Buffer
+		if err := json.Indent(&out, entity.Data, "", "  "); err != nil {
+			return fmt.Errorf("failed to format entity: %w", err)
+		}
+		fmt.Println(out.String())
+		return nil
+	}
+
+	if p.entityType != "" {
+		a.Entities = map[string][]*archive.Entity{p.entityType: a.Entities[p.entityType]}
+	}
+
+	if cli.JSON() {
+		cli.SetResult(a)
+		return nil
+	}
+	if p.jsonOutput {
+		data, err := json.MarshalIndent(a, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal backup contents: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	p.printTree(a)
+	return nil
+}
+
+// printTree prints the archive as a tree grouped by entity type
+func (p *InspectPlugin) printTree(a *archive.Archive) {
+	fmt.Println(filepath.Base(a.Path))
+	if m := a.Metadata; m != nil {
+		fmt.Printf("  tool %s, created %s, %d items", m.BackupToolVersion, m.BackupTimestamp, m.ItemCount)
+		if m.OpenWebUIURL != "" {
+			fmt.Printf(", from %s", m.OpenWebUIURL)
+		}
+		fmt.Println()
+		if r := m.Redaction; r != nil {
+			fmt.Printf("  redacted with profile %q on %s: %s\n", r.Profile, r.RedactedAt, strings.Join(r.Rules, ", "))
+		}
+		if len(m.Erasures) > 0 {
+			fmt.Printf("  erasure requests applied: %s\n", strings.Join(m.Erasures, ", "))
+		}
+	}
+
+	var types []string
+	for _, entityType := range archive.Types {
+		if len(a.Entities[entityType]) > 0 {
+			types = append(types, entityType)
+		}
+	}
+	if len(a.Other) > 0 && p.entityType == "" {
+		types = append(types, "")
+	}
+
+	for i, entityType := range types {
+		last := i == len(types)-1
+		branch, indent := treeBranch(last)
+
+		if entityType == "" {
+			fmt.Printf("%sother (%d)\n", branch, len(a.Other))
+			for j, name := range a.Other {
+				child, _ := treeBranch(j == len(a.Other)-1)
+				fmt.Printf("%s%s%s\n", indent, child, name)
+			}
+			continue
+		}
+
+		entities := a.Entities[entityType]
+		fmt.Printf("%s%s (%d)\n", branch, entityType, len(entities))
+		for j, entity := range entities {
+			child, childIndent := treeBranch(j == len(entities)-1)
+			fmt.Printf("%s%s%s\n", indent, child, entityLine(entity))
+
+			if !p.attachments {
+				continue
+			}
+			attachments := slices.Clone(entity.Attachments)
+			slices.Sort(attachments)
+			for k, name := range attachments {
+				leaf, _ := treeBranch(k == len(attachments)-1)
+				fmt.Printf("%s%s%s%s\n", indent, childIndent, leaf, strings.TrimPrefix(name, entity.Dir()))
+			}
+		}
+	}
+}
+
+// entityLine formats a single entity for tree output
+func entityLine(e *archive.Entity) string {
+	line := e.Name
+	if e.ID != "" && e.ID != e.Name {
+		line += fmt.Sprintf(" [%s]", e.ID)
+	}
+	if ts := e.UpdatedAt; ts > 0 {
+		line += "  updated " + formatTimestamp(ts)
+	} else if ts := e.CreatedAt; ts > 0 {
+		line += "  created " + formatTimestamp(ts)
+	}
+	if n := len(e.Attachments); n > 0 {
+		line += fmt.Sprintf("  (%d attachments)", n)
+	}
+	return line
+}
+
+// treeBranch returns the branch prefix for a node and the indentation for its children
+func treeBranch(last bool) (string, string) {
+	if last {
+		return "└── ", "    "
+	}
+	return "├── ", "│   "
+}
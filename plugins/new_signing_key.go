@@ -0,0 +1,81 @@
+package plugins
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/vosiander/open-webui-backup/pkg/config"
+	"github.com/vosiander/open-webui-backup/pkg/signing"
+)
+
+// NewSigningKeyPlugin generates a new Ed25519 signing keypair and saves it to files
+type NewSigningKeyPlugin struct {
+	path string
+}
+
+// NewNewSigningKeyPlugin creates a new instance of the NewSigningKeyPlugin
+func NewNewSigningKeyPlugin() *NewSigningKeyPlugin {
+	return &NewSigningKeyPlugin{}
+}
+
+// Name returns the command name
+func (p *NewSigningKeyPlugin) Name() string {
+	return "new-signing-key"
+}
+
+// Description returns the command description
+func (p *NewSigningKeyPlugin) Description() string {
+	return "Generate a new Ed25519 signing keypair and save to signing.key and signing.pub"
+}
+
+// SetupFlags configures the command-line flags
+func (p *NewSigningKeyPlugin) SetupFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&p.path, "path", "", "Directory to save signing key files (required)")
+	cmd.MarkFlagRequired("path")
+}
+
+// Execute generates the signing keypair and saves to files
+func (p *NewSigningKeyPlugin) Execute(cfg *config.Config) error {
+	log := logrus.WithField("plugin", p.Name())
+
+	if err := os.MkdirAll(p.path, 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	keyPath := filepath.Join(p.path, "signing.key")
+	pubPath := filepath.Join(p.path, "signing.pub")
+
+	// Check if files already exist
+	if _, err := os.Stat(keyPath); err == nil {
+		return fmt.Errorf("signing.key already exists at %s (will not overwrite)", keyPath)
+	}
+	if _, err := os.Stat(pubPath); err == nil {
+		return fmt.Errorf("signing.pub already exists at %s (will not overwrite)", pubPath)
+	}
+
+	log.Info("Generating new Ed25519 signing keypair...")
+
+	key, err := signing.GenerateKey()
+	if err != nil {
+		return fmt.Errorf("failed to generate signing key: %w", err)
+	}
+
+	if err := signing.WriteKeyFiles(key, keyPath, pubPath); err != nil {
+		return err
+	}
+	log.Infof("Saved signing key to: %s", keyPath)
+	log.Infof("Saved public key to: %s", pubPath)
+
+	logrus.Info("✓ Signing keypair generated successfully!")
+	logrus.Infof("Key ID: %s", key.Public().KeyID())
+	logrus.Info("To sign backups, set:")
+	logrus.Infof("  export %s=\"%s\"", signing.EnvSigningKey, keyPath)
+	logrus.Info("To verify backups, distribute signing.pub and set:")
+	logrus.Infof("  export %s=\"%s\"", signing.EnvTrustedKeys, pubPath)
+	logrus.Info("IMPORTANT: Keep signing.key secure - anyone holding it can produce trusted backups!")
+
+	return nil
+}
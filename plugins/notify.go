@@ -0,0 +1,76 @@
+package plugins
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/vosiander/open-webui-backup/pkg/config"
+	"github.com/vosiander/open-webui-backup/pkg/notify"
+)
+
+// NotifyTestPlugin sends a test notification to the configured sinks
+type NotifyTestPlugin struct {
+	operation string
+	outcome   string
+	sinks     []string
+}
+
+// NewNotifyTestPlugin creates a new instance of the NotifyTestPlugin
+func NewNotifyTestPlugin() *NotifyTestPlugin {
+	return &NotifyTestPlugin{}
+}
+
+// Name returns the command name
+func (p *NotifyTestPlugin) Name() string {
+	return "notify-test"
+}
+
+// Description returns the command description
+func (p *NotifyTestPlugin) Description() string {
+	return "Send a test notification to the configured webhook, Slack, Teams and email sinks"
+}
+
+// SetupFlags configures the command-line flags
+func (p *NotifyTestPlugin) SetupFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&p.operation, "operation", "backup", "Operation name in the test event")
+	cmd.Flags().StringVar(&p.outcome, "outcome", notify.OutcomeSuccess, "Outcome of the test event: success or failure")
+	cmd.Flags().StringSliceVar(&p.sinks, "sink", nil, "Only send to these sinks (default: all)")
+}
+
+// Execute sends the test event, ignoring the sinks' operation and outcome filters
+func (p *NotifyTestPlugin) Execute(cfg *config.Config) error {
+	if cfg.NotifyConfig == "" {
+		return fmt.Errorf("no notification config (use --notify-config or OWUI_NOTIFY_CONFIG)")
+	}
+	notifier, err := notify.Load(cfg.NotifyConfig)
+	if err != nil {
+		return err
+	}
+	notifier, err = notifier.Only(p.sinks)
+	if err != nil {
+		return err
+	}
+
+	var cause error
+	switch p.outcome {
+	case notify.OutcomeSuccess:
+	case notify.OutcomeFailure:
+		cause = fmt.Errorf("this is a test failure")
+	default:
+		return fmt.Errorf("invalid --outcome %q (use success or failure)", p.outcome)
+	}
+
+	event := notify.NewEvent(p.operation, "cli", time.Now().Add(-42*time.Second), cause)
+	event.Test = true
+	event.File = "backup-test.zip.age"
+
+	names := notifier.SinkNames()
+	logrus.Infof("Sending test notification to %d sink(s)...", len(names))
+	if err := notifier.Notify(event); err != nil {
+		return fmt.Errorf("test notification failed: %w", err)
+	}
+	logrus.Infof("✓ Test notification delivered to %d sink(s)", len(names))
+	return nil
+}
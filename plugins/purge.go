@@ -6,6 +6,7 @@ import (
 
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	"github.com/vosiander/open-webui-backup/pkg/cli"
 	"github.com/vosiander/open-webui-backup/pkg/config"
 	"github.com/vosiander/open-webui-backup/pkg/openwebui"
 )
@@ -26,6 +27,15 @@ type PurgePlugin struct {
 	users        bool
 }
 
+// PurgeResult is the result object of the purge command
+type PurgeResult struct {
+	DryRun       bool           `json:"dryRun"`
+	Counts       map[string]int `json:"counts"`
+	Total        int            `json:"total"`
+	Failed       map[string]int `json:"failed,omitempty"`
+	SkippedUsers int            `json:"skippedUsers,omitempty"`
+}
+
 func NewPurgePlugin() *PurgePlugin {
 	return &PurgePlugin{}
 }
@@ -59,11 +69,14 @@ func (p *PurgePlugin) SetupFlags(cmd *cobra.Command) {
 // Execute runs the plugin with the given configuration
 func (p *PurgePlugin) Execute(cfg *config.Config) error {
 	if cfg.OpenWebUIAPIKey == "" {
-		logrus.Fatal("OPEN_WEBUI_API_KEY environment variable is required")
+		return fmt.Errorf("OPEN_WEBUI_API_KEY environment variable is required")
 	}
 
 	// Create client
 	client := openwebui.NewClient(cfg.OpenWebUIURL, cfg.OpenWebUIAPIKey)
+	if err := checkAPIKey(client); err != nil {
+		return err
+	}
 
 	// Determine what to purge
 	purgeAll := !p.chats && !p.files && !p.models && !p.knowledge &&
@@ -168,6 +181,9 @@ func (p *PurgePlugin) Execute(cfg *config.Config) error {
 		total += count
 	}
 
+	result := &PurgeResult{DryRun: !p.force, Counts: counts, Total: total, Failed: map[string]int{}}
+	cli.SetResult(result)
+
 	if total == 0 {
 		logrus.Info("No items to purge")
 		return nil
@@ -195,20 +211,32 @@ func (p *PurgePlugin) Execute(cfg *config.Config) error {
 	}
 	logrus.Infof("Total: %d items", total)
 
-	logrus.Infof("Waiting %s before proceeding...", p.waitDuration)
-	time.Sleep(p.waitDuration)
+	logrus.Infof("Waiting %s before proceeding (press Ctrl+C to abort)...", p.waitDuration)
+	if err := cli.Wait(p.waitDuration); err != nil {
+		logrus.Warn("Purge aborted, nothing was deleted")
+		return err
+	}
 	logrus.Info("Proceeding with deletion...")
 
 	// Perform deletions
-	if err := p.performDeletions(client, counts); err != nil {
+	if err := p.performDeletions(client, counts, result); err != nil {
 		return err
 	}
 
-	logrus.Infof("Successfully deleted %d items", total)
+	if len(result.Failed) > 0 {
+		failed := 0
+		for _, n := range result.Failed {
+			failed += n
+		}
+		logrus.Warnf("Deleted %d of %d items", total-failed-result.SkippedUsers, total)
+		return cli.Partialf("purge incomplete, %d items could not be deleted", failed)
+	}
+	logrus.Infof("Successfully deleted %d items", total-result.SkippedUsers)
 	return nil
 }
 
-func (p *PurgePlugin) performDeletions(client *openwebui.Client, counts map[string]int) error {
+// performDeletions deletes the counted items; individual failures are recorded in result
+func (p *PurgePlugin) performDeletions(client *openwebui.Client, counts map[string]int, result *PurgeResult) error {
 	purgeAll := !p.chats && !p.files && !p.models && !p.knowledge &&
 		!p.prompts && !p.tools && !p.functions && !p.memories && !p.feedbacks && !p.groups && !p.users
 
@@ -249,6 +277,7 @@ func (p *PurgePlugin) performDeletions(client *openwebui.Client, counts map[stri
 		for i, kb := range knowledgeBases {
 			if err := client.DeleteKnowledgeByID(kb.ID); err != nil {
 				logrus.Warnf("Failed to delete knowledge base %s: %v", kb.ID, err)
+				result.Failed["knowledge"]++
 			}
 			if (i+1)%10 == 0 || i == len(knowledgeBases)-1 {
 				logrus.Infof("  Progress: %d/%d", i+1, len(knowledgeBases))
@@ -267,6 +296,7 @@ func (p *PurgePlugin) performDeletions(client *openwebui.Client, counts map[stri
 		for i, prompt := range prompts {
 			if err := client.DeletePromptByCommand(prompt.Command); err != nil {
 				logrus.Warnf("Failed to delete prompt %s: %v", prompt.Command, err)
+				result.Failed["prompts"]++
 			}
 			if (i+1)%10 == 0 || i == len(prompts)-1 {
 				logrus.Infof("  Progress: %d/%d", i+1, len(prompts))
@@ -285,6 +315,7 @@ func (p *PurgePlugin) performDeletions(client *openwebui.Client, counts map[stri
 		for i, tool := range tools {
 			if err := client.DeleteToolByID(tool.ID); err != nil {
 				logrus.Warnf("Failed to delete tool %s: %v", tool.ID, err)
+				result.Failed["tools"]++
 			}
 			if (i+1)%10 == 0 || i == len(tools)-1 {
 				logrus.Infof("  Progress: %d/%d", i+1, len(tools))
@@ -303,6 +334,7 @@ func (p *PurgePlugin) performDeletions(client *openwebui.Client, counts map[stri
 		for i, function := range functions {
 			if err := client.DeleteFunctionByID(function.ID); err != nil {
 				logrus.Warnf("Failed to delete function %s: %v", function.ID, err)
+				result.Failed["functions"]++
 			}
 			if (i+1)%10 == 0 || i == len(functions)-1 {
 				logrus.Infof("  Progress: %d/%d", i+1, len(functions))
@@ -326,10 +358,12 @@ func (p *PurgePlugin) performDeletions(client *openwebui.Client, counts map[stri
 		feedbacks, err := client.GetAllFeedbacks()
 		if err != nil {
 			logrus.Warnf("Failed to list feedbacks: %v", err)
+			result.Failed["feedbacks"] = counts["feedbacks"]
 		} else {
 			for i, feedback := range feedbacks {
 				if err := client.DeleteFeedbackByID(feedback.ID); err != nil {
 					logrus.Warnf("Failed to delete feedback %s: %v", feedback.ID, err)
+					result.Failed["feedbacks"]++
 				}
 				if (i+1)%10 == 0 || i == len(feedbacks)-1 {
 					logrus.Infof("  Progress: %d/%d", i+1, len(feedbacks))
@@ -349,6 +383,7 @@ func (p *PurgePlugin) performDeletions(client *openwebui.Client, counts map[stri
 		for i, group := range groups {
 			if err := client.DeleteGroupByID(group.ID); err != nil {
 				logrus.Warnf("Failed to delete group %s: %v", group.ID, err)
+				result.Failed["groups"]++
 			}
 			if (i+1)%10 == 0 || i == len(groups)-1 {
 				logrus.Infof("  Progress: %d/%d", i+1, len(groups))
@@ -380,6 +415,7 @@ func (p *PurgePlugin) performDeletions(client *openwebui.Client, counts map[stri
 
 			if err := client.DeleteUserByID(user.ID); err != nil {
 				logrus.Warnf("  Failed to delete user %s (ID: %s): %v", user.Email, user.ID, err)
+				result.Failed["users"]++
 			} else {
 				deleted++
 			}
@@ -388,6 +424,7 @@ func (p *PurgePlugin) performDeletions(client *openwebui.Client, counts map[stri
 				logrus.Infof("  Progress: %d/%d (deleted: %d, skipped: %d)", i+1, len(users), deleted, skipped)
 			}
 		}
+		result.SkippedUsers = skipped
 		if skipped > 0 {
 			logrus.Infof("✓ Users deleted (skipped %d for safety)", skipped)
 		} else {
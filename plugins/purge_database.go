@@ -5,12 +5,14 @@ import (
 
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	"github.com/vosiander/open-webui-backup/pkg/cli"
 	"github.com/vosiander/open-webui-backup/pkg/config"
-	"github.com/vosiander/open-webui-backup/pkg/database"
 )
 
 type PurgeDatabasePlugin struct {
 	postgresURL string
+	dataDir     string
+	pgBackend   string
 	force       bool
 }
 
@@ -30,6 +32,8 @@ func (p *PurgeDatabasePlugin) Description() string {
 
 func (p *PurgeDatabasePlugin) SetupFlags(cmd *cobra.Command) {
 	cmd.Flags().StringVar(&p.postgresURL, "postgres-url", "", "PostgreSQL connection URL (or use POSTGRES_URL env variable)")
+	cmd.Flags().StringVar(&p.dataDir, "data-dir", "", "Open WebUI data directory with webui.db, for SQLite deployments (or use OWUI_DATA_DIR env variable)")
+	cmd.Flags().StringVar(&p.pgBackend, "pg-backend", "", "Dump and restore backend: auto, tools, docker or native (or use OWUI_PG_BACKEND env variable)")
 	cmd.Flags().BoolVarP(&p.force, "force", "f", false, "Actually delete data (without this flag, only shows what would be deleted)")
 }
 
@@ -47,46 +51,32 @@ func (p *PurgeDatabasePlugin) Execute(cfg *config.Config) error {
 		logrus.Warn("⚠️  WARNING: FORCE MODE - Data will be permanently deleted!\n")
 	}
 
-	// Check if PostgreSQL tools are available
-	if err := database.CheckToolsAvailable(); err != nil {
-		return fmt.Errorf("PostgreSQL tools check failed: %w", err)
-	}
-
-	// Get PostgreSQL URL from flag or environment
-	postgresURL := p.postgresURL
-	if postgresURL == "" {
-		postgresURL = database.GetPostgresURLFromEnv()
-	}
-
-	if postgresURL == "" {
-		return fmt.Errorf("PostgreSQL connection URL is required (use --postgres-url flag or POSTGRES_URL environment variable)")
-	}
-
-	// Parse connection URL
-	dbConfig, err := database.ParsePostgresURL(postgresURL)
+	target, err := resolveDatabaseTarget(p.postgresURL, p.dataDir, p.pgBackend)
 	if err != nil {
-		return fmt.Errorf("failed to parse PostgreSQL URL: %w", err)
+		return err
 	}
 
-	logrus.Infof("Target database: %s", database.FormatConnectionInfo(dbConfig))
+	logrus.Infof("Target database: %s", target)
 
-	// Test connection
-	if err := database.TestConnection(dbConfig); err != nil {
-		return fmt.Errorf("database connection test failed: %w", err)
+	if err := target.Check(); err != nil {
+		return err
 	}
 
 	// Perform the purge (or dry-run)
-	if err := database.PurgeDatabase(dbConfig, dryRun); err != nil {
+	if err := target.Purge(dryRun); err != nil {
 		return fmt.Errorf("failed to purge database: %w", err)
 	}
 
+	result := target.Result()
+	result.DryRun = dryRun
+	result.Purged = !dryRun
+	cli.SetResult(result)
+
 	if dryRun {
 		logrus.Info("✓ Dry run completed - no changes were made")
 		logrus.Info("Run with --force flag to actually delete the data")
-		logrus.Info("Dry run completed")
 	} else {
 		logrus.Info("✓ Database purged successfully")
-		logrus.Info("Database purged successfully")
 	}
 
 	return nil
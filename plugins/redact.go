@@ -0,0 +1,148 @@
+package plugins
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/vosiander/open-webui-backup/pkg/archive"
+	"github.com/vosiander/open-webui-backup/pkg/config"
+	"github.com/vosiander/open-webui-backup/pkg/encryption"
+	"github.com/vosiander/open-webui-backup/pkg/redact"
+	"github.com/vosiander/open-webui-backup/pkg/signing"
+)
+
+// RedactPlugin writes a redacted copy of a backup that can be shared
+type RedactPlugin struct {
+	file             string
+	out              string
+	profile          string
+	salt             string
+	decryptIdentity  []string
+	encryptRecipient []string
+	signKey          string
+}
+
+// NewRedactPlugin creates a new instance of the RedactPlugin
+func NewRedactPlugin() *RedactPlugin {
+	return &RedactPlugin{}
+}
+
+// Name returns the command name
+func (p *RedactPlugin) Name() string {
+	return "redact"
+}
+
+// Description returns the command description
+func (p *RedactPlugin) Description() string {
+	return "Write a copy of a backup with emails, names, API keys and sensitive content redacted"
+}
+
+// SetupFlags configures the command-line flags
+func (p *RedactPlugin) SetupFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&p.file, "file", "f", "", "Backup file to redact (required)")
+	cmd.Flags().StringVarP(&p.out, "out", "o", "", "Output file for the redacted backup (required)")
+	cmd.Flags().StringVar(&p.profile, "profile", "standard", "Redaction profile: "+strings.Join(redact.ProfileNames(), ", ")+" or a profile JSON file")
+	cmd.Flags().StringVar(&p.salt, "salt", "", "Secret for hashes and pseudonyms, stable across runs (or use OWUI_REDACT_SALT env variable)")
+	cmd.Flags().StringSliceVar(&p.decryptIdentity, "decrypt-identity", nil, "Age identity file(s) for encrypted backups (or use OWUI_DECRYPT_IDENTITY env variable)")
+	cmd.Flags().StringSliceVar(&p.encryptRecipient, "encrypt-recipient", nil, "Encrypt the redacted backup with age public key(s), e.g. the recipient's key")
+	cmd.Flags().StringVar(&p.signKey, "sign-key", "", "Sign the redacted backup manifest with an Ed25519 key file (or use OWUI_SIGNING_KEY env variable)")
+	cmd.MarkFlagRequired("file")
+	cmd.MarkFlagRequired("out")
+}
+
+// Execute redacts the backup
+func (p *RedactPlugin) Execute(cfg *config.Config) error {
+	profile, err := loadRedactionProfile(p.profile, p.salt)
+	if err != nil {
+		return err
+	}
+
+	signKey, err := signing.GetSigningKeyFromEnvOrFlag(p.signKey)
+	if err != nil {
+		return fmt.Errorf("failed to load signing key: %w", err)
+	}
+
+	identities, err := loadIdentityContents(p.decryptIdentity, "")
+	if err != nil {
+		return err
+	}
+	zipPath, cleanup, err := archive.Decrypt(p.file, identities)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	redactedPath := strings.TrimSuffix(p.out, ".age")
+	if len(p.encryptRecipient) > 0 {
+		redactedPath = redactedPath + ".tmp"
+	}
+
+	logrus.Infof("Redacting %s with profile %q", filepath.Base(p.file), profile.Name)
+	stats, err := redact.Archive(zipPath, redactedPath, profile)
+	if err != nil {
+		os.Remove(redactedPath)
+		return fmt.Errorf("failed to redact backup: %w", err)
+	}
+	logRedactionStats(stats)
+
+	archivePath := redactedPath
+	if len(p.encryptRecipient) > 0 {
+		recipients, err := encryption.GetEncryptRecipientsFromEnvOrFlag(p.encryptRecipient)
+		if err != nil {
+			return fmt.Errorf("failed to get encryption recipients: %w", err)
+		}
+		archivePath = strings.TrimSuffix(p.out, ".age") + ".age"
+		if err := encryption.EncryptFileWithRecipients(redactedPath, archivePath, recipients); err != nil {
+			return fmt.Errorf("failed to encrypt redacted backup: %w", err)
+		}
+	}
+
+	if signKey != nil {
+		if _, err := signing.SignArchive(signKey, redactedPath, archivePath); err != nil {
+			return fmt.Errorf("failed to sign redacted backup: %w", err)
+		}
+	}
+
+	if archivePath != redactedPath {
+		if err := os.Remove(redactedPath); err != nil {
+			logrus.Warnf("Failed to remove unencrypted redacted backup: %v", err)
+		}
+	}
+
+	logrus.Infof("Redacted backup written: %s", archivePath)
+	return nil
+}
+
+// loadRedactionProfile loads a profile and applies the salt from the flag or environment
+func loadRedactionProfile(nameOrPath, salt string) (*redact.Profile, error) {
+	profile, err := redact.LoadProfile(nameOrPath)
+	if err != nil {
+		return nil, err
+	}
+	if salt == "" {
+		salt = os.Getenv("OWUI_REDACT_SALT")
+	}
+	if salt != "" {
+		profile.Salt = salt
+	}
+	if profile.Salt == "" {
+		logrus.Info("No redaction salt set; pseudonyms will differ from other redacted backups")
+	}
+	return profile, nil
+}
+
+// logRedactionStats logs what was redacted
+func logRedactionStats(stats *redact.Stats) {
+	logrus.Infof("Redacted %d emails, %d names, %d API keys, %d other fields and %d text values",
+		stats.Emails, stats.Names, stats.Keys, stats.Fields, stats.Masked)
+	if stats.Attachments > 0 {
+		logrus.Infof("Dropped %d attachments", stats.Attachments)
+	}
+	if stats.Database > 0 {
+		logrus.Infof("Dropped the database dump")
+	}
+}
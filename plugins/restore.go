@@ -2,22 +2,32 @@ package plugins
 
 import (
 	"archive/zip"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	"github.com/vosiander/open-webui-backup/pkg/cli"
 	"github.com/vosiander/open-webui-backup/pkg/config"
 	"github.com/vosiander/open-webui-backup/pkg/encryption"
 	"github.com/vosiander/open-webui-backup/pkg/openwebui"
 	"github.com/vosiander/open-webui-backup/pkg/restore"
+	"github.com/vosiander/open-webui-backup/pkg/signing"
+	"github.com/vosiander/open-webui-backup/pkg/uploads"
+	"github.com/vosiander/open-webui-backup/pkg/vectorstore"
 )
 
 type RestorePlugin struct {
 	file            string
 	overwrite       bool
 	decryptIdentity []string
+	trustedKeys     []string
+	requireSig      bool
+	plan            bool
+	planOutput      string
+	only            []string
 	prompts         bool
 	tools           bool
 	knowledge       bool
@@ -29,6 +39,19 @@ type RestorePlugin struct {
 	feedbacks       bool
 }
 
+// RestoreResult is the result object of the restore command
+type RestoreResult struct {
+	File      string            `json:"file"`
+	TargetURL string            `json:"targetUrl"`
+	Signature string            `json:"signature"`
+	Restored  []string          `json:"restored"`
+	Missing   []string          `json:"missing,omitempty"`
+	Failed    map[string]string `json:"failed,omitempty"`
+	Database  bool              `json:"databaseInBackup"`
+	Vectors   bool              `json:"vectorsInBackup"`
+	Uploads   bool              `json:"uploadsInBackup"`
+}
+
 func NewRestorePlugin() *RestorePlugin {
 	return &RestorePlugin{}
 }
@@ -47,7 +70,12 @@ func (p *RestorePlugin) SetupFlags(cmd *cobra.Command) {
 	cmd.Flags().StringVarP(&p.file, "file", "f", "", "Backup file path to restore from (required)")
 	cmd.MarkFlagRequired("file")
 	cmd.Flags().BoolVar(&p.overwrite, "overwrite", false, "Overwrite existing data")
+	cmd.Flags().BoolVar(&p.plan, "plan", false, "Show what the restore would change on the live instance without restoring")
+	cmd.Flags().StringVar(&p.planOutput, "plan-output", "", "Write the restore plan as JSON to this file (implies --plan)")
+	cmd.Flags().StringArrayVar(&p.only, "only", nil, "Restore only matching items, as type:pattern (e.g. knowledge:Name, prompt:/command, chat:<id>; globs allowed, ~ for regex, = for literal; repeatable)")
 	cmd.Flags().StringSliceVar(&p.decryptIdentity, "decrypt-identity", nil, "Decrypt backup with age identity file(s) (or use OWUI_DECRYPT_IDENTITY env variable)")
+	cmd.Flags().StringSliceVar(&p.trustedKeys, "trusted-keys", nil, "Trusted signing public key(s) or key file(s) (or use OWUI_TRUSTED_KEYS env variable)")
+	cmd.Flags().BoolVar(&p.requireSig, "require-signature", false, "Refuse unsigned or untrusted backups (or use OWUI_REQUIRE_SIGNATURE env variable)")
 	cmd.Flags().BoolVar(&p.prompts, "prompts", false, "Restore only prompts")
 	cmd.Flags().BoolVar(&p.tools, "tools", false, "Restore only tools")
 	cmd.Flags().BoolVar(&p.knowledge, "knowledge", false, "Restore only knowledge bases")
@@ -64,21 +92,42 @@ func (p *RestorePlugin) Execute(cfg *config.Config) error {
 	logrus.Info("Starting restore...")
 
 	if cfg.OpenWebUIAPIKey == "" {
-		logrus.Fatalf("OPEN_WEBUI_API_KEY environment variable is required")
+		return fmt.Errorf("OPEN_WEBUI_API_KEY environment variable is required")
 	}
 
 	if p.file == "" {
-		logrus.Fatalf("backup file is required (use --file flag)")
+		return cli.Usage(fmt.Errorf("backup file is required (use --file flag)"))
+	}
+
+	items, err := restore.ParseItemSelectors(p.only)
+	if err != nil {
+		return cli.Usage(fmt.Errorf("invalid --only selector: %w", err))
 	}
 
 	// Get decryption identity files (required)
 	identities, err := encryption.GetDecryptIdentityFilesFromEnvOrFlag(p.decryptIdentity)
 	if err != nil {
-		logrus.Fatalf("Failed to get decryption identity files: %v", err)
+		return fmt.Errorf("failed to get decryption identity files: %w", err)
+	}
+
+	// Verify the detached signature before decrypting
+	trusted, err := signing.GetTrustedKeysFromEnvOrFlag(p.trustedKeys)
+	if err != nil {
+		return fmt.Errorf("failed to load trusted keys: %w", err)
+	}
+	sigResult, err := signing.VerifyArchive(p.file, trusted)
+	if err != nil {
+		return fmt.Errorf("failed to verify signature: %w", err)
+	}
+	if err := signing.Enforce(sigResult, signing.IsSignatureRequired(p.requireSig)); err != nil {
+		return cli.Verification(fmt.Errorf("refusing to restore: %w", err))
 	}
 
 	// Create client
 	client := openwebui.NewClient(cfg.OpenWebUIURL, cfg.OpenWebUIAPIKey)
+	if err := checkAPIKey(client); err != nil {
+		return err
+	}
 
 	// Create temporary file for decrypted backup
 	tempFile := filepath.Join(os.TempDir(), "owuiback_restore_"+filepath.Base(p.file))
@@ -94,7 +143,7 @@ func (p *RestorePlugin) Execute(cfg *config.Config) error {
 	for _, identityFile := range identities {
 		content, err := os.ReadFile(identityFile)
 		if err != nil {
-			logrus.Fatalf("Failed to read identity file %s: %v", identityFile, err)
+			return fmt.Errorf("failed to read identity file %s: %w", identityFile, err)
 		}
 		identityContents = append(identityContents, string(content))
 	}
@@ -104,7 +153,7 @@ func (p *RestorePlugin) Execute(cfg *config.Config) error {
 	}
 
 	if err := encryption.DecryptFile(p.file, tempFile, decryptOpts); err != nil {
-		logrus.Fatalf("Failed to decrypt backup: %v", err)
+		return fmt.Errorf("failed to decrypt backup: %w", err)
 	}
 
 	// Ensure temporary file is cleaned up
@@ -112,6 +161,14 @@ func (p *RestorePlugin) Execute(cfg *config.Config) error {
 
 	logrus.Info("Backup decrypted successfully")
 
+	if sigResult.Manifest != nil {
+		if err := signing.VerifyEntries(sigResult.Manifest, tempFile); err != nil {
+			os.Remove(tempFile)
+			return cli.Verification(fmt.Errorf("refusing to restore: backup contents do not match signed manifest: %w", err))
+		}
+		logrus.Info("✓ Backup contents match the signed manifest")
+	}
+
 	// Determine what to restore
 	options := &restore.SelectiveRestoreOptions{
 		Prompts:   p.prompts,
@@ -123,10 +180,11 @@ func (p *RestorePlugin) Execute(cfg *config.Config) error {
 		Users:     p.users,
 		Groups:    p.groups,
 		Feedbacks: p.feedbacks,
+		Items:     items,
 	}
 
-	// If no specific types are selected, restore everything
-	if !options.Prompts && !options.Tools && !options.Knowledge && !options.Models && !options.Files && !options.Chats && !options.Users && !options.Groups && !options.Feedbacks {
+	// If no specific types or items are selected, restore everything
+	if len(items) == 0 && !options.Prompts && !options.Tools && !options.Knowledge && !options.Models && !options.Files && !options.Chats && !options.Users && !options.Groups && !options.Feedbacks {
 		logrus.Info("No specific types selected, restoring all data from backup")
 		options.Prompts = true
 		options.Tools = true
@@ -139,18 +197,65 @@ func (p *RestorePlugin) Execute(cfg *config.Config) error {
 		options.Feedbacks = true
 	}
 
+	// Plan mode: compare with the live instance and stop
+	if p.plan || p.planOutput != "" {
+		plan, err := restore.BuildPlan(client, tempFile, options, p.overwrite)
+		if err != nil {
+			return fmt.Errorf("failed to build restore plan: %w", err)
+		}
+		plan.Backup = p.file
+
+		if cli.JSON() {
+			cli.SetResult(plan)
+		} else {
+			printPlan(plan)
+		}
+
+		if p.planOutput != "" {
+			if err := restore.WritePlan(plan, p.planOutput); err != nil {
+				return fmt.Errorf("failed to save restore plan: %w", err)
+			}
+			logrus.Infof("Restore plan saved to %s", p.planOutput)
+		}
+		return nil
+	}
+
 	// Check if backup contains database folder
-	hasDatabaseBackup, err := p.checkForDatabaseBackup(tempFile)
+	hasDatabaseBackup, err := p.checkForSection(tempFile, "database/")
 	if err != nil {
 		logrus.Warnf("Failed to check for database backup: %v", err)
 	}
+	hasVectorBackup, err := p.checkForSection(tempFile, vectorstore.Prefix)
+	if err != nil {
+		logrus.Warnf("Failed to check for vector store backup: %v", err)
+	}
+	hasUploadsBackup, err := p.checkForSection(tempFile, uploads.Prefix)
+	if err != nil {
+		logrus.Warnf("Failed to check for data directory backup: %v", err)
+	}
 
 	// Perform the restore (no progress callback for CLI)
-	if err := restore.RestoreSelective(client, tempFile, options, p.overwrite, nil); err != nil {
-		logrus.Fatalf("Failed to restore: %v", err)
+	summary, err := restore.RestoreSelectiveWithSummary(client, tempFile, options, p.overwrite, nil)
+	if err != nil {
+		return fmt.Errorf("failed to restore: %w", err)
 	}
+	cli.SetResult(&RestoreResult{
+		File:      p.file,
+		TargetURL: cfg.OpenWebUIURL,
+		Signature: string(sigResult.Status),
+		Restored:  summary.Restored,
+		Missing:   summary.Missing,
+		Failed:    summary.Failed,
+		Database:  hasDatabaseBackup,
+		Vectors:   hasVectorBackup,
+		Uploads:   hasUploadsBackup,
+	})
 
-	logrus.Info("Restore completed successfully")
+	if len(summary.Failed) > 0 {
+		logrus.Warn("Restore completed with errors")
+	} else {
+		logrus.Info("Restore completed successfully")
+	}
 
 	// Display database skip notification if database backup was detected
 	if hasDatabaseBackup {
@@ -162,11 +267,34 @@ func (p *RestorePlugin) Execute(cfg *config.Config) error {
 		logrus.Info("═══════════════════════════════════════════════════════════════")
 	}
 
+	// Knowledge restored through the API is re-embedded by Open WebUI; the backed up
+	// vectors only match the original IDs, as restored by restore-database
+	if hasVectorBackup {
+		logrus.Info("")
+		logrus.Info("═══════════════════════════════════════════════════════════════")
+		logrus.Info("Note: Vector store backup detected but not restored.")
+		logrus.Info("Restore it together with 'restore-database' to bring knowledge bases back without re-indexing:")
+		logrus.Info("  owuicli restore-vectors --file " + p.file + " --decrypt-identity <identity-file>")
+		logrus.Info("═══════════════════════════════════════════════════════════════")
+	}
+
+	if hasUploadsBackup {
+		logrus.Info("")
+		logrus.Info("═══════════════════════════════════════════════════════════════")
+		logrus.Info("Note: Data directory backup detected but not restored.")
+		logrus.Info("Use the 'restore-uploads' command to restore uploaded files into the data directory:")
+		logrus.Info("  owuicli restore-uploads --file " + p.file + " --decrypt-identity <identity-file> --data-dir <dir>")
+		logrus.Info("═══════════════════════════════════════════════════════════════")
+	}
+
+	if len(summary.Failed) > 0 {
+		return cli.Partialf("restore incomplete, failed: %s", failedNames(summary.Failed))
+	}
 	return nil
 }
 
-// checkForDatabaseBackup checks if the backup ZIP contains a database folder
-func (p *RestorePlugin) checkForDatabaseBackup(zipPath string) (bool, error) {
+// checkForSection checks if the backup ZIP contains a folder such as database/
+func (p *RestorePlugin) checkForSection(zipPath, prefix string) (bool, error) {
 	zipReader, err := zip.OpenReader(zipPath)
 	if err != nil {
 		return false, err
@@ -174,10 +302,36 @@ func (p *RestorePlugin) checkForDatabaseBackup(zipPath string) (bool, error) {
 	defer zipReader.Close()
 
 	for _, file := range zipReader.File {
-		if strings.HasPrefix(file.Name, "database/") {
+		if strings.HasPrefix(file.Name, prefix) {
 			return true, nil
 		}
 	}
 
 	return false, nil
 }
+
+// printPlan prints a restore plan in a Terraform-like format
+func printPlan(plan *restore.Plan) {
+	symbols := map[string]string{
+		restore.ActionCreate:   "+",
+		restore.ActionUpdate:   "~",
+		restore.ActionConflict: "!",
+	}
+
+	fmt.Printf("Restore plan for %s → %s\n\n", plan.Backup, plan.TargetURL)
+
+	for _, item := range plan.Changes() {
+		fmt.Printf("  %s %s %s %q", symbols[item.Action], item.Action, item.Type, item.Key)
+		if item.Reason != "" {
+			fmt.Printf(" (%s)", item.Reason)
+		}
+		fmt.Println()
+		printFieldChanges(item.Fields, "      ")
+	}
+
+	fmt.Printf("\nPlan: %d to create, %d to update, %d conflicts, %d unchanged or skipped.\n",
+		plan.Summary.Create, plan.Summary.Update, plan.Summary.Conflict, plan.Summary.Skip)
+	if !plan.Overwrite && plan.Summary.Skip > 0 {
+		fmt.Println("Existing entities that differ are skipped; run with --overwrite to include them as updates.")
+	}
+}
@@ -5,21 +5,46 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	"github.com/vosiander/open-webui-backup/pkg/cli"
 	"github.com/vosiander/open-webui-backup/pkg/config"
 	"github.com/vosiander/open-webui-backup/pkg/database"
 	"github.com/vosiander/open-webui-backup/pkg/encryption"
+	"github.com/vosiander/open-webui-backup/pkg/wal"
 )
 
 type RestoreDatabasePlugin struct {
 	postgresURL     string
+	dataDir         string
+	pgBackend       string
 	file            string
 	decryptIdentity []string
 	purge           bool
 	createDB        bool
 	verbose         bool
+	tables          []string
+	excludeTables   []string
+	sideSchema      string
+	toTime          string
+	path            string
+	pgData          string
+	baseBackup      string
+	requireEmpty    bool
+	allowMismatch   bool
+}
+
+// RecoveryResult is the result object of a point-in-time restore
+type RecoveryResult struct {
+	BaseBackup     string `json:"baseBackup"`
+	TargetTime     string `json:"targetTime"`
+	DataDir        string `json:"dataDir"`
+	RestoreCommand string `json:"restoreCommand"`
+	WALSegments    int    `json:"walSegments"`
 }
 
 func NewRestoreDatabasePlugin() *RestoreDatabasePlugin {
@@ -33,51 +58,53 @@ func (p *RestoreDatabasePlugin) Name() string {
 
 // Description returns a short description of the plugin
 func (p *RestoreDatabasePlugin) Description() string {
-	return "Restore PostgreSQL database from an encrypted backup"
+	return "Restore the PostgreSQL or SQLite database from an encrypted backup"
 }
 
 func (p *RestoreDatabasePlugin) SetupFlags(cmd *cobra.Command) {
 	cmd.Flags().StringVar(&p.postgresURL, "postgres-url", "", "PostgreSQL connection URL (or use POSTGRES_URL env variable)")
-	cmd.Flags().StringVarP(&p.file, "file", "f", "", "Encrypted backup file to restore from (required)")
-	cmd.MarkFlagRequired("file")
+	cmd.Flags().StringVar(&p.dataDir, "data-dir", "", "Open WebUI data directory to restore webui.db into, for SQLite backups (or use OWUI_DATA_DIR env variable)")
+	cmd.Flags().StringVar(&p.pgBackend, "pg-backend", "", "Dump and restore backend: auto, tools, docker or native (or use OWUI_PG_BACKEND env variable)")
+	cmd.Flags().StringVarP(&p.file, "file", "f", "", "Encrypted backup file to restore from (required unless --to-time is set)")
 	cmd.Flags().StringSliceVar(&p.decryptIdentity, "decrypt-identity", nil, "Age identity file(s) for decryption (or use OWUI_DECRYPTION_IDENTITY env variable)")
 	cmd.Flags().BoolVar(&p.purge, "purge", false, "Purge all database objects before restoring (drops all tables, sequences, and views)")
 	cmd.Flags().BoolVar(&p.createDB, "create-db", false, "Create database if it doesn't exist")
 	cmd.Flags().BoolVarP(&p.verbose, "verbose", "v", false, "Enable verbose output (show Docker commands and pg_restore/psql output)")
+	cmd.Flags().StringSliceVar(&p.tables, "table", nil, "Restore only these tables, as name or schema.name with shell wildcards (replaces their data in one transaction)")
+	cmd.Flags().StringSliceVar(&p.excludeTables, "exclude-table", nil, "Restore all tables except these, as name or schema.name with shell wildcards")
+	cmd.Flags().StringVar(&p.sideSchema, "side-schema", "", "Load the selected tables into this new schema next to the live tables instead of replacing them")
+	cmd.Flags().BoolVar(&p.requireEmpty, "require-empty", false, "Only restore into an empty database, e.g. one freshly created for a migration")
+	cmd.Flags().BoolVar(&p.allowMismatch, "allow-schema-mismatch", false, "Restore even if the backup's Alembic revision or Open WebUI version does not fit the target database")
+	cmd.Flags().StringVar(&p.toTime, "to-time", "", "Rebuild a PostgreSQL data directory as of this time from a base backup and the WAL archive (RFC3339 or 'YYYY-MM-DD HH:MM:SS' local time)")
+	cmd.Flags().StringVar(&p.path, "path", "", "Backup directory holding the WAL archive and base backups, for --to-time")
+	cmd.Flags().StringVar(&p.pgData, "pgdata", "", "New PostgreSQL data directory to rebuild, for --to-time (must be missing or empty)")
+	cmd.Flags().StringVar(&p.baseBackup, "base-backup", "", "Base backup ID to start from, for --to-time (default: the newest one finished before the target)")
 }
 
 // Execute runs the plugin with the given configuration
 func (p *RestoreDatabasePlugin) Execute(cfg *config.Config) error {
-	logrus.Info("Starting database restore...")
-
-	// Check if PostgreSQL tools are available
-	if err := database.CheckToolsAvailable(); err != nil {
-		return fmt.Errorf("PostgreSQL tools check failed: %w", err)
+	if p.toTime != "" {
+		return p.restoreToTime()
 	}
-
-	// Get PostgreSQL URL from flag or environment
-	postgresURL := p.postgresURL
-	if postgresURL == "" {
-		postgresURL = database.GetPostgresURLFromEnv()
+	if p.file == "" {
+		return cli.Usage(fmt.Errorf("--file is required (or use --to-time for a point-in-time restore)"))
 	}
 
-	if postgresURL == "" {
-		return fmt.Errorf("PostgreSQL connection URL is required (use --postgres-url flag or POSTGRES_URL environment variable)")
+	selective := len(p.tables) > 0 || len(p.excludeTables) > 0 || p.sideSchema != ""
+	if selective && p.purge {
+		return cli.Usage(fmt.Errorf("--purge cannot be combined with --table, --exclude-table or --side-schema"))
 	}
-
-	// Parse connection URL
-	dbConfig, err := database.ParsePostgresURL(postgresURL)
-	if err != nil {
-		return fmt.Errorf("failed to parse PostgreSQL URL: %w", err)
+	if p.requireEmpty && (selective || p.purge) {
+		return cli.Usage(fmt.Errorf("--require-empty cannot be combined with --purge, --table, --exclude-table or --side-schema"))
 	}
-
-	logrus.Infof("Target database: %s", database.FormatConnectionInfo(dbConfig))
-
-	// Test connection
-	if err := database.TestConnection(dbConfig); err != nil {
-		return fmt.Errorf("database connection test failed: %w", err)
+	if p.sideSchema != "" {
+		if err := database.ValidateSideSchema(p.sideSchema); err != nil {
+			return cli.Usage(err)
+		}
 	}
 
+	logrus.Info("Starting database restore...")
+
 	// Get decryption identities
 	identities, err := encryption.GetDecryptIdentitiesFromEnvOrFlag(p.decryptIdentity)
 	if err != nil {
@@ -97,70 +124,376 @@ func (p *RestoreDatabasePlugin) Execute(cfg *config.Config) error {
 		return fmt.Errorf("failed to decrypt backup: %w", err)
 	}
 
-	// Extract database dump from ZIP
-	dumpData, err := p.extractDatabaseDump(tempDecrypted)
+	// Stream the database dump from the ZIP into the restore
+	zipReader, err := zip.OpenReader(tempDecrypted)
+	if err != nil {
+		return fmt.Errorf("failed to open ZIP file: %w", err)
+	}
+	defer zipReader.Close()
+
+	dumpFile, err := findDatabaseDump(&zipReader.Reader)
 	if err != nil {
 		return fmt.Errorf("failed to extract database dump: %w", err)
 	}
+	if dumpFile.Name == database.SQLiteEntry && selective {
+		return cli.Usage(fmt.Errorf("--table, --exclude-table and --side-schema only apply to PostgreSQL backups"))
+	}
+	metadata, err := database.ReadMetadata(&zipReader.Reader)
+	if err != nil {
+		return err
+	}
+
+	dump, err := dumpFile.Open()
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", dumpFile.Name, err)
+	}
+	defer dump.Close()
+	logrus.Infof("Restoring database dump from backup (%d bytes)", dumpFile.UncompressedSize64)
+
+	var result *DatabaseResult
+	if dumpFile.Name == database.SQLiteEntry {
+		result, err = p.restoreSQLite(cfg, dump, metadata)
+	} else {
+		result, err = p.restorePostgres(cfg, dump, metadata)
+	}
+	if err != nil {
+		return err
+	}
+
+	result.File = p.file
+	result.DumpSize = int64(dumpFile.UncompressedSize64)
+	if metadata != nil {
+		result.AlembicRevision = metadata.AlembicRevision
+		result.OpenWebUIVersion = metadata.OpenWebUIVersion
+	}
+	cli.SetResult(result)
+	logrus.Info("Database restored successfully")
+	return nil
+}
+
+// restorePostgres restores a plain SQL or custom format dump into the PostgreSQL database
+func (p *RestoreDatabasePlugin) restorePostgres(cfg *config.Config, dump io.Reader, metadata *database.DatabaseBackupMetadata) (*DatabaseResult, error) {
+	postgresURL := p.postgresURL
+	if postgresURL == "" {
+		postgresURL = database.GetPostgresURLFromEnv()
+	}
+	if postgresURL == "" {
+		return nil, cli.Usage(fmt.Errorf("PostgreSQL connection URL is required (use --postgres-url flag or POSTGRES_URL environment variable)"))
+	}
+
+	target, err := resolvePostgresTarget(postgresURL, p.pgBackend)
+	if err != nil {
+		return nil, err
+	}
+
+	logrus.Infof("Target database: %s", target)
+
+	if err := target.Check(); err != nil {
+		return nil, err
+	}
+
+	// Check the schema before the purge, so a refused restore leaves the database alone
+	mismatch, err := p.checkSchema(cfg, target, metadata, !p.purge && p.sideSchema == "")
+	if err != nil {
+		return nil, err
+	}
 
 	// Purge database if requested
 	if p.purge {
 		logrus.Warn("Purging all database objects before restore...")
-		if err := database.PurgeDatabase(dbConfig, false); err != nil {
-			return fmt.Errorf("failed to purge database: %w", err)
+		if err := target.Purge(false); err != nil {
+			return nil, fmt.Errorf("failed to purge database: %w", err)
 		}
 	}
 
 	// Restore the database
 	restoreOptions := &database.RestoreOptions{
-		CreateDB:     p.createDB,
-		NoOwner:      true,
-		NoPrivileges: true,
-		Verbose:      p.verbose,
+		CreateDB:      p.createDB,
+		NoOwner:       true,
+		NoPrivileges:  true,
+		Verbose:       p.verbose,
+		Tables:        p.tables,
+		ExcludeTables: p.excludeTables,
+		SideSchema:    p.sideSchema,
 	}
 
-	if err := database.RestoreDump(dbConfig, dumpData, restoreOptions); err != nil {
-		return fmt.Errorf("failed to restore database: %w", err)
+	result := target.Result()
+	result.Purged = p.purge
+	result.SchemaMismatch = mismatch
+
+	if len(p.tables) == 0 && len(p.excludeTables) == 0 && p.sideSchema == "" {
+		if err := database.RestoreDump(target.Postgres, dump, restoreOptions); err != nil {
+			return nil, fmt.Errorf("failed to restore database: %w", err)
+		}
+		return result, nil
 	}
 
-	logrus.Info("Database restored successfully")
-	return nil
+	tables, err := database.RestoreTables(target.Postgres, dump, restoreOptions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to restore tables: %w", err)
+	}
+	result.Tables = tables
+	result.SideSchema = p.sideSchema
+	if p.sideSchema != "" {
+		logrus.Infof("Compare and copy rows back with SQL, e.g. INSERT INTO public.<table> SELECT * FROM %s.<table> WHERE ...; drop the schema when done with DROP SCHEMA %s CASCADE", p.sideSchema, p.sideSchema)
+	}
+	return result, nil
 }
 
-// extractDatabaseDump extracts the database dump from a ZIP file
-func (p *RestoreDatabasePlugin) extractDatabaseDump(zipPath string) ([]byte, error) {
-	// Open ZIP file
-	zipReader, err := zip.OpenReader(zipPath)
+// restoreSQLite replaces webui.db in the data directory with the backed up snapshot
+func (p *RestoreDatabasePlugin) restoreSQLite(cfg *config.Config, snapshot io.Reader, metadata *database.DatabaseBackupMetadata) (*DatabaseResult, error) {
+	path, err := database.ResolveSQLitePath(p.dataDir)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open ZIP file: %w", err)
+		return nil, cli.Usage(fmt.Errorf("Open WebUI data directory is required to restore a SQLite backup (use --data-dir or OWUI_DATA_DIR): %w", err))
+	}
+
+	if err := database.CheckSQLiteAvailable(); err != nil {
+		return nil, err
+	}
+
+	logrus.Infof("Target database: sqlite:%s", path)
+
+	// The snapshot replaces the whole file, so differing revisions are never merged
+	mismatch, err := p.checkSchema(cfg, &databaseTarget{SQLitePath: path}, metadata, false)
+	if err != nil {
+		return nil, err
+	}
+
+	logrus.Warn("Open WebUI must be stopped while its SQLite database is restored")
+	if p.purge {
+		logrus.Info("SQLite restores replace the whole database file, --purge is not needed")
 	}
-	defer zipReader.Close()
 
-	// Find database/dump.sql in the ZIP
-	var dumpFile *zip.File
+	if err := database.RestoreSQLite(path, snapshot); err != nil {
+		return nil, fmt.Errorf("failed to restore database: %w", err)
+	}
+
+	return &DatabaseResult{Engine: database.EngineSQLite, Database: path, Purged: p.purge, SchemaMismatch: mismatch}, nil
+}
+
+// checkSchema compares the backup's schema versions with the target database before anything is changed
+// merges is true when the restored tables land next to the existing ones instead of replacing the schema;
+// a differing Alembic revision is then refused, since the rows would not fit the existing tables.
+// It reports whether a mismatch was allowed with --allow-schema-mismatch
+func (p *RestoreDatabasePlugin) checkSchema(cfg *config.Config, target *databaseTarget, metadata *database.DatabaseBackupMetadata, merges bool) (bool, error) {
+	schema, err := target.Schema()
+	if err != nil {
+		if p.requireEmpty {
+			return false, fmt.Errorf("failed to check that the target database is empty: %w", err)
+		}
+		logrus.Warnf("Failed to read the target schema, skipping the compatibility check: %v", err)
+		return false, nil
+	}
+	if p.requireEmpty && !schema.Empty() {
+		return false, fmt.Errorf("target database is not empty (%d tables); --require-empty only restores into an empty database", schema.Tables)
+	}
+
+	if metadata == nil || metadata.AlembicRevision == "" {
+		logrus.Warn("Backup has no recorded Alembic revision; schema compatibility cannot be checked")
+	} else if schema.AlembicRevision == "" {
+		if !schema.Empty() {
+			logrus.Warnf("Target database has %d tables but no Alembic revision; schema compatibility cannot be checked", schema.Tables)
+		}
+	} else {
+		logrus.Infof("Alembic revision: backup %s, target %s", metadata.AlembicRevision, schema.AlembicRevision)
+	}
+
+	var problems []string
+	if metadata != nil && metadata.AlembicRevision != "" && schema.AlembicRevision != "" && metadata.AlembicRevision != schema.AlembicRevision {
+		if merges && !schema.Empty() {
+			problems = append(problems, fmt.Sprintf("backup has Alembic revision %s but the target database has %s, so the restored rows may not fit its tables", metadata.AlembicRevision, schema.AlembicRevision))
+		} else if p.sideSchema != "" {
+			logrus.Warnf("Backup has Alembic revision %s, the target database has %s; columns in %s may differ from the live tables", metadata.AlembicRevision, schema.AlembicRevision, p.sideSchema)
+		} else {
+			logrus.Warnf("Backup has Alembic revision %s, the target database had %s; Open WebUI migrates an older schema on startup but cannot run on a newer one", metadata.AlembicRevision, schema.AlembicRevision)
+		}
+	}
+	if metadata != nil && metadata.OpenWebUIVersion != "" {
+		if !openWebUIConfigured() {
+			logrus.Warnf("OPEN_WEBUI_URL is not set; skipping the check that the running Open WebUI is not older than the backup (%s)", metadata.OpenWebUIVersion)
+		} else if current := openWebUIVersion(cfg); current != "" {
+			if cmp, ok := database.CompareOpenWebUIVersions(metadata.OpenWebUIVersion, current); ok && cmp > 0 {
+				problems = append(problems, fmt.Sprintf("backup is from Open WebUI %s, newer than the running %s, which cannot downgrade its schema", metadata.OpenWebUIVersion, current))
+			}
+		}
+	}
+
+	if len(problems) == 0 {
+		return false, nil
+	}
+	if !p.allowMismatch {
+		return false, fmt.Errorf("schema mismatch: %s (use --allow-schema-mismatch to restore anyway)", strings.Join(problems, "; "))
+	}
+	for _, problem := range problems {
+		logrus.Warnf("⚠️  Schema mismatch: %s", problem)
+	}
+	return true, nil
+}
+
+// findDatabaseDump returns the database dump or SQLite snapshot entry of a backup ZIP
+// The metadata is written after the dump, so a dump without it was cut off
+func findDatabaseDump(zipReader *zip.Reader) (*zip.File, error) {
+	var dump *zip.File
+	hasMetadata := false
 	for _, f := range zipReader.File {
-		if f.Name == "database/dump.sql" {
-			dumpFile = f
-			break
+		switch f.Name {
+		case database.DumpEntry, database.CustomDumpEntry, database.SQLiteEntry:
+			dump = f
+		case database.MetadataEntry:
+			hasMetadata = true
+		}
+	}
+	if dump == nil {
+		return nil, fmt.Errorf("%s, %s or %s not found in backup ZIP", database.DumpEntry, database.CustomDumpEntry, database.SQLiteEntry)
+	}
+	if !hasMetadata {
+		return nil, fmt.Errorf("%s has no %s, so the database dump did not complete", dump.Name, database.MetadataEntry)
+	}
+	return dump, nil
+}
+
+// restoreToTime rebuilds a PostgreSQL data directory as of --to-time from a base backup and the WAL archive
+func (p *RestoreDatabasePlugin) restoreToTime() error {
+	if p.path == "" || p.pgData == "" {
+		return cli.Usage(fmt.Errorf("--to-time needs --path with the WAL archive and --pgdata for the new data directory"))
+	}
+	target, err := parseTargetTime(p.toTime)
+	if err != nil {
+		return cli.Usage(err)
+	}
+	if target.After(time.Now()) {
+		return cli.Usage(fmt.Errorf("recovery target %s is in the future", target.Format(time.RFC3339)))
+	}
+
+	logrus.Infof("Starting point-in-time restore to %s...", target.UTC().Format(time.RFC3339Nano))
+
+	identities, err := loadIdentityContents(p.decryptIdentity, p.path)
+	if err != nil {
+		return err
+	}
+	if len(identities) == 0 {
+		return cli.Usage(fmt.Errorf("no decryption identity provided (use --decrypt-identity, OWUI_DECRYPT_IDENTITY or identity.txt in --path)"))
+	}
+
+	store := wal.NewStore(p.path)
+	var base *wal.BaseBackup
+	if p.baseBackup != "" {
+		base, err = store.Base(p.baseBackup)
+		if err == nil && base.Stopped().After(target) {
+			err = fmt.Errorf("base backup %s finished at %s, after the recovery target", base.ID, base.StopTime)
 		}
+	} else {
+		base, err = store.BaseFor(target)
 	}
+	if err != nil {
+		return err
+	}
+	logrus.Infof("Using base backup %s (finished %s, starts at WAL %s)", base.ID, base.StopTime, base.StartWAL)
 
-	if dumpFile == nil {
-		return nil, fmt.Errorf("database/dump.sql not found in backup ZIP")
+	segments, err := store.Segments()
+	if err != nil {
+		return err
+	}
+	replay := 0
+	var newest time.Time
+	for _, segment := range segments {
+		if segment.Name >= base.StartWAL {
+			replay++
+			if segment.ArchivedAt.After(newest) {
+				newest = segment.ArchivedAt
+			}
+		}
+	}
+	if replay == 0 {
+		logrus.Warn("⚠️  No WAL was archived after the base backup; recovery can only reach the end of the base backup")
+	} else if newest.Before(target) {
+		// Segments are archived when they are full, so the last one may hold changes from after its archive time
+		logrus.Warnf("⚠️  The newest archived WAL is from %s; if the target is not reached, PostgreSQL stops recovery with an error", newest.UTC().Format(time.RFC3339))
+	}
+
+	restoreCommand, err := p.walRestoreCommand()
+	if err != nil {
+		return err
 	}
 
-	// Read the dump file
-	rc, err := dumpFile.Open()
+	dataDir, err := filepath.Abs(p.pgData)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open dump.sql: %w", err)
+		return fmt.Errorf("failed to resolve data directory: %w", err)
+	}
+	logrus.Infof("Rebuilding data directory %s...", dataDir)
+	if err := store.PrepareRecovery(base, &wal.RecoveryOptions{
+		DataDir:        dataDir,
+		TargetTime:     target,
+		RestoreCommand: restoreCommand,
+	}, &encryption.DecryptOptions{Identities: identities}); err != nil {
+		return fmt.Errorf("failed to prepare recovery: %w", err)
 	}
-	defer rc.Close()
 
-	dumpData, err := io.ReadAll(rc)
+	cli.SetResult(&RecoveryResult{
+		BaseBackup:     base.ID,
+		TargetTime:     target.UTC().Format(time.RFC3339Nano),
+		DataDir:        dataDir,
+		RestoreCommand: restoreCommand,
+		WALSegments:    replay,
+	})
+
+	logrus.Info("✓ Data directory prepared for point-in-time recovery")
+	logrus.Infof("Start PostgreSQL on it to replay %d archived WAL files up to the target, e.g.:", replay)
+	logrus.Infof("  pg_ctl -D %s start", dataDir)
+	logrus.Info("The server promotes itself once the target is reached; its owner must be able to run owuicli and read the backup directory")
+	return nil
+}
+
+// walRestoreCommand builds the restore_command that fetches WAL through this binary
+// Identity files are passed as absolute paths, since PostgreSQL runs the command from its data directory
+func (p *RestoreDatabasePlugin) walRestoreCommand() (string, error) {
+	exe, err := os.Executable()
 	if err != nil {
-		return nil, fmt.Errorf("failed to read dump.sql: %w", err)
+		return "", fmt.Errorf("failed to locate owuicli: %w", err)
+	}
+	path, err := filepath.Abs(p.path)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve backup directory: %w", err)
 	}
 
-	logrus.Infof("Extracted database dump from backup (%d bytes)", len(dumpData))
-	return dumpData, nil
+	args := []string{shellQuote(exe), "wal-restore", "--path", shellQuote(path)}
+	if files, err := encryption.GetDecryptIdentityFilesFromEnvOrFlag(p.decryptIdentity); err == nil {
+		for _, file := range files {
+			abs, err := filepath.Abs(file)
+			if err != nil {
+				return "", fmt.Errorf("failed to resolve identity file: %w", err)
+			}
+			args = append(args, "--decrypt-identity", shellQuote(abs))
+		}
+	}
+	args = append(args, "--name", "%f", "--target", "%p")
+	return strings.Join(args, " "), nil
+}
+
+// parseTargetTime parses an RFC3339 timestamp or a local 'YYYY-MM-DD HH:MM:SS' time
+func parseTargetTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return t, nil
+	}
+	for _, layout := range []string{"2006-01-02 15:04:05.999999999", "2006-01-02T15:04:05.999999999"} {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid recovery target %q (use RFC3339 or 'YYYY-MM-DD HH:MM:SS')", value)
+}
+
+// shellQuote quotes an argument for the shell that runs restore_command
+func shellQuote(arg string) string {
+	safe := true
+	for _, c := range arg {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || strings.ContainsRune("/._-+=:,@", c)) {
+			safe = false
+			break
+		}
+	}
+	if safe && arg != "" {
+		return arg
+	}
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
 }
@@ -0,0 +1,129 @@
+package plugins
+
+import (
+	"archive/zip"
+	"fmt"
+	"os"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/vosiander/open-webui-backup/pkg/cli"
+	"github.com/vosiander/open-webui-backup/pkg/config"
+	"github.com/vosiander/open-webui-backup/pkg/database"
+	"github.com/vosiander/open-webui-backup/pkg/encryption"
+	"github.com/vosiander/open-webui-backup/pkg/uploads"
+)
+
+type RestoreUploadsPlugin struct {
+	file            string
+	decryptIdentity []string
+	dataDir         string
+	overwrite       bool
+	dryRun          bool
+}
+
+func NewRestoreUploadsPlugin() *RestoreUploadsPlugin {
+	return &RestoreUploadsPlugin{}
+}
+
+// Name returns the name of the plugin (used as command name)
+func (p *RestoreUploadsPlugin) Name() string {
+	return "restore-uploads"
+}
+
+// Description returns a short description of the plugin
+func (p *RestoreUploadsPlugin) Description() string {
+	return "Restore uploaded files and other assets into the Open WebUI data directory from an encrypted backup"
+}
+
+func (p *RestoreUploadsPlugin) SetupFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&p.file, "file", "f", "", "Encrypted backup file to restore from (required)")
+	cmd.MarkFlagRequired("file")
+	cmd.Flags().StringSliceVar(&p.decryptIdentity, "decrypt-identity", nil, "Age identity file(s) for decryption (or use OWUI_DECRYPTION_IDENTITY env variable)")
+	cmd.Flags().StringVar(&p.dataDir, "data-dir", "", "Data directory to restore into (or use OWUI_DATA_DIR env variable)")
+	cmd.Flags().BoolVar(&p.overwrite, "overwrite", false, "Replace existing files that differ from the backup")
+	cmd.Flags().BoolVar(&p.dryRun, "dry-run", false, "Only report what would be restored")
+}
+
+// Execute runs the plugin with the given configuration
+func (p *RestoreUploadsPlugin) Execute(cfg *config.Config) error {
+	logrus.Info("Starting data directory restore...")
+
+	target := p.dataDir
+	if target == "" {
+		// The target may not exist yet on a fresh standby, so it is not resolved through ResolveRoot
+		target = database.GetDataDirFromEnv()
+	}
+	if target == "" {
+		return cli.Usage(fmt.Errorf("data directory is required (use --data-dir or OWUI_DATA_DIR)"))
+	}
+
+	// Get decryption identities
+	identities, err := encryption.GetDecryptIdentitiesFromEnvOrFlag(p.decryptIdentity)
+	if err != nil {
+		return fmt.Errorf("failed to get decryption identities: %w", err)
+	}
+
+	// Decrypt the backup file
+	logrus.Info("Decrypting backup file...")
+	tempDecrypted := p.file + ".decrypted.tmp"
+	defer os.Remove(tempDecrypted) // Clean up temp file
+
+	decryptOpts := &encryption.DecryptOptions{
+		Identities: identities,
+	}
+
+	if err := encryption.DecryptFile(p.file, tempDecrypted, decryptOpts); err != nil {
+		return fmt.Errorf("failed to decrypt backup: %w", err)
+	}
+
+	zipReader, err := zip.OpenReader(tempDecrypted)
+	if err != nil {
+		return fmt.Errorf("failed to open ZIP file: %w", err)
+	}
+	defer zipReader.Close()
+
+	manifest, err := uploads.ReadManifest(&zipReader.Reader)
+	if err != nil {
+		return err
+	}
+	if manifest == nil {
+		return fmt.Errorf("%s not found in backup ZIP", uploads.ManifestEntry)
+	}
+
+	if p.dryRun {
+		logrus.Infof("Dry run: checking what would be restored to '%s'", target)
+	} else {
+		logrus.Infof("Restoring %d files to '%s'...", manifest.Files, target)
+	}
+
+	stats, err := uploads.Restore(&zipReader.Reader, manifest, &uploads.RestoreOptions{
+		Target:    target,
+		Overwrite: p.overwrite,
+		DryRun:    p.dryRun,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to restore data directory: %w", err)
+	}
+
+	cli.SetResult(&UploadsResult{
+		File:    p.file,
+		Root:    target,
+		Files:   manifest.Files,
+		Dirs:    manifest.Dirs,
+		Size:    manifest.Size,
+		Restore: stats,
+		DryRun:  p.dryRun,
+	})
+
+	logrus.Infof("Restored %d files (%d bytes), %d unchanged, %d kept", stats.Restored, stats.Size, stats.Unchanged, stats.Kept)
+	if stats.Kept > 0 {
+		logrus.Warnf("%d existing files differ from the backup and were kept; rerun with --overwrite to replace them", stats.Kept)
+	}
+	if p.dryRun {
+		logrus.Info("Dry run complete, nothing was written")
+	} else {
+		logrus.Info("Data directory restored successfully")
+	}
+	return nil
+}
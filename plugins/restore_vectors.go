@@ -0,0 +1,115 @@
+package plugins
+
+import (
+	"archive/zip"
+	"fmt"
+	"os"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/vosiander/open-webui-backup/pkg/cli"
+	"github.com/vosiander/open-webui-backup/pkg/config"
+	"github.com/vosiander/open-webui-backup/pkg/encryption"
+	"github.com/vosiander/open-webui-backup/pkg/vectorstore"
+)
+
+type RestoreVectorsPlugin struct {
+	vectorFlags
+	file            string
+	decryptIdentity []string
+}
+
+func NewRestoreVectorsPlugin() *RestoreVectorsPlugin {
+	return &RestoreVectorsPlugin{}
+}
+
+// Name returns the name of the plugin (used as command name)
+func (p *RestoreVectorsPlugin) Name() string {
+	return "restore-vectors"
+}
+
+// Description returns a short description of the plugin
+func (p *RestoreVectorsPlugin) Description() string {
+	return "Restore knowledge embeddings into Chroma or pgvector from an encrypted backup"
+}
+
+func (p *RestoreVectorsPlugin) SetupFlags(cmd *cobra.Command) {
+	p.vectorFlags.setup(cmd)
+	cmd.Flags().StringVarP(&p.file, "file", "f", "", "Encrypted backup file to restore from (required)")
+	cmd.MarkFlagRequired("file")
+	cmd.Flags().StringSliceVar(&p.decryptIdentity, "decrypt-identity", nil, "Age identity file(s) for decryption (or use OWUI_DECRYPTION_IDENTITY env variable)")
+}
+
+// Execute runs the plugin with the given configuration
+func (p *RestoreVectorsPlugin) Execute(cfg *config.Config) error {
+	logrus.Info("Starting vector store restore...")
+
+	// Get decryption identities
+	identities, err := encryption.GetDecryptIdentitiesFromEnvOrFlag(p.decryptIdentity)
+	if err != nil {
+		return fmt.Errorf("failed to get decryption identities: %w", err)
+	}
+
+	// Decrypt the backup file
+	logrus.Info("Decrypting backup file...")
+	tempDecrypted := p.file + ".decrypted.tmp"
+	defer os.Remove(tempDecrypted) // Clean up temp file
+
+	decryptOpts := &encryption.DecryptOptions{
+		Identities: identities,
+	}
+
+	if err := encryption.DecryptFile(p.file, tempDecrypted, decryptOpts); err != nil {
+		return fmt.Errorf("failed to decrypt backup: %w", err)
+	}
+
+	zipReader, err := zip.OpenReader(tempDecrypted)
+	if err != nil {
+		return fmt.Errorf("failed to open ZIP file: %w", err)
+	}
+	defer zipReader.Close()
+
+	metadata, err := vectorstore.ReadMetadata(&zipReader.Reader)
+	if err != nil {
+		return err
+	}
+	if metadata == nil {
+		return fmt.Errorf("%s not found in backup ZIP", vectorstore.MetadataEntry)
+	}
+
+	// The backend of the backup wins unless one is given explicitly
+	if p.vectorDB == "" {
+		p.vectorDB = metadata.Backend
+	}
+	if p.vectorDB != metadata.Backend {
+		return cli.Usage(fmt.Errorf("backup contains %s embeddings and cannot be restored into %s", metadata.Backend, p.vectorDB))
+	}
+
+	store, err := p.store()
+	if err != nil {
+		return err
+	}
+
+	logrus.Infof("Target vector store: %s", store)
+
+	if err := store.Check(); err != nil {
+		return err
+	}
+	if metadata.Backend == vectorstore.BackendChroma {
+		logrus.Warn("Open WebUI must be stopped while its Chroma directory is restored")
+	}
+
+	if err := store.Restore(&zipReader.Reader, metadata); err != nil {
+		return fmt.Errorf("failed to restore vector store: %w", err)
+	}
+
+	cli.SetResult(&VectorResult{
+		File:        p.file,
+		Backend:     store.Name(),
+		Store:       store.String(),
+		Collections: metadata.Collections,
+		Embeddings:  metadata.Embeddings,
+	})
+	logrus.Info("Vector store restored successfully")
+	return nil
+}
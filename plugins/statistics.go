@@ -1,14 +1,14 @@
 package plugins
 
 import (
-	"bytes"
 	"fmt"
+	"os"
 	"text/tabwriter"
 
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	"github.com/vosiander/open-webui-backup/pkg/cli"
 	"github.com/vosiander/open-webui-backup/pkg/config"
-	"github.com/vosiander/open-webui-backup/pkg/database"
 	"github.com/vosiander/open-webui-backup/pkg/openwebui"
 )
 
@@ -44,9 +44,12 @@ func (p *StatisticsPlugin) Execute(cfg *config.Config) error {
 
 	// Create client
 	client := openwebui.NewClient(cfg.OpenWebUIURL, cfg.OpenWebUIAPIKey)
+	if err := checkAPIKey(client); err != nil {
+		return err
+	}
 
 	// Collect statistics
-	stats := &BackupStatistics{}
+	stats := &BackupStatistics{Failed: map[string]string{}}
 
 	// Knowledge bases
 	if p.verbose {
@@ -55,6 +58,7 @@ func (p *StatisticsPlugin) Execute(cfg *config.Config) error {
 	knowledgeBases, err := client.ListKnowledge()
 	if err != nil {
 		logrus.Warnf("Failed to fetch knowledge bases: %v. Continuing with other statistics...", err)
+		stats.Failed["knowledge"] = err.Error()
 		stats.KnowledgeCount = 0
 	} else {
 		stats.KnowledgeCount = len(knowledgeBases)
@@ -85,6 +89,7 @@ func (p *StatisticsPlugin) Execute(cfg *config.Config) error {
 	models, err := client.ExportModels()
 	if err != nil {
 		logrus.Warnf("Failed to fetch models: %v. Continuing with other statistics...", err)
+		stats.Failed["models"] = err.Error()
 		stats.ModelsCount = 0
 	} else {
 		stats.ModelsCount = len(models)
@@ -100,6 +105,7 @@ func (p *StatisticsPlugin) Execute(cfg *config.Config) error {
 	tools, err := client.ExportTools()
 	if err != nil {
 		logrus.Warnf("Failed to fetch tools: %v. Continuing with other statistics...", err)
+		stats.Failed["tools"] = err.Error()
 		stats.ToolsCount = 0
 	} else {
 		stats.ToolsCount = len(tools)
@@ -115,6 +121,7 @@ func (p *StatisticsPlugin) Execute(cfg *config.Config) error {
 	prompts, err := client.ListPrompts()
 	if err != nil {
 		logrus.Warnf("Failed to fetch prompts: %v. Continuing with other statistics...", err)
+		stats.Failed["prompts"] = err.Error()
 		stats.PromptsCount = 0
 	} else {
 		stats.PromptsCount = len(prompts)
@@ -130,6 +137,7 @@ func (p *StatisticsPlugin) Execute(cfg *config.Config) error {
 	files, err := client.ListFiles()
 	if err != nil {
 		logrus.Warnf("Failed to fetch files: %v. Continuing with other statistics...", err)
+		stats.Failed["files"] = err.Error()
 		stats.FilesCount = 0
 	} else {
 		stats.FilesCount = len(files)
@@ -148,6 +156,7 @@ func (p *StatisticsPlugin) Execute(cfg *config.Config) error {
 	chats, err := client.GetAllChatsDB()
 	if err != nil {
 		logrus.Warnf("Failed to fetch chats: %v. Continuing with other statistics...", err)
+		stats.Failed["chats"] = err.Error()
 		stats.ChatsCount = 0
 	} else {
 		stats.ChatsCount = len(chats)
@@ -163,6 +172,7 @@ func (p *StatisticsPlugin) Execute(cfg *config.Config) error {
 	groups, err := client.GetAllGroups()
 	if err != nil {
 		logrus.Warnf("Failed to fetch groups: %v. Continuing with other statistics...", err)
+		stats.Failed["groups"] = err.Error()
 		stats.GroupsCount = 0
 	} else {
 		stats.GroupsCount = len(groups)
@@ -178,6 +188,7 @@ func (p *StatisticsPlugin) Execute(cfg *config.Config) error {
 	feedbacks, err := client.GetAllFeedbacks()
 	if err != nil {
 		logrus.Warnf("Failed to fetch feedbacks: %v. Continuing with other statistics...", err)
+		stats.Failed["feedbacks"] = err.Error()
 		stats.FeedbacksCount = 0
 	} else {
 		stats.FeedbacksCount = len(feedbacks)
@@ -193,6 +204,7 @@ func (p *StatisticsPlugin) Execute(cfg *config.Config) error {
 	users, err := client.GetAllUsers()
 	if err != nil {
 		logrus.Warnf("Failed to fetch users: %v. Continuing with other statistics...", err)
+		stats.Failed["users"] = err.Error()
 		stats.UsersCount = 0
 	} else {
 		stats.UsersCount = len(users)
@@ -202,46 +214,59 @@ func (p *StatisticsPlugin) Execute(cfg *config.Config) error {
 	}
 
 	// Database
-	stats.DatabaseConfigured = database.IsPostgresURLSet()
+	stats.DatabaseConfigured = isDatabaseConfigured()
 	if stats.DatabaseConfigured {
-		postgresURL := database.GetPostgresURLFromEnv()
-		dbConfig, err := database.ParsePostgresURL(postgresURL)
-		if err == nil {
-			stats.DatabaseName = dbConfig.Database
+		if target, err := resolveDatabaseTarget("", "", ""); err == nil {
+			stats.DatabaseEngine = target.Engine()
+			stats.DatabaseName = target.Result().Database
 		}
 	}
 
+	stats.TotalItems = stats.KnowledgeCount + stats.ModelsCount + stats.ToolsCount + stats.PromptsCount +
+		stats.FilesCount + stats.ChatsCount + stats.GroupsCount + stats.FeedbacksCount + stats.UsersCount
+	stats.DownloadSize = stats.FilesSize + stats.KnowledgeSize
+
 	// Display statistics
-	p.displayStatistics(stats)
+	cli.SetResult(stats)
+	if !cli.JSON() {
+		p.displayStatistics(stats)
+	}
 
+	if len(stats.Failed) > 0 {
+		return cli.Partialf("statistics incomplete, failed to fetch: %s", failedNames(stats.Failed))
+	}
 	return nil
 }
 
 // BackupStatistics holds statistics about backup content
 type BackupStatistics struct {
-	KnowledgeCount     int
-	KnowledgeSize      int64
-	ModelsCount        int
-	ToolsCount         int
-	PromptsCount       int
-	FilesCount         int
-	FilesSize          int64
-	ChatsCount         int
-	GroupsCount        int
-	FeedbacksCount     int
-	UsersCount         int
-	DatabaseConfigured bool
-	DatabaseName       string
+	KnowledgeCount     int               `json:"knowledgeCount"`
+	KnowledgeSize      int64             `json:"knowledgeSize"`
+	ModelsCount        int               `json:"modelsCount"`
+	ToolsCount         int               `json:"toolsCount"`
+	PromptsCount       int               `json:"promptsCount"`
+	FilesCount         int               `json:"filesCount"`
+	FilesSize          int64             `json:"filesSize"`
+	ChatsCount         int               `json:"chatsCount"`
+	GroupsCount        int               `json:"groupsCount"`
+	FeedbacksCount     int               `json:"feedbacksCount"`
+	UsersCount         int               `json:"usersCount"`
+	TotalItems         int               `json:"totalItems"`
+	DownloadSize       int64             `json:"estimatedDownloadSize"`
+	DatabaseConfigured bool              `json:"databaseConfigured"`
+	DatabaseEngine     string            `json:"databaseEngine,omitempty"`
+	DatabaseName       string            `json:"databaseName,omitempty"`
+	Failed             map[string]string `json:"failed,omitempty"`
 }
 
 // displayStatistics prints the statistics in a formatted table
 func (p *StatisticsPlugin) displayStatistics(stats *BackupStatistics) {
-	logrus.Info("Backup Statistics")
-	logrus.Info("=================\n")
+	fmt.Println("Backup Statistics")
+	fmt.Println("=================")
+	fmt.Println()
 
-	// Create tab writer for aligned columns using buffer
-	var buf bytes.Buffer
-	w := tabwriter.NewWriter(&buf, 0, 0, 3, ' ', 0)
+	// Create tab writer for aligned columns
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
 
 	// Header
 	fmt.Fprintln(w, "Content Type\tCount\tSize")
@@ -259,28 +284,22 @@ func (p *StatisticsPlugin) displayStatistics(stats *BackupStatistics) {
 	fmt.Fprintf(w, "Users\t%d\tN/A\n", stats.UsersCount)
 
 	if stats.DatabaseConfigured {
-		fmt.Fprintf(w, "Database\tConfigured\t%s\n", stats.DatabaseName)
+		fmt.Fprintf(w, "Database\tConfigured (%s)\t%s\n", stats.DatabaseEngine, stats.DatabaseName)
 	} else {
 		fmt.Fprintf(w, "Database\tNot Configured\t-\n")
 	}
 
 	w.Flush()
 
-	// Output the table via logrus
-	logrus.Info(buf.String())
-
 	// Summary
-	totalItems := stats.KnowledgeCount + stats.ModelsCount + stats.ToolsCount + stats.PromptsCount +
-		stats.FilesCount + stats.ChatsCount + stats.GroupsCount + stats.FeedbacksCount + stats.UsersCount
-
-	logrus.Infof("Total Items: %d", totalItems)
+	fmt.Println()
+	fmt.Printf("Total Items: %d\n", stats.TotalItems)
 
-	// Calculate total download size (only files and knowledge base files)
-	totalSize := stats.FilesSize + stats.KnowledgeSize
-	if totalSize > 0 {
-		logrus.Infof("Estimated Download Size: %s (files and knowledge base content)", formatSize(totalSize))
+	// Only files and knowledge base files have a known download size
+	if stats.DownloadSize > 0 {
+		fmt.Printf("Estimated Download Size: %s (files and knowledge base content)\n", formatSize(stats.DownloadSize))
 	} else {
-		logrus.Info("Estimated Download Size: No file content available")
+		fmt.Println("Estimated Download Size: No file content available")
 	}
 
 	// Notes
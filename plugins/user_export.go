@@ -0,0 +1,122 @@
+package plugins
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/vosiander/open-webui-backup/pkg/archive"
+	"github.com/vosiander/open-webui-backup/pkg/config"
+	"github.com/vosiander/open-webui-backup/pkg/encryption"
+	"github.com/vosiander/open-webui-backup/pkg/openwebui"
+	"github.com/vosiander/open-webui-backup/pkg/userexport"
+)
+
+// UserExportPlugin writes a subject access export of one user's data
+type UserExportPlugin struct {
+	user             string
+	out              string
+	encryptRecipient []string
+	file             string
+	decryptIdentity  []string
+}
+
+// NewUserExportPlugin creates a new instance of the UserExportPlugin
+func NewUserExportPlugin() *UserExportPlugin {
+	return &UserExportPlugin{}
+}
+
+// Name returns the command name
+func (p *UserExportPlugin) Name() string {
+	return "user-export"
+}
+
+// Description returns the command description
+func (p *UserExportPlugin) Description() string {
+	return "Export everything owned by one user into an encrypted bundle (subject access request)"
+}
+
+// SetupFlags configures the command-line flags
+func (p *UserExportPlugin) SetupFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&p.user, "user", "u", "", "User email or ID (required)")
+	cmd.Flags().StringVarP(&p.out, "out", "o", "", "Output file for the bundle (required, .age extension will be appended)")
+	cmd.Flags().StringSliceVar(&p.encryptRecipient, "encrypt-recipient", nil, "Age public key(s) or key file(s) chosen by the user (required)")
+	cmd.Flags().StringVar(&p.file, "file", "", "Collect from this backup file instead of the live instance")
+	cmd.Flags().StringSliceVar(&p.decryptIdentity, "decrypt-identity", nil, "Age identity file(s) for encrypted backups (or use OWUI_DECRYPT_IDENTITY env variable)")
+	cmd.MarkFlagRequired("user")
+	cmd.MarkFlagRequired("out")
+	cmd.MarkFlagRequired("encrypt-recipient")
+}
+
+// Execute collects the user's data and writes the encrypted bundle
+func (p *UserExportPlugin) Execute(cfg *config.Config) error {
+	// Only the recipient given on the command line is used: the bundle is for the user, not for our backup key
+	recipients, err := encryption.GetEncryptRecipientsFromEnvOrFlag(p.encryptRecipient)
+	if err != nil {
+		return fmt.Errorf("failed to get encryption recipients: %w", err)
+	}
+
+	bundle, err := p.collect(cfg)
+	if err != nil {
+		return err
+	}
+
+	var summary []string
+	for _, section := range []string{"chats", "memories", "files", "knowledge", "prompts", "models", "tools", "feedbacks"} {
+		if n := bundle.Counts()[section]; n > 0 {
+			summary = append(summary, fmt.Sprintf("%d %s", n, section))
+		}
+	}
+	if len(summary) == 0 {
+		summary = append(summary, "profile only")
+	}
+	logrus.Infof("Collected data of %s: %s", bundle.User.Email, strings.Join(summary, ", "))
+	for _, note := range bundle.Notes {
+		logrus.Warn(note)
+	}
+
+	encryptedPath := strings.TrimSuffix(p.out, ".age") + ".age"
+	zipPath := encryptedPath + ".tmp"
+	defer os.Remove(zipPath)
+
+	if err := bundle.Write(zipPath); err != nil {
+		return err
+	}
+	if err := encryption.EncryptFileWithRecipients(zipPath, encryptedPath, recipients); err != nil {
+		return fmt.Errorf("failed to encrypt bundle: %w", err)
+	}
+
+	logrus.Infof("User export written: %s", filepath.Base(encryptedPath))
+	return nil
+}
+
+// collect gathers the bundle from a backup file or the live instance
+func (p *UserExportPlugin) collect(cfg *config.Config) (*userexport.Bundle, error) {
+	if p.file != "" {
+		identities, err := loadIdentityContents(p.decryptIdentity, "")
+		if err != nil {
+			return nil, err
+		}
+		zipPath, cleanup, err := archive.Decrypt(p.file, identities)
+		if err != nil {
+			return nil, err
+		}
+		defer cleanup()
+
+		bundle, err := userexport.FromArchive(zipPath, p.user)
+		if err != nil {
+			return nil, err
+		}
+		bundle.Source = filepath.Base(p.file)
+		return bundle, nil
+	}
+
+	if cfg.OpenWebUIAPIKey == "" {
+		return nil, fmt.Errorf("OPEN_WEBUI_API_KEY environment variable is required (or use --file)")
+	}
+	client := openwebui.NewClient(cfg.OpenWebUIURL, cfg.OpenWebUIAPIKey)
+	return userexport.Collect(client, p.user)
+}
@@ -12,16 +12,53 @@ import (
 
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	"github.com/vosiander/open-webui-backup/pkg/cli"
 	"github.com/vosiander/open-webui-backup/pkg/config"
+	"github.com/vosiander/open-webui-backup/pkg/database"
 	"github.com/vosiander/open-webui-backup/pkg/encryption"
 	"github.com/vosiander/open-webui-backup/pkg/openwebui"
+	"github.com/vosiander/open-webui-backup/pkg/signing"
+	"github.com/vosiander/open-webui-backup/pkg/snapshot"
+	"github.com/vosiander/open-webui-backup/pkg/uploads"
 )
 
 // VerifyPlugin verifies that a backup can be decrypted and optionally validates contents
 type VerifyPlugin struct {
-	path           string
-	file           string
-	onlyEncryption bool
+	path             string
+	file             string
+	onlyEncryption   bool
+	trustedKeys      []string
+	requireSignature bool
+	dataDir          string
+}
+
+// VerifyResult is the result object of the verify command
+type VerifyResult struct {
+	File            string                `json:"file"`
+	Encrypted       bool                  `json:"encrypted"`
+	Signature       string                `json:"signature"`
+	KeyID           string                `json:"keyId,omitempty"`
+	ManifestEntries int                   `json:"manifestEntries,omitempty"`
+	ContentsChecked bool                  `json:"contentsChecked"`
+	BackupType      string                `json:"backupType,omitempty"`
+	CreatedAt       string                `json:"createdAt,omitempty"`
+	ToolVersion     string                `json:"toolVersion,omitempty"`
+	ItemCount       int                   `json:"itemCount,omitempty"`
+	Items           map[string]int        `json:"items,omitempty"`
+	Uploads         *uploads.VerifyReport `json:"uploads,omitempty"`
+	Snapshot        *SnapshotResult       `json:"snapshot,omitempty"`
+	Database        *DatabaseInfo         `json:"database,omitempty"`
+}
+
+// DatabaseInfo describes the database section of a backup
+type DatabaseInfo struct {
+	Engine           string                  `json:"engine"`
+	Database         string                  `json:"database"`
+	Format           string                  `json:"format"`
+	EngineVersion    string                  `json:"engineVersion,omitempty"`
+	AlembicRevision  string                  `json:"alembicRevision,omitempty"`
+	OpenWebUIVersion string                  `json:"openWebUIVersion,omitempty"`
+	Anonymization    *database.Anonymization `json:"anonymization,omitempty"`
 }
 
 // NewVerifyPlugin creates a new instance of the VerifyPlugin
@@ -44,6 +81,9 @@ func (p *VerifyPlugin) SetupFlags(cmd *cobra.Command) {
 	cmd.Flags().StringVar(&p.path, "path", "", "Directory containing identity.txt and backup files (required)")
 	cmd.Flags().StringVar(&p.file, "file", "", "Specific backup file to verify (optional, auto-detects newest .age file if not provided)")
 	cmd.Flags().BoolVar(&p.onlyEncryption, "only-encryption", false, "Only verify decryption, skip content validation")
+	cmd.Flags().StringSliceVar(&p.trustedKeys, "trusted-keys", nil, "Trusted signing public key(s) or key file(s) (or use OWUI_TRUSTED_KEYS env variable)")
+	cmd.Flags().BoolVar(&p.requireSignature, "require-signature", false, "Fail if the backup is unsigned or signed by an untrusted key (or use OWUI_REQUIRE_SIGNATURE env variable)")
+	cmd.Flags().StringVar(&p.dataDir, "data-dir", "", "Compare the data directory backup with this live directory")
 	cmd.MarkFlagRequired("path")
 }
 
@@ -82,15 +122,39 @@ func (p *VerifyPlugin) Execute(cfg *config.Config) error {
 		return fmt.Errorf("backup file not found: %s", backupFile)
 	}
 
+	// Check the detached signature before touching the archive contents
+	trusted, err := signing.GetTrustedKeysFromEnvOrFlag(p.trustedKeys)
+	if err != nil {
+		return fmt.Errorf("failed to load trusted keys: %w", err)
+	}
+	sigResult, err := signing.VerifyArchive(backupFile, trusted)
+	if err != nil {
+		return fmt.Errorf("failed to verify signature: %w", err)
+	}
+	result := &VerifyResult{
+		File:      backupFile,
+		Signature: string(sigResult.Status),
+		KeyID:     sigResult.KeyID,
+	}
+	cli.SetResult(result)
+	if err := signing.Enforce(sigResult, signing.IsSignatureRequired(p.requireSignature)); err != nil {
+		logrus.Error("❌ Verification FAILED: Signature check did not pass")
+		return cli.Verification(err)
+	}
+
 	// Check if file is encrypted
 	if !encryption.IsEncrypted(backupFile) {
 		logrus.Warn("⚠️  Backup file is not encrypted")
+		if err := p.verifySignedEntries(sigResult, backupFile, result); err != nil {
+			return err
+		}
 		if p.onlyEncryption {
 			return nil
 		}
 		// For unencrypted files, validate directly
-		return p.validateBackupContents(backupFile, log)
+		return p.validateBackupContents(backupFile, log, result)
 	}
+	result.Encrypted = true
 
 	// Decrypt to temporary file
 	log.Info("Verifying backup decryption...")
@@ -99,11 +163,15 @@ func (p *VerifyPlugin) Execute(cfg *config.Config) error {
 
 	if err := encryption.DecryptFileWithIdentities(backupFile, tempFile, []string{string(identityContent)}); err != nil {
 		logrus.Error("❌ Verification FAILED: Unable to decrypt backup")
-		return fmt.Errorf("decryption failed: %w", err)
+		return cli.Verification(fmt.Errorf("decryption failed: %w", err))
 	}
 
 	logrus.Info("✓ Decryption successful - identity key is correct")
 
+	if err := p.verifySignedEntries(sigResult, tempFile, result); err != nil {
+		return err
+	}
+
 	// If only checking encryption, we're done
 	if p.onlyEncryption {
 		os.Remove(tempFile)
@@ -111,18 +179,32 @@ func (p *VerifyPlugin) Execute(cfg *config.Config) error {
 	}
 
 	// Validate backup contents
-	return p.validateBackupContents(tempFile, log)
+	return p.validateBackupContents(tempFile, log, result)
+}
+
+// verifySignedEntries checks the decrypted ZIP against the signed manifest, if any
+func (p *VerifyPlugin) verifySignedEntries(sigResult *signing.VerifyResult, zipPath string, result *VerifyResult) error {
+	if sigResult.Manifest == nil {
+		return nil
+	}
+	if err := signing.VerifyEntries(sigResult.Manifest, zipPath); err != nil {
+		logrus.Error("❌ Verification FAILED: Backup contents do not match signed manifest")
+		return cli.Verification(fmt.Errorf("signed manifest mismatch: %w", err))
+	}
+	result.ManifestEntries = len(sigResult.Manifest.Entries)
+	logrus.Infof("✓ All %d entries match the signed manifest", result.ManifestEntries)
+	return nil
 }
 
 // validateBackupContents validates the ZIP structure and contents
-func (p *VerifyPlugin) validateBackupContents(zipPath string, log *logrus.Entry) error {
+func (p *VerifyPlugin) validateBackupContents(zipPath string, log *logrus.Entry, result *VerifyResult) error {
 	log.Info("Validating backup contents...")
 
 	// Open ZIP file
 	r, err := zip.OpenReader(zipPath)
 	if err != nil {
 		logrus.Error("❌ Verification FAILED: Invalid ZIP file")
-		return fmt.Errorf("failed to open ZIP file: %w", err)
+		return cli.Verification(fmt.Errorf("failed to open ZIP file: %w", err))
 	}
 	defer r.Close()
 
@@ -135,6 +217,34 @@ func (p *VerifyPlugin) validateBackupContents(zipPath string, log *logrus.Entry)
 
 	// Count items by type
 	itemCounts := countBackupItems(r)
+	result.ContentsChecked = true
+	result.Items = itemCounts
+	if metadata != nil {
+		result.BackupType = getBackupType(metadata)
+		result.CreatedAt = metadata.BackupTimestamp
+		result.ToolVersion = metadata.BackupToolVersion
+		result.ItemCount = metadata.ItemCount
+	}
+
+	if err := p.verifyUploads(r, result); err != nil {
+		return err
+	}
+
+	record, err := snapshot.Read(&r.Reader)
+	if err != nil {
+		logrus.Warnf("⚠️  Warning: Could not read snapshot record: %v", err)
+	}
+	if record != nil {
+		result.Snapshot = newSnapshotResult(record)
+	}
+
+	dbMetadata, err := database.ReadMetadata(&r.Reader)
+	if err != nil {
+		logrus.Warnf("⚠️  Warning: Could not read database metadata: %v", err)
+	}
+	if dbMetadata != nil {
+		result.Database = newDatabaseInfo(dbMetadata)
+	}
 
 	// Print results
 	logrus.Info("✓ Backup contents validated successfully")
@@ -154,6 +264,40 @@ func (p *VerifyPlugin) validateBackupContents(zipPath string, log *logrus.Entry)
 		}
 	}
 
+	if record != nil {
+		logrus.Info("=== Consistent Snapshot ===")
+		logrus.Infof("Started: %s (window %.1fs)", record.StartedAt, record.WindowSecs)
+		if record.Database != nil && record.Database.LSN != "" {
+			logrus.Infof("Database LSN: %s", record.Database.LSN)
+		} else if record.Database == nil {
+			logrus.Warn("⚠️  No database state was pinned for this snapshot")
+		}
+		if record.Maintenance != nil && record.Maintenance.Error != "" {
+			logrus.Warnf("⚠️  Maintenance exit failed: %s", record.Maintenance.Error)
+		}
+		if len(record.Changed) > 0 {
+			logrus.Warnf("⚠️  %d entities changed during the capture window (see %s)", len(record.Changed), snapshot.Entry)
+		} else {
+			logrus.Info("No entities changed during the capture window")
+		}
+	}
+
+	if info := result.Database; info != nil {
+		logrus.Info("=== Database ===")
+		logrus.Infof("Engine: %s %s (%s)", info.Engine, info.EngineVersion, info.Format)
+		if info.AlembicRevision != "" {
+			logrus.Infof("Alembic Revision: %s", info.AlembicRevision)
+		} else {
+			logrus.Warn("⚠️  No Alembic revision recorded; restores cannot check schema compatibility")
+		}
+		if info.OpenWebUIVersion != "" {
+			logrus.Infof("Open WebUI Version: %s", info.OpenWebUIVersion)
+		}
+		if info.Anonymization != nil {
+			logrus.Infof("Anonymized: ruleset %s (%s)", info.Anonymization.Ruleset, info.Anonymization.Fingerprint)
+		}
+	}
+
 	if len(itemCounts) > 0 {
 		logrus.Info("=== Item Counts ===")
 		// Sort keys for consistent output
@@ -173,6 +317,74 @@ func (p *VerifyPlugin) validateBackupContents(zipPath string, log *logrus.Entry)
 	return nil
 }
 
+// newDatabaseInfo summarizes the metadata of a database section
+func newDatabaseInfo(metadata *database.DatabaseBackupMetadata) *DatabaseInfo {
+	info := &DatabaseInfo{
+		Engine:           metadata.Engine,
+		Database:         metadata.DatabaseName,
+		Format:           metadata.DumpFormat,
+		EngineVersion:    metadata.PostgresVersion,
+		AlembicRevision:  metadata.AlembicRevision,
+		OpenWebUIVersion: metadata.OpenWebUIVersion,
+		Anonymization:    metadata.Anonymization,
+	}
+	if info.EngineVersion == "" {
+		info.EngineVersion = metadata.SQLiteVersion
+	}
+	return info
+}
+
+// verifyUploads checks the files of the data directory section against its manifest
+// Live directory differences are reported but do not fail verification
+func (p *VerifyPlugin) verifyUploads(r *zip.ReadCloser, result *VerifyResult) error {
+	manifest, err := uploads.ReadManifest(&r.Reader)
+	if err != nil {
+		logrus.Error("❌ Verification FAILED: Data directory manifest is unreadable")
+		return cli.Verification(err)
+	}
+	if manifest == nil {
+		if p.dataDir != "" {
+			logrus.Warn("⚠️  Backup has no data directory section to compare with --data-dir")
+		}
+		return nil
+	}
+
+	report, err := uploads.Verify(&r.Reader, manifest, p.dataDir)
+	if err != nil {
+		logrus.Error("❌ Verification FAILED: Data directory backup could not be checked")
+		return cli.Verification(err)
+	}
+	result.Uploads = report
+
+	if !report.OK() {
+		for _, path := range report.Corrupt {
+			logrus.Errorf("Corrupt or missing in backup: %s", path)
+		}
+		logrus.Error("❌ Verification FAILED: Data directory files do not match their checksums")
+		return cli.Verification(fmt.Errorf("%d of %d data directory files failed verification", len(report.Corrupt), report.Files))
+	}
+	logrus.Infof("✓ All %d data directory files match their checksums", report.Checked)
+
+	if p.dataDir != "" {
+		if report.InSync() {
+			logrus.Infof("✓ Data directory %s matches the backup", p.dataDir)
+		} else {
+			logrus.Warnf("⚠️  Data directory %s differs from the backup: %d missing, %d changed, %d added",
+				p.dataDir, len(report.Missing), len(report.Changed), len(report.Added))
+			for _, path := range report.Missing {
+				logrus.Infof("  missing: %s", path)
+			}
+			for _, path := range report.Changed {
+				logrus.Infof("  changed: %s", path)
+			}
+			for _, path := range report.Added {
+				logrus.Infof("  added:   %s", path)
+			}
+		}
+	}
+	return nil
+}
+
 // findNewestBackup finds the most recent .age file in the directory
 func findNewestBackup(dir string) (string, error) {
 	entries, err := os.ReadDir(dir)
@@ -291,6 +503,11 @@ func countBackupItems(r *zip.ReadCloser) map[string]int {
 			if strings.HasSuffix(f.Name, "/group.json") {
 				counts["groups"]++
 			}
+		case "uploads":
+			// Count files of the data directory section
+			if strings.HasPrefix(f.Name, uploads.FilesPrefix) {
+				counts["uploads"]++
+			}
 		case "feedbacks":
 			// Count feedback.json files
 			if strings.HasSuffix(f.Name, "/feedback.json") {
@@ -0,0 +1,79 @@
+package plugins
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/vosiander/open-webui-backup/pkg/cli"
+	"github.com/vosiander/open-webui-backup/pkg/config"
+	"github.com/vosiander/open-webui-backup/pkg/encryption"
+	"github.com/vosiander/open-webui-backup/pkg/wal"
+)
+
+// WALArchivePlugin stores a WAL segment as PostgreSQL's archive_command
+type WALArchivePlugin struct {
+	path             string
+	source           string
+	name             string
+	encryptRecipient []string
+}
+
+// WALResult is the result object of the WAL archive commands
+type WALResult struct {
+	Name     string `json:"name"`
+	File     string `json:"file"`
+	Archived bool   `json:"archived"`
+	Existed  bool   `json:"existed,omitempty"`
+}
+
+func NewWALArchivePlugin() *WALArchivePlugin {
+	return &WALArchivePlugin{}
+}
+
+// Name returns the name of the plugin (used as command name)
+func (p *WALArchivePlugin) Name() string {
+	return "wal-archive"
+}
+
+// Description returns a short description of the plugin
+func (p *WALArchivePlugin) Description() string {
+	return "Encrypt a PostgreSQL WAL segment into the backup directory (use as archive_command)"
+}
+
+func (p *WALArchivePlugin) SetupFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&p.path, "path", "", "Backup directory holding the WAL archive (required)")
+	cmd.MarkFlagRequired("path")
+	cmd.Flags().StringVar(&p.source, "source", "", "Path of the WAL file to archive, %p in archive_command (required)")
+	cmd.MarkFlagRequired("source")
+	cmd.Flags().StringVar(&p.name, "name", "", "Name of the WAL file, %f in archive_command (required)")
+	cmd.MarkFlagRequired("name")
+	cmd.Flags().StringSliceVar(&p.encryptRecipient, "encrypt-recipient", nil, "Encrypt with age public key(s) (or use OWUI_ENCRYPTED_RECIPIENT env variable, defaults to recipient.txt in --path)")
+}
+
+// Execute runs the plugin with the given configuration
+func (p *WALArchivePlugin) Execute(cfg *config.Config) error {
+	if err := wal.ValidName(p.name); err != nil {
+		return cli.Usage(err)
+	}
+
+	recipients, err := loadRecipients(p.encryptRecipient, p.path)
+	if err != nil {
+		return fmt.Errorf("failed to get encryption recipients: %w", err)
+	}
+
+	store := wal.NewStore(p.path)
+	existed, err := store.Archive(p.source, p.name, &encryption.EncryptOptions{Recipients: recipients})
+	if err != nil {
+		return err
+	}
+
+	cli.SetResult(&WALResult{Name: p.name, File: store.SegmentPath(p.name), Archived: true, Existed: existed})
+	if existed {
+		logrus.Infof("%s was already archived", p.name)
+	} else {
+		logrus.Infof("Archived %s to %s", p.name, filepath.Join(p.path, wal.SegmentDir))
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package plugins
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/vosiander/open-webui-backup/pkg/cli"
+	"github.com/vosiander/open-webui-backup/pkg/config"
+	"github.com/vosiander/open-webui-backup/pkg/encryption"
+	"github.com/vosiander/open-webui-backup/pkg/wal"
+)
+
+// WALRestorePlugin fetches a WAL segment as PostgreSQL's restore_command
+type WALRestorePlugin struct {
+	path            string
+	name            string
+	target          string
+	decryptIdentity []string
+}
+
+func NewWALRestorePlugin() *WALRestorePlugin {
+	return &WALRestorePlugin{}
+}
+
+// Name returns the name of the plugin (used as command name)
+func (p *WALRestorePlugin) Name() string {
+	return "wal-restore"
+}
+
+// Description returns a short description of the plugin
+func (p *WALRestorePlugin) Description() string {
+	return "Decrypt a PostgreSQL WAL segment from the backup directory (use as restore_command)"
+}
+
+func (p *WALRestorePlugin) SetupFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&p.path, "path", "", "Backup directory holding the WAL archive (required)")
+	cmd.MarkFlagRequired("path")
+	cmd.Flags().StringVar(&p.name, "name", "", "Name of the WAL file, %f in restore_command (required)")
+	cmd.MarkFlagRequired("name")
+	cmd.Flags().StringVar(&p.target, "target", "", "Path to write the WAL file to, %p in restore_command (required)")
+	cmd.MarkFlagRequired("target")
+	cmd.Flags().StringSliceVar(&p.decryptIdentity, "decrypt-identity", nil, "Age identity file(s) for decryption (or use OWUI_DECRYPT_IDENTITY env variable, defaults to identity.txt in --path)")
+}
+
+// Execute runs the plugin with the given configuration
+func (p *WALRestorePlugin) Execute(cfg *config.Config) error {
+	if err := wal.ValidName(p.name); err != nil {
+		return cli.Usage(err)
+	}
+
+	identities, err := loadIdentityContents(p.decryptIdentity, p.path)
+	if err != nil {
+		return err
+	}
+	if len(identities) == 0 {
+		return cli.Usage(fmt.Errorf("no decryption identity provided (use --decrypt-identity, OWUI_DECRYPT_IDENTITY or identity.txt in --path)"))
+	}
+
+	store := wal.NewStore(p.path)
+	if err := store.Fetch(p.name, p.target, &encryption.DecryptOptions{Identities: identities}); err != nil {
+		if errors.Is(err, wal.ErrNotFound) {
+			// PostgreSQL probes for files past the end of the archive; this ends recovery and is not a failure
+			logrus.Infof("%s is not in the WAL archive", p.name)
+		}
+		return err
+	}
+
+	cli.SetResult(&WALResult{Name: p.name, File: p.target})
+	logrus.Infof("Restored %s", p.name)
+	return nil
+}
@@ -1,9 +1,12 @@
 import type {
+    BackupContents,
+    BackupEntityPreview,
     BackupRequest,
     ConfigResponse,
     GenerateIdentityResponse,
     OperationStartResponse,
     OperationStatus,
+    RestorePlan,
     RestoreRequest,
     UpdateConfigRequest,
 } from '../types/api';
@@ -87,6 +90,68 @@ export async function startRestore(
   });
 }
 
+export async function planRestore(
+  request: RestoreRequest
+): Promise<RestorePlan> {
+  return fetchJSON<RestorePlan>(`${API_BASE}/restore/plan`, {
+    method: 'POST',
+    body: JSON.stringify(request),
+  });
+}
+
+export async function getBackupContents(
+  filename: string,
+  decryptIdentity: string
+): Promise<BackupContents> {
+  return fetchJSON<BackupContents>(`${API_BASE}/backups/contents`, {
+    method: 'POST',
+    body: JSON.stringify({ filename, decryptIdentity }),
+  });
+}
+
+export async function getBackupEntity(
+  filename: string,
+  decryptIdentity: string,
+  type: string,
+  id: string
+): Promise<BackupEntityPreview> {
+  return fetchJSON<BackupEntityPreview>(`${API_BASE}/backups/entity`, {
+    method: 'POST',
+    body: JSON.stringify({ filename, decryptIdentity, type, id }),
+  });
+}
+
+export async function getBackupAttachment(
+  filename: string,
+  decryptIdentity: string,
+  type: string,
+  id: string,
+  attachment: string
+): Promise<Blob> {
+  const response = await fetch(`${API_BASE}/backups/entity`, {
+    method: 'POST',
+    headers: {
+      'Content-Type': 'application/json',
+    },
+    body: JSON.stringify({ filename, decryptIdentity, type, id, attachment }),
+  });
+
+  if (!response.ok) {
+    let errorMessage = `HTTP ${response.status}: ${response.statusText}`;
+    try {
+      const errorData = await response.json();
+      if (errorData.error) {
+        errorMessage = errorData.error;
+      }
+    } catch {
+      // Ignore JSON parse errors for error responses
+    }
+    throw new APIError(errorMessage, response.status);
+  }
+
+  return await response.blob();
+}
+
 export async function getOperationStatus(
   operationId: string
 ): Promise<OperationStatus> {
@@ -98,6 +163,7 @@ export interface BackupFile {
   size: number;
   modTime: string;
   downloadUrl: string;
+  signed: boolean;
 }
 
 export async function listBackups(): Promise<BackupFile[]> {
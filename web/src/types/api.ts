@@ -9,6 +9,85 @@ export interface RestoreRequest {
   decryptIdentity: string;
   dataTypes: DataTypeSelection;
   overwrite: boolean;
+  items?: string[];
+}
+
+export interface BackupItem {
+  id: string;
+  name: string;
+  selector: string;
+  createdAt?: number;
+  updatedAt?: number;
+  attachments?: number;
+}
+
+export interface BackupMetadata {
+  open_webui_url: string;
+  open_webui_version?: string;
+  backup_tool_version: string;
+  backup_timestamp: string;
+  item_count: number;
+  contained_types?: string[];
+  redaction?: {
+    profile: string;
+    redacted_at: string;
+    rules: string[];
+    keyed: boolean;
+  };
+  erasures?: string[];
+}
+
+export interface BackupContents {
+  filename: string;
+  metadata?: BackupMetadata;
+  counts: Record<string, number>;
+  items: Record<string, BackupItem[]>;
+}
+
+export interface BackupEntity {
+  type: string;
+  id: string;
+  key: string;
+  name: string;
+  path: string;
+  createdAt?: number;
+  updatedAt?: number;
+  attachments?: string[];
+}
+
+export interface BackupEntityPreview {
+  entity: BackupEntity;
+  data: unknown;
+}
+
+export interface FieldChange {
+  field: string;
+  old?: unknown;
+  new?: unknown;
+  diff?: string;
+}
+
+export interface RestorePlanItem {
+  type: string;
+  key: string;
+  name: string;
+  action: 'create' | 'update' | 'skip' | 'conflict';
+  reason?: string;
+  fields?: FieldChange[];
+}
+
+export interface RestorePlan {
+  backup: string;
+  targetUrl: string;
+  overwrite: boolean;
+  createdAt: string;
+  summary: {
+    create: number;
+    update: number;
+    skip: number;
+    conflict: number;
+  };
+  items: RestorePlanItem[];
 }
 
 export interface DataTypeSelection {
@@ -25,7 +104,7 @@ export interface DataTypeSelection {
 
 export interface OperationStatus {
   id: string;
-  type: 'backup' | 'restore';
+  type: 'backup' | 'restore' | 'drill';
   status: 'running' | 'completed' | 'failed';
   progress: number;
   message: string;